@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/monitor"
+)
+
+// 小时分布柱状图的最大宽度
+const analyzeBarWidth = 40
+
+// analyzeReport 离线日志分析报告
+type analyzeReport struct {
+	Files        []string     `json:"files"`                   // 已分析的日志文件
+	Since        *time.Time   `json:"since,omitempty"`         // 统计起始时间，未指定时为空
+	Failed       int          `json:"failed"`                  // 登录失败次数
+	Success      int          `json:"success"`                 // 登录成功次数
+	TopIPs       []countEntry `json:"top_ips"`                 // 失败次数最多的IP
+	TopUsers     []countEntry `json:"top_users"`               // 失败次数最多的用户名
+	TopCountries []countEntry `json:"top_countries,omitempty"` // 失败次数最多的国家
+	GeoIPs       int          `json:"geo_ips,omitempty"`       // 参与国家统计的IP数量
+	Hourly       [24]int      `json:"hourly"`                  // 各小时的登录失败次数
+}
+
+// countEntry 排行榜中的一项
+type countEntry struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// runAnalyze 处理analyze子命令，离线分析SSH日志并输出攻击报告
+// 只读取日志文件，不会修改防火墙规则和黑名单
+// 参数:
+//   - args: analyze之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	since := fs.String("since", "", "只统计最近一段时间的日志，如 7d、12h，默认统计全部")
+	top := fs.Int("top", 10, "排行榜显示的条数")
+	geo := fs.Bool("geo", true, "是否查询IP属地以统计国家分布")
+	geoLimit := fs.Int("geo-limit", 50, "最多查询属地的IP数量，按失败次数从高到低选取")
+	configPath := fs.String("config", defaultConfigPath, "读取IP信息接口配置的配置文件路径")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fmt.Println("用法: ssh_fb analyze [--since 7d] [--top N] [--geo=false] [--output text|json] <日志文件>...")
+		return exitUsage
+	}
+
+	report := &analyzeReport{Files: fs.Args()}
+	if *since != "" {
+		d, err := parseSince(*since)
+		if err != nil {
+			fmt.Printf("无效的时间范围: %v\n", err)
+			return exitUsage
+		}
+		start := time.Now().Add(-d)
+		report.Since = &start
+	}
+
+	ipCounts := make(map[string]int)
+	userCounts := make(map[string]int)
+	for _, path := range report.Files {
+		if err := analyzeFile(path, report, ipCounts, userCounts); err != nil {
+			fmt.Fprintf(os.Stderr, "分析日志失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+	}
+	report.TopIPs = topCounts(ipCounts, *top)
+	report.TopUsers = topCounts(userCounts, *top)
+
+	if *geo && len(ipCounts) > 0 {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置失败，使用默认的IP信息接口: %v\n", err)
+			cfg = config.Default()
+		}
+		client := monitor.NewIPInfoClient(cfg.IPInfo)
+		if err := client.SetCache(cfg.IPInfo.CacheFile, time.Duration(cfg.IPInfo.CacheTTL), cfg.IPInfo.CacheSize); err != nil {
+			fmt.Fprintf(os.Stderr, "加载IP信息缓存失败: %v\n", err)
+		}
+		entries := topCounts(ipCounts, *geoLimit)
+		ips := make([]string, len(entries))
+		for i, entry := range entries {
+			ips[i] = entry.Name
+		}
+		infos, err := client.GetIPInfoBatch(ips)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "部分IP属地查询失败: %v\n", err)
+		}
+		countryCounts := make(map[string]int)
+		for _, entry := range entries {
+			country := "未知"
+			if info := infos[entry.Name]; info != nil && info.Country != "" {
+				country = info.Country
+			}
+			countryCounts[country] += entry.Count
+			report.GeoIPs++
+		}
+		report.TopCountries = topCounts(countryCounts, *top)
+		if err := client.SaveCache(); err != nil {
+			fmt.Fprintf(os.Stderr, "保存IP信息缓存失败: %v\n", err)
+		}
+	}
+
+	return printResult(*output, report, func() { printAnalyzeReport(report) })
+}
+
+// parseSince 解析时间范围，在time.ParseDuration的基础上支持以d结尾的天数
+// 参数:
+//   - s: 时间范围，如 7d、36h
+// 返回:
+//   - time.Duration: 时间范围
+//   - error: 格式无效时的错误信息
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s", s)
+	}
+	return d, nil
+}
+
+// analyzeFile 统计单个日志文件中的登录记录
+// 以.gz结尾的轮转日志会自动解压；syslog时间戳没有年份，按文件修改时间推断
+// 参数:
+//   - path: 日志文件路径
+//   - report: 累加统计结果的报告
+//   - ipCounts: 各IP的失败次数
+//   - userCounts: 各用户名的失败次数
+// 返回:
+//   - error: 打开或读取文件失败时的错误信息
+func analyzeFile(path string, report *analyzeReport, ipCounts, userCounts map[string]int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	ref := stat.ModTime()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("解压%s失败: %v", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		login, ok := monitor.ParseSSHLine(scanner.Text(), ref)
+		if !ok {
+			continue
+		}
+		if report.Since != nil && login.Timestamp.Before(*report.Since) {
+			continue
+		}
+		if login.Outcome == monitor.OutcomeSuccess {
+			report.Success++
+			continue
+		}
+		if !login.CountsAsFailure() {
+			continue
+		}
+		report.Failed++
+		report.Hourly[login.Timestamp.Hour()]++
+		ipCounts[login.IP]++
+		if login.User != "" {
+			userCounts[login.User]++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取%s失败: %v", path, err)
+	}
+	return nil
+}
+
+// topCounts 按次数从高到低返回前n项，次数相同时按名称排序
+func topCounts(counts map[string]int, n int) []countEntry {
+	entries := make([]countEntry, 0, len(counts))
+	for name, count := range counts {
+		entries = append(entries, countEntry{Name: name, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	if n >= 0 && len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries
+}
+
+// printAnalyzeReport 以文本形式输出分析报告
+func printAnalyzeReport(report *analyzeReport) {
+	fmt.Printf("已分析 %d 个文件", len(report.Files))
+	if report.Since != nil {
+		fmt.Printf("，统计自 %s 起", report.Since.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("\n登录失败: %d  登录成功: %d\n", report.Failed, report.Success)
+
+	printCountTable("攻击来源IP", report.TopIPs)
+	printCountTable("尝试的用户名", report.TopUsers)
+	if report.GeoIPs > 0 {
+		printCountTable(fmt.Sprintf("攻击来源国家（基于失败次数最多的%d个IP）", report.GeoIPs), report.TopCountries)
+	}
+
+	fmt.Println("\n按小时分布:")
+	max := 0
+	for _, n := range report.Hourly {
+		if n > max {
+			max = n
+		}
+	}
+	for hour, n := range report.Hourly {
+		bar := 0
+		if max > 0 {
+			bar = (n*analyzeBarWidth + max - 1) / max
+		}
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %02d:00 %8d %s", hour, n, strings.Repeat("#", bar)), " "))
+	}
+}
+
+func printCountTable(title string, entries []countEntry) {
+	fmt.Printf("\n%s:\n", title)
+	if len(entries) == 0 {
+		fmt.Println("  无")
+		return
+	}
+	for i, entry := range entries {
+		fmt.Printf("  %2d. %s %8d\n", i+1, runewidth.FillRight(entry.Name, 32), entry.Count)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/audit"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// runAudit 处理audit子命令，检查主机配置并给出加固建议
+// 支持的子命令:
+//   - host: 检查主机的SSH安全配置并打分（默认）
+//   - sshd: 检查sshd_config中与暴力破解相关的配置项
+// 参数:
+//   - args: audit之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runAudit(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runAuditHost(args)
+	}
+	switch args[0] {
+	case "host":
+		return runAuditHost(args[1:])
+	case "sshd":
+		return runAuditSSHD(args[1:])
+	default:
+		fmt.Printf("未知的检查项: %s\n", args[0])
+		fmt.Println("用法: ssh_fb audit [host|sshd] [--output text|json]")
+		return exitUsage
+	}
+}
+
+// loadAuditConfig 加载防护配置，失败时使用默认配置
+func loadAuditConfig(path string) *config.Config {
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载配置失败，按默认配置检查: %v\n", err)
+		cfg = config.Default()
+	}
+	return cfg
+}
+
+// sshdConfigPath 返回要检查的sshd_config路径，命令行参数优先
+func sshdConfigPath(file string, cfg *config.Config) string {
+	if file != "" {
+		return file
+	}
+	if cfg.SSHProtection.SSHDConfig != "" {
+		return cfg.SSHProtection.SSHDConfig
+	}
+	return config.Default().SSHProtection.SSHDConfig
+}
+
+// runAuditHost 检查主机的SSH安全配置，输出带得分的报告
+// 指定--notify时把报告发送到所有已启用的通知渠道
+func runAuditHost(args []string) int {
+	fs := flag.NewFlagSet("audit host", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	configPath := fs.String("config", defaultConfigPath, "读取防护配置的配置文件路径")
+	file := fs.String("file", "", "sshd配置文件路径，默认使用配置中的ssh_protection.sshd_config")
+	notify := fs.Bool("notify", false, "将检查报告发送到已启用的通知渠道")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg := loadAuditConfig(*configPath)
+	report, err := audit.AuditHost(audit.DefaultHostOptions(sshdConfigPath(*file, cfg)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "检查主机配置失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	code := printResult(*output, report, func() {
+		fmt.Printf("得分: %d/100\n", report.Score)
+		for _, c := range report.Checks {
+			mark := "✓"
+			if !c.Passed {
+				mark = "✗"
+			}
+			fmt.Printf("%s %s（%d分）: %s\n", mark, c.Name, c.Weight, c.Detail)
+		}
+	})
+	if code != exitOK || !*notify {
+		return code
+	}
+	return sendAuditReport(cfg, report)
+}
+
+// sendAuditReport 将主机检查报告直接发送到所有已启用的通知渠道
+// 命令行中没有运行中的发送队列，不经过严重级别路由和重试
+// 返回:
+//   - int: 进程退出码，部分渠道发送失败时返回exitPartialSuccess
+func sendAuditReport(cfg *config.Config, report audit.HostReport) int {
+	if !notification.Enabled(cfg.Notifications, notification.EventAuditReport) {
+		fmt.Fprintln(os.Stderr, "notifications.audit_report未启用，未发送通知")
+		return exitOK
+	}
+
+	var findings []string
+	for _, c := range report.Failed() {
+		findings = append(findings, fmt.Sprintf("%s: %s", c.Name, c.Detail))
+	}
+	server := fmt.Sprintf("%s (%s)", cfg.Service.ServiceName, cfg.Service.InstallPath)
+	event := notification.AuditReportEvent(report.Score, findings, server, time.Now())
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	var sent, failed int
+	for _, c := range notifyChannels {
+		channel, err := c.open(cfg, logger)
+		if err == notification.ErrDisabled {
+			continue
+		}
+		if err == nil {
+			err = channel.Notify(event)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "发送到%s失败: %v\n", c.name, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	switch {
+	case failed == 0:
+		return exitOK
+	case sent > 0:
+		return exitPartialSuccess
+	default:
+		return exitFailure
+	}
+}
+
+// runAuditSSHD 检查sshd配置，优先读取 sshd -T 输出的生效配置
+func runAuditSSHD(args []string) int {
+	fs := flag.NewFlagSet("audit sshd", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	configPath := fs.String("config", defaultConfigPath, "读取防护配置的配置文件路径")
+	file := fs.String("file", "", "sshd配置文件路径，默认使用配置中的ssh_protection.sshd_config")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	cfg := loadAuditConfig(*configPath)
+	options, err := audit.LoadSSHDConfig(sshdConfigPath(*file, cfg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "检查sshd配置失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+	findings := audit.CheckSSHD(options, audit.NewSSHDContext(cfg.SSHProtection, 0))
+	if findings == nil {
+		findings = []audit.Finding{}
+	}
+	return printResult(*output, findings, func() {
+		if len(findings) == 0 {
+			fmt.Println("未发现需要调整的配置项")
+			return
+		}
+		for _, finding := range findings {
+			fmt.Printf("%-24s 当前: %-20s 建议: %s\n", finding.Option, finding.Current, finding.Suggested)
+			fmt.Printf("%-24s %s\n", "", finding.Reason)
+		}
+	})
+}
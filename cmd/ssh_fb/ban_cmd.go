@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// addSocketFlag 为需要连接守护进程的子命令注册--socket参数
+// 参数:
+//   - fs: 子命令的参数集
+// 返回:
+//   - *string: 解析后的socket路径
+func addSocketFlag(fs *flag.FlagSet) *string {
+	return fs.String("socket", config.Default().Control.Socket, "守护进程控制接口socket路径")
+}
+
+// runPermanent 处理permanent子命令，将IP提升为永久封禁
+// 参数:
+//   - args: permanent之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runPermanent(args []string) int {
+	fs := flag.NewFlagSet("permanent", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("用法: ssh_fb permanent [--socket 路径] <IP>")
+		return exitUsage
+	}
+
+	ip := fs.Arg(0)
+	if err := control.NewClient(*socket).MakePermanent(ip); err != nil {
+		fmt.Printf("永久封禁失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	fmt.Printf("IP %s 已永久封禁\n", ip)
+	return exitOK
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/banlist"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// runExport 处理export子命令，从守护进程读取当前封禁并按格式输出
+// 限速的IP不是封禁，不会导出
+// 参数:
+//   - args: export之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	format := fs.String("format", banlist.FormatTxt, "导出格式: txt、csv、json、ipset 或 nft")
+	path := fs.String("file", "-", "输出文件路径，为 - 时输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 0 || *format == banlist.FormatFail2ban {
+		fmt.Fprintln(os.Stderr, "用法: ssh_fb export [--socket 路径] [--format txt|csv|json|ipset|nft] [--file 路径]")
+		return exitUsage
+	}
+
+	bans, err := control.NewClient(*socket).Bans()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询封禁失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+	entries := make([]banlist.Entry, 0, len(bans))
+	for _, ban := range bans {
+		if ban.Limited {
+			continue
+		}
+		entries = append(entries, banlist.Entry{IP: ban.IP, Permanent: ban.Permanent, ExpiresAt: ban.ExpireTime, Reason: ban.Reason})
+	}
+
+	var w io.Writer = os.Stdout
+	if *path != "-" {
+		file, err := os.Create(*path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := banlist.Write(w, *format, entries); err != nil {
+		fmt.Fprintf(os.Stderr, "导出失败: %v\n", err)
+		return exitFailure
+	}
+	if *path != "-" {
+		fmt.Printf("已导出 %d 条封禁到 %s\n", len(entries), *path)
+	}
+	return exitOK
+}
+
+// importResult 导入的统计
+type importResult struct {
+	Imported int      `json:"imported"` // 成功封禁的数量
+	Skipped  int      `json:"skipped"`  // 已到期、已被封禁或不是单个IP而跳过的数量
+	Failed   []string `json:"failed"`   // 封禁失败的IP及原因
+}
+
+// runImport 处理import子命令，读取文件中的封禁并通过守护进程逐条封禁
+// 永久封禁按永久封禁导入，临时封禁按剩余时长导入，没有解封时间的按--duration或守护进程配置的封禁时长导入
+// 参数:
+//   - args: import之后的命令行参数
+// 返回:
+//   - int: 进程退出码，部分条目封禁失败时返回exitPartialSuccess
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	output := addOutputFlag(fs)
+	format := fs.String("format", banlist.FormatTxt, "导入格式: txt、csv、json、ipset、nft 或 fail2ban")
+	jail := fs.String("jail", "", "fail2ban数据库中只导入该jail的封禁，为空时导入所有jail")
+	banFor := fs.String("duration", "", "没有解封时间的条目的封禁时长，如 30m、12h、7d，为空时使用守护进程配置的封禁时长")
+	hours := fs.Int("hours", 0, "已废弃，请使用--duration；没有解封时间的条目的封禁时长（小时）")
+	permanent := fs.Bool("permanent", false, "所有条目都按永久封禁导入")
+	dryRun := fs.Bool("dry-run", false, "只列出将要导入的条目，不实际封禁")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	defaultDuration, err := config.ParseDuration(*banFor)
+	if fs.NArg() != 1 || *hours < 0 || err != nil || defaultDuration < 0 {
+		fmt.Println("用法: ssh_fb import [--socket 路径] [--format txt|csv|json|ipset|nft|fail2ban] [--jail 名称] [--duration 时长] [--permanent] [--dry-run] <文件|->")
+		return exitUsage
+	}
+	if defaultDuration == 0 {
+		defaultDuration = time.Duration(*hours) * time.Hour
+	}
+
+	entries, err := banlist.ReadFile(fs.Arg(0), *format, *jail)
+	if err != nil {
+		fmt.Printf("读取封禁列表失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	client := control.NewClient(*socket)
+	result := importResult{Failed: []string{}}
+	now := time.Now()
+	for _, entry := range entries {
+		entry.Permanent = entry.Permanent || *permanent
+		// 守护进程只支持封禁单个IP
+		if net.ParseIP(entry.IP) == nil || !entry.Permanent && !entry.ExpiresAt.IsZero() && !entry.ExpiresAt.After(now) {
+			result.Skipped++
+			continue
+		}
+		duration := defaultDuration
+		if !entry.ExpiresAt.IsZero() {
+			duration = entry.ExpiresAt.Sub(now)
+		}
+		if *dryRun {
+			printImportEntry(*output, entry, duration)
+			result.Imported++
+			continue
+		}
+
+		if entry.Permanent {
+			err = client.MakePermanent(entry.IP)
+		} else {
+			err = client.BanFor(entry.IP, duration)
+		}
+		switch {
+		case errors.Is(err, control.ErrUnreachable):
+			fmt.Printf("导入失败: %v\n", err)
+			return exitCodeFor(err)
+		case err != nil && strings.Contains(err.Error(), "已被封禁"):
+			result.Skipped++
+		case err != nil:
+			result.Failed = append(result.Failed, fmt.Sprintf("%s: %v", entry.IP, err))
+		default:
+			result.Imported++
+		}
+	}
+
+	if code := printResult(*output, result, func() {
+		verb := "已导入"
+		if *dryRun {
+			verb = "将导入"
+		}
+		fmt.Printf("%s %d 条，跳过 %d 条，失败 %d 条\n", verb, result.Imported, result.Skipped, len(result.Failed))
+		for _, failure := range result.Failed {
+			fmt.Printf("  %s\n", failure)
+		}
+	}); code != exitOK {
+		return code
+	}
+	switch {
+	case len(result.Failed) == 0:
+		return exitOK
+	case result.Imported > 0:
+		return exitPartialSuccess
+	default:
+		return exitFailure
+	}
+}
+
+// printImportEntry --dry-run时以文本形式输出一条将要导入的封禁
+func printImportEntry(output string, entry banlist.Entry, duration time.Duration) {
+	if output == outputJSON {
+		return
+	}
+	switch {
+	case entry.Permanent:
+		fmt.Printf("  %-40s 永久封禁\n", entry.IP)
+	case duration > 0:
+		fmt.Printf("  %-40s 封禁 %s\n", entry.IP, duration.Round(time.Minute))
+	default:
+		fmt.Printf("  %-40s 按配置的时长封禁\n", entry.IP)
+	}
+}
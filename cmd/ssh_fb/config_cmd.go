@@ -0,0 +1,140 @@
+package main
+
+//go:generate go run . config docs --file ../../configs/config.yaml
+//go:generate go run . config docs --format markdown --file ../../docs/config.md
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// runConfigCommand 处理config子命令
+// 支持的子命令:
+//   - docs: 根据代码生成参考配置或Markdown说明表
+//   - test: 检查配置文件并使用示例数据渲染通知模板
+// 参数:
+//   - args: config之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Println("用法: ssh_fb config docs [--format yaml|markdown] [--file 输出文件]")
+		fmt.Println("      ssh_fb config test [--config 配置文件] [--output text|json]")
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "docs":
+		return runConfigDocs(args[1:])
+	case "test":
+		return runConfigTest(args[1:])
+	default:
+		fmt.Printf("未知的config子命令: %s\n", args[0])
+		return exitUsage
+	}
+}
+
+func runConfigDocs(args []string) int {
+	fs := flag.NewFlagSet("config docs", flag.ContinueOnError)
+	format := fs.String("format", "yaml", "输出格式: yaml 或 markdown")
+	file := fs.String("file", "", "输出文件路径，默认输出到标准输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	var out io.Writer = os.Stdout
+	if *file != "" {
+		f, err := os.Create(*file)
+		if err != nil {
+			fmt.Printf("创建输出文件失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	switch *format {
+	case "yaml":
+		err = config.WriteReferenceYAML(out)
+	case "markdown", "md":
+		err = config.WriteReferenceMarkdown(out)
+	default:
+		fmt.Printf("不支持的输出格式: %s\n", *format)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Printf("生成配置文档失败: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// configTestResult 配置检查结果
+type configTestResult struct {
+	Config     string                        `json:"config"`               // 配置文件路径
+	Error      string                        `json:"error,omitempty"`      // 配置加载或校验错误
+	Deprecated []config.DeprecatedKey        `json:"deprecated,omitempty"` // 使用的废弃配置项，不影响检查结果
+	Templates  []notification.TemplateResult `json:"templates"`            // 通知模板检查结果
+}
+
+// runConfigTest 检查配置文件，并用示例数据渲染所有通知模板
+// 配置或任一模板有误时返回exitConfigError
+func runConfigTest(args []string) int {
+	fs := flag.NewFlagSet("config test", flag.ContinueOnError)
+	path := fs.String("config", defaultConfigPath, "要检查的配置文件路径")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	result := configTestResult{Config: *path}
+	cfg, deprecated, err := config.LoadConfigWithDeprecations(*path)
+	result.Deprecated = deprecated
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Templates = notification.LintTemplates(cfg.Notifications)
+	}
+
+	failed := result.Error != ""
+	for _, t := range result.Templates {
+		if t.Error != "" {
+			failed = true
+		}
+	}
+
+	code := printResult(*output, result, func() {
+		if result.Error != "" {
+			fmt.Printf("✗ %s: %s\n", result.Config, result.Error)
+			return
+		}
+		fmt.Printf("✓ %s 配置校验通过\n", result.Config)
+		for _, d := range result.Deprecated {
+			fmt.Printf("⚠ %s\n", d)
+		}
+		for _, t := range result.Templates {
+			state := "已启用"
+			if !t.Enabled {
+				state = "未启用"
+			}
+			if t.Error != "" {
+				fmt.Printf("\n✗ %s（%s）: %s\n", t.Name, state, t.Error)
+				continue
+			}
+			fmt.Printf("\n✓ %s（%s）示例渲染:\n%s\n", t.Name, state, t.Output)
+		}
+	})
+	if code != exitOK {
+		return code
+	}
+	if failed {
+		return exitConfigError
+	}
+	return exitOK
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// 命令行退出码
+// 供自动化脚本区分失败原因，新增取值时需同步更新README
+const (
+	exitOK                = 0 // 执行成功
+	exitFailure           = 1 // 一般性错误
+	exitUsage             = 2 // 命令行用法错误
+	exitConfigError       = 3 // 配置文件加载或校验失败
+	exitPermissionDenied  = 4 // 权限不足
+	exitDaemonUnreachable = 5 // 无法连接到运行中的守护进程
+	exitPartialSuccess    = 6 // 部分步骤执行失败
+)
+
+// 输出格式
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// addOutputFlag 为查询类子命令注册--output参数
+// 参数:
+//   - fs: 子命令的参数集
+// 返回:
+//   - *string: 解析后的输出格式
+func addOutputFlag(fs *flag.FlagSet) *string {
+	return fs.String("output", outputText, "输出格式: text 或 json")
+}
+
+// printResult 按输出格式打印查询结果
+// 参数:
+//   - format: 输出格式
+//   - v: JSON输出时序列化的结果
+//   - text: 文本输出时调用的打印函数
+// 返回:
+//   - int: 进程退出码
+func printResult(format string, v interface{}, text func()) int {
+	switch format {
+	case outputJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			fmt.Fprintf(os.Stderr, "输出JSON失败: %v\n", err)
+			return exitFailure
+		}
+	case outputText, "":
+		text()
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的输出格式: %s\n", format)
+		return exitUsage
+	}
+	return exitOK
+}
+
+// exitCodeFor 根据错误类型选择退出码
+// 参数:
+//   - err: 命令执行过程中的错误
+// 返回:
+//   - int: 对应的退出码
+func exitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, os.ErrPermission):
+		return exitPermissionDenied
+	case errors.Is(err, control.ErrUnreachable):
+		return exitDaemonUnreachable
+	default:
+		return exitFailure
+	}
+}
+
+// requireRoot 检查当前进程是否以root身份运行
+// 返回:
+//   - error: 非root运行时的错误信息
+func requireRoot() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("该操作需要root权限，请使用sudo运行")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/monitor"
+	"github.com/yourusername/ssh_fb/internal/ticket"
+)
+
+// runIncident 处理incident子命令，汇总一个IP或网段的事件、封禁、执法记录、防火墙规则和原始日志
+// 默认输出Markdown报告，--zip 时写为压缩包，--push 时创建工单并附上压缩包
+// 参数:
+//   - args: incident之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runIncident(args []string) int {
+	fs := flag.NewFlagSet("incident", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	zipPath := fs.String("zip", "", "将报告写为zip压缩包（incident.md、incident.json、raw.log）")
+	since := fs.String("since", "", "只收集最近一段时间的事件和执法记录，如 7d、12h，默认收集全部")
+	maxLines := fs.Int("max-lines", 500, "最多收集的原始日志行数，超出时保留最新的")
+	noLookup := fs.Bool("no-lookup", false, "不查询IP属地、ASN等补充信息")
+	push := fs.String("push", "", "创建工单并附上报告，取值为jira或servicenow")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("用法: ssh_fb incident [--since 7d] [--zip 文件] [--push jira|servicenow] [--output text|json] <IP或CIDR>")
+		return exitUsage
+	}
+	target := fs.Arg(0)
+
+	opts := monitor.IncidentOptions{MaxLines: *maxLines, Lookup: !*noLookup}
+	if *since != "" {
+		d, err := parseSince(*since)
+		if err != nil {
+			fmt.Printf("无效的时间范围: %v\n", err)
+			return exitUsage
+		}
+		opts.Since = time.Now().Add(-d)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		return exitConfigError
+	}
+	var tracker ticket.Tracker
+	if *push != "" {
+		if tracker, err = ticket.New(*push, cfg.Alerting); err != nil {
+			fmt.Println(err)
+			return exitUsage
+		}
+	}
+
+	inc, err := monitor.CollectIncident(cfg, target, opts)
+	if err != nil {
+		fmt.Printf("生成事件报告失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	var bundle bytes.Buffer
+	if err := inc.WriteZip(&bundle); err != nil {
+		fmt.Printf("生成压缩包失败: %v\n", err)
+		return exitFailure
+	}
+	if *zipPath != "" {
+		if err := os.WriteFile(*zipPath, bundle.Bytes(), 0600); err != nil {
+			fmt.Printf("写入压缩包失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		fmt.Fprintf(os.Stderr, "事件报告已写入 %s\n", *zipPath)
+	}
+	if tracker != nil {
+		ref, err := tracker.Create(ticket.Ticket{
+			Title:       inc.Title(),
+			Description: inc.Summary(),
+			Attachments: []ticket.Attachment{{Name: fmt.Sprintf("incident-%s.zip", inc.Generated.Format("20060102-150405")), Data: bundle.Bytes()}},
+		})
+		if ref != "" {
+			fmt.Fprintf(os.Stderr, "已创建工单 %s\n", ref)
+		}
+		if err != nil {
+			fmt.Printf("提交工单失败: %v\n", err)
+			if ref != "" {
+				return exitPartialSuccess
+			}
+			return exitFailure
+		}
+	}
+	if *zipPath != "" || tracker != nil {
+		return exitOK
+	}
+	return printResult(*output, inc, func() { fmt.Print(inc.Markdown()) })
+}
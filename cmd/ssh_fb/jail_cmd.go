@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// runJail 处理jail子命令，查看或切换jail的启用状态
+// 支持的子命令:
+//   - list: 列出所有jail及其状态（默认）
+//   - enable <名称>: 启用jail
+//   - disable <名称>: 停用jail
+// 参数:
+//   - args: jail之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runJail(args []string) int {
+	fs := flag.NewFlagSet("jail", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	client := control.NewClient(*socket)
+	action := fs.Arg(0)
+	switch {
+	case action == "" || action == "list":
+		jails, err := client.Jails()
+		if err != nil {
+			fmt.Printf("查询jail失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		return printResult(*output, jails, func() {
+			for _, jail := range jails {
+				state := "已启用"
+				if !jail.Enabled {
+					state = "已停用"
+				}
+				reading := "读取中"
+				if !jail.Running {
+					reading = "已中断"
+				}
+				fmt.Printf("%-16s %s  %s  协程: %d  已读取: %d  待处理: %d  重启: %d  panic: %d\n",
+					jail.Name, state, reading, jail.Workers, jail.Lines, jail.Queue, jail.Restarts, jail.Panics)
+				if jail.LastError != "" {
+					fmt.Printf("%-16s 最近错误: %s\n", "", jail.LastError)
+				}
+			}
+		})
+	case (action == "enable" || action == "disable") && fs.NArg() == 2:
+		name := fs.Arg(1)
+		if err := client.SetJailEnabled(name, action == "enable"); err != nil {
+			fmt.Printf("切换jail状态失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		if action == "enable" {
+			fmt.Printf("jail %s 已启用\n", name)
+		} else {
+			fmt.Printf("jail %s 已停用\n", name)
+		}
+		return exitOK
+	default:
+		fmt.Println("用法: ssh_fb jail [--socket 路径] [list | enable <名称> | disable <名称>]")
+		return exitUsage
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/mattn/go-runewidth"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/logging"
+)
+
+// 事件在日志流中使用的模块名
+const eventModule = "event"
+
+// 终端颜色
+const (
+	colorReset  = "\x1b[0m"
+	colorGray   = "\x1b[90m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorCyan   = "\x1b[36m"
+)
+
+// logFilter 日志过滤条件
+type logFilter struct {
+	level  logrus.Level // 最低日志级别
+	module string       // 模块名，为空时不过滤
+	ip     string       // IP地址，为空时不过滤
+	events bool         // 是否包含事件
+}
+
+// runLogs 处理logs子命令，输出守护进程的日志与事件
+// 参数:
+//   - args: logs之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runLogs(args []string) int {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	output := addOutputFlag(fs)
+	follow := fs.Bool("follow", false, "持续输出新的日志")
+	fs.BoolVar(follow, "f", false, "同--follow")
+	level := fs.String("level", "info", "最低日志级别: trace|debug|info|warn|error")
+	module := fs.String("module", "", "只显示指定模块的日志，如 monitor、notification、event")
+	ip := fs.String("ip", "", "只显示与指定IP相关的日志")
+	withEvents := fs.Bool("events", true, "是否同时显示监控事件")
+	noColor := fs.Bool("no-color", false, "禁用彩色输出")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	minLevel, err := logrus.ParseLevel(*level)
+	if err != nil {
+		fmt.Printf("无效的日志级别: %s\n", *level)
+		return exitUsage
+	}
+	if *output != outputText && *output != outputJSON {
+		fmt.Printf("不支持的输出格式: %s\n", *output)
+		return exitUsage
+	}
+
+	filter := logFilter{level: minLevel, module: *module, ip: *ip, events: *withEvents}
+	color := !*noColor && *output == outputText && isatty.IsTerminal(os.Stdout.Fd())
+	client := control.NewClient(*socket)
+
+	var logSeq, eventSeq uint64
+	for {
+		entries, err := client.Logs(logSeq)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "读取日志失败: %v\n", err)
+			return exitCodeFor(err)
+		}
+		var events []event.Event
+		if filter.events {
+			if events, err = client.Events(eventSeq); err != nil {
+				fmt.Fprintf(os.Stderr, "读取事件失败: %v\n", err)
+				return exitCodeFor(err)
+			}
+		}
+
+		for _, e := range entries {
+			logSeq = e.Seq
+		}
+		for _, e := range events {
+			eventSeq = e.Seq
+			entries = append(entries, eventEntry(e))
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+		for _, e := range entries {
+			if !filter.match(e) {
+				continue
+			}
+			if *output == outputJSON {
+				data, _ := json.Marshal(e)
+				fmt.Println(string(data))
+			} else {
+				fmt.Println(formatLogEntry(e, color))
+			}
+		}
+
+		if !*follow {
+			return exitOK
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// eventEntry 将监控事件转换为日志条目，便于统一过滤和输出
+func eventEntry(e event.Event) logging.Entry {
+	fields := map[string]interface{}{"ip": e.IP}
+	if e.Port != 0 {
+		fields["port"] = e.Port
+	}
+	if e.PID != 0 {
+		fields["pid"] = e.PID
+	}
+	if e.User != "" {
+		fields["user"] = e.User
+	}
+	if e.Method != "" {
+		fields["method"] = e.Method
+	}
+	if e.Client != "" {
+		fields["client"] = e.Client
+	}
+	return logging.Entry{
+		Seq:     e.Seq,
+		Time:    e.Time,
+		Level:   eventModule,
+		Module:  eventModule,
+		Message: fmt.Sprintf("%s %s", e.Type, e.Message),
+		Fields:  fields,
+	}
+}
+
+// match 检查日志是否满足过滤条件
+func (f logFilter) match(e logging.Entry) bool {
+	if e.Module == eventModule {
+		if f.level < logrus.InfoLevel {
+			return false
+		}
+	} else if level, err := logrus.ParseLevel(e.Level); err == nil && level > f.level {
+		return false
+	}
+	if f.module != "" && e.Module != f.module {
+		return false
+	}
+	if f.ip != "" && fmt.Sprint(e.Fields["ip"]) != f.ip {
+		return false
+	}
+	return true
+}
+
+// formatLogEntry 将日志格式化为对齐的可读文本
+func formatLogEntry(e logging.Entry, color bool) string {
+	var fields []string
+	for k, v := range e.Fields {
+		fields = append(fields, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(fields)
+
+	line := fmt.Sprintf("%s %s %s %s",
+		paint(color, colorGray, e.Time.Format("2006-01-02 15:04:05")),
+		paint(color, levelColor(e.Level), fmt.Sprintf("%-5s", strings.ToUpper(shortLevel(e.Level)))),
+		paint(color, colorCyan, fmt.Sprintf("%-12s", e.Module)),
+		runewidth.FillRight(e.Message, 36))
+	if len(fields) > 0 {
+		line += " " + paint(color, colorGray, strings.Join(fields, " "))
+	}
+	return strings.TrimRight(line, " ")
+}
+
+func shortLevel(level string) string {
+	if level == "warning" {
+		return "warn"
+	}
+	return level
+}
+
+func levelColor(level string) string {
+	switch level {
+	case "trace", "debug":
+		return colorGray
+	case "info":
+		return colorGreen
+	case "warning":
+		return colorYellow
+	case "error", "fatal", "panic":
+		return colorRed
+	case eventModule:
+		return colorCyan
+	}
+	return ""
+}
+
+func paint(enabled bool, color, text string) string {
+	if !enabled || color == "" {
+		return text
+	}
+	return color + text + colorReset
+}
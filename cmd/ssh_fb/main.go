@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
@@ -8,14 +9,21 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/Axnl/ssh_fb/internal/config"
-	"github.com/Axnl/ssh_fb/internal/monitor"
-	"github.com/Axnl/ssh_fb/internal/notification"
-	"github.com/Axnl/ssh_fb/pkg/firewall"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/logging"
+	"github.com/yourusername/ssh_fb/internal/monitor"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/pkg/firewall"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// 默认配置文件路径
+const defaultConfigPath = "configs/config.yaml"
+
 // 版本信息
 var (
 	Version   string = "unknown"
@@ -27,6 +35,7 @@ var (
 	cmdUninstall bool
 	cmdHelp      bool
 	cmdVersion   bool
+	cmdOutput    string
 )
 
 func init() {
@@ -34,7 +43,8 @@ func init() {
 	flag.BoolVar(&cmdUninstall, "uninstall", false, "卸载服务")
 	flag.BoolVar(&cmdHelp, "help", false, "显示帮助信息")
 	flag.BoolVar(&cmdVersion, "version", false, "显示版本信息")
-	
+	flag.StringVar(&cmdOutput, "output", outputText, "查询类命令的输出格式: text 或 json")
+
 	flag.Usage = func() {
 		fmt.Println("SSH防护系统使用说明：")
 		fmt.Println("\n命令选项：")
@@ -42,12 +52,47 @@ func init() {
 		fmt.Println("  uninstall 卸载系统服务")
 		fmt.Println("  help     显示帮助信息")
 		fmt.Println("  version  显示版本信息")
+		fmt.Println("  config docs 生成参考配置（--format yaml|markdown）")
+		fmt.Println("  permanent <IP> 将IP提升为永久封禁")
+		fmt.Println("  top      终端实时监控界面")
+		fmt.Println("  logs     查看守护进程日志与事件（--follow 持续输出）")
+		fmt.Println("  notify-test 用示例数据测试通知渠道与模板")
+		fmt.Println("  config test 检查配置文件并试渲染通知模板")
+		fmt.Println("  jail [list|enable|disable] 查看或切换jail的启用状态")
+		fmt.Println("  analyze <日志文件>... 离线分析SSH日志（支持.gz），不修改防火墙")
+		fmt.Println("  status   查看守护进程运行状态（IP信息接口配额与熔断）")
+		fmt.Println("  stats    查看最近24小时或最近7天的攻击统计（--by hour|day）")
+		fmt.Println("  audit [host] 检查主机的SSH安全配置并打分（--notify 发送报告）")
+		fmt.Println("  audit sshd 检查sshd配置并给出加固建议")
+		fmt.Println("  verify   交叉检查黑名单、存储与防火墙规则（--repair 修复）")
+		fmt.Println("  incident <IP或CIDR> 汇总事件、封禁、防火墙规则与原始日志，生成工单附件")
+		fmt.Println("  export   导出当前封禁（--format txt|csv|json|ipset|nft）")
+		fmt.Println("  import <文件> 导入封禁列表（--format 同上，或 fail2ban 读取fail2ban数据库）")
 		fmt.Println("\n无参数启动：直接运行SSH防护系统")
 		fmt.Println("\n示例：")
 		fmt.Println("  ./ssh_fb         # 启动SSH防护系统")
 		fmt.Println("  ./ssh_fb install # 安装服务")
 		fmt.Println("  ./ssh_fb uninstall # 卸载服务")
 		fmt.Println("  ./ssh_fb version  # 显示版本信息")
+		fmt.Println("  ./ssh_fb config docs --format markdown # 生成配置说明表")
+		fmt.Println("  ./ssh_fb version --output json # 以JSON格式输出版本信息")
+		fmt.Println("  ./ssh_fb permanent 1.2.3.4 # 永久封禁IP")
+		fmt.Println("  ./ssh_fb top      # 实时查看事件与封禁")
+		fmt.Println("  ./ssh_fb logs --follow --level warn --ip 1.2.3.4 # 按条件跟踪日志")
+		fmt.Println("  ./ssh_fb notify-test --channel all --event banned # 测试封禁通知")
+		fmt.Println("  ./ssh_fb jail disable sshd # 临时停用SSH防护")
+		fmt.Println("  ./ssh_fb analyze --since 7d /var/log/auth.log* # 分析最近7天的攻击")
+		fmt.Println("  ./ssh_fb status --output json # 以JSON格式输出运行状态")
+		fmt.Println("  ./ssh_fb stats --by day # 按天查看最近7天的登录失败、封禁与排行")
+		fmt.Println("  sudo ./ssh_fb audit --notify # 检查主机并把报告发送到通知渠道")
+		fmt.Println("  ./ssh_fb audit sshd # 检查sshd的MaxAuthTries、MaxStartups等配置")
+		fmt.Println("  sudo ./ssh_fb verify --repair # 修复黑名单与防火墙规则的不一致")
+		fmt.Println("  sudo ./ssh_fb incident --zip 1.2.3.4.zip --push jira 1.2.3.4 # 生成事件报告并创建Jira工单")
+		fmt.Println("  ./ssh_fb export --format nft --file blacklist.nft # 导出为nftables集合")
+		fmt.Println("  ./ssh_fb import --format fail2ban --jail sshd /var/lib/fail2ban/fail2ban.sqlite3 # 从fail2ban迁移")
+		fmt.Println("\n退出码：")
+		fmt.Println("  0 成功  1 一般错误  2 用法错误  3 配置错误")
+		fmt.Println("  4 权限不足  5 守护进程不可达  6 部分成功")
 	}
 }
 
@@ -63,49 +108,111 @@ func main() {
 		case "help":
 			cmdHelp = true
 		case "version":
-			cmdVersion = true
+			os.Exit(runVersion(os.Args[2:]))
+		case "config":
+			os.Exit(runConfigCommand(os.Args[2:]))
+		case "permanent":
+			os.Exit(runPermanent(os.Args[2:]))
+		case "top":
+			os.Exit(runTop(os.Args[2:]))
+		case "logs":
+			os.Exit(runLogs(os.Args[2:]))
+		case "notify-test":
+			os.Exit(runNotifyTest(os.Args[2:]))
+		case "jail":
+			os.Exit(runJail(os.Args[2:]))
+		case "analyze":
+			os.Exit(runAnalyze(os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		case "audit":
+			os.Exit(runAudit(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		case "incident":
+			os.Exit(runIncident(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
 		default:
 			fmt.Printf("未知命令: %s\n", os.Args[1])
 			flag.Usage()
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 	}
 
 	if cmdHelp {
 		flag.Usage()
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	if cmdVersion {
-		fmt.Printf("SSH防护系统\n版本: %s\n构建时间: %s\nGo版本: %s\n操作系统: %s/%s\n",
-			Version, BuildTime, runtime.Version(), runtime.GOOS, runtime.GOARCH)
-		os.Exit(0)
+		os.Exit(printVersion(cmdOutput))
 	}
 
 	// 加载配置
-	cfg, err := config.LoadConfig("configs/config.yaml")
+	cfg, deprecated, err := config.LoadConfigWithDeprecations(defaultConfigPath)
 	if err != nil {
 		fmt.Printf("加载配置失败: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitConfigError)
+	}
+	if err := notification.CheckTemplates(cfg.Notifications); err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		os.Exit(exitConfigError)
 	}
 
-	// 初始化日志
+	// 初始化日志，最近的日志同时保留在内存中供 ssh_fb logs 读取
 	logger := initLogger(cfg)
+	logBuffer := logging.NewBuffer(1000)
+	logger.AddHook(logBuffer)
+	var syslogClient *logging.Syslog
+	if cfg.Syslog.Enabled {
+		if syslogClient, err = logging.NewSyslog(cfg.Syslog); err != nil {
+			logger.WithError(err).Fatal("初始化syslog失败")
+		}
+		defer syslogClient.Close()
+		if level, err := logrus.ParseLevel(cfg.Syslog.LogLevel); err == nil {
+			// 同时发送程序日志
+			logger.AddHook(logging.NewSyslogHook(syslogClient, level))
+		}
+	}
+	for _, d := range deprecated {
+		logger.WithFields(logrus.Fields{
+			"key":         d.Path,
+			"replacement": d.Replacement,
+			"since":       d.Since,
+			"removed_in":  fmt.Sprintf("%d.0", d.RemovedIn),
+			"note":        d.Note,
+		}).Warn("配置项已废弃")
+	}
+
+	if cmdInstall || cmdUninstall {
+		if err := requireRoot(); err != nil {
+			fmt.Println(err)
+			os.Exit(exitPermissionDenied)
+		}
+	}
 
 	if cmdInstall {
 		if err := installService(cfg, logger); err != nil {
-			logger.WithError(err).Fatal("服务安装失败")
+			logger.WithError(err).Error("服务安装失败")
+			os.Exit(exitFailure)
 		}
 		logger.Info("服务安装成功")
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	if cmdUninstall {
-		if err := uninstallService(cfg, logger); err != nil {
-			logger.WithError(err).Fatal("服务卸载失败")
+		failed := uninstallService(cfg, logger)
+		if failed > 0 {
+			logger.WithField("failed_steps", failed).Warn("服务卸载部分完成")
+			os.Exit(exitPartialSuccess)
 		}
 		logger.Info("服务卸载成功")
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
 	// 正常启动程序
@@ -116,25 +223,93 @@ func main() {
 		logger.WithError(err).Fatal("工具检查/安装失败")
 	}
 
-	// 初始化Telegram通知
-	telegram, err := notification.NewTelegram(&notification.Config{
-		BotToken: cfg.Telegram.BotToken,
-		ChatID:   cfg.Telegram.ChatID,
-		Debug:    cfg.Debug.Enabled,
-	}, logger)
-	if err != nil {
-		logger.WithError(err).Fatal("初始化Telegram通知失败")
+	// 初始化通知渠道，Telegram未启用时只记录日志
+	notifier := notification.NewDispatcher(cfg.Notifications, logger)
+	var telegram *notification.Telegram
+	if cfg.Telegram.Enabled {
+		telegram, err = notification.NewTelegram(&notification.Config{
+			BotToken: cfg.Telegram.BotToken,
+			ChatID:   cfg.Telegram.ChatID,
+			ThreadID: cfg.Telegram.ThreadID,
+			Debug:    cfg.Debug.Enabled,
+			ProxyURL: cfg.Telegram.ProxyURL,
+			Chats:    cfg.Telegram.Chats,
+
+			ParseMode: cfg.Telegram.ParseMode,
+
+			AuthorizedUserIDs: cfg.Telegram.AuthorizedUserIDs,
+
+			Notifications: cfg.Notifications,
+		}, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("初始化Telegram通知失败")
+		}
+		notifier.Add("telegram", telegram)
+
+		// 启动Telegram命令处理，main返回时停止
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			if err := telegram.HandleCommands(ctx); err != nil {
+				logger.WithError(err).Error("Telegram命令处理失败")
+			}
+		}()
+	}
+	if cfg.Webhook.Enabled {
+		notifier.Add("webhook", notification.NewWebhook(cfg.Webhook, cfg.Notifications))
+	}
+	if syslogClient != nil {
+		notifier.Add("syslog", notification.NewSyslog(syslogClient, cfg.Syslog.Events, cfg.Notifications))
+	}
+	if cfg.Notifications.DingTalk.Enabled {
+		notifier.Add("dingtalk", notification.NewDingTalk(cfg.Notifications.DingTalk, cfg.Notifications))
+	}
+	if cfg.Notifications.WeCom.Enabled {
+		notifier.Add("wecom", notification.NewWeCom(cfg.Notifications.WeCom, cfg.Notifications))
+	}
+	if cfg.Alerting.PagerDuty.Enabled {
+		notifier.Add("pagerduty", notification.NewPagerDuty(cfg.Alerting.PagerDuty, cfg.Alerting.Events, cfg.Notifications))
 	}
+	if cfg.Alerting.Opsgenie.Enabled {
+		notifier.Add("opsgenie", notification.NewOpsgenie(cfg.Alerting.Opsgenie, cfg.Alerting.Events, cfg.Notifications))
+	}
+	if len(notifier.Channels()) == 0 {
+		logger.Warn("未启用任何通知渠道，事件只记录在日志中")
+	}
+
+	// 按调试配置启动性能分析接口
+	startProfiling(cfg.Debug, logger)
 
-	// 启动Telegram命令处理
+	// 创建监控器
+	mon := monitor.NewMonitor(cfg, logger, notifier)
+	if telegram != nil {
+		telegram.SetController(mon.As(monitor.SourceTelegram))
+	}
+
+	// 启动本地控制接口
+	ctrl := control.NewServer(cfg.Control.Socket, mon, logger)
+	ctrl.SetLogSource(logBuffer)
 	go func() {
-		if err := telegram.HandleCommands(); err != nil {
-			logger.WithError(err).Error("Telegram命令处理失败")
+		if err := ctrl.Start(); err != nil {
+			logger.WithError(err).Error("控制接口启动失败")
 		}
 	}()
+	defer ctrl.Close()
+	if cfg.Control.Listen != "" {
+		api := control.NewTCPServer(cfg.Control.Listen, cfg.Control.Token, mon.As(monitor.SourceAPI), logger)
+		api.SetLogSource(logBuffer)
+		if cfg.Control.Dashboard {
+			api.EnableDashboard()
+		}
+		go func() {
+			if err := api.Start(); err != nil {
+				logger.WithError(err).Error("HTTP接口启动失败")
+			}
+		}()
+		defer api.Close()
+	}
 
-	// 创建并启动监控器
-	mon := monitor.NewMonitor(cfg, logger, telegram)
+	// 启动监控器
 	if err := mon.Start(); err != nil {
 		logger.WithError(err).Fatal("启动监控器失败")
 	}
@@ -150,10 +325,14 @@ func initLogger(cfg *config.Config) *logrus.Logger {
 	}
 
 	// 配置日志格式
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp:   true,
-		TimestampFormat: "2006-01-02 15:04:05",
-	})
+	if cfg.Logging.Format == "json" {
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02 15:04:05",
+		})
+	}
 
 	// 设置日志级别
 	level, err := logrus.ParseLevel(cfg.Debug.LogLevel)
@@ -162,16 +341,51 @@ func initLogger(cfg *config.Config) *logrus.Logger {
 	}
 	logger.SetLevel(level)
 
-	// 输出到文件
+	// 输出到文件，轮转时按时间戳重命名旧文件，超出数量或天数的旧文件被删除
+	// 轮转过程中无法报告打开文件的错误，先确认日志文件可以写入
 	file, err := os.OpenFile(cfg.Logging.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		logger.Fatalf("无法创建日志文件: %v", err)
 	}
-	logger.SetOutput(file)
+	file.Close()
+	rotator := &lumberjack.Logger{
+		Filename:   cfg.Logging.LogFile,
+		MaxSize:    cfg.Logging.MaxSize,
+		MaxBackups: cfg.Logging.MaxBackups,
+		MaxAge:     cfg.Logging.MaxAge,
+		Compress:   cfg.Logging.Compress,
+		LocalTime:  true,
+	}
+	if cfg.Logging.RotateInterval > 0 {
+		go rotateLogPeriodically(rotator, time.Duration(cfg.Logging.RotateInterval)*time.Hour, logger)
+	}
+
+	switch cfg.Logging.Console {
+	case "stdout":
+		logger.SetOutput(io.MultiWriter(rotator, os.Stdout))
+	case "stderr":
+		logger.SetOutput(io.MultiWriter(rotator, os.Stderr))
+	default:
+		logger.SetOutput(rotator)
+	}
 
 	return logger
 }
 
+// rotateLogPeriodically 除按大小轮转外，每隔interval轮转一次程序日志
+// 参数:
+//   - rotator: 程序日志文件
+//   - interval: 轮转间隔
+//   - logger: 日志记录器，用于记录轮转失败
+func rotateLogPeriodically(rotator *lumberjack.Logger, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	for range ticker.C {
+		if err := rotator.Rotate(); err != nil {
+			logger.WithError(err).Error("轮转程序日志失败")
+		}
+	}
+}
+
 func checkAndInstallTools(logger *logrus.Logger) error {
 	ufw := firewall.NewUFW()
 	if !ufw.IsEnabled() {
@@ -207,28 +421,33 @@ func installService(cfg *config.Config, logger *logrus.Logger) error {
 	}
 
 	// 复制配置文件
-	if err := copyFile("configs/config.yaml", filepath.Join(cfg.Service.InstallPath, "config.yaml")); err != nil {
+	if err := copyFile(defaultConfigPath, filepath.Join(cfg.Service.InstallPath, "config.yaml")); err != nil {
 		return fmt.Errorf("复制配置文件失败: %v", err)
 	}
 
-	// 创建服务文件
+	// 创建服务文件，守护进程在加载完成后通过sd_notify报告启动完成
+	watchdog := ""
+	if cfg.Service.WatchdogSec > 0 {
+		watchdog = fmt.Sprintf("\nWatchdogSec=%d", cfg.Service.WatchdogSec)
+	}
 	serviceContent := fmt.Sprintf(`[Unit]
 Description=SSH Protection Service
 After=network.target
 
 [Service]
-Type=simple
+Type=notify
 User=%s
 WorkingDirectory=%s
 ExecStart=%s/ssh_fb
 Restart=always
-RestartSec=10
+RestartSec=10%s
 
 [Install]
 WantedBy=multi-user.target`,
 		cfg.Service.User,
 		cfg.Service.WorkingDirectory,
-		cfg.Service.InstallPath)
+		cfg.Service.InstallPath,
+		watchdog)
 
 	servicePath := filepath.Join("/etc/systemd/system", cfg.Service.ServiceFile)
 	if err := os.WriteFile(servicePath, []byte(serviceContent), 0644); err != nil {
@@ -256,40 +475,50 @@ WantedBy=multi-user.target`,
 	return nil
 }
 
-func uninstallService(cfg *config.Config, logger *logrus.Logger) error {
+// uninstallService 卸载系统服务
+// 各步骤失败时记录警告并继续执行后续步骤
+// 返回:
+//   - int: 执行失败的步骤数
+func uninstallService(cfg *config.Config, logger *logrus.Logger) int {
 	logger.Info("开始卸载服务")
+	failed := 0
 
 	// 停止服务
 	cmd := exec.Command("systemctl", "stop", cfg.Service.ServiceName)
 	if err := cmd.Run(); err != nil {
+		failed++
 		logger.Warnf("停止服务失败: %v", err)
 	}
 
 	// 禁用服务
 	cmd = exec.Command("systemctl", "disable", cfg.Service.ServiceName)
 	if err := cmd.Run(); err != nil {
+		failed++
 		logger.Warnf("禁用服务失败: %v", err)
 	}
 
 	// 删除服务文件
 	servicePath := filepath.Join("/etc/systemd/system", cfg.Service.ServiceFile)
 	if err := os.Remove(servicePath); err != nil {
+		failed++
 		logger.Warnf("删除服务文件失败: %v", err)
 	}
 
 	// 重新加载systemd配置
 	cmd = exec.Command("systemctl", "daemon-reload")
 	if err := cmd.Run(); err != nil {
+		failed++
 		logger.Warnf("重新加载systemd配置失败: %v", err)
 	}
 
 	// 删除安装目录
 	if err := os.RemoveAll(cfg.Service.InstallPath); err != nil {
+		failed++
 		logger.Warnf("删除安装目录失败: %v", err)
 	}
 
 	logger.Info("服务卸载完成")
-	return nil
+	return failed
 }
 
 func copyFile(src, dst string) error {
@@ -316,4 +545,36 @@ func copyFile(src, dst string) error {
 	}
 
 	return os.Chmod(dst, sourceInfo.Mode())
-} 
\ No newline at end of file
+}
+
+// versionInfo 版本信息的JSON输出结构
+type versionInfo struct {
+	Version   string `json:"version"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+func runVersion(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	return printVersion(*output)
+}
+
+func printVersion(output string) int {
+	info := versionInfo{
+		Version:   Version,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	return printResult(output, info, func() {
+		fmt.Printf("SSH防护系统\n版本: %s\n构建时间: %s\nGo版本: %s\n操作系统: %s/%s\n",
+			info.Version, info.BuildTime, info.GoVersion, info.OS, info.Arch)
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/logging"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// notifyTester 可发送测试通知的渠道
+type notifyTester interface {
+	SendTest(name string) error
+	Notify(event notification.Event) error
+}
+
+// notifyChannel 已配置的通知渠道
+type notifyChannel struct {
+	name string
+	open func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error)
+}
+
+// notifyChannels 所有支持测试的通知渠道
+var notifyChannels = []notifyChannel{
+	{
+		name: "telegram",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Telegram.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewTelegram(&notification.Config{
+				BotToken:      cfg.Telegram.BotToken,
+				ChatID:        cfg.Telegram.ChatID,
+				ThreadID:      cfg.Telegram.ThreadID,
+				Debug:         cfg.Debug.Enabled,
+				ProxyURL:      cfg.Telegram.ProxyURL,
+				Chats:         cfg.Telegram.Chats,
+				ParseMode:     cfg.Telegram.ParseMode,
+				Notifications: cfg.Notifications,
+			}, logger)
+		},
+	},
+	{
+		name: "webhook",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Webhook.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewWebhook(cfg.Webhook, cfg.Notifications), nil
+		},
+	},
+	{
+		name: "syslog",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Syslog.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			client, err := logging.NewSyslog(cfg.Syslog)
+			if err != nil {
+				return nil, err
+			}
+			return notification.NewSyslog(client, cfg.Syslog.Events, cfg.Notifications), nil
+		},
+	},
+	{
+		name: "dingtalk",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Notifications.DingTalk.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewDingTalk(cfg.Notifications.DingTalk, cfg.Notifications), nil
+		},
+	},
+	{
+		name: "wecom",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Notifications.WeCom.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewWeCom(cfg.Notifications.WeCom, cfg.Notifications), nil
+		},
+	},
+	{
+		name: "pagerduty",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Alerting.PagerDuty.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewPagerDuty(cfg.Alerting.PagerDuty, cfg.Alerting.Events, cfg.Notifications), nil
+		},
+	},
+	{
+		name: "opsgenie",
+		open: func(cfg *config.Config, logger *logrus.Logger) (notifyTester, error) {
+			if !cfg.Alerting.Opsgenie.Enabled {
+				return nil, notification.ErrDisabled
+			}
+			return notification.NewOpsgenie(cfg.Alerting.Opsgenie, cfg.Alerting.Events, cfg.Notifications), nil
+		},
+	},
+}
+
+// 事件名称的简写
+var notifyEventAliases = map[string]string{
+	"success":   notification.EventLoginSuccess,
+	"failed":    notification.EventLoginFailed,
+	"banned":    notification.EventIPBanned,
+	"spray":     notification.EventPasswordSpray,
+	"subnet":    notification.EventSubnetAttack,
+	"root":      notification.EventRootLogin,
+	"rule":      notification.EventRuleMatched,
+	"unbanned":  notification.EventIPUnbanned,
+	"location":  notification.EventNewLocation,
+	"logout":    notification.EventLogout,
+	"adaptive":  notification.EventAdaptiveReport,
+	"digest":    notification.EventUnbanDigest,
+	"summary":   notification.EventSummary,
+	"lag":       notification.EventLogLag,
+	"heartbeat": notification.EventHeartbeat,
+	"startup":   notification.EventStartup,
+	"audit":     notification.EventAuditReport,
+	"allow":     notification.EventAllowExpiring,
+	"lock":      notification.EventAccountLock,
+	"batch":     notification.EventBatch,
+}
+
+// notifyTestResult 单个渠道单个事件的测试结果
+type notifyTestResult struct {
+	Channel string `json:"channel"`         // 通知渠道
+	Event   string `json:"event"`           // 事件类型
+	Status  string `json:"status"`          // ok、failed 或 skipped
+	Error   string `json:"error,omitempty"` // 失败或跳过的原因
+}
+
+// runNotifyTest 处理notify-test子命令，用示例数据测试通知渠道和模板
+// 参数:
+//   - args: notify-test之后的命令行参数
+// 返回:
+//   - int: 进程退出码，部分失败时返回exitPartialSuccess
+func runNotifyTest(args []string) int {
+	fs := flag.NewFlagSet("notify-test", flag.ContinueOnError)
+	channel := fs.String("channel", "all", "要测试的通知渠道，all表示全部")
+	eventName := fs.String("event", "all", "要测试的事件: all|success|failed|banned|spray|subnet|root|rule|unbanned|location|logout|adaptive|digest|summary|lag|heartbeat|startup|audit|allow|lock|batch")
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	events := notification.TestEvents
+	if *eventName != "all" {
+		name := *eventName
+		if alias, ok := notifyEventAliases[name]; ok {
+			name = alias
+		}
+		events = []string{name}
+	}
+
+	var channels []notifyChannel
+	for _, c := range notifyChannels {
+		if *channel == "all" || *channel == c.name {
+			channels = append(channels, c)
+		}
+	}
+	if len(channels) == 0 {
+		fmt.Printf("未知的通知渠道: %s\n", *channel)
+		return exitUsage
+	}
+
+	cfg, err := config.LoadConfig(defaultConfigPath)
+	if err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		return exitConfigError
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.WarnLevel)
+
+	var results []notifyTestResult
+	for _, c := range channels {
+		tester, err := c.open(cfg, logger)
+		for _, name := range events {
+			result := notifyTestResult{Channel: c.name, Event: name, Status: "ok"}
+			if err == nil {
+				err := tester.SendTest(name)
+				switch {
+				case err == notification.ErrDisabled:
+					result.Status, result.Error = "skipped", err.Error()
+				case err != nil:
+					result.Status, result.Error = "failed", err.Error()
+				}
+			} else if err == notification.ErrDisabled {
+				result.Status, result.Error = "skipped", "该渠道未启用"
+			} else {
+				result.Status, result.Error = "failed", err.Error()
+			}
+			results = append(results, result)
+		}
+	}
+
+	code := printResult(*output, results, func() {
+		for _, r := range results {
+			mark := "✓"
+			switch r.Status {
+			case "failed":
+				mark = "✗"
+			case "skipped":
+				mark = "-"
+			}
+			line := fmt.Sprintf("%s %-10s %-14s %s", mark, r.Channel, r.Event, r.Status)
+			if r.Error != "" {
+				line += ": " + r.Error
+			}
+			fmt.Println(line)
+		}
+	})
+	if code != exitOK {
+		return code
+	}
+
+	var ok, failed int
+	for _, r := range results {
+		switch r.Status {
+		case "ok":
+			ok++
+		case "failed":
+			failed++
+		}
+	}
+	switch {
+	case failed == 0:
+		return exitOK
+	case ok > 0:
+		return exitPartialSuccess
+	default:
+		return exitFailure
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"path/filepath"
+	"runtime"
+	rpprof "runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// cpuProfileDuration 定期写入的CPU profile的采样时长
+const cpuProfileDuration = 30 * time.Second
+
+// memoryProfiles 需要启用profile_memory才提供的内存类profile
+var memoryProfiles = map[string]bool{"heap": true, "allocs": true}
+
+// startProfiling 按调试配置在本机回环地址上提供net/http/pprof接口，并定期将profile写入文件
+// 未启用profile_cpu和profile_memory时不做任何事
+// 参数:
+//   - cfg: 调试配置
+//   - logger: 日志记录器
+func startProfiling(cfg config.DebugConfig, logger *logrus.Logger) {
+	if !cfg.ProfileCPU && !cfg.ProfileMemory {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		if name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/"); memoryProfiles[name] && !cfg.ProfileMemory {
+			http.Error(w, "未启用profile_memory", http.StatusNotFound)
+			return
+		}
+		pprof.Index(w, r)
+	})
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	if cfg.ProfileCPU {
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	go func() {
+		logger.WithField("listen", cfg.ProfileListen).Info("pprof接口已启动")
+		if err := http.ListenAndServe(cfg.ProfileListen, mux); err != nil {
+			logger.WithError(err).Error("pprof接口启动失败")
+		}
+	}()
+
+	if cfg.ProfileInterval > 0 {
+		if err := os.MkdirAll(cfg.ProfileDir, 0700); err != nil {
+			logger.WithError(err).Error("创建profile目录失败")
+			return
+		}
+		go writeProfilesPeriodically(cfg, time.Duration(cfg.ProfileInterval)*time.Minute, logger)
+	}
+}
+
+// writeProfilesPeriodically 每隔interval写入一次已启用的profile，并删除超出保留数量的旧文件
+// 参数:
+//   - cfg: 调试配置
+//   - interval: 写入间隔
+//   - logger: 日志记录器，用于记录写入失败
+func writeProfilesPeriodically(cfg config.DebugConfig, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		stamp := time.Now().Format("20060102-150405")
+		if cfg.ProfileMemory {
+			if err := writeHeapProfile(filepath.Join(cfg.ProfileDir, "heap-"+stamp+".pprof")); err != nil {
+				logger.WithError(err).Error("写入堆内存profile失败")
+			}
+			pruneProfiles(cfg.ProfileDir, "heap-", cfg.ProfileKeep, logger)
+		}
+		if cfg.ProfileCPU {
+			if err := writeCPUProfile(filepath.Join(cfg.ProfileDir, "cpu-"+stamp+".pprof")); err != nil {
+				logger.WithError(err).Error("写入CPU profile失败")
+			}
+			pruneProfiles(cfg.ProfileDir, "cpu-", cfg.ProfileKeep, logger)
+		}
+	}
+}
+
+// writeHeapProfile 写入一次堆内存profile，写入前先执行GC，使结果反映当前仍在使用的内存
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	runtime.GC()
+	return rpprof.Lookup("heap").WriteTo(file, 0)
+}
+
+// writeCPUProfile 采样cpuProfileDuration的CPU profile，通过接口采样期间会失败
+func writeCPUProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := rpprof.StartCPUProfile(file); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("开始CPU采样失败: %v", err)
+	}
+	time.Sleep(cpuProfileDuration)
+	rpprof.StopCPUProfile()
+	return nil
+}
+
+// pruneProfiles 删除目录中指定前缀的profile文件，只保留最近的keep个
+// 文件名中的时间戳按字典序即为时间顺序
+func pruneProfiles(dir, prefix string, keep int, logger *logrus.Logger) {
+	matches, err := filepath.Glob(filepath.Join(dir, prefix+"*.pprof"))
+	if err != nil || len(matches) <= keep {
+		return
+	}
+	sort.Strings(matches)
+	for _, path := range matches[:len(matches)-keep] {
+		if err := os.Remove(path); err != nil {
+			logger.WithError(err).WithField("file", path).Warn("删除旧的profile失败")
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// runStats 处理stats子命令，输出最近24小时或最近7天的攻击统计
+// 参数:
+//   - args: stats之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	output := addOutputFlag(fs)
+	by := fs.String("by", "hour", "统计粒度: hour 按小时统计最近24小时，day 按天统计最近7天")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *by != "hour" && *by != "day" {
+		fmt.Printf("统计粒度必须为hour或day: %s\n", *by)
+		return exitUsage
+	}
+
+	history, err := control.NewClient(*socket).StatsHistory(*by)
+	if err != nil {
+		fmt.Printf("查询统计失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+	return printResult(*output, history, func() { printStatsHistory(history) })
+}
+
+// printStatsHistory 以文本形式输出滚动统计
+func printStatsHistory(history control.StatsHistory) {
+	layout := "2006-01-02 15:00"
+	if history.By == "day" {
+		layout = "2006-01-02"
+	}
+	fmt.Printf("%-16s %10s %8s %8s\n", "时间", "登录失败", "IP数", "封禁")
+	for _, b := range history.Buckets {
+		fmt.Printf("%-16s %10d %8d %8d\n", b.Start.Format(layout), b.Failures, b.UniqueIPs, b.Bans)
+	}
+	fmt.Printf("%-16s %10d %8d %8d\n", "合计", history.Failures, history.UniqueIPs, history.Bans)
+
+	printNamedCounts("用户名TOP", history.TopUsers)
+	printNamedCounts("国家TOP", history.TopCountries)
+}
+
+// printNamedCounts 输出排行，没有数据时不输出
+func printNamedCounts(title string, counts []control.NamedCount) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Printf("%s:\n", title)
+	for _, c := range counts {
+		fmt.Printf("  %-24s %d\n", c.Name, c.Count)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// runStatus 处理status子命令，输出守护进程的运行状态
+// 参数:
+//   - args: status之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	output := addOutputFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	status, err := control.NewClient(*socket).Status()
+	if err != nil {
+		fmt.Printf("查询状态失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+	return printResult(*output, status, func() { printStatus(status) })
+}
+
+// printStatus 以文本形式输出运行状态
+func printStatus(status control.Status) {
+	if !status.Started.IsZero() {
+		fmt.Printf("启动时间: %s（已运行 %s）\n", status.Started.Format("2006-01-02 15:04:05"), time.Since(status.Started).Truncate(time.Second))
+	}
+	fmt.Printf("当前封禁: %d  永久封禁: %d  限速: %d  最近1小时登录失败: %d\n", status.Banned, status.Permanent, status.Limited, status.FailuresLastHour)
+	fw := status.Firewall
+	switch {
+	case fw.Error != "":
+		fmt.Printf("防火墙: %s 状态未知，%s\n", fw.Backend, fw.Error)
+	case fw.Active:
+		fmt.Printf("防火墙: %s 已启用\n", fw.Backend)
+	default:
+		fmt.Printf("防火墙: %s 未启用，封禁不会生效\n", fw.Backend)
+	}
+	fmt.Println("日志读取:")
+	for _, jail := range status.Jails {
+		state := "读取中"
+		switch {
+		case !jail.Enabled:
+			state = "已停用"
+		case !jail.Running:
+			state = "已中断"
+		}
+		last := "-"
+		if !jail.LastRead.IsZero() {
+			last = jail.LastRead.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %-12s %s  已读取: %d  最近一行: %s  待处理: %d\n", jail.Name, state, jail.Lines, last, jail.Queue)
+		if jail.LastError != "" {
+			fmt.Printf("  %-12s 最近错误: %s\n", "", jail.LastError)
+		}
+	}
+
+	info := status.IPInfo
+	fmt.Println("IP信息接口:")
+	fmt.Printf("  请求: %d  失败: %d  限流: %d  熔断跳过: %d\n", info.Requests, info.Failures, info.Throttled, info.Skipped)
+	capacity := "不限"
+	if info.CacheSize > 0 {
+		capacity = fmt.Sprint(info.CacheSize)
+	}
+	fmt.Printf("  缓存: %d/%s  命中: %d  未命中: %d  淘汰: %d\n", info.Cached, capacity, info.CacheHits, info.CacheMisses, info.CacheEvicted)
+
+	codes := make([]int, 0, len(info.StatusCodes))
+	for code := range info.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		name := fmt.Sprintf("HTTP %d", code)
+		if code == 0 {
+			name = "请求未完成"
+		}
+		fmt.Printf("  %-12s %d\n", name, info.StatusCodes[code])
+	}
+
+	if info.QuotaRemaining >= 0 {
+		fmt.Printf("  剩余配额: %d\n", info.QuotaRemaining)
+	}
+	if info.BreakerOpen {
+		fmt.Printf("  熔断中，%s 恢复\n", info.BreakerUntil.Format("2006-01-02 15:04:05"))
+	}
+	if info.LastError != "" {
+		fmt.Printf("  最近错误: %s\n", info.LastError)
+	}
+	for _, p := range info.Providers {
+		state := "正常"
+		if p.BreakerOpen {
+			state = "熔断至 " + p.BreakerUntil.Format("15:04:05")
+		}
+		fmt.Printf("  %-12s %s  请求: %d  失败: %d  限流: %d\n", p.Name, state, p.Requests, p.Failures, p.Throttled)
+	}
+
+	printChannelHealth(status.Channels)
+	printFeeds(status.Feeds)
+	printAggregator(status)
+	printBanLatency(status.BanLatency)
+	if s := status.Sampling; s.Enabled {
+		fmt.Printf("事件抽样: 每%d个保存1个  参与抽样: %d  已保存: %d\n", s.Rate, s.Seen, s.Kept)
+	}
+	fmt.Println("资源占用:")
+	for _, line := range notification.ResourceLines(status.Resources) {
+		fmt.Printf("  %s\n", line)
+	}
+
+	lag := status.LogLag
+	fmt.Println("日志处理延迟:")
+	if lag.Count == 0 {
+		fmt.Println("  尚未处理日志")
+		return
+	}
+	fmt.Printf("  已处理: %d  平均: %.2fs  最大: %.2fs  最近: %.2fs  待处理: %d  告警: %d\n",
+		lag.Count, lag.Sum/float64(lag.Count), lag.Max, lag.Last, lag.Queue, lag.Alerts)
+	lower := 0.0
+	for _, bucket := range lag.Buckets {
+		name := fmt.Sprintf("%gs-%gs", lower, bucket.LE)
+		if bucket.LE == 0 {
+			name = fmt.Sprintf(">%gs", lower)
+		}
+		fmt.Printf("  %-12s %d\n", name, bucket.Count)
+		lower = bucket.LE
+	}
+}
+
+// printFeeds 以文本形式输出订阅黑名单的刷新状态，未配置订阅时不输出
+func printFeeds(feeds []control.FeedStatus) {
+	if len(feeds) == 0 {
+		return
+	}
+	fmt.Println("订阅黑名单:")
+	for _, f := range feeds {
+		updated := "从未"
+		if !f.Updated.IsZero() {
+			updated = f.Updated.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("  %-12s 条目: %d  白名单跳过: %d  无效行: %d  最近更新: %s\n", f.Name, f.Entries, f.Whitelisted, f.Invalid, updated)
+		if !f.Next.IsZero() {
+			fmt.Printf("  %-12s 下次下载: %s\n", "", f.Next.Format("2006-01-02 15:04:05"))
+		}
+		if f.LastError != "" {
+			fmt.Printf("  %-12s 最近错误: %s\n", "", f.LastError)
+		}
+	}
+}
+
+// printAggregator 以文本形式输出集中监控的转发状态或各agent的统计，未启用集中监控时不输出
+func printAggregator(status control.Status) {
+	if f := status.Forwarder; f != nil {
+		lastSent := "从未"
+		if !f.LastSent.IsZero() {
+			lastSent = f.LastSent.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("集中监控: %s\n", f.Server)
+		fmt.Printf("  已转发: %d  待转发: %d  丢弃: %d  最近转发: %s\n", f.Sent, f.Queued, f.Dropped, lastSent)
+		if f.LastError != "" {
+			fmt.Printf("  最近错误: %s\n", f.LastError)
+		}
+	}
+	if len(status.Agents) > 0 {
+		fmt.Println("集中监控agent:")
+		for _, a := range status.Agents {
+			fmt.Printf("  %-16s %s  最近提交: %s  事件: %d  登录失败: %d  登录成功: %d  封禁: %d  丢弃: %d\n",
+				a.Host, a.Addr, a.LastSeen.Format("2006-01-02 15:04:05"), a.Events, a.Failures, a.Successes, a.Bans, a.Dropped)
+		}
+	}
+	if len(status.FleetAttackers) > 0 {
+		fmt.Println("攻击多台服务器的来源（24小时）:")
+		for _, a := range status.FleetAttackers {
+			fmt.Printf("  %-39s 服务器: %d  登录失败: %d  %s\n", a.IP, len(a.Hosts), a.Failures, strings.Join(a.Hosts, ", "))
+		}
+	}
+}
+
+// printBanLatency 以文本形式输出封禁生效耗时
+func printBanLatency(b control.BanLatencyStats) {
+	fmt.Println("封禁生效耗时:")
+	if b.Count == 0 {
+		fmt.Println("  尚未自动封禁")
+		return
+	}
+	fmt.Printf("  总耗时   P50: %.3fs  P99: %.3fs  最大: %.3fs  最近: %.3fs\n", b.Total.P50, b.Total.P99, b.Total.Max, b.Total.Last)
+	fmt.Printf("  防火墙   P50: %.3fs  P99: %.3fs  最大: %.3fs  最近: %.3fs\n", b.Firewall.P50, b.Firewall.P99, b.Firewall.Max, b.Firewall.Last)
+	if b.SLO > 0 {
+		fmt.Printf("  封禁次数: %d  超过目标(%gs): %d\n", b.Count, b.SLO, b.OverSLO)
+	} else {
+		fmt.Printf("  封禁次数: %d\n", b.Count)
+	}
+}
+
+// channelStateNames 渠道健康状态的中文名称
+var channelStateNames = map[string]string{
+	notification.StateHealthy:  "正常",
+	notification.StateFailing:  "失败中",
+	notification.StateDisabled: "已暂停",
+}
+
+// printChannelHealth 以文本形式输出各通知渠道的健康状态
+func printChannelHealth(channels []control.ChannelHealth) {
+	fmt.Println("通知渠道:")
+	if len(channels) == 0 {
+		fmt.Println("  未启用任何通知渠道")
+		return
+	}
+	for _, c := range channels {
+		fmt.Printf("  %-10s %-6s 成功: %d  失败: %d  连续失败: %d  待重试: %d  暂停期间推迟: %d\n",
+			c.Name, channelStateNames[c.State], c.Sent, c.Failed, c.ConsecutiveFailures, c.Pending, c.Skipped)
+		if c.LastError != "" {
+			fmt.Printf("             最近错误: %s\n", c.LastError)
+		}
+		if !c.NextProbe.IsZero() {
+			fmt.Printf("             下次探测: %s\n", c.NextProbe.Format("2006-01-02 15:04:05"))
+		}
+		if u := c.Updates; u != nil {
+			state := "运行中"
+			if !u.Running {
+				state = "未运行"
+			}
+			last := "-"
+			if !u.LastPoll.IsZero() {
+				last = u.LastPoll.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("             命令接收: %s  最近拉取: %s  连续失败: %d  累计失败: %d  异常: %d\n",
+				state, last, u.ConsecutiveFailures, u.Failures, u.Panics)
+			if u.ConsecutiveFailures > 0 && u.LastError != "" {
+				fmt.Printf("             拉取错误: %s\n", u.LastError)
+			}
+		}
+	}
+}
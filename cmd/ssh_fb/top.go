@@ -0,0 +1,296 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// top界面保留的最近事件数量
+const topEventLimit = 200
+
+// top界面的焦点区域
+const (
+	focusBans = iota
+	focusAttackers
+)
+
+// runTop 处理top子命令，启动终端实时监控界面
+// 参数:
+//   - args: top之后的命令行参数
+// 返回:
+//   - int: 进程退出码
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	socket := addSocketFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	client := control.NewClient(*socket)
+	if _, err := client.Bans(); err != nil {
+		fmt.Printf("连接守护进程失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+
+	program := tea.NewProgram(&topModel{client: client}, tea.WithAltScreen())
+	if _, err := program.Run(); err != nil {
+		fmt.Printf("运行监控界面失败: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// topModel 终端监控界面的状态
+type topModel struct {
+	client    *control.Client    // 控制接口客户端
+	bans      []control.BanInfo  // 当前封禁
+	attackers []control.Attacker // 攻击来源排行
+	events    []event.Event      // 最近事件
+	lastSeq   uint64             // 已读取的最大事件序号
+	focus     int                // 当前焦点区域
+	cursor    [2]int             // 各区域的选中行
+	status    string             // 状态栏信息
+	height    int                // 终端高度
+}
+
+type topTickMsg time.Time
+
+type topSnapshotMsg struct {
+	bans      []control.BanInfo
+	attackers []control.Attacker
+	events    []event.Event
+	err       error
+}
+
+type topActionMsg struct {
+	text string
+	err  error
+}
+
+func (m *topModel) Init() tea.Cmd {
+	return tea.Batch(m.fetch(), topTick())
+}
+
+func topTick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return topTickMsg(t) })
+}
+
+// fetch 从守护进程拉取最新状态
+func (m *topModel) fetch() tea.Cmd {
+	client, since := m.client, m.lastSeq
+	return func() tea.Msg {
+		var msg topSnapshotMsg
+		if msg.bans, msg.err = client.Bans(); msg.err != nil {
+			return msg
+		}
+		if msg.attackers, msg.err = client.TopAttackers(20); msg.err != nil {
+			return msg
+		}
+		msg.events, msg.err = client.Events(since)
+		return msg
+	}
+}
+
+// action 对选中的IP执行管理操作
+func (m *topModel) action(name, ip string, fn func(string) error) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(ip); err != nil {
+			return topActionMsg{err: fmt.Errorf("%s %s 失败: %v", name, ip, err)}
+		}
+		return topActionMsg{text: fmt.Sprintf("%s %s 成功", name, ip)}
+	}
+}
+
+func (m *topModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case topTickMsg:
+		return m, tea.Batch(m.fetch(), topTick())
+	case topSnapshotMsg:
+		if msg.err != nil {
+			m.status = fmt.Sprintf("刷新失败: %v", msg.err)
+			return m, nil
+		}
+		m.bans, m.attackers = msg.bans, msg.attackers
+		for _, e := range msg.events {
+			m.events = append(m.events, e)
+			m.lastSeq = e.Seq
+		}
+		if len(m.events) > topEventLimit {
+			m.events = m.events[len(m.events)-topEventLimit:]
+		}
+		m.clampCursor()
+	case topActionMsg:
+		if msg.err != nil {
+			m.status = msg.err.Error()
+		} else {
+			m.status = msg.text
+		}
+		return m, m.fetch()
+	case tea.KeyMsg:
+		return m, m.handleKey(msg.String())
+	}
+	return m, nil
+}
+
+func (m *topModel) handleKey(key string) tea.Cmd {
+	switch key {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % 2
+	case "up", "k":
+		if m.cursor[m.focus] > 0 {
+			m.cursor[m.focus]--
+		}
+	case "down", "j":
+		m.cursor[m.focus]++
+		m.clampCursor()
+	case "b":
+		if ip := m.selectedIP(); ip != "" {
+			return m.action("封禁", ip, m.client.Ban)
+		}
+	case "u":
+		if ip := m.selectedIP(); ip != "" {
+			return m.action("解除封禁", ip, m.client.Unban)
+		}
+	case "p":
+		if ip := m.selectedIP(); ip != "" {
+			return m.action("永久封禁", ip, m.client.MakePermanent)
+		}
+	case "w":
+		if ip := m.selectedIP(); ip != "" {
+			return m.action("加入白名单", ip, m.client.AddWhitelist)
+		}
+	case "r":
+		return m.fetch()
+	}
+	return nil
+}
+
+// selectedIP 返回当前焦点区域选中行的IP
+func (m *topModel) selectedIP() string {
+	i := m.cursor[m.focus]
+	switch m.focus {
+	case focusBans:
+		if i < len(m.bans) {
+			return m.bans[i].IP
+		}
+	case focusAttackers:
+		if i < len(m.attackers) {
+			return m.attackers[i].IP
+		}
+	}
+	return ""
+}
+
+func (m *topModel) clampCursor() {
+	limits := [2]int{len(m.bans), len(m.attackers)}
+	for i, n := range limits {
+		if m.cursor[i] >= n {
+			m.cursor[i] = n - 1
+		}
+		if m.cursor[i] < 0 {
+			m.cursor[i] = 0
+		}
+	}
+}
+
+func (m *topModel) View() string {
+	var b strings.Builder
+	now := time.Now()
+
+	fmt.Fprintf(&b, "SSH防护系统实时监控  %s  封禁: %d  攻击来源: %d\n\n",
+		now.Format("2006-01-02 15:04:05"), len(m.bans), len(m.attackers))
+
+	// 每个区域最多显示的行数，根据终端高度分配
+	rows := 10
+	if m.height > 0 {
+		rows = (m.height - 14) / 3
+		if rows < 3 {
+			rows = 3
+		}
+	}
+
+	b.WriteString(m.sectionTitle("当前封禁", focusBans))
+	for _, i := range visible(len(m.bans), m.cursor[focusBans], rows) {
+		entry := m.bans[i]
+		remaining := "永久"
+		if !entry.Permanent {
+			remaining = formatCountdown(entry.ExpireTime.Sub(now))
+		}
+		if entry.Limited {
+			remaining += "  限速"
+		}
+		b.WriteString(m.row(focusBans, i, fmt.Sprintf("%-40s %s", entry.IP, remaining)))
+	}
+
+	b.WriteString("\n" + m.sectionTitle("攻击来源TOP", focusAttackers))
+	for _, i := range visible(len(m.attackers), m.cursor[focusAttackers], rows) {
+		a := m.attackers[i]
+		state := ""
+		if a.Banned {
+			state = "已封禁"
+		}
+		b.WriteString(m.row(focusAttackers, i, fmt.Sprintf("%-40s %6d次  %s", a.IP, a.Failures, state)))
+	}
+
+	b.WriteString("\n实时事件\n")
+	start := len(m.events) - rows
+	if start < 0 {
+		start = 0
+	}
+	for _, e := range m.events[start:] {
+		fmt.Fprintf(&b, "  %s  %-14s %-40s %s\n", e.Time.Format("15:04:05"), e.Type, e.IP, e.Message)
+	}
+
+	fmt.Fprintf(&b, "\n%s\n", m.status)
+	b.WriteString("[tab]切换区域 [↑↓]选择 [b]封禁 [u]解封 [p]永久封禁 [w]白名单 [r]刷新 [q]退出\n")
+	return b.String()
+}
+
+func (m *topModel) sectionTitle(title string, focus int) string {
+	if m.focus == focus {
+		return "\x1b[1m" + title + " *\x1b[0m\n"
+	}
+	return title + "\n"
+}
+
+func (m *topModel) row(focus, i int, text string) string {
+	if m.focus == focus && m.cursor[focus] == i {
+		return "\x1b[7m> " + text + "\x1b[0m\n"
+	}
+	return "  " + text + "\n"
+}
+
+// visible 返回以选中行为中心、最多rows行的可见行下标
+func visible(n, cursor, rows int) []int {
+	start := cursor - rows/2
+	if start > n-rows {
+		start = n - rows
+	}
+	if start < 0 {
+		start = 0
+	}
+	var idx []int
+	for i := start; i < n && i < start+rows; i++ {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// formatCountdown 将剩余时间格式化为 时:分:秒
+func formatCountdown(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/monitor"
+)
+
+// runVerify 处理verify子命令，交叉检查黑名单文件、存储与防火墙规则
+// 直接读取文件、存储和防火墙，不需要守护进程运行，可以放在cron中定期执行
+// 参数:
+//   - args: verify之后的命令行参数
+// 返回:
+//   - int: 进程退出码，存在未修复的不一致时返回exitFailure，部分修复失败时返回exitPartialSuccess
+func runVerify(args []string) int {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	output := addOutputFlag(fs)
+	configPath := fs.String("config", defaultConfigPath, "配置文件路径")
+	repair := fs.Bool("repair", false, "按黑名单修复存储记录并补上缺失的防火墙规则")
+	prune := fs.Bool("prune", false, "修复时同时删除黑名单中没有的ufw deny规则，需要与--repair一起使用")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *prune && !*repair {
+		fmt.Println("--prune需要与--repair一起使用")
+		return exitUsage
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("加载配置失败: %v\n", err)
+		return exitConfigError
+	}
+	if *repair {
+		if err := requireRoot(); err != nil {
+			fmt.Println(err)
+			return exitPermissionDenied
+		}
+	}
+
+	report, err := monitor.VerifyBans(cfg, monitor.VerifyOptions{Repair: *repair, Prune: *prune})
+	if err != nil {
+		fmt.Printf("检查失败: %v\n", err)
+		return exitCodeFor(err)
+	}
+	if code := printResult(*output, report, func() { printVerifyReport(report, *repair) }); code != exitOK {
+		return code
+	}
+
+	switch unrepaired := report.Unrepaired(); {
+	case unrepaired == 0:
+		return exitOK
+	case *repair && unrepaired < len(report.Issues):
+		return exitPartialSuccess
+	default:
+		return exitFailure
+	}
+}
+
+// printVerifyReport 以文本形式输出检查报告
+func printVerifyReport(report monitor.VerifyReport, repair bool) {
+	fmt.Printf("黑名单: %d  存储: %d  ufw deny规则: %d\n", report.Blacklist, report.Store, report.Firewall)
+	if len(report.Issues) == 0 {
+		fmt.Println("封禁状态一致")
+		return
+	}
+
+	fmt.Printf("发现 %d 处不一致:\n", len(report.Issues))
+	for _, issue := range report.Issues {
+		state := ""
+		switch {
+		case issue.Error != "":
+			state = "  修复失败: " + issue.Error
+		case issue.Repaired:
+			state = "  已修复"
+		}
+		fmt.Printf("  %-16s %-40s %s%s\n", issue.Kind, issue.IP, issue.Detail, state)
+	}
+	if !repair {
+		fmt.Println("使用 --repair 按黑名单修复，--repair --prune 同时删除黑名单中没有的ufw规则")
+	}
+}
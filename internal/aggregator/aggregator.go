@@ -0,0 +1,169 @@
+// Package aggregator 提供集中监控的传输层
+// 各服务器上的agent通过HTTPS将事件批量提交到server，server校验Token后交给监控器处理
+package aggregator
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// 请求超时时间与单次请求的最大长度
+const (
+	requestTimeout = 15 * time.Second
+	maxBatchBytes  = 8 << 20
+)
+
+// 提交事件的接口路径
+const eventsPath = "/v1/agent/events"
+
+// Batch agent一次提交的事件
+type Batch struct {
+	Host    string        `json:"host"`    // agent的名称
+	Dropped int           `json:"dropped"` // 上次提交以来因队列已满丢弃的事件数
+	Events  []event.Event `json:"events"`  // 按发生顺序排列的事件
+}
+
+// Client agent使用的客户端
+type Client struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+// NewClient 创建agent使用的客户端
+// 参数:
+//   - cfg: 集中监控配置，使用url、token和ca_file
+// 返回:
+//   - *Client: 初始化后的客户端
+//   - error: 无法读取CA证书文件时的错误信息
+func NewClient(cfg config.AggregatorConfig) (*Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		data, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA证书文件中没有有效的证书: %s", cfg.CAFile)
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+	return &Client{
+		url:   strings.TrimSuffix(cfg.URL, "/") + eventsPath,
+		token: cfg.Token,
+		http:  &http.Client{Timeout: requestTimeout, Transport: transport},
+	}, nil
+}
+
+// Send 提交一批事件
+// 参数:
+//   - batch: 要提交的事件
+// 返回:
+//   - error: 请求失败或server拒绝时的错误信息，调用方应保留事件稍后重试
+func (c *Client) Send(batch Batch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ssh_fb")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("提交事件到集中监控失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("集中监控返回HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Server 接收agent事件的HTTPS服务
+type Server struct {
+	token      string
+	certFile   string
+	keyFile    string
+	handle     func(batch Batch, addr string)
+	httpServer *http.Server
+}
+
+// NewServer 创建接收agent事件的服务
+// 参数:
+//   - cfg: 集中监控配置，使用listen、cert_file、key_file和token
+//   - handle: 处理一批事件，addr为agent的地址；在请求的协程中同步调用
+// 返回:
+//   - *Server: 初始化后的服务
+func NewServer(cfg config.AggregatorConfig, handle func(batch Batch, addr string)) *Server {
+	s := &Server{token: cfg.Token, certFile: cfg.CertFile, keyFile: cfg.KeyFile, handle: handle}
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventsPath, s.handleEvents)
+	s.httpServer = &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           mux,
+		ReadHeaderTimeout: requestTimeout,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	return s
+}
+
+// Start 开始监听，阻塞直到服务关闭
+// 返回:
+//   - error: 监听失败或证书无效时的错误信息
+func (s *Server) Start() error {
+	if err := s.httpServer.ListenAndServeTLS(s.certFile, s.keyFile); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("集中监控服务启动失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭服务
+func (s *Server) Close() error {
+	return s.httpServer.Close()
+}
+
+// handleEvents 处理 POST /v1/agent/events
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持POST", http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		http.Error(w, "认证失败", http.StatusUnauthorized)
+		return
+	}
+	var batch Batch
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&batch); err != nil {
+		http.Error(w, "请求格式错误", http.StatusBadRequest)
+		return
+	}
+	if batch.Host == "" {
+		http.Error(w, "缺少host", http.StatusBadRequest)
+		return
+	}
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	s.handle(batch, addr)
+	w.WriteHeader(http.StatusNoContent)
+}
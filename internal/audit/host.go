@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 已知不安全的加密算法，sshd -T 输出的默认算法列表中不包含这些算法
+var weakAlgorithms = map[string][]string{
+	"ciphers": {"3des-cbc", "aes128-cbc", "aes192-cbc", "aes256-cbc", "blowfish-cbc", "cast128-cbc",
+		"arcfour", "arcfour128", "arcfour256", "rijndael-cbc@lysator.liu.se"},
+	"macs": {"hmac-md5", "hmac-md5-96", "hmac-md5-etm@openssh.com", "hmac-md5-96-etm@openssh.com",
+		"hmac-sha1-96", "hmac-sha1-96-etm@openssh.com", "umac-32@openssh.com"},
+	"kexalgorithms": {"diffie-hellman-group1-sha1", "diffie-hellman-group14-sha1", "diffie-hellman-group-exchange-sha1"},
+}
+
+// 账户锁定使用的PAM模块
+var lockoutModules = []string{"pam_faillock.so", "pam_tally2.so", "pam_tally.so"}
+
+// HostOptions 主机检查读取的路径，默认值见DefaultHostOptions
+type HostOptions struct {
+	SSHDConfig string // sshd_config路径
+	SSHDir     string // 主机密钥所在目录
+	PAMDir     string // PAM配置目录
+	Passwd     string // 用户列表文件，用于查找各用户的authorized_keys
+}
+
+// DefaultHostOptions 返回常见Linux发行版上的默认路径
+// 参数:
+//   - sshdConfig: sshd_config路径
+// 返回:
+//   - HostOptions: 主机检查读取的路径
+func DefaultHostOptions(sshdConfig string) HostOptions {
+	return HostOptions{SSHDConfig: sshdConfig, SSHDir: sshdConfigDir, PAMDir: "/etc/pam.d", Passwd: "/etc/passwd"}
+}
+
+// Check 一项主机检查的结果
+type Check struct {
+	Name   string `json:"name"`   // 检查项名称
+	Weight int    `json:"weight"` // 在总分中的权重
+	Passed bool   `json:"passed"` // 是否通过
+	Detail string `json:"detail"` // 检查结果说明，未通过时包含修改建议
+}
+
+// HostReport 主机检查报告
+type HostReport struct {
+	Score  int     `json:"score"`  // 得分，通过的检查项权重之和占总权重的百分比
+	Checks []Check `json:"checks"` // 各项检查结果
+}
+
+// Failed 返回未通过的检查项
+func (r HostReport) Failed() []Check {
+	var failed []Check
+	for _, c := range r.Checks {
+		if !c.Passed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// AuditHost 检查主机上与SSH相关的安全配置并计算得分
+// 包括密码登录、root登录、弱加密算法、账户锁定以及密钥文件权限
+// 参数:
+//   - opts: 检查读取的路径
+// 返回:
+//   - HostReport: 检查报告
+//   - error: 无法读取sshd配置时的错误信息
+func AuditHost(opts HostOptions) (HostReport, error) {
+	options, err := LoadSSHDConfig(opts.SSHDConfig)
+	if err != nil {
+		return HostReport{}, err
+	}
+	get := func(key string) string {
+		if v, ok := options[key]; ok {
+			return strings.ToLower(v)
+		}
+		return sshdDefaults[key]
+	}
+	passwordAuth := get("passwordauthentication") != "no"
+
+	checks := []Check{
+		checkPasswordAuth(passwordAuth),
+		checkRootLogin(get("permitrootlogin")),
+		checkWeakAlgorithms(options),
+		checkLockout(opts.PAMDir, passwordAuth),
+		checkKeyFiles(opts),
+	}
+
+	report := HostReport{Checks: checks}
+	total, passed := 0, 0
+	for _, c := range checks {
+		total += c.Weight
+		if c.Passed {
+			passed += c.Weight
+		}
+	}
+	if total > 0 {
+		report.Score = passed * 100 / total
+	}
+	return report, nil
+}
+
+func checkPasswordAuth(enabled bool) Check {
+	c := Check{Name: "密码登录", Weight: 25, Passed: !enabled, Detail: "已禁用密码登录"}
+	if enabled {
+		c.Detail = "PasswordAuthentication未关闭，建议改用公钥登录并设置 PasswordAuthentication no"
+	}
+	return c
+}
+
+func checkRootLogin(value string) Check {
+	c := Check{Name: "root登录", Weight: 20, Passed: value != "yes", Detail: "PermitRootLogin " + value}
+	if value == "yes" {
+		c.Detail = "root可以使用密码登录，建议设置 PermitRootLogin no 或 prohibit-password"
+	}
+	return c
+}
+
+// checkWeakAlgorithms 检查Ciphers、MACs和KexAlgorithms中是否启用了弱算法
+// 未配置时使用OpenSSH的默认算法，视为通过
+func checkWeakAlgorithms(options map[string]string) Check {
+	var found []string
+	for _, key := range []string{"ciphers", "macs", "kexalgorithms"} {
+		value, ok := options[key]
+		if !ok {
+			continue
+		}
+		// 以+开头表示在默认算法上追加，以-或^开头的写法不会引入新的算法
+		for _, alg := range strings.Split(strings.TrimLeft(strings.ToLower(value), "+"), ",") {
+			for _, weak := range weakAlgorithms[key] {
+				if alg == weak {
+					found = append(found, alg)
+				}
+			}
+		}
+	}
+	c := Check{Name: "加密算法", Weight: 15, Passed: len(found) == 0, Detail: "未启用已知的弱算法"}
+	if len(found) > 0 {
+		c.Detail = fmt.Sprintf("启用了弱算法: %s，建议从Ciphers、MACs、KexAlgorithms中移除", strings.Join(found, ", "))
+	}
+	return c
+}
+
+// checkLockout 检查PAM中是否配置了按账户的失败锁定
+// ssh_fb按IP封禁，分布式攻击同一账户时需要pam_faillock按账户锁定；已禁用密码登录时视为通过
+func checkLockout(pamDir string, passwordAuth bool) Check {
+	c := Check{Name: "账户锁定", Weight: 15, Passed: true}
+	if !passwordAuth {
+		c.Detail = "已禁用密码登录，不需要按账户锁定"
+		return c
+	}
+	for _, name := range []string{"sshd", "common-auth", "system-auth", "password-auth"} {
+		if module := findPAMModule(filepath.Join(pamDir, name)); module != "" {
+			c.Detail = fmt.Sprintf("%s中已配置%s", name, module)
+			return c
+		}
+	}
+	c.Passed = false
+	c.Detail = "PAM中未配置pam_faillock，来自大量IP的攻击可以持续尝试同一账户，建议在sshd的auth配置中启用pam_faillock"
+	return c
+}
+
+// findPAMModule 返回PAM配置中启用的账户锁定模块，没有时返回空字符串
+func findPAMModule(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, module := range lockoutModules {
+			if strings.Contains(line, module) {
+				return module
+			}
+		}
+	}
+	return ""
+}
+
+// checkKeyFiles 检查主机私钥和用户authorized_keys的权限
+// 主机私钥只能由属主读写；authorized_keys及其所在的.ssh目录、sshd_config不能被其他用户写入
+func checkKeyFiles(opts HostOptions) Check {
+	var problems []string
+	hostKeys, _ := filepath.Glob(filepath.Join(opts.SSHDir, "ssh_host_*_key"))
+	for _, path := range hostKeys {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0077 != 0 {
+			problems = append(problems, fmt.Sprintf("%s 权限为%#o，应为0600", path, info.Mode().Perm()))
+		}
+	}
+	paths := []string{opts.SSHDConfig}
+	for _, home := range userHomes(opts.Passwd) {
+		paths = append(paths, filepath.Join(home, ".ssh"), filepath.Join(home, ".ssh", "authorized_keys"))
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.Mode().Perm()&0022 != 0 {
+			problems = append(problems, fmt.Sprintf("%s 权限为%#o，其他用户可以写入", path, info.Mode().Perm()))
+		}
+	}
+	c := Check{Name: "密钥文件权限", Weight: 25, Passed: len(problems) == 0, Detail: "主机私钥和authorized_keys的权限正常"}
+	if len(problems) > 0 {
+		c.Detail = strings.Join(problems, "；")
+	}
+	return c
+}
+
+// userHomes 返回可以登录的用户的主目录，登录shell为nologin或false的用户被跳过
+func userHomes(passwd string) []string {
+	file, err := os.Open(passwd)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var homes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) < 7 || fields[5] == "" {
+			continue
+		}
+		shell := filepath.Base(fields[6])
+		if shell == "nologin" || shell == "false" {
+			continue
+		}
+		homes = append(homes, fields[5])
+	}
+	return homes
+}
@@ -0,0 +1,210 @@
+// Package audit 检查主机上与SSH防护相关的配置并给出加固建议
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// sshd未配置时各项的默认值，与OpenSSH的默认值一致
+var sshdDefaults = map[string]string{
+	"passwordauthentication": "yes",
+	"permitrootlogin":        "prohibit-password",
+	"maxauthtries":           "6",
+	"logingracetime":         "120",
+	"maxstartups":            "10:30:100",
+	"loglevel":               "info",
+}
+
+// sshd_config中Include使用相对路径时的基准目录
+const sshdConfigDir = "/etc/ssh"
+
+// Finding 一条检查结果
+type Finding struct {
+	Option    string `json:"option"`    // 配置项名称
+	Current   string `json:"current"`   // 当前生效的值
+	Suggested string `json:"suggested"` // 建议的值
+	Reason    string `json:"reason"`    // 建议的原因
+}
+
+// String 返回单行的可读描述，用于通知和日志
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s → %s：%s", f.Option, f.Current, f.Suggested, f.Reason)
+}
+
+// SSHDContext 生成建议时参考的防护配置与攻击情况
+type SSHDContext struct {
+	MaxFailedAttempts int  // ssh_fb的封禁阈值
+	Failures          int  // 统计周期内的登录失败次数，未知时为0
+	ClientVersion     bool // 即时封禁条件中使用了客户端版本，需要sshd记录DEBUG日志
+}
+
+// NewSSHDContext 根据防护配置创建检查上下文
+// 参数:
+//   - cfg: SSH防护配置
+//   - failures: 统计周期内的登录失败次数，未知时为0
+// 返回:
+//   - SSHDContext: 检查上下文
+func NewSSHDContext(cfg config.SSHProtectionConfig, failures int) SSHDContext {
+	ctx := SSHDContext{MaxFailedAttempts: cfg.MaxFailedAttempts, Failures: failures}
+	for _, rule := range cfg.InstantBan {
+		if rule.ClientVersion != "" {
+			ctx.ClientVersion = true
+		}
+	}
+	return ctx
+}
+
+// LoadSSHDConfig 读取sshd当前生效的配置
+// 优先使用 sshd -T 输出的完整生效配置，没有权限或找不到sshd时直接解析配置文件
+// 参数:
+//   - path: sshd_config路径
+// 返回:
+//   - map[string]string: 配置项到值的映射，键为小写的配置项名称
+//   - error: 两种方式都失败时的错误信息
+func LoadSSHDConfig(path string) (map[string]string, error) {
+	if out, err := exec.Command("sshd", "-T", "-f", path).Output(); err == nil {
+		options := make(map[string]string)
+		parseSSHDConfig(bytes.NewReader(out), options)
+		return options, nil
+	}
+	options := make(map[string]string)
+	if err := parseSSHDFile(path, options, 0); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// parseSSHDFile 解析配置文件，处理Include指令，嵌套过深时停止展开
+func parseSSHDFile(path string, options map[string]string, depth int) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("读取sshd配置失败: %v", err)
+	}
+	defer file.Close()
+
+	for _, include := range parseSSHDConfig(file, options) {
+		if depth >= 8 {
+			break
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(sshdConfigDir, include)
+		}
+		matches, _ := filepath.Glob(include)
+		for _, match := range matches {
+			if err := parseSSHDFile(match, options, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSSHDConfig 按sshd的规则解析配置：关键字不区分大小写，同一项以第一次出现的值为准，
+// Match块只对部分连接生效，遇到第一个Match后停止解析
+// 返回:
+//   - []string: 按出现顺序排列的Include路径，由调用方在本文件之后展开，
+//     与sshd在原位置展开相比，Include中的配置优先级略低
+func parseSSHDConfig(r io.Reader, options map[string]string) []string {
+	var includes []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(strings.Join(strings.Fields(strings.Replace(line, "=", " ", 1)), " "), " ")
+		key = strings.ToLower(key)
+		switch key {
+		case "match":
+			return includes
+		case "include":
+			includes = append(includes, strings.Fields(value)...)
+			continue
+		}
+		if _, ok := options[key]; !ok {
+			options[key] = strings.Trim(value, `"`)
+		}
+	}
+	return includes
+}
+
+// CheckSSHD 检查sshd配置，返回加固建议
+// 参数:
+//   - options: LoadSSHDConfig读取的配置，未配置的项使用OpenSSH的默认值
+//   - ctx: 防护配置与攻击情况
+// 返回:
+//   - []Finding: 建议修改的配置项，没有时为空
+func CheckSSHD(options map[string]string, ctx SSHDContext) []Finding {
+	get := func(key string) string {
+		if v, ok := options[key]; ok {
+			return strings.ToLower(v)
+		}
+		return sshdDefaults[key]
+	}
+	var findings []Finding
+
+	if get("passwordauthentication") == "yes" {
+		reason := "密码登录是暴力破解的唯一目标，改用公钥登录后攻击无法成功"
+		if ctx.Failures > 0 {
+			reason += fmt.Sprintf("，统计周期内有%d次登录失败", ctx.Failures)
+		}
+		findings = append(findings, Finding{"PasswordAuthentication", "yes", "no", reason})
+	}
+	if get("permitrootlogin") == "yes" {
+		findings = append(findings, Finding{"PermitRootLogin", "yes", "prohibit-password",
+			"root是最常被尝试的用户名，至少禁止root使用密码登录"})
+	}
+	if tries, err := strconv.Atoi(get("maxauthtries")); err == nil && tries > 3 {
+		findings = append(findings, Finding{"MaxAuthTries", strconv.Itoa(tries), "3",
+			fmt.Sprintf("每个连接可尝试%d次密码，ssh_fb在失败%d次后才封禁；降低后扫描程序需要建立更多连接，更早被连接洪泛检测发现", tries, ctx.MaxFailedAttempts)})
+	}
+	if grace, ok := parseSSHDTime(get("logingracetime")); ok && (grace == 0 || grace > time.Minute) {
+		current := get("logingracetime")
+		findings = append(findings, Finding{"LoginGraceTime", current, "30",
+			"未完成认证的连接会占用MaxStartups的名额，扫描程序建立连接后拖延认证可以让正常用户无法登录"})
+	}
+	if full := maxStartupsFull(get("maxstartups")); full > 60 {
+		findings = append(findings, Finding{"MaxStartups", get("maxstartups"), "10:30:60",
+			"限制同时处于认证阶段的连接数，超过10个后按比例随机拒绝新连接，大规模扫描时保护sshd自身"})
+	}
+	if level := get("loglevel"); level == "quiet" || level == "fatal" || level == "error" || level == "info" {
+		suggested, reason := "VERBOSE", "记录Connection from日志，ssh_fb可以按连接补全客户端IP，并记录公钥登录使用的密钥指纹"
+		if ctx.ClientVersion {
+			suggested, reason = "DEBUG", "即时封禁条件使用了client_version，需要DEBUG日志才能看到客户端版本"
+		}
+		findings = append(findings, Finding{"LogLevel", strings.ToUpper(level), suggested, reason})
+	} else if level == "verbose" && ctx.ClientVersion {
+		findings = append(findings, Finding{"LogLevel", "VERBOSE", "DEBUG", "即时封禁条件使用了client_version，需要DEBUG日志才能看到客户端版本"})
+	}
+	return findings
+}
+
+// parseSSHDTime 解析sshd的时间格式，如 120、2m、1m30s，不带单位时为秒
+func parseSSHDTime(s string) (time.Duration, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return time.Duration(n) * time.Second, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// maxStartupsFull 返回MaxStartups中拒绝全部新连接的数量，格式为 start:rate:full 或单个数字
+func maxStartupsFull(s string) int {
+	parts := strings.Split(s, ":")
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
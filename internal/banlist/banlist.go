@@ -0,0 +1,339 @@
+// Package banlist 提供封禁列表的导入导出格式
+// 用于与fail2ban、ipset、nftables等工具交换封禁列表，或在多台服务器之间复制
+package banlist
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持的格式
+const (
+	FormatTxt      = "txt"      // 每行一个IP，# 之后为注释
+	FormatCSV      = "csv"      // ip,type,expires_at,reason，第一行为表头
+	FormatJSON     = "json"     // Entry数组
+	FormatIPSet    = "ipset"    // ipset restore可以读取的命令
+	FormatNft      = "nft"      // nft -f可以读取的集合定义
+	FormatFail2ban = "fail2ban" // 只能导入：fail2ban的sqlite数据库或fail2ban-client status的输出
+)
+
+// ipset与nftables导出时使用的集合名称
+const (
+	ipsetName = "ssh_fb_blacklist"
+	nftTable  = "ssh_fb"
+)
+
+// Entry 一条封禁
+type Entry struct {
+	IP        string    `json:"ip"`                   // IP地址或CIDR网段
+	Permanent bool      `json:"permanent,omitempty"`  // 是否永久封禁
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 临时封禁的解封时间，为零值时按导入方配置的封禁时长
+	Reason    string    `json:"reason,omitempty"`     // 封禁原因
+}
+
+// csvHeader CSV格式的表头
+var csvHeader = []string{"ip", "type", "expires_at", "reason"}
+
+// Write 按格式写出封禁列表
+// txt、ipset和nft格式不保存封禁原因，ipset和nft中临时封禁写为剩余秒数的timeout
+// 参数:
+//   - w: 输出
+//   - format: 格式，fail2ban只能导入
+//   - entries: 封禁列表
+// 返回:
+//   - error: 格式不支持或写入失败时的错误信息
+func Write(w io.Writer, format string, entries []Entry) error {
+	switch format {
+	case FormatTxt:
+		return writeTxt(w, entries)
+	case FormatCSV:
+		return writeCSV(w, entries)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatIPSet:
+		return writeIPSet(w, entries, time.Now())
+	case FormatNft:
+		return writeNft(w, entries, time.Now())
+	}
+	return fmt.Errorf("不支持导出的格式: %s", format)
+}
+
+// Read 按格式读取封禁列表
+// 参数:
+//   - r: 输入
+//   - format: 格式
+// 返回:
+//   - []Entry: 封禁列表，同一IP只保留最后一条
+//   - error: 格式不支持或内容无法解析时的错误信息
+func Read(r io.Reader, format string) ([]Entry, error) {
+	var entries []Entry
+	var err error
+	switch format {
+	case FormatTxt:
+		entries, err = readTxt(r)
+	case FormatCSV:
+		entries, err = readCSV(r)
+	case FormatJSON:
+		err = json.NewDecoder(r).Decode(&entries)
+	case FormatIPSet:
+		entries, err = readIPSet(r, time.Now())
+	case FormatNft:
+		entries, err = readNft(r, time.Now())
+	case FormatFail2ban:
+		entries, err = readFail2ban(r)
+	default:
+		return nil, fmt.Errorf("不支持导入的格式: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析%s格式失败: %v", format, err)
+	}
+	return dedup(entries), nil
+}
+
+// dedup 去掉重复的IP，保留最后一条
+func dedup(entries []Entry) []Entry {
+	index := make(map[string]int, len(entries))
+	result := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if i, ok := index[entry.IP]; ok {
+			result[i] = entry
+			continue
+		}
+		index[entry.IP] = len(result)
+		result = append(result, entry)
+	}
+	return result
+}
+
+// timeout 返回临时封禁的剩余秒数，永久封禁或没有解封时间时为0
+func timeout(entry Entry, now time.Time) int64 {
+	if entry.Permanent || entry.ExpiresAt.IsZero() {
+		return 0
+	}
+	return max(int64(entry.ExpiresAt.Sub(now)/time.Second), 1)
+}
+
+func writeTxt(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		fmt.Fprintln(bw, entry.IP)
+	}
+	return bw.Flush()
+}
+
+func readTxt(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if fields := strings.Fields(line); len(fields) > 0 {
+			entries = append(entries, Entry{IP: fields[0]})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+func writeCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, entry := range entries {
+		banType, expires := "temporary", ""
+		if entry.Permanent {
+			banType = "permanent"
+		} else if !entry.ExpiresAt.IsZero() {
+			expires = entry.ExpiresAt.Format(time.RFC3339)
+		}
+		cw.Write([]string{entry.IP, banType, expires, entry.Reason})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV 读取CSV，按表头确定各列，没有表头时依次为ip、type、expires_at、reason
+func readCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]int{"ip": 0, "type": 1, "expires_at": 2, "reason": 3}
+	if len(records) > 0 && strings.EqualFold(strings.TrimSpace(records[0][0]), "ip") {
+		columns = make(map[string]int)
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		records = records[1:]
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var entries []Entry
+	for n, record := range records {
+		entry := Entry{IP: field(record, "ip"), Reason: field(record, "reason")}
+		if entry.IP == "" {
+			continue
+		}
+		entry.Permanent = field(record, "type") == "permanent"
+		if expires := field(record, "expires_at"); expires != "" && !entry.Permanent {
+			t, err := time.Parse(time.RFC3339, expires)
+			if err != nil {
+				return nil, fmt.Errorf("第%d行的解封时间无效: %s", n+1, expires)
+			}
+			entry.ExpiresAt = t
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// writeIPSet 写出ipset restore的命令，集合使用hash:net，IPv4与IPv6分别放在两个集合中
+func writeIPSet(w io.Writer, entries []Entry, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "create %s hash:net family inet timeout 0 -exist\n", ipsetName)
+	fmt.Fprintf(bw, "create %s6 hash:net family inet6 timeout 0 -exist\n", ipsetName)
+	for _, entry := range entries {
+		name := ipsetName
+		if strings.Contains(entry.IP, ":") {
+			name += "6"
+		}
+		fmt.Fprintf(bw, "add %s %s", name, entry.IP)
+		if seconds := timeout(entry, now); seconds > 0 {
+			fmt.Fprintf(bw, " timeout %d", seconds)
+		}
+		fmt.Fprintln(bw, " -exist")
+	}
+	return bw.Flush()
+}
+
+// readIPSet 读取ipset save或ipset restore格式中的add行，有timeout的条目按临时封禁导入
+func readIPSet(r io.Reader, now time.Time) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "add" && fields[0] != "-A" {
+			continue
+		}
+		entry := Entry{IP: fields[2], Permanent: true}
+		for i := 3; i+1 < len(fields); i++ {
+			if fields[i] == "timeout" {
+				if seconds, err := strconv.ParseInt(fields[i+1], 10, 64); err == nil && seconds > 0 {
+					entry.Permanent = false
+					entry.ExpiresAt = now.Add(time.Duration(seconds) * time.Second)
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// writeNft 写出nft -f可以读取的表定义，IPv4与IPv6分别放在两个集合中
+// 只定义集合，不创建引用集合的规则
+func writeNft(w io.Writer, entries []Entry, now time.Time) error {
+	var v4, v6 []string
+	for _, entry := range entries {
+		element := entry.IP
+		if seconds := timeout(entry, now); seconds > 0 {
+			element += fmt.Sprintf(" timeout %ds", seconds)
+		}
+		if strings.Contains(entry.IP, ":") {
+			v6 = append(v6, element)
+		} else {
+			v4 = append(v4, element)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "table inet %s {\n", nftTable)
+	writeNftSet(bw, "blacklist4", "ipv4_addr", v4)
+	writeNftSet(bw, "blacklist6", "ipv6_addr", v6)
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func writeNftSet(w io.Writer, name, addrType string, elements []string) {
+	fmt.Fprintf(w, "\tset %s {\n\t\ttype %s\n\t\tflags interval,timeout\n", name, addrType)
+	if len(elements) > 0 {
+		fmt.Fprintf(w, "\t\telements = {\n\t\t\t%s\n\t\t}\n", strings.Join(elements, ",\n\t\t\t"))
+	}
+	fmt.Fprintln(w, "\t}")
+}
+
+// readNft 读取nft list set、nft list ruleset或writeNft输出中的集合元素
+// 有timeout的元素按临时封禁导入，剩余时间取expires（如有）否则取timeout
+func readNft(r io.Reader, now time.Time) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for {
+		start := bytes.Index(data, []byte("elements"))
+		if start < 0 {
+			return entries, nil
+		}
+		open := bytes.IndexByte(data[start:], '{')
+		end := bytes.IndexByte(data[start:], '}')
+		if open < 0 || end < open {
+			return nil, fmt.Errorf("集合元素不完整")
+		}
+		for _, element := range strings.Split(string(data[start+open+1:start+end]), ",") {
+			fields := strings.Fields(element)
+			if len(fields) == 0 {
+				continue
+			}
+			entry := Entry{IP: fields[0], Permanent: true}
+			for i := 1; i+1 < len(fields); i++ {
+				if fields[i] != "timeout" && fields[i] != "expires" {
+					continue
+				}
+				if d, err := parseNftDuration(fields[i+1]); err == nil && d > 0 {
+					entry.Permanent = false
+					entry.ExpiresAt = now.Add(d)
+				}
+			}
+			entries = append(entries, entry)
+		}
+		data = data[start+end+1:]
+	}
+}
+
+// parseNftDuration 解析nftables的时长，如 1d2h3m4s 或 3600s
+func parseNftDuration(s string) (time.Duration, error) {
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, fmt.Errorf("无效的时长: %s", s)
+		}
+		n, _ := strconv.Atoi(s[:i])
+		j := i
+		for j < len(s) && (s[j] < '0' || s[j] > '9') {
+			j++
+		}
+		units := map[string]time.Duration{"d": 24 * time.Hour, "h": time.Hour, "m": time.Minute, "s": time.Second, "ms": time.Millisecond}
+		unit, ok := units[s[i:j]]
+		if !ok {
+			return 0, fmt.Errorf("无效的时长: %s", s)
+		}
+		total += time.Duration(n) * unit
+		s = s[j:]
+	}
+	return total, nil
+}
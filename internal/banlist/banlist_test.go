@@ -0,0 +1,189 @@
+package banlist
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	expires := time.Now().Add(2 * time.Hour).Truncate(time.Second).UTC()
+	entries := []Entry{
+		{IP: "1.2.3.4", Permanent: true, Reason: "手动永久封禁"},
+		{IP: "5.6.7.8", ExpiresAt: expires, Reason: "失败次数过多"},
+		{IP: "10.0.0.0/24"},
+		{IP: "2001:db8::1", ExpiresAt: expires},
+	}
+	tests := []struct {
+		format  string
+		expires bool // 是否保存永久封禁与解封时间
+		reason  bool // 是否保存封禁原因
+	}{
+		{format: FormatTxt},
+		{format: FormatCSV, expires: true, reason: true},
+		{format: FormatJSON, expires: true, reason: true},
+		{format: FormatIPSet, expires: true},
+		{format: FormatNft, expires: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, entries); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Read(&buf, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			byIP := make(map[string]Entry, len(got))
+			for _, entry := range got {
+				byIP[entry.IP] = entry
+			}
+			if len(byIP) != len(entries) {
+				t.Fatalf("读取到 %+v，期望%d条", got, len(entries))
+			}
+			for _, want := range entries {
+				entry, ok := byIP[want.IP]
+				if !ok {
+					t.Errorf("缺少 %s", want.IP)
+					continue
+				}
+				if tt.reason && entry.Reason != want.Reason {
+					t.Errorf("%s: Reason = %q，期望 %q", want.IP, entry.Reason, want.Reason)
+				}
+				if !tt.expires {
+					continue
+				}
+				// ipset和nft中没有解封时间的临时封禁与永久封禁相同
+				permanent := want.Permanent || want.ExpiresAt.IsZero() && (tt.format == FormatIPSet || tt.format == FormatNft)
+				if entry.Permanent != permanent {
+					t.Errorf("%s: Permanent = %v，期望 %v", want.IP, entry.Permanent, permanent)
+				}
+				// ipset和nft保存剩余秒数，允许读写之间的误差
+				if diff := entry.ExpiresAt.Sub(want.ExpiresAt); diff < -2*time.Second || diff > 2*time.Second {
+					t.Errorf("%s: ExpiresAt = %v，期望 %v", want.IP, entry.ExpiresAt, want.ExpiresAt)
+				}
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		content string
+		want    []Entry
+		ok      bool
+	}{
+		{
+			name:    "txt注释与空行",
+			format:  FormatTxt,
+			content: "# 导出的封禁列表\n1.2.3.4 # 手动\n\n  5.6.7.8  \n",
+			want:    []Entry{{IP: "1.2.3.4"}, {IP: "5.6.7.8"}},
+			ok:      true,
+		},
+		{
+			name:    "txt重复的IP保留最后一条",
+			format:  FormatTxt,
+			content: "1.2.3.4\n5.6.7.8\n1.2.3.4\n",
+			want:    []Entry{{IP: "1.2.3.4"}, {IP: "5.6.7.8"}},
+			ok:      true,
+		},
+		{
+			name:    "csv没有表头",
+			format:  FormatCSV,
+			content: "1.2.3.4,permanent,,手动\n",
+			want:    []Entry{{IP: "1.2.3.4", Permanent: true, Reason: "手动"}},
+			ok:      true,
+		},
+		{
+			name:    "csv按表头确定列",
+			format:  FormatCSV,
+			content: "IP,reason,type\n1.2.3.4,扫描,permanent\n",
+			want:    []Entry{{IP: "1.2.3.4", Permanent: true, Reason: "扫描"}},
+			ok:      true,
+		},
+		{
+			name:    "csv无效的解封时间",
+			format:  FormatCSV,
+			content: "ip,type,expires_at\n1.2.3.4,temporary,明天\n",
+		},
+		{
+			name:    "ipset save输出",
+			format:  FormatIPSet,
+			content: "create x hash:ip family inet\nadd x 1.2.3.4\n-A x 5.6.7.8\n",
+			want:    []Entry{{IP: "1.2.3.4", Permanent: true}, {IP: "5.6.7.8", Permanent: true}},
+			ok:      true,
+		},
+		{
+			name:    "nft元素不完整",
+			format:  FormatNft,
+			content: "set x { elements = { 1.2.3.4",
+		},
+		{
+			name:    "fail2ban-client status",
+			format:  FormatFail2ban,
+			content: "Status for the jail: sshd\n`- Actions\n   `- Banned IP list:\t1.2.3.4 2001:db8::1 not-an-ip\n",
+			want:    []Entry{{IP: "1.2.3.4", Reason: "fail2ban"}, {IP: "2001:db8::1", Reason: "fail2ban"}},
+			ok:      true,
+		},
+		{
+			name:    "fail2ban-client banned",
+			format:  FormatFail2ban,
+			content: "[{'sshd': ['1.2.3.4', '5.6.7.8']}]\n",
+			want:    []Entry{{IP: "1.2.3.4", Reason: "fail2ban"}, {IP: "5.6.7.8", Reason: "fail2ban"}},
+			ok:      true,
+		},
+		{
+			name:    "从标准输入读取fail2ban数据库",
+			format:  FormatFail2ban,
+			content: sqliteMagic + "...",
+		},
+		{
+			name:    "不支持的格式",
+			format:  "xml",
+			content: "<bans/>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.content), tt.format)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v，期望成功 %v", err, tt.ok)
+			}
+			if tt.ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFail2ban(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatFail2ban, nil); err == nil {
+		t.Error("fail2ban格式只能导入，导出时应返回错误")
+	}
+}
+
+func TestParseNftDuration(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Duration
+		ok   bool
+	}{
+		{text: "3600s", want: time.Hour, ok: true},
+		{text: "1d2h3m4s", want: 26*time.Hour + 3*time.Minute + 4*time.Second, ok: true},
+		{text: "500ms", want: 500 * time.Millisecond, ok: true},
+		{text: "10"},
+		{text: "5y"},
+		{text: "h"},
+	}
+	for _, tt := range tests {
+		got, err := parseNftDuration(tt.text)
+		if (err == nil) != tt.ok || got != tt.want && tt.ok {
+			t.Errorf("parseNftDuration(%q) = %v, %v，期望 %v", tt.text, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package banlist
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // 纯Go实现的SQLite驱动，不依赖cgo
+)
+
+// sqliteMagic SQLite数据库文件的开头
+const sqliteMagic = "SQLite format 3\x00"
+
+// fail2banHistory 旧版fail2ban的数据库不记录封禁时长，只导入该时间内的封禁
+const fail2banHistory = 24 * time.Hour
+
+// ReadFile 读取文件中的封禁列表
+// fail2ban格式下文件为SQLite数据库时按数据库读取（通常为/var/lib/fail2ban/fail2ban.sqlite3），否则按fail2ban-client的输出读取
+// 参数:
+//   - path: 文件路径，为 - 时读取标准输入
+//   - format: 格式
+//   - jail: 只导入该jail的封禁，为空时导入所有jail，只对fail2ban数据库有效
+// 返回:
+//   - []Entry: 封禁列表
+//   - error: 读取或解析失败时的错误信息
+func ReadFile(path, format, jail string) ([]Entry, error) {
+	if path == "-" {
+		return Read(os.Stdin, format)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if format == FormatFail2ban {
+		header := make([]byte, len(sqliteMagic))
+		if n, _ := io.ReadFull(file, header); n == len(header) && string(header) == sqliteMagic {
+			entries, err := readFail2banDB(path, jail, time.Now())
+			if err != nil {
+				return nil, fmt.Errorf("读取fail2ban数据库失败: %v", err)
+			}
+			return dedup(entries), nil
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return Read(file, format)
+}
+
+// readFail2banDB 读取fail2ban数据库中仍然有效的封禁
+// 0.11及之后的版本从bips表读取当前封禁，bantime为-1时为永久封禁；
+// 更早的版本只有不记录时长的bans表，只导入fail2banHistory内的封禁，时长由导入方决定
+func readFail2banDB(path, jail string, now time.Time) ([]Entry, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	filter, args := "", []interface{}{}
+	if jail != "" {
+		filter, args = " WHERE jail = ?", append(args, jail)
+	}
+	rows, err := db.Query("SELECT jail, ip, timeofban, bantime FROM bips"+filter, args...)
+	legacy := err != nil && strings.Contains(err.Error(), "no such table")
+	if legacy {
+		rows, err = db.Query("SELECT jail, ip, timeofban, -2 FROM bans"+filter, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var name, ip string
+		var timeOfBan, banTime int64
+		if err := rows.Scan(&name, &ip, &timeOfBan, &banTime); err != nil {
+			return nil, err
+		}
+		bannedAt := time.Unix(timeOfBan, 0)
+		entry := Entry{IP: ip, Reason: "fail2ban " + name}
+		switch {
+		case legacy:
+			if now.Sub(bannedAt) > fail2banHistory {
+				continue
+			}
+		case banTime < 0:
+			entry.Permanent = true
+		default:
+			entry.ExpiresAt = bannedAt.Add(time.Duration(banTime) * time.Second)
+			if !entry.ExpiresAt.After(now) {
+				continue
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// readFail2ban 读取fail2ban-client的输出
+// 支持 fail2ban-client status <jail> 中的 "Banned IP list:" 行，以及 fail2ban-client banned 的输出
+func readFail2ban(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(data, []byte(sqliteMagic)) {
+		return nil, fmt.Errorf("fail2ban数据库需要以文件路径指定，不能从标准输入读取")
+	}
+
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if _, list, ok := strings.Cut(line, "Banned IP list:"); ok {
+			line = list
+		}
+		tokens := strings.FieldsFunc(line, func(r rune) bool {
+			return strings.ContainsRune(" \t[]{}'\",", r)
+		})
+		for _, token := range tokens {
+			if net.ParseIP(token) != nil {
+				entries = append(entries, Entry{IP: token, Reason: "fail2ban"})
+			}
+		}
+	}
+	return entries, scanner.Err()
+}
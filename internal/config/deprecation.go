@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MajorVersion 当前的主版本号
+// 废弃的配置项在RemovedIn指定的主版本之前仍然生效，加载时记录警告；到达该主版本后加载配置时报错，
+// 不会因为配置项被删除而被静默忽略
+const MajorVersion = 1
+
+// Deprecation 一个已废弃的配置项
+type Deprecation struct {
+	Key         string `json:"key"`         // 配置项路径，[]表示列表中的每个元素，*表示映射中的每个值
+	Replacement string `json:"replacement"` // 替代的配置项或写法
+	Since       string `json:"since"`       // 开始废弃的版本
+	RemovedIn   int    `json:"removed_in"`  // 移除该配置项的主版本号
+	Note        string `json:"note"`        // 迁移说明，如新旧写法的换算方式
+}
+
+// DeprecatedKey 配置文件中出现的一个废弃配置项
+type DeprecatedKey struct {
+	Deprecation
+	Path string `json:"path"` // 在配置文件中的实际路径，如 rules[0].ban_duration_hours
+}
+
+// String 返回单行的可读描述，用于日志和 config test 的输出
+func (d DeprecatedKey) String() string {
+	text := fmt.Sprintf("%s 已于%s废弃，将在%d.0版本中移除，请改用 %s", d.Path, d.Since, d.RemovedIn, d.Replacement)
+	if d.Note != "" {
+		text += "（" + d.Note + "）"
+	}
+	return text
+}
+
+// deprecations 所有已废弃的配置项
+// 废弃配置项时在此登记并保留原有字段，读取配置的代码在替代项未设置时继续使用旧值；
+// 替代项与废弃项位于同一层级时，两者不能同时填写；
+// 主版本号到达RemovedIn后删除旧字段和对应的条目
+var deprecations = []Deprecation{
+	{Key: "ssh_protection.ban_duration_hours", Replacement: "ssh_protection.ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.instant_ban[].ban_duration_hours", Replacement: "ssh_protection.instant_ban[].ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.users.*.ban_duration_hours", Replacement: "ssh_protection.users.*.ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.preauth.ban_duration_hours", Replacement: "ssh_protection.preauth.ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.country_policy.ban_duration_hours", Replacement: "ssh_protection.country_policy.ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "rules[].ban_duration_hours", Replacement: "rules[].ban_duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.rate_limit.duration_hours", Replacement: "ssh_protection.rate_limit.duration", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.shared_ip.window_hours", Replacement: "ssh_protection.shared_ip.window", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "blacklist.cleanup_interval_hours", Replacement: "blacklist.cleanup_interval", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "whitelist.allow_ttl_hours", Replacement: "whitelist.allow_ttl", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ip_info.cache_ttl_hours", Replacement: "ip_info.cache_ttl", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "store.retention.interval_hours", Replacement: "store.retention.interval", Since: "1.1", RemovedIn: 2, Note: hoursNote},
+	{Key: "ssh_protection.attempt_window_minutes", Replacement: "ssh_protection.attempt_window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "ssh_protection.password_spray.window_minutes", Replacement: "ssh_protection.password_spray.window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "ssh_protection.account_lock.duration_minutes", Replacement: "ssh_protection.account_lock.duration", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "ssh_protection.subnet_aggregation.window_minutes", Replacement: "ssh_protection.subnet_aggregation.window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "ssh_protection.preauth.window_minutes", Replacement: "ssh_protection.preauth.window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "ssh_protection.risk_score.rate_window_seconds", Replacement: "ssh_protection.risk_score.rate_window", Since: "1.1", RemovedIn: 2, Note: secondsNote},
+	{Key: "rules[].window_minutes", Replacement: "rules[].window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "blacklist.feeds[].refresh_minutes", Replacement: "blacklist.feeds[].refresh_interval", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "crowdsec.pull_interval_seconds", Replacement: "crowdsec.pull_interval", Since: "1.1", RemovedIn: 2, Note: secondsNote},
+	{Key: "fleet.resync_minutes", Replacement: "fleet.resync_interval", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "aggregator.flush_seconds", Replacement: "aggregator.flush_interval", Since: "1.1", RemovedIn: 2, Note: secondsNote},
+	{Key: "aggregator.dedup_minutes", Replacement: "aggregator.dedup_window", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+	{Key: "whitelist.trust_after_login_minutes", Replacement: "whitelist.trust_after_login", Since: "1.1", RemovedIn: 2, Note: minutesNote},
+}
+
+// 以整数为单位的时长改为时长字符串的迁移说明，新旧配置项不能同时填写
+const (
+	hoursNote   = "N小时改写为 Nh，如 168 改写为 168h 或 7d；新写法支持不足一小时的时长，如 30m"
+	minutesNote = "N分钟改写为 Nm，如 1440 改写为 1440m 或 1d；新写法支持不足一分钟的时长，如 30s"
+	secondsNote = "N秒改写为 Ns，如 60 改写为 60s 或 1m"
+)
+
+// applyDeprecated 将配置文件中仍在使用的废弃配置项换算到替代项，读取配置的代码只需使用替代项
+// 参数:
+//   - config: 已从配置文件解码、尚未校验的配置
+func applyDeprecated(config *Config) {
+	p := &config.SSHProtection
+	p.BanDuration = durationFromHours(p.BanDuration, p.BanDurationHours)
+	for i := range p.InstantBan {
+		p.InstantBan[i].BanDuration = durationFromHours(p.InstantBan[i].BanDuration, p.InstantBan[i].BanDurationHours)
+	}
+	for user, policy := range p.Users {
+		policy.BanDuration = durationFromHours(policy.BanDuration, policy.BanDurationHours)
+		p.Users[user] = policy
+	}
+	p.Preauth.BanDuration = durationFromHours(p.Preauth.BanDuration, p.Preauth.BanDurationHours)
+	p.CountryPolicy.BanDuration = durationFromHours(p.CountryPolicy.BanDuration, p.CountryPolicy.BanDurationHours)
+	for i := range config.Rules {
+		config.Rules[i].BanDuration = durationFromHours(config.Rules[i].BanDuration, config.Rules[i].BanDurationHours)
+	}
+	p.RateLimit.Duration = durationFromHours(p.RateLimit.Duration, p.RateLimit.DurationHours)
+	p.SharedIP.Window = durationFromHours(p.SharedIP.Window, p.SharedIP.WindowHours)
+	config.Blacklist.CleanupInterval = durationFromHours(config.Blacklist.CleanupInterval, config.Blacklist.CleanupIntervalHours)
+	config.Whitelist.AllowTTL = durationFromHours(config.Whitelist.AllowTTL, config.Whitelist.AllowTTLHours)
+	config.Store.Retention.Interval = durationFromHours(config.Store.Retention.Interval, config.Store.Retention.IntervalHours)
+	p.AttemptWindow = durationFromMinutes(p.AttemptWindow, p.AttemptWindowMinutes)
+	p.PasswordSpray.Window = durationFromMinutes(p.PasswordSpray.Window, p.PasswordSpray.WindowMinutes)
+	p.AccountLock.Duration = durationFromMinutes(p.AccountLock.Duration, p.AccountLock.DurationMinutes)
+	p.SubnetAggregation.Window = durationFromMinutes(p.SubnetAggregation.Window, p.SubnetAggregation.WindowMinutes)
+	p.Preauth.Window = durationFromMinutes(p.Preauth.Window, p.Preauth.WindowMinutes)
+	p.RiskScore.RateWindow = durationFromSeconds(p.RiskScore.RateWindow, p.RiskScore.RateWindowSeconds)
+	for i := range config.Rules {
+		config.Rules[i].Window = durationFromMinutes(config.Rules[i].Window, config.Rules[i].WindowMinutes)
+	}
+	for i := range config.Blacklist.Feeds {
+		feed := &config.Blacklist.Feeds[i]
+		feed.RefreshInterval = durationFromMinutes(feed.RefreshInterval, feed.RefreshMinutes)
+	}
+	config.CrowdSec.PullInterval = durationFromSeconds(config.CrowdSec.PullInterval, config.CrowdSec.PullIntervalSeconds)
+	config.Fleet.ResyncInterval = durationFromMinutes(config.Fleet.ResyncInterval, config.Fleet.ResyncMinutes)
+	config.Aggregator.FlushInterval = durationFromSeconds(config.Aggregator.FlushInterval, config.Aggregator.FlushSeconds)
+	config.Aggregator.DedupWindow = durationFromMinutes(config.Aggregator.DedupWindow, config.Aggregator.DedupMinutes)
+	config.Whitelist.TrustAfterLogin = durationFromMinutes(config.Whitelist.TrustAfterLogin, config.Whitelist.TrustAfterLoginMinutes)
+	// cache_ttl_hours为0表示不缓存，未填写时为-1
+	if ip := &config.IPInfo; ip.CacheTTLHours >= 0 {
+		ip.CacheTTL = Duration(time.Duration(ip.CacheTTLHours) * time.Hour)
+	}
+}
+
+// Deprecations 返回所有已废弃的配置项，用于生成升级说明
+// 返回:
+//   - []Deprecation: 按配置项路径排序的废弃配置项
+func Deprecations() []Deprecation {
+	list := append([]Deprecation(nil), deprecations...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+// findDeprecated 在配置文件的原始内容中查找废弃的配置项
+// 替代项带有默认值，解码后无法区分是否填写，因此在原始内容中检查新旧配置项是否同时填写
+// 参数:
+//   - data: 配置文件内容
+// 返回:
+//   - []DeprecatedKey: 配置文件中出现的废弃配置项，按出现的路径排序
+//   - error: 使用了已到达移除版本的配置项，或同时填写了废弃项与替代项时的错误信息
+func findDeprecated(data []byte) ([]DeprecatedKey, error) {
+	var root interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	var found []DeprecatedKey
+	for _, d := range deprecations {
+		replaced := make(map[string]bool)
+		for _, path := range lookupKeys(root, strings.Split(d.Replacement, "."), "") {
+			replaced[path] = true
+		}
+		for _, path := range lookupKeys(root, strings.Split(d.Key, "."), "") {
+			if d.RemovedIn <= MajorVersion {
+				return nil, fmt.Errorf("配置项 %s 已在%d.0版本中移除，请改用 %s", path, d.RemovedIn, d.Replacement)
+			}
+			if replacement := siblingKey(path, d.Replacement); replaced[replacement] {
+				return nil, fmt.Errorf("配置项 %s 与 %s 不能同时填写，请删除已废弃的 %s", path, replacement, path)
+			}
+			found = append(found, DeprecatedKey{Deprecation: d, Path: path})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found, nil
+}
+
+// siblingKey 返回与path同一层级、名称为replacement最后一段的配置项路径
+// 如 rules[0].window_minutes 与 rules[].window 得到 rules[0].window
+func siblingKey(path, replacement string) string {
+	name := replacement[strings.LastIndex(replacement, ".")+1:]
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		return path[:i+1] + name
+	}
+	return name
+}
+
+// lookupKeys 按路径在YAML内容中查找配置项，返回所有出现位置的实际路径
+// 路径段以[]结尾时依次进入列表中的每个元素，为*时进入映射中的每个值
+func lookupKeys(node interface{}, parts []string, path string) []string {
+	if len(parts) == 0 {
+		return []string{path}
+	}
+	m, ok := node.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+
+	part := parts[0]
+	if part == "*" {
+		var paths []string
+		for key, value := range m {
+			paths = append(paths, lookupKeys(value, parts[1:], joinKey(path, fmt.Sprint(key)))...)
+		}
+		return paths
+	}
+	key, isList := strings.CutSuffix(part, "[]")
+	value, ok := m[key]
+	if !ok {
+		return nil
+	}
+	if !isList {
+		return lookupKeys(value, parts[1:], joinKey(path, key))
+	}
+	items, _ := value.([]interface{})
+	var paths []string
+	for i, item := range items {
+		paths = append(paths, lookupKeys(item, parts[1:], joinKey(path, key)+"["+strconv.Itoa(i)+"]")...)
+	}
+	return paths
+}
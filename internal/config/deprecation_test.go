@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// baseConfig 通过校验所需的最少配置，测试用例在其后追加配置项
+const baseConfig = "telegram:\n  enabled: false\n"
+
+// loadTestConfig 将内容写入临时文件并加载
+func loadTestConfig(t *testing.T, content string) (*Config, []DeprecatedKey, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(baseConfig+content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfigWithDeprecations(path)
+}
+
+func TestDeprecatedDurations(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		get     func(*Config) Duration
+		want    time.Duration
+		paths   []string
+	}{
+		{
+			name:    "小时数换算",
+			content: "ssh_protection:\n  ban_duration_hours: 2\n",
+			get:     func(c *Config) Duration { return c.SSHProtection.BanDuration },
+			want:    2 * time.Hour,
+			paths:   []string{"ssh_protection.ban_duration_hours"},
+		},
+		{
+			name:    "分钟数换算",
+			content: "ssh_protection:\n  attempt_window_minutes: 15\n",
+			get:     func(c *Config) Duration { return c.SSHProtection.AttemptWindow },
+			want:    15 * time.Minute,
+			paths:   []string{"ssh_protection.attempt_window_minutes"},
+		},
+		{
+			name:    "秒数换算",
+			content: "ssh_protection:\n  risk_score:\n    rate_window_seconds: 90\n",
+			get:     func(c *Config) Duration { return c.SSHProtection.RiskScore.RateWindow },
+			want:    90 * time.Second,
+			paths:   []string{"ssh_protection.risk_score.rate_window_seconds"},
+		},
+		{
+			name:    "列表中的废弃项",
+			content: "rules:\n  - name: x\n    pattern: \"foo <ip>\"\n    log_file: /var/log/x\n    window_minutes: 5\n",
+			get:     func(c *Config) Duration { return c.Rules[0].Window },
+			want:    5 * time.Minute,
+			paths:   []string{"rules[0].window_minutes"},
+		},
+		{
+			name:    "cache_ttl_hours为0表示不缓存",
+			content: "ip_info:\n  cache_ttl_hours: 0\n",
+			get:     func(c *Config) Duration { return c.IPInfo.CacheTTL },
+			paths:   []string{"ip_info.cache_ttl_hours"},
+		},
+		{
+			name:    "只填写替代项",
+			content: "ssh_protection:\n  attempt_window: 1d\n",
+			get:     func(c *Config) Duration { return c.SSHProtection.AttemptWindow },
+			want:    day,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, deprecated, err := loadTestConfig(t, tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := time.Duration(tt.get(config)); got != tt.want {
+				t.Errorf("got %v，期望 %v", got, tt.want)
+			}
+			var paths []string
+			for _, d := range deprecated {
+				paths = append(paths, d.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.paths, ",") {
+				t.Errorf("废弃配置项 %v，期望 %v", paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestDeprecatedWithReplacement(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "同一层级",
+			content: "ssh_protection:\n  ban_duration: 2h\n  ban_duration_hours: 2\n",
+			want:    "ssh_protection.ban_duration_hours 与 ssh_protection.ban_duration 不能同时填写",
+		},
+		{
+			name:    "列表中的同一元素",
+			content: "rules:\n  - name: x\n    pattern: \"foo <ip>\"\n    log_file: /var/log/x\n    window: 5m\n    window_minutes: 5\n",
+			want:    "rules[0].window_minutes 与 rules[0].window 不能同时填写",
+		},
+		{
+			name:    "替代项改名",
+			content: "fleet:\n  resync_interval: 10m\n  resync_minutes: 10\n",
+			want:    "fleet.resync_minutes 与 fleet.resync_interval 不能同时填写",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := loadTestConfig(t, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v，期望包含 %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeprecatedInDifferentElements(t *testing.T) {
+	content := "rules:\n" +
+		"  - name: a\n    pattern: \"a <ip>\"\n    log_file: /var/log/a\n    window: 5m\n" +
+		"  - name: b\n    pattern: \"b <ip>\"\n    log_file: /var/log/b\n    window_minutes: 20\n"
+	config, _, err := loadTestConfig(t, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := time.Duration(config.Rules[0].Window); got != 5*time.Minute {
+		t.Errorf("rules[0].window = %v，期望 5m", got)
+	}
+	if got := time.Duration(config.Rules[1].Window); got != 20*time.Minute {
+		t.Errorf("rules[1].window = %v，期望 20m", got)
+	}
+}
+
+func TestSiblingKey(t *testing.T) {
+	tests := []struct {
+		path, replacement, want string
+	}{
+		{"rules[0].window_minutes", "rules[].window", "rules[0].window"},
+		{"ssh_protection.users.bob.ban_duration_hours", "ssh_protection.users.*.ban_duration", "ssh_protection.users.bob.ban_duration"},
+		{"fleet.resync_minutes", "fleet.resync_interval", "fleet.resync_interval"},
+		{"top", "other", "other"},
+	}
+	for _, tt := range tests {
+		if got := siblingKey(tt.path, tt.replacement); got != tt.want {
+			t.Errorf("siblingKey(%q, %q) = %q，期望 %q", tt.path, tt.replacement, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 参考配置文件头部说明
+const referenceHeader = `# SSH防护系统参考配置
+# 本文件由 "ssh_fb config docs" 根据代码中的结构体标签生成，请勿手工维护
+# 所有配置项均已填入默认值，按需修改即可
+
+`
+
+// WriteReferenceYAML 输出带注释的完整参考配置
+// 配置项、默认值与校验规则均来自结构体标签，不会与代码脱节
+// 参数:
+//   - w: 输出目标
+// 返回:
+//   - error: 输出过程中的错误信息
+func WriteReferenceYAML(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(referenceHeader)
+	writeYAMLStruct(&buf, reflect.ValueOf(Default()).Elem(), 0)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// WriteReferenceMarkdown 输出Markdown格式的配置项说明表
+// 参数:
+//   - w: 输出目标
+// 返回:
+//   - error: 输出过程中的错误信息
+func WriteReferenceMarkdown(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString("# 配置参考\n\n")
+	buf.WriteString("本文档由 `ssh_fb config docs --format markdown` 生成，请勿手工维护。\n")
+
+	v := reflect.ValueOf(Default()).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fmt.Fprintf(&buf, "\n## %s\n\n", yamlKey(field))
+		if comment := field.Tag.Get("comment"); comment != "" {
+			fmt.Fprintf(&buf, "%s\n\n", comment)
+		}
+		buf.WriteString("| 配置项 | 类型 | 默认值 | 校验规则 | 说明 |\n")
+		buf.WriteString("| --- | --- | --- | --- | --- |\n")
+		if v.Field(i).Kind() == reflect.Struct {
+			writeMarkdownRows(&buf, v.Field(i), yamlKey(field))
+		} else {
+			writeMarkdownField(&buf, field, v.Field(i), yamlKey(field))
+		}
+	}
+	writeDeprecations(&buf)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeYAMLStruct(buf *bytes.Buffer, v reflect.Value, depth int) {
+	indent := strings.Repeat("  ", depth)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		key := yamlKey(field)
+		if isDeprecated(field) {
+			continue
+		}
+
+		if depth == 0 && i > 0 {
+			buf.WriteString("\n")
+		}
+		writeYAMLComment(buf, indent, field)
+
+		switch {
+		case fv.Kind() == reflect.Struct:
+			fmt.Fprintf(buf, "%s%s:\n", indent, key)
+			writeYAMLStruct(buf, fv, depth+1)
+		case isCollection(fv) && fv.Len() == 0:
+			if elem := elemStruct(fv.Type()); elem != nil {
+				writeYAMLExample(buf, indent, fv.Type(), elem)
+			}
+			fmt.Fprintf(buf, "%s%s: %s\n", indent, key, emptyCollection(fv))
+		case isCollection(fv):
+			out, _ := yaml.Marshal(fv.Interface())
+			fmt.Fprintf(buf, "%s%s:\n", indent, key)
+			for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+				fmt.Fprintf(buf, "%s  %s\n", indent, line)
+			}
+		default:
+			fmt.Fprintf(buf, "%s%s: %s\n", indent, key, formatScalar(fv))
+		}
+	}
+}
+
+func writeYAMLComment(buf *bytes.Buffer, indent string, field reflect.StructField) {
+	comment := field.Tag.Get("comment")
+	if rules := describeRules(field.Tag.Get("validate")); rules != "" {
+		comment = fmt.Sprintf("%s（校验: %s）", comment, rules)
+	}
+	if comment != "" {
+		fmt.Fprintf(buf, "%s# %s\n", indent, comment)
+	}
+}
+
+// writeYAMLExample 为空的结构体列表/映射输出注释形式的示例元素
+func writeYAMLExample(buf *bytes.Buffer, indent string, t reflect.Type, elem reflect.Type) {
+	example := reflect.New(elem).Elem()
+	setDefaults(example, "")
+
+	var sub bytes.Buffer
+	writeYAMLStruct(&sub, example, 0)
+
+	buf.WriteString(indent + "# 示例:\n")
+	lines := strings.Split(strings.TrimRight(sub.String(), "\n"), "\n")
+	prefix := "#   - "
+	if t.Kind() == reflect.Map {
+		buf.WriteString(indent + "#   <名称>:\n")
+		prefix = "#     "
+	}
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		if i > 0 && t.Kind() == reflect.Slice {
+			prefix = "#     "
+		}
+		fmt.Fprintf(buf, "%s%s%s\n", indent, prefix, line)
+	}
+}
+
+func writeMarkdownRows(buf *bytes.Buffer, v reflect.Value, path string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		key := path + "." + yamlKey(field)
+		if isDeprecated(field) {
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct {
+			writeMarkdownRows(buf, fv, key)
+			continue
+		}
+		writeMarkdownField(buf, field, fv, key)
+	}
+}
+
+// writeMarkdownField 输出单个配置项的说明行，结构体列表/映射会继续输出元素的各字段
+func writeMarkdownField(buf *bytes.Buffer, field reflect.StructField, fv reflect.Value, key string) {
+	fmt.Fprintf(buf, "| `%s` | %s | %s | %s | %s |\n",
+		key,
+		typeName(fv.Type()),
+		markdownEscape(formatDefault(fv)),
+		markdownEscape(describeRules(field.Tag.Get("validate"))),
+		markdownEscape(field.Tag.Get("comment")))
+
+	if elem := elemStruct(fv.Type()); elem != nil {
+		example := reflect.New(elem).Elem()
+		setDefaults(example, "")
+		sep := "[]"
+		if fv.Kind() == reflect.Map {
+			sep = ".<名称>"
+		}
+		writeMarkdownRows(buf, example, key+sep)
+	}
+}
+
+func formatScalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	default:
+		return fmt.Sprintf("%v", v.Interface())
+	}
+}
+
+func formatDefault(v reflect.Value) string {
+	if isCollection(v) {
+		if v.Len() == 0 {
+			return "`" + emptyCollection(v) + "`"
+		}
+		out, _ := yaml.Marshal(v.Interface())
+		return "`" + strings.ReplaceAll(strings.TrimSpace(string(out)), "\n", ", ") + "`"
+	}
+	if v.Kind() == reflect.String && v.String() == "" {
+		return ""
+	}
+	return "`" + formatScalar(v) + "`"
+}
+
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem())
+	case reflect.Map:
+		return "map of " + typeName(t.Elem())
+	case reflect.Struct:
+		return "object"
+	}
+	return t.Kind().String()
+}
+
+// isDeprecated 检查字段是否为废弃的配置项，参考配置和配置说明中不再列出，见deprecation.go
+func isDeprecated(field reflect.StructField) bool {
+	_, ok := field.Tag.Lookup("deprecated")
+	return ok
+}
+
+func isCollection(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Map
+}
+
+func emptyCollection(v reflect.Value) string {
+	if v.Kind() == reflect.Map {
+		return "{}"
+	}
+	return "[]"
+}
+
+func elemStruct(t reflect.Type) reflect.Type {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+		return nil
+	}
+	if t.Elem().Kind() == reflect.Struct {
+		return t.Elem()
+	}
+	return nil
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", "\\|", "<", "&lt;").Replace(s)
+}
+
+// writeDeprecations 输出废弃配置项的升级说明
+func writeDeprecations(buf *bytes.Buffer) {
+	buf.WriteString("\n## 废弃的配置项\n\n")
+	fmt.Fprintf(buf, "废弃的配置项在移除版本之前仍然生效，加载时记录警告；当前主版本为%d，到达移除版本后使用这些配置项将无法启动。\n\n", MajorVersion)
+	list := Deprecations()
+	if len(list) == 0 {
+		buf.WriteString("当前没有废弃的配置项。\n")
+		return
+	}
+	buf.WriteString("| 配置项 | 替代 | 废弃版本 | 移除版本 | 说明 |\n")
+	buf.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, d := range list {
+		fmt.Fprintf(buf, "| `%s` | %s | %s | %d.0 | %s |\n",
+			d.Key, markdownEscape(d.Replacement), d.Since, d.RemovedIn, markdownEscape(d.Note))
+	}
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// day 配置时长中d单位的长度
+const day = 24 * time.Hour
+
+// durationType Duration的反射类型，用于按default标签填充默认值和生成配置文档
+var durationType = reflect.TypeOf(Duration(0))
+
+// Duration 配置中的时长
+// 写作Go时长字符串，如 30m、12h、1h30m，另外支持以d为单位的天数，如 7d、1d12h；0表示未设置
+type Duration time.Duration
+
+// ParseDuration 解析配置中的时长，不带单位的数字只接受0
+// 参数:
+//   - s: 时长字符串，如 30m、12h、7d
+// 返回:
+//   - time.Duration: 解析出的时长
+//   - error: 格式无效时的错误信息
+func ParseDuration(s string) (time.Duration, error) {
+	text := strings.TrimSpace(s)
+	if text == "" || text == "0" {
+		return 0, nil
+	}
+	// 天数只能写在最前面，之后可以接Go时长，如 1d12h
+	var days time.Duration
+	if n, rest, ok := strings.Cut(text, "d"); ok {
+		count, err := strconv.Atoi(n)
+		if err != nil || strings.HasPrefix(rest, "-") || strings.HasPrefix(rest, "+") {
+			return 0, fmt.Errorf("无效的时长: %s，应写作如 30m、12h、7d", s)
+		}
+		days = time.Duration(count) * day
+		if rest == "" {
+			return days, nil
+		}
+		text = rest
+	}
+	d, err := time.ParseDuration(text)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时长: %s，应写作如 30m、12h、7d", s)
+	}
+	return days + d, nil
+}
+
+// String 以配置中的写法返回时长，整天数写作d，如 7d、36h、1h30m
+func (d Duration) String() string {
+	v := time.Duration(d)
+	switch {
+	case v == 0:
+		return "0"
+	case v%day == 0:
+		return fmt.Sprintf("%dd", v/day)
+	}
+	s := v.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// UnmarshalYAML 从时长字符串解析
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalYAML 输出为时长字符串
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// durationFromHours 返回已废弃的小时数配置项换算出的时长，小时数为0时返回替代项的值
+// 替代项带有默认值，无法区分是否在配置文件中填写；两者同时填写时findDeprecated已报错，
+// 废弃项非0时必然只填写了废弃项
+func durationFromHours(d Duration, hours int) Duration {
+	return durationFromUnits(d, hours, time.Hour)
+}
+
+// durationFromMinutes 返回已废弃的分钟数配置项换算出的时长，分钟数为0时返回替代项的值
+func durationFromMinutes(d Duration, minutes int) Duration {
+	return durationFromUnits(d, minutes, time.Minute)
+}
+
+// durationFromSeconds 返回已废弃的秒数配置项换算出的时长，秒数为0时返回替代项的值
+func durationFromSeconds(d Duration, seconds int) Duration {
+	return durationFromUnits(d, seconds, time.Second)
+}
+
+// durationFromUnits 返回n个unit的时长，n为0时返回d
+func durationFromUnits(d Duration, n int, unit time.Duration) Duration {
+	if n > 0 {
+		return Duration(time.Duration(n) * unit)
+	}
+	return d
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want time.Duration
+		ok   bool
+	}{
+		{name: "空字符串", text: "", ok: true},
+		{name: "零", text: "0", ok: true},
+		{name: "分钟", text: "30m", want: 30 * time.Minute, ok: true},
+		{name: "小时与分钟", text: "1h30m", want: 90 * time.Minute, ok: true},
+		{name: "天数", text: "7d", want: 7 * day, ok: true},
+		{name: "天数后接时长", text: "1d12h", want: 36 * time.Hour, ok: true},
+		{name: "前后空白", text: " 12h ", want: 12 * time.Hour, ok: true},
+		{name: "不带单位的数字", text: "30"},
+		{name: "天数不在最前面", text: "12h1d"},
+		{name: "天数后接负数", text: "1d-1h"},
+		{name: "非数字的天数", text: "xd"},
+		{name: "无效单位", text: "5y"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDuration(tt.text)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v，期望成功 %v", err, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("got %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{30 * time.Second, "30s"},
+		{30 * time.Minute, "30m"},
+		{90 * time.Minute, "1h30m"},
+		{12 * time.Hour, "12h"},
+		{day, "1d"},
+		{36 * time.Hour, "36h"},
+		{7 * day, "7d"},
+	}
+	for _, tt := range tests {
+		if got := Duration(tt.d).String(); got != tt.want {
+			t.Errorf("Duration(%v).String() = %q，期望 %q", tt.d, got, tt.want)
+		}
+		if back, err := ParseDuration(tt.want); err != nil || back != tt.d {
+			t.Errorf("ParseDuration(%q) = %v, %v，期望 %v", tt.want, back, err, tt.d)
+		}
+	}
+}
+
+func TestDurationFromUnits(t *testing.T) {
+	def := Duration(10 * time.Minute)
+	tests := []struct {
+		name string
+		got  Duration
+		want time.Duration
+	}{
+		{name: "小时数", got: durationFromHours(def, 2), want: 2 * time.Hour},
+		{name: "分钟数", got: durationFromMinutes(def, 15), want: 15 * time.Minute},
+		{name: "秒数", got: durationFromSeconds(def, 90), want: 90 * time.Second},
+		{name: "未填写时使用替代项", got: durationFromMinutes(def, 0), want: 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		if time.Duration(tt.got) != tt.want {
+			t.Errorf("%s: got %v，期望 %v", tt.name, time.Duration(tt.got), tt.want)
+		}
+	}
+}
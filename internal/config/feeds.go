@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// 订阅名称用作ipset集合名的一部分，集合名最长31个字符
+var feedNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]{1,20}$`)
+
+// CrowdSecFeedName CrowdSec拉取的决策与订阅黑名单一样写入ipset集合，集合使用该名称，订阅不能重名
+const CrowdSecFeedName = "crowdsec"
+
+// FeedConfig 定义一个订阅的外部黑名单
+type FeedConfig struct {
+	Name            string   `yaml:"name" validate:"required" comment:"订阅名称，只能包含字母、数字和连字符，最长20个字符，用作ipset集合名 ssh_fb_<name>"`
+	URL             string   `yaml:"url" validate:"required" comment:"黑名单下载地址，内容为每行一个IP或CIDR，#和;之后的内容按注释忽略，如 https://lists.blocklist.de/lists/ssh.txt"`
+	Enabled         bool     `yaml:"enabled" default:"true" comment:"是否启用该订阅，停用后删除其集合与规则"`
+	RefreshInterval Duration `yaml:"refresh_interval" default:"1h" validate:"gt=0" comment:"重新下载的间隔，如 30m、12h，请遵守黑名单提供方的频率限制"`
+	RefreshMinutes  int      `yaml:"refresh_minutes" validate:"gte=0" deprecated:"refresh_interval"`
+}
+
+// UnmarshalYAML 解析订阅时先填充default标签中的默认值
+// 列表元素不经过Default()，未填写的字段需要在这里补齐
+func (f *FeedConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain FeedConfig
+	var feed plain
+	if err := applyDefaults(&feed); err != nil {
+		return err
+	}
+	if err := unmarshal(&feed); err != nil {
+		return err
+	}
+	*f = FeedConfig(feed)
+	return nil
+}
+
+// validateFeeds 校验订阅名称有效且唯一、地址为http或https
+func validateFeeds(feeds []FeedConfig) error {
+	seen := make(map[string]bool)
+	for i, feed := range feeds {
+		if !feedNamePattern.MatchString(feed.Name) {
+			return fmt.Errorf("黑名单配置错误: feeds[%d].name只能包含字母、数字和连字符，最长20个字符: %s", i, feed.Name)
+		}
+		if feed.Name == CrowdSecFeedName {
+			return fmt.Errorf("黑名单配置错误: feeds[%d].name %s 保留给CrowdSec集成使用", i, feed.Name)
+		}
+		if seen[feed.Name] {
+			return fmt.Errorf("黑名单配置错误: feeds[%d].name重复: %s", i, feed.Name)
+		}
+		seen[feed.Name] = true
+		if !isHTTPURL(feed.URL) {
+			return fmt.Errorf("黑名单配置错误: feeds[%d].url必须是http或https地址: %s", i, feed.URL)
+		}
+	}
+	return nil
+}
+
+// validateCrowdSec 校验启用的拉取与推送都有对应的凭据
+func validateCrowdSec(cs CrowdSecConfig) error {
+	if !cs.Enabled {
+		return nil
+	}
+	if !isHTTPURL(cs.URL) {
+		return fmt.Errorf("CrowdSec配置错误: url必须是http或https地址: %s", cs.URL)
+	}
+	if !cs.Pull && !cs.Push {
+		return fmt.Errorf("CrowdSec配置错误: pull和push至少启用一项")
+	}
+	if cs.Pull && cs.BouncerKey == "" {
+		return fmt.Errorf("CrowdSec配置错误: 启用pull时bouncer_key不能为空")
+	}
+	if cs.Push && (cs.MachineID == "" || cs.Password == "") {
+		return fmt.Errorf("CrowdSec配置错误: 启用push时machine_id和password不能为空")
+	}
+	return nil
+}
+
+// isHTTPURL 检查地址是否为带主机名的http或https地址
+func isHTTPURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && parsed.Host != "" && (parsed.Scheme == "http" || parsed.Scheme == "https")
+}
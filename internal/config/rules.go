@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 规则正则中的IP占位符及其替换后的捕获组
+const (
+	ipPlaceholder = "<ip>"
+	ipGroup       = `(?P<ip>[0-9A-Fa-f:.]+)`
+)
+
+// 内置jail的名称，规则不能与其重名
+var builtinJails = []string{"sshd", "haproxy"}
+
+// CompileRulePattern 编译规则的正则表达式
+// 表达式中的<ip>占位符会被替换为名为ip的捕获组
+// 参数:
+//   - pattern: 配置中的正则表达式
+// 返回:
+//   - *regexp.Regexp: 编译后的正则
+//   - error: 表达式无效或不含ip捕获组时的错误信息
+func CompileRulePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(strings.ReplaceAll(pattern, ipPlaceholder, ipGroup))
+	if err != nil {
+		return nil, fmt.Errorf("正则表达式无效: %v", err)
+	}
+	if re.SubexpIndex("ip") < 0 {
+		return nil, fmt.Errorf("正则表达式缺少<ip>占位符或名为ip的捕获组")
+	}
+	return re, nil
+}
+
+// CompileHAProxyPattern 编译haproxy jail的正则表达式
+// 除ip外还必须包含名为port的捕获组
+// 参数:
+//   - pattern: 配置中的正则表达式
+// 返回:
+//   - *regexp.Regexp: 编译后的正则
+//   - error: 表达式无效或缺少捕获组时的错误信息
+func CompileHAProxyPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := CompileRulePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if re.SubexpIndex("port") < 0 {
+		return nil, fmt.Errorf("正则表达式缺少名为port的捕获组")
+	}
+	return re, nil
+}
+
+// UnmarshalYAML 解析规则时先填充default标签中的默认值
+// 列表元素不经过Default()，未填写的字段需要在这里补齐
+func (r *RuleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RuleConfig
+	var rule plain
+	if err := applyDefaults(&rule); err != nil {
+		return err
+	}
+	if err := unmarshal(&rule); err != nil {
+		return err
+	}
+	*r = RuleConfig(rule)
+	return nil
+}
+
+// validateRules 校验规则名称唯一且正则表达式有效
+func validateRules(rules []RuleConfig) error {
+	seen := make(map[string]bool)
+	for _, name := range builtinJails {
+		seen[name] = true
+	}
+	for i, rule := range rules {
+		if seen[rule.Name] {
+			return fmt.Errorf("规则配置错误: [%d].name重复或与内置jail重名: %s", i, rule.Name)
+		}
+		seen[rule.Name] = true
+		if _, err := CompileRulePattern(rule.Pattern); err != nil {
+			return fmt.Errorf("规则配置错误: [%d].pattern%v", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// applyDefaults 按default标签递归填充结构体字段
+// 结构体类型字段的default标签为YAML，在其各项按自身的标签填充后覆盖
+// 参数:
+//   - v: 指向结构体的指针
+// 返回:
+//   - error: 默认值无法解析时的错误信息
+func applyDefaults(v interface{}) error {
+	return setDefaults(reflect.ValueOf(v).Elem(), "")
+}
+
+func setDefaults(v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		key := joinKey(path, yamlKey(field))
+
+		if field.Type.Kind() == reflect.Struct {
+			if err := setDefaults(fv, key); err != nil {
+				return err
+			}
+			if def, ok := field.Tag.Lookup("default"); ok {
+				if err := yaml.Unmarshal([]byte(def), fv.Addr().Interface()); err != nil {
+					return fmt.Errorf("%s: %v", key, err)
+				}
+			}
+			continue
+		}
+
+		def, ok := field.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if err := setScalar(fv, def); err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// setScalar 将字符串形式的默认值写入字段
+// 切片和映射类型的默认值使用YAML语法书写，Duration类型写作时长字符串
+func setScalar(fv reflect.Value, s string) error {
+	if fv.Type() == durationType {
+		d, err := ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice, reflect.Map:
+		return yaml.Unmarshal([]byte(s), fv.Addr().Interface())
+	default:
+		return fmt.Errorf("不支持的默认值类型 %s", fv.Kind())
+	}
+	return nil
+}
+
+// validateTags 按validate标签递归校验配置
+// 错误信息格式为"<配置段>配置错误: <键名><原因>"
+// 参数:
+//   - config: 待校验的配置
+// 返回:
+//   - error: 第一个不满足规则的配置项
+func validateTags(config *Config) error {
+	v := reflect.ValueOf(config).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		label := field.Tag.Get("label")
+		if err := validateValue(v.Field(i), field, "", label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, field reflect.StructField, path, label string) error {
+	if rules := field.Tag.Get("validate"); rules != "" {
+		for _, rule := range strings.Split(rules, ",") {
+			if msg := checkRule(v, rule); msg != "" {
+				return fmt.Errorf("%s配置错误: %s%s", label, path, msg)
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return validateStruct(v, path, label)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := validateStruct(v.Index(i), fmt.Sprintf("%s[%d]", path, i), label); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateStruct(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()), label); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, path, label string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if err := validateValue(v.Field(i), field, joinKey(path, yamlKey(field)), label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRule 检查单条校验规则
+// 返回:
+//   - string: 不满足规则时的原因描述，满足时为空
+func checkRule(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+	switch name {
+	case "required":
+		if v.IsZero() || ((v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0) {
+			return "不能为空"
+		}
+	case "gt", "gte", "lte":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return fmt.Sprintf("校验规则错误: %s", rule)
+		}
+		n, ok := numeric(v)
+		if !ok {
+			return ""
+		}
+		if name == "gt" && n <= limit {
+			return fmt.Sprintf("必须大于%s", arg)
+		}
+		if name == "gte" && n < limit {
+			return fmt.Sprintf("不能小于%s", arg)
+		}
+		if name == "lte" && n > limit {
+			return fmt.Sprintf("不能大于%s", arg)
+		}
+	case "oneof":
+		if v.Kind() != reflect.String || v.String() == "" {
+			return ""
+		}
+		options := strings.Split(arg, "|")
+		for _, option := range options {
+			if v.String() == option {
+				return ""
+			}
+		}
+		return fmt.Sprintf("必须是以下值之一: %s", strings.Join(options, ", "))
+	case "ip":
+		for _, s := range stringValues(v) {
+			if net.ParseIP(s) == nil {
+				return fmt.Sprintf("包含无效的IP地址: %s", s)
+			}
+		}
+	case "cidr":
+		for _, s := range stringValues(v) {
+			if !IsIPOrCIDR(s) {
+				return fmt.Sprintf("包含无效的IP或CIDR: %s", s)
+			}
+		}
+	case "clock":
+		for _, s := range stringValues(v) {
+			if _, err := time.Parse(ClockLayout, s); err != nil {
+				return fmt.Sprintf("必须是HH:MM格式的时间: %s", s)
+			}
+		}
+	case "cron":
+		for _, s := range stringValues(v) {
+			if _, err := ParseSchedule(s); err != nil {
+				return fmt.Sprintf("不是有效的cron表达式，%v", err)
+			}
+		}
+	}
+	return ""
+}
+
+// ClockLayout 配置中每日定时任务的时间格式
+const ClockLayout = "15:04"
+
+// IsIPOrCIDR 检查字符串是否为IP地址或CIDR网段
+// 参数:
+//   - s: 待检查的字符串
+// 返回:
+//   - bool: 是IP或CIDR时为true
+func IsIPOrCIDR(s string) bool {
+	if net.ParseIP(s) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+// ParseNetwork 将IP或CIDR解析为网段，单个IP解析为/32或/128网段
+// 参数:
+//   - entry: IP地址或CIDR
+// 返回:
+//   - *net.IPNet: 解析出的网段
+//   - error: 格式无效时的错误信息
+func ParseNetwork(entry string) (*net.IPNet, error) {
+	if ip := net.ParseIP(entry); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip = ip.To4()
+			bits = 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, network, err := net.ParseCIDR(entry)
+	if err != nil {
+		return nil, fmt.Errorf("无效的IP或CIDR: %s", entry)
+	}
+	return network, nil
+}
+
+// stringValues 返回字符串或字符串列表字段中的所有值
+func stringValues(v reflect.Value) []string {
+	switch {
+	case v.Kind() == reflect.String && v.String() != "":
+		return []string{v.String()}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).String()
+		}
+		return values
+	}
+	return nil
+}
+
+// describeRules 将validate标签转换为可读的说明
+func describeRules(rules string) string {
+	if rules == "" {
+		return ""
+	}
+	var parts []string
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			parts = append(parts, "必填")
+		case "gt":
+			parts = append(parts, "必须大于"+arg)
+		case "gte":
+			parts = append(parts, "不能小于"+arg)
+		case "lte":
+			parts = append(parts, "不能大于"+arg)
+		case "oneof":
+			parts = append(parts, "可选值: "+strings.ReplaceAll(arg, "|", ", "))
+		case "ip":
+			parts = append(parts, "有效的IP地址")
+		case "cidr":
+			parts = append(parts, "有效的IP或CIDR")
+		case "clock":
+			parts = append(parts, "HH:MM格式的时间")
+		case "cron":
+			parts = append(parts, "cron表达式")
+		default:
+			parts = append(parts, rule)
+		}
+	}
+	return strings.Join(parts, "；")
+}
+
+func numeric(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func yamlKey(field reflect.StructField) string {
+	tag := field.Tag.Get("yaml")
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
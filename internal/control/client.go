@@ -0,0 +1,255 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/logging"
+)
+
+// Client 本地控制接口客户端
+type Client struct {
+	httpClient *http.Client // 通过unix socket通信的HTTP客户端
+}
+
+// NewClient 创建一个新的控制接口客户端
+// 参数:
+//   - socketPath: 守护进程的unix socket路径
+// 返回:
+//   - *Client: 初始化后的客户端实例
+func NewClient(socketPath string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Client{
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   10 * time.Second,
+		},
+	}
+}
+
+// Bans 查询当前所有封禁
+// 返回:
+//   - []BanInfo: 封禁列表
+//   - error: 请求过程中的错误信息
+func (c *Client) Bans() ([]BanInfo, error) {
+	var bans []BanInfo
+	err := c.do(http.MethodGet, "/v1/bans", &bans)
+	return bans, err
+}
+
+// Ban 请求守护进程封禁IP
+// 参数:
+//   - ip: 要封禁的IP地址
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) Ban(ip string) error {
+	return c.do(http.MethodPost, "/v1/bans/"+url.PathEscape(ip), nil)
+}
+
+// BanFor 请求守护进程按指定时长封禁IP，时长按秒向上取整
+// 参数:
+//   - ip: 要封禁的IP地址
+//   - duration: 封禁时长，为0时使用守护进程配置的封禁时长
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) BanFor(ip string, duration time.Duration) error {
+	if duration <= 0 {
+		return c.Ban(ip)
+	}
+	d := config.Duration((duration + time.Second - 1).Truncate(time.Second))
+	return c.do(http.MethodPost, fmt.Sprintf("/v1/bans/%s?duration=%s", url.PathEscape(ip), url.QueryEscape(d.String())), nil)
+}
+
+// Unban 请求守护进程解除IP封禁
+// 参数:
+//   - ip: 要解除封禁的IP地址
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) Unban(ip string) error {
+	return c.do(http.MethodDelete, "/v1/bans/"+url.PathEscape(ip), nil)
+}
+
+// Events 查询序号大于since的最近事件
+// 参数:
+//   - since: 已读取的最大事件序号
+// 返回:
+//   - []event.Event: 新事件列表
+//   - error: 请求过程中的错误信息
+func (c *Client) Events(since uint64) ([]event.Event, error) {
+	var events []event.Event
+	err := c.do(http.MethodGet, fmt.Sprintf("/v1/events?since=%d", since), &events)
+	return events, err
+}
+
+// Logs 查询序号大于since的最近日志
+// 参数:
+//   - since: 已读取的最大日志序号
+// 返回:
+//   - []logging.Entry: 新日志列表
+//   - error: 请求过程中的错误信息
+func (c *Client) Logs(since uint64) ([]logging.Entry, error) {
+	var entries []logging.Entry
+	err := c.do(http.MethodGet, fmt.Sprintf("/v1/logs?since=%d", since), &entries)
+	return entries, err
+}
+
+// TopAttackers 查询累计失败次数最多的IP
+// 参数:
+//   - limit: 返回的最大条数
+// 返回:
+//   - []Attacker: 攻击来源列表
+//   - error: 请求过程中的错误信息
+func (c *Client) TopAttackers(limit int) ([]Attacker, error) {
+	var attackers []Attacker
+	err := c.do(http.MethodGet, fmt.Sprintf("/v1/attackers?limit=%d", limit), &attackers)
+	return attackers, err
+}
+
+// AddWhitelist 请求守护进程添加白名单条目
+// 参数:
+//   - entry: IP或CIDR网段
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) AddWhitelist(entry string) error {
+	return c.doJSON(http.MethodPost, "/v1/whitelist", WhitelistRequest{Entry: entry}, nil)
+}
+
+// RemoveWhitelist 请求守护进程删除白名单条目
+// 参数:
+//   - entry: IP或CIDR网段
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) RemoveWhitelist(entry string) error {
+	return c.doJSON(http.MethodDelete, "/v1/whitelist", WhitelistRequest{Entry: entry}, nil)
+}
+
+// MakePermanent 请求守护进程将IP提升为永久封禁
+// 参数:
+//   - ip: 要永久封禁的IP地址
+// 返回:
+//   - error: 请求过程中的错误信息，无法连接时包装ErrUnreachable
+func (c *Client) MakePermanent(ip string) error {
+	return c.do(http.MethodPost, "/v1/bans/"+url.PathEscape(ip)+"/permanent", nil)
+}
+
+// Jails 查询所有jail及其启用状态
+// 返回:
+//   - []JailInfo: jail列表
+//   - error: 请求过程中的错误信息
+func (c *Client) Jails() ([]JailInfo, error) {
+	var jails []JailInfo
+	err := c.do(http.MethodGet, "/v1/jails", &jails)
+	return jails, err
+}
+
+// SetJailEnabled 请求守护进程启用或停用jail
+// 参数:
+//   - name: jail名称
+//   - enabled: true为启用，false为停用
+// 返回:
+//   - error: 请求过程中的错误信息
+func (c *Client) SetJailEnabled(name string, enabled bool) error {
+	action := "disable"
+	if enabled {
+		action = "enable"
+	}
+	return c.do(http.MethodPost, "/v1/jails/"+url.PathEscape(name)+"/"+action, nil)
+}
+
+// Status 查询守护进程的运行状态
+// 返回:
+//   - Status: 运行状态
+//   - error: 请求过程中的错误信息
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.do(http.MethodGet, "/v1/status", &status)
+	return status, err
+}
+
+// Stats 查询封禁与登录失败的统计
+// 返回:
+//   - Stats: 统计
+//   - error: 请求过程中的错误信息
+func (c *Client) Stats() (Stats, error) {
+	var stats Stats
+	err := c.do(http.MethodGet, "/v1/stats", &stats)
+	return stats, err
+}
+
+// StatsHistory 查询按小时或按天汇总的滚动统计
+// 参数:
+//   - by: hour 返回最近24小时，day 返回最近7天
+// 返回:
+//   - StatsHistory: 统计结果
+//   - error: 请求过程中的错误信息
+func (c *Client) StatsHistory(by string) (StatsHistory, error) {
+	var history StatsHistory
+	err := c.do(http.MethodGet, "/v1/stats/history?by="+url.QueryEscape(by), &history)
+	return history, err
+}
+
+// do 发送不带请求体的请求并解析JSON响应
+func (c *Client) do(method, path string, out interface{}) error {
+	return c.doJSON(method, path, nil, out)
+}
+
+// doJSON 发送请求并解析JSON响应，in不为nil时作为JSON请求体
+func (c *Client) doJSON(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, "http://ssh_fb"+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取控制接口响应失败: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e errorResponse
+		if json.Unmarshal(data, &e) == nil && e.Error != "" {
+			return errors.New(e.Error)
+		}
+		return fmt.Errorf("控制接口返回错误状态: %s", resp.Status)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("解析控制接口响应失败: %v", err)
+		}
+	}
+	return nil
+}
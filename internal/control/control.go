@@ -0,0 +1,362 @@
+// Package control 提供守护进程的本地控制接口
+// 守护进程在unix socket上提供HTTP/JSON接口，命令行和其他组件通过它管理运行中的监控器；
+// 也可以在本机回环地址上以Token认证提供同样的接口，供脚本和网页界面使用
+package control
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/logging"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// ErrUnreachable 表示无法连接到守护进程
+var ErrUnreachable = errors.New("无法连接到守护进程")
+
+// Controller 定义守护进程对外提供的管理操作
+// 由监控器实现，控制接口与Telegram命令均通过它操作封禁状态
+type Controller interface {
+	// Bans 返回当前所有封禁
+	Bans() []BanInfo
+	// Ban 封禁IP，duration为0时使用配置的封禁时长
+	Ban(ip string, duration time.Duration) error
+	// Unban 解除IP的封禁（包括永久封禁）
+	Unban(ip string) error
+	// MakePermanent 将IP提升为永久封禁
+	MakePermanent(ip string) error
+	// Events 返回序号大于since的最近事件
+	Events(since uint64) []event.Event
+	// TopAttackers 返回累计失败次数最多的IP
+	TopAttackers(limit int) []Attacker
+	// AddWhitelist 添加白名单条目（IP或CIDR）
+	AddWhitelist(entry string) error
+	// RemoveWhitelist 删除运行时添加的白名单条目或临时白名单
+	RemoveWhitelist(entry string) error
+	// AllowTemporary 添加有有效期的白名单条目，条目已存在时续期，返回到期时间
+	AllowTemporary(entry, requestedBy string) (time.Time, error)
+	// Jails 返回所有jail及其启用状态
+	Jails() []JailInfo
+	// SetJailEnabled 启用或停用jail，状态会持久化
+	SetJailEnabled(name string, enabled bool) error
+	// Status 返回守护进程的运行状态
+	Status() Status
+	// Stats 返回封禁与登录失败的统计
+	Stats() Stats
+	// StatsHistory 返回按小时（最近24小时）或按天（最近7天）汇总的统计，by为hour或day
+	StatsHistory(by string) (StatsHistory, error)
+	// Health 检查日志读取、登录事件处理、防火墙与Telegram是否正常
+	Health() HealthReport
+	// Live 订阅实时的登录事件与封禁、解封、限速等事件，通道缓冲已满时新消息被丢弃，返回的函数用于取消订阅
+	Live(buffer int) (<-chan LiveMessage, func())
+}
+
+// WhitelistRequest 添加白名单的请求体
+type WhitelistRequest struct {
+	Entry string `json:"entry"` // IP或CIDR网段
+}
+
+// BanRequest POST /v1/bans 的请求体
+type BanRequest struct {
+	IP        string `json:"ip"`                  // 要封禁的IP地址
+	Duration  string `json:"duration,omitempty"`  // 封禁时长，如 30m、12h、7d，优先于hours
+	Hours     int    `json:"hours,omitempty"`     // 封禁时长（小时），与duration都为空时使用配置的封禁时长
+	Permanent bool   `json:"permanent,omitempty"` // 是否永久封禁，为true时忽略duration与hours
+}
+
+// Health GET /v1/health 的响应，只表示守护进程正在提供接口，不需要认证
+type Health struct {
+	Status  string    `json:"status"`  // 固定为ok
+	Started time.Time `json:"started"` // 接口开始服务的时间
+}
+
+// LogSource 提供最近的结构化日志
+type LogSource interface {
+	// Since 返回序号大于seq的日志
+	Since(seq uint64) []logging.Entry
+}
+
+// BanInfo 封禁信息
+type BanInfo struct {
+	IP         string    `json:"ip"`                    // 被封禁的IP
+	Permanent  bool      `json:"permanent"`             // 是否永久封禁
+	Limited    bool      `json:"limited,omitempty"`     // 是否只是限速，未完全封禁
+	ExpireTime time.Time `json:"expire_time,omitempty"` // 解封时间，永久封禁时为零值
+
+	// 以下为封禁时记录的原因与来源信息，存储中没有记录时为空
+	Reason      string `json:"reason,omitempty"`       // 封禁原因
+	Country     string `json:"country,omitempty"`      // 国家代码
+	Hostname    string `json:"hostname,omitempty"`     // 反向解析得到的主机名
+	ASN         string `json:"asn,omitempty"`          // 自治系统号
+	ASOrg       string `json:"as_org,omitempty"`       // 自治系统名称
+	NetworkType string `json:"network_type,omitempty"` // 网络类型：hosting、residential
+	Origin      string `json:"origin,omitempty"`       // 从集群同步的封禁所来自的服务器
+}
+
+// LiveMessage GET /v1/live 推送的一条消息
+type LiveMessage struct {
+	Kind  string       `json:"kind"`            // login 或 event
+	Login *LiveLogin   `json:"login,omitempty"` // kind为login时，处理完的一次登录相关事件
+	Event *event.Event `json:"event,omitempty"` // kind为event时，封禁、解封、限速、告警等事件，不包括与login重复的登录事件
+}
+
+// LiveLogin 从日志行中解析出并处理完的一次登录相关事件
+type LiveLogin struct {
+	Time        time.Time `json:"time"`                   // 日志行记录的时间
+	Outcome     string    `json:"outcome"`                // success、failure、logout、preauth、connect 或 client
+	IP          string    `json:"ip,omitempty"`           // 客户端IP，已解析为真实客户端IP
+	Port        int       `json:"port,omitempty"`         // 客户端源端口
+	User        string    `json:"user,omitempty"`         // 用户名
+	InvalidUser bool      `json:"invalid_user,omitempty"` // 用户名在系统中不存在
+	Method      string    `json:"method,omitempty"`       // 认证方式，如password、publickey
+	Client      string    `json:"client,omitempty"`       // 客户端版本标识
+	PID         int       `json:"pid,omitempty"`          // sshd进程ID
+	Counted     bool      `json:"counted,omitempty"`      // 是否计入登录失败次数
+}
+
+// Attacker 攻击来源统计
+type Attacker struct {
+	IP       string `json:"ip"`       // 攻击来源IP
+	Failures int    `json:"failures"` // 累计失败次数
+	Banned   bool   `json:"banned"`   // 当前是否被封禁
+}
+
+// HealthReport GET /healthz 与 GET /readyz 的响应
+type HealthReport struct {
+	Status string        `json:"status"` // ok 或 failing，任一检查项失败时为failing
+	Ready  bool          `json:"ready"`  // 启动时的加载是否已完成、是否已开始处理日志
+	Checks []HealthCheck `json:"checks"` // 各检查项的结果
+}
+
+// HealthCheck 一个健康检查项
+type HealthCheck struct {
+	Name   string `json:"name"`             // 检查项：monitor、jail:<名称>、firewall、telegram
+	OK     bool   `json:"ok"`               // 是否正常
+	Detail string `json:"detail,omitempty"` // 异常原因
+}
+
+// Stats 封禁与登录失败的统计，不查询防火墙等外部状态，适合频繁轮询
+type Stats struct {
+	Banned           int                `json:"banned"`             // 当前封禁的IP数，包括永久封禁
+	Permanent        int                `json:"permanent"`          // 其中永久封禁的IP数
+	Limited          int                `json:"limited"`            // 当前被限速的IP数
+	FailuresLastHour int                `json:"failures_last_hour"` // 最近一小时计入封禁的登录失败次数
+	Attackers        int                `json:"attackers"`          // 启动以来登录失败过的IP数
+	Events           map[event.Type]int `json:"events"`             // 最近24小时存储中各类事件的数量
+	TopAttackers     []Attacker         `json:"top_attackers"`      // 累计失败次数最多的10个IP
+}
+
+// StatsHistory GET /v1/stats/history 的响应，按小时或按天汇总的滚动统计
+type StatsHistory struct {
+	By           string        `json:"by"`            // 统计粒度：hour 或 day
+	Buckets      []StatsBucket `json:"buckets"`       // 各时间段的统计，按时间从早到晚排列，最后一段为当前未结束的时间段
+	Failures     int           `json:"failures"`      // 整个范围内的登录失败次数
+	Bans         int           `json:"bans"`          // 整个范围内的封禁次数
+	UniqueIPs    int           `json:"unique_ips"`    // 整个范围内登录失败过的IP数
+	TopUsers     []NamedCount  `json:"top_users"`     // 登录失败次数最多的用户名
+	TopCountries []NamedCount  `json:"top_countries"` // 登录失败次数最多的国家
+}
+
+// StatsBucket 一个时间段的统计
+type StatsBucket struct {
+	Start     time.Time `json:"start"`      // 时间段开始时间
+	Failures  int       `json:"failures"`   // 登录失败次数
+	Bans      int       `json:"bans"`       // 封禁次数
+	UniqueIPs int       `json:"unique_ips"` // 登录失败过的IP数
+}
+
+// NamedCount 排行中的一项
+type NamedCount struct {
+	Name  string `json:"name"`  // 用户名或国家
+	Count int    `json:"count"` // 登录失败次数
+}
+
+// Status 守护进程运行状态
+type Status struct {
+	Started          time.Time      `json:"started"`            // 监控器启动时间
+	Banned           int            `json:"banned"`             // 当前封禁的IP数，包括永久封禁
+	Permanent        int            `json:"permanent"`          // 其中永久封禁的IP数
+	Limited          int            `json:"limited"`            // 当前被限速的IP数
+	FailuresLastHour int            `json:"failures_last_hour"` // 最近一小时计入封禁的登录失败次数
+	Firewall         FirewallStatus `json:"firewall"`           // 防火墙状态
+	Jails            []JailInfo     `json:"jails"`              // 各jail的日志读取状态
+
+	IPInfo ipinfo.Stats `json:"ip_info"` // IP信息接口的调用统计与熔断状态
+	LogLag LagStats     `json:"log_lag"` // sshd日志的处理延迟
+
+	BanLatency BanLatencyStats `json:"ban_latency"` // 封禁生效耗时
+	Sampling   SamplingStats   `json:"sampling"`    // 登录失败事件的抽样统计
+
+	Feeds []FeedStatus `json:"feeds,omitempty"` // 订阅黑名单的刷新状态
+
+	Forwarder      *ForwarderStatus `json:"forwarder,omitempty"`       // agent模式下转发到集中监控的状态
+	Agents         []AgentStatus    `json:"agents,omitempty"`          // server模式下各agent的统计
+	FleetAttackers []FleetAttacker  `json:"fleet_attackers,omitempty"` // server模式下攻击过多台服务器的来源
+
+	Channels []ChannelHealth `json:"channels"` // 各通知渠道的健康状态
+
+	Resources Resources `json:"resources"` // 守护进程自身的资源占用
+}
+
+// Resources 守护进程自身的资源占用，用于在小内存VPS上提前发现容量问题
+type Resources struct {
+	RSS           int64  `json:"rss"`             // 常驻内存（字节），无法读取时为0
+	HeapAlloc     uint64 `json:"heap_alloc"`      // Go堆上正在使用的内存（字节）
+	Goroutines    int    `json:"goroutines"`      // 协程数
+	OpenFiles     int    `json:"open_files"`      // 打开的文件描述符数，无法读取时为-1
+	MaxOpenFiles  int    `json:"max_open_files"`  // 文件描述符数量的软限制，无法读取时为0
+	StoreBytes    int64  `json:"store_bytes"`     // 存储文件占用的磁盘空间（字节），memory和redis存储为0
+	LoginQueue    int    `json:"login_queue"`     // 等待处理的登录事件数
+	LoginQueueCap int    `json:"login_queue_cap"` // 登录事件队列的容量
+	NotifyQueue   int    `json:"notify_queue"`    // 各通知渠道等待发送和等待重试的通知总数
+	EventQueue    int    `json:"event_queue"`     // 等待写入存储和日志的事件数
+	EventQueueCap int    `json:"event_queue_cap"` // 事件写入队列的容量
+	EventsDropped int64  `json:"events_dropped"`  // 因事件写入队列已满未写入的事件数，自启动起
+	TrackedIPs    int    `json:"tracked_ips"`     // 记录了失败次数的IP数
+	MaxTrackedIPs int    `json:"max_tracked_ips"` // 记录失败次数的IP数上限，0表示不限制
+	EvictedIPs    int64  `json:"evicted_ips"`     // 因超出上限被清除失败次数的IP数，自启动起
+	TempBans      int    `json:"temp_bans"`       // 限时封禁数，包括网段
+	MaxTempBans   int    `json:"max_temp_bans"`   // 限时封禁数上限，0表示不限制
+	EvictedBans   int64  `json:"evicted_bans"`    // 因超出上限提前解除的封禁数，自启动起
+}
+
+// FeedStatus 一个订阅黑名单的刷新状态
+type FeedStatus struct {
+	Name        string    `json:"name"`                 // 订阅名称
+	URL         string    `json:"url"`                  // 下载地址
+	Entries     int       `json:"entries"`              // 写入防火墙的条目数
+	Whitelisted int       `json:"whitelisted"`          // 与白名单重叠而跳过的条目数
+	Invalid     int       `json:"invalid"`              // 无法解析的行数
+	Updated     time.Time `json:"updated,omitempty"`    // 最近一次成功写入防火墙的时间
+	Checked     time.Time `json:"checked,omitempty"`    // 最近一次下载的时间，内容未变化时只更新该时间
+	Next        time.Time `json:"next,omitempty"`       // 下一次下载的时间
+	LastError   string    `json:"last_error,omitempty"` // 最近一次刷新失败的原因，成功后清空
+}
+
+// ForwarderStatus agent转发事件的状态，计数自守护进程启动起
+type ForwarderStatus struct {
+	Server    string    `json:"server"`               // 集中监控server的地址
+	Queued    int       `json:"queued"`               // 等待转发的事件数
+	Sent      int64     `json:"sent"`                 // 已转发的事件数
+	Dropped   int64     `json:"dropped"`              // 因队列已满丢弃的事件数
+	LastSent  time.Time `json:"last_sent,omitempty"`  // 最近一次转发成功的时间
+	LastError string    `json:"last_error,omitempty"` // 最近一次转发失败的原因，成功后清空
+}
+
+// AgentStatus 集中监控中一个agent的统计，计数自server启动起，抽样的事件按其代表的事件数计
+type AgentStatus struct {
+	Host      string    `json:"host"`      // agent的名称
+	Addr      string    `json:"addr"`      // 最近一次提交事件的地址
+	LastSeen  time.Time `json:"last_seen"` // 最近一次提交事件的时间
+	Events    int64     `json:"events"`    // 收到的事件数
+	Failures  int64     `json:"failures"`  // 其中登录失败的次数
+	Successes int64     `json:"successes"` // 其中登录成功的次数
+	Bans      int64     `json:"bans"`      // 其中封禁的次数
+	Dropped   int64     `json:"dropped"`   // agent报告的因队列已满丢弃的事件数
+}
+
+// FleetAttacker 最近24小时内在多台服务器上登录失败的来源
+type FleetAttacker struct {
+	IP       string   `json:"ip"`       // 攻击来源IP
+	Hosts    []string `json:"hosts"`    // 登录失败的服务器
+	Failures int64    `json:"failures"` // 所有服务器上的登录失败次数
+}
+
+// SamplingStats 登录失败事件的抽样统计，计数自守护进程启动起
+type SamplingStats struct {
+	Enabled bool  `json:"enabled"` // 是否启用抽样
+	Rate    int   `json:"rate"`    // 每多少个事件保存1个
+	Seen    int64 `json:"seen"`    // 参与抽样的登录失败事件数
+	Kept    int64 `json:"kept"`    // 其中保存和通知的事件数
+}
+
+// FirewallStatus 防火墙状态
+type FirewallStatus struct {
+	Backend string `json:"backend"`         // 防火墙类型，目前只有ufw
+	Active  bool   `json:"active"`          // 规则是否正在生效
+	Error   string `json:"error,omitempty"` // 无法查询状态时的原因
+}
+
+// ChannelHealth 通知渠道的健康状态
+type ChannelHealth struct {
+	Name                string    `json:"name"`                   // 渠道名称
+	State               string    `json:"state"`                  // healthy、failing 或 disabled
+	Sent                int       `json:"sent"`                   // 发送成功的次数
+	Failed              int       `json:"failed"`                 // 发送失败的次数
+	Skipped             int       `json:"skipped"`                // 暂停期间推迟发送的次数
+	Queued              int       `json:"queued"`                 // 等待首次发送的通知数
+	Pending             int       `json:"pending"`                // 等待重试的通知数
+	ConsecutiveFailures int       `json:"consecutive_failures"`   // 连续失败次数
+	LastError           string    `json:"last_error,omitempty"`   // 最近一次失败的原因
+	LastSuccess         time.Time `json:"last_success,omitempty"` // 最近一次发送成功的时间
+	LastFailure         time.Time `json:"last_failure,omitempty"` // 最近一次发送失败的时间
+	NextProbe           time.Time `json:"next_probe,omitempty"`   // 暂停期间下一次探测的时间
+
+	Updates *UpdatesHealth `json:"updates,omitempty"` // 接收管理命令的长轮询状态，只有telegram渠道有
+}
+
+// UpdatesHealth Telegram接收管理命令的长轮询循环的健康状态
+type UpdatesHealth struct {
+	Running             bool      `json:"running"`                // 循环是否在运行
+	LastPoll            time.Time `json:"last_poll,omitempty"`    // 最近一次成功拉取更新的时间
+	ConsecutiveFailures int       `json:"consecutive_failures"`   // 连续拉取失败的次数
+	Failures            int       `json:"failures"`               // 累计拉取失败的次数
+	Panics              int       `json:"panics"`                 // 处理单条更新时恢复的异常次数
+	LastError           string    `json:"last_error,omitempty"`   // 最近一次拉取失败的原因
+	LastFailure         time.Time `json:"last_failure,omitempty"` // 最近一次拉取失败的时间
+}
+
+// LagStats 日志处理延迟统计，延迟为处理登录事件的时间与日志行自身时间之差
+type LagStats struct {
+	Count   int64       `json:"count"`   // 已统计的日志行数
+	Sum     float64     `json:"sum"`     // 延迟总和（秒）
+	Max     float64     `json:"max"`     // 最大延迟（秒）
+	Last    float64     `json:"last"`    // 最近一行的延迟（秒）
+	Queue   int         `json:"queue"`   // 等待处理的登录事件数
+	Alerts  int         `json:"alerts"`  // 已发出的延迟告警次数
+	Buckets []LagBucket `json:"buckets"` // 延迟分布
+}
+
+// BanLatencyStats 封禁生效耗时统计，基于最近的若干次自动封禁
+type BanLatencyStats struct {
+	Count    int64        `json:"count"`    // 启动以来统计的封禁次数
+	SLO      float64      `json:"slo"`      // 耗时目标（秒），0表示不检查
+	OverSLO  int64        `json:"over_slo"` // 耗时超过目标的封禁次数
+	Total    LatencyStats `json:"total"`    // 从读取到日志行到防火墙规则生效
+	Firewall LatencyStats `json:"firewall"` // 其中防火墙操作本身的耗时
+}
+
+// LatencyStats 一组耗时样本的分位数（秒）
+type LatencyStats struct {
+	P50  float64 `json:"p50"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+	Last float64 `json:"last"`
+}
+
+// LagBucket 延迟分布中的一个区间
+type LagBucket struct {
+	LE    float64 `json:"le,omitempty"` // 区间上限（秒），最后一个区间没有上限，为0
+	Count int64   `json:"count"`        // 延迟落在上一个区间上限与本区间上限之间的行数
+}
+
+// JailInfo jail状态
+type JailInfo struct {
+	Name    string `json:"name"`    // jail名称
+	Enabled bool   `json:"enabled"` // 是否启用
+
+	Running   bool      `json:"running"`              // 日志读取协程是否正在运行
+	Workers   int       `json:"workers"`              // 处理协程数
+	Lines     int64     `json:"lines"`                // 已读取的日志行数
+	LastRead  time.Time `json:"last_read,omitempty"`  // 最近一次读取到日志行的时间
+	Queue     int       `json:"queue"`                // 等待处理的日志行数
+	Restarts  int64     `json:"restarts"`             // 日志读取中断后重启的次数
+	Panics    int64     `json:"panics"`               // 处理日志时发生panic的次数
+	LastError string    `json:"last_error,omitempty"` // 最近一次读取中断或panic的原因
+}
+
+// errorResponse 接口返回的错误信息
+type errorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,20 @@
+package control
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles 网页管理界面的静态文件，页面通过 /v1/ 下的接口读取数据
+//
+//go:embed web
+var webFiles embed.FS
+
+// EnableDashboard 在 /ui/ 下提供网页管理界面，需在Start之前调用
+// 静态文件不需要认证，页面中输入的Token保存在浏览器中，用于调用接口
+func (s *Server) EnableDashboard() {
+	root, _ := fs.Sub(webFiles, "web")
+	s.mux.Handle("/ui/", http.StripPrefix("/ui/", http.FileServer(http.FS(root))))
+	s.dashboard = true
+}
@@ -0,0 +1,97 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// liveBuffer 每个实时流连接的消息缓冲长度，客户端读取不及时导致缓冲已满时新消息被丢弃
+const liveBuffer = 256
+
+// sseKeepalive 没有消息时发送SSE注释的间隔，避免代理因连接空闲而断开
+const sseKeepalive = 30 * time.Second
+
+// handleLive 处理 GET /v1/live 请求，实时推送每个处理完的登录事件和封禁、解封、限速等事件
+// 请求为WebSocket握手时每条消息为一个JSON文本帧，否则以Server-Sent Events推送，
+// 事件名为消息的kind，数据为JSON编码的消息；连接之前的消息不会补发
+func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	if headerContains(r.Header, "Upgrade", "websocket") {
+		s.serveLiveWebSocket(w, r)
+		return
+	}
+	s.serveLiveSSE(w, r)
+}
+
+// serveLiveWebSocket 通过WebSocket推送实时消息，直到客户端关闭连接或写入失败
+func (s *Server) serveLiveWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	messages, cancel := s.controller.Live(liveBuffer)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		conn.discard()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-messages:
+			if err := conn.writeJSON(msg); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// serveLiveSSE 以Server-Sent Events推送实时消息，直到客户端断开或写入失败
+func (s *Server) serveLiveSSE(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("连接不支持流式响应"))
+		return
+	}
+	messages, cancel := s.controller.Live(liveBuffer)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	controller := http.NewResponseController(w)
+	keepalive := time.NewTicker(sseKeepalive)
+	defer keepalive.Stop()
+	for {
+		var frame string
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			frame = ": keepalive\n\n"
+		case msg := <-messages:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			frame = fmt.Sprintf("event: %s\ndata: %s\n\n", msg.Kind, data)
+		}
+		controller.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
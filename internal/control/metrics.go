@@ -0,0 +1,52 @@
+package control
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// metric 一项Prometheus指标
+type metric struct {
+	name  string  // 指标名，不含ssh_fb_前缀
+	kind  string  // gauge或counter
+	help  string  // 说明
+	value float64 // 当前值
+}
+
+// resourceMetrics 将资源占用转换为Prometheus指标
+// 参数:
+//   - r: 守护进程自身的资源占用
+// 返回:
+//   - []metric: 各项指标
+func resourceMetrics(r Resources) []metric {
+	return []metric{
+		{"tracked_ips", "gauge", "记录了失败次数的IP数", float64(r.TrackedIPs)},
+		{"tracked_ips_max", "gauge", "记录失败次数的IP数上限，0表示不限制", float64(r.MaxTrackedIPs)},
+		{"evicted_ips_total", "counter", "因超出上限被清除失败次数的IP数", float64(r.EvictedIPs)},
+		{"temp_bans", "gauge", "限时封禁数，包括网段", float64(r.TempBans)},
+		{"temp_bans_max", "gauge", "限时封禁数上限，0表示不限制", float64(r.MaxTempBans)},
+		{"evicted_bans_total", "counter", "因超出上限提前解除的封禁数", float64(r.EvictedBans)},
+		{"login_queue", "gauge", "等待处理的登录事件数", float64(r.LoginQueue)},
+		{"event_queue", "gauge", "等待写入存储和日志的事件数", float64(r.EventQueue)},
+		{"events_dropped_total", "counter", "因事件写入队列已满未写入的事件数", float64(r.EventsDropped)},
+		{"notify_queue", "gauge", "各通知渠道等待发送和等待重试的通知总数", float64(r.NotifyQueue)},
+		{"goroutines", "gauge", "协程数", float64(r.Goroutines)},
+		{"heap_alloc_bytes", "gauge", "Go堆上正在使用的内存（字节）", float64(r.HeapAlloc)},
+	}
+}
+
+// handleMetrics 处理 GET /v1/metrics 请求，以Prometheus文本格式输出资源占用
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	status := s.controller.Status()
+	var b strings.Builder
+	for _, m := range resourceMetrics(status.Resources) {
+		fmt.Fprintf(&b, "# HELP ssh_fb_%s %s\n# TYPE ssh_fb_%s %s\nssh_fb_%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
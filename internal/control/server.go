@@ -0,0 +1,438 @@
+package control
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/logging"
+)
+
+// Server 本地控制接口服务端
+type Server struct {
+	socketPath string         // unix socket路径，监听TCP时为空
+	addr       string         // TCP监听地址，监听unix socket时为空
+	token      string         // 监听TCP时请求需要携带的Token
+	controller Controller     // 监控器提供的管理操作
+	logs       LogSource      // 最近日志来源，可为空
+	logger     *logrus.Logger // 日志记录器
+	httpServer *http.Server   // HTTP服务
+	mux        *http.ServeMux // 接口路由
+	started    time.Time      // 创建服务端的时间
+	dashboard  bool           // 是否在 /ui/ 下提供网页管理界面
+}
+
+// NewServer 创建一个监听unix socket的控制接口服务端
+// socket文件只允许所有者访问，请求不需要认证
+// 参数:
+//   - socketPath: unix socket路径
+//   - controller: 管理操作的实现
+//   - logger: 日志记录器
+// 返回:
+//   - *Server: 初始化后的服务端实例
+func NewServer(socketPath string, controller Controller, logger *logrus.Logger) *Server {
+	s := &Server{socketPath: socketPath}
+	s.init(controller, logger)
+	return s
+}
+
+// NewTCPServer 创建一个监听TCP地址的控制接口服务端
+// 除健康检查与网页管理界面的静态文件外，请求需要在 Authorization: Bearer <token> 中携带Token，
+// 浏览器无法为WebSocket和EventSource设置请求头，事件流与实时流也可以通过 ?token= 携带
+// 参数:
+//   - addr: 监听地址，如 127.0.0.1:9700
+//   - token: 认证Token
+//   - controller: 管理操作的实现
+//   - logger: 日志记录器
+// 返回:
+//   - *Server: 初始化后的服务端实例
+func NewTCPServer(addr, token string, controller Controller, logger *logrus.Logger) *Server {
+	s := &Server{addr: addr, token: token}
+	s.init(controller, logger)
+	return s
+}
+
+// init 注册接口路由
+func (s *Server) init(controller Controller, logger *logrus.Logger) {
+	s.controller = controller
+	s.logger = logger
+	s.started = time.Now()
+
+	mux := http.NewServeMux()
+	s.mux = mux
+	mux.HandleFunc("/v1/bans", s.handleBanList)
+	mux.HandleFunc("/v1/bans/", s.handleBans)
+	mux.HandleFunc("/v1/events", s.handleEvents)
+	mux.HandleFunc("/v1/events/stream", s.handleEventStream)
+	mux.HandleFunc("/v1/live", s.handleLive)
+	mux.HandleFunc("/v1/attackers", s.handleAttackers)
+	mux.HandleFunc("/v1/whitelist", s.handleWhitelist)
+	mux.HandleFunc("/v1/logs", s.handleLogs)
+	mux.HandleFunc("/v1/jails", s.handleJailList)
+	mux.HandleFunc("/v1/jails/", s.handleJails)
+	mux.HandleFunc("/v1/status", s.handleStatus)
+	mux.HandleFunc("/v1/stats", s.handleStats)
+	mux.HandleFunc("/v1/stats/history", s.handleStatsHistory)
+	mux.HandleFunc("/v1/metrics", s.handleMetrics)
+	mux.HandleFunc("/v1/health", s.handleHealth)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	s.httpServer = &http.Server{Handler: s.authorize(mux), ReadHeaderTimeout: 10 * time.Second}
+}
+
+// authorize 监听TCP时检查请求携带的Token，健康检查与网页管理界面的静态文件不需要认证
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		public := r.URL.Path == "/v1/health" || r.URL.Path == "/healthz" || r.URL.Path == "/readyz" ||
+			s.dashboard && strings.HasPrefix(r.URL.Path, "/ui/")
+		if s.addr != "" && !public {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if (r.URL.Path == "/v1/events/stream" || r.URL.Path == "/v1/live") && token == "" {
+				token = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				writeError(w, http.StatusUnauthorized, fmt.Errorf("认证失败"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SetLogSource 设置日志来源，用于 GET /v1/logs
+// 参数:
+//   - logs: 最近日志来源
+func (s *Server) SetLogSource(logs LogSource) {
+	s.logs = logs
+}
+
+// Start 监听unix socket或TCP地址并开始处理请求
+// 该方法会阻塞直到服务关闭
+// 返回:
+//   - error: 监听或服务过程中的错误信息
+func (s *Server) Start() error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+	if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("控制接口服务失败: %v", err)
+	}
+	return nil
+}
+
+// listen 按配置创建监听器
+func (s *Server) listen() (net.Listener, error) {
+	if s.addr != "" {
+		listener, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			return nil, fmt.Errorf("监听HTTP接口失败: %v", err)
+		}
+		s.logger.WithField("addr", s.addr).Info("HTTP接口已启动")
+		return listener, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0755); err != nil {
+		return nil, fmt.Errorf("创建控制接口目录失败: %v", err)
+	}
+	// 清理上次异常退出残留的socket文件
+	os.Remove(s.socketPath)
+
+	listener, err := net.Listen("unix", s.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("监听控制接口失败: %v", err)
+	}
+	if err := os.Chmod(s.socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("设置控制接口权限失败: %v", err)
+	}
+	s.logger.WithField("socket", s.socketPath).Info("控制接口已启动")
+	return listener, nil
+}
+
+// Close 关闭控制接口，监听unix socket时删除socket文件
+// 返回:
+//   - error: 关闭过程中的错误信息
+func (s *Server) Close() error {
+	err := s.httpServer.Close()
+	if s.socketPath != "" {
+		os.Remove(s.socketPath)
+	}
+	return err
+}
+
+// handleBanList 处理 GET /v1/bans 与 POST /v1/bans 请求
+func (s *Server) handleBanList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.controller.Bans())
+	case http.MethodPost:
+		var req BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("解析请求失败: %v", err))
+			return
+		}
+		duration, err := banDuration(req.Duration, strconv.Itoa(req.Hours))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if req.Permanent {
+			s.respond(w, nil, s.controller.MakePermanent(req.IP))
+			return
+		}
+		s.respond(w, nil, s.controller.Ban(req.IP, duration))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+	}
+}
+
+// banDuration 解析封禁请求的时长，duration优先，都为空或为0时返回0，即使用配置的封禁时长
+// 参数:
+//   - duration: 时长字符串，如 30m、12h、7d
+//   - hours: 小时数，兼容旧的请求
+// 返回:
+//   - time.Duration: 封禁时长
+//   - error: 时长无效或为负数时的错误信息
+func banDuration(duration, hours string) (time.Duration, error) {
+	if duration != "" {
+		d, err := config.ParseDuration(duration)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("无效的封禁时长: %s", duration)
+		}
+		return d, nil
+	}
+	if hours == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(hours)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("无效的封禁时长: %s", hours)
+	}
+	return time.Duration(n) * time.Hour, nil
+}
+
+// handleBans 处理 /v1/bans/{ip}/... 请求
+func (s *Server) handleBans(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/bans/"), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodPost:
+		query := r.URL.Query()
+		duration, err := banDuration(query.Get("duration"), query.Get("hours"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		s.respond(w, nil, s.controller.Ban(parts[0], duration))
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		s.respond(w, nil, s.controller.Unban(parts[0]))
+	case len(parts) == 2 && parts[1] == "permanent" && r.Method == http.MethodPost:
+		s.respond(w, nil, s.controller.MakePermanent(parts[0]))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("未知的接口: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// handleEvents 处理 GET /v1/events?since=N 请求
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	events := s.controller.Events(since)
+	if events == nil {
+		events = []event.Event{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// handleEventStream 处理 GET /v1/events/stream?since=N 的WebSocket请求
+// 连接后先推送序号大于since的最近事件，之后每秒推送新事件，每条消息为一个JSON编码的事件
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	go func() {
+		conn.discard()
+		close(done)
+	}()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		for _, e := range s.controller.Events(since) {
+			if err := conn.writeJSON(e); err != nil {
+				return
+			}
+			since = e.Seq
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleAttackers 处理 GET /v1/attackers?limit=N 请求
+func (s *Server) handleAttackers(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	writeJSON(w, http.StatusOK, s.controller.TopAttackers(limit))
+}
+
+// handleWhitelist 处理 POST /v1/whitelist 与 DELETE /v1/whitelist 请求
+func (s *Server) handleWhitelist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	var req WhitelistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("解析请求失败: %v", err))
+		return
+	}
+	if r.Method == http.MethodDelete {
+		s.respond(w, nil, s.controller.RemoveWhitelist(req.Entry))
+		return
+	}
+	s.respond(w, nil, s.controller.AddWhitelist(req.Entry))
+}
+
+// handleLogs 处理 GET /v1/logs?since=N 请求
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if s.logs == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("日志流不可用"))
+		return
+	}
+	since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	entries := s.logs.Since(since)
+	if entries == nil {
+		entries = []logging.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleJailList 处理 GET /v1/jails 请求
+func (s *Server) handleJailList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.controller.Jails())
+}
+
+// handleJails 处理 POST /v1/jails/{name}/enable 与 /v1/jails/{name}/disable 请求
+func (s *Server) handleJails(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/jails/"), "/"), "/")
+
+	switch {
+	case len(parts) == 2 && parts[1] == "enable" && r.Method == http.MethodPost:
+		s.respond(w, nil, s.controller.SetJailEnabled(parts[0], true))
+	case len(parts) == 2 && parts[1] == "disable" && r.Method == http.MethodPost:
+		s.respond(w, nil, s.controller.SetJailEnabled(parts[0], false))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("未知的接口: %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// handleStatus 处理 GET /v1/status 请求
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.controller.Status())
+}
+
+// handleStats 处理 GET /v1/stats 请求
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.controller.Stats())
+}
+
+// handleStatsHistory 处理 GET /v1/stats/history?by=hour|day 请求，by默认为hour
+func (s *Server) handleStatsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "hour"
+	}
+	history, err := s.controller.StatsHistory(by)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// handleHealth 处理 GET /v1/health 请求
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("不支持的请求方法: %s", r.Method))
+		return
+	}
+	writeJSON(w, http.StatusOK, Health{Status: "ok", Started: s.started})
+}
+
+// handleHealthz 处理 GET /healthz 请求，任一检查项失败时返回503
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := s.controller.Health()
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// handleReadyz 处理 GET /readyz 请求，启动时的加载未完成时返回503
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := s.controller.Health()
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// respond 输出操作结果，操作失败时返回400
+func (s *Server) respond(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if v == nil {
+		v = struct{}{}
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
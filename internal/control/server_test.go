@@ -0,0 +1,55 @@
+package control
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		tcp       bool // 是否监听TCP
+		dashboard bool
+		path      string
+		header    string
+		want      int
+	}{
+		{name: "unix socket不需要认证", path: "/v1/bans", want: http.StatusOK},
+		{name: "缺少Token", tcp: true, path: "/v1/bans", want: http.StatusUnauthorized},
+		{name: "Token正确", tcp: true, path: "/v1/bans", header: "Bearer secret", want: http.StatusOK},
+		{name: "Token错误", tcp: true, path: "/v1/bans", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "Token为前缀", tcp: true, path: "/v1/bans", header: "Bearer secre", want: http.StatusUnauthorized},
+		{name: "查询参数只用于事件流", tcp: true, path: "/v1/bans?token=secret", want: http.StatusUnauthorized},
+		{name: "事件流的查询参数", tcp: true, path: "/v1/events/stream?token=secret", want: http.StatusOK},
+		{name: "实时流的查询参数", tcp: true, path: "/v1/live?token=secret", want: http.StatusOK},
+		{name: "实时流的查询参数错误", tcp: true, path: "/v1/live?token=wrong", want: http.StatusUnauthorized},
+		{name: "请求头优先于查询参数", tcp: true, path: "/v1/live?token=secret", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "健康检查", tcp: true, path: "/healthz", want: http.StatusOK},
+		{name: "就绪检查", tcp: true, path: "/readyz", want: http.StatusOK},
+		{name: "健康状态", tcp: true, path: "/v1/health", want: http.StatusOK},
+		{name: "指标需要认证", tcp: true, path: "/v1/metrics", want: http.StatusUnauthorized},
+		{name: "网页管理界面的静态文件", tcp: true, dashboard: true, path: "/ui/index.html", want: http.StatusOK},
+		{name: "未启用网页管理界面", tcp: true, path: "/ui/index.html", want: http.StatusUnauthorized},
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{token: "secret", dashboard: tt.dashboard}
+			if tt.tcp {
+				s.addr = "127.0.0.1:9700"
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			s.authorize(ok).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("状态码 %d，期望 %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
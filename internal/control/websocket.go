@@ -0,0 +1,184 @@
+package control
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID RFC 6455中用于计算Sec-WebSocket-Accept的固定字符串
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocketWriteTimeout 单条消息的写超时，客户端长时间不读取时断开连接
+const websocketWriteTimeout = 10 * time.Second
+
+// WebSocket帧的操作码
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// wsConn 只由服务端推送消息的WebSocket连接
+// 只实现推送事件流所需的部分：发送不分片的文本帧，读取并丢弃客户端发来的帧
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // 推送事件与回应ping可能同时写入
+}
+
+// upgradeWebSocket 完成WebSocket握手并接管底层连接
+// 握手失败时已向客户端返回错误响应
+// 参数:
+//   - w: HTTP响应
+//   - r: HTTP请求
+// 返回:
+//   - *wsConn: 握手后的连接
+//   - error: 请求不是有效的WebSocket握手或无法接管连接时的错误信息
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		err := fmt.Errorf("需要WebSocket连接")
+		writeError(w, http.StatusBadRequest, err)
+		return nil, err
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		err := fmt.Errorf("不支持的WebSocket版本")
+		writeError(w, http.StatusUpgradeRequired, err)
+		return nil, err
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		err := fmt.Errorf("连接不支持WebSocket")
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, err
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("接管连接失败: %v", err)
+	}
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+		base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+// headerContains 判断逗号分隔的请求头中是否包含指定的值，不区分大小写
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header.Values(name) {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeJSON 将v编码为JSON并作为一个文本帧发送
+func (c *wsConn) writeJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.writeFrame(opText, data)
+}
+
+// writeFrame 发送一个不分片、不加掩码的帧
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// discard 读取并丢弃客户端发来的帧，回应ping，直到客户端关闭连接或读取失败
+// 客户端发来的帧按RFC 6455必须带掩码，丢弃时不需要解码
+func (c *wsConn) discard() {
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(c.rw, header); err != nil {
+			return
+		}
+		opcode, length := header[0]&0x0F, uint64(header[1]&0x7F)
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := io.ReadFull(c.rw, ext); err != nil {
+				return
+			}
+			length = uint64(binary.BigEndian.Uint16(ext))
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := io.ReadFull(c.rw, ext); err != nil {
+				return
+			}
+			length = binary.BigEndian.Uint64(ext)
+		}
+		if header[1]&0x80 != 0 {
+			length += 4
+		}
+		if opcode == opClose {
+			c.writeFrame(opClose, nil)
+			return
+		}
+		if opcode == opPing && length <= 125+4 {
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(c.rw, frame); err != nil {
+				return
+			}
+			c.writeFrame(opPong, unmask(frame, header[1]&0x80 != 0))
+			continue
+		}
+		if _, err := io.CopyN(io.Discard, c.rw, int64(length)); err != nil {
+			return
+		}
+	}
+}
+
+// unmask 去掉客户端帧的掩码，frame以4字节的掩码开头
+func unmask(frame []byte, masked bool) []byte {
+	if !masked {
+		return frame
+	}
+	mask, payload := frame[:4], frame[4:]
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return payload
+}
+
+// Close 关闭底层连接
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
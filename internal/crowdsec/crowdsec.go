@@ -0,0 +1,208 @@
+// Package crowdsec 提供CrowdSec本地API（LAPI）客户端
+// 以bouncer身份拉取封禁决策，以机器（watcher）身份推送本机的封禁
+package crowdsec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 推送决策时使用的来源，拉取时可以据此区分
+const origin = "ssh_fb"
+
+// Decision 本地API中的一条决策
+type Decision struct {
+	ID       int64  `json:"id"`
+	Origin   string `json:"origin"`   // 决策来源，如 CAPI、crowdsec、cscli、lists
+	Type     string `json:"type"`     // 处置方式，如 ban、captcha
+	Scope    string `json:"scope"`    // 作用范围，如 Ip、Range
+	Value    string `json:"value"`    // IP地址或CIDR网段
+	Duration string `json:"duration"` // 剩余时长，如 3h59m58s
+	Scenario string `json:"scenario"` // 触发的场景
+}
+
+// Client CrowdSec本地API客户端
+type Client struct {
+	config config.CrowdSecConfig
+	http   *http.Client
+
+	mu      sync.Mutex
+	token   string    // 机器登录得到的JWT
+	expires time.Time // token的到期时间
+}
+
+// New 创建本地API客户端
+// 参数:
+//   - cfg: CrowdSec配置
+// 返回:
+//   - *Client: 初始化后的客户端
+func New(cfg config.CrowdSecConfig) *Client {
+	return &Client{
+		config: cfg,
+		http:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+// Stream 以bouncer身份拉取决策的变化
+// startup为true时返回当前全部有效的决策，之后只返回上次拉取以来新增和删除的决策
+// 参数:
+//   - startup: 是否为首次拉取
+// 返回:
+//   - []Decision: 新增的决策
+//   - []Decision: 删除或到期的决策
+//   - error: 请求失败或接口返回错误时的错误信息
+func (c *Client) Stream(startup bool) ([]Decision, []Decision, error) {
+	req, err := http.NewRequest(http.MethodGet, c.endpoint(fmt.Sprintf("decisions/stream?startup=%t", startup)), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("X-Api-Key", c.config.BouncerKey)
+	var body struct {
+		New     []Decision `json:"new"`
+		Deleted []Decision `json:"deleted"`
+	}
+	if err := c.do(req, &body); err != nil {
+		return nil, nil, fmt.Errorf("拉取CrowdSec决策失败: %v", err)
+	}
+	return body.New, body.Deleted, nil
+}
+
+// PushBan 以机器身份推送一条封禁告警，本地API据此创建一条ban决策
+// 参数:
+//   - ip: 被封禁的IP
+//   - reason: 封禁原因，作为告警说明
+//   - at: 封禁时间
+//   - duration: 剩余的封禁时长
+// 返回:
+//   - error: 登录或推送失败时的错误信息
+func (c *Client) PushBan(ip, reason string, at time.Time, duration time.Duration) error {
+	scope, source := "Ip", map[string]string{"scope": "Ip", "value": ip, "ip": ip}
+	if strings.Contains(ip, "/") {
+		scope, source = "Range", map[string]string{"scope": "Range", "value": ip, "range": ip}
+	}
+	timestamp := at.UTC().Format(time.RFC3339)
+	alerts := []map[string]interface{}{{
+		"scenario":         c.config.Scenario,
+		"scenario_hash":    "",
+		"scenario_version": "",
+		"message":          "ssh_fb: " + reason,
+		"events_count":     1,
+		"events":           []interface{}{},
+		"start_at":         timestamp,
+		"stop_at":          timestamp,
+		"capacity":         0,
+		"leakspeed":        "0",
+		"simulated":        false,
+		"source":           source,
+		"decisions": []map[string]interface{}{{
+			"origin":   origin,
+			"type":     "ban",
+			"scope":    scope,
+			"value":    ip,
+			"duration": duration.Round(time.Second).String(),
+			"scenario": c.config.Scenario,
+		}},
+	}}
+	data, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	// token过期或被本地API拒绝时重新登录一次
+	for retry := 0; ; retry++ {
+		token, err := c.login(retry > 0)
+		if err != nil {
+			return fmt.Errorf("登录CrowdSec失败: %v", err)
+		}
+		req, err := http.NewRequest(http.MethodPost, c.endpoint("alerts"), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		err = c.do(req, nil)
+		if err == errUnauthorized && retry == 0 {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("推送到CrowdSec失败: %v", err)
+		}
+		return nil
+	}
+}
+
+// login 以机器身份登录并缓存token，force为true时忽略缓存
+func (c *Client) login(force bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 提前一分钟视为过期，避免请求途中过期
+	if !force && c.token != "" && time.Now().Add(time.Minute).Before(c.expires) {
+		return c.token, nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"machine_id": c.config.MachineID,
+		"password":   c.config.Password,
+		"scenarios":  []string{c.config.Scenario},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.endpoint("watchers/login"), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	var body struct {
+		Token  string    `json:"token"`
+		Expire time.Time `json:"expire"`
+	}
+	if err := c.do(req, &body); err != nil {
+		return "", err
+	}
+	c.token, c.expires = body.Token, body.Expire
+	return c.token, nil
+}
+
+// errUnauthorized 本地API拒绝了凭据
+var errUnauthorized = fmt.Errorf("HTTP %d: 凭据无效或已过期", http.StatusUnauthorized)
+
+// endpoint 返回接口地址
+func (c *Client) endpoint(path string) string {
+	return strings.TrimSuffix(c.config.URL, "/") + "/v1/" + path
+}
+
+// do 发送请求，出错时附上接口返回的错误说明
+func (c *Client) do(req *http.Request, result interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "ssh_fb")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return errUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if body.Message != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body.Message)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,174 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// AbuseIPDB查询结果的缓存时长与条数上限，免费账号每天只能查询1000次
+const (
+	abuseCacheTTL  = 6 * time.Hour
+	abuseCacheSize = 10000
+)
+
+// AbuseReport AbuseIPDB中一个IP的信誉
+type AbuseReport struct {
+	Score   int // 滥用置信度，0-100
+	Reports int // 统计期内被举报的次数
+}
+
+// abuseEntry 缓存的一条查询结果
+type abuseEntry struct {
+	report  AbuseReport
+	expires time.Time
+}
+
+// AbuseIPDB AbuseIPDB接口客户端，既作为enricher查询IP的滥用置信度，也用于举报封禁的IP
+type AbuseIPDB struct {
+	config config.AbuseIPDBConfig
+	http   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]abuseEntry // 按IP缓存的查询结果
+}
+
+// NewAbuseIPDB 创建AbuseIPDB客户端
+// 参数:
+//   - cfg: AbuseIPDB配置
+// 返回:
+//   - *AbuseIPDB: 初始化后的客户端
+func NewAbuseIPDB(cfg config.AbuseIPDBConfig) *AbuseIPDB {
+	return &AbuseIPDB{
+		config: cfg,
+		http:   &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		cache:  make(map[string]abuseEntry),
+	}
+}
+
+func (*AbuseIPDB) Name() string { return "abuseipdb" }
+
+// Enrich 查询IP的滥用置信度
+func (a *AbuseIPDB) Enrich(ctx context.Context, ip string) (Result, error) {
+	report, err := a.Check(ctx, ip)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Abuse: &report}, nil
+}
+
+// Check 查询IP的滥用置信度，结果缓存6小时
+// 参数:
+//   - ctx: 控制请求超时
+//   - ip: 要查询的IP地址
+// 返回:
+//   - AbuseReport: 置信度与举报次数
+//   - error: 请求失败或接口返回错误时的错误信息
+func (a *AbuseIPDB) Check(ctx context.Context, ip string) (AbuseReport, error) {
+	a.mu.Lock()
+	entry, ok := a.cache[ip]
+	a.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.report, nil
+	}
+
+	query := url.Values{"ipAddress": {ip}, "maxAgeInDays": {strconv.Itoa(a.config.MaxAgeDays)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.endpoint("check")+"?"+query.Encode(), nil)
+	if err != nil {
+		return AbuseReport{}, err
+	}
+	var body struct {
+		Data struct {
+			AbuseConfidenceScore int `json:"abuseConfidenceScore"`
+			TotalReports         int `json:"totalReports"`
+		} `json:"data"`
+	}
+	if err := a.do(req, &body); err != nil {
+		return AbuseReport{}, fmt.Errorf("查询AbuseIPDB失败: %v", err)
+	}
+	report := AbuseReport{Score: body.Data.AbuseConfidenceScore, Reports: body.Data.TotalReports}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if len(a.cache) >= abuseCacheSize {
+		for cached, e := range a.cache {
+			if now.After(e.expires) {
+				delete(a.cache, cached)
+			}
+		}
+	}
+	if len(a.cache) < abuseCacheSize {
+		a.cache[ip] = abuseEntry{report: report, expires: now.Add(abuseCacheTTL)}
+	}
+	return report, nil
+}
+
+// Report 向AbuseIPDB举报IP，分类为配置中的categories（默认18 Brute-Force、22 SSH）
+// AbuseIPDB对同一IP每15分钟只接受一次举报，重复举报返回的错误可以忽略
+// 参数:
+//   - ip: 被举报的IP
+//   - comment: 举报说明，不应包含本机信息
+// 返回:
+//   - error: 请求失败或接口返回错误时的错误信息
+func (a *AbuseIPDB) Report(ip, comment string) error {
+	categories := make([]string, len(a.config.Categories))
+	for i, c := range a.config.Categories {
+		categories[i] = strconv.Itoa(c)
+	}
+	form := url.Values{"ip": {ip}, "categories": {strings.Join(categories, ",")}, "comment": {comment}}
+	req, err := http.NewRequest(http.MethodPost, a.endpoint("report"), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := a.do(req, nil); err != nil {
+		return fmt.Errorf("举报到AbuseIPDB失败: %v", err)
+	}
+	return nil
+}
+
+// Reporting 返回是否需要举报封禁的IP
+func (a *AbuseIPDB) Reporting() bool {
+	return a.config.Report
+}
+
+// endpoint 返回接口地址
+func (a *AbuseIPDB) endpoint(name string) string {
+	return strings.TrimSuffix(a.config.URL, "/") + "/" + name
+}
+
+// do 发送请求，出错时附上接口返回的错误说明
+func (a *AbuseIPDB) do(req *http.Request, result interface{}) error {
+	req.Header.Set("Key", a.config.APIKey)
+	req.Header.Set("Accept", "application/json")
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Errors []struct {
+				Detail string `json:"detail"`
+			} `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		if len(body.Errors) > 0 {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, body.Errors[0].Detail)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
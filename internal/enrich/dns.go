@@ -0,0 +1,118 @@
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// rdnsEnricher 反向解析IP对应的主机名
+type rdnsEnricher struct{}
+
+func (rdnsEnricher) Name() string { return "rdns" }
+
+func (rdnsEnricher) Enrich(ctx context.Context, ip string) (Result, error) {
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(names) == 0 {
+		return Result{}, nil
+	}
+	return Result{Hostname: strings.TrimSuffix(names[0], ".")}, nil
+}
+
+// asnEnricher 通过Team Cymru的DNS接口查询ASN
+type asnEnricher struct{}
+
+func (asnEnricher) Name() string { return "asn" }
+
+// Enrich 查询IP所属的ASN及其名称
+// origin记录形如 "4134 | 1.2.3.0/24 | CN | apnic | 2002-01-01"，
+// ASN记录形如 "4134 | CN | apnic | 2002-01-01 | CHINANET-BACKBONE No.31,Jin-rong Street, CN"
+func (asnEnricher) Enrich(ctx context.Context, ip string) (Result, error) {
+	reversed, v6, err := reverseIP(ip)
+	if err != nil {
+		return Result{}, err
+	}
+	zone := "origin.asn.cymru.com"
+	if v6 {
+		zone = "origin6.asn.cymru.com"
+	}
+	records, err := net.DefaultResolver.LookupTXT(ctx, reversed+"."+zone)
+	if err != nil {
+		return Result{}, err
+	}
+	if len(records) == 0 {
+		return Result{}, nil
+	}
+	// 同一前缀由多个AS宣告时以空格分隔，取第一个
+	asn := strings.Fields(cymruField(records[0], 0))
+	if len(asn) == 0 {
+		return Result{}, nil
+	}
+	result := Result{ASN: "AS" + asn[0]}
+	if names, err := net.DefaultResolver.LookupTXT(ctx, result.ASN+".asn.cymru.com"); err == nil && len(names) > 0 {
+		result.ASOrg = cymruField(names[0], 4)
+	}
+	return result, nil
+}
+
+// dnsblEnricher 在DNSBL中查询IP的信誉
+type dnsblEnricher struct {
+	zones []string
+}
+
+func (dnsblEnricher) Name() string { return "reputation" }
+
+// Enrich 依次查询所有DNSBL，能解析出A记录即表示IP被列入该列表
+func (e dnsblEnricher) Enrich(ctx context.Context, ip string) (Result, error) {
+	reversed, _, err := reverseIP(ip)
+	if err != nil {
+		return Result{}, err
+	}
+	var result Result
+	for _, zone := range e.zones {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, reversed+"."+zone)
+		if err == nil && len(addrs) > 0 {
+			result.Listed = append(result.Listed, zone)
+			continue
+		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+	}
+	return result, nil
+}
+
+// reverseIP 返回DNS反向查询使用的IP形式
+// IPv4为倒序的四段，IPv6为倒序的32个十六进制位
+// 返回:
+//   - string: 倒序后的IP，不含区域后缀
+//   - bool: 是否为IPv6地址
+//   - error: IP无效时的错误信息
+func reverseIP(ip string) (string, bool, error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", false, fmt.Errorf("无效的IP地址: %s", ip)
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0]), false, nil
+	}
+	const hex = "0123456789abcdef"
+	nibbles := make([]string, 0, 32)
+	for i := len(parsed) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, string(hex[parsed[i]&0x0f]), string(hex[parsed[i]>>4]))
+	}
+	return strings.Join(nibbles, "."), true, nil
+}
+
+// cymruField 返回Team Cymru记录中以竖线分隔的第n个字段
+func cymruField(record string, n int) string {
+	fields := strings.Split(record, "|")
+	if n >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[n])
+}
@@ -0,0 +1,187 @@
+// Package enrich 提供IP信息补充功能
+// 属地、反向解析、ASN、信誉和whois等信息分别由独立的enricher查询，并发执行后合并为一个结果
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// Result 合并后的IP补充信息
+type Result struct {
+	IP           string           // 查询的IP地址
+	Geo          *ipinfo.IPInfo   // 属地信息，未启用或查询失败时为nil
+	Hostname     string           // 反向解析得到的主机名
+	ASN          string           // 自治系统号，如 AS4134
+	ASOrg        string           // 自治系统名称
+	Listed       []string         // 命中的DNSBL
+	Network      string           // RDAP中登记的网络名称
+	AbuseContact string           // RDAP中登记的滥用投诉邮箱
+	Abuse        *AbuseReport     // AbuseIPDB中的信誉，未启用或查询失败时为nil
+	Errors       map[string]error // 各enricher的失败原因，键为enricher名称
+}
+
+// Enricher 单项IP信息查询
+// 每个enricher只填充自己负责的字段，返回的结果由Pipeline合并
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, ip string) (Result, error)
+}
+
+// stage 流水线中的一个enricher及其超时时间
+type stage struct {
+	enricher Enricher
+	timeout  time.Duration
+}
+
+// Pipeline 并发执行所有已启用的enricher
+type Pipeline struct {
+	stages []stage
+	abuse  *AbuseIPDB // AbuseIPDB客户端，未启用时为nil
+}
+
+// New 按配置创建补充信息流水线
+// 参数:
+//   - cfg: 各enricher的开关与超时配置
+//   - client: 属地查询使用的IP信息客户端
+// 返回:
+//   - *Pipeline: 只包含已启用enricher的流水线
+func New(cfg config.EnrichmentConfig, client *ipinfo.Client) *Pipeline {
+	p := &Pipeline{}
+	add := func(enabled bool, timeout int, e Enricher) {
+		if enabled {
+			p.stages = append(p.stages, stage{enricher: e, timeout: time.Duration(timeout) * time.Second})
+		}
+	}
+	// 合并时先完成的不覆盖排在前面的，顺序即字段的优先级
+	add(cfg.Geo.Enabled, cfg.Geo.Timeout, geoEnricher{client: client})
+	add(cfg.ASN.Enabled, cfg.ASN.Timeout, asnEnricher{})
+	add(cfg.RDNS.Enabled, cfg.RDNS.Timeout, rdnsEnricher{})
+	add(cfg.Reputation.Enabled, cfg.Reputation.Timeout, dnsblEnricher{zones: cfg.Reputation.Zones})
+	add(cfg.Whois.Enabled, cfg.Whois.Timeout, newRDAPEnricher(cfg.Whois.RDAPURL))
+	if cfg.AbuseIPDB.Enabled {
+		p.abuse = NewAbuseIPDB(cfg.AbuseIPDB)
+		add(true, cfg.AbuseIPDB.Timeout, p.abuse)
+	}
+	return p
+}
+
+// AbuseIPDB 返回流水线使用的AbuseIPDB客户端，与通知共用查询缓存
+// 返回:
+//   - *AbuseIPDB: 未启用时为nil
+func (p *Pipeline) AbuseIPDB() *AbuseIPDB {
+	return p.abuse
+}
+
+// Enrichers 返回已启用的enricher名称
+// 返回:
+//   - []string: 按合并优先级排列的名称
+func (p *Pipeline) Enrichers() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.enricher.Name()
+	}
+	return names
+}
+
+// Enrich 并发查询IP的补充信息
+// 每个enricher有独立的超时时间，超时或失败只影响它负责的字段
+// 参数:
+//   - ip: 要查询的IP地址
+// 返回:
+//   - *Result: 合并后的结果，不会为nil
+func (p *Pipeline) Enrich(ip string) *Result {
+	partials := make([]Result, len(p.stages))
+	errs := make([]error, len(p.stages))
+	var wg sync.WaitGroup
+	for i, s := range p.stages {
+		wg.Add(1)
+		go func(i int, s stage) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			defer cancel()
+			partials[i], errs[i] = s.enricher.Enrich(ctx, ip)
+		}(i, s)
+	}
+	wg.Wait()
+
+	result := &Result{IP: ip}
+	for i, s := range p.stages {
+		if errs[i] != nil {
+			if result.Errors == nil {
+				result.Errors = make(map[string]error)
+			}
+			result.Errors[s.enricher.Name()] = errs[i]
+			continue
+		}
+		result.merge(partials[i])
+	}
+	// 属地接口没有返回ASN时使用ASN查询的结果，供网段汇总和异地登录检测使用
+	if result.Geo != nil && result.Geo.ASN == "" && result.ASN != "" {
+		geo := *result.Geo
+		geo.ASN = result.ASN
+		result.Geo = &geo
+	}
+	return result
+}
+
+// merge 用other中的字段填充尚未填充的字段
+func (r *Result) merge(other Result) {
+	if r.Geo == nil {
+		r.Geo = other.Geo
+	}
+	if r.Hostname == "" {
+		r.Hostname = other.Hostname
+	}
+	if r.ASN == "" {
+		r.ASN, r.ASOrg = other.ASN, other.ASOrg
+	}
+	if len(r.Listed) == 0 {
+		r.Listed = other.Listed
+	}
+	if r.Network == "" {
+		r.Network, r.AbuseContact = other.Network, other.AbuseContact
+	}
+	if r.Abuse == nil {
+		r.Abuse = other.Abuse
+	}
+}
+
+// Format 将补充信息格式化为通知中使用的可读字符串
+// 返回:
+//   - string: 属地信息之后依次附加已查询到的主机名、ASN、DNSBL、网络登记信息和AbuseIPDB信誉
+func (r *Result) Format() string {
+	var b strings.Builder
+	b.WriteString(ipinfo.Format(r.IP, r.Geo))
+	if r.Hostname != "" {
+		fmt.Fprintf(&b, "\n主机名: %s", r.Hostname)
+	}
+	if r.ASN != "" {
+		fmt.Fprintf(&b, "\nASN: %s %s", r.ASN, r.ASOrg)
+	}
+	if name := NetworkTypeName(r.NetworkType()); name != "" {
+		fmt.Fprintf(&b, "\n网络类型: %s", name)
+	}
+	if r.Geo != nil && r.Geo.Proxy {
+		b.WriteString("\n代理: 代理、VPN或TOR出口节点")
+	}
+	if len(r.Listed) > 0 {
+		fmt.Fprintf(&b, "\nDNSBL: %s", strings.Join(r.Listed, ", "))
+	}
+	if r.Network != "" {
+		fmt.Fprintf(&b, "\n网络: %s", r.Network)
+		if r.AbuseContact != "" {
+			fmt.Fprintf(&b, "（投诉: %s）", r.AbuseContact)
+		}
+	}
+	if r.Abuse != nil {
+		fmt.Fprintf(&b, "\nAbuseIPDB: 置信度%d%%，被举报%d次", r.Abuse.Score, r.Abuse.Reports)
+	}
+	return b.String()
+}
@@ -0,0 +1,37 @@
+package enrich
+
+import (
+	"context"
+
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// geoEnricher 通过ip_info配置的接口查询属地
+type geoEnricher struct {
+	client *ipinfo.Client
+}
+
+func (geoEnricher) Name() string { return "geo" }
+
+// Enrich 查询属地信息
+// IP信息客户端自带超时与重试，不感知ctx；超时后直接返回，查询完成的结果仍会写入缓存供下次使用
+func (e geoEnricher) Enrich(ctx context.Context, ip string) (Result, error) {
+	type reply struct {
+		info *ipinfo.IPInfo
+		err  error
+	}
+	done := make(chan reply, 1)
+	go func() {
+		info, err := e.client.GetIPInfo(ip)
+		done <- reply{info, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return Result{}, r.err
+		}
+		return Result{Geo: r.info}, nil
+	case <-ctx.Done():
+		return Result{}, ctx.Err()
+	}
+}
@@ -0,0 +1,92 @@
+package enrich
+
+import "strings"
+
+// 来源网络的类型
+const (
+	NetworkHosting     = "hosting"     // 云服务商或机房，通常是被入侵或租用的服务器
+	NetworkResidential = "residential" // 家庭或移动宽带，通常是僵尸网络中的设备
+)
+
+// 家庭宽带主机名中常见的片段，如 ppp-1-2-3-4.dynamic.example.net
+var residentialHostnameHints = []string{
+	"dynamic", "dyn.", "dsl", "dhcp", "pool", "ppp", "cable", "broadband", "residential",
+	"cust", "home", "fiber", "ftth", "mobile", "wireless",
+}
+
+// 机房主机名中常见的片段
+var hostingHostnameHints = []string{
+	"amazonaws.com", "googleusercontent.com", "cloudapp", "linodeusercontent.com", "vultrusercontent.com",
+	"digitalocean", "your-server.de", "contaboserver", "ovh.net", "vps", "server", "hosting",
+}
+
+// 云服务商和机房的自治系统名称或ISP中常见的片段
+var hostingOrgHints = []string{
+	"amazon", "aws", "google", "microsoft", "azure", "digitalocean", "linode", "akamai", "vultr", "choopa",
+	"ovh", "hetzner", "contabo", "alibaba", "aliyun", "tencent", "huawei cloud", "oracle", "scaleway",
+	"leaseweb", "hostinger", "hostwinds", "m247", "datacamp", "hosting", "datacenter", "data center",
+	"cloud", "server", "vps", "colocation",
+}
+
+// NetworkType 按属地接口的标记、主机名、自治系统名称和ISP粗略判断来源网络的类型
+// 主机名带有家庭宽带特征时按家庭宽带处理，否则属地接口标记为机房或主机名、组织名带有机房特征时按机房处理
+// 返回:
+//   - string: NetworkHosting、NetworkResidential，无法判断时为空
+func (r *Result) NetworkType() string {
+	hostname := strings.ToLower(r.Hostname)
+	if containsAny(hostname, residentialHostnameHints) {
+		return NetworkResidential
+	}
+	if (r.Geo != nil && r.Geo.Hosting) || containsAny(hostname, hostingHostnameHints) {
+		return NetworkHosting
+	}
+	names := []string{r.ASOrg, r.Network}
+	if r.Geo != nil {
+		names = append(names, r.Geo.Org, r.Geo.ISP)
+	}
+	for _, name := range names {
+		if containsAny(strings.ToLower(name), hostingOrgHints) {
+			return NetworkHosting
+		}
+	}
+	return ""
+}
+
+// ASNumber 返回来源的自治系统号与名称，ASN查询未启用或失败时使用属地接口返回的值
+// 返回:
+//   - string: 自治系统号，如 AS4134
+//   - string: 自治系统名称
+func (r *Result) ASNumber() (string, string) {
+	if r.ASN != "" || r.Geo == nil {
+		return r.ASN, r.ASOrg
+	}
+	return r.Geo.ASN, r.Geo.Org
+}
+
+// NetworkTypeName 返回网络类型在通知和报告中显示的名称
+// 参数:
+//   - networkType: NetworkType返回的网络类型
+// 返回:
+//   - string: 显示名称，未知类型时为空
+func NetworkTypeName(networkType string) string {
+	return networkTypeNames[networkType]
+}
+
+// networkTypeNames 网络类型的显示名称
+var networkTypeNames = map[string]string{
+	NetworkHosting:     "机房/云服务",
+	NetworkResidential: "家庭/移动宽带",
+}
+
+// containsAny 检查s中是否包含任一片段
+func containsAny(s string, hints []string) bool {
+	if s == "" {
+		return false
+	}
+	for _, hint := range hints {
+		if strings.Contains(s, hint) {
+			return true
+		}
+	}
+	return false
+}
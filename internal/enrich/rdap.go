@@ -0,0 +1,91 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// rdapEnricher 通过RDAP查询IP所属网络的登记信息，代替传统的whois
+type rdapEnricher struct {
+	url  string
+	http *http.Client
+}
+
+func newRDAPEnricher(url string) rdapEnricher {
+	return rdapEnricher{url: strings.TrimSuffix(url, "/") + "/", http: &http.Client{}}
+}
+
+func (rdapEnricher) Name() string { return "whois" }
+
+// rdapEntity RDAP响应中的联系人
+type rdapEntity struct {
+	Roles      []string      `json:"roles"`
+	VCardArray []interface{} `json:"vcardArray"`
+	Entities   []rdapEntity  `json:"entities"`
+}
+
+// Enrich 查询IP所属网络的名称和滥用投诉邮箱
+func (e rdapEnricher) Enrich(ctx context.Context, ip string) (Result, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+ip, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	resp, err := e.http.Do(req)
+	if err != nil {
+		return Result{}, fmt.Errorf("查询RDAP失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, fmt.Errorf("RDAP接口返回HTTP %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Name     string       `json:"name"`
+		Entities []rdapEntity `json:"entities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Result{}, fmt.Errorf("解析RDAP响应失败: %v", err)
+	}
+	return Result{Network: body.Name, AbuseContact: abuseEmail(body.Entities)}, nil
+}
+
+// abuseEmail 在联系人中查找abuse角色的邮箱，联系人可以多层嵌套
+func abuseEmail(entities []rdapEntity) string {
+	for _, entity := range entities {
+		for _, role := range entity.Roles {
+			if role != "abuse" {
+				continue
+			}
+			if email := vcardEmail(entity.VCardArray); email != "" {
+				return email
+			}
+		}
+		if email := abuseEmail(entity.Entities); email != "" {
+			return email
+		}
+	}
+	return ""
+}
+
+// vcardEmail 从jCard中取出email属性
+// jCard形如 ["vcard", [["fn", {}, "text", "Abuse"], ["email", {}, "text", "abuse@example.com"]]]
+func vcardEmail(vcard []interface{}) string {
+	if len(vcard) < 2 {
+		return ""
+	}
+	props, _ := vcard[1].([]interface{})
+	for _, p := range props {
+		prop, _ := p.([]interface{})
+		if len(prop) < 4 || prop[0] != "email" {
+			continue
+		}
+		if email, ok := prop[3].(string); ok {
+			return email
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+// Package event 定义监控过程中产生的事件及其分发
+package event
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// hookQueue 等待OnPublish设置的函数处理的最大事件数
+const hookQueue = 1024
+
+// dropWarnInterval 两次调用OnDrop设置的函数的最小间隔
+const dropWarnInterval = time.Minute
+
+// Type 事件类型
+type Type string
+
+// 事件类型定义
+const (
+	TypeLoginFailed  Type = "login_failed"  // SSH登录失败
+	TypeLoginSuccess Type = "login_success" // SSH登录成功
+	TypeBanned       Type = "banned"        // IP被封禁
+	TypeUnbanned     Type = "unbanned"      // IP被解除封禁
+	TypeRateLimited  Type = "rate_limited"  // IP被限速
+
+	TypePasswordSpray Type = "password_spray" // 同一用户名被多个IP尝试
+	TypeSubnetAttack  Type = "subnet_attack"  // 同一网段或ASN的失败次数过多
+	TypeJailChanged   Type = "jail_changed"   // jail被启用或停用
+	TypeRuleMatched   Type = "rule_matched"   // 通用规则达到阈值
+	TypeNewLocation   Type = "new_location"   // 用户从新的国家或ASN登录成功
+	TypeLogout        Type = "logout"         // SSH会话结束
+)
+
+// Event 监控事件
+type Event struct {
+	Seq     uint64    `json:"seq"`     // 递增序号，由Bus分配
+	Time    time.Time `json:"time"`    // 事件发生时间
+	Type    Type      `json:"type"`    // 事件类型
+	IP      string    `json:"ip"`      // 相关IP地址
+	Message string    `json:"message"` // 可读的事件描述
+
+	Port   int    `json:"port,omitempty"`   // 客户端源端口，来自日志行
+	PID    int    `json:"pid,omitempty"`    // 处理该连接的sshd进程ID，来自日志行
+	User   string `json:"user,omitempty"`   // 登录使用的用户名，来自日志行
+	Method string `json:"method,omitempty"` // 认证方式，如password、publickey
+	Client string `json:"client,omitempty"` // 客户端版本标识，如 OpenSSH_9.6、libssh_0.9.6
+	Weight int    `json:"weight,omitempty"` // 抽样保存时该事件代表的事件数，未抽样时为0
+
+	Expires time.Time `json:"expires,omitempty"` // 临时封禁事件的解封时间，转发到集中监控时用于通知，不写入存储
+}
+
+// Count 返回该事件代表的事件数，未抽样的事件为1
+func (e Event) Count() int {
+	return max(e.Weight, 1)
+}
+
+// Bus 保存最近的事件，供控制接口按序号增量读取
+type Bus struct {
+	mu     sync.RWMutex
+	events []Event // 环形缓冲区
+	next   int     // 下一个写入位置
+	seq    uint64  // 最近分配的序号
+
+	syncHook func(Event)   // OnPublishSync设置的函数，在Publish中直接调用
+	hooks    chan Event    // 等待OnPublish设置的函数处理的事件，由单独的协程按发布顺序处理
+	dropped  atomic.Uint64 // 因hooks已满未处理的事件数
+
+	onDrop   func(uint64) // OnDrop设置的函数
+	dropWarn atomic.Int64 // 最近一次调用onDrop的时间，Unix纳秒
+}
+
+// NewBus 创建一个新的事件总线
+// 参数:
+//   - size: 保留的最近事件数量
+// 返回:
+//   - *Bus: 初始化后的事件总线
+func NewBus(size int) *Bus {
+	return &Bus{events: make([]Event, 0, size)}
+}
+
+// Publish 发布一个事件
+// 未设置时间的事件使用当前时间
+// 参数:
+//   - e: 要发布的事件
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	if len(b.events) < cap(b.events) {
+		b.events = append(b.events, e)
+	} else {
+		b.events[b.next] = e
+		b.next = (b.next + 1) % len(b.events)
+	}
+	syncFn, hooks, onDrop := b.syncHook, b.hooks, b.onDrop
+	b.mu.Unlock()
+
+	if syncFn != nil {
+		syncFn(e)
+	}
+	if hooks == nil {
+		return
+	}
+	// 调用方通常持有监控状态的锁，队列已满时丢弃而不等待
+	select {
+	case hooks <- e:
+	default:
+		dropped := b.dropped.Add(1)
+		if onDrop == nil {
+			return
+		}
+		now := time.Now().UnixNano()
+		last := b.dropWarn.Load()
+		if (last == 0 || now-last >= int64(dropWarnInterval)) && b.dropWarn.CompareAndSwap(last, now) {
+			onDrop(dropped)
+		}
+	}
+}
+
+// OnPublishSync 设置每个事件发布后在Publish中直接调用的函数，只应在启动时调用一次
+// 调用方通常持有监控状态的锁，fn必须很快返回且不能阻塞，如更新内存中的统计或放入其他有界队列；
+// 与OnPublish设置的函数不同，fn处理每个事件，不会因队列已满而跳过
+// 参数:
+//   - fn: 接收已分配序号和时间的事件
+func (b *Bus) OnPublishSync(fn func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.syncHook = fn
+}
+
+// OnPublish 设置每个事件发布后调用的函数，只应在启动时调用一次
+// 事件先放入有界队列，由单独的协程按发布顺序调用fn，Publish不等待持久化等耗时操作；
+// 队列已满时事件不会交给fn，只计入Dropped
+// 参数:
+//   - fn: 接收已分配序号和时间的事件
+func (b *Bus) OnPublish(fn func(Event)) {
+	hooks := make(chan Event, hookQueue)
+	go func() {
+		for e := range hooks {
+			fn(e)
+		}
+	}()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.hooks = hooks
+}
+
+// Pending 返回等待OnPublish设置的函数处理的事件数与队列容量
+// 返回:
+//   - int: 等待处理的事件数
+//   - int: 队列容量，未设置函数时为0
+func (b *Bus) Pending() (int, int) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.hooks), cap(b.hooks)
+}
+
+// Dropped 返回因队列已满未交给OnPublish设置的函数处理的事件数，自创建起
+func (b *Bus) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// OnDrop 设置队列已满丢弃事件时调用的函数，只应在启动时调用一次
+// 第一次丢弃时立即调用，之后最多每分钟调用一次，fn在Publish中直接调用，不能阻塞
+// 参数:
+//   - fn: 接收自创建起丢弃的事件总数
+func (b *Bus) OnDrop(fn func(dropped uint64)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onDrop = fn
+}
+
+// Since 返回序号大于seq的事件，按发生顺序排列
+// 参数:
+//   - seq: 调用方已读取的最大序号，0表示读取全部
+// 返回:
+//   - []Event: 新事件列表
+func (b *Bus) Since(seq uint64) []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var result []Event
+	for i := 0; i < len(b.events); i++ {
+		e := b.events[(b.next+i)%len(b.events)]
+		if e.Seq > seq {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+// Package fleet 通过Redis在多台服务器之间同步封禁与解封
+// 每个IP的最新变更保存在哈希中，同时发布到频道；新加入或断线重连的服务器按哈希对齐，
+// 同一IP的并发变更以版本（变更时间）较新者为准
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 变更的类型
+const (
+	ActionBan       = "ban"       // 临时封禁
+	ActionPermanent = "permanent" // 永久封禁
+	ActionUnban     = "unban"     // 解除封禁
+)
+
+// Redis请求的超时时间
+const redisTimeout = 5 * time.Second
+
+// Retention 解封记录在集群状态中保留的时长，离线超过该时长的服务器可能错过期间的解封
+const Retention = 7 * 24 * time.Hour
+
+// Message 一条封禁变更
+type Message struct {
+	Action    string    `json:"action"`               // 变更类型
+	IP        string    `json:"ip"`                   // IP地址
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 临时封禁的解封时间
+	Reason    string    `json:"reason,omitempty"`     // 封禁原因
+	Origin    string    `json:"origin"`               // 发起变更的服务器
+	Version   int64     `json:"version"`              // 变更时间（Unix毫秒），同一IP以较大者为准
+}
+
+// publishScript 只在没有更新的变更时写入哈希并发布，避免乱序到达的旧变更覆盖新变更
+// KEYS[1]为状态哈希，KEYS[2]为频道；ARGV[1]为IP，ARGV[2]为消息，ARGV[3]为版本
+var publishScript = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], ARGV[1])
+if current then
+	local ok, decoded = pcall(cjson.decode, current)
+	if ok and tonumber(decoded['version']) and tonumber(decoded['version']) > tonumber(ARGV[3]) then
+		return 0
+	end
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+redis.call('PUBLISH', KEYS[2], ARGV[2])
+return 1
+`)
+
+// pruneScript 只在记录未被修改时删除，避免删除期间写入的新变更
+var pruneScript = redis.NewScript(`
+if redis.call('HGET', KEYS[1], ARGV[1]) == ARGV[2] then
+	return redis.call('HDEL', KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// Redis 基于Redis哈希与发布订阅的同步通道
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// New 创建同步通道，连接在首次请求时建立，断线后自动重连
+// 参数:
+//   - cfg: Redis连接配置，prefix相同的服务器属于同一集群
+// 返回:
+//   - *Redis: 同步通道
+func New(cfg config.RedisConfig) *Redis {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	return &Redis{client: client, prefix: cfg.Prefix}
+}
+
+// Publish 发布一条变更
+// 参数:
+//   - msg: 封禁变更
+// 返回:
+//   - bool: Redis中已有该IP更新的变更、本条被丢弃时为false
+//   - error: 请求失败时的错误信息
+func (r *Redis) Publish(msg Message) (bool, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return false, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	applied, err := publishScript.Run(ctx, r.client, []string{r.key("fleet"), r.key("fleet_events")}, msg.IP, data, msg.Version).Int()
+	if err != nil {
+		return false, fmt.Errorf("发布封禁变更失败: %v", err)
+	}
+	return applied == 1, nil
+}
+
+// State 返回每个IP的最新变更，并删除已到期的封禁和保留期已过的解封记录
+// 返回:
+//   - []Message: 仍然有效的变更
+//   - error: 读取失败时的错误信息
+func (r *Redis) State() ([]Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := r.client.HGetAll(ctx, r.key("fleet")).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取集群封禁状态失败: %v", err)
+	}
+
+	now := time.Now()
+	messages := make([]Message, 0, len(values))
+	for ip, value := range values {
+		var msg Message
+		expired := json.Unmarshal([]byte(value), &msg) != nil
+		switch msg.Action {
+		case ActionBan:
+			expired = expired || now.After(msg.ExpiresAt)
+		case ActionUnban:
+			expired = expired || now.Sub(time.UnixMilli(msg.Version)) > Retention
+		}
+		if expired {
+			pruneScript.Run(ctx, r.client, []string{r.key("fleet")}, ip, value)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// Subscribe 订阅其他服务器发布的变更，阻塞直到ctx被取消
+// 断线期间发布的变更会丢失，需要定期调用State对齐
+// 参数:
+//   - ctx: 控制订阅的生命周期
+//   - handle: 处理收到的变更，包括本机发布的
+func (r *Redis) Subscribe(ctx context.Context, handle func(Message)) {
+	pubsub := r.client.Subscribe(ctx, r.key("fleet_events"))
+	defer pubsub.Close()
+	channel := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload, ok := <-channel:
+			if !ok {
+				return
+			}
+			var msg Message
+			if err := json.Unmarshal([]byte(payload.Payload), &msg); err != nil {
+				continue
+			}
+			handle(msg)
+		}
+	}
+}
+
+// Close 关闭连接
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) key(name string) string {
+	return r.prefix + name
+}
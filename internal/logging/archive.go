@@ -0,0 +1,168 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 归档目录中的文件名
+const (
+	archiveCurrent = "matched.log"         // 正在写入的归档文件
+	archivePrefix  = "matched-"            // 已压缩归档文件名前缀
+	archiveSuffix  = ".log.gz"             // 已压缩归档文件名后缀
+	archiveLayout  = "20060102-150405.000" // 已压缩归档文件名中的时间格式
+)
+
+// Archive 保存匹配到的原始日志行，与程序日志分开存放
+// 当前文件超过大小上限时压缩为带时间戳的.gz文件，并只保留最近的若干个
+type Archive struct {
+	mu       sync.Mutex
+	dir      string   // 归档目录
+	maxSize  int64    // 单个归档文件的最大字节数
+	maxFiles int      // 保留的压缩归档数量
+	file     *os.File // 正在写入的归档文件
+	size     int64    // 正在写入的文件大小
+}
+
+// NewArchive 创建原始日志归档
+// 参数:
+//   - dir: 归档目录，不存在时自动创建
+//   - maxSizeMB: 单个归档文件的最大大小（MB）
+//   - maxFiles: 保留的压缩归档数量
+//
+// 返回:
+//   - *Archive: 初始化后的归档
+//   - error: 创建目录或打开文件失败时的错误信息
+func NewArchive(dir string, maxSizeMB, maxFiles int) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("创建归档目录失败: %v", err)
+	}
+	a := &Archive{dir: dir, maxSize: int64(maxSizeMB) << 20, maxFiles: maxFiles}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Write 归档一行原始日志
+// 参数:
+//   - source: 日志来源，通常为jail名称
+//   - line: 未经解析的原始日志行
+//
+// 返回:
+//   - error: 写入或轮转失败时的错误信息
+func (a *Archive) Write(source, line string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	record := fmt.Sprintf("%s %s\n", source, strings.TrimRight(line, "\r\n"))
+	if a.size > 0 && a.size+int64(len(record)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.WriteString(record)
+	a.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入归档失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭正在写入的归档文件
+// 返回:
+//   - error: 关闭过程中的错误信息
+func (a *Archive) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+func (a *Archive) open() error {
+	file, err := os.OpenFile(filepath.Join(a.dir, archiveCurrent), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("打开归档文件失败: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("读取归档文件信息失败: %v", err)
+	}
+	a.file = file
+	a.size = info.Size()
+	return nil
+}
+
+// rotate 压缩当前归档文件，清理超出数量的旧归档后重新打开
+func (a *Archive) rotate() error {
+	current := filepath.Join(a.dir, archiveCurrent)
+	a.file.Close()
+
+	err := compressFile(current, a.nextName())
+	if err != nil {
+		// 压缩失败时继续追加写入当前文件，不丢失日志
+		if openErr := a.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("压缩归档文件失败: %v", err)
+	}
+	os.Remove(current)
+	a.prune()
+	return a.open()
+}
+
+// nextName 返回一个尚未使用的压缩归档文件名
+func (a *Archive) nextName() string {
+	for {
+		target := filepath.Join(a.dir, archivePrefix+time.Now().Format(archiveLayout)+archiveSuffix)
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			return target
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// prune 删除超出保留数量的最旧归档
+func (a *Archive) prune() {
+	matches, _ := filepath.Glob(filepath.Join(a.dir, archivePrefix+"*"+archiveSuffix))
+	if len(matches) <= a.maxFiles {
+		return
+	}
+	// 文件名中的时间戳按字典序即为时间顺序
+	sort.Strings(matches)
+	for _, path := range matches[:len(matches)-a.maxFiles] {
+		os.Remove(path)
+	}
+}
+
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
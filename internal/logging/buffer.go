@@ -0,0 +1,129 @@
+// Package logging 提供程序日志的辅助功能
+package logging
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Entry 一条结构化日志
+type Entry struct {
+	Seq     uint64                 `json:"seq"`              // 递增序号
+	Time    time.Time              `json:"time"`             // 日志时间
+	Level   string                 `json:"level"`            // 日志级别
+	Module  string                 `json:"module"`           // 产生日志的模块（包名）
+	Message string                 `json:"message"`          // 日志内容
+	Fields  map[string]interface{} `json:"fields,omitempty"` // 结构化字段
+}
+
+// Buffer 是一个logrus钩子，在内存中保留最近的日志供控制接口读取
+type Buffer struct {
+	mu      sync.RWMutex
+	entries []Entry // 环形缓冲区
+	next    int     // 下一个写入位置
+	seq     uint64  // 最近分配的序号
+}
+
+// NewBuffer 创建一个新的日志缓冲区
+// 参数:
+//   - size: 保留的最近日志条数
+// 返回:
+//   - *Buffer: 初始化后的日志缓冲区
+func NewBuffer(size int) *Buffer {
+	return &Buffer{entries: make([]Entry, 0, size)}
+}
+
+// Levels 实现logrus.Hook接口，记录所有级别的日志
+func (b *Buffer) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire 实现logrus.Hook接口，保存一条日志
+func (b *Buffer) Fire(entry *logrus.Entry) error {
+	fields := make(map[string]interface{}, len(entry.Data))
+	for k, v := range entry.Data {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		fields[k] = v
+	}
+
+	module := callerModule()
+	if m, ok := fields["module"].(string); ok {
+		module = m
+		delete(fields, "module")
+	}
+
+	e := Entry{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Module:  module,
+		Message: entry.Message,
+		Fields:  fields,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	e.Seq = b.seq
+	if len(b.entries) < cap(b.entries) {
+		b.entries = append(b.entries, e)
+		return nil
+	}
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	return nil
+}
+
+// Since 返回序号大于seq的日志，按时间顺序排列
+// 参数:
+//   - seq: 调用方已读取的最大序号，0表示读取全部
+// 返回:
+//   - []Entry: 新日志列表
+func (b *Buffer) Since(seq uint64) []Entry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	var result []Entry
+	for i := 0; i < len(b.entries); i++ {
+		e := b.entries[(b.next+i)%len(b.entries)]
+		if e.Seq > seq {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// callerModule 从调用栈中找到logrus之外的第一个调用方，返回其包名
+func callerModule() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		fn := frame.Function
+		if !strings.Contains(fn, "sirupsen/logrus") && !strings.Contains(fn, "/internal/logging.") {
+			return packageName(fn)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// packageName 从完整函数名中提取包名
+// 如 github.com/x/ssh_fb/internal/monitor.(*Monitor).banIP 返回 monitor
+func packageName(fn string) string {
+	if i := strings.LastIndex(fn, "/"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if i := strings.Index(fn, "."); i >= 0 {
+		fn = fn[:i]
+	}
+	return fn
+}
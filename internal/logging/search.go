@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArchiveFiles 返回归档目录中的所有归档文件，按时间从旧到新排列，正在写入的文件在最后
+// 参数:
+//   - dir: 归档目录
+// 返回:
+//   - []string: 存在的归档文件路径
+func ArchiveFiles(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, archivePrefix+"*"+archiveSuffix))
+	sort.Strings(files)
+	current := filepath.Join(dir, archiveCurrent)
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files
+}
+
+// Search 按顺序读取日志文件，返回匹配的行，.gz结尾的文件先解压
+// 不存在的文件被跳过
+// 参数:
+//   - paths: 日志文件路径，按时间从旧到新排列
+//   - match: 判断一行是否匹配
+//   - limit: 最多返回的行数，超出时保留最新的，0表示不限制
+// 返回:
+//   - []string: 匹配的行
+//   - error: 读取文件失败时的错误信息
+func Search(paths []string, match func(line string) bool, limit int) ([]string, error) {
+	var lines []string
+	for _, path := range paths {
+		err := searchFile(path, func(line string) {
+			if !match(line) {
+				return
+			}
+			lines = append(lines, line)
+			if limit > 0 && len(lines) > 2*limit {
+				lines = append(lines[:0], lines[len(lines)-limit:]...)
+			}
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("读取日志文件失败 %s: %v", path, err)
+		}
+	}
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines, nil
+}
+
+// searchFile 逐行读取一个日志文件
+func searchFile(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	return scanner.Err()
+}
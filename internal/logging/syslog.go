@@ -0,0 +1,271 @@
+package logging
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// syslogTimeout 连接与单条消息的写超时
+const syslogTimeout = 5 * time.Second
+
+// syslogSDID RFC 5424中结构化数据的ID，32473为RFC 5612保留给示例使用的企业号
+const syslogSDID = "ssh_fb@32473"
+
+// 本机syslog的socket路径，依次尝试
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogFacilities syslog设施的编号
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "daemon": 3, "auth": 4, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Syslog 严重级别
+const (
+	SyslogCrit    = 2
+	SyslogErr     = 3
+	SyslogWarning = 4
+	SyslogNotice  = 5
+	SyslogInfo    = 6
+	SyslogDebug   = 7
+)
+
+// Syslog 向本机或远程syslog服务器发送消息
+// 本机syslog按传统格式写入，远程服务器按RFC 5424格式发送，tcp和tls连接按RFC 6587的长度前缀分帧
+// 连接断开后在下一次发送时重新连接
+type Syslog struct {
+	mu        sync.Mutex
+	network   string      // unix、udp、tcp或tls
+	address   string      // 服务器地址或本机socket路径
+	tlsConfig *tls.Config // tls连接的配置
+	facility  int         // 设施编号
+	tag       string      // 程序名
+	hostname  string      // 本机主机名
+	conn      net.Conn    // 当前连接，未连接时为nil
+}
+
+// NewSyslog 按配置创建syslog客户端，创建时不连接
+// 参数:
+//   - cfg: syslog配置
+// 返回:
+//   - *Syslog: 初始化后的客户端
+//   - error: 无法读取CA证书文件时的错误信息
+func NewSyslog(cfg config.SyslogConfig) (*Syslog, error) {
+	s := &Syslog{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: syslogFacilities[cfg.Facility],
+		tag:      cfg.Tag,
+	}
+	s.hostname, _ = os.Hostname()
+	if s.hostname == "" {
+		s.hostname = "-"
+	}
+	if cfg.Network == "tls" {
+		s.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.CAFile != "" {
+			data, err := os.ReadFile(cfg.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("读取CA证书失败: %v", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("CA证书文件中没有有效的证书: %s", cfg.CAFile)
+			}
+			s.tlsConfig.RootCAs = pool
+		}
+	}
+	return s, nil
+}
+
+// Send 发送一条消息，连接失败或写入失败时重新连接并重试一次
+// 参数:
+//   - severity: 严重级别，如SyslogWarning
+//   - msgID: 消息类型，如通知类型，只在RFC 5424格式中发送
+//   - t: 消息时间
+//   - message: 消息内容，换行会被替换
+//   - fields: 结构化数据，只在RFC 5424格式中发送，可为空
+// 返回:
+//   - error: 发送失败时的错误信息
+func (s *Syslog) Send(severity int, msgID string, t time.Time, message string, fields map[string]string) error {
+	data := s.format(severity, msgID, t, strings.ReplaceAll(message, "\n", "; "), fields)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(); err != nil {
+				continue
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		if _, err = s.conn.Write(data); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("发送syslog失败: %v", err)
+}
+
+// Close 关闭连接
+// 返回:
+//   - error: 关闭过程中的错误信息
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// dial 连接syslog服务器，本机syslog依次尝试数据报与流式socket
+func (s *Syslog) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogTimeout}
+	switch s.network {
+	case "tls":
+		return tls.DialWithDialer(dialer, "tcp", s.address, s.tlsConfig)
+	case "udp", "tcp":
+		return dialer.Dial(s.network, s.address)
+	}
+	paths := localSyslogPaths
+	if s.address != "" {
+		paths = []string{s.address}
+	}
+	var lastErr error
+	for _, path := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			conn, err := dialer.Dial(network, path)
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// format 按连接类型编码一条消息
+func (s *Syslog) format(severity int, msgID string, t time.Time, message string, fields map[string]string) []byte {
+	pri := s.facility*8 + severity
+	if s.network == "unix" {
+		return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s", pri, t.Format(time.Stamp), s.tag, os.Getpid(), message))
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		pri, t.Format(time.RFC3339Nano), s.hostname, s.tag, os.Getpid(), nilValue(msgID), structuredData(fields), message)
+	if s.network == "udp" {
+		return []byte(msg)
+	}
+	return []byte(fmt.Sprintf("%d %s", len(msg), msg))
+}
+
+// structuredData 编码RFC 5424的结构化数据，键按字母顺序排列，没有字段时为"-"
+func structuredData(fields map[string]string) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("[" + syslogSDID)
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+	for _, key := range keys {
+		fmt.Fprintf(&b, ` %s="%s"`, key, escaper.Replace(fields[key]))
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// nilValue RFC 5424中空字段写为"-"
+func nilValue(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// syslogQueueSize 程序日志钩子等待发送的最大日志数，syslog服务器不可达时超出的日志被丢弃
+const syslogQueueSize = 1000
+
+// SyslogHook 是一个logrus钩子，将程序日志异步发送到syslog，不阻塞写日志的协程
+type SyslogHook struct {
+	syslog *Syslog
+	levels []logrus.Level
+	queue  chan *logrus.Entry
+}
+
+// NewSyslogHook 创建发送程序日志的钩子并启动发送协程
+// 参数:
+//   - syslog: syslog客户端
+//   - level: 发送该级别及以上的日志
+// 返回:
+//   - *SyslogHook: 钩子
+func NewSyslogHook(syslog *Syslog, level logrus.Level) *SyslogHook {
+	h := &SyslogHook{syslog: syslog, queue: make(chan *logrus.Entry, syslogQueueSize)}
+	for _, l := range logrus.AllLevels {
+		if l <= level {
+			h.levels = append(h.levels, l)
+		}
+	}
+	go h.run()
+	return h
+}
+
+// Levels 实现logrus.Hook接口
+func (h *SyslogHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire 实现logrus.Hook接口，队列已满时丢弃该日志
+func (h *SyslogHook) Fire(entry *logrus.Entry) error {
+	select {
+	case h.queue <- entry:
+	default:
+	}
+	return nil
+}
+
+func (h *SyslogHook) run() {
+	for entry := range h.queue {
+		fields := make(map[string]string, len(entry.Data))
+		for k, v := range entry.Data {
+			fields[k] = fmt.Sprint(v)
+		}
+		// 发送失败时无法再写入程序日志，否则会再次触发钩子
+		h.syslog.Send(syslogSeverity(entry.Level), "log", entry.Time, entry.Message, fields)
+	}
+}
+
+// syslogSeverity 将logrus的日志级别转换为syslog严重级别
+func syslogSeverity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return SyslogCrit
+	case logrus.ErrorLevel:
+		return SyslogErr
+	case logrus.WarnLevel:
+		return SyslogWarning
+	case logrus.InfoLevel:
+		return SyslogInfo
+	}
+	return SyslogDebug
+}
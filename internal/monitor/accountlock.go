@@ -0,0 +1,272 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// 检查账户锁定是否到期的间隔
+const accountLockCheckInterval = time.Minute
+
+// accountLock 一个已锁定的本机账户
+type accountLock struct {
+	Method   string    `json:"method"`           // 锁定方式，解锁时使用相同的方式
+	LockedAt time.Time `json:"locked_at"`        // 锁定时间
+	UnlockAt time.Time `json:"unlock_at"`        // 自动解锁时间
+	Expire   string    `json:"expire,omitempty"` // expire方式锁定前账户的过期日期（YYYY-MM-DD），解锁时恢复，为空表示不过期
+}
+
+// loadAccountLocks 加载上次运行时锁定的账户，到期的账户在下一次检查时解锁
+// 未启用账户锁定时仍然加载，关闭该功能后已锁定的账户也会按时解锁
+// 返回:
+//   - error: 读取或解析状态文件失败时的错误信息
+func (m *Monitor) loadAccountLocks() error {
+	data, err := os.ReadFile(m.config.SSHProtection.AccountLock.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取账户锁定文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &m.accountLocks); err != nil {
+		return fmt.Errorf("解析账户锁定文件失败: %v", err)
+	}
+	if m.accountLocks == nil {
+		m.accountLocks = make(map[string]accountLock)
+	}
+	return nil
+}
+
+// saveAccountLocks 保存已锁定的账户
+// 调用方需持有m.mu锁
+func (m *Monitor) saveAccountLocks() error {
+	data, err := json.MarshalIndent(m.accountLocks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.config.SSHProtection.AccountLock.StateFile, data, 0600)
+}
+
+// lockAccount 锁定遭受密码喷洒的本机账户
+// 豁免的用户与已锁定的账户被跳过，其余账户在单独的协程中执行锁定命令，不持有m.mu锁
+// 调用方需持有m.mu锁
+// 参数:
+//   - name: 登录使用的用户名
+//   - ips: 时间窗口内失败的不同IP
+//   - now: 日志中记录的失败时间
+func (m *Monitor) lockAccount(name string, ips []string, now time.Time) {
+	cfg := m.config.SSHProtection.AccountLock
+	if !cfg.Enabled || slices.Contains(cfg.ExemptUsers, name) {
+		return
+	}
+	if _, locked := m.accountLocks[name]; locked || m.accountsBusy[name] {
+		return
+	}
+	m.accountsBusy[name] = true
+	go m.applyAccountLock(name, ips, now)
+}
+
+// applyAccountLock 执行锁定命令并记录锁定的账户
+// 不存在的用户名以及管理员手动锁定的账户被跳过
+// 参数:
+//   - name: 登录使用的用户名
+//   - ips: 时间窗口内失败的不同IP
+//   - now: 日志中记录的失败时间
+func (m *Monitor) applyAccountLock(name string, ips []string, now time.Time) {
+	cfg := m.config.SSHProtection.AccountLock
+	lock, ok := m.runAccountLock(cfg.Method, name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.accountsBusy, name)
+	if !ok {
+		return
+	}
+	lock.LockedAt = time.Now()
+	lock.UnlockAt = lock.LockedAt.Add(time.Duration(cfg.Duration))
+	m.accountLocks[name] = lock
+	if err := m.saveAccountLocks(); err != nil {
+		m.logger.WithError(err).Error("保存账户锁定文件失败")
+	}
+
+	reason := fmt.Sprintf("%s内被%d个IP尝试登录", m.config.SSHProtection.PasswordSpray.Window, len(ips))
+	m.logger.WithFields(logrus.Fields{
+		"user":   name,
+		"method": cfg.Method,
+		"ips":    len(ips),
+		"unlock": lock.UnlockAt.Format("2006-01-02 15:04:05"),
+	}).Warn("账户遭受密码喷洒，已临时锁定")
+	m.audit(auditAccountLock, name, SourceLog, reason)
+	m.notifier.Notify(notification.AccountLockedEvent(name, reason, m.serverName(), lock.UnlockAt, now))
+}
+
+// runAccountLock 检查账户并执行锁定命令，不要求持有m.mu锁
+// 参数:
+//   - method: 锁定方式，passwd或expire
+//   - name: 登录使用的用户名
+// 返回:
+//   - accountLock: 锁定方式与锁定前的过期日期，锁定时间由调用方填写
+//   - bool: 是否已锁定
+func (m *Monitor) runAccountLock(method, name string) (accountLock, bool) {
+	lock := accountLock{Method: method}
+	if _, err := user.Lookup(name); err != nil {
+		m.logger.WithField("user", name).Debug("用户名不是本机账户，不锁定")
+		return lock, false
+	}
+	switch method {
+	case "passwd":
+		if passwordLocked(name) {
+			// 已经被管理员锁定，到期后不能替管理员解锁
+			m.logger.WithField("user", name).Info("账户的密码已处于锁定状态，不重复锁定")
+			return lock, false
+		}
+	case "expire":
+		expire, err := accountExpiry(name)
+		if err != nil {
+			// 无法得知原来的过期日期时不锁定，否则解锁时会清除管理员设置的过期日期
+			m.logger.WithError(err).WithField("user", name).Error("读取账户过期日期失败，不锁定")
+			return lock, false
+		}
+		if expire != "" && expire <= time.Now().UTC().Format(time.DateOnly) {
+			m.logger.WithField("user", name).Info("账户已过期，不重复锁定")
+			return lock, false
+		}
+		lock.Expire = expire
+	}
+
+	if err := runAccountCommand(lockCommand(lock, name)); err != nil {
+		m.logger.WithError(err).WithField("user", name).Error("锁定账户失败")
+		return lock, false
+	}
+	return lock, true
+}
+
+// expireAccountLocks 定期解锁到期的账户
+func (m *Monitor) expireAccountLocks() {
+	ticker := time.NewTicker(accountLockCheckInterval)
+	for range ticker.C {
+		m.unlockAccounts(time.Now())
+	}
+}
+
+// unlockAccounts 解锁到期的账户，解锁失败的账户在下一次检查时重试
+// 在m.mu锁内找出到期的账户，释放锁后执行解锁命令，再加锁更新状态
+// 参数:
+//   - now: 当前时间
+func (m *Monitor) unlockAccounts(now time.Time) {
+	m.mu.Lock()
+	due := make(map[string]accountLock)
+	for name, lock := range m.accountLocks {
+		if !now.Before(lock.UnlockAt) && !m.accountsBusy[name] {
+			due[name] = lock
+			m.accountsBusy[name] = true
+		}
+	}
+	resetFaillock := m.config.SSHProtection.AccountLock.ResetFaillock
+	m.mu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	unlocked := make([]string, 0, len(due))
+	for name, lock := range due {
+		if err := runAccountCommand(unlockCommand(lock, name)); err != nil {
+			m.logger.WithError(err).WithField("user", name).Error("解锁账户失败，稍后重试")
+			continue
+		}
+		if resetFaillock {
+			if err := runAccountCommand([]string{"faillock", "--user", name, "--reset"}); err != nil {
+				m.logger.WithError(err).WithField("user", name).Debug("清除faillock记录失败")
+			}
+		}
+		unlocked = append(unlocked, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range due {
+		delete(m.accountsBusy, name)
+	}
+	for _, name := range unlocked {
+		delete(m.accountLocks, name)
+		m.logger.WithField("user", name).Info("账户锁定已到期，已解锁")
+		m.audit(auditAccountUnlock, name, SourceExpiry, "锁定到期")
+		m.notifier.Notify(notification.AccountUnlockedEvent(name, "锁定到期", m.serverName(), now))
+	}
+	if len(unlocked) > 0 {
+		if err := m.saveAccountLocks(); err != nil {
+			m.logger.WithError(err).Error("保存账户锁定文件失败")
+		}
+	}
+}
+
+// lockCommand 返回锁定账户的命令
+func lockCommand(lock accountLock, name string) []string {
+	if lock.Method == "expire" {
+		return []string{"usermod", "--expiredate", "1", name}
+	}
+	return []string{"passwd", "-l", name}
+}
+
+// unlockCommand 返回解锁账户的命令，expire方式恢复锁定前的过期日期
+func unlockCommand(lock accountLock, name string) []string {
+	if lock.Method == "expire" {
+		return []string{"usermod", "--expiredate", lock.Expire, name}
+	}
+	return []string{"passwd", "-u", name}
+}
+
+// accountExpiry 读取账户当前的过期日期
+// getent shadow 输出的第8列为自1970-01-01起的天数，为空表示不过期
+// 参数:
+//   - name: 本机账户名
+// 返回:
+//   - string: 过期日期（YYYY-MM-DD），不过期时为空
+//   - error: 读取或解析失败时的错误信息
+func accountExpiry(name string) (string, error) {
+	out, err := exec.Command("getent", "shadow", name).Output()
+	if err != nil {
+		return "", fmt.Errorf("执行getent shadow %s失败: %v", name, err)
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), ":")
+	if len(fields) < 8 {
+		return "", fmt.Errorf("无法解析%s的shadow记录", name)
+	}
+	if fields[7] == "" {
+		return "", nil
+	}
+	days, err := strconv.Atoi(fields[7])
+	if err != nil {
+		return "", fmt.Errorf("无法解析%s的过期日期%q: %v", name, fields[7], err)
+	}
+	return time.Unix(int64(days)*86400, 0).UTC().Format(time.DateOnly), nil
+}
+
+// passwordLocked 检查账户的密码是否已被锁定，passwd -S 输出的第二列为L时表示已锁定
+func passwordLocked(name string) bool {
+	out, err := exec.Command("passwd", "-S", name).Output()
+	if err != nil {
+		return false
+	}
+	fields := strings.Fields(string(out))
+	return len(fields) > 1 && fields[1] == "L"
+}
+
+// runAccountCommand 执行账户管理命令，失败时返回包含命令输出的错误
+func runAccountCommand(args []string) error {
+	out, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("执行%s失败: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
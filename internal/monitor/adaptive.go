@@ -0,0 +1,190 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/audit"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// 自适应阈值报告中列出的国家数量
+const adaptiveReportTop = 5
+
+// countryStats 按国家自适应阈值的统计状态，保存在状态文件中
+type countryStats struct {
+	Failures     map[string]int `json:"failures"`      // 本统计周期内各国家的失败次数
+	Strict       []string       `json:"strict"`        // 当前使用严格阈值的国家
+	CalculatedAt time.Time      `json:"calculated_at"` // 本统计周期的开始时间
+}
+
+// loadCountryStats 从状态文件加载国家统计
+// 状态文件不存在时从现在开始第一个统计周期
+// 返回:
+//   - error: 读取或解析状态文件失败时的错误信息
+func (m *Monitor) loadCountryStats() error {
+	m.countryStats = countryStats{Failures: make(map[string]int), CalculatedAt: time.Now()}
+	cfg := m.config.SSHProtection.AdaptiveCountry
+	if !cfg.Enabled {
+		return nil
+	}
+
+	data, err := os.ReadFile(cfg.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取国家统计文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &m.countryStats); err != nil {
+		return fmt.Errorf("解析国家统计文件失败: %v", err)
+	}
+	if m.countryStats.Failures == nil {
+		m.countryStats.Failures = make(map[string]int)
+	}
+	if len(m.countryStats.Strict) > 0 {
+		m.logger.WithField("countries", m.countryStats.Strict).Info("以下国家使用严格阈值")
+	}
+	return nil
+}
+
+// saveCountryStats 保存国家统计
+// 调用方需持有m.mu锁
+func (m *Monitor) saveCountryStats() error {
+	data, err := json.MarshalIndent(m.countryStats, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.config.SSHProtection.AdaptiveCountry.StateFile, data, 0644)
+}
+
+// countryOf 返回IP信息中的国家，查询失败时为空
+func countryOf(info *ipinfo.IPInfo) string {
+	if info == nil {
+		return ""
+	}
+	return info.Country
+}
+
+// recordCountryFailure 记录一次来自该国家的登录失败
+// 调用方需持有m.mu锁
+func (m *Monitor) recordCountryFailure(country string) {
+	if !m.config.SSHProtection.AdaptiveCountry.Enabled || country == "" {
+		return
+	}
+	m.countryStats.Failures[country]++
+}
+
+// isStrictCountry 检查该国家是否使用严格阈值
+// 调用方需持有m.mu锁
+func (m *Monitor) isStrictCountry(country string) bool {
+	if !m.config.SSHProtection.AdaptiveCountry.Enabled || country == "" {
+		return false
+	}
+	for _, c := range m.countryStats.Strict {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCountryStats 保存统计，统计周期结束时重新计算使用严格阈值的国家并发送报告
+// 由清理协程每小时调用一次
+// 调用方需持有m.mu锁
+func (m *Monitor) updateCountryStats() {
+	cfg := m.config.SSHProtection.AdaptiveCountry
+	if !cfg.Enabled {
+		return
+	}
+
+	period := time.Duration(cfg.RecalculateDays) * 24 * time.Hour
+	if time.Since(m.countryStats.CalculatedAt) >= period {
+		m.recalculateCountries()
+	}
+	if err := m.saveCountryStats(); err != nil {
+		m.logger.WithError(err).Error("保存国家统计失败")
+	}
+}
+
+// recalculateCountries 根据本统计周期的失败次数重新选出使用严格阈值的国家，并开始新的周期
+// 样本数不足min_samples时保留上一周期的结果
+// 调用方需持有m.mu锁
+func (m *Monitor) recalculateCountries() {
+	cfg := m.config.SSHProtection.AdaptiveCountry
+	stats := m.countryStats
+
+	total := 0
+	for _, n := range stats.Failures {
+		total += n
+	}
+	ranked := topCountries(stats.Failures)
+
+	if total >= cfg.MinSamples && total > 0 {
+		strict := []string{}
+		for _, country := range ranked {
+			if stats.Failures[country]*100 >= total*cfg.SharePercent {
+				strict = append(strict, country)
+			}
+		}
+		m.countryStats.Strict = strict
+	}
+
+	var report []string
+	for i, country := range ranked {
+		if i >= adaptiveReportTop {
+			break
+		}
+		report = append(report, fmt.Sprintf("%s %d%%", country, stats.Failures[country]*100/total))
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"total":   total,
+		"top":     report,
+		"strict":  m.countryStats.Strict,
+		"samples": cfg.MinSamples,
+	}).Info("国家自适应阈值已重新计算")
+	m.notifier.Notify(notification.AdaptiveReportEvent(total, report, m.countryStats.Strict, cfg.MaxFailedAttempts, m.sshdAdvice(total), m.serverName()))
+
+	m.countryStats.Failures = make(map[string]int)
+	m.countryStats.CalculatedAt = time.Now()
+}
+
+// sshdAdvice 检查sshd配置，返回附在报告中的加固建议
+// 未配置sshd_config或读取失败时返回空，读取失败只记录日志
+func (m *Monitor) sshdAdvice(failures int) []string {
+	path := m.config.SSHProtection.SSHDConfig
+	if path == "" {
+		return nil
+	}
+	options, err := audit.LoadSSHDConfig(path)
+	if err != nil {
+		m.logger.WithError(err).Warn("检查sshd配置失败")
+		return nil
+	}
+	var advice []string
+	for _, finding := range audit.CheckSSHD(options, audit.NewSSHDContext(m.config.SSHProtection, failures)) {
+		advice = append(advice, finding.String())
+	}
+	return advice
+}
+
+// topCountries 按失败次数从高到低返回国家列表
+func topCountries(failures map[string]int) []string {
+	countries := make([]string, 0, len(failures))
+	for country := range failures {
+		countries = append(countries, country)
+	}
+	sort.Slice(countries, func(i, j int) bool {
+		if failures[countries[i]] != failures[countries[j]] {
+			return failures[countries[i]] > failures[countries[j]]
+		}
+		return countries[i] < countries[j]
+	})
+	return countries
+}
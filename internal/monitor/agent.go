@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/aggregator"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// 集中监控的运行模式
+const (
+	aggregatorAgent  = "agent"
+	aggregatorServer = "server"
+)
+
+// agentForwarder agent模式下将事件转发到集中监控server
+type agentForwarder struct {
+	client  *aggregator.Client
+	host    string
+	queue   chan event.Event // 等待转发的事件，已满时新事件被丢弃
+	sent    atomic.Int64
+	dropped atomic.Int64 // 丢弃的事件总数
+	unsent  atomic.Int64 // 尚未报告给server的丢弃数
+
+	mu        sync.Mutex
+	pending   int       // 已从队列取出、等待重试的事件数
+	lastSent  time.Time // 最近一次转发成功的时间
+	lastError string    // 最近一次转发失败的原因
+}
+
+// aggregatorHost 返回本机在集中监控中的名称，未设置host_id时使用主机名
+func (m *Monitor) aggregatorHost() (string, error) {
+	if host := m.config.Aggregator.HostID; host != "" {
+		return host, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("获取主机名失败，请设置aggregator.host_id: %v", err)
+	}
+	return host, nil
+}
+
+// startAgent agent模式下创建转发器并开始定期转发事件
+// 需要在设置事件总线的OnPublishSync之前调用，启动之后发布的事件都会被转发
+// 返回:
+//   - error: CA证书无效或无法获取主机名时的错误信息
+func (m *Monitor) startAgent() error {
+	cfg := m.config.Aggregator
+	if cfg.Mode != aggregatorAgent {
+		return nil
+	}
+	client, err := aggregator.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("集中监控配置无效: %v", err)
+	}
+	host, err := m.aggregatorHost()
+	if err != nil {
+		return err
+	}
+	m.agent = &agentForwarder{client: client, host: host, queue: make(chan event.Event, cfg.QueueSize)}
+	m.logger.WithField("server", cfg.URL).Info("事件将转发到集中监控")
+	go m.runAgent(m.agent)
+	return nil
+}
+
+// forwardEvent 将事件放入转发队列，队列已满时丢弃，不阻塞事件发布
+func (m *Monitor) forwardEvent(e event.Event) {
+	if m.agent == nil {
+		return
+	}
+	select {
+	case m.agent.queue <- e:
+	default:
+		m.agent.dropped.Add(1)
+		m.agent.unsent.Add(1)
+	}
+}
+
+// runAgent 每隔flush_interval按batch_size分批转发队列中的事件
+// 转发失败时保留事件并在下一个周期重试，保留的事件不超过queue_size，server恢复前超出的事件留在队列中
+func (m *Monitor) runAgent(a *agentForwarder) {
+	cfg := m.config.Aggregator
+	ticker := time.NewTicker(time.Duration(cfg.FlushInterval))
+	defer ticker.Stop()
+
+	var pending []event.Event
+	for range ticker.C {
+	drain:
+		for len(pending) < cfg.QueueSize {
+			select {
+			case e := <-a.queue:
+				pending = append(pending, e)
+			default:
+				break drain
+			}
+		}
+
+		for len(pending) > 0 {
+			n := min(len(pending), cfg.BatchSize)
+			dropped := a.unsent.Swap(0)
+			err := a.client.Send(aggregator.Batch{Host: a.host, Dropped: int(dropped), Events: pending[:n]})
+			a.mu.Lock()
+			if err != nil {
+				a.unsent.Add(dropped)
+				// 只在刚开始失败时记录警告，server长时间不可达时不重复刷屏
+				if a.lastError == "" {
+					m.logger.WithError(err).Warn("转发事件到集中监控失败，稍后重试")
+				}
+				a.lastError = err.Error()
+				a.mu.Unlock()
+				break
+			}
+			if a.lastError != "" {
+				m.logger.Info("集中监控已恢复，继续转发事件")
+			}
+			a.lastSent, a.lastError = time.Now(), ""
+			a.mu.Unlock()
+			a.sent.Add(int64(n))
+			pending = pending[n:]
+		}
+		a.mu.Lock()
+		a.pending = len(pending)
+		a.mu.Unlock()
+	}
+}
+
+// status 返回转发状态
+func (a *agentForwarder) status(server string) *control.ForwarderStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return &control.ForwarderStatus{
+		Server:    server,
+		Queued:    a.pending + len(a.queue),
+		Sent:      a.sent.Load(),
+		Dropped:   a.dropped.Load(),
+		LastSent:  a.lastSent,
+		LastError: a.lastError,
+	}
+}
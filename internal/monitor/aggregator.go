@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/aggregator"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// 跨服务器攻击来源的统计窗口，以及status中列出的最大条数
+const (
+	fleetAttackerWindow = 24 * time.Hour
+	fleetAttackerLimit  = 10
+)
+
+// agentHub server模式下各agent的统计与通知去重状态
+type agentHub struct {
+	mu       sync.Mutex
+	agents   map[string]*control.AgentStatus // 各agent的统计，键为agent名称
+	sources  map[string]*fleetSource         // 统计窗口内登录失败过的来源
+	notified map[string]time.Time            // 去重窗口内已发送的通知，键为通知类型、IP和用户名
+}
+
+// fleetSource 一个来源在各服务器上的登录失败
+type fleetSource struct {
+	hosts    map[string]bool
+	failures int64
+	last     time.Time
+}
+
+// startAggregator server模式下开始接收各agent提交的事件
+func (m *Monitor) startAggregator() {
+	cfg := m.config.Aggregator
+	if cfg.Mode != aggregatorServer {
+		return
+	}
+	m.hub = &agentHub{
+		agents:   make(map[string]*control.AgentStatus),
+		sources:  make(map[string]*fleetSource),
+		notified: make(map[string]time.Time),
+	}
+	server := aggregator.NewServer(cfg, m.handleAgentBatch)
+	go func() {
+		if err := server.Start(); err != nil {
+			m.logger.WithError(err).Error("集中监控服务启动失败")
+		}
+	}()
+	m.logger.WithField("listen", cfg.Listen).Info("集中监控服务已启动")
+}
+
+// handleAgentBatch 处理agent提交的一批事件
+// 更新统计后以 "[agent名称] " 为前缀发布到本机的事件总线，登录成功、封禁和解封按去重窗口发送通知，
+// 通知中的服务器名称为agent名称
+// 参数:
+//   - batch: agent提交的事件
+//   - addr: agent的地址
+func (m *Monitor) handleAgentBatch(batch aggregator.Batch, addr string) {
+	m.hub.record(batch, addr, time.Now())
+	for _, e := range batch.Events {
+		original := e
+		e.Seq = 0
+		e.Message = fmt.Sprintf("[%s] %s", batch.Host, e.Message)
+		m.events.Publish(e)
+		m.notifyAgentEvent(batch.Host, original)
+	}
+}
+
+// record 更新agent与跨服务器来源的统计
+func (h *agentHub) record(batch aggregator.Batch, addr string, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	agent := h.agents[batch.Host]
+	if agent == nil {
+		agent = &control.AgentStatus{Host: batch.Host}
+		h.agents[batch.Host] = agent
+	}
+	agent.Addr, agent.LastSeen = addr, now
+	agent.Dropped += int64(batch.Dropped)
+	for _, e := range batch.Events {
+		count := int64(e.Count())
+		agent.Events += count
+		switch e.Type {
+		case event.TypeLoginFailed:
+			agent.Failures += count
+			source := h.sources[e.IP]
+			if source == nil {
+				source = &fleetSource{hosts: make(map[string]bool)}
+				h.sources[e.IP] = source
+			}
+			source.hosts[batch.Host] = true
+			source.failures += count
+			source.last = now
+		case event.TypeLoginSuccess:
+			agent.Successes += count
+		case event.TypeBanned:
+			agent.Bans += count
+		}
+	}
+}
+
+// claim 检查去重窗口内是否已发送过同样的通知，未发送过时记录本次发送
+func (h *agentHub) claim(key string, window time.Duration, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if last, ok := h.notified[key]; ok && now.Sub(last) < window {
+		return false
+	}
+	h.notified[key] = now
+	return true
+}
+
+// notifyAgentEvent 为agent的登录成功、封禁和解封事件发送通知
+// 同一IP在多台服务器上的同类事件（登录成功还需用户名相同）在dedup_window内只通知一次
+func (m *Monitor) notifyAgentEvent(host string, e event.Event) {
+	switch e.Type {
+	case event.TypeLoginSuccess, event.TypeBanned, event.TypeUnbanned:
+	default:
+		return
+	}
+	if window := time.Duration(m.config.Aggregator.DedupWindow); window > 0 {
+		key := fmt.Sprintf("%s|%s|%s", e.Type, e.IP, e.User)
+		if !m.hub.claim(key, window, time.Now()) {
+			m.logger.WithFields(logrus.Fields{"ip": e.IP, "host": host}).Debug("其他服务器已发送过同样的通知，跳过")
+			return
+		}
+	}
+
+	// 补充来源信息交给查询协程，不阻塞agent批次的处理
+	m.afterLookup(e.IP, nil, func(enriched *enrich.Result) {
+		info := enriched.Format()
+		var n notification.Event
+		switch {
+		case e.Type == event.TypeLoginSuccess && e.User == rootUser:
+			n = notification.RootLoginEvent(e.IP, info, host, true, 0, m.config.SSHProtection.MaxFailedAttempts, e.Time).WithClient(e.Client)
+		case e.Type == event.TypeLoginSuccess:
+			n = notification.LoginSuccessEvent(e.IP, info, host, e.Time).WithClient(e.Client).WithUser(e.User)
+		case e.Type == event.TypeBanned:
+			var duration time.Duration
+			if !e.Expires.IsZero() {
+				duration = e.Expires.Sub(e.Time)
+			}
+			n = notification.IPBannedEvent(e.IP, info, host, e.Message, duration, e.Expires, e.Time)
+		default:
+			n = notification.IPUnbannedEvent(e.IP, info, host, e.Message, e.Time)
+		}
+		m.notifier.Notify(n.WithCountry(countryOf(enriched.Geo)))
+	})
+}
+
+// prune 清理去重窗口和统计窗口之外的记录
+func (h *agentHub) prune(window time.Duration, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key, at := range h.notified {
+		if now.Sub(at) >= window {
+			delete(h.notified, key)
+		}
+	}
+	for ip, source := range h.sources {
+		if now.Sub(source.last) >= fleetAttackerWindow {
+			delete(h.sources, ip)
+		}
+	}
+}
+
+// pruneAgentHub 清理集中监控的过期记录，未启用server模式时不做任何事
+func (m *Monitor) pruneAgentHub() {
+	if m.hub == nil {
+		return
+	}
+	m.hub.prune(time.Duration(m.config.Aggregator.DedupWindow), time.Now())
+}
+
+// status 返回各agent的统计，以及在最多台服务器上登录失败的来源
+func (h *agentHub) status() ([]control.AgentStatus, []control.FleetAttacker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	agents := make([]control.AgentStatus, 0, len(h.agents))
+	for _, agent := range h.agents {
+		agents = append(agents, *agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Host < agents[j].Host })
+
+	var attackers []control.FleetAttacker
+	for ip, source := range h.sources {
+		if len(source.hosts) < 2 {
+			continue
+		}
+		hosts := make([]string, 0, len(source.hosts))
+		for host := range source.hosts {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		attackers = append(attackers, control.FleetAttacker{IP: ip, Hosts: hosts, Failures: source.failures})
+	}
+	sort.Slice(attackers, func(i, j int) bool {
+		if len(attackers[i].Hosts) != len(attackers[j].Hosts) {
+			return len(attackers[i].Hosts) > len(attackers[j].Hosts)
+		}
+		return attackers[i].Failures > attackers[j].Failures
+	})
+	if len(attackers) > fleetAttackerLimit {
+		attackers = attackers[:fleetAttackerLimit]
+	}
+	return agents, attackers
+}
@@ -0,0 +1,155 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// 检查临时白名单到期与续期提醒的间隔
+const allowCheckInterval = time.Minute
+
+// temporaryAllow 一条有有效期的白名单条目
+type temporaryAllow struct {
+	network *net.IPNet
+	store.Allow
+}
+
+// loadAllows 从存储加载临时白名单，已过期的记录被删除
+// 返回:
+//   - error: 读取存储失败时的错误信息
+func (m *Monitor) loadAllows() error {
+	allows, err := m.store.Allows()
+	if err != nil {
+		return fmt.Errorf("读取临时白名单失败: %v", err)
+	}
+	now := time.Now()
+	for _, allow := range allows {
+		network, err := config.ParseNetwork(allow.Entry)
+		if err != nil || !now.Before(allow.ExpiresAt) {
+			if err := m.store.DeleteAllow(allow.Entry); err != nil {
+				m.logger.WithError(err).WithField("entry", allow.Entry).Warn("删除临时白名单记录失败")
+			}
+			continue
+		}
+		m.whitelist.temporary = append(m.whitelist.temporary, temporaryAllow{network: network, Allow: allow})
+	}
+	return nil
+}
+
+// AllowTemporary 添加有有效期的白名单条目，有效期为whitelist.allow_ttl
+// 条目已存在时从现在起重新计算有效期并重新发送到期提醒；匹配的已有封禁会立即解除
+// 参数:
+//   - entry: IP或CIDR网段
+//   - requestedBy: 申请人，记录在存储中
+// 返回:
+//   - time.Time: 到期时间
+//   - error: 条目无效或保存失败时的错误信息
+func (m *Monitor) AllowTemporary(entry, requestedBy string) (time.Time, error) {
+	return m.allowTemporary(entry, requestedBy, SourceControl)
+}
+
+// allowTemporary 添加临时白名单，source为审计记录中的触发来源
+func (m *Monitor) allowTemporary(entry, requestedBy, source string) (time.Time, error) {
+	network, err := config.ParseNetwork(entry)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 单个IP不带掩码保存，与封禁通知中的IP一致
+	key := network.String()
+	if ones, bits := network.Mask.Size(); ones == bits {
+		key = network.IP.String()
+	}
+
+	now := time.Now()
+	allow := store.Allow{
+		Entry:       key,
+		RequestedBy: requestedBy,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Duration(m.config.Whitelist.AllowTTL)),
+	}
+	renewed := false
+	for i, existing := range m.whitelist.temporary {
+		if existing.Entry == allow.Entry {
+			allow.CreatedAt = existing.CreatedAt
+			m.whitelist.temporary[i].Allow = allow
+			renewed = true
+		}
+	}
+	if !renewed {
+		m.whitelist.temporary = append(m.whitelist.temporary, temporaryAllow{network: network, Allow: allow})
+	}
+	if err := m.store.PutAllow(allow); err != nil {
+		return time.Time{}, fmt.Errorf("保存临时白名单失败: %v", err)
+	}
+	m.audit(auditAllow, allow.Entry, source, fmt.Sprintf("申请人 %s，到期时间 %s", requestedBy, allow.ExpiresAt.Format("2006-01-02 15:04:05")))
+	m.releaseWhitelisted()
+	if !renewed {
+		m.reapplyFeeds()
+	}
+
+	fields := logrus.Fields{
+		"entry":        allow.Entry,
+		"requested_by": requestedBy,
+		"expire":       allow.ExpiresAt.Format("2006-01-02 15:04:05"),
+	}
+	if renewed {
+		m.logger.WithFields(fields).Info("临时白名单已续期")
+	} else {
+		m.logger.WithFields(fields).Info("已添加临时白名单")
+	}
+	return allow.ExpiresAt, nil
+}
+
+// expireAllows 定期移除到期的临时白名单，并在到期前发送续期提醒
+func (m *Monitor) expireAllows() {
+	ticker := time.NewTicker(allowCheckInterval)
+	for range ticker.C {
+		m.mu.Lock()
+		m.checkAllows(time.Now())
+		m.mu.Unlock()
+	}
+}
+
+// checkAllows 移除到期的临时白名单，对进入提醒时间的条目发送一次续期提醒
+// 调用方需持有m.mu锁
+func (m *Monitor) checkAllows(now time.Time) {
+	reminder := time.Duration(m.config.Whitelist.AllowReminderMinutes) * time.Minute
+	kept := m.whitelist.temporary[:0]
+	expired := false
+	for _, allow := range m.whitelist.temporary {
+		if !now.Before(allow.ExpiresAt) {
+			expired = true
+			if err := m.store.DeleteAllow(allow.Entry); err != nil {
+				m.logger.WithError(err).WithField("entry", allow.Entry).Warn("删除临时白名单记录失败")
+			}
+			m.logger.WithFields(logrus.Fields{
+				"entry":        allow.Entry,
+				"requested_by": allow.RequestedBy,
+			}).Info("临时白名单已到期")
+			m.audit(auditWhitelistRemove, allow.Entry, SourceExpiry, "临时白名单到期，申请人 "+allow.RequestedBy)
+			continue
+		}
+		if reminder > 0 && !allow.Reminded && !now.Before(allow.ExpiresAt.Add(-reminder)) {
+			allow.Reminded = true
+			if err := m.store.PutAllow(allow.Allow); err != nil {
+				m.logger.WithError(err).WithField("entry", allow.Entry).Warn("保存临时白名单失败")
+			}
+			m.notifier.Notify(notification.AllowExpiringEvent(allow.Entry, allow.RequestedBy, m.serverName(), allow.ExpiresAt, now))
+		}
+		kept = append(kept, allow)
+	}
+	m.whitelist.temporary = kept
+	if expired {
+		m.reapplyFeeds()
+	}
+}
@@ -0,0 +1,156 @@
+package monitor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/store"
+	"gopkg.in/yaml.v2"
+)
+
+// 审计记录的操作类型
+const (
+	auditBan             = "ban"              // 临时封禁IP或网段
+	auditPermanent       = "permanent"        // 永久封禁
+	auditLimit           = "limit"            // 限速
+	auditUnban           = "unban"            // 解除封禁或限速
+	auditWhitelistAdd    = "whitelist_add"    // 添加白名单
+	auditWhitelistRemove = "whitelist_remove" // 删除白名单或临时白名单
+	auditAllow           = "allow"            // 添加或续期临时白名单
+	auditJailEnable      = "jail_enable"      // 启用jail
+	auditJailDisable     = "jail_disable"     // 停用jail
+	auditAccountLock     = "account_lock"     // 锁定账户
+	auditAccountUnlock   = "account_unlock"   // 解锁账户
+	auditFeedUpdate      = "feed_update"      // 订阅黑名单的条目变化
+	auditConfigLoad      = "config_load"      // 启动时加载配置
+)
+
+// 审计记录的触发来源
+const (
+	SourceLog       = "log"       // 日志匹配
+	SourceControl   = "control"   // 本地控制接口，即ssh_fb命令行
+	SourceAPI       = "api"       // 本机TCP上的HTTP接口
+	SourceTelegram  = "telegram"  // Telegram命令
+	SourceFleet     = "fleet"     // 集群中其他服务器的变更
+	SourceFeed      = "feed"      // 订阅黑名单与CrowdSec
+	SourceExpiry    = "expiry"    // 到期自动解除
+	SourceWhitelist = "whitelist" // 来源加入白名单后自动解除
+	SourceRecovery  = "recovery"  // 启动时恢复或回滚未完成的封禁
+	SourceSystem    = "system"    // 守护进程自身，如启动
+)
+
+// auditLog 只追加的JSON审计日志文件，每行一条记录
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openAuditLog 以追加方式打开审计日志文件，文件只允许所有者读写
+// 参数:
+//   - path: 文件路径
+// 返回:
+//   - *auditLog: 打开的审计日志
+//   - error: 无法打开文件时的错误信息
+func openAuditLog(path string) (*auditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %v", err)
+	}
+	return &auditLog{file: file}, nil
+}
+
+// write 追加一条记录
+func (l *auditLog) write(entry store.AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(data, '\n'))
+	return err
+}
+
+// Close 关闭文件
+func (l *auditLog) Close() error {
+	return l.file.Close()
+}
+
+// audit 记录一次执法操作，同时写入存储和审计日志文件
+// 不获取m.mu锁，持有锁时也可以调用
+// 参数:
+//   - action: 操作类型，见audit*常量
+//   - target: 操作对象，如IP、网段、用户名或jail名称
+//   - source: 触发来源，见Source*常量
+//   - detail: 补充说明，如封禁原因
+func (m *Monitor) audit(action, target, source, detail string) {
+	entry := store.AuditEntry{Time: time.Now(), Action: action, Target: target, Source: source, Detail: detail}
+	if err := m.store.AppendAudit(entry); err != nil {
+		m.logger.WithError(err).WithField("action", action).Warn("保存审计记录失败")
+	}
+	if m.auditLog != nil {
+		if err := m.auditLog.write(entry); err != nil {
+			m.logger.WithError(err).WithField("action", action).Warn("写入审计日志失败")
+		}
+	}
+}
+
+// auditConfigLoaded 记录启动时加载的配置，详情中的摘要可用于判断两次启动之间配置是否被修改
+func (m *Monitor) auditConfigLoaded() {
+	detail := "守护进程启动"
+	if data, err := yaml.Marshal(m.config); err == nil {
+		sum := sha256.Sum256(data)
+		detail += "，配置摘要 " + hex.EncodeToString(sum[:6])
+	}
+	m.audit(auditConfigLoad, "", SourceSystem, detail)
+}
+
+// As 返回以指定触发来源记录审计日志的控制器，如供Telegram命令使用
+// Monitor自身实现的控制器方法以SourceControl记录
+// 参数:
+//   - source: 触发来源，见Source*常量
+// 返回:
+//   - control.Controller: 控制器
+func (m *Monitor) As(source string) control.Controller {
+	return sourcedController{Monitor: m, source: source}
+}
+
+// sourcedController 以固定的触发来源执行管理操作
+type sourcedController struct {
+	*Monitor
+	source string
+}
+
+func (c sourcedController) Ban(ip string, duration time.Duration) error {
+	return c.ban(ip, duration, c.source)
+}
+
+func (c sourcedController) Unban(ip string) error {
+	return c.unban(ip, c.source)
+}
+
+func (c sourcedController) MakePermanent(ip string) error {
+	return c.makePermanentBy(ip, c.source)
+}
+
+func (c sourcedController) AddWhitelist(entry string) error {
+	return c.addWhitelist(entry, c.source)
+}
+
+func (c sourcedController) RemoveWhitelist(entry string) error {
+	return c.removeWhitelist(entry, c.source)
+}
+
+func (c sourcedController) AllowTemporary(entry, requestedBy string) (time.Time, error) {
+	return c.allowTemporary(entry, requestedBy, c.source)
+}
+
+func (c sourcedController) SetJailEnabled(name string, enabled bool) error {
+	return c.setJailEnabled(name, enabled, c.source)
+}
@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// 计算分位数时保留的最近样本数
+const banLatencySamples = 1000
+
+// latencySamples 固定容量的耗时样本环形缓冲区
+type latencySamples struct {
+	values []time.Duration // 样本，写满后从头覆盖
+	next   int             // 下一个样本写入的位置
+	max    time.Duration   // 启动以来的最大耗时
+	last   time.Duration   // 最近一个样本
+}
+
+// observe 记录一个样本
+func (s *latencySamples) observe(d time.Duration) {
+	if len(s.values) < banLatencySamples {
+		s.values = append(s.values, d)
+	} else {
+		s.values[s.next] = d
+	}
+	s.next = (s.next + 1) % banLatencySamples
+	s.max = max(s.max, d)
+	s.last = d
+}
+
+// stats 计算当前样本的分位数
+func (s *latencySamples) stats() control.LatencyStats {
+	stats := control.LatencyStats{Max: s.max.Seconds(), Last: s.last.Seconds()}
+	if len(s.values) == 0 {
+		return stats
+	}
+	sorted := append([]time.Duration(nil), s.values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.P50 = percentile(sorted, 0.50).Seconds()
+	stats.P99 = percentile(sorted, 0.99).Seconds()
+	return stats
+}
+
+// percentile 返回已排序样本的分位数，取不小于该比例的最小样本
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(float64(len(sorted))*p+0.999999) - 1
+	return sorted[max(min(i, len(sorted)-1), 0)]
+}
+
+// banLatencyStats 封禁生效耗时统计
+type banLatencyStats struct {
+	mu       sync.Mutex
+	count    int64          // 统计的封禁次数
+	overSLO  int64          // 超过耗时目标的次数
+	total    latencySamples // 从读取到日志行到规则生效
+	firewall latencySamples // 防火墙操作本身
+}
+
+// stats 返回耗时统计的快照
+// 参数:
+//   - slo: 耗时目标，0表示不检查
+// 返回:
+//   - control.BanLatencyStats: 耗时统计
+func (b *banLatencyStats) stats(slo time.Duration) control.BanLatencyStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return control.BanLatencyStats{
+		Count:    b.count,
+		SLO:      slo.Seconds(),
+		OverSLO:  b.overSLO,
+		Total:    b.total.stats(),
+		Firewall: b.firewall.stats(),
+	}
+}
+
+// banLatencySLO 返回配置的封禁生效耗时目标
+func (m *Monitor) banLatencySLO() time.Duration {
+	return time.Duration(m.config.SSHProtection.BanLatencySLOMs) * time.Millisecond
+}
+
+// recordBanLatency 记录一次封禁或限速从读取到日志行到防火墙规则生效的耗时
+// 手动封禁和启动时恢复的规则没有对应的日志行，不参与统计；
+// 超过耗时目标时记录警告，通常说明处理队列积压或防火墙后端响应慢
+// 参数:
+//   - ip: 被处置的IP地址
+//   - observed: 读取到触发日志行的时间，零值时不统计
+//   - applyStart: 开始调用防火墙的时间
+func (m *Monitor) recordBanLatency(ip string, observed, applyStart time.Time) {
+	if observed.IsZero() {
+		return
+	}
+	now := time.Now()
+	total := max(now.Sub(observed), 0)
+	firewall := now.Sub(applyStart)
+	slo := m.banLatencySLO()
+
+	m.banLatency.mu.Lock()
+	m.banLatency.count++
+	m.banLatency.total.observe(total)
+	m.banLatency.firewall.observe(firewall)
+	over := slo > 0 && total > slo
+	if over {
+		m.banLatency.overSLO++
+	}
+	m.banLatency.mu.Unlock()
+
+	if over {
+		m.logger.WithFields(logrus.Fields{
+			"ip":       ip,
+			"latency":  total.Round(time.Millisecond),
+			"firewall": firewall.Round(time.Millisecond),
+			"slo":      slo,
+		}).Warn("封禁生效耗时超过目标")
+	}
+}
@@ -0,0 +1,100 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// summaryEntry 启动时生效配置摘要中的一项
+type summaryEntry struct {
+	field string // 日志字段名
+	label string // 通知中显示的名称
+	value string // 内容
+}
+
+// announceStartup 记录启动时的生效配置摘要，并在启用startup通知时发送一次
+// 摘要在加载jail状态、黑名单与白名单之后生成，反映的是实际生效的状态而不只是配置文件
+func (m *Monitor) announceStartup() {
+	entries := m.startupSummary()
+
+	fields := make(logrus.Fields, len(entries))
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		fields[e.field] = e.value
+		lines[i] = fmt.Sprintf("%s: %s", e.label, e.value)
+	}
+	m.logger.WithFields(fields).Info("SSH防护系统已启动")
+
+	m.notifier.Notify(notification.StartupEvent(strings.Join(lines, "\n"), m.serverName(), time.Now()))
+}
+
+// startupSummary 汇总防火墙后端、日志来源、jail、阈值、通知渠道与存储的生效配置
+// 返回:
+//   - []summaryEntry: 按固定顺序排列的摘要
+func (m *Monitor) startupSummary() []summaryEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cfg := m.config
+	protection := cfg.SSHProtection
+
+	backend := "ufw，封禁时丢弃流量"
+	if protection.Tarpit.Enabled {
+		backend = fmt.Sprintf("ufw，封禁的SSH连接重定向到tarpit端口%d", protection.Tarpit.Port)
+	}
+	if protection.RateLimit.Enabled {
+		backend += fmt.Sprintf("，首次违规限速为每分钟%d个连接", protection.RateLimit.ConnectionsPerMinute)
+	}
+
+	sources := []string{fmt.Sprintf("%s=%s", jailSSHD, protection.SSHLogFile)}
+	if cfg.Jails.HAProxy.Enabled {
+		sources = append(sources, fmt.Sprintf("%s=%s", jailHAProxy, cfg.Jails.HAProxy.LogFile))
+	}
+	for _, r := range cfg.Rules {
+		sources = append(sources, fmt.Sprintf("%s=%s", r.Name, r.LogFile))
+	}
+
+	var jails []string
+	for _, name := range m.jailNames() {
+		state := "启用"
+		if m.disabledJails[name] {
+			state = "停用"
+		}
+		jails = append(jails, fmt.Sprintf("%s(%s)", name, state))
+	}
+
+	thresholds := fmt.Sprintf("失败%d次封禁%s", protection.MaxFailedAttempts, protection.BanDuration)
+	if protection.RootLogin.BanImmediately {
+		thresholds += "，root失败1次即封禁"
+	} else if protection.RootLogin.MaxFailedAttempts > 0 {
+		thresholds += fmt.Sprintf("，root失败%d次", protection.RootLogin.MaxFailedAttempts)
+	}
+	if protection.Preauth.Enabled {
+		thresholds += fmt.Sprintf("，%s内认证前断开%d次", protection.Preauth.Window, protection.Preauth.MaxConnections)
+	}
+	if len(protection.Users) > 0 {
+		thresholds += fmt.Sprintf("，%d个用户名单独设置", len(protection.Users))
+	}
+
+	channels := "无，事件只记录在日志中"
+	if d, ok := m.notifier.(interface{ Channels() []string }); ok && len(d.Channels()) > 0 {
+		channels = strings.Join(d.Channels(), ", ")
+	}
+
+	state := fmt.Sprintf("临时封禁%d个，永久封禁%d个，限速%d个，白名单%d条",
+		len(m.bannedIPs), len(m.permanentIPs), len(m.limitedIPs), len(m.whitelist.networks)+len(m.whitelist.runtime))
+
+	return []summaryEntry{
+		{"backend", "防火墙", backend},
+		{"sources", "日志来源", strings.Join(sources, ", ")},
+		{"jails", "jail", strings.Join(jails, ", ")},
+		{"thresholds", "阈值", thresholds},
+		{"channels", "通知渠道", channels},
+		{"store", "存储", cfg.Store.Driver},
+		{"state", "当前状态", state},
+	}
+}
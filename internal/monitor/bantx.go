@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// recoverBans 处理上次运行中未完成的封禁
+// 存储中仍为pending的记录表示进程在添加防火墙规则前后退出：
+// 封禁尚未到期的重新添加规则并补全为正式封禁，已到期或重新添加失败的删除规则和记录；
+// 已生效的限时封禁不在黑名单文件中时，表示进程在黑名单文件延迟保存之前退出，按存储中的记录恢复
+// 必须在loadBlacklist之后、syncStoreBans之前调用，调用方需持有m.mu锁
+func (m *Monitor) recoverBans() {
+	bans, err := m.store.Bans()
+	if err != nil {
+		m.logger.WithError(err).Warn("读取存储中的封禁记录失败，跳过未完成封禁的恢复")
+		return
+	}
+
+	recovered := false
+	for _, ban := range bans {
+		if ban.State != store.StatePending {
+			if _, known := m.bannedIPs[ban.IP]; !known && ban.Type == banTypeTemporary && !m.permanentIPs[ban.IP] {
+				m.bannedIPs[ban.IP] = ban.ExpiresAt
+				m.trackSubnet(ban.IP)
+				recovered = true
+				m.logger.WithFields(logrus.Fields{"ip": ban.IP, "expires_at": ban.ExpiresAt}).Info("已按存储中的记录恢复未写入黑名单文件的封禁")
+			}
+			continue
+		}
+		fields := logrus.Fields{"ip": ban.IP, "type": ban.Type}
+
+		if ban.Type == banTypeTemporary && time.Now().After(ban.ExpiresAt) {
+			m.rollbackBan(ban.IP)
+			m.logger.WithFields(fields).Info("未完成的封禁已到期，已回滚")
+			continue
+		}
+		if m.whitelist.contains(ban.IP) {
+			m.rollbackBan(ban.IP)
+			m.logger.WithFields(fields).Info("未完成的封禁来源已加入白名单，已回滚")
+			continue
+		}
+		if err := m.blockIP(ban.IP); err != nil {
+			m.logger.WithError(err).WithFields(fields).Error("补全未完成的封禁失败，已回滚")
+			m.rollbackBan(ban.IP)
+			continue
+		}
+
+		if ban.Type == banTypePermanent {
+			m.permanentIPs[ban.IP] = true
+		} else {
+			m.bannedIPs[ban.IP] = ban.ExpiresAt
+		}
+		m.trackSubnet(ban.IP)
+		ban.State = store.StateApplied
+		if err := m.store.PutBan(ban); err != nil {
+			m.logger.WithError(err).WithFields(fields).Warn("标记封禁已生效失败")
+		}
+		recovered = true
+		m.logger.WithFields(fields).Info("未完成的封禁已补全")
+		action := auditBan
+		if ban.Type == banTypePermanent {
+			action = auditPermanent
+		}
+		m.audit(action, ban.IP, SourceRecovery, "补全未完成的封禁："+ban.Reason)
+	}
+
+	if recovered {
+		m.saveBlacklist()
+	}
+}
+
+// rollbackBan 撤销未完成的封禁
+// 规则可能根本没有添加，删除规则失败只记录调试日志
+func (m *Monitor) rollbackBan(ip string) {
+	if err := m.unblockIP(ip); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Debug("回滚封禁时删除防火墙规则失败")
+	}
+	if err := m.store.DeleteBan(ip); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Warn("删除未完成的封禁记录失败")
+	}
+	m.audit(auditUnban, ip, SourceRecovery, "回滚未完成的封禁")
+}
@@ -0,0 +1,342 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/fleet"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// 黑名单文件中的封禁类型
+const (
+	banTypeTemporary = "temporary"
+	banTypePermanent = "permanent"
+	banTypeLimited   = "limited"
+)
+
+// blacklistVersion 当前黑名单文件格式的版本
+// 版本2为JSON，记录每条封禁的解封时间与原因；版本1每行为 "<IP> <类型>"，仅有IP的行按临时封禁处理，读取时仍然支持
+const blacklistVersion = 2
+
+// blacklistFile 黑名单文件的内容
+type blacklistFile struct {
+	Version int              `json:"version"`
+	Bans    []blacklistEntry `json:"bans"`
+}
+
+// blacklistEntry 黑名单文件中的一条封禁
+type blacklistEntry struct {
+	IP        string    `json:"ip"`
+	Type      string    `json:"type"`                 // 封禁类型：temporary、permanent或limited
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 解封时间，永久封禁和旧格式文件中为零值
+	Reason    string    `json:"reason,omitempty"`     // 封禁原因
+}
+
+// loadBlacklist 从文件加载黑名单，并合并配置中的永久封禁列表
+// 按文件中记录的解封时间恢复封禁，停止期间已到期的封禁由下一次定期清理解除；
+// 旧格式文件没有解封时间，从现在起按配置的时长重新计算
+// 返回:
+//   - error: 加载过程中的错误信息
+func (m *Monitor) loadBlacklist() error {
+	file, err := os.OpenFile(m.config.Blacklist.File, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := parseBlacklist(file)
+	if err != nil {
+		return err
+	}
+	for ip, entry := range entries {
+		switch entry.Type {
+		case banTypePermanent:
+			m.permanentIPs[ip] = true
+		case banTypeLimited:
+			m.limitedIPs[ip] = expiresOr(entry.ExpiresAt, time.Duration(m.config.SSHProtection.RateLimit.Duration))
+		default:
+			m.bannedIPs[ip] = expiresOr(entry.ExpiresAt, m.banDuration())
+		}
+		m.trackSubnet(ip)
+	}
+	m.restoreBanReasons(entries)
+
+	// 配置中的永久封禁IP每次启动都确保防火墙规则存在
+	for _, ip := range m.config.Blacklist.Permanent {
+		m.permanentIPs[ip] = true
+		m.trackSubnet(ip)
+		delete(m.bannedIPs, ip)
+		if err := m.blockIP(ip); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("永久封禁IP失败")
+		}
+	}
+
+	return nil
+}
+
+// expiresOr 返回记录的解封时间，没有记录时从现在起按duration计算
+func expiresOr(expires time.Time, duration time.Duration) time.Time {
+	if expires.IsZero() {
+		return time.Now().Add(duration)
+	}
+	return expires
+}
+
+// restoreBanReasons 存储中没有对应记录时，按黑名单文件写入封禁原因，如改用memory存储或数据库文件丢失后
+// 需要在syncStoreBans之前调用，之后同步时保留这些原因
+func (m *Monitor) restoreBanReasons(entries map[string]blacklistEntry) {
+	current, err := m.store.Bans()
+	if err != nil {
+		m.logger.WithError(err).Warn("读取封禁记录失败")
+		return
+	}
+	stored := make(map[string]bool, len(current))
+	for _, ban := range current {
+		stored[ban.IP] = true
+	}
+	for ip, entry := range entries {
+		if entry.Reason == "" || stored[ip] {
+			continue
+		}
+		ban := store.Ban{IP: ip, Type: entry.Type, ExpiresAt: m.bannedIPs[ip], State: store.StateApplied, Reason: entry.Reason}
+		switch entry.Type {
+		case banTypePermanent:
+			ban.ExpiresAt = time.Time{}
+		case banTypeLimited:
+			ban.ExpiresAt = m.limitedIPs[ip]
+		}
+		if err := m.store.PutBan(ban); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Warn("保存封禁记录失败")
+		}
+	}
+}
+
+// parseBlacklist 解析黑名单文件，内容以 { 开头时按版本2的JSON解析，否则按版本1的文本格式解析
+// 参数:
+//   - r: 黑名单文件内容
+// 返回:
+//   - map[string]blacklistEntry: IP到封禁记录的映射，版本1的记录没有解封时间和原因，仅有IP的行按临时封禁处理
+//   - error: 读取或解析失败时的错误信息
+func parseBlacklist(r io.Reader) (map[string]blacklistEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[string]blacklistEntry)
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		var file blacklistFile
+		if err := json.Unmarshal(trimmed, &file); err != nil {
+			return nil, fmt.Errorf("解析黑名单文件失败: %v", err)
+		}
+		if file.Version > blacklistVersion {
+			return nil, fmt.Errorf("黑名单文件版本%d高于当前支持的版本%d，请升级ssh_fb", file.Version, blacklistVersion)
+		}
+		for _, entry := range file.Bans {
+			if entry.Type != banTypePermanent && entry.Type != banTypeLimited {
+				entry.Type = banTypeTemporary
+			}
+			entries[entry.IP] = entry
+		}
+		return entries, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		banType := banTypeTemporary
+		if len(fields) > 1 && (fields[1] == banTypePermanent || fields[1] == banTypeLimited) {
+			banType = fields[1]
+		}
+		entries[fields[0]] = blacklistEntry{IP: fields[0], Type: banType}
+	}
+	return entries, scanner.Err()
+}
+
+// blacklistSaveDelay 封禁状态变化后延迟保存黑名单文件的时间
+// 攻击期间每秒可能有数十次封禁，同一延迟内的变化合并为一次存储同步和文件写入
+const blacklistSaveDelay = time.Second
+
+// saveBlacklist 标记封禁状态已变化，在blacklistSaveDelay后同步存储并保存黑名单文件
+// 延迟期间进程退出时，新增的限时封禁已由banIP写入存储，启动时由recoverBans恢复
+// 调用方需持有m.mu锁
+func (m *Monitor) saveBlacklist() {
+	m.blacklistDirty = true
+	if m.blacklistTimer == nil {
+		m.blacklistTimer = time.AfterFunc(blacklistSaveDelay, m.flushBlacklist)
+	}
+}
+
+// flushBlacklist 同步封禁记录到存储，并以版本2的格式保存黑名单文件
+// 存储同步在持有m.mu锁时进行，序列化和写入文件在释放锁之后进行，不阻塞封禁处理；
+// 保存失败时只记录日志，下一次封禁状态变化时重试
+func (m *Monitor) flushBlacklist() {
+	m.blacklistWrite.Lock()
+	defer m.blacklistWrite.Unlock()
+
+	m.mu.Lock()
+	m.blacklistTimer = nil
+	if !m.blacklistDirty {
+		m.mu.Unlock()
+		return
+	}
+	m.blacklistDirty = false
+	// 封禁原因取自存储，同步失败时仍然保存文件，只是缺少原因
+	records, err := m.syncStoreBans()
+	if err != nil {
+		m.logger.WithError(err).Error("同步封禁记录到存储失败")
+	}
+	file := m.blacklistSnapshot(records)
+	m.mu.Unlock()
+
+	if err := writeBlacklist(m.config.Blacklist.File, file); err != nil {
+		m.logger.WithError(err).WithField("file", m.config.Blacklist.File).Error("保存黑名单失败")
+	}
+}
+
+// blacklistSnapshot 按当前的封禁状态生成黑名单文件的内容，配置中的永久封禁IP不写入文件
+// 调用方需持有m.mu锁
+// 参数:
+//   - records: 存储中各IP的封禁记录，用于填写封禁原因
+// 返回:
+//   - blacklistFile: 按IP排序的黑名单
+func (m *Monitor) blacklistSnapshot(records map[string]store.Ban) blacklistFile {
+	file := blacklistFile{Version: blacklistVersion, Bans: make([]blacklistEntry, 0, len(m.permanentIPs)+len(m.bannedIPs)+len(m.limitedIPs))}
+	for ip := range m.permanentIPs {
+		if m.isConfigPermanent(ip) {
+			continue
+		}
+		file.Bans = append(file.Bans, blacklistEntry{IP: ip, Type: banTypePermanent, Reason: records[ip].Reason})
+	}
+	for ip, expires := range m.bannedIPs {
+		file.Bans = append(file.Bans, blacklistEntry{IP: ip, Type: banTypeTemporary, ExpiresAt: expires, Reason: records[ip].Reason})
+	}
+	for ip, expires := range m.limitedIPs {
+		file.Bans = append(file.Bans, blacklistEntry{IP: ip, Type: banTypeLimited, ExpiresAt: expires, Reason: records[ip].Reason})
+	}
+	sort.Slice(file.Bans, func(i, j int) bool { return file.Bans[i].IP < file.Bans[j].IP })
+	return file
+}
+
+// writeBlacklist 将黑名单写入文件
+// 先写入同目录下的临时文件再替换，写入中途退出时原文件保持完整
+// 参数:
+//   - path: 黑名单文件路径
+//   - file: 黑名单内容
+// 返回:
+//   - error: 序列化或写入失败时的错误信息
+func writeBlacklist(path string, file blacklistFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0644)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并刷新到磁盘，再替换目标文件
+// 参数:
+//   - path: 目标文件路径
+//   - data: 文件内容
+//   - perm: 文件权限
+// 返回:
+//   - error: 写入或替换失败时的错误信息，此时目标文件保持不变
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// MakePermanent 将IP提升为永久封禁
+// 已临时封禁的IP会转为永久封禁，未封禁的IP会立即封禁
+// 参数:
+//   - ip: 要永久封禁的IP地址
+// 返回:
+//   - error: 操作过程中的错误信息
+func (m *Monitor) MakePermanent(ip string) error {
+	return m.makePermanentBy(ip, SourceControl)
+}
+
+// makePermanentBy 手动将IP提升为永久封禁，source为审计记录中的触发来源
+func (m *Monitor) makePermanentBy(ip, source string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("无效的IP地址: %s", ip)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.whitelist.contains(ip) {
+		return fmt.Errorf("IP %s 在白名单中", ip)
+	}
+	if err := m.makePermanent(ip); err != nil {
+		return err
+	}
+	m.audit(auditPermanent, ip, source, "手动永久封禁")
+	m.publishFleet(fleet.ActionPermanent, ip, time.Time{}, "手动永久封禁")
+	return nil
+}
+
+// makePermanent 将IP提升为永久封禁，已是永久封禁时不做任何事
+// 调用方需持有m.mu锁，并已检查IP不在白名单中
+func (m *Monitor) makePermanent(ip string) error {
+	if m.permanentIPs[ip] {
+		return nil
+	}
+
+	if _, banned := m.bannedIPs[ip]; !banned {
+		if err := m.blockIP(ip); err != nil {
+			return err
+		}
+	}
+
+	m.permanentIPs[ip] = true
+	m.trackSubnet(ip)
+	delete(m.bannedIPs, ip)
+	m.failedAttempts.remove(ip)
+
+	m.saveBlacklist()
+
+	m.logger.WithField("ip", ip).Info("IP已永久封禁")
+	return nil
+}
+
+// isConfigPermanent 检查IP是否来自配置中的永久封禁列表
+func (m *Monitor) isConfigPermanent(ip string) bool {
+	for _, p := range m.config.Blacklist.Permanent {
+		if p == ip {
+			return true
+		}
+	}
+	return false
+}
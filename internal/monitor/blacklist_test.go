@@ -0,0 +1,142 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+func TestParseBlacklist(t *testing.T) {
+	expires := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]blacklistEntry
+		ok      bool
+	}{
+		{
+			name:    "版本1",
+			content: "# 注释\n1.2.3.4\n5.6.7.8 permanent\n\n9.9.9.9 limited\n10.0.0.1 unknown\n",
+			want: map[string]blacklistEntry{
+				"1.2.3.4":  {IP: "1.2.3.4", Type: banTypeTemporary},
+				"5.6.7.8":  {IP: "5.6.7.8", Type: banTypePermanent},
+				"9.9.9.9":  {IP: "9.9.9.9", Type: banTypeLimited},
+				"10.0.0.1": {IP: "10.0.0.1", Type: banTypeTemporary},
+			},
+			ok: true,
+		},
+		{
+			name:    "空文件",
+			content: "",
+			want:    map[string]blacklistEntry{},
+			ok:      true,
+		},
+		{
+			name:    "版本2",
+			content: `{"version":2,"bans":[{"ip":"1.2.3.4","type":"temporary","expires_at":"2024-03-10T12:00:00Z","reason":"失败次数过多"},{"ip":"5.6.7.8","type":"permanent"}]}`,
+			want: map[string]blacklistEntry{
+				"1.2.3.4": {IP: "1.2.3.4", Type: banTypeTemporary, ExpiresAt: expires, Reason: "失败次数过多"},
+				"5.6.7.8": {IP: "5.6.7.8", Type: banTypePermanent},
+			},
+			ok: true,
+		},
+		{
+			name:    "版本2前有空白且类型未知",
+			content: "\n  {\"version\":2,\"bans\":[{\"ip\":\"1.2.3.4\",\"type\":\"other\"}]}",
+			want: map[string]blacklistEntry{
+				"1.2.3.4": {IP: "1.2.3.4", Type: banTypeTemporary},
+			},
+			ok: true,
+		},
+		{
+			name:    "更高的版本",
+			content: `{"version":3,"bans":[]}`,
+		},
+		{
+			name:    "无效的JSON",
+			content: `{"version":2,"bans":[`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseBlacklist(strings.NewReader(tt.content))
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v，期望成功 %v", err, tt.ok)
+			}
+			if tt.ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlacklistRoundTrip(t *testing.T) {
+	expires := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	cfg := config.Default()
+	cfg.Blacklist.Permanent = []string{"8.8.8.8"}
+	m := &Monitor{
+		config:       cfg,
+		permanentIPs: map[string]bool{"5.6.7.8": true, "8.8.8.8": true},
+		bannedIPs:    map[string]time.Time{"1.2.3.4": expires},
+		limitedIPs:   map[string]time.Time{"9.9.9.9": expires.Add(time.Hour)},
+	}
+	records := map[string]store.Ban{
+		"1.2.3.4": {IP: "1.2.3.4", Reason: "失败次数过多"},
+		"5.6.7.8": {IP: "5.6.7.8", Reason: "手动永久封禁"},
+	}
+
+	path := filepath.Join(t.TempDir(), "blacklist.json")
+	if err := writeBlacklist(path, m.blacklistSnapshot(records)); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	got, err := parseBlacklist(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]blacklistEntry{
+		"1.2.3.4": {IP: "1.2.3.4", Type: banTypeTemporary, ExpiresAt: expires, Reason: "失败次数过多"},
+		"5.6.7.8": {IP: "5.6.7.8", Type: banTypePermanent, Reason: "手动永久封禁"},
+		"9.9.9.9": {IP: "9.9.9.9", Type: banTypeLimited, ExpiresAt: expires.Add(time.Hour)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v，期望 %+v", got, want)
+	}
+	for ip, entry := range want {
+		g := got[ip]
+		if g.Type != entry.Type || g.Reason != entry.Reason || !g.ExpiresAt.Equal(entry.ExpiresAt) {
+			t.Errorf("%s: got %+v，期望 %+v", ip, g, entry)
+		}
+	}
+}
+
+func TestBlacklistSnapshotSorted(t *testing.T) {
+	m := &Monitor{
+		config:       config.Default(),
+		permanentIPs: map[string]bool{"3.3.3.3": true},
+		bannedIPs:    map[string]time.Time{"1.1.1.1": {}, "2.2.2.2": {}},
+		limitedIPs:   map[string]time.Time{},
+	}
+	file := m.blacklistSnapshot(nil)
+	if file.Version != blacklistVersion {
+		t.Errorf("Version = %d，期望 %d", file.Version, blacklistVersion)
+	}
+	var ips []string
+	for _, entry := range file.Bans {
+		ips = append(ips, entry.IP)
+	}
+	if strings.Join(ips, ",") != "1.1.1.1,2.2.2.2,3.3.3.3" {
+		t.Errorf("IP顺序 %v，期望按IP排序", ips)
+	}
+}
@@ -0,0 +1,252 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/fleet"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// Bans 返回当前所有封禁和限速，永久封禁排在前面，其余按到期时间排序
+// 返回:
+//   - []control.BanInfo: 封禁列表
+func (m *Monitor) Bans() []control.BanInfo {
+	// 来源信息只保存在存储中，在加锁前读取
+	stored, err := m.store.Bans()
+	if err != nil {
+		m.logger.WithError(err).Warn("读取封禁记录失败")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	bans := make([]control.BanInfo, 0, len(m.permanentIPs)+len(m.bannedIPs)+len(m.limitedIPs))
+	for ip := range m.permanentIPs {
+		bans = append(bans, control.BanInfo{IP: ip, Permanent: true})
+	}
+	for ip, expire := range m.bannedIPs {
+		bans = append(bans, control.BanInfo{IP: ip, ExpireTime: expire})
+	}
+	for ip, expire := range m.limitedIPs {
+		bans = append(bans, control.BanInfo{IP: ip, Limited: true, ExpireTime: expire})
+	}
+
+	sources := make(map[string]store.Ban, len(stored))
+	for _, ban := range stored {
+		sources[ban.IP] = ban
+	}
+	for i := range bans {
+		if source, ok := sources[bans[i].IP]; ok {
+			bans[i].Reason, bans[i].Country, bans[i].Hostname = source.Reason, source.Country, source.Hostname
+			bans[i].ASN, bans[i].ASOrg, bans[i].NetworkType = source.ASN, source.ASOrg, source.NetworkType
+			bans[i].Origin = source.Origin
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		if bans[i].Permanent != bans[j].Permanent {
+			return bans[i].Permanent
+		}
+		if bans[i].Permanent {
+			return bans[i].IP < bans[j].IP
+		}
+		return bans[i].ExpireTime.Before(bans[j].ExpireTime)
+	})
+	return bans
+}
+
+// Ban 手动封禁IP
+// 参数:
+//   - ip: 要封禁的IP地址
+//   - duration: 封禁时长，0表示与自动封禁相同
+// 返回:
+//   - error: 操作过程中的错误信息
+func (m *Monitor) Ban(ip string, duration time.Duration) error {
+	return m.ban(ip, duration, SourceControl)
+}
+
+// ban 手动封禁IP，source为审计记录中的触发来源
+func (m *Monitor) ban(ip string, duration time.Duration, source string) error {
+	if net.ParseIP(ip) == nil {
+		return fmt.Errorf("无效的IP地址: %s", ip)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.whitelist.contains(ip) {
+		return fmt.Errorf("IP %s 在白名单中", ip)
+	}
+	if m.isIPBanned(ip) {
+		return fmt.Errorf("IP %s 已被封禁", ip)
+	}
+	if duration <= 0 {
+		duration = m.banDuration()
+	}
+	return m.banIP(ip, time.Now(), time.Time{}, nil, duration, "手动封禁", source)
+}
+
+// Unban 解除IP的封禁，临时封禁、永久封禁和限速均可解除
+// 配置文件中的永久封禁IP无法解除
+// 参数:
+//   - ip: 要解除封禁的IP地址
+// 返回:
+//   - error: 操作过程中的错误信息
+func (m *Monitor) Unban(ip string) error {
+	return m.unban(ip, SourceControl)
+}
+
+// unban 手动解除IP的封禁，source为审计记录中的触发来源
+func (m *Monitor) unban(ip, source string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.isConfigPermanent(ip) {
+		return fmt.Errorf("IP %s 在配置文件的永久封禁列表中，请修改配置后重启", ip)
+	}
+
+	_, banned := m.bannedIPs[ip]
+	_, limited := m.limitedIPs[ip]
+	blocked := banned || m.permanentIPs[ip]
+	if !blocked && !limited {
+		return fmt.Errorf("IP %s 未被封禁", ip)
+	}
+
+	if limited {
+		m.liftLimit(ip)
+	} else if err := m.unblockIP(ip); err != nil {
+		return err
+	}
+
+	delete(m.bannedIPs, ip)
+	delete(m.permanentIPs, ip)
+	m.failedAttempts.remove(ip)
+
+	m.saveBlacklist()
+
+	m.logger.WithField("ip", ip).Info("IP已手动解除封禁")
+	m.audit(auditUnban, ip, source, "手动解除")
+	if blocked {
+		m.publishFleet(fleet.ActionUnban, ip, time.Time{}, "手动解除")
+	}
+	m.events.Publish(event.Event{Type: event.TypeUnbanned, IP: ip, Message: "已手动解除封禁"})
+	// 补充来源信息可能需要数秒，交给查询协程，不持有锁等待
+	unbannedAt := time.Now()
+	m.afterLookup(ip, nil, func(enriched *enrich.Result) {
+		m.notifier.Notify(notification.IPUnbannedEvent(ip, enriched.Format(), m.serverName(), "手动解除", unbannedAt).WithCountry(countryOf(enriched.Geo)))
+	})
+	return nil
+}
+
+// Events 返回序号大于since的最近事件
+// 参数:
+//   - since: 调用方已读取的最大事件序号
+// 返回:
+//   - []event.Event: 新事件列表
+func (m *Monitor) Events(since uint64) []event.Event {
+	return m.events.Since(since)
+}
+
+// TopAttackers 返回累计失败次数最多的IP
+// 参数:
+//   - limit: 返回的最大条数
+// 返回:
+//   - []control.Attacker: 按失败次数降序排列的攻击来源
+func (m *Monitor) TopAttackers(limit int) []control.Attacker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	attackers := make([]control.Attacker, 0, len(m.totalFailures))
+	for ip, failures := range m.totalFailures {
+		_, banned := m.bannedIPs[ip]
+		attackers = append(attackers, control.Attacker{
+			IP:       ip,
+			Failures: failures,
+			Banned:   banned || m.permanentIPs[ip],
+		})
+	}
+
+	sort.Slice(attackers, func(i, j int) bool {
+		if attackers[i].Failures != attackers[j].Failures {
+			return attackers[i].Failures > attackers[j].Failures
+		}
+		return attackers[i].IP < attackers[j].IP
+	})
+	if len(attackers) > limit {
+		attackers = attackers[:limit]
+	}
+	return attackers
+}
+
+// Stats 返回封禁与登录失败的统计
+// 返回:
+//   - control.Stats: 统计
+func (m *Monitor) Stats() control.Stats {
+	now := time.Now()
+	stats := control.Stats{
+		FailuresLastHour: m.recentFailures.lastHour(now),
+		Events:           make(map[event.Type]int),
+		TopAttackers:     m.TopAttackers(10),
+	}
+	events, err := m.store.Events(now.Add(-24*time.Hour), 0)
+	if err != nil {
+		m.logger.WithError(err).Warn("读取事件记录失败")
+	}
+	for _, e := range events {
+		stats.Events[e.Type]++
+	}
+
+	m.mu.RLock()
+	stats.Permanent = len(m.permanentIPs)
+	stats.Banned = len(m.bannedIPs) + stats.Permanent
+	stats.Limited = len(m.limitedIPs)
+	stats.Attackers = len(m.totalFailures)
+	m.mu.RUnlock()
+	return stats
+}
+
+// Status 返回守护进程的运行状态
+// 返回:
+//   - control.Status: 运行状态
+func (m *Monitor) Status() control.Status {
+	status := control.Status{
+		Started:          m.startedAt,
+		FailuresLastHour: m.recentFailures.lastHour(time.Now()),
+		Firewall:         control.FirewallStatus{Backend: "ufw"},
+		Jails:            m.Jails(),
+		IPInfo:           m.ipInfo.Stats(),
+		LogLag:           m.logLag.stats(m.queuedLogins()),
+		BanLatency:       m.banLatency.stats(m.banLatencySLO()),
+		Sampling:         m.samplingStats(),
+		Feeds:            m.feedStatus(),
+	}
+	if active, err := m.firewall.Active(); err != nil {
+		status.Firewall.Error = err.Error()
+	} else {
+		status.Firewall.Active = active
+	}
+
+	m.mu.RLock()
+	status.Permanent = len(m.permanentIPs)
+	status.Banned = len(m.bannedIPs) + status.Permanent
+	status.Limited = len(m.limitedIPs)
+	m.mu.RUnlock()
+	if m.agent != nil {
+		status.Forwarder = m.agent.status(m.config.Aggregator.URL)
+	}
+	if m.hub != nil {
+		status.Agents, status.FleetAttackers = m.hub.status()
+	}
+	if d, ok := m.notifier.(interface{ Health() []control.ChannelHealth }); ok {
+		status.Channels = d.Health()
+	}
+	status.Resources = m.resourceUsage(status.Channels)
+	return status
+}
@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// countryCodeOf 返回IP信息中的国家代码，查询失败或接口未返回时为空
+func countryCodeOf(info *ipinfo.IPInfo) string {
+	if info == nil {
+		return ""
+	}
+	return info.CountryCode
+}
+
+// isBlockedCountry 检查国家是否在country_policy.block中
+func (m *Monitor) isBlockedCountry(code string) bool {
+	return config.ContainsCountry(m.config.SSHProtection.CountryPolicy.Block, code)
+}
+
+// isForeignCountry 检查国家是否不在country_policy.home中，未设置home或国家未知时为false
+func (m *Monitor) isForeignCountry(code string) bool {
+	home := m.config.SSHProtection.CountryPolicy.Home
+	return len(home) > 0 && code != "" && !config.ContainsCountry(home, code)
+}
+
+// countryBanDuration 返回按国家策略封禁的时长
+func (m *Monitor) countryBanDuration() time.Duration {
+	if d := m.config.SSHProtection.CountryPolicy.BanDuration; d > 0 {
+		return time.Duration(d)
+	}
+	return m.banDuration()
+}
+
+// checkCountryBlock 事件来自country_policy.block中的国家时直接封禁，不经过失败计数
+// 用于新建连接和认证前断开事件；属地交给补充信息查询协程查询，不阻塞日志处理，
+// 查询完成后再按国家封禁，查询队列已满或属地查询失败时不封禁
+// 白名单和临时信任的IP不会被封禁
+// 参数:
+//   - e: 新建连接或认证前断开事件
+func (m *Monitor) checkCountryBlock(e LoginEvent) {
+	if len(m.config.SSHProtection.CountryPolicy.Block) == 0 {
+		return
+	}
+	// isTrusted会删除过期的信任记录，需要持有写锁
+	m.mu.Lock()
+	skip := m.whitelist.contains(e.IP) || m.isTrusted(e.IP, e.Timestamp) || m.isIPBanned(e.IP)
+	m.mu.Unlock()
+	if skip {
+		return
+	}
+
+	// 回调执行时该行日志的跟踪可能已经结束，封禁不再记入跟踪
+	span := e.Span
+	e.Span = nil
+	m.afterLookup(e.IP, span, func(r *enrich.Result) {
+		code := countryCodeOf(r.Geo)
+		if !m.isBlockedCountry(code) {
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.whitelist.contains(e.IP) || m.isTrusted(e.IP, e.Timestamp) || m.isIPBanned(e.IP) {
+			return
+		}
+		m.banCountry(e.IP, code, e)
+	})
+}
+
+// banCountry 以国家策略封禁IP
+// 调用方需持有m.mu锁
+func (m *Monitor) banCountry(ip, code string, e LoginEvent) {
+	m.logger.WithFields(logrus.Fields{
+		"ip":      ip,
+		"country": code,
+		"outcome": e.Outcome,
+	}).Warn("来源国家在封禁列表中")
+	if err := m.banIP(ip, e.Timestamp, e.Observed, e.Span, m.countryBanDuration(), "来源国家 "+code+" 在封禁列表中", SourceLog); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+	}
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/crowdsec"
+)
+
+// newCrowdSec 按配置创建CrowdSec本地API客户端
+// 返回:
+//   - *crowdsec.Client: 未启用CrowdSec集成时为nil
+func newCrowdSec(cfg config.CrowdSecConfig) *crowdsec.Client {
+	if !cfg.Enabled {
+		return nil
+	}
+	return crowdsec.New(cfg)
+}
+
+// crowdsecPuller 跟踪从本地API拉取的有效决策
+// 只在所属订阅的work锁内访问
+type crowdsecPuller struct {
+	client  *crowdsec.Client
+	config  config.CrowdSecConfig
+	started bool                      // 是否已完成首次全量拉取
+	active  map[string]map[int64]bool // 各IP或网段上有效的决策ID，同一来源可能有多条决策
+}
+
+// newCrowdSecFeed 为拉取的决策创建订阅状态，与订阅黑名单一样定期刷新并通过ipset写入防火墙
+// 返回:
+//   - *feedState: 未启用CrowdSec集成或拉取时为nil
+func (m *Monitor) newCrowdSecFeed() *feedState {
+	cfg := m.config.CrowdSec
+	if m.crowdsec == nil || !cfg.Pull {
+		return nil
+	}
+	puller := &crowdsecPuller{client: m.crowdsec, config: cfg, active: make(map[string]map[int64]bool)}
+	return &feedState{
+		config:   config.FeedConfig{Name: config.CrowdSecFeedName, URL: cfg.URL, Enabled: true},
+		interval: time.Duration(cfg.PullInterval),
+		fetch:    puller.pull,
+		status:   control.FeedStatus{Name: config.CrowdSecFeedName, URL: cfg.URL},
+	}
+}
+
+// pull 拉取决策的变化并更新订阅的条目
+// 首次拉取全部有效的决策，之后只拉取增量；跳过非ban决策、本机推送的场景以及不在origins中的来源
+// 调用方需持有f.work锁
+// 返回:
+//   - bool: 有效的IP或网段有变化时为true
+//   - error: 拉取失败时的错误信息
+func (p *crowdsecPuller) pull(f *feedState) (bool, error) {
+	added, deleted, err := p.client.Stream(!p.started)
+	if err != nil {
+		return false, err
+	}
+	p.started = true
+
+	changed := false
+	invalid := 0
+	for _, d := range added {
+		if !p.wanted(d) {
+			continue
+		}
+		entry, ok := decisionEntry(d)
+		if !ok {
+			invalid++
+			continue
+		}
+		if p.active[entry] == nil {
+			p.active[entry] = make(map[int64]bool)
+			changed = true
+		}
+		p.active[entry][d.ID] = true
+	}
+	for _, d := range deleted {
+		entry, ok := decisionEntry(d)
+		if !ok || p.active[entry] == nil {
+			continue
+		}
+		delete(p.active[entry], d.ID)
+		if len(p.active[entry]) == 0 {
+			delete(p.active, entry)
+			changed = true
+		}
+	}
+
+	if changed || f.entries == nil {
+		entries := make([]string, 0, len(p.active))
+		for entry := range p.active {
+			entries = append(entries, entry)
+		}
+		sort.Strings(entries)
+		f.entries = entries
+	}
+	f.mu.Lock()
+	f.status.Invalid += invalid
+	f.mu.Unlock()
+	return changed, nil
+}
+
+// wanted 检查决策是否需要封禁，只处理作用于IP或网段的ban决策
+func (p *crowdsecPuller) wanted(d crowdsec.Decision) bool {
+	if !strings.EqualFold(d.Type, "ban") || d.Scenario == p.config.Scenario {
+		return false
+	}
+	if !strings.EqualFold(d.Scope, "ip") && !strings.EqualFold(d.Scope, "range") {
+		return false
+	}
+	if len(p.config.Origins) == 0 {
+		return true
+	}
+	for _, origin := range p.config.Origins {
+		if strings.EqualFold(origin, d.Origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// decisionEntry 将决策的值转换为与订阅黑名单相同格式的条目，值不是IP或网段时返回false
+func decisionEntry(d crowdsec.Decision) (string, bool) {
+	network, err := config.ParseNetwork(d.Value)
+	if err != nil {
+		return "", false
+	}
+	return feedEntry(network), true
+}
+
+// pushCrowdSec 在后台将封禁推送到CrowdSec本地API，未启用推送时不做任何事
+// 参数:
+//   - ip: 被封禁的IP
+//   - reason: 封禁原因，作为告警说明
+//   - at: 封禁时间
+//   - expires: 解封时间
+func (m *Monitor) pushCrowdSec(ip, reason string, at, expires time.Time) {
+	if m.crowdsec == nil || !m.config.CrowdSec.Push {
+		return
+	}
+	go func() {
+		if err := m.crowdsec.PushBan(ip, reason, at, time.Until(expires)); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Warn("推送封禁到CrowdSec失败")
+			return
+		}
+		m.logger.WithField("ip", ip).Debug("已推送封禁到CrowdSec")
+	}()
+}
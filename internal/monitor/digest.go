@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"net"
+	"sort"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// 汇总报告中查询属地的IP数量上限，按失败次数从多到少选取，其余IP不计入国家统计
+const summaryGeoLimit = 100
+
+// runUnbanDigest 每天在配置的时间发送当天自动解封的IP汇总
+// 当天没有IP到期解封时不发送
+func (m *Monitor) runUnbanDigest() {
+	for {
+		next := nextClock(time.Now(), m.config.Notifications.UnbanDigest.Time)
+		time.Sleep(time.Until(next))
+
+		m.mu.Lock()
+		ips := m.autoUnbanned
+		m.autoUnbanned = nil
+		m.mu.Unlock()
+
+		if len(ips) == 0 {
+			continue
+		}
+		if err := m.notifier.Notify(notification.UnbanDigestEvent(next, ips, m.serverName())); err != nil {
+			m.logger.WithError(err).Error("发送自动解封汇总失败")
+		}
+	}
+}
+
+// runSummary 按notifications.summary.schedule定期发送攻击汇总报告
+// 统计范围为上一个周期，数据来自存储中的事件，守护进程重启不影响统计
+func (m *Monitor) runSummary() {
+	schedule, _ := config.ParseSchedule(m.config.Notifications.Summary.Schedule)
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			m.logger.WithField("schedule", m.config.Notifications.Summary.Schedule).Error("汇总报告的发送时间永远不会到达，停止发送")
+			return
+		}
+		time.Sleep(time.Until(next))
+
+		// 周期长度取本次与下一次发送的间隔，每天、每周的固定周期与上一次发送的间隔相同
+		start := next.Add(-schedule.Next(next).Sub(next))
+		if err := m.sendSummary(start, next); err != nil {
+			m.logger.WithError(err).Error("发送汇总报告失败")
+		}
+	}
+}
+
+// sendSummary 统计start到end之间的事件并发送汇总报告
+// 参数:
+//   - start: 统计开始时间
+//   - end: 统计结束时间
+// 返回:
+//   - error: 读取事件或发送失败时的错误信息
+func (m *Monitor) sendSummary(start, end time.Time) error {
+	events, err := m.store.Events(start, 0)
+	if err != nil {
+		return err
+	}
+	var failed, bans, success int
+	ipCounts := make(map[string]int)
+	for _, e := range events {
+		if e.Time.After(end) {
+			break
+		}
+		switch e.Type {
+		case event.TypeLoginFailed:
+			failed += e.Count()
+			ipCounts[e.IP] += e.Count()
+		case event.TypeBanned:
+			bans++
+		case event.TypeLoginSuccess:
+			success++
+		}
+	}
+
+	top := m.config.Notifications.Summary.Top
+	return m.notifier.Notify(notification.SummaryEvent(start, end, failed, bans, success, ipCounts, m.countryCounts(ipCounts), top, m.serverName()))
+}
+
+// countryCounts 按属地将各IP的失败次数汇总为各国家的失败次数
+// 只查询失败次数最多的summaryGeoLimit个IP，其余IP不计入；查询失败的IP计入"未知"
+// 参数:
+//   - ipCounts: 各IP的失败次数
+// 返回:
+//   - map[string]int: 各国家的失败次数
+func (m *Monitor) countryCounts(ipCounts map[string]int) map[string]int {
+	ips := make([]string, 0, len(ipCounts))
+	for ip := range ipCounts {
+		if net.ParseIP(ip) != nil {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		if ipCounts[ips[i]] != ipCounts[ips[j]] {
+			return ipCounts[ips[i]] > ipCounts[ips[j]]
+		}
+		return ips[i] < ips[j]
+	})
+	if len(ips) > summaryGeoLimit {
+		ips = ips[:summaryGeoLimit]
+	}
+	countryCounts := make(map[string]int)
+	if len(ips) > 0 {
+		infos, err := m.ipInfo.GetIPInfoBatch(ips)
+		if err != nil {
+			m.logger.WithError(err).Warn("部分IP属地查询失败")
+		}
+		for _, ip := range ips {
+			country := "未知"
+			if info := infos[ip]; info != nil && info.Country != "" {
+				country = info.Country
+			}
+			countryCounts[country] += ipCounts[ip]
+		}
+	}
+	return countryCounts
+}
+
+// nextClock 返回now之后下一次到达指定时刻的时间
+// 参数:
+//   - now: 当前时间
+//   - clock: HH:MM格式的时刻，已在加载配置时校验
+// 返回:
+//   - time.Time: 下一次到达该时刻的本地时间
+func nextClock(now time.Time, clock string) time.Time {
+	t, _ := time.Parse(config.ClockLayout, clock)
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
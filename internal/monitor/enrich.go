@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/store"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// enrichIP 查询IP的补充信息
+// 各项查询失败只记录调试日志，对应字段按未知处理
+// 参数:
+//   - ip: 要查询的IP地址
+// 返回:
+//   - *enrich.Result: 合并后的补充信息，不会为nil
+func (m *Monitor) enrichIP(ip string) *enrich.Result {
+	result := m.enricher.Enrich(ip)
+	for name, err := range result.Errors {
+		m.logger.WithError(err).WithFields(logrus.Fields{"ip": ip, "enricher": name}).Debug("查询IP补充信息失败")
+	}
+	return result
+}
+
+// enrichIPTraced 查询IP的补充信息，并在日志行的跟踪中记录为一个子span
+// 参数:
+//   - ip: 要查询的IP地址
+//   - span: 触发查询的日志行的跟踪，为nil时与enrichIP相同
+// 返回:
+//   - *enrich.Result: 合并后的补充信息，不会为nil
+func (m *Monitor) enrichIPTraced(ip string, span *tracing.Span) *enrich.Result {
+	lookup := span.Client("geo_lookup")
+	result := m.enrichIP(ip)
+	lookup.Set("ip", ip)
+	lookup.Set("country", countryCodeOf(result.Geo))
+	for name, err := range result.Errors {
+		lookup.Fail(fmt.Errorf("%s: %v", name, err))
+	}
+	lookup.End()
+	return result
+}
+
+// recordBanSource 将查询到的来源信息写入封禁记录，便于事后区分云服务器与家庭宽带中的僵尸设备
+// 补充信息在防火墙规则添加之后才查询，不会延迟封禁生效
+// 参数:
+//   - ban: 已生效的封禁记录
+//   - r: IP的补充信息
+func (m *Monitor) recordBanSource(ban store.Ban, r *enrich.Result) {
+	ban.Country = countryCodeOf(r.Geo)
+	ban.Hostname = r.Hostname
+	ban.ASN, ban.ASOrg = r.ASNumber()
+	ban.NetworkType = r.NetworkType()
+	if !ban.HasSource() {
+		return
+	}
+	if err := m.store.PutBan(ban); err != nil {
+		m.logger.WithError(err).WithField("ip", ban.IP).Warn("保存封禁来源信息失败")
+	}
+}
+
+// reportAbuse 将由日志触发的封禁举报到AbuseIPDB
+// 在单独的协程中执行，举报失败只记录警告
+// 参数:
+//   - ip: 被封禁的IP，网段不会被举报
+//   - reason: 封禁原因，作为举报说明
+func (m *Monitor) reportAbuse(ip, reason string) {
+	abuse := m.enricher.AbuseIPDB()
+	if abuse == nil || !abuse.Reporting() || net.ParseIP(ip) == nil {
+		return
+	}
+	go func() {
+		if err := abuse.Report(ip, "ssh_fb: "+reason); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Warn("举报到AbuseIPDB失败")
+			return
+		}
+		m.logger.WithField("ip", ip).Info("已举报到AbuseIPDB")
+	}()
+}
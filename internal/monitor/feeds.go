@@ -0,0 +1,320 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// 下载订阅黑名单的超时时间与内容大小上限
+const (
+	feedTimeout  = 60 * time.Second
+	feedMaxBytes = 32 << 20
+)
+
+// feedState 一个订阅黑名单的下载与写入状态
+type feedState struct {
+	config   config.FeedConfig
+	interval time.Duration                    // 刷新间隔
+	http     *http.Client                     // 下载使用的客户端
+	fetch    func(f *feedState) (bool, error) // 获取条目的方式，为nil时按config.URL下载，返回值与download相同
+
+	mu     sync.Mutex // 保护status，下载期间也可以读取状态
+	status control.FeedStatus
+
+	work     sync.Mutex // 串行化下载与写入，保护以下字段
+	etag     string     // 上次下载响应的ETag，内容未变化时服务器返回304
+	modified string     // 上次下载响应的Last-Modified
+	entries  []string   // 上次下载解析出的条目
+	applied  []string   // 上次写入防火墙的条目，已去掉与白名单重叠的
+}
+
+// newFeeds 为每个启用的订阅创建状态，刷新协程由startFeeds启动
+func newFeeds(feeds []config.FeedConfig) []*feedState {
+	var states []*feedState
+	for _, feed := range feeds {
+		if !feed.Enabled {
+			continue
+		}
+		states = append(states, &feedState{
+			config:   feed,
+			interval: time.Duration(feed.RefreshInterval),
+			http:     &http.Client{Timeout: feedTimeout},
+			status:   control.FeedStatus{Name: feed.Name, URL: feed.URL},
+		})
+	}
+	return states
+}
+
+// startFeeds 删除已移出配置或停用的订阅留下的集合，并为每个启用的订阅启动刷新协程
+func (m *Monitor) startFeeds() {
+	if !m.firewall.SupportsFeeds() {
+		if len(m.feeds) > 0 {
+			m.logger.Warn("系统中没有ipset或iptables，订阅黑名单不会生效")
+		}
+		for _, f := range m.feeds {
+			f.mu.Lock()
+			f.status.LastError = "系统中没有ipset或iptables"
+			f.mu.Unlock()
+		}
+		return
+	}
+
+	enabled := make(map[string]bool)
+	for _, f := range m.feeds {
+		enabled[f.config.Name] = true
+	}
+
+	if existing, err := m.firewall.Feeds(); err != nil {
+		m.logger.WithError(err).Warn("读取已有的订阅黑名单失败")
+	} else {
+		for _, name := range existing {
+			if enabled[name] {
+				continue
+			}
+			if err := m.firewall.RemoveFeed(name); err != nil {
+				m.logger.WithError(err).WithField("feed", name).Error("删除订阅黑名单失败")
+			} else {
+				m.logger.WithField("feed", name).Info("订阅已移出配置或停用，已删除其黑名单")
+				m.audit(auditFeedUpdate, name, SourceFeed, "订阅已移出配置或停用，已删除其全部条目")
+			}
+		}
+	}
+
+	for _, f := range m.feeds {
+		go m.runFeed(f)
+	}
+}
+
+// runFeed 立即下载一次订阅，之后按刷新间隔定期刷新
+func (m *Monitor) runFeed(f *feedState) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		m.refreshFeed(f)
+		f.mu.Lock()
+		f.status.Next = time.Now().Add(f.interval)
+		f.mu.Unlock()
+		<-ticker.C
+	}
+}
+
+// refreshFeed 下载订阅并将变化写入防火墙
+// 下载或写入失败时保留防火墙中已有的条目，下次刷新时重试
+func (m *Monitor) refreshFeed(f *feedState) {
+	logger := m.logger.WithField("feed", f.config.Name)
+	f.work.Lock()
+	defer f.work.Unlock()
+
+	fetch := (*feedState).download
+	if f.fetch != nil {
+		fetch = f.fetch
+	}
+	changed, err := fetch(f)
+	if err != nil {
+		logger.WithError(err).Warn("下载订阅黑名单失败")
+	} else if changed || f.applied == nil {
+		if err = m.applyFeed(f); err != nil {
+			logger.WithError(err).Error("写入订阅黑名单失败")
+		}
+	}
+	f.mu.Lock()
+	f.status.Checked = time.Now()
+	f.status.LastError = ""
+	if err != nil {
+		f.status.LastError = err.Error()
+	}
+	f.mu.Unlock()
+}
+
+// reapplyFeeds 白名单变化后按已下载的条目重新写入各订阅，不重新下载
+func (m *Monitor) reapplyFeeds() {
+	for _, f := range m.feeds {
+		go func(f *feedState) {
+			f.work.Lock()
+			defer f.work.Unlock()
+			if f.entries == nil {
+				return
+			}
+			if err := m.applyFeed(f); err != nil {
+				m.logger.WithError(err).WithField("feed", f.config.Name).Error("写入订阅黑名单失败")
+			}
+		}(f)
+	}
+}
+
+// applyFeed 去掉与白名单重叠的条目后写入防火墙，条目与上次写入的相同时不做任何事
+// 调用方需持有f.work锁
+func (m *Monitor) applyFeed(f *feedState) error {
+	entries := make([]string, 0, len(f.entries))
+	whitelisted := 0
+	m.mu.RLock()
+	for _, entry := range f.entries {
+		// 条目在解析时已经校验
+		network, _ := config.ParseNetwork(entry)
+		if m.whitelist.overlaps(network) {
+			whitelisted++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	m.mu.RUnlock()
+	if f.applied != nil && slices.Equal(entries, f.applied) {
+		return nil
+	}
+
+	if err := m.firewall.ApplyFeed(f.config.Name, entries); err != nil {
+		return err
+	}
+	added, removed := diffEntries(f.applied, entries)
+	f.applied = entries
+	f.mu.Lock()
+	f.status.Entries = len(entries)
+	f.status.Whitelisted = whitelisted
+	f.status.Updated = time.Now()
+	f.mu.Unlock()
+	m.logger.WithFields(logrus.Fields{
+		"feed":        f.config.Name,
+		"entries":     len(entries),
+		"added":       added,
+		"removed":     removed,
+		"whitelisted": whitelisted,
+	}).Info("订阅黑名单已更新")
+	m.audit(auditFeedUpdate, f.config.Name, SourceFeed, fmt.Sprintf("共%d条，新增%d条，删除%d条", len(entries), added, removed))
+	return nil
+}
+
+// download 下载并解析订阅，服务器返回304时沿用上次的条目
+// 调用方需持有f.work锁
+// 返回:
+//   - bool: 下载到新的内容时为true
+//   - error: 请求失败、内容过大或没有任何有效条目时的错误信息
+func (f *feedState) download() (bool, error) {
+	req, err := http.NewRequest(http.MethodGet, f.config.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	if f.entries != nil {
+		if f.etag != "" {
+			req.Header.Set("If-None-Match", f.etag)
+		}
+		if f.modified != "" {
+			req.Header.Set("If-Modified-Since", f.modified)
+		}
+	}
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && f.entries != nil {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	entries, invalid, err := parseFeed(io.LimitReader(resp.Body, feedMaxBytes+1))
+	if err != nil {
+		return false, err
+	}
+	// 返回的可能是错误页面，此时不应清空防火墙中的条目
+	if len(entries) == 0 && invalid > 0 {
+		return false, fmt.Errorf("内容中没有有效的IP或CIDR，共%d行无法解析", invalid)
+	}
+	f.entries = entries
+	f.mu.Lock()
+	f.status.Invalid = invalid
+	f.mu.Unlock()
+	f.etag = resp.Header.Get("ETag")
+	f.modified = resp.Header.Get("Last-Modified")
+	return true, nil
+}
+
+// parseFeed 解析每行一个IP或CIDR的黑名单，#和;之后的内容按注释忽略
+// Spamhaus DROP的行形如 "1.10.16.0/20 ; SBL256894"，只取每行的第一列
+// 返回:
+//   - []string: 去重后的网段，单个IP不带前缀长度
+//   - int: 无法解析的行数
+//   - error: 读取失败或内容超过大小上限时的错误信息
+func parseFeed(r io.Reader) ([]string, int, error) {
+	seen := make(map[string]bool)
+	entries := []string{}
+	invalid := 0
+	read := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		read += len(line) + 1
+		if read > feedMaxBytes {
+			return nil, 0, fmt.Errorf("内容超过%dMB", feedMaxBytes>>20)
+		}
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		network, err := config.ParseNetwork(fields[0])
+		if err != nil {
+			invalid++
+			continue
+		}
+		entry := feedEntry(network)
+		if !seen[entry] {
+			seen[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取内容失败: %v", err)
+	}
+	return entries, invalid, nil
+}
+
+// feedEntry 返回网段写入集合时的格式，单个IP不带前缀长度
+func feedEntry(network *net.IPNet) string {
+	if ones, bits := network.Mask.Size(); ones == bits {
+		return network.IP.String()
+	}
+	return network.String()
+}
+
+// diffEntries 统计两次写入之间新增和移除的条目数
+func diffEntries(before, after []string) (int, int) {
+	previous := make(map[string]bool, len(before))
+	for _, entry := range before {
+		previous[entry] = true
+	}
+	added := 0
+	for _, entry := range after {
+		if previous[entry] {
+			delete(previous, entry)
+		} else {
+			added++
+		}
+	}
+	return added, len(previous)
+}
+
+// feedStatus 返回各订阅黑名单的刷新状态
+func (m *Monitor) feedStatus() []control.FeedStatus {
+	statuses := make([]control.FeedStatus, 0, len(m.feeds))
+	for _, f := range m.feeds {
+		f.mu.Lock()
+		statuses = append(statuses, f.status)
+		f.mu.Unlock()
+	}
+	return statuses
+}
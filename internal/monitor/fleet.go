@@ -0,0 +1,258 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/fleet"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// fleetSync 集群同步的状态
+type fleetSync struct {
+	client   *fleet.Redis     // 同步通道，未启用集群同步时为nil
+	host     string           // 本机在集群中的名称
+	versions map[string]int64 // 各IP已发布或应用的最新变更版本，旧版本的变更会被忽略，只在m.mu锁内访问
+}
+
+// startFleet 启用集群同步时连接Redis，订阅其他服务器的变更并定期按集群状态对齐
+func (m *Monitor) startFleet() {
+	cfg := m.config.Fleet
+	if !cfg.Enabled {
+		return
+	}
+	host := cfg.HostID
+	if host == "" {
+		name, err := os.Hostname()
+		if err != nil {
+			m.logger.WithError(err).Error("获取主机名失败，集群同步未启动，请设置fleet.host_id")
+			return
+		}
+		host = name
+	}
+
+	client := fleet.New(cfg.Redis)
+	m.mu.Lock()
+	m.fleet = fleetSync{client: client, host: host, versions: make(map[string]int64)}
+	m.mu.Unlock()
+	m.logger.WithFields(logrus.Fields{
+		"host":  host,
+		"redis": cfg.Redis.Addr,
+	}).Info("集群同步已启动")
+
+	// 先订阅再对齐，对齐期间发布的变更不会错过
+	go client.Subscribe(context.Background(), func(msg fleet.Message) {
+		m.applyFleet([]fleet.Message{msg}, true)
+	})
+	go func() {
+		m.resyncFleet(client)
+		ticker := time.NewTicker(time.Duration(cfg.ResyncInterval))
+		for range ticker.C {
+			m.resyncFleet(client)
+		}
+	}()
+}
+
+// resyncFleet 读取集群状态并应用本机尚未应用的变更
+func (m *Monitor) resyncFleet(client *fleet.Redis) {
+	messages, err := client.State()
+	if err != nil {
+		m.logger.WithError(err).Warn("集群同步失败")
+		return
+	}
+	m.applyFleet(messages, false)
+}
+
+// applyFleet 应用其他服务器发布的变更，全部应用后保存一次黑名单
+// 参数:
+//   - messages: 收到的变更，本机发布的和不比已知版本新的会被忽略
+//   - live: 是否为实时收到的变更，只有实时变更会按fleet.notify发送通知，对齐时补上的不通知
+func (m *Monitor) applyFleet(messages []fleet.Message, live bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changed := false
+	for _, msg := range messages {
+		if m.applyFleetMessage(msg, live) {
+			changed = true
+		}
+	}
+	if changed {
+		m.saveBlacklist()
+	}
+}
+
+// applyFleetMessage 应用一条变更
+// 同一IP的变更以版本较新者为准，因此两台服务器同时封禁和解封同一IP时，所有服务器最终采用同一结果
+// 调用方需持有m.mu锁
+// 返回:
+//   - bool: 封禁状态有变化时为true，调用方需保存黑名单
+func (m *Monitor) applyFleetMessage(msg fleet.Message, live bool) bool {
+	if msg.Origin == m.fleet.host || msg.Version <= m.fleet.versions[msg.IP] {
+		return false
+	}
+	if _, err := config.ParseNetwork(msg.IP); err != nil {
+		m.logger.WithFields(logrus.Fields{
+			"ip":     msg.IP,
+			"origin": msg.Origin,
+		}).Warn("集群同步的IP无效，已忽略")
+		return false
+	}
+	m.fleet.versions[msg.IP] = msg.Version
+
+	switch msg.Action {
+	case fleet.ActionBan, fleet.ActionPermanent:
+		return m.applyFleetBan(msg, live)
+	case fleet.ActionUnban:
+		return m.applyFleetUnban(msg)
+	}
+	return false
+}
+
+// applyFleetBan 应用其他服务器的封禁
+// 白名单、代理地址和登录后临时信任的IP不封禁；本机已有更长的封禁时保持不变
+// 调用方需持有m.mu锁
+func (m *Monitor) applyFleetBan(msg fleet.Message, live bool) bool {
+	ip := msg.IP
+	fields := logrus.Fields{"ip": ip, "origin": msg.Origin, "reason": msg.Reason}
+	if m.whitelist.contains(ip) || m.isProxy(ip) || m.isTrusted(ip, time.Now()) {
+		m.logger.WithFields(fields).Info("IP在白名单中、是代理地址或处于临时信任期，跳过同步的封禁")
+		return false
+	}
+	if m.permanentIPs[ip] {
+		return false
+	}
+	permanent := msg.Action == fleet.ActionPermanent
+	expires, banned := m.bannedIPs[ip]
+	if !permanent && (!time.Now().Before(msg.ExpiresAt) || banned && !msg.ExpiresAt.After(expires)) {
+		return false
+	}
+
+	if !banned {
+		if err := m.blockIP(ip); err != nil {
+			m.logger.WithError(err).WithFields(fields).Error("应用集群同步的封禁失败")
+			return false
+		}
+	}
+	if _, limited := m.limitedIPs[ip]; limited {
+		m.liftLimit(ip)
+	}
+	m.failedAttempts.remove(ip)
+
+	ban := store.Ban{IP: ip, Type: banTypeTemporary, ExpiresAt: msg.ExpiresAt, State: store.StateApplied, Reason: msg.Reason, Origin: msg.Origin}
+	message := fmt.Sprintf("来自 %s 的同步封禁：%s", msg.Origin, msg.Reason)
+	var duration time.Duration
+	if permanent {
+		m.permanentIPs[ip] = true
+		m.trackSubnet(ip)
+		delete(m.bannedIPs, ip)
+		ban.Type, ban.ExpiresAt = banTypePermanent, time.Time{}
+		message += "，已永久封禁"
+		fields["expire_time"] = "永久"
+	} else {
+		m.bannedIPs[ip] = msg.ExpiresAt
+		m.trackSubnet(ip)
+		m.scheduleUnban(ip, msg.ExpiresAt)
+		m.limitBannedIPs(ip)
+		duration = time.Until(msg.ExpiresAt)
+		fields["expire_time"] = msg.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	// 保存黑名单时保留已有记录中的来源，这里先写入封禁原因与所来自的服务器
+	if err := m.store.PutBan(ban); err != nil {
+		m.logger.WithError(err).WithFields(fields).Warn("保存封禁记录失败")
+	}
+
+	m.logger.WithFields(fields).Info("已应用集群同步的封禁")
+	action := auditBan
+	if permanent {
+		action = auditPermanent
+	}
+	m.audit(action, ip, SourceFleet, message)
+	m.events.Publish(event.Event{Type: event.TypeBanned, IP: ip, Message: message, Expires: ban.ExpiresAt})
+	if live && m.config.Fleet.Notify {
+		reason := fmt.Sprintf("%s（来自 %s）", msg.Reason, msg.Origin)
+		m.afterLookup(ip, nil, func(enriched *enrich.Result) {
+			m.notifier.Notify(notification.IPBannedEvent(ip, enriched.Format(), m.serverName(), reason, duration, ban.ExpiresAt, time.UnixMilli(msg.Version)).WithCountry(countryOf(enriched.Geo)))
+		})
+	}
+	return true
+}
+
+// applyFleetUnban 应用其他服务器的解封，配置中的永久封禁和本机的限速不受影响
+// 调用方需持有m.mu锁
+func (m *Monitor) applyFleetUnban(msg fleet.Message) bool {
+	ip := msg.IP
+	_, banned := m.bannedIPs[ip]
+	if m.isConfigPermanent(ip) || !banned && !m.permanentIPs[ip] {
+		return false
+	}
+	fields := logrus.Fields{"ip": ip, "origin": msg.Origin}
+	if err := m.unblockIP(ip); err != nil {
+		m.logger.WithError(err).WithFields(fields).Error("应用集群同步的解封失败")
+		return false
+	}
+	delete(m.bannedIPs, ip)
+	delete(m.permanentIPs, ip)
+	m.failedAttempts.remove(ip)
+
+	m.logger.WithFields(fields).Info("已应用集群同步的解封")
+	m.audit(auditUnban, ip, SourceFleet, "来自 "+msg.Origin)
+	m.events.Publish(event.Event{Type: event.TypeUnbanned, IP: ip, Message: fmt.Sprintf("来自 %s 的同步解封", msg.Origin)})
+	return true
+}
+
+// publishFleet 在后台将本机的封禁变更发布到集群，未启用集群同步时不做任何事
+// 调用方需持有m.mu锁
+// 参数:
+//   - action: 变更类型，见fleet.ActionBan等
+//   - ip: IP地址
+//   - expires: 临时封禁的解封时间，其他变更为零值
+//   - reason: 封禁或解封的原因
+func (m *Monitor) publishFleet(action, ip string, expires time.Time, reason string) {
+	if m.fleet.client == nil {
+		return
+	}
+	msg := fleet.Message{
+		Action:    action,
+		IP:        ip,
+		ExpiresAt: expires,
+		Reason:    reason,
+		Origin:    m.fleet.host,
+		Version:   time.Now().UnixMilli(),
+	}
+	// 同一毫秒内的连续变更仍保持版本递增
+	if msg.Version <= m.fleet.versions[ip] {
+		msg.Version = m.fleet.versions[ip] + 1
+	}
+	m.fleet.versions[ip] = msg.Version
+
+	client := m.fleet.client
+	go func() {
+		applied, err := client.Publish(msg)
+		if err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Warn("发布封禁变更到集群失败")
+			return
+		}
+		if !applied {
+			m.logger.WithField("ip", ip).Debug("集群中已有该IP更新的变更，本机的变更未发布")
+		}
+	}()
+}
+
+// pruneFleetVersions 清理超过集群状态保留期的变更版本
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneFleetVersions() {
+	cutoff := time.Now().Add(-fleet.Retention).UnixMilli()
+	for ip, version := range m.fleet.versions {
+		if version < cutoff {
+			delete(m.fleet.versions, ip)
+		}
+	}
+}
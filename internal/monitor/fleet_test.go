@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/fleet"
+)
+
+// TestApplyFleetLastWriteWins 同一IP的变更以版本较新者为准，乱序到达的旧变更被忽略
+// 测试中的变更都不会改变封禁状态，只检查各变更是否被接受，不需要操作防火墙
+func TestApplyFleetLastWriteWins(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name     string
+		messages []fleet.Message
+		want     int64 // 处理完所有变更后记录的版本
+	}{
+		{
+			name: "按版本顺序到达",
+			messages: []fleet.Message{
+				{Action: fleet.ActionBan, IP: "1.2.3.4", ExpiresAt: expired, Origin: "a", Version: 100},
+				{Action: fleet.ActionUnban, IP: "1.2.3.4", Origin: "b", Version: 200},
+			},
+			want: 200,
+		},
+		{
+			name: "旧变更乱序到达",
+			messages: []fleet.Message{
+				{Action: fleet.ActionUnban, IP: "1.2.3.4", Origin: "b", Version: 200},
+				{Action: fleet.ActionBan, IP: "1.2.3.4", ExpiresAt: expired, Origin: "a", Version: 100},
+			},
+			want: 200,
+		},
+		{
+			name: "相同版本只应用第一条",
+			messages: []fleet.Message{
+				{Action: fleet.ActionUnban, IP: "1.2.3.4", Origin: "a", Version: 100},
+				{Action: fleet.ActionBan, IP: "1.2.3.4", ExpiresAt: expired, Origin: "b", Version: 100},
+			},
+			want: 100,
+		},
+		{
+			name: "本机发布的变更",
+			messages: []fleet.Message{
+				{Action: fleet.ActionUnban, IP: "1.2.3.4", Origin: "self", Version: 300},
+			},
+		},
+		{
+			name: "无效的IP",
+			messages: []fleet.Message{
+				{Action: fleet.ActionUnban, IP: "1.2.3", Origin: "a", Version: 300},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newFleetTestMonitor()
+			for _, msg := range tt.messages {
+				m.applyFleetMessage(msg, false)
+			}
+			if got := m.fleet.versions[tt.messages[0].IP]; got != tt.want {
+				t.Errorf("版本 = %d，期望 %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestApplyFleetBanKeepsLonger 本机已有更长的封禁时，同步的较短封禁不缩短解封时间
+func TestApplyFleetBanKeepsLonger(t *testing.T) {
+	m := newFleetTestMonitor()
+	expires := time.Now().Add(2 * time.Hour)
+	m.bannedIPs["1.2.3.4"] = expires
+
+	msg := fleet.Message{Action: fleet.ActionBan, IP: "1.2.3.4", ExpiresAt: time.Now().Add(time.Hour), Origin: "a", Version: 100}
+	if m.applyFleetMessage(msg, false) {
+		t.Error("较短的同步封禁改变了封禁状态")
+	}
+	if !m.bannedIPs["1.2.3.4"].Equal(expires) {
+		t.Errorf("解封时间变为 %v，期望 %v", m.bannedIPs["1.2.3.4"], expires)
+	}
+	if m.fleet.versions["1.2.3.4"] != 100 {
+		t.Errorf("版本 = %d，期望 100", m.fleet.versions["1.2.3.4"])
+	}
+}
+
+// newFleetTestMonitor 创建只包含集群同步所需状态的监控器
+func newFleetTestMonitor() *Monitor {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return &Monitor{
+		config:       config.Default(),
+		logger:       logger,
+		fleet:        fleetSync{host: "self", versions: make(map[string]int64)},
+		bannedIPs:    make(map[string]time.Time),
+		permanentIPs: make(map[string]bool),
+	}
+}
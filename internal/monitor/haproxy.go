@@ -0,0 +1,175 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// jailHAProxy 内置的HAProxy/Nginx stream日志jail
+const jailHAProxy = "haproxy"
+
+// proxyConn 代理日志中的一条转发连接
+type proxyConn struct {
+	ip   string    // 真实客户端IP
+	time time.Time // 代理日志记录的时间
+}
+
+// startHAProxyJail 启动haproxy jail，持续读取代理日志记录转发连接
+// sshd日志中来自代理的连接按代理连接sshd时的源端口对应到真实客户端IP
+func (m *Monitor) startHAProxyJail() {
+	cfg := m.config.Jails.HAProxy
+	if !cfg.Enabled {
+		return
+	}
+	pattern, err := config.CompileHAProxyPattern(cfg.Pattern)
+	if err != nil {
+		m.logger.WithError(err).WithField("jail", jailHAProxy).Error("haproxy jail正则表达式无效")
+		return
+	}
+
+	runner := m.newJailRunner(jailHAProxy, cfg.LogFile, m.config.Jails.Workers, func(l logLine) {
+		line := l.text
+		if !m.jailEnabled(jailHAProxy) {
+			return
+		}
+		matches := pattern.FindStringSubmatch(line)
+		if matches == nil {
+			return
+		}
+		ip := matches[pattern.SubexpIndex("ip")]
+		port, err := strconv.Atoi(matches[pattern.SubexpIndex("port")])
+		if net.ParseIP(ip) == nil || err != nil {
+			return
+		}
+		var proxy string
+		if i := pattern.SubexpIndex("proxy"); i >= 0 {
+			proxy = matches[i]
+		}
+		m.archiveLine(jailHAProxy, line)
+		m.recordProxyConn(proxy, port, ip, parseLogTime(line, time.Now()))
+	})
+	go m.superviseJail(runner)
+}
+
+// proxyKey 返回转发连接的索引键
+func proxyKey(proxy string, port int) string {
+	return fmt.Sprintf("%s:%d", proxy, port)
+}
+
+// recordProxyConn 记录一条转发连接，并将代理地址加入不可封禁的代理列表
+// 参数:
+//   - proxy: 代理连接sshd的地址，日志中没有时为空
+//   - port: 代理连接sshd时的源端口
+//   - ip: 真实客户端IP
+//   - at: 代理日志记录的时间
+func (m *Monitor) recordProxyConn(proxy string, port int, ip string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.proxyConns[proxyKey(proxy, port)] = proxyConn{ip: ip, time: at}
+	if proxy != "" && !m.proxyAddrs[proxy] {
+		m.proxyAddrs[proxy] = true
+		m.logger.WithField("proxy", proxy).Info("发现代理地址，该地址不会被封禁")
+	}
+}
+
+// lookupProxyConn 按sshd日志中的来源地址和端口查找真实客户端IP
+// 调用方需持有m.mu锁
+// 参数:
+//   - proxy: sshd记录的来源IP，即代理地址
+//   - port: sshd记录的来源端口
+//   - at: sshd日志记录的时间
+// 返回:
+//   - string: 真实客户端IP
+//   - bool: 是否在时间窗口内找到了对应的转发连接
+func (m *Monitor) lookupProxyConn(proxy string, port int, at time.Time) (string, bool) {
+	if port == 0 {
+		return "", false
+	}
+	window := time.Duration(m.config.Jails.HAProxy.WindowSeconds) * time.Second
+	for _, key := range []string{proxyKey(proxy, port), proxyKey("", port)} {
+		conn, ok := m.proxyConns[key]
+		if !ok {
+			continue
+		}
+		if diff := at.Sub(conn.time); diff > window || diff < -window {
+			continue
+		}
+		return conn.ip, true
+	}
+	return "", false
+}
+
+// isProxy 检查IP是否为负载均衡或代理地址
+// 包括real_ip.trusted_proxies中的地址和haproxy jail从代理日志中发现的地址
+// 调用方需持有m.mu锁
+func (m *Monitor) isProxy(ip string) bool {
+	if m.proxyAddrs[ip] {
+		return true
+	}
+	return m.realIP != nil && len(m.realIP.proxies) > 0 && m.realIP.fromProxy(ip)
+}
+
+// proxyIn 返回网段中包含的负载均衡或代理地址，用于封禁网段前检查
+// 调用方需持有m.mu锁
+// 参数:
+//   - network: 要封禁的网段
+// 返回:
+//   - string: 网段中的一个代理地址或可信代理网段，没有时为空
+func (m *Monitor) proxyIn(network *net.IPNet) string {
+	for ip := range m.proxyAddrs {
+		if parsed := net.ParseIP(ip); parsed != nil && network.Contains(parsed) {
+			return ip
+		}
+	}
+	if m.realIP != nil {
+		for _, proxy := range m.realIP.proxies {
+			if network.Contains(proxy.IP) || proxy.Contains(network.IP) {
+				return proxy.String()
+			}
+		}
+	}
+	return ""
+}
+
+// resolveClientIP 返回sshd日志对应的真实客户端IP
+// 来源是代理时，依次使用haproxy jail记录的转发连接和real_ip.pattern提取真实IP
+// 参数:
+//   - line: 日志行
+//   - ip: sshd记录的来源IP
+//   - port: sshd记录的来源端口
+//   - at: 日志记录的时间
+// 返回:
+//   - string: 真实客户端IP
+//   - bool: 是否得到了可用于计数和封禁的IP，来自代理但无法还原时为false
+func (m *Monitor) resolveClientIP(line, ip string, port int, at time.Time) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fromProxy := m.isProxy(ip) || (m.realIP != nil && m.realIP.fromProxy(ip))
+	if !fromProxy {
+		return ip, true
+	}
+	if ip, ok := m.lookupProxyConn(ip, port, at); ok {
+		return ip, true
+	}
+	if m.realIP != nil {
+		return m.realIP.extract(line)
+	}
+	return "", false
+}
+
+// pruneProxyConns 清理超出时间窗口的转发连接记录
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneProxyConns() {
+	cutoff := time.Now().Add(-time.Duration(m.config.Jails.HAProxy.WindowSeconds) * time.Second)
+	for key, conn := range m.proxyConns {
+		if conn.time.Before(cutoff) {
+			delete(m.proxyConns, key)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/systemd"
+)
+
+// 登录事件处理协程的心跳
+const (
+	dispatchBeat       = 10 * time.Second // 没有登录事件时更新心跳的间隔
+	dispatchStallAfter = time.Minute      // 心跳超过该时间未更新视为处理协程已卡住
+)
+
+// Health 检查日志读取、登录事件处理、防火墙与Telegram是否正常
+// 返回:
+//   - control.HealthReport: 检查结果
+func (m *Monitor) Health() control.HealthReport {
+	now := time.Now()
+	report := control.HealthReport{Status: "ok", Ready: m.ready.Load()}
+	add := func(name string, err error) {
+		check := control.HealthCheck{Name: name, OK: err == nil}
+		if err != nil {
+			check.Detail = err.Error()
+			report.Status = "failing"
+		}
+		report.Checks = append(report.Checks, check)
+	}
+
+	add("monitor", m.checkDispatcher(now))
+	for _, jail := range m.Jails() {
+		if !jail.Enabled {
+			continue
+		}
+		var err error
+		if !jail.Running {
+			err = fmt.Errorf("日志读取已停止: %s", jail.LastError)
+		}
+		add("jail:"+jail.Name, err)
+	}
+	if active, err := m.firewall.Active(); err != nil {
+		add("firewall", err)
+	} else if !active {
+		add("firewall", fmt.Errorf("防火墙未启用"))
+	} else {
+		add("firewall", nil)
+	}
+	if d, ok := m.notifier.(interface{ Health() []control.ChannelHealth }); ok {
+		for _, channel := range d.Health() {
+			if channel.Name == "telegram" {
+				add("telegram", checkTelegram(channel))
+			}
+		}
+	}
+	return report
+}
+
+// checkDispatcher 检查登录事件处理协程的心跳
+func (m *Monitor) checkDispatcher(now time.Time) error {
+	beat := m.dispatchBeat.Load()
+	if beat == 0 {
+		return fmt.Errorf("登录事件处理尚未开始")
+	}
+	if age := now.Sub(time.Unix(0, beat)); age > dispatchStallAfter {
+		return fmt.Errorf("登录事件处理已 %s 没有响应", age.Round(time.Second))
+	}
+	return nil
+}
+
+// checkTelegram 检查Telegram的发送与接收管理命令是否正常
+func checkTelegram(channel control.ChannelHealth) error {
+	if channel.State == "failing" {
+		return fmt.Errorf("发送失败: %s", channel.LastError)
+	}
+	if updates := channel.Updates; updates != nil && !updates.Running {
+		return fmt.Errorf("接收管理命令已停止: %s", updates.LastError)
+	}
+	return nil
+}
+
+// markReady 启动时的加载完成后调用，通知systemd启动完成，并在启用看门狗时开始发送心跳
+func (m *Monitor) markReady() {
+	m.ready.Store(true)
+	if _, err := systemd.Notify(systemd.Ready); err != nil {
+		m.logger.WithError(err).Warn("通知systemd启动完成失败")
+	}
+	if interval := systemd.WatchdogInterval(); interval > 0 {
+		m.logger.WithField("timeout", interval).Info("已启用systemd看门狗")
+		go m.runWatchdog(interval)
+	}
+}
+
+// runWatchdog 以看门狗超时的一半为间隔向systemd发送心跳
+// 登录事件处理协程卡住时停止发送，由systemd在超时后重启服务
+func (m *Monitor) runWatchdog(interval time.Duration) {
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	stalled := false
+	for range ticker.C {
+		if err := m.checkDispatcher(time.Now()); err != nil {
+			if !stalled {
+				m.logger.WithError(err).Error("登录事件处理已卡住，停止发送看门狗心跳")
+				stalled = true
+			}
+			continue
+		}
+		stalled = false
+		if _, err := systemd.Notify(systemd.Watchdog); err != nil {
+			m.logger.WithError(err).Warn("发送看门狗心跳失败")
+		}
+	}
+}
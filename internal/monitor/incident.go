@@ -0,0 +1,400 @@
+package monitor
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/logging"
+	"github.com/yourusername/ssh_fb/internal/store"
+	"github.com/yourusername/ssh_fb/pkg/firewall"
+)
+
+// 事件报告中查询补充信息的IP数量上限，网段中的IP按事件数从多到少选取
+const incidentLookupLimit = 20
+
+// IncidentOptions 事件报告的收集选项
+type IncidentOptions struct {
+	Since    time.Time // 只收集该时间之后的事件和日志记录时间，零值表示全部
+	MaxLines int       // 最多收集的原始日志行数，超出时保留最新的
+	Lookup   bool      // 是否查询IP属地、ASN等补充信息，需要访问网络
+}
+
+// IncidentHost 报告中一个IP的补充信息
+type IncidentHost struct {
+	IP     string `json:"ip"`     // IP地址
+	Events int    `json:"events"` // 相关的事件数，抽样保存的事件按代表的事件数计算
+	Info   string `json:"info"`   // 属地、ASN、rDNS等补充信息，未查询时为空
+}
+
+// Incident 一个IP或网段的事件报告，汇总事件、封禁、执法记录、防火墙规则和原始日志，可作为工单附件
+type Incident struct {
+	Target    string             `json:"target"`     // 报告对象，IP或CIDR网段
+	Generated time.Time          `json:"generated"`  // 生成时间
+	Server    string             `json:"server"`     // 服务器信息
+	FirstSeen time.Time          `json:"first_seen"` // 最早一条事件的时间
+	LastSeen  time.Time          `json:"last_seen"`  // 最近一条事件的时间
+	Counts    map[string]int     `json:"counts"`     // 各类事件的次数
+	Users     map[string]int     `json:"users"`      // 登录失败和成功使用的用户名及次数
+	Hosts     []IncidentHost     `json:"hosts"`      // 涉及的IP，按事件数从多到少排列
+	Bans      []store.Ban        `json:"bans"`       // 存储中的当前封禁记录
+	Firewall  []string           `json:"firewall"`   // 防火墙中与报告对象相关的规则
+	Audit     []store.AuditEntry `json:"audit"`      // 执法操作记录，如封禁、解封
+	Events    []event.Event      `json:"events"`     // 事件时间线，按时间顺序排列
+	Logs      []string           `json:"logs"`       // 原始日志中提到报告对象的行
+	Warnings  []string           `json:"warnings"`   // 收集过程中无法读取的部分
+}
+
+// CollectIncident 收集一个IP或网段的事件报告，直接读取存储、防火墙和日志文件，不依赖运行中的守护进程
+// 原始日志优先读取archive目录中的归档，未启用归档时读取sshd日志文件；无法读取防火墙或日志时记录在Warnings中
+// 参数:
+//   - cfg: 配置信息
+//   - target: IP地址或CIDR网段
+//   - opts: 收集选项
+// 返回:
+//   - *Incident: 事件报告
+//   - error: target无效或无法读取存储时的错误信息
+func CollectIncident(cfg *config.Config, target string, opts IncidentOptions) (*Incident, error) {
+	network, err := config.ParseNetwork(target)
+	if err != nil {
+		return nil, err
+	}
+	st, err := store.Open(cfg.Store)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储失败: %w", err)
+	}
+	defer st.Close()
+
+	m := &Monitor{config: cfg, firewall: firewall.NewUFW(), store: st}
+	inc := &Incident{
+		Target:    target,
+		Generated: time.Now(),
+		Server:    m.serverName(),
+		Counts:    make(map[string]int),
+		Users:     make(map[string]int),
+	}
+	if err := inc.collectEvents(st, network, opts.Since); err != nil {
+		return nil, err
+	}
+	if err := inc.collectBans(st, network); err != nil {
+		return nil, err
+	}
+	inc.collectFirewall(m, network)
+	inc.collectLogs(cfg, network, opts)
+	if opts.Lookup && len(inc.Hosts) > 0 {
+		client := NewIPInfoClient(cfg.IPInfo)
+		if cfg.IPInfo.CacheFile != "" {
+			client.SetCache(cfg.IPInfo.CacheFile, time.Duration(cfg.IPInfo.CacheTTL), cfg.IPInfo.CacheSize)
+		}
+		pipeline := enrich.New(cfg.Enrichment, client)
+		for i := range inc.Hosts[:min(len(inc.Hosts), incidentLookupLimit)] {
+			inc.Hosts[i].Info = pipeline.Enrich(inc.Hosts[i].IP).Format()
+		}
+	}
+	return inc, nil
+}
+
+// collectEvents 读取存储中与网段相关的事件和执法记录，统计各类事件、用户名和IP
+func (inc *Incident) collectEvents(st store.Store, network *net.IPNet, since time.Time) error {
+	events, err := st.Events(since, 0)
+	if err != nil {
+		return fmt.Errorf("读取存储中的事件失败: %w", err)
+	}
+	hosts := make(map[string]int)
+	for _, e := range events {
+		if !networkContains(network, e.IP) {
+			continue
+		}
+		inc.Events = append(inc.Events, e)
+		inc.Counts[string(e.Type)] += e.Count()
+		hosts[e.IP] += e.Count()
+		if e.User != "" && (e.Type == event.TypeLoginFailed || e.Type == event.TypeLoginSuccess) {
+			inc.Users[e.User] += e.Count()
+		}
+	}
+	sort.SliceStable(inc.Events, func(i, j int) bool { return inc.Events[i].Time.Before(inc.Events[j].Time) })
+	if len(inc.Events) > 0 {
+		inc.FirstSeen, inc.LastSeen = inc.Events[0].Time, inc.Events[len(inc.Events)-1].Time
+	}
+
+	for ip, n := range hosts {
+		inc.Hosts = append(inc.Hosts, IncidentHost{IP: ip, Events: n})
+	}
+	// 没有事件的单个IP同样列出，便于查询补充信息
+	if ones, bits := network.Mask.Size(); ones == bits && len(inc.Hosts) == 0 {
+		inc.Hosts = append(inc.Hosts, IncidentHost{IP: network.IP.String()})
+	}
+	sort.Slice(inc.Hosts, func(i, j int) bool {
+		if inc.Hosts[i].Events != inc.Hosts[j].Events {
+			return inc.Hosts[i].Events > inc.Hosts[j].Events
+		}
+		return inc.Hosts[i].IP < inc.Hosts[j].IP
+	})
+
+	audit, err := st.Audit(since, 0)
+	if err != nil {
+		return fmt.Errorf("读取执法记录失败: %w", err)
+	}
+	for _, entry := range audit {
+		if networkContains(network, entry.Target) || entry.Target == network.String() {
+			inc.Audit = append(inc.Audit, entry)
+		}
+	}
+	return nil
+}
+
+// collectBans 读取存储中网段内的封禁记录
+func (inc *Incident) collectBans(st store.Store, network *net.IPNet) error {
+	bans, err := st.Bans()
+	if err != nil {
+		return fmt.Errorf("读取存储中的封禁记录失败: %w", err)
+	}
+	for _, ban := range bans {
+		if networkContains(network, ban.IP) || ban.IP == network.String() {
+			inc.Bans = append(inc.Bans, ban)
+		}
+	}
+	return nil
+}
+
+// collectFirewall 列出防火墙中与网段相关的规则
+// ufw的deny规则逐条检查，限速和tarpit重定向规则只对存储中有记录的IP检查
+func (inc *Incident) collectFirewall(m *Monitor, network *net.IPNet) {
+	denied, err := m.firewall.DeniedIPs()
+	if err != nil {
+		inc.Warnings = append(inc.Warnings, fmt.Sprintf("读取防火墙规则失败: %v", err))
+	}
+	for _, ip := range denied {
+		if networkContains(network, ip) || ip == network.String() {
+			inc.Firewall = append(inc.Firewall, "ufw deny from "+ip)
+		}
+	}
+
+	protection := m.config.SSHProtection
+	for _, ban := range inc.Bans {
+		switch {
+		case ban.Type == banTypeLimited && m.firewall.HasLimit(ban.IP, protection.SSHPort, protection.RateLimit.ConnectionsPerMinute):
+			inc.Firewall = append(inc.Firewall, fmt.Sprintf("iptables hashlimit %s -> %d/分钟", ban.IP, protection.RateLimit.ConnectionsPerMinute))
+		case protection.Tarpit.Enabled && m.firewall.HasRedirect(ban.IP, protection.SSHPort, protection.Tarpit.Port):
+			inc.Firewall = append(inc.Firewall, fmt.Sprintf("iptables redirect %s:%d -> %d", ban.IP, protection.SSHPort, protection.Tarpit.Port))
+		}
+	}
+}
+
+// collectLogs 从原始日志归档或sshd日志文件中收集提到网段内IP的行
+func (inc *Incident) collectLogs(cfg *config.Config, network *net.IPNet, opts IncidentOptions) {
+	var paths []string
+	switch {
+	case cfg.Archive.Enabled:
+		paths = logging.ArchiveFiles(cfg.Archive.Dir)
+	case !strings.Contains(cfg.SSHProtection.SSHLogFile, "://"):
+		paths = []string{cfg.SSHProtection.SSHLogFile + ".1", cfg.SSHProtection.SSHLogFile}
+	default:
+		inc.Warnings = append(inc.Warnings, "sshd日志来自容器且未启用归档，未收集原始日志")
+		return
+	}
+	lines, err := logging.Search(paths, func(line string) bool { return lineMentions(line, network) }, opts.MaxLines)
+	if err != nil {
+		inc.Warnings = append(inc.Warnings, err.Error())
+	}
+	inc.Logs = lines
+}
+
+// lineMentions 检查日志行中是否出现网段内的IP
+func lineMentions(line string, network *net.IPNet) bool {
+	if ones, bits := network.Mask.Size(); ones == bits {
+		if !strings.Contains(line, network.IP.String()) {
+			return false
+		}
+	}
+	for _, token := range strings.FieldsFunc(line, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F' || r == '.' || r == ':')
+	}) {
+		if networkContains(network, strings.Trim(token, ".:")) {
+			return true
+		}
+	}
+	return false
+}
+
+// networkContains 检查字符串形式的IP是否在网段中
+func networkContains(network *net.IPNet, ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && network.Contains(parsed)
+}
+
+// Title 返回报告的标题，用作工单标题
+func (inc *Incident) Title() string {
+	return fmt.Sprintf("SSH攻击事件: %s（登录失败%d次，封禁%d次）", inc.Target, inc.Counts[string(event.TypeLoginFailed)], inc.Counts[string(event.TypeBanned)])
+}
+
+// Summary 返回报告的文字概要，每项一行，用作工单描述
+func (inc *Incident) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "报告对象: %s\n", inc.Target)
+	fmt.Fprintf(&b, "服务器: %s\n", inc.Server)
+	fmt.Fprintf(&b, "生成时间: %s\n", inc.Generated.Format("2006-01-02 15:04:05"))
+	if len(inc.Events) > 0 {
+		fmt.Fprintf(&b, "时间范围: %s 至 %s\n", inc.FirstSeen.Format("2006-01-02 15:04:05"), inc.LastSeen.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(&b, "登录失败: %d次  登录成功: %d次  封禁: %d次  解封: %d次\n",
+		inc.Counts[string(event.TypeLoginFailed)], inc.Counts[string(event.TypeLoginSuccess)],
+		inc.Counts[string(event.TypeBanned)], inc.Counts[string(event.TypeUnbanned)])
+	fmt.Fprintf(&b, "涉及IP: %d个\n", len(inc.Hosts))
+	if users := topUsers(inc.Users, 10); users != "" {
+		fmt.Fprintf(&b, "尝试的用户名: %s\n", users)
+	}
+	if len(inc.Bans) > 0 {
+		fmt.Fprintf(&b, "当前封禁: %d条\n", len(inc.Bans))
+	}
+	return b.String()
+}
+
+// topUsers 返回次数最多的用户名，格式为 用户名(次数)，以逗号分隔
+func topUsers(users map[string]int, limit int) string {
+	names := make([]string, 0, len(users))
+	for name := range users {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if users[names[i]] != users[names[j]] {
+			return users[names[i]] > users[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, 0, limit)
+	for _, name := range names[:min(len(names), limit)] {
+		parts = append(parts, fmt.Sprintf("%s(%d)", name, users[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Markdown 返回Markdown格式的完整报告
+func (inc *Incident) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", inc.Title())
+	b.WriteString("## 概要\n\n")
+	for _, line := range strings.Split(strings.TrimRight(inc.Summary(), "\n"), "\n") {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	for _, warning := range inc.Warnings {
+		fmt.Fprintf(&b, "- ⚠ %s\n", warning)
+	}
+
+	b.WriteString("\n## 涉及的IP\n\n")
+	for _, host := range inc.Hosts {
+		fmt.Fprintf(&b, "### %s（%d条事件）\n\n", host.IP, host.Events)
+		if host.Info != "" {
+			fmt.Fprintf(&b, "```\n%s\n```\n\n", host.Info)
+		}
+	}
+
+	b.WriteString("## 封禁与防火墙规则\n\n")
+	if len(inc.Bans) == 0 && len(inc.Firewall) == 0 {
+		b.WriteString("无\n")
+	}
+	for _, ban := range inc.Bans {
+		expires := "永久"
+		if !ban.ExpiresAt.IsZero() {
+			expires = ban.ExpiresAt.Format("2006-01-02 15:04:05") + " 到期"
+		}
+		fmt.Fprintf(&b, "- %s %s，%s", ban.IP, ban.Type, expires)
+		if ban.Reason != "" {
+			fmt.Fprintf(&b, "，%s", ban.Reason)
+		}
+		if ban.HasSource() {
+			fmt.Fprintf(&b, "（%s）", banSourceSummary(ban))
+		}
+		b.WriteString("\n")
+	}
+	for _, rule := range inc.Firewall {
+		fmt.Fprintf(&b, "- `%s`\n", rule)
+	}
+
+	b.WriteString("\n## 执法记录\n\n")
+	if len(inc.Audit) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("| 时间 | 操作 | 对象 | 来源 | 说明 |\n|---|---|---|---|---|\n")
+		for _, entry := range inc.Audit {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", entry.Time.Format("2006-01-02 15:04:05"), entry.Action, entry.Target, entry.Source, markdownCell(entry.Detail))
+		}
+	}
+
+	b.WriteString("\n## 事件时间线\n\n")
+	if len(inc.Events) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("| 时间 | 类型 | IP | 用户名 | 说明 |\n|---|---|---|---|---|\n")
+		for _, e := range inc.Events {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", e.Time.Format("2006-01-02 15:04:05"), e.Type, e.IP, markdownCell(e.User), markdownCell(e.Message))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n## 原始日志（%d行）\n\n", len(inc.Logs))
+	if len(inc.Logs) > 0 {
+		fmt.Fprintf(&b, "```\n%s\n```\n", strings.Join(inc.Logs, "\n"))
+	}
+	return b.String()
+}
+
+// markdownCell 转义Markdown表格单元格中的竖线和换行
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+// WriteZip 将报告写为zip压缩包，包含incident.md、incident.json和原始日志raw.log
+// 参数:
+//   - w: 写入的目标
+// 返回:
+//   - error: 写入失败时的错误信息
+func (inc *Incident) WriteZip(w io.Writer) error {
+	data, err := json.MarshalIndent(inc, "", "  ")
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{"incident.md", []byte(inc.Markdown())},
+		{"incident.json", data},
+		{"raw.log", []byte(strings.Join(inc.Logs, "\n") + "\n")},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: inc.Generated})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// banSourceSummary 将封禁记录中的来源信息合并为一行，如 CN，AS4134 CHINANET，家庭/移动宽带
+func banSourceSummary(ban store.Ban) string {
+	var parts []string
+	for _, part := range []string{
+		ban.Country,
+		strings.TrimSpace(ban.ASN + " " + ban.ASOrg),
+		ban.Hostname,
+		enrich.NetworkTypeName(ban.NetworkType),
+	} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "，")
+}
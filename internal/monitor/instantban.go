@@ -0,0 +1,197 @@
+package monitor
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 连接信息最多保留的条数与时长，超出后清理早于时长的记录
+const (
+	maxSSHConns = 4096
+	sshConnTTL  = 10 * time.Minute
+)
+
+// sshConn 同一sshd进程记录的连接信息，用于补全该进程后续日志中缺失的IP和客户端版本
+type sshConn struct {
+	ip     string    // 真实客户端IP
+	port   int       // 客户端源端口
+	client string    // 客户端版本标识，sshd未记录时为空
+	seen   time.Time // 记录的时间
+}
+
+// instantBan 一条已编译的即时封禁条件
+type instantBan struct {
+	config.InstantBanConfig
+	user   *regexp.Regexp // 为nil时不限制用户名
+	client *regexp.Regexp // 为nil时不限制客户端版本
+}
+
+// compileInstantBans 编译即时封禁条件，正则表达式已在加载配置时校验
+// 参数:
+//   - cfgs: 配置中的即时封禁条件
+// 返回:
+//   - []instantBan: 编译后的条件，无效的条件被跳过
+func compileInstantBans(cfgs []config.InstantBanConfig) []instantBan {
+	var triggers []instantBan
+	for _, cfg := range cfgs {
+		t := instantBan{InstantBanConfig: cfg}
+		var err error
+		if cfg.User != "" {
+			if t.user, err = regexp.Compile(cfg.User); err != nil {
+				continue
+			}
+		}
+		if cfg.ClientVersion != "" {
+			if t.client, err = regexp.Compile(cfg.ClientVersion); err != nil {
+				continue
+			}
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers
+}
+
+// matches 检查事件是否满足该条件
+// 只有客户端版本的条件在收到版本日志时就匹配，不必等到认证失败；
+// 其余条件匹配登录失败事件，失败事件带有同一连接记录的客户端版本。
+// 滥用置信度在其他条件都满足后才取用，abuseScore需要等待提前提交的补充信息查询
+func (t *instantBan) matches(e LoginEvent, abuseScore func() int) bool {
+	if e.Outcome == OutcomeClient {
+		return t.client != nil && t.user == nil && !t.InvalidUser && t.AbuseScore == 0 && t.client.MatchString(e.Client)
+	}
+	if e.Outcome != OutcomeFailure {
+		return false
+	}
+	if t.client != nil && !t.client.MatchString(e.Client) {
+		return false
+	}
+	if t.InvalidUser && !e.InvalidUser {
+		return false
+	}
+	if t.user != nil && !t.user.MatchString(e.User) {
+		return false
+	}
+	return t.AbuseScore == 0 || abuseScore() >= t.AbuseScore
+}
+
+// trackConnection 记录或补全与sshd进程对应的连接信息
+// 新建连接事件记录进程ID与IP的对应关系；客户端版本事件中没有IP，从同一进程的连接记录中补全，
+// 并记下客户端版本，之后同一进程的登录成功和失败事件带上该版本；认证前断开的进程不会再有登录事件，删除其连接记录。
+// 只在sshd的日志处理协程中调用，不需要加锁
+// 参数:
+//   - e: 解析出的事件，新建连接事件的IP已解析为真实客户端IP
+// 返回:
+//   - LoginEvent: 补全IP后的事件
+//   - bool: 客户端版本事件找不到对应连接时为false
+func (m *Monitor) trackConnection(e LoginEvent) (LoginEvent, bool) {
+	switch e.Outcome {
+	case OutcomeConnect:
+		if len(m.sshConns) >= maxSSHConns {
+			for pid, conn := range m.sshConns {
+				if e.Timestamp.Sub(conn.seen) > sshConnTTL {
+					delete(m.sshConns, pid)
+				}
+			}
+		}
+		if e.PID != 0 && len(m.sshConns) < maxSSHConns {
+			m.sshConns[e.PID] = sshConn{ip: e.IP, port: e.Port, seen: e.Timestamp}
+		}
+	case OutcomeClient:
+		conn, ok := m.sshConns[e.PID]
+		if !ok {
+			return e, false
+		}
+		e.IP, e.Port = conn.ip, conn.port
+		conn.client = e.Client
+		m.sshConns[e.PID] = conn
+	case OutcomeSuccess, OutcomeFailure:
+		if conn, ok := m.sshConns[e.PID]; ok && e.PID != 0 {
+			e.Client = conn.client
+		}
+	case OutcomePreauth:
+		delete(m.sshConns, e.PID)
+	}
+	return e, true
+}
+
+// abuseTriggers 检查是否有按滥用置信度的即时封禁条件
+// 有这类条件时所有登录失败事件都需要提前查询补充信息，见needsLookup
+func (m *Monitor) abuseTriggers() bool {
+	for i := range m.instantBans {
+		if m.instantBans[i].AbuseScore > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInstantBan 按即时封禁条件检查事件，匹配任一条件时直接封禁，不经过失败计数和限速
+// 白名单和临时信任的IP不会被即时封禁，继续按普通流程处理，也不会为其等待补充信息；
+// 滥用置信度取自prefetchLogins提前提交的查询结果，不在日志处理协程中请求AbuseIPDB
+// 参数:
+//   - e: 登录失败或客户端版本事件
+// 返回:
+//   - bool: 已匹配条件、调用方不应再按普通流程处理时为true
+func (m *Monitor) checkInstantBan(e LoginEvent) bool {
+	if len(m.instantBans) == 0 {
+		return false
+	}
+	exempt := func() bool {
+		return m.whitelist.contains(e.IP) || m.isTrusted(e.IP, e.Timestamp)
+	}
+	m.mu.Lock()
+	skip := exempt()
+	m.mu.Unlock()
+	if skip {
+		return false
+	}
+
+	// 未启用AbuseIPDB、查询失败或没有提前提交查询时视为0，即不满足任何置信度条件
+	abuseScore := func() int {
+		if e.lookup == nil {
+			return 0
+		}
+		if r := e.lookup.wait(); r.Abuse != nil {
+			return r.Abuse.Score
+		}
+		return 0
+	}
+	var trigger *instantBan
+	for i := range m.instantBans {
+		if m.instantBans[i].matches(e, abuseScore) {
+			trigger = &m.instantBans[i]
+			break
+		}
+	}
+	if trigger == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 等待补充信息期间IP可能已被加入白名单
+	if exempt() {
+		return false
+	}
+	if m.isIPBanned(e.IP) {
+		return true
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"ip":      e.IP,
+		"user":    e.User,
+		"client":  e.Client,
+		"trigger": trigger.Name,
+	}).Warn("匹配即时封禁条件")
+	duration := m.banDuration()
+	if trigger.BanDuration > 0 {
+		duration = time.Duration(trigger.BanDuration)
+	}
+	if err := m.banIP(e.IP, e.Timestamp, e.Observed, e.Span, duration, "即时封禁条件 "+trigger.Name, SourceLog); err != nil {
+		m.logger.WithError(err).WithField("ip", e.IP).Error("封禁IP失败")
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package monitor
+
+import (
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// IP信息缓存写入文件的间隔
+const ipInfoCacheSaveInterval = time.Minute
+
+// loadIPInfoCache 启用IP信息缓存并加载缓存文件
+// 缓存文件损坏时只记录警告并从空缓存开始，不影响启动
+func (m *Monitor) loadIPInfoCache() {
+	cfg := m.config.IPInfo
+	if err := m.ipInfo.SetCache(cfg.CacheFile, time.Duration(cfg.CacheTTL), cfg.CacheSize); err != nil {
+		m.logger.WithError(err).WithField("file", cfg.CacheFile).Warn("加载IP信息缓存失败")
+		return
+	}
+	if cfg.CacheFile != "" {
+		m.logger.WithField("cached", m.ipInfo.Stats().Cached).Info("IP信息缓存已加载")
+	}
+}
+
+// saveIPInfoCache 定期将IP信息缓存写入文件
+func (m *Monitor) saveIPInfoCache() {
+	ticker := time.NewTicker(ipInfoCacheSaveInterval)
+	for range ticker.C {
+		if err := m.ipInfo.SaveCache(); err != nil {
+			m.logger.WithError(err).Error("保存IP信息缓存失败")
+		}
+	}
+}
+
+// prefetchBannedIPInfo 启动时批量查询黑名单中IP的属地信息并写入缓存
+// 之后的通知、排行和国家统计都直接命中缓存，不必在解封或查看时逐个请求接口
+func (m *Monitor) prefetchBannedIPInfo() {
+	// 未启用缓存时预取的结果无处保存
+	if m.config.IPInfo.CacheTTL == 0 {
+		return
+	}
+	m.mu.RLock()
+	ips := make([]string, 0, len(m.bannedIPs)+len(m.permanentIPs))
+	for ip := range m.bannedIPs {
+		ips = append(ips, ip)
+	}
+	for ip := range m.permanentIPs {
+		ips = append(ips, ip)
+	}
+	m.mu.RUnlock()
+
+	// 网段无法查询属地，只保留单个IP
+	n := 0
+	for _, ip := range ips {
+		if net.ParseIP(ip) != nil {
+			ips[n] = ip
+			n++
+		}
+	}
+	ips = ips[:n]
+	if len(ips) == 0 {
+		return
+	}
+
+	infos, err := m.ipInfo.GetIPInfoBatch(ips)
+	entry := m.logger.WithFields(logrus.Fields{
+		"ips":     len(ips),
+		"fetched": len(infos),
+	})
+	if err != nil {
+		entry.WithError(err).Warn("预取封禁IP信息未全部完成")
+		return
+	}
+	entry.Info("封禁IP信息预取完成")
+}
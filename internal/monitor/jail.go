@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// jailSSHD 内置的SSH登录防护jail
+const jailSSHD = "sshd"
+
+// jailState jail状态文件的内容
+type jailState struct {
+	Disabled []string `json:"disabled"` // 已停用的jail
+}
+
+// jailNames 返回所有jail的名称，内置的jail排在最前，其后为配置中的通用规则
+func (m *Monitor) jailNames() []string {
+	names := []string{jailSSHD}
+	if m.config.Jails.HAProxy.Enabled {
+		names = append(names, jailHAProxy)
+	}
+	for _, r := range m.config.Rules {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// loadJailState 从状态文件加载已停用的jail
+// 状态文件不存在时所有jail均为启用状态
+// 返回:
+//   - error: 读取或解析状态文件失败时的错误信息
+func (m *Monitor) loadJailState() error {
+	data, err := os.ReadFile(m.config.Jails.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取jail状态文件失败: %v", err)
+	}
+
+	var state jailState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("解析jail状态文件失败: %v", err)
+	}
+	for _, name := range state.Disabled {
+		m.disabledJails[name] = true
+		m.logger.WithField("jail", name).Warn("jail处于停用状态")
+	}
+	return nil
+}
+
+// saveJailState 保存已停用的jail
+// 调用方需持有m.mu锁
+func (m *Monitor) saveJailState() error {
+	state := jailState{Disabled: []string{}}
+	for name := range m.disabledJails {
+		state.Disabled = append(state.Disabled, name)
+	}
+	sort.Strings(state.Disabled)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.config.Jails.StateFile, data, 0644)
+}
+
+// jailEnabled 检查jail是否启用
+func (m *Monitor) jailEnabled(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.disabledJails[name]
+}
+
+// Jails 返回所有jail及其启用状态
+// 返回:
+//   - []control.JailInfo: jail列表
+func (m *Monitor) Jails() []control.JailInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var jails []control.JailInfo
+	for _, name := range m.jailNames() {
+		info := control.JailInfo{Name: name, Enabled: !m.disabledJails[name]}
+		if r := m.jailRunners[name]; r != nil {
+			r.fill(&info)
+		}
+		jails = append(jails, info)
+	}
+	return jails
+}
+
+// SetJailEnabled 启用或停用jail，无需重启
+// 停用期间该jail的日志仍会被读取但不做处理，已有的封禁不受影响
+// 参数:
+//   - name: jail名称
+//   - enabled: true为启用，false为停用
+// 返回:
+//   - error: jail不存在或保存状态失败时的错误信息
+func (m *Monitor) SetJailEnabled(name string, enabled bool) error {
+	return m.setJailEnabled(name, enabled, SourceControl)
+}
+
+// setJailEnabled 启用或停用jail，source为审计记录中的触发来源
+func (m *Monitor) setJailEnabled(name string, enabled bool, source string) error {
+	known := false
+	for _, n := range m.jailNames() {
+		if n == name {
+			known = true
+		}
+	}
+	if !known {
+		return fmt.Errorf("未知的jail: %s", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.disabledJails[name] == !enabled {
+		return nil
+	}
+	if enabled {
+		delete(m.disabledJails, name)
+	} else {
+		m.disabledJails[name] = true
+	}
+	if err := m.saveJailState(); err != nil {
+		return fmt.Errorf("保存jail状态失败: %v", err)
+	}
+	if enabled {
+		m.audit(auditJailEnable, name, source, "")
+	} else {
+		m.audit(auditJailDisable, name, source, "")
+	}
+
+	message := "jail已启用"
+	if !enabled {
+		message = "jail已停用"
+	}
+	m.logger.WithField("jail", name).Warn(message)
+	m.events.Publish(event.Event{Type: event.TypeJailChanged, Message: message + ": " + name})
+	return nil
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// lagBuckets 延迟分布各区间的上限，超过最后一个上限的计入溢出区间
+var lagBuckets = []time.Duration{
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// lagHistogram 日志处理延迟的分布统计
+type lagHistogram struct {
+	mu        sync.Mutex
+	counts    [9]int64      // 各区间的行数，最后一项为溢出区间
+	count     int64         // 已统计的行数
+	sum       time.Duration // 延迟总和
+	max       time.Duration // 最大延迟
+	last      time.Duration // 最近一行的延迟
+	alertedAt time.Time     // 最近一次告警的时间
+	alerts    int           // 已发出的告警次数
+}
+
+// observe 记录一行日志的处理延迟
+func (h *lagHistogram) observe(lag time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(lagBuckets) && lag > lagBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += lag
+	h.max = max(h.max, lag)
+	h.last = lag
+}
+
+// shouldAlert 判断是否需要发出告警，距上次告警不足cooldown时不重复告警
+func (h *lagHistogram) shouldAlert(now time.Time, cooldown time.Duration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.alertedAt.IsZero() && now.Sub(h.alertedAt) < cooldown {
+		return false
+	}
+	h.alertedAt = now
+	h.alerts++
+	return true
+}
+
+// stats 返回延迟统计的快照
+// 参数:
+//   - queue: 当前等待处理的登录事件数
+// 返回:
+//   - control.LagStats: 延迟统计
+func (h *lagHistogram) stats(queue int) control.LagStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := control.LagStats{
+		Count:   h.count,
+		Sum:     h.sum.Seconds(),
+		Max:     h.max.Seconds(),
+		Last:    h.last.Seconds(),
+		Queue:   queue,
+		Alerts:  h.alerts,
+		Buckets: make([]control.LagBucket, len(h.counts)),
+	}
+	for i, n := range h.counts {
+		stats.Buckets[i].Count = n
+		if i < len(lagBuckets) {
+			stats.Buckets[i].LE = lagBuckets[i].Seconds()
+		}
+	}
+	return stats
+}
+
+// recordLag 记录登录事件的处理延迟，超过阈值时告警
+// 延迟是处理完事件的时间与日志行自身时间之差，包括在队列中等待的时间；
+// syslog格式的时间只精确到秒，延迟会有不到一秒的误差
+// 参数:
+//   - e: 刚处理完的登录事件
+func (m *Monitor) recordLag(e LoginEvent) {
+	now := time.Now()
+	lag := max(now.Sub(e.Timestamp), 0)
+	m.logLag.observe(lag)
+
+	cfg := m.config.Notifications.LogLag
+	threshold := time.Duration(cfg.Threshold) * time.Second
+	if lag < threshold || !m.logLag.shouldAlert(now, time.Duration(cfg.Cooldown)*time.Minute) {
+		return
+	}
+	queue := m.queuedLogins()
+	m.logger.WithFields(logrus.Fields{"lag": lag.Round(time.Second), "queue": queue}).Warn("日志处理延迟超过阈值，处理速度跟不上日志写入")
+	m.notifier.Notify(notification.LogLagEvent(lag, threshold, queue, m.serverName()))
+}
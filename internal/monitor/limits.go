@@ -0,0 +1,206 @@
+package monitor
+
+import (
+	"container/list"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// attemptCompactInterval 清除滑动窗口外失败记录的间隔
+// 失败记录按最近一次失败的时间排列，每次只需检查最旧的几项，可以频繁执行
+const attemptCompactInterval = time.Minute
+
+// maxAttemptTimes 每个IP最多保留的失败时间数，超出后丢弃最早的
+// 失败次数在达到阈值后就会被处置，保留的时间数远大于任何阈值
+const maxAttemptTimes = 1024
+
+// attemptCounts 各IP在滑动窗口内的失败时间，按最近一次失败的时间从旧到新排列
+// 每次计入失败时丢弃该IP窗口外的失败时间，失败次数即窗口内的失败数；
+// 记录的IP数超出上限时清除最久未失败的IP，所有失败都已在窗口外的IP由compactAttempts定期清除；
+// 失败时间取自日志时间，窗口按已计入的最新日志时间滑动，处理积压或历史日志时不受当前时间影响；
+// 调用方需持有m.mu锁
+type attemptCounts struct {
+	max     int                      // 最多记录的IP数，0表示不限制
+	window  time.Duration            // 滑动窗口的长度，0表示不按时间丢弃失败
+	order   *list.List               // 元素值为*attemptEntry，最前面的最久未失败
+	entries map[string]*list.Element // 键为IP
+	evicted int64                    // 自启动起因超出上限被清除的IP数
+	latest  time.Time                // 已计入的最新失败时间
+}
+
+// attemptEntry 一个IP在滑动窗口内的失败
+type attemptEntry struct {
+	ip    string
+	times []time.Time // 各次失败的时间，从旧到新
+}
+
+// last 返回最近一次失败的时间
+func (e *attemptEntry) last() time.Time {
+	return e.times[len(e.times)-1]
+}
+
+// newAttemptCounts 创建失败次数记录
+// 参数:
+//   - max: 最多记录的IP数，0表示不限制
+//   - window: 滑动窗口的长度，0表示不按时间丢弃失败
+// 返回:
+//   - *attemptCounts: 初始化后的记录
+func newAttemptCounts(max int, window time.Duration) *attemptCounts {
+	return &attemptCounts{max: max, window: window, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// add 计入一次失败并丢弃该IP窗口外的失败，超出上限时清除最久未失败的IP
+// 多个日志来源的失败时间可能乱序到达，按时间插入并以该IP最新的失败时间为窗口终点
+// 参数:
+//   - ip: 失败的IP
+//   - now: 失败的日志时间
+// 返回:
+//   - int: 计入后该IP在滑动窗口内的失败次数
+func (a *attemptCounts) add(ip string, now time.Time) int {
+	if now.After(a.latest) {
+		a.latest = now
+	}
+	if el := a.entries[ip]; el != nil {
+		entry := el.Value.(*attemptEntry)
+		times := entry.times
+		i := sort.Search(len(times), func(i int) bool { return times[i].After(now) })
+		times = append(times, time.Time{})
+		copy(times[i+1:], times[i:])
+		times[i] = now
+		if a.window > 0 {
+			cutoff := times[len(times)-1].Add(-a.window)
+			i := 0
+			for i < len(times) && times[i].Before(cutoff) {
+				i++
+			}
+			times = times[i:]
+		}
+		if len(times) > maxAttemptTimes {
+			times = times[len(times)-maxAttemptTimes:]
+		}
+		entry.times = times
+		a.order.MoveToBack(el)
+		return len(entry.times)
+	}
+	a.entries[ip] = a.order.PushBack(&attemptEntry{ip: ip, times: []time.Time{now}})
+	for a.max > 0 && len(a.entries) > a.max {
+		a.removeElement(a.order.Front())
+		a.evicted++
+	}
+	return 1
+}
+
+// get 返回IP在滑动窗口内的失败次数，未记录时为0
+// 窗口外的失败在下一次add时丢弃，get应在计入本次失败之后调用
+func (a *attemptCounts) get(ip string) int {
+	if el := a.entries[ip]; el != nil {
+		return len(el.Value.(*attemptEntry).times)
+	}
+	return 0
+}
+
+// remove 清除IP的失败次数，如封禁或解封后
+func (a *attemptCounts) remove(ip string) {
+	if el := a.entries[ip]; el != nil {
+		a.removeElement(el)
+	}
+}
+
+// len 返回记录了失败次数的IP数
+func (a *attemptCounts) len() int {
+	return len(a.entries)
+}
+
+// expire 清除所有失败都已在滑动窗口外的IP，即最近一次失败早于窗口起点的IP
+// 窗口终点为已计入的最新失败时间，而不是当前时间
+// 返回:
+//   - int: 清除的IP数
+func (a *attemptCounts) expire() int {
+	if a.window == 0 || a.latest.IsZero() {
+		return 0
+	}
+	cutoff := a.latest.Add(-a.window)
+	n := 0
+	for el := a.order.Front(); el != nil && el.Value.(*attemptEntry).last().Before(cutoff); el = a.order.Front() {
+		a.removeElement(el)
+		n++
+	}
+	return n
+}
+
+// removeElement 从链表和索引中删除一项
+func (a *attemptCounts) removeElement(el *list.Element) {
+	delete(a.entries, a.order.Remove(el).(*attemptEntry).ip)
+}
+
+// compactAttempts 定期清除所有失败都已在滑动窗口外的IP，超出上限清除过IP时记录警告
+func (m *Monitor) compactAttempts() {
+	ticker := time.NewTicker(attemptCompactInterval)
+	defer ticker.Stop()
+	var reported int64
+	for range ticker.C {
+		m.mu.Lock()
+		expired := m.failedAttempts.expire()
+		evicted := m.failedAttempts.evicted - reported
+		reported = m.failedAttempts.evicted
+		tracked := m.failedAttempts.len()
+		m.mu.Unlock()
+
+		if expired > 0 {
+			m.logger.WithFields(logrus.Fields{"expired": expired, "tracked": tracked}).Debug("已清除滑动窗口外的失败次数")
+		}
+		if evicted > 0 {
+			m.logger.WithFields(logrus.Fields{
+				"evicted": evicted,
+				"tracked": tracked,
+				"max":     m.config.SSHProtection.MaxTrackedIPs,
+			}).Warn("记录失败次数的IP数达到上限，已清除最久未失败的IP")
+		}
+	}
+}
+
+// limitBannedIPs 限时封禁数超过max_banned_ips时提前解除最早到期的封禁，永久封禁不计入
+// 一次解除到上限的99%，避免封禁数在上限附近时每次封禁都遍历所有封禁；
+// 调用方需持有m.mu锁，并在之后保存黑名单
+// 参数:
+//   - keep: 刚加入的封禁，即使最早到期也不解除
+// 返回:
+//   - bool: 是否解除了封禁
+func (m *Monitor) limitBannedIPs(keep string) bool {
+	max := m.config.SSHProtection.MaxBannedIPs
+	if max == 0 || len(m.bannedIPs) <= max {
+		return false
+	}
+	ips := make([]string, 0, len(m.bannedIPs))
+	for ip := range m.bannedIPs {
+		if ip != keep {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool { return m.bannedIPs[ips[i]].Before(m.bannedIPs[ips[j]]) })
+
+	released := 0
+	for _, ip := range ips[:len(m.bannedIPs)-(max-max/100)] {
+		if err := m.unblockIP(ip); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("提前解除IP封禁失败")
+			continue
+		}
+		delete(m.bannedIPs, ip)
+		m.cancelUnban(ip)
+		m.failedAttempts.remove(ip)
+		m.audit(auditUnban, ip, SourceSystem, fmt.Sprintf("限时封禁数超过上限%d，提前解除最早到期的封禁", max))
+		m.events.Publish(event.Event{Type: event.TypeUnbanned, IP: ip, Message: "限时封禁数超过上限，已提前解除"})
+		released++
+	}
+	m.bansEvicted += int64(released)
+	m.logger.WithFields(logrus.Fields{
+		"released": released,
+		"banned":   len(m.bannedIPs),
+		"max":      max,
+	}).Warn("限时封禁数超过上限，已提前解除最早到期的封禁")
+	return released > 0
+}
@@ -0,0 +1,95 @@
+package monitor
+
+import (
+	"sync"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// liveFeed 将登录事件和封禁等事件实时分发给控制接口的实时流订阅者
+// 与loginFeed不同，订阅者随连接建立和断开，需要支持取消订阅
+type liveFeed struct {
+	mu   sync.Mutex
+	subs map[chan control.LiveMessage]struct{}
+}
+
+// subscribe 添加一个订阅者
+// 参数:
+//   - buffer: 订阅通道的缓冲长度
+// 返回:
+//   - chan control.LiveMessage: 消息通道
+//   - func(): 取消订阅，可以重复调用
+func (f *liveFeed) subscribe(buffer int) (chan control.LiveMessage, func()) {
+	ch := make(chan control.LiveMessage, buffer)
+	f.mu.Lock()
+	if f.subs == nil {
+		f.subs = make(map[chan control.LiveMessage]struct{})
+	}
+	f.subs[ch] = struct{}{}
+	f.mu.Unlock()
+	return ch, func() {
+		f.mu.Lock()
+		delete(f.subs, ch)
+		f.mu.Unlock()
+	}
+}
+
+// active 是否有订阅者，没有时调用方不必构造消息
+func (f *liveFeed) active() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs) > 0
+}
+
+// publish 向所有订阅者发送消息，订阅者来不及处理时丢弃该消息，不阻塞日志处理
+func (f *liveFeed) publish(msg control.LiveMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// publishLogin 向实时流发送处理完的登录事件
+func (m *Monitor) publishLogin(e LoginEvent) {
+	if !m.live.active() {
+		return
+	}
+	m.live.publish(control.LiveMessage{Kind: "login", Login: &control.LiveLogin{
+		Time:        e.Timestamp,
+		Outcome:     string(e.Outcome),
+		IP:          e.IP,
+		Port:        e.Port,
+		User:        e.User,
+		InvalidUser: e.InvalidUser,
+		Method:      e.Method,
+		Client:      e.Client,
+		PID:         e.PID,
+		Counted:     e.CountsAsFailure(),
+	}})
+}
+
+// publishLiveEvent 向实时流发送事件总线上的事件，登录成功、失败与会话结束已作为登录事件发送，不重复发送
+func (m *Monitor) publishLiveEvent(e event.Event) {
+	switch e.Type {
+	case event.TypeLoginFailed, event.TypeLoginSuccess, event.TypeLogout:
+		return
+	}
+	if m.live.active() {
+		m.live.publish(control.LiveMessage{Kind: "event", Event: &e})
+	}
+}
+
+// Live 订阅实时的登录事件与封禁、解封、限速等事件
+// 参数:
+//   - buffer: 订阅通道的缓冲长度，缓冲已满时新消息会被丢弃
+// 返回:
+//   - <-chan control.LiveMessage: 消息通道
+//   - func(): 取消订阅
+func (m *Monitor) Live(buffer int) (<-chan control.LiveMessage, func()) {
+	return m.live.subscribe(buffer)
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// userLocations 用户登录成功过的国家和ASN
+type userLocations struct {
+	Countries map[string]bool `json:"countries"`
+	ASNs      map[string]bool `json:"asns"`
+}
+
+// loadLocations 从状态文件加载各用户登录过的位置
+// 状态文件不存在时视为没有任何历史记录
+// 返回:
+//   - error: 读取或解析状态文件失败时的错误信息
+func (m *Monitor) loadLocations() error {
+	if !m.config.SSHProtection.NewLocation.Enabled {
+		return nil
+	}
+	data, err := os.ReadFile(m.config.SSHProtection.NewLocation.StateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取登录位置文件失败: %v", err)
+	}
+	if err := json.Unmarshal(data, &m.locations); err != nil {
+		return fmt.Errorf("解析登录位置文件失败: %v", err)
+	}
+	if m.locations == nil {
+		m.locations = make(map[string]*userLocations)
+	}
+	return nil
+}
+
+// saveLocations 保存各用户登录过的位置
+// 调用方需持有m.mu锁
+func (m *Monitor) saveLocations() error {
+	data, err := json.MarshalIndent(m.locations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.config.SSHProtection.NewLocation.StateFile, data, 0644)
+}
+
+// recordLocation 记录用户本次登录的位置，返回首次出现的位置类型
+// 用户没有任何历史记录时只记录不告警，避免首次部署时每个用户都触发告警
+// 调用方需持有m.mu锁
+// 参数:
+//   - user: 登录用户名
+//   - info: 登录IP的属地信息
+// 返回:
+//   - string: 首次出现的位置类型（国家、ASN或国家和ASN），没有新位置时为空
+func (m *Monitor) recordLocation(user string, info *ipinfo.IPInfo) string {
+	checkASN := m.config.SSHProtection.NewLocation.CheckASN
+	if info.Country == "" && (!checkASN || info.ASN == "") {
+		return ""
+	}
+
+	seen, known := m.locations[user]
+	if !known {
+		seen = &userLocations{Countries: make(map[string]bool), ASNs: make(map[string]bool)}
+		m.locations[user] = seen
+	}
+
+	newCountry := info.Country != "" && !seen.Countries[info.Country]
+	newASN := checkASN && info.ASN != "" && !seen.ASNs[info.ASN]
+	if !newCountry && !newASN {
+		return ""
+	}
+	if info.Country != "" {
+		seen.Countries[info.Country] = true
+	}
+	if checkASN && info.ASN != "" {
+		seen.ASNs[info.ASN] = true
+	}
+	if err := m.saveLocations(); err != nil {
+		m.logger.WithError(err).Error("保存登录位置失败")
+	}
+
+	if !known {
+		m.logger.WithFields(logrus.Fields{"user": user, "country": info.Country, "asn": info.ASN}).Info("记录用户首次登录位置")
+		return ""
+	}
+	switch {
+	case newCountry && newASN:
+		return "国家和ASN"
+	case newCountry:
+		return "国家"
+	default:
+		return "ASN"
+	}
+}
@@ -0,0 +1,93 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// 日志读取与登录事件处理之间的队列长度，队列满时读取日志会等待处理
+const loginQueueSize = 1024
+
+// loginFeed 将处理完的登录事件分发给订阅者
+type loginFeed struct {
+	mu   sync.Mutex
+	subs []chan LoginEvent
+}
+
+// subscribe 添加一个订阅者
+func (f *loginFeed) subscribe(buffer int) <-chan LoginEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan LoginEvent, buffer)
+	f.subs = append(f.subs, ch)
+	return ch
+}
+
+// publish 向所有订阅者发送事件，订阅者来不及处理时丢弃该事件，不阻塞日志处理
+// 返回:
+//   - int: 被丢弃的订阅者数量
+func (f *loginFeed) publish(e LoginEvent) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dropped := 0
+	for _, ch := range f.subs {
+		select {
+		case ch <- e:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// SubscribeLogins 订阅登录事件
+// 每个sshd登录成功、失败、会话结束和认证前断开事件在监控器处理完后发送给所有订阅者，
+// 其中的IP已解析为真实客户端IP
+// 参数:
+//   - buffer: 订阅通道的缓冲长度，缓冲已满时新事件会被丢弃
+// 返回:
+//   - <-chan LoginEvent: 登录事件通道
+func (m *Monitor) SubscribeLogins(buffer int) <-chan LoginEvent {
+	return m.loginFeed.subscribe(buffer)
+}
+
+// dispatchLogins 按日志顺序处理登录事件并分发给订阅者
+// 每处理一个事件以及空闲时每隔dispatchBeat更新一次心跳，空闲时的心跳需要获取m.mu，锁被长期占用时心跳也会停止
+func (m *Monitor) dispatchLogins() {
+	ticker := time.NewTicker(dispatchBeat)
+	defer ticker.Stop()
+	for {
+		m.dispatchBeat.Store(time.Now().UnixNano())
+		var e LoginEvent
+		select {
+		case e = <-m.pending:
+		case <-ticker.C:
+			m.mu.RLock()
+			m.mu.RUnlock()
+			continue
+		}
+		switch e.Outcome {
+		case OutcomeSuccess:
+			m.handleSuccessfulLogin(e)
+		case OutcomeFailure:
+			m.handleFailedLogin(e)
+		case OutcomeLogout:
+			m.handleLogout(e)
+		case OutcomePreauth:
+			m.handlePreauth(e)
+		case OutcomeClient:
+			m.checkInstantBan(e)
+		case OutcomeConnect:
+			m.checkCountryBlock(e)
+		}
+		if e.IP != "" {
+			e.Span.Set("ip", e.IP)
+		}
+		e.Span.End()
+		m.recordLag(e)
+		if dropped := m.loginFeed.publish(e); dropped > 0 {
+			m.logger.WithField("subscribers", dropped).Debug("登录事件订阅者处理不及时，已丢弃事件")
+		}
+		m.publishLogin(e)
+	}
+}
@@ -5,15 +5,23 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"net"
 	"os"
-	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/crowdsec"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/fleet"
+	"github.com/yourusername/ssh_fb/internal/logging"
 	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/store"
+	"github.com/yourusername/ssh_fb/internal/tracing"
 	"github.com/yourusername/ssh_fb/pkg/firewall"
 	"github.com/yourusername/ssh_fb/pkg/ipinfo"
 )
@@ -22,11 +30,67 @@ import (
 type Monitor struct {
 	config         *config.Config                // 配置信息
 	logger         *logrus.Logger               // 日志记录器
-	telegram       *notification.Telegram       // Telegram通知器
+	notifier       notification.Notifier        // 通知分发器
 	firewall       *firewall.UFW                // 防火墙管理器
 	ipInfo         *ipinfo.Client               // IP信息查询客户端
-	failedAttempts map[string]int               // IP失败尝试次数记录
+	enricher       *enrich.Pipeline             // IP补充信息流水线，属地查询使用ipInfo
+	failedAttempts *attemptCounts               // IP在滑动窗口内各次失败的时间，记录的IP数有上限
 	bannedIPs      map[string]time.Time         // 被封禁IP及其解封时间
+	unbanTimers    map[string]*time.Timer       // 各限时封禁在解封时间触发的定时器
+	bansEvicted    int64                        // 因限时封禁数超过上限提前解除的封禁数
+	permanentIPs   map[string]bool              // 永久封禁的IP
+	bannedSubnets  map[string]*net.IPNet        // 封禁中网段的解析结果，键为CIDR
+	blacklistDirty bool                         // 封禁状态有变化、尚未写入黑名单文件
+	blacklistTimer *time.Timer                  // 延迟写入黑名单文件的定时器，没有等待中的写入时为nil
+	blacklistWrite sync.Mutex                   // 保证黑名单文件按顺序写入，写入期间不持有mu
+	whitelist      whitelist                    // 白名单，不计数也不封禁
+	trustedIPs     map[string]time.Time         // 登录成功后临时信任的IP及其到期时间
+	totalFailures  map[string]int               // IP累计失败次数，封禁解除后保留
+	events         *event.Bus                   // 最近事件，供控制接口读取
+	userFailures   map[string][]failureRecord   // 用户名在检测窗口内的失败记录
+	sprayUsers     map[string]bool              // 正在遭受密码喷洒的用户名
+	subnetFailures map[string][]failureRecord   // 网段或ASN在检测窗口内的失败记录
+	subnetAlerted  map[string]bool              // 已发出分布式攻击告警的网段或ASN
+	disabledJails  map[string]bool              // 运行时停用的jail
+	rules          []*rule                      // 通用正则规则
+	archive        *logging.Archive             // 原始日志归档，未启用时为nil
+	auditLog       *auditLog                    // 审计日志文件，未启用时为nil
+	autoUnbanned   []string                     // 当天封禁到期自动解除的IP，用于每日汇总
+	realIP         *realIPResolver              // 代理之后的真实IP解析器，未启用时为nil
+	locations      map[string]*userLocations    // 各用户登录成功过的国家和ASN
+	proxyConns     map[string]proxyConn         // haproxy jail记录的转发连接，键为代理地址和源端口
+	proxyAddrs     map[string]bool              // 从代理日志中发现的代理地址，不会被封禁
+	countryStats   countryStats                 // 按国家自适应阈值的统计状态
+	sessions       map[int]session              // 尚未结束的SSH会话，键为sshd进程ID
+	sshConns       map[int]sshConn              // 新建连接的客户端地址，键为sshd进程ID，只在sshd的处理协程中访问
+	instantBans    []instantBan                 // 已编译的即时封禁条件
+	preauthConns   map[string][]failureRecord   // 各IP在检测窗口内认证前断开的连接
+	riskFailures   map[string][]failureRecord   // 各IP在风险评分速率窗口内的登录失败
+	limitedIPs     map[string]time.Time         // 限速模式下被限速的IP及其到期时间
+	sharedIPs      sharedIPs                    // 共享IP的标记与按用户名的失败计数
+	accountLocks   map[string]accountLock       // 遭受密码喷洒后临时锁定的本机账户
+	accountsBusy   map[string]bool              // 正在执行锁定或解锁命令的账户，命令执行期间不持有mu
+	logins         chan LoginEvent              // 已解析、等待提交补充信息查询的登录事件，保持日志顺序
+	pending        chan LoginEvent              // 已提交查询、等待处理的登录事件，保持日志顺序
+	lookups        *lookupPool                  // IP补充信息的查询协程池，Start之前为nil
+	jailRunners    map[string]*jailRunner       // 各jail的日志读取与处理协程
+	loginFeed      loginFeed                    // 登录事件的订阅者
+	live           liveFeed                     // 控制接口实时流的订阅者
+	logLag         lagHistogram                 // 登录事件的处理延迟分布
+	banLatency     banLatencyStats              // 封禁生效耗时统计
+	recentFailures failureCounter               // 最近一小时的登录失败次数
+	stats          rollingStats                 // 最近8天按小时分桶的登录失败与封禁统计
+	sampler        failureSampler               // 登录失败事件的抽样状态
+	feeds          []*feedState                 // 启用的订阅黑名单，包括CrowdSec拉取的决策
+	crowdsec       *crowdsec.Client             // CrowdSec本地API客户端，未启用时为nil
+	fleet          fleetSync                    // 集群同步的状态
+	agent          *agentForwarder              // 转发事件到集中监控，非agent模式时为nil
+	hub            *agentHub                    // 集中监控收到的agent统计，非server模式时为nil
+	startedAt      time.Time                    // 监控器启动时间
+	ready          atomic.Bool                  // 启动时的加载是否已完成
+	dispatchBeat   atomic.Int64                 // 登录事件处理协程的最近心跳（UnixNano）
+	store          store.Store                  // 封禁记录与事件的持久化存储
+	tracer         *tracing.Tracer              // 处理流程的跟踪数据导出，未启用时为nil
 	mu             sync.RWMutex                 // 并发控制锁
 }
 
@@ -34,19 +98,73 @@ type Monitor struct {
 // 参数:
 //   - config: 配置信息
 //   - logger: 日志记录器
-//   - telegram: Telegram通知器
+//   - notifier: 通知分发器，通常为*notification.Dispatcher
 // 返回:
 //   - *Monitor: 初始化后的监控器实例
-func NewMonitor(config *config.Config, logger *logrus.Logger, telegram *notification.Telegram) *Monitor {
-	return &Monitor{
+func NewMonitor(config *config.Config, logger *logrus.Logger, notifier notification.Notifier) *Monitor {
+	ipInfo := NewIPInfoClient(config.IPInfo)
+	m := &Monitor{
 		config:         config,
 		logger:         logger,
-		telegram:       telegram,
+		notifier:       notifier,
 		firewall:       firewall.NewUFW(),
-		ipInfo:         ipinfo.NewClient(config.IPInfo.APIURL, config.IPInfo.Language, config.IPInfo.Timeout, config.IPInfo.RetryCount, config.IPInfo.RetryInterval),
-		failedAttempts: make(map[string]int),
+		ipInfo:         ipInfo,
+		enricher:       enrich.New(config.Enrichment, ipInfo),
+		failedAttempts: newAttemptCounts(config.SSHProtection.MaxTrackedIPs, time.Duration(config.SSHProtection.AttemptWindow)),
 		bannedIPs:      make(map[string]time.Time),
+		unbanTimers:    make(map[string]*time.Timer),
+		permanentIPs:   make(map[string]bool),
+		bannedSubnets:  make(map[string]*net.IPNet),
+		trustedIPs:     make(map[string]time.Time),
+		totalFailures:  make(map[string]int),
+		events:         event.NewBus(500),
+		userFailures:   make(map[string][]failureRecord),
+		sprayUsers:     make(map[string]bool),
+		subnetFailures: make(map[string][]failureRecord),
+		subnetAlerted:  make(map[string]bool),
+		disabledJails:  make(map[string]bool),
+		locations:      make(map[string]*userLocations),
+		proxyConns:     make(map[string]proxyConn),
+		proxyAddrs:     make(map[string]bool),
+		sessions:       make(map[int]session),
+		sshConns:       make(map[int]sshConn),
+		instantBans:    compileInstantBans(config.SSHProtection.InstantBan),
+		preauthConns:   make(map[string][]failureRecord),
+		riskFailures:   make(map[string][]failureRecord),
+		limitedIPs:     make(map[string]time.Time),
+		sharedIPs:      newSharedIPs(config.SSHProtection.SharedIP),
+		accountLocks:   make(map[string]accountLock),
+		accountsBusy:   make(map[string]bool),
+		logins:         make(chan LoginEvent, loginQueueSize),
+		pending:        make(chan LoginEvent, loginQueueSize),
+		jailRunners:    make(map[string]*jailRunner),
+		feeds:          newFeeds(config.Blacklist.Feeds),
+		crowdsec:       newCrowdSec(config.CrowdSec),
+		store:          store.NewMemory(),
 	}
+	if feed := m.newCrowdSecFeed(); feed != nil {
+		m.feeds = append(m.feeds, feed)
+	}
+	return m
+}
+
+// NewIPInfoClient 按配置创建IP信息查询客户端，包括属地查询接口、熔断与批量查询设置
+// 缓存文件需要调用方通过SetCache加载，守护进程在Start中加载
+// 参数:
+//   - cfg: IP信息查询配置
+// 返回:
+//   - *ipinfo.Client: 初始化后的客户端
+func NewIPInfoClient(cfg config.IPInfoConfig) *ipinfo.Client {
+	client := ipinfo.NewClient(cfg.APIURL, cfg.Language, cfg.Timeout, cfg.RetryCount, cfg.RetryInterval)
+	client.SetBreaker(cfg.BreakerFailures, time.Duration(cfg.BreakerCooldown)*time.Second)
+	client.SetBatch(cfg.BatchURL, cfg.BatchSize, time.Duration(cfg.BatchInterval)*time.Second)
+	providers := make([]ipinfo.Provider, 0, len(cfg.Providers))
+	for _, p := range cfg.Providers {
+		providers = append(providers, p.Provider())
+	}
+	// 接口配置在加载配置时已经校验
+	client.SetProviders(providers)
+	return client
 }
 
 // Start 启动监控器
@@ -54,87 +172,337 @@ func NewMonitor(config *config.Config, logger *logrus.Logger, telegram *notifica
 // 返回:
 //   - error: 启动过程中的错误信息
 func (m *Monitor) Start() error {
-	// 加载黑名单
-	if err := m.loadBlacklist(); err != nil {
+	m.startedAt = time.Now()
+	m.tracer = tracing.New(m.config.Debug, m.logger)
+	m.lookups = newLookupPool(m.config.Enrichment.Workers, m.config.Enrichment.QueueSize, m.enrichIPTraced)
+
+	// 打开存储，之后的事件与封禁变更都会写入
+	st, err := store.Open(m.config.Store)
+	if err != nil {
+		return fmt.Errorf("打开存储失败: %v", err)
+	}
+	m.store = st
+	defer st.Close()
+	if cfg := m.config.AuditLog; cfg.Enabled {
+		auditLog, err := openAuditLog(cfg.File)
+		if err != nil {
+			return err
+		}
+		m.auditLog = auditLog
+		defer auditLog.Close()
+	}
+	m.auditConfigLoaded()
+	if err := m.startAgent(); err != nil {
 		return err
 	}
+	m.loadStats()
+	// 统计、实时订阅与转发只操作内存和有界队列，每个事件都处理；写入存储和日志较慢，队列已满时丢弃
+	m.events.OnPublishSync(func(e event.Event) {
+		m.stats.add(e, time.Now())
+		m.publishLiveEvent(e)
+		m.forwardEvent(e)
+	})
+	m.events.OnDrop(func(dropped uint64) {
+		m.logger.WithField("dropped", dropped).Warn("事件写入队列已满，部分事件未写入存储和日志")
+	})
+	m.events.OnPublish(func(e event.Event) {
+		m.persistEvent(e)
+		if m.config.Logging.Format == "json" {
+			m.logEvent(e)
+		}
+	})
 
-	// 启动清理协程
-	go m.cleanupBannedIPs()
-
-	// 监控SSH日志
-	return m.monitorSSHLogs()
-}
-
-// loadBlacklist 从文件加载黑名单
-// 返回:
-//   - error: 加载过程中的错误信息
-func (m *Monitor) loadBlacklist() error {
-	file, err := os.OpenFile(m.config.Blacklist.File, os.O_RDONLY|os.O_CREATE, 0644)
-	if err != nil {
+	// 加载jail状态、登录位置、国家统计、白名单与黑名单
+	if err := m.loadJailState(); err != nil {
 		return err
 	}
-	defer file.Close()
+	if err := m.loadLocations(); err != nil {
+		return err
+	}
+	if err := m.loadCountryStats(); err != nil {
+		return err
+	}
+	if err := m.loadWhitelist(); err != nil {
+		return err
+	}
+	if err := m.loadAllows(); err != nil {
+		return err
+	}
+	if err := m.loadAccountLocks(); err != nil {
+		return err
+	}
+	if err := m.loadBlacklist(); err != nil {
+		return err
+	}
+	// 恢复封禁状态的各步骤都要求持有m.mu锁
+	m.mu.Lock()
+	m.recoverBans()
+	m.restoreTarpit()
+	m.restoreRateLimits()
+	m.releaseWhitelisted()
+	m.scheduleLoadedUnbans()
+	if _, err := m.syncStoreBans(); err != nil {
+		m.logger.WithError(err).Warn("同步封禁记录到存储失败")
+	}
+	m.mu.Unlock()
+	m.loadIPInfoCache()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		ip := scanner.Text()
-		if ip != "" {
-			m.bannedIPs[ip] = time.Now().Add(time.Duration(m.config.SSHProtection.BanDurationHours) * time.Hour)
+	if cfg := m.config.Archive; cfg.Enabled {
+		archive, err := logging.NewArchive(cfg.Dir, cfg.MaxSizeMB, cfg.MaxFiles)
+		if err != nil {
+			return err
 		}
+		m.archive = archive
+		defer archive.Close()
 	}
 
-	return scanner.Err()
-}
-
-// saveBlacklist 保存黑名单到文件
-// 返回:
-//   - error: 保存过程中的错误信息
-func (m *Monitor) saveBlacklist() error {
-	file, err := os.OpenFile(m.config.Blacklist.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	realIP, err := newRealIPResolver(m.config.SSHProtection.RealIP)
 	if err != nil {
-		return err
+		return fmt.Errorf("真实IP提取配置无效: %v", err)
 	}
-	defer file.Close()
+	m.realIP = realIP
 
-	m.mu.RLock()
-	for ip := range m.bannedIPs {
-		if _, err := file.WriteString(ip + "\n"); err != nil {
-			return err
-		}
+	// 启动通用规则、haproxy jail与清理协程
+	m.startRules()
+	m.startHAProxyJail()
+	go m.pruneExpiredState()
+	go m.cleanupBannedIPs()
+	go m.compactAttempts()
+	go m.expireAllows()
+	go m.expireAccountLocks()
+	go m.saveIPInfoCache()
+	go m.prefetchBannedIPInfo()
+	go m.pruneStore()
+	m.startFeeds()
+	m.startFleet()
+	m.startAggregator()
+	if m.config.Notifications.UnbanDigest.Enabled {
+		go m.runUnbanDigest()
+	}
+	if m.config.Notifications.Summary.Enabled {
+		go m.runSummary()
+	}
+	if m.config.Notifications.Heartbeat.Enabled {
+		go m.runHeartbeat()
 	}
-	m.mu.RUnlock()
 
-	return nil
+	m.announceStartup()
+
+	// 监控SSH日志
+	return m.monitorSSHLogs()
 }
 
-// cleanupBannedIPs 定期清理过期的封禁IP
-// 每小时检查一次，解除已过期的IP封禁
+// pruneExpiredState 每小时清理各检测窗口外的记录与到期的限速
+func (m *Monitor) pruneExpiredState() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.mu.Lock()
+		m.pruneUserFailures()
+		m.pruneAggregates()
+		m.pruneRuleFailures()
+		m.pruneProxyConns()
+		m.updateCountryStats()
+		m.pruneSessions()
+		m.prunePreauthConns()
+		m.pruneRiskFailures()
+		m.pruneFleetVersions()
+		m.pruneAgentHub()
+		m.pruneSharedIPs()
+		m.pruneBannedSubnets()
+		if m.pruneRateLimits() {
+			m.saveBlacklist()
+		}
+		m.mu.Unlock()
+	}
+}
+
+// cleanupBannedIPs 定期解除已过期的IP封禁，永久封禁的IP不受影响
+// 封禁到期时由scheduleUnban设置的定时器解除，这里每blacklist.cleanup_interval检查一次，
+// 兜底解除定时器未能按时解除的封禁
 func (m *Monitor) cleanupBannedIPs() {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(time.Duration(m.config.Blacklist.CleanupInterval))
+	defer ticker.Stop()
 	for range ticker.C {
 		m.mu.Lock()
+		removed := false
 		for ip, banTime := range m.bannedIPs {
 			if time.Now().After(banTime) {
-				if err := m.firewall.UnbanIP(ip); err != nil {
-					m.logger.WithError(err).WithField("ip", ip).Error("解除IP封禁失败")
-				} else {
-					m.logger.WithField("ip", ip).Info("IP已解除封禁")
-				}
-				delete(m.bannedIPs, ip)
-				delete(m.failedAttempts, ip)
+				m.expireBan(ip)
+				removed = true
 			}
 		}
+		if removed {
+			m.saveBlacklist()
+		}
 		m.mu.Unlock()
 	}
 }
 
+// expireBan 解除到期的封禁并发送通知，防火墙操作失败时同样移除封禁记录
+// 调用方需持有m.mu锁，并在之后保存黑名单
+// 参数:
+//   - ip: 到期的IP或网段
+func (m *Monitor) expireBan(ip string) {
+	if err := m.unblockIP(ip); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Error("解除IP封禁失败")
+	} else {
+		m.logger.WithField("ip", ip).Info("IP已解除封禁")
+		m.audit(auditUnban, ip, SourceExpiry, "封禁到期")
+		m.events.Publish(event.Event{Type: event.TypeUnbanned, IP: ip, Message: "封禁到期，已自动解除"})
+		if m.config.Notifications.UnbanDigest.Enabled {
+			m.autoUnbanned = append(m.autoUnbanned, ip)
+		}
+		unbannedAt := time.Now()
+		m.afterLookup(ip, nil, func(enriched *enrich.Result) {
+			m.notifier.Notify(notification.IPUnbannedEvent(ip, enriched.Format(), m.serverName(), "封禁到期", unbannedAt).WithCountry(countryOf(enriched.Geo)))
+		})
+	}
+	delete(m.bannedIPs, ip)
+	m.failedAttempts.remove(ip)
+}
+
+// scheduleUnban 设置在解封时间解除封禁的定时器，替换该IP之前的定时器
+// 定时器触发时封禁已被解除或解封时间已变更则不做任何事；调用方需持有m.mu锁
+// 参数:
+//   - ip: 被封禁的IP或网段
+//   - expires: 解封时间
+func (m *Monitor) scheduleUnban(ip string, expires time.Time) {
+	if timer := m.unbanTimers[ip]; timer != nil {
+		timer.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(expires), func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.unbanTimers[ip] == timer {
+			delete(m.unbanTimers, ip)
+		}
+		if current, banned := m.bannedIPs[ip]; !banned || !current.Equal(expires) {
+			return
+		}
+		m.expireBan(ip)
+		m.saveBlacklist()
+	})
+	m.unbanTimers[ip] = timer
+}
+
+// cancelUnban 停止并删除IP的解封定时器，封禁在到期前被解除时调用
+// 调用方需持有m.mu锁
+func (m *Monitor) cancelUnban(ip string) {
+	if timer := m.unbanTimers[ip]; timer != nil {
+		timer.Stop()
+		delete(m.unbanTimers, ip)
+	}
+}
+
+// scheduleLoadedUnbans 为启动时加载和恢复的封禁设置解封定时器
+// 调用方需持有m.mu锁
+func (m *Monitor) scheduleLoadedUnbans() {
+	for ip, expires := range m.bannedIPs {
+		m.scheduleUnban(ip, expires)
+	}
+}
+
 // monitorSSHLogs 监控SSH日志文件
-// 实时读取并分析SSH日志，处理登录成功和失败事件
+// 读取日志时只解析事件并解析真实客户端IP，事件按顺序交给dispatchLogins处理；
+// 日志读取中断后由superviseJail自动重启，该函数不会返回
 // 返回:
 //   - error: 监控过程中的错误信息
 func (m *Monitor) monitorSSHLogs() error {
-	file, err := os.Open(m.config.SSHProtection.SSHLogFile)
+	go m.prefetchLogins()
+	go m.dispatchLogins()
+	m.markReady()
+	// 单协程处理以保持日志顺序
+	r := m.newJailRunner(jailSSHD, m.config.SSHProtection.SSHLogFile, 1, func(l logLine) {
+		line := l.text
+		// 停用期间继续读取日志，重新启用后不会重放
+		if !m.jailEnabled(jailSSHD) {
+			return
+		}
+
+		// 跟踪从读取到该行开始，包括在队列中等待的时间；不需要处理的日志行不导出跟踪
+		span := m.tracer.Start("sshd.line", l.observed)
+		parse := span.Child("parse")
+		e, ok := ParseSSHEvent(line, time.Now())
+		// 新建连接和认证前断开事件只在需要处理时才交给登录事件处理协程，按国家封禁需要查询其属地
+		blockCountries := len(m.config.SSHProtection.CountryPolicy.Block) > 0
+		if !ok || (e.Outcome == OutcomePreauth && !m.config.SSHProtection.Preauth.Enabled && !blockCountries) {
+			return
+		}
+		parse.Set("outcome", string(e.Outcome))
+		parse.End()
+		span.Set("jail", jailSSHD)
+		span.Set("outcome", string(e.Outcome))
+		e.Observed, e.Span = l.observed, span
+		// 会话结束按sshd进程ID对应到登录时的会话，客户端版本按进程ID对应到新建连接，不需要解析IP
+		if e.Outcome != OutcomeLogout && e.Outcome != OutcomeClient {
+			if e.Outcome != OutcomeConnect {
+				m.archiveLine(jailSSHD, line)
+			}
+			ip, ok := m.resolveClientIP(line, e.IP, e.Port, e.Timestamp)
+			if !ok {
+				if e.Outcome != OutcomePreauth && e.Outcome != OutcomeConnect {
+					m.logger.WithFields(logrus.Fields{"proxy": e.IP, "port": e.Port}).Warn("来自代理的日志中未找到真实客户端IP，已忽略")
+				}
+				span.Set("ignored", "no_client_ip")
+				span.End()
+				return
+			}
+			e.IP = ip
+		}
+		if e, ok = m.trackConnection(e); !ok || (e.Outcome == OutcomeConnect && !blockCountries) {
+			span.End()
+			return
+		}
+		m.logins <- e
+	})
+	m.superviseJail(r)
+	return nil
+}
+
+// archiveLine 归档匹配到的原始日志行，未启用归档时不做任何事
+// 参数:
+//   - jail: 日志所属的jail
+//   - line: 原始日志行
+func (m *Monitor) archiveLine(jail, line string) {
+	if m.archive == nil {
+		return
+	}
+	if err := m.archive.Write(jail, line); err != nil {
+		m.logger.WithError(err).Error("归档原始日志失败")
+	}
+}
+
+// logEvent 将事件的所有字段作为结构化字段写入程序日志，空字段省略
+// 参数:
+//   - e: 已分配序号的事件
+func (m *Monitor) logEvent(e event.Event) {
+	fields := logrus.Fields{"event": e.Type, "seq": e.Seq}
+	for key, value := range map[string]string{"ip": e.IP, "user": e.User, "method": e.Method, "client": e.Client} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	for key, value := range map[string]int{"port": e.Port, "pid": e.PID, "weight": e.Weight} {
+		if value != 0 {
+			fields[key] = value
+		}
+	}
+	if !e.Expires.IsZero() {
+		fields["expires"] = e.Expires
+	}
+	m.logger.WithFields(fields).Info(e.Message)
+}
+
+// tailFile 从文件末尾开始持续读取新写入的行
+// 参数:
+//   - path: 日志文件路径
+//   - handle: 每读到一行调用一次
+// 返回:
+//   - error: 打开或读取文件失败时的错误信息
+func tailFile(path string, handle func(line string)) error {
+	file, err := os.Open(path)
 	if err != nil {
 		return err
 	}
@@ -153,101 +521,384 @@ func (m *Monitor) monitorSSHLogs() error {
 			}
 			return err
 		}
-
-		if strings.Contains(line, "Failed password") {
-			re := regexp.MustCompile(`from (\d+\.\d+\.\d+\.\d+)`)
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				m.handleFailedLogin(matches[1])
-			}
-		} else if strings.Contains(line, "Accepted password") {
-			re := regexp.MustCompile(`from (\d+\.\d+\.\d+\.\d+)`)
-			matches := re.FindStringSubmatch(line)
-			if len(matches) > 1 {
-				m.handleSuccessfulLogin(matches[1])
-			}
-		}
+		handle(strings.TrimRight(line, "\r\n"))
 	}
 }
 
+// rootUser root用户名，适用加强的登录规则
+const rootUser = "root"
+
 // handleFailedLogin 处理登录失败事件
 // 参数:
-//   - ip: 登录失败的IP地址
-func (m *Monitor) handleFailedLogin(ip string) {
+//   - login: 从日志行解析出的登录失败记录
+func (m *Monitor) handleFailedLogin(login LoginEvent) {
+	ip, user := login.IP, login.User
+	if m.checkInstantBan(login) {
+		return
+	}
+	if !login.CountsAsFailure() {
+		m.logger.WithFields(logrus.Fields{"ip": ip, "user": user, "method": login.Method}).Debug("非密码认证失败，不计入失败次数")
+		return
+	}
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	ignored := m.failureIgnored(ip, login.Timestamp)
+	m.mu.Unlock()
+	if ignored {
+		return
+	}
 
-	if m.isIPBanned(ip) {
-		m.logger.WithField("ip", ip).Warn("尝试登录的IP已被封禁")
+	// 属地等补充信息未命中缓存时需要请求外部接口，已由查询协程池提前查询，在加锁前等待结果，避免阻塞控制命令的处理
+	enriched := m.enrichLogin(login)
+	info := enriched.Geo
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// 查询期间该IP可能已被其他jail或管理命令处置，重新检查
+	if m.failureIgnored(ip, login.Timestamp) {
+		return
+	}
+	if code := countryCodeOf(info); m.isBlockedCountry(code) {
+		m.banCountry(ip, code, login)
 		return
 	}
 
-	m.failedAttempts[ip]++
-	
-	m.logger.WithFields(logrus.Fields{
+	m.failedAttempts.add(ip, login.Timestamp)
+	m.totalFailures[ip]++
+	m.recentFailures.add(login.Timestamp)
+	m.recordUserFailure(user, ip, login.Timestamp)
+	m.recordSubnetFailure(ip, info, login)
+	m.recordCountryFailure(countryOf(info))
+	maxAttempts := m.maxAttemptsFor(user, info)
+	attempts := m.failedAttempts.get(ip)
+	shared := m.isSharedIP(ip, login.Timestamp)
+	if shared {
+		attempts = m.sharedAttempts(ip, user)
+	}
+	// 启用风险评分时以风险分代替失败次数阈值决定是否处置，共享IP仍按用户名的失败次数处置，root_login.ban_immediately仍然生效
+	punish := attempts >= maxAttempts
+	var risk riskScore
+	message := fmt.Sprintf("登录失败 %s %d/%d", user, attempts, maxAttempts)
+	if m.riskEnabled() {
+		risk = m.scoreRisk(login, enriched)
+		if !shared {
+			punish = risk.total >= m.config.SSHProtection.RiskScore.BanScore ||
+				(user == rootUser && m.config.SSHProtection.RootLogin.BanImmediately)
+		}
+		message += "，风险分 " + risk.String()
+	}
+
+	fields := logrus.Fields{
 		"ip":           ip,
-		"attempts":     m.failedAttempts[ip],
-		"max_attempts": m.config.SSHProtection.MaxFailedAttempts,
-	}).Warn("SSH登录失败")
+		"user":         user,
+		"method":       login.Method,
+		"client":       login.Client,
+		"port":         login.Port,
+		"pid":          login.PID,
+		"attempts":     attempts,
+		"max_attempts": maxAttempts,
+		"shared":       shared,
+	}
+	if m.riskEnabled() {
+		fields["risk_score"] = risk.total
+		fields["risk"] = risk.String()
+	}
+	m.logger.WithFields(fields).Warn("SSH登录失败")
 
-	if m.failedAttempts[ip] >= m.config.SSHProtection.MaxFailedAttempts {
-		m.banIP(ip)
+	// 触发处置的失败和root用户的失败总是保存和通知，其余的按配置抽样，计数不受影响
+	weight, keep := 0, true
+	if !punish && user != rootUser {
+		weight, keep = m.sampleFailure()
+	}
+	if keep {
+		m.events.Publish(event.Event{
+			Time:    login.Timestamp,
+			Type:    event.TypeLoginFailed,
+			IP:      ip,
+			Message: message,
+			Port:    login.Port,
+			PID:     login.PID,
+			User:    user,
+			Method:  login.Method,
+			Client:  login.Client,
+			Weight:  weight,
+		})
+	}
+
+	switch {
+	case !punish:
+	case shared:
+		if err := m.punishSharedIP(ip, user, login.Timestamp, login.Observed, login.Span); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("共享IP限速失败")
+		}
+	case m.riskEnabled() && m.isPermanentRisk(risk):
+		if err := m.banPermanent(ip, login.Timestamp, fmt.Sprintf("SSH暴力破解（风险分 %d）", risk.total), enriched); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("永久封禁IP失败")
+		}
+	case m.riskEnabled():
+		if err := m.punishIP(ip, login.Timestamp, login.Observed, login.Span, m.banDurationFor(user), fmt.Sprintf("SSH暴力破解（风险分 %d）", risk.total)); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+		}
+	case m.isForeignCountry(countryCodeOf(info)):
+		duration := m.banDurationFor(user)
+		if m.config.SSHProtection.CountryPolicy.BanDuration > 0 {
+			duration = m.countryBanDuration()
+		}
+		if err := m.punishIP(ip, login.Timestamp, login.Observed, login.Span, duration, "SSH暴力破解（来自非常用国家 "+countryCodeOf(info)+"）"); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+		}
+	default:
+		if err := m.punishIP(ip, login.Timestamp, login.Observed, login.Span, m.banDurationFor(user), "SSH暴力破解"); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+		}
+	}
+
+	if user == rootUser {
+		m.notifier.Notify(notification.RootLoginEvent(ip, enriched.Format(), m.serverName(), false, attempts, maxAttempts, login.Timestamp).
+			WithClient(login.Client).WithCountry(countryOf(info)).WithSpan(login.Span))
+		return
+	}
+	if !keep || (m.riskEnabled() && risk.total < m.config.SSHProtection.RiskScore.NotifyScore) {
+		return
+	}
+	ipInfo := enriched.Format()
+	if m.riskEnabled() {
+		ipInfo += "\n风险分: " + risk.String()
+	}
+	m.notifier.Notify(notification.LoginFailedEvent(ip, ipInfo, m.serverName(), attempts, maxAttempts, login.Timestamp).
+		WithClient(login.Client).WithUser(user).WithCountry(countryOf(info)).WithSpan(login.Span))
+}
+
+// failureIgnored 检查是否忽略该IP的登录失败：白名单、临时信任和已封禁的IP不再计数
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 登录失败的来源IP
+//   - at: 登录失败的时间
+// 返回:
+//   - bool: 需要忽略时返回true
+func (m *Monitor) failureIgnored(ip string, at time.Time) bool {
+	switch {
+	case m.whitelist.contains(ip):
+		m.logger.WithField("ip", ip).Debug("白名单IP登录失败，已忽略")
+	case m.isTrusted(ip, at):
+		m.logger.WithField("ip", ip).Debug("临时信任IP登录失败，已忽略")
+	case m.isIPBanned(ip):
+		m.logger.WithField("ip", ip).Warn("尝试登录的IP已被封禁")
+	default:
+		return false
 	}
+	return true
+}
 
-	ipInfo := m.ipInfo.FormatIPInfo(ip)
-	server := fmt.Sprintf("%s (%s)", m.config.Service.ServiceName, m.config.Service.InstallPath)
-	m.telegram.NotifyLoginFailed(ip, ipInfo, server, m.failedAttempts[ip], m.config.SSHProtection.MaxFailedAttempts)
+// maxAttemptsFor 返回针对该用户名和来源国家的失败次数阈值
+// 配置了用户策略的用户名以策略阈值代替全局阈值；
+// root用户、正在遭受密码喷洒的用户名、自适应统计中攻击占比高的国家和常用国家以外的来源使用更严格的阈值，取其中最小者
+// 调用方需持有m.mu锁
+// 参数:
+//   - user: 登录使用的用户名
+//   - info: 来源IP的属地信息，查询失败时为nil
+// 返回:
+//   - int: 封禁前允许的失败次数
+func (m *Monitor) maxAttemptsFor(user string, info *ipinfo.IPInfo) int {
+	max := m.config.SSHProtection.MaxFailedAttempts
+	if policy := m.config.SSHProtection.Users[user]; policy.MaxFailedAttempts > 0 {
+		max = policy.MaxFailedAttempts
+	}
+	if adaptive := m.config.SSHProtection.AdaptiveCountry; adaptive.MaxFailedAttempts < max && m.isStrictCountry(countryOf(info)) {
+		max = adaptive.MaxFailedAttempts
+	}
+	if foreign := m.config.SSHProtection.CountryPolicy.ForeignMaxFailedAttempts; foreign < max && m.isForeignCountry(countryCodeOf(info)) {
+		max = foreign
+	}
+	if user == rootUser {
+		root := m.config.SSHProtection.RootLogin
+		if root.BanImmediately {
+			return 1
+		}
+		if root.MaxFailedAttempts > 0 && root.MaxFailedAttempts < max {
+			max = root.MaxFailedAttempts
+		}
+	}
+	strict := m.config.SSHProtection.PasswordSpray.StrictMaxFailedAttempts
+	if strict > 0 && strict < max && m.sprayUsers[user] {
+		max = strict
+	}
+	return max
 }
 
 // handleSuccessfulLogin 处理登录成功事件
 // 参数:
-//   - ip: 登录成功的IP地址
-func (m *Monitor) handleSuccessfulLogin(ip string) {
-	m.logger.WithField("ip", ip).Info("SSH登录成功")
+//   - login: 从日志行解析出的登录成功记录
+func (m *Monitor) handleSuccessfulLogin(login LoginEvent) {
+	ip, user := login.IP, login.User
+	m.logger.WithFields(logrus.Fields{"ip": ip, "user": user, "method": login.Method, "client": login.Client, "port": login.Port, "pid": login.PID}).Info("SSH登录成功")
 
-	ipInfo := m.ipInfo.FormatIPInfo(ip)
-	server := fmt.Sprintf("%s (%s)", m.config.Service.ServiceName, m.config.Service.InstallPath)
-	m.telegram.NotifyLoginSuccess(ip, ipInfo, server)
+	m.mu.Lock()
+	m.trust(ip, login.Timestamp)
+	m.recordSharedSuccess(ip, user, login.Timestamp)
+	m.mu.Unlock()
+	m.openSession(login)
+
+	m.events.Publish(event.Event{Time: login.Timestamp, Type: event.TypeLoginSuccess, IP: ip, Message: fmt.Sprintf("登录成功 %s (%s)", user, login.Method), Port: login.Port, PID: login.PID, User: user, Method: login.Method, Client: login.Client})
+
+	enriched := m.enrichLogin(login)
+	info, ipInfo := enriched.Geo, enriched.Format()
+	if info != nil && m.config.SSHProtection.NewLocation.Enabled {
+		m.mu.Lock()
+		kind := m.recordLocation(user, info)
+		m.mu.Unlock()
+		if kind != "" {
+			m.logger.WithFields(logrus.Fields{"ip": ip, "user": user, "country": info.Country, "asn": info.ASN}).Warn("用户从新的位置登录")
+			m.events.Publish(event.Event{Time: login.Timestamp, Type: event.TypeNewLocation, IP: ip, Message: fmt.Sprintf("%s 从新的%s登录: %s %s", user, kind, info.Country, info.ASN)})
+			m.notifier.Notify(notification.NewLocationEvent(ip, ipInfo, m.serverName(), user, kind, info.Country, info.ASN, login.Timestamp).WithSpan(login.Span))
+		}
+	}
+
+	if user == rootUser {
+		m.mu.RLock()
+		maxAttempts := m.maxAttemptsFor(user, info)
+		m.mu.RUnlock()
+		m.notifier.Notify(notification.RootLoginEvent(ip, ipInfo, m.serverName(), true, 0, maxAttempts, login.Timestamp).
+			WithClient(login.Client).WithCountry(countryOf(info)).WithSpan(login.Span))
+		return
+	}
+	m.notifier.Notify(notification.LoginSuccessEvent(ip, ipInfo, m.serverName(), login.Timestamp).
+		WithClient(login.Client).WithUser(user).WithCountry(countryOf(info)).WithSpan(login.Span))
 }
 
 // banIP 封禁指定的IP地址
+// 解封时间从触发封禁的日志时间起算，重放历史日志时已过期的封禁会被跳过
+// 负载均衡或代理地址永远不会被封禁
+// 依次记录封禁意图、添加防火墙规则、标记已生效、保存黑名单，最后发送通知；
+// 防火墙操作失败时撤销封禁意图，内存中的状态不会与防火墙不一致
+// 调用方需持有m.mu锁
 // 参数:
-//   - ip: 要封禁的IP地址
-func (m *Monitor) banIP(ip string) {
-	banTime := time.Now().Add(time.Duration(m.config.SSHProtection.BanDurationHours) * time.Hour)
-	m.bannedIPs[ip] = banTime
+//   - ip: 要封禁的IP地址，封禁网段时为CIDR
+//   - at: 触发封禁的时间
+//   - observed: 读取到触发日志行的时间，用于统计封禁生效耗时，手动封禁时为零值
+//   - span: 触发日志行的跟踪，手动封禁或未启用跟踪时为nil
+//   - duration: 封禁时长
+//   - reason: 封禁原因，用于日志和通知
+//   - source: 审计记录中的触发来源，见Source*常量
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) banIP(ip string, at, observed time.Time, span *tracing.Span, duration time.Duration, reason, source string) error {
+	if m.isProxy(ip) {
+		return fmt.Errorf("IP %s 是负载均衡或代理地址，不能封禁", ip)
+	}
 
-	if err := m.firewall.BanIP(ip); err != nil {
-		m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
-		return
+	banTime := at.Add(duration)
+	if banTime.Before(time.Now()) {
+		m.logger.WithFields(logrus.Fields{
+			"ip":          ip,
+			"expire_time": banTime.Format("2006-01-02 15:04:05"),
+		}).Info("历史日志中的封禁已过期，跳过")
+		m.failedAttempts.remove(ip)
+		return nil
 	}
 
+	// 先记录封禁意图，进程在添加防火墙规则前后退出时由recoverBans补全或回滚
+	intent := store.Ban{IP: ip, Type: banTypeTemporary, ExpiresAt: banTime, State: store.StatePending, Reason: reason}
+	if err := m.store.PutBan(intent); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Warn("记录封禁意图失败")
+	}
+	applyStart := time.Now()
+	block := span.Client("firewall.block")
+	block.Set("ip", ip)
+	err := m.blockIP(ip)
+	block.Fail(err)
+	block.End()
+	if err != nil {
+		if err := m.store.DeleteBan(ip); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Warn("回滚封禁意图失败")
+		}
+		return err
+	}
+	m.recordBanLatency(ip, observed, applyStart)
+	intent.State = store.StateApplied
+	if err := m.store.PutBan(intent); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Warn("标记封禁已生效失败")
+	}
+	m.bannedIPs[ip] = banTime
+	m.trackSubnet(ip)
+	m.scheduleUnban(ip, banTime)
+	// 完全封禁后不再需要限速规则
+	if _, limited := m.limitedIPs[ip]; limited {
+		m.liftLimit(ip)
+	}
+	m.limitBannedIPs(ip)
+
 	m.saveBlacklist()
 
 	m.logger.WithFields(logrus.Fields{
 		"ip":           ip,
-		"duration":     m.config.SSHProtection.BanDurationHours,
+		"duration":     duration.Hours(),
+		"reason":       reason,
 		"expire_time": banTime.Format("2006-01-02 15:04:05"),
 	}).Info("IP已被封禁")
+	m.audit(auditBan, ip, source, fmt.Sprintf("%s，解封时间 %s", reason, banTime.Format("2006-01-02 15:04:05")))
+	m.events.Publish(event.Event{
+		Time:    at,
+		Type:    event.TypeBanned,
+		IP:      ip,
+		Message: fmt.Sprintf("%s，已封禁%s", reason, config.Duration(duration)),
+		Expires: banTime,
+	})
 
-	ipInfo := m.ipInfo.FormatIPInfo(ip)
-	server := fmt.Sprintf("%s (%s)", m.config.Service.ServiceName, m.config.Service.InstallPath)
-	m.telegram.NotifyIPBanned(ip, ipInfo, server, time.Duration(m.config.SSHProtection.BanDurationHours)*time.Hour, banTime)
+	// 来源信息在查询协程池中补充，不在持有锁时查询；查询期间封禁已被解除或变更时不再写入
+	notify := func(enriched *enrich.Result) {
+		m.mu.RLock()
+		if m.bannedIPs[ip] == banTime {
+			m.recordBanSource(intent, enriched)
+		}
+		m.mu.RUnlock()
+		m.notifier.Notify(notification.IPBannedEvent(ip, enriched.Format(), m.serverName(), reason, duration, banTime, at).
+			WithCountry(countryOf(enriched.Geo)).WithSpan(span))
+	}
+	if strings.Contains(ip, "/") {
+		// 网段封禁没有可查询的来源信息
+		go notify(&enrich.Result{IP: ip})
+	} else {
+		m.afterLookup(ip, span, notify)
+	}
+	// 手动封禁没有触发日志，不举报
+	if !observed.IsZero() {
+		m.reportAbuse(ip, reason)
+	}
+	m.pushCrowdSec(ip, reason, at, banTime)
+	m.publishFleet(fleet.ActionBan, ip, banTime, reason)
+	return nil
+}
+
+// banDuration 返回配置的默认封禁时长
+func (m *Monitor) banDuration() time.Duration {
+	return time.Duration(m.config.SSHProtection.BanDuration)
+}
+
+// banDurationFor 返回因该用户名登录失败而封禁的时长，配置了用户策略时使用策略中的时长
+func (m *Monitor) banDurationFor(user string) time.Duration {
+	if policy := m.config.SSHProtection.Users[user]; policy.BanDuration > 0 {
+		return time.Duration(policy.BanDuration)
+	}
+	return m.banDuration()
+}
+
+// serverName 返回通知中展示的服务器信息
+func (m *Monitor) serverName() string {
+	return fmt.Sprintf("%s (%s)", m.config.Service.ServiceName, m.config.Service.InstallPath)
 }
 
-// isIPBanned 检查IP是否被封禁
+// isIPBanned 检查IP是否被封禁，只读取封禁记录
+// 到期的封禁由解封定时器通过expireBan解除防火墙规则并移除记录
 // 参数:
 //   - ip: 要检查的IP地址
 // 返回:
 //   - bool: true表示被封禁，false表示未被封禁
 func (m *Monitor) isIPBanned(ip string) bool {
-	if banTime, exists := m.bannedIPs[ip]; exists {
-		if time.Now().Before(banTime) {
-			return true
-		}
-		delete(m.bannedIPs, ip)
-		delete(m.failedAttempts, ip)
-		return false
+	if m.permanentIPs[ip] {
+		return true
+	}
+	if banTime, exists := m.bannedIPs[ip]; exists && time.Now().Before(banTime) {
+		return true
 	}
-	return false
+	return m.inBannedSubnet(ip)
 } 
\ No newline at end of file
@@ -0,0 +1,268 @@
+package monitor
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// Outcome 登录事件的结果
+type Outcome string
+
+// 登录事件结果定义
+const (
+	OutcomeSuccess Outcome = "success" // 认证成功
+	OutcomeFailure Outcome = "failure" // 认证失败
+	OutcomeLogout  Outcome = "logout"  // 会话结束
+	OutcomePreauth Outcome = "preauth" // 未进入认证阶段就断开的连接
+	OutcomeConnect Outcome = "connect" // 新建连接，sshd的LogLevel为VERBOSE及以上时记录
+	OutcomeClient  Outcome = "client"  // 客户端版本标识，sshd的LogLevel为DEBUG时记录
+)
+
+// 认证方式，取自sshd日志 "Accepted/Failed <方式> for" 中的方式名，去掉 "/pam" 等后缀
+const (
+	MethodPassword            = "password"
+	MethodPublicKey           = "publickey"
+	MethodKeyboardInteractive = "keyboard-interactive"
+)
+
+// LoginEvent 从sshd日志行中解析出的一次登录相关事件
+// 登录成功、失败、会话结束、认证前断开和连接信息都统一为该结构，按Outcome区分
+type LoginEvent struct {
+	IP          string        // 客户端IP地址，pam_unix的会话结束日志和客户端版本日志中没有时为空
+	User        string        // 用户名，无法识别时为空
+	InvalidUser bool          // 用户名在系统中不存在（日志中为invalid user）
+	Client      string        // 客户端版本标识，如 OpenSSH_9.6、libssh_0.9.6，只有OutcomeClient事件有
+	Method      string        // 认证方式，如password、publickey，非认证事件为空
+	Port        int           // 客户端源端口，无法识别时为0
+	PID         int           // sshd进程ID，无法识别时为0
+	Timestamp   time.Time     // 日志行记录的时间，无法识别时为读取时间
+	Observed    time.Time     // 守护进程读取到该行的时间，离线解析时为零值
+	Span        *tracing.Span // 该行日志的跟踪，未启用跟踪、未被抽样或离线解析时为nil
+	Outcome     Outcome       // 事件结果
+
+	lookup *lookup // 已提前提交的IP补充信息查询，见prefetchLogins
+}
+
+// CountsAsFailure 检查事件是否计入登录失败次数
+// 只有密码类认证的失败才计入；公钥认证失败通常只是客户端依次尝试多把密钥
+// 返回:
+//   - bool: 计入失败次数时为true
+func (e LoginEvent) CountsAsFailure() bool {
+	return e.Outcome == OutcomeFailure && (e.Method == MethodPassword || e.Method == MethodKeyboardInteractive)
+}
+
+// linePattern 带子串预过滤的日志行正则
+// 能匹配的日志行必然包含literals之一，不包含任何一个时直接跳过正则；
+// 攻击期间每秒可达数百行日志，其中大多数行只需几次strings.Contains即可排除
+type linePattern struct {
+	literals []string       // 匹配的日志行必然包含其中之一的子串
+	re       *regexp.Regexp // 编译后的正则
+}
+
+// newLinePattern 编译日志行正则，只应在包初始化时调用，表达式无效时panic
+// 参数:
+//   - expr: 正则表达式
+//   - literals: 表达式匹配的内容中必然出现的子串，至少一个
+// 返回:
+//   - linePattern: 编译后的正则
+func newLinePattern(expr string, literals ...string) linePattern {
+	return linePattern{literals: literals, re: regexp.MustCompile(expr)}
+}
+
+// find 日志行包含预过滤子串时执行正则匹配
+// 参数:
+//   - line: 日志行
+// 返回:
+//   - []string: 完整匹配与各捕获组，不匹配时为nil
+func (p linePattern) find(line string) []string {
+	for _, literal := range p.literals {
+		if strings.Contains(line, literal) {
+			return p.re.FindStringSubmatch(line)
+		}
+	}
+	return nil
+}
+
+// sshd日志中的进程ID，如 "sshd[1234]: Failed password for invalid user admin from 1.2.3.4 port 52214 ssh2"
+var (
+	pidPattern = regexp.MustCompile(`sshd\[(\d+)\]`)
+
+	// 认证结果、方式、用户名与来源，如 "Accepted publickey for bob from 1.2.3.4 port 52214 ssh2: RSA SHA256:..."
+	// 或 "Failed keyboard-interactive/pam for invalid user root from 2001:db8::1 port 52214 ssh2"；
+	// 用户名由客户端提供，可能包含 " from 1.2.3.4 port 22" 之类的内容，来源地址只取行尾的那一组
+	// 以下识别事件类型的正则都带有预过滤子串，上面的进程ID正则只在识别出事件后使用
+	authPattern = newLinePattern(`(Accepted|Failed) ([a-z-]+)(?:/\S+)? for (invalid user )?(.*) from ([0-9A-Fa-f:.]+) port (\d+)(?: ssh2)?(?:: .*)?$`, "Accepted ", "Failed ")
+
+	// 会话结束，如 "pam_unix(sshd:session): session closed for user bob"
+	// 或 "Disconnected from user bob 1.2.3.4 port 52214"
+	sessionClosedPattern = newLinePattern(`session closed for user (\S+)`, "session closed for user ")
+	disconnectedPattern  = newLinePattern(`Disconnected from user (\S+) ([0-9A-Fa-f:.]+) port (\d+)`, "Disconnected from user ")
+
+	// 未进入认证阶段就结束的连接，如端口扫描和banner探测
+	preauthPatterns = []linePattern{
+		newLinePattern(`Did not receive identification string from ([0-9A-Fa-f:.]+)(?: port (\d+))?`, "Did not receive identification string "),
+		newLinePattern(`Connection (?:closed|reset) by ([0-9A-Fa-f:.]+) port (\d+) \[preauth\]`, "[preauth]"),
+		newLinePattern(`banner exchange: Connection from ([0-9A-Fa-f:.]+) port (\d+)`, "banner exchange: "),
+	}
+
+	// 连接信息，如 "Connection from 1.2.3.4 port 52214 on 10.0.0.1 port 22"
+	// 以及 "debug1: Remote protocol version 2.0, remote software version libssh_0.9.6"，
+	// 旧版本sshd记录为 "Client protocol version 2.0; client software version ..."
+	connectPattern       = newLinePattern(`Connection from ([0-9A-Fa-f:.]+) port (\d+) on `, "Connection from ")
+	clientVersionPattern = newLinePattern(`(?:remote|client) software version (\S+)`, "software version ")
+)
+
+// sshdParsers ParseSSHEvent按顺序尝试的解析器，以第一个识别成功的结果为准
+// 认证日志最常见，排在最前面
+var sshdParsers = []func(line string, now time.Time) (LoginEvent, bool){
+	ParseSSHLine,
+	ParseSSHLogout,
+	parsePreauth,
+	parseConnection,
+}
+
+// ParseSSHEvent 解析sshd日志行中的登录成功、失败、会话结束、认证前断开或连接信息事件
+// 行尾的换行符会被去掉，识别认证结果的正则匹配到行尾
+// 参数:
+//   - line: 日志行，可以带有行尾的换行符
+//   - now: 当前时间，用于推断syslog时间戳的年份
+// 返回:
+//   - LoginEvent: 解析出的事件
+//   - bool: 是否为可识别的事件
+func ParseSSHEvent(line string, now time.Time) (LoginEvent, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	for _, parse := range sshdParsers {
+		if e, ok := parse(line, now); ok {
+			return e, true
+		}
+	}
+	return LoginEvent{}, false
+}
+
+// ParseSSHLine 解析sshd的登录成功与失败日志行，支持所有认证方式
+// 守护进程与离线分析共用，保证两者的识别结果一致
+// 参数:
+//   - line: 日志行，可以带有行尾的换行符
+//   - now: 当前时间，用于推断syslog时间戳的年份
+// 返回:
+//   - LoginEvent: 解析出的登录事件，Outcome为OutcomeSuccess或OutcomeFailure
+//   - bool: 是否为可识别的登录成功或失败日志
+func ParseSSHLine(line string, now time.Time) (LoginEvent, bool) {
+	var login LoginEvent
+	line = strings.TrimRight(line, "\r\n")
+	auth := authPattern.find(line)
+	if auth == nil {
+		return login, false
+	}
+	login.Outcome = OutcomeFailure
+	if auth[1] == "Accepted" {
+		login.Outcome = OutcomeSuccess
+	}
+	login.Method = auth[2]
+	if net.ParseIP(auth[5]) == nil {
+		return login, false
+	}
+	login.IP = auth[5]
+	login.User = auth[4]
+	login.InvalidUser = auth[3] != ""
+	login.Port, _ = strconv.Atoi(auth[6])
+	login.Timestamp = parseLogTime(line, now)
+	login.PID = parsePID(line)
+	return login, true
+}
+
+// ParseSSHLogout 解析sshd的会话结束日志行
+// 同一会话通常会同时记录pam_unix的session closed和Disconnected两行，由调用方按进程ID去重
+// 参数:
+//   - line: 日志行
+//   - now: 当前时间，用于推断syslog时间戳的年份
+// 返回:
+//   - LoginEvent: 解析出的会话结束事件，Outcome为OutcomeLogout
+//   - bool: 是否为可识别的会话结束日志
+func ParseSSHLogout(line string, now time.Time) (LoginEvent, bool) {
+	logout := LoginEvent{Outcome: OutcomeLogout}
+	if matches := disconnectedPattern.find(line); matches != nil {
+		if net.ParseIP(matches[2]) == nil {
+			return logout, false
+		}
+		logout.User, logout.IP = matches[1], matches[2]
+		logout.Port, _ = strconv.Atoi(matches[3])
+	} else if matches := sessionClosedPattern.find(line); matches != nil && strings.Contains(line, "sshd") {
+		logout.User = matches[1]
+	} else {
+		return logout, false
+	}
+
+	logout.Timestamp = parseLogTime(line, now)
+	logout.PID = parsePID(line)
+	return logout, true
+}
+
+// parsePreauth 解析认证前断开的连接日志行，IP地址无效时不识别
+// 参数:
+//   - line: 日志行
+//   - now: 当前时间，用于推断syslog时间戳的年份
+// 返回:
+//   - LoginEvent: 解析出的连接事件，Outcome为OutcomePreauth
+//   - bool: 是否为可识别的认证前断开日志
+func parsePreauth(line string, now time.Time) (LoginEvent, bool) {
+	for _, pattern := range preauthPatterns {
+		matches := pattern.find(line)
+		if matches == nil {
+			continue
+		}
+		if net.ParseIP(matches[1]) == nil {
+			return LoginEvent{}, false
+		}
+		conn := LoginEvent{IP: matches[1], PID: parsePID(line), Timestamp: parseLogTime(line, now), Outcome: OutcomePreauth}
+		conn.Port, _ = strconv.Atoi(matches[2])
+		return conn, true
+	}
+	return LoginEvent{}, false
+}
+
+// parseConnection 解析新建连接和客户端版本日志行
+// 客户端版本日志中没有IP，由调用方按进程ID对应到同一进程的新建连接日志；新建连接日志的IP地址无效时不识别
+// 参数:
+//   - line: 日志行
+//   - now: 当前时间，用于推断syslog时间戳的年份
+// 返回:
+//   - LoginEvent: 解析出的事件，Outcome为OutcomeConnect或OutcomeClient
+//   - bool: 是否为可识别的连接信息日志
+func parseConnection(line string, now time.Time) (LoginEvent, bool) {
+	var e LoginEvent
+	if matches := connectPattern.find(line); matches != nil {
+		if net.ParseIP(matches[1]) == nil {
+			return e, false
+		}
+		e = LoginEvent{IP: matches[1], Outcome: OutcomeConnect}
+		e.Port, _ = strconv.Atoi(matches[2])
+	} else if matches := clientVersionPattern.find(line); matches != nil {
+		e = LoginEvent{Client: strings.TrimRight(matches[1], ","), Outcome: OutcomeClient}
+	} else {
+		return e, false
+	}
+
+	e.Timestamp = parseLogTime(line, now)
+	e.PID = parsePID(line)
+	return e, true
+}
+
+// parsePID 提取日志行中的sshd进程ID
+// 参数:
+//   - line: 日志行
+// 返回:
+//   - int: 进程ID，无法识别时为0
+func parsePID(line string) int {
+	matches := pidPattern.FindStringSubmatch(line)
+	if len(matches) < 2 {
+		return 0
+	}
+	pid, _ := strconv.Atoi(matches[1])
+	return pid
+}
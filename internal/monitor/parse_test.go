@@ -0,0 +1,212 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSSHEvent(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name string
+		line string
+		want LoginEvent
+		ok   bool
+	}{
+		{
+			name: "密码认证失败",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for root from 1.2.3.4 port 52214 ssh2",
+			want: LoginEvent{IP: "1.2.3.4", User: "root", Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "不存在的用户",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for invalid user admin from 1.2.3.4 port 52214 ssh2",
+			want: LoginEvent{IP: "1.2.3.4", User: "admin", InvalidUser: true, Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "键盘交互认证失败",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed keyboard-interactive/pam for root from 1.2.3.4 port 52214 ssh2",
+			want: LoginEvent{IP: "1.2.3.4", User: "root", Method: MethodKeyboardInteractive, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "公钥认证成功",
+			line: "Mar 10 11:59:58 host sshd[1234]: Accepted publickey for bob from 1.2.3.4 port 52214 ssh2: RSA SHA256:abcdef",
+			want: LoginEvent{IP: "1.2.3.4", User: "bob", Method: MethodPublicKey, Port: 52214, PID: 1234, Outcome: OutcomeSuccess},
+			ok:   true,
+		},
+		{
+			name: "行尾换行符",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for root from 1.2.3.4 port 52214 ssh2\n",
+			want: LoginEvent{IP: "1.2.3.4", User: "root", Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "CRLF行尾",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for root from 1.2.3.4 port 52214 ssh2\r\n",
+			want: LoginEvent{IP: "1.2.3.4", User: "root", Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "公钥认证成功带换行符",
+			line: "Mar 10 11:59:58 host sshd[1234]: Accepted publickey for bob from 1.2.3.4 port 52214 ssh2: RSA SHA256:abcdef\n",
+			want: LoginEvent{IP: "1.2.3.4", User: "bob", Method: MethodPublicKey, Port: 52214, PID: 1234, Outcome: OutcomeSuccess},
+			ok:   true,
+		},
+		{
+			name: "会话结束带换行符",
+			line: "Mar 10 11:59:58 host sshd[1234]: Disconnected from user bob 1.2.3.4 port 52214\r\n",
+			want: LoginEvent{IP: "1.2.3.4", User: "bob", Port: 52214, PID: 1234, Outcome: OutcomeLogout},
+			ok:   true,
+		},
+		{
+			name: "IPv6来源",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for root from 2001:db8::1 port 52214 ssh2",
+			want: LoginEvent{IP: "2001:db8::1", User: "root", Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "用户名中伪造来源",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for invalid user x from 10.0.0.1 port 22 from 1.2.3.4 port 52214 ssh2",
+			want: LoginEvent{IP: "1.2.3.4", User: "x from 10.0.0.1 port 22", InvalidUser: true, Method: MethodPassword, Port: 52214, PID: 1234, Outcome: OutcomeFailure},
+			ok:   true,
+		},
+		{
+			name: "无效的IP地址",
+			line: "Mar 10 11:59:58 host sshd[1234]: Failed password for root from 999.1.1.1 port 52214 ssh2",
+		},
+		{
+			name: "会话结束",
+			line: "Mar 10 11:59:58 host sshd[1234]: Disconnected from user bob 1.2.3.4 port 52214",
+			want: LoginEvent{IP: "1.2.3.4", User: "bob", Port: 52214, PID: 1234, Outcome: OutcomeLogout},
+			ok:   true,
+		},
+		{
+			name: "pam会话结束",
+			line: "Mar 10 11:59:58 host sshd[1234]: pam_unix(sshd:session): session closed for user bob",
+			want: LoginEvent{User: "bob", PID: 1234, Outcome: OutcomeLogout},
+			ok:   true,
+		},
+		{
+			name: "未发送标识",
+			line: "Mar 10 11:59:58 host sshd[1234]: Did not receive identification string from 1.2.3.4 port 52214",
+			want: LoginEvent{IP: "1.2.3.4", Port: 52214, PID: 1234, Outcome: OutcomePreauth},
+			ok:   true,
+		},
+		{
+			name: "认证前断开",
+			line: "Mar 10 11:59:58 host sshd[1234]: Connection closed by 2001:db8::1 port 52214 [preauth]",
+			want: LoginEvent{IP: "2001:db8::1", Port: 52214, PID: 1234, Outcome: OutcomePreauth},
+			ok:   true,
+		},
+		{
+			name: "认证前断开的无效IP",
+			line: "Mar 10 11:59:58 host sshd[1234]: Connection closed by 1.2.3 port 52214 [preauth]",
+		},
+		{
+			name: "会话结束的无效IP",
+			line: "Mar 10 11:59:58 host sshd[1234]: Disconnected from user bob ::: port 52214",
+		},
+		{
+			name: "新建连接",
+			line: "Mar 10 11:59:58 host sshd[1234]: Connection from 1.2.3.4 port 52214 on 10.0.0.1 port 22 rdomain \"\"",
+			want: LoginEvent{IP: "1.2.3.4", Port: 52214, PID: 1234, Outcome: OutcomeConnect},
+			ok:   true,
+		},
+		{
+			name: "新建连接的无效IP",
+			line: "Mar 10 11:59:58 host sshd[1234]: Connection from 1.2.3.4.5 port 52214 on 10.0.0.1 port 22 rdomain \"\"",
+		},
+		{
+			name: "客户端版本",
+			line: "Mar 10 11:59:58 host sshd[1234]: debug1: Remote protocol version 2.0, remote software version libssh_0.9.6",
+			want: LoginEvent{Client: "libssh_0.9.6", PID: 1234, Outcome: OutcomeClient},
+			ok:   true,
+		},
+		{
+			name: "无关的sshd日志",
+			line: "Mar 10 11:59:58 host sshd[1234]: Server listening on 0.0.0.0 port 22.",
+		},
+		{
+			name: "其他程序的日志",
+			line: "Mar 10 11:59:58 host CRON[99]: pam_unix(cron:session): session closed for user root",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSSHEvent(tt.line, now)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v，期望 %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			got.Timestamp = time.Time{}
+			if got != tt.want {
+				t.Errorf("got %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSSHLineTrailingNewline(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	for _, suffix := range []string{"", "\n", "\r\n"} {
+		e, ok := ParseSSHLine("Mar 10 11:59:58 host sshd[1234]: Failed password for invalid user admin from 2001:db8::1 port 52214 ssh2"+suffix, now)
+		if !ok || e.IP != "2001:db8::1" || e.User != "admin" || !e.InvalidUser {
+			t.Errorf("行尾 %q: got %+v, ok = %v", suffix, e, ok)
+		}
+	}
+}
+
+func TestParseSSHEventTimestamp(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	e, ok := ParseSSHEvent("Mar 10 11:59:58 host sshd[1234]: Failed password for root from 1.2.3.4 port 52214 ssh2", now)
+	if !ok {
+		t.Fatal("未识别登录失败日志")
+	}
+	if want := time.Date(2024, 3, 10, 11, 59, 58, 0, time.Local); !e.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %v，期望 %v", e.Timestamp, want)
+	}
+}
+
+// benchmarkLines 攻击期间常见的日志组合，大多数行不是认证结果
+var benchmarkLines = []string{
+	"Mar 10 11:59:58 host sshd[1234]: Connection from 1.2.3.4 port 52214 on 10.0.0.1 port 22 rdomain \"\"",
+	"Mar 10 11:59:58 host sshd[1234]: Invalid user admin from 1.2.3.4 port 52214",
+	"Mar 10 11:59:58 host sshd[1234]: pam_unix(sshd:auth): check pass; user unknown",
+	"Mar 10 11:59:58 host sshd[1234]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=1.2.3.4",
+	"Mar 10 11:59:58 host sshd[1234]: Failed password for invalid user admin from 1.2.3.4 port 52214 ssh2",
+	"Mar 10 11:59:58 host sshd[1234]: Received disconnect from 1.2.3.4 port 52214:11: Bye Bye [preauth]",
+	"Mar 10 11:59:58 host sshd[1234]: Disconnected from invalid user admin 1.2.3.4 port 52214 [preauth]",
+	"Mar 10 11:59:58 host sshd[1235]: Connection closed by 5.6.7.8 port 40000 [preauth]",
+	"Mar 10 11:59:58 host sshd[1236]: Accepted publickey for bob from 9.9.9.9 port 50000 ssh2: RSA SHA256:abcdef",
+	"Mar 10 11:59:58 host systemd[1]: Started Session 42 of User bob.",
+	"Mar 10 11:59:58 host CRON[99]: pam_unix(cron:session): session closed for user root",
+}
+
+// BenchmarkParseSSHEvent 对比带子串预过滤的解析与对每行依次执行所有正则的解析
+func BenchmarkParseSSHEvent(b *testing.B) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	b.Run("prefiltered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ParseSSHEvent(benchmarkLines[i%len(benchmarkLines)], now)
+		}
+	})
+
+	patterns := append([]linePattern{authPattern, disconnectedPattern, sessionClosedPattern}, preauthPatterns...)
+	patterns = append(patterns, connectPattern, clientVersionPattern)
+	b.Run("unfiltered", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			line := benchmarkLines[i%len(benchmarkLines)]
+			for _, p := range patterns {
+				if p.re.FindStringSubmatch(line) != nil {
+					break
+				}
+			}
+			parseLogTime(line, now)
+		}
+	})
+}
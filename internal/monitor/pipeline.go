@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"sync"
+
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// 登录事件的处理分为三级，各级之间是有长度上限的队列：
+//   1. 日志读取协程同步解析日志行，放入m.logins
+//   2. prefetchLogins按日志顺序为需要补充信息的事件向查询协程池提交查询，放入m.pending
+//   3. dispatchLogins按日志顺序处理事件，需要补充信息时等待该事件的查询结果
+// 查询在加锁前完成，不同日志行的查询由协程池并发执行，某个接口响应慢只延迟依赖它的事件，
+// 不会占用监控状态的锁；封禁后补充来源信息与发送通知同样交给协程池，不在持有锁时查询。
+// 通知由各渠道的发送队列异步发送。
+
+// lookupPool 并发查询IP补充信息的协程池，同一IP正在进行中的查询会被复用
+type lookupPool struct {
+	enrich func(ip string, span *tracing.Span) *enrich.Result // 执行一次查询
+	jobs   chan *lookup                                       // 等待查询的IP
+
+	mu       sync.Mutex
+	inflight map[string]*lookup // 尚未完成的查询，键为IP
+}
+
+// lookup 一次IP补充信息查询
+type lookup struct {
+	ip     string
+	span   *tracing.Span            // 提交查询的日志行的跟踪
+	done   chan struct{}            // 查询完成后关闭
+	result *enrich.Result           // 查询结果，done关闭后可读
+	then   []func(r *enrich.Result) // 查询完成后依次执行的回调
+}
+
+// newLookupPool 创建查询协程池并启动查询协程
+// 参数:
+//   - workers: 查询协程数
+//   - queue: 等待查询的最大IP数
+//   - fn: 执行一次查询
+// 返回:
+//   - *lookupPool: 初始化后的协程池
+func newLookupPool(workers, queue int, fn func(ip string, span *tracing.Span) *enrich.Result) *lookupPool {
+	p := &lookupPool{enrich: fn, jobs: make(chan *lookup, queue), inflight: make(map[string]*lookup)}
+	for i := 0; i < workers; i++ {
+		go p.run()
+	}
+	return p
+}
+
+// start 提交一次查询，队列已满时等待
+// 只应由prefetchLogins调用，它等待时日志读取会在m.logins已满后等待，不影响已解析事件的处理
+// 参数:
+//   - ip: 要查询的IP
+//   - span: 日志行的跟踪，复用进行中的查询时不记录
+// 返回:
+//   - *lookup: 查询，调用wait获取结果
+func (p *lookupPool) start(ip string, span *tracing.Span) *lookup {
+	p.mu.Lock()
+	if l := p.inflight[ip]; l != nil {
+		p.mu.Unlock()
+		return l
+	}
+	l := &lookup{ip: ip, span: span, done: make(chan struct{})}
+	p.inflight[ip] = l
+	p.mu.Unlock()
+	p.jobs <- l
+	return l
+}
+
+// after 在查询完成后执行fn，不等待
+// 可以在持有m.mu时调用；队列已满时返回false，由调用方决定如何处理
+// 参数:
+//   - ip: 要查询的IP
+//   - span: 触发查询的日志行的跟踪
+//   - fn: 查询完成后在查询协程中执行的回调
+// 返回:
+//   - bool: 已提交或已附加到进行中的查询时为true
+func (p *lookupPool) after(ip string, span *tracing.Span, fn func(r *enrich.Result)) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if l := p.inflight[ip]; l != nil {
+		l.then = append(l.then, fn)
+		return true
+	}
+	l := &lookup{ip: ip, span: span, done: make(chan struct{}), then: []func(*enrich.Result){fn}}
+	select {
+	case p.jobs <- l:
+		p.inflight[ip] = l
+		return true
+	default:
+		return false
+	}
+}
+
+// run 查询协程，查询完成后先移出进行中的查询，再执行回调
+func (p *lookupPool) run() {
+	for l := range p.jobs {
+		l.result = p.enrich(l.ip, l.span)
+		p.mu.Lock()
+		delete(p.inflight, l.ip)
+		then := l.then
+		p.mu.Unlock()
+		close(l.done)
+		for _, fn := range then {
+			fn(l.result)
+		}
+	}
+}
+
+// wait 等待查询完成并返回结果
+func (l *lookup) wait() *enrich.Result {
+	<-l.done
+	return l.result
+}
+
+// prefetchLogins 按日志顺序为需要补充信息的登录事件提交查询，再交给dispatchLogins处理
+func (m *Monitor) prefetchLogins() {
+	for e := range m.logins {
+		if m.needsLookup(e) {
+			e.lookup = m.lookups.start(e.IP, e.Span)
+		}
+		m.pending <- e
+	}
+}
+
+// needsLookup 检查处理该事件时是否需要IP的补充信息：计入失败次数的登录失败和登录成功，
+// 有按滥用置信度的即时封禁条件时还包括其余的登录失败
+func (m *Monitor) needsLookup(e LoginEvent) bool {
+	if e.IP == "" {
+		return false
+	}
+	return e.Outcome == OutcomeSuccess || e.CountsAsFailure() || e.Outcome == OutcomeFailure && m.abuseTriggers()
+}
+
+// enrichLogin 返回登录事件的IP补充信息，已提前提交查询时等待其结果，否则同步查询
+// 不能在持有m.mu时调用
+// 参数:
+//   - e: 登录事件
+// 返回:
+//   - *enrich.Result: 合并后的补充信息，不会为nil
+func (m *Monitor) enrichLogin(e LoginEvent) *enrich.Result {
+	if e.lookup != nil {
+		return e.lookup.wait()
+	}
+	return m.enrichIPTraced(e.IP, e.Span)
+}
+
+// afterLookup 查询IP的补充信息后执行fn，不等待，可以在持有m.mu时调用
+// fn在查询协程或新的协程中执行，需要访问监控状态时自行加锁；
+// 队列已满时不再查询，以只含IP的结果执行fn，保证通知不会丢失
+// 参数:
+//   - ip: 要查询的IP
+//   - span: 触发查询的日志行的跟踪，可为nil
+//   - fn: 查询完成后执行的回调
+func (m *Monitor) afterLookup(ip string, span *tracing.Span, fn func(r *enrich.Result)) {
+	if m.lookups == nil {
+		go func() { fn(m.enrichIPTraced(ip, span)) }()
+		return
+	}
+	if !m.lookups.after(ip, span, fn) {
+		m.logger.WithField("ip", ip).Warn("IP补充信息查询队列已满，跳过查询")
+		go fn(&enrich.Result{IP: ip})
+	}
+}
+
+// queuedLogins 返回已解析、尚未处理的登录事件数
+func (m *Monitor) queuedLogins() int {
+	return len(m.logins) + len(m.pending)
+}
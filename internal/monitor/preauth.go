@@ -0,0 +1,61 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// handlePreauth 处理一次认证前断开的连接
+// 来源国家在country_policy.block中时直接封禁；否则时间窗口内同一IP的次数达到阈值时按连接洪泛封禁，与登录失败分开计数
+// 参数:
+//   - conn: 解析出的连接事件，IP已解析为真实客户端IP
+func (m *Monitor) handlePreauth(conn LoginEvent) {
+	cfg := m.config.SSHProtection.Preauth
+	ip := conn.IP
+	m.checkCountryBlock(conn)
+	if !cfg.Enabled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.whitelist.contains(ip) || m.isTrusted(ip, conn.Timestamp) || m.isIPBanned(ip) {
+		return
+	}
+
+	window := time.Duration(cfg.Window)
+	records, _ := pruneRecords(append(m.preauthConns[ip], failureRecord{ip: ip, time: conn.Timestamp}), conn.Timestamp.Add(-window))
+	m.preauthConns[ip] = records
+	m.logger.WithFields(logrus.Fields{
+		"ip":              ip,
+		"connections":     len(records),
+		"max_connections": cfg.MaxConnections,
+	}).Debug("认证前断开的连接")
+	if len(records) < cfg.MaxConnections {
+		return
+	}
+	delete(m.preauthConns, ip)
+
+	duration := m.banDuration()
+	if cfg.BanDuration > 0 {
+		duration = time.Duration(cfg.BanDuration)
+	}
+	if err := m.banIP(ip, conn.Timestamp, conn.Observed, conn.Span, duration, "SSH连接洪泛", SourceLog); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+	}
+}
+
+// prunePreauthConns 清理超出时间窗口的认证前连接记录
+// 调用方需持有m.mu锁
+func (m *Monitor) prunePreauthConns() {
+	cutoff := time.Now().Add(-time.Duration(m.config.SSHProtection.Preauth.Window))
+	for ip, records := range m.preauthConns {
+		if kept, _ := pruneRecords(records, cutoff); len(kept) == 0 {
+			delete(m.preauthConns, ip)
+		} else {
+			m.preauthConns[ip] = kept
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// punishIP 失败次数达到阈值时处置IP
+// 启用限速模式时，首次违规的IP只被限制新建连接的速率，限速期间再次达到阈值才完全封禁
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 违规的IP地址
+//   - at: 触发处置的时间
+//   - observed: 读取到触发日志行的时间，用于统计生效耗时，手动操作时为零值
+//   - span: 触发日志行的跟踪，未启用跟踪时为nil
+//   - duration: 完全封禁的时长
+//   - reason: 处置原因
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) punishIP(ip string, at, observed time.Time, span *tracing.Span, duration time.Duration, reason string) error {
+	if !m.config.SSHProtection.RateLimit.Enabled {
+		return m.banIP(ip, at, observed, span, duration, reason, SourceLog)
+	}
+	if _, limited := m.limitedIPs[ip]; limited {
+		return m.banIP(ip, at, observed, span, duration, reason+"（限速期间继续攻击）", SourceLog)
+	}
+	return m.limitIP(ip, at, observed, span, reason)
+}
+
+// limitIP 对IP限速，限速期间失败次数重新计算
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 要限速的IP地址
+//   - at: 触发限速的时间，到期时间从此起算
+//   - observed: 读取到触发日志行的时间，用于统计生效耗时
+//   - span: 触发日志行的跟踪，未启用跟踪时为nil
+//   - reason: 限速原因
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) limitIP(ip string, at, observed time.Time, span *tracing.Span, reason string) error {
+	if m.isProxy(ip) {
+		return fmt.Errorf("IP %s 是负载均衡或代理地址，不能限速", ip)
+	}
+
+	cfg := m.config.SSHProtection.RateLimit
+	expire := at.Add(time.Duration(cfg.Duration))
+	m.failedAttempts.remove(ip)
+	if expire.Before(time.Now()) {
+		return nil
+	}
+
+	applyStart := time.Now()
+	limit := span.Client("firewall.limit")
+	limit.Set("ip", ip)
+	err := m.firewall.LimitIP(ip, m.config.SSHProtection.SSHPort, cfg.ConnectionsPerMinute)
+	limit.Fail(err)
+	limit.End()
+	if err != nil {
+		return err
+	}
+	m.recordBanLatency(ip, observed, applyStart)
+	m.limitedIPs[ip] = expire
+
+	m.saveBlacklist()
+
+	m.logger.WithFields(logrus.Fields{
+		"ip":          ip,
+		"reason":      reason,
+		"per_minute":  cfg.ConnectionsPerMinute,
+		"expire_time": expire.Format("2006-01-02 15:04:05"),
+	}).Info("IP已被限速")
+	m.audit(auditLimit, ip, SourceLog, fmt.Sprintf("%s，每分钟%d次连接，解除时间 %s", reason, cfg.ConnectionsPerMinute, expire.Format("2006-01-02 15:04:05")))
+	m.events.Publish(event.Event{
+		Time:    at,
+		Type:    event.TypeRateLimited,
+		IP:      ip,
+		Message: fmt.Sprintf("%s，已限速为每分钟%d次连接", reason, cfg.ConnectionsPerMinute),
+	})
+	return nil
+}
+
+// liftLimit 删除IP的限速规则
+// 调用方需持有m.mu锁
+func (m *Monitor) liftLimit(ip string) {
+	cfg := m.config.SSHProtection.RateLimit
+	if err := m.firewall.UnlimitIP(ip, m.config.SSHProtection.SSHPort, cfg.ConnectionsPerMinute); err != nil {
+		m.logger.WithError(err).WithField("ip", ip).Error("解除IP限速失败")
+	}
+	delete(m.limitedIPs, ip)
+}
+
+// pruneRateLimits 解除已到期的限速
+// 调用方需持有m.mu锁
+// 返回:
+//   - bool: 有限速被解除时为true，调用方需保存黑名单
+func (m *Monitor) pruneRateLimits() bool {
+	removed := false
+	now := time.Now()
+	for ip, expire := range m.limitedIPs {
+		if now.Before(expire) {
+			continue
+		}
+		m.liftLimit(ip)
+		m.failedAttempts.remove(ip)
+		removed = true
+		m.logger.WithField("ip", ip).Info("IP已解除限速")
+		m.audit(auditUnban, ip, SourceExpiry, "限速到期")
+		m.events.Publish(event.Event{Type: event.TypeUnbanned, IP: ip, Message: "限速到期，已自动解除"})
+	}
+	return removed
+}
+
+// restoreRateLimits 启动时为黑名单中的限速IP重新添加规则
+// 未启用限速模式时直接解除这些限速，避免遗留规则
+// 调用方需持有m.mu锁
+func (m *Monitor) restoreRateLimits() {
+	cfg := m.config.SSHProtection.RateLimit
+	for ip := range m.limitedIPs {
+		if !cfg.Enabled {
+			m.liftLimit(ip)
+			continue
+		}
+		if err := m.firewall.LimitIP(ip, m.config.SSHProtection.SSHPort, cfg.ConnectionsPerMinute); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("恢复IP限速失败")
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package monitor
+
+import (
+	"net"
+	"regexp"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// realIPResolver 从经过负载均衡或堡垒机转发的日志中还原真实客户端IP
+type realIPResolver struct {
+	pattern *regexp.Regexp // 提取真实IP的正则，包含名为ip的捕获组，只使用haproxy jail时为nil
+	proxies []*net.IPNet   // 可信代理地址，为空表示所有日志都经过代理
+}
+
+// newRealIPResolver 根据配置创建真实IP解析器
+// 参数:
+//   - cfg: 真实IP提取配置
+// 返回:
+//   - *realIPResolver: 解析器，未启用时为nil
+//   - error: 正则表达式或代理地址无效时的错误信息
+func newRealIPResolver(cfg config.RealIPConfig) (*realIPResolver, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	r := &realIPResolver{}
+	if cfg.Pattern != "" {
+		pattern, err := config.CompileRulePattern(cfg.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		r.pattern = pattern
+	}
+	for _, entry := range cfg.TrustedProxies {
+		network, err := config.ParseNetwork(entry)
+		if err != nil {
+			return nil, err
+		}
+		r.proxies = append(r.proxies, network)
+	}
+	return r, nil
+}
+
+// extract 从日志行中提取真实客户端IP
+// 参数:
+//   - line: 日志行
+// 返回:
+//   - string: 真实客户端IP
+//   - bool: 是否提取到了有效的IP
+func (r *realIPResolver) extract(line string) (string, bool) {
+	if r.pattern == nil {
+		return "", false
+	}
+	matches := r.pattern.FindStringSubmatch(line)
+	if matches == nil {
+		return "", false
+	}
+	real := matches[r.pattern.SubexpIndex("ip")]
+	if net.ParseIP(real) == nil {
+		return "", false
+	}
+	return real, true
+}
+
+// fromProxy 检查来源IP是否为可信代理
+func (r *realIPResolver) fromProxy(ip string) bool {
+	if len(r.proxies) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range r.proxies {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"bufio"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// runHeartbeat 按notifications.heartbeat.schedule定期发送心跳通知
+// 心跳停止到达即说明守护进程已退出或通知渠道中断
+func (m *Monitor) runHeartbeat() {
+	cfg := m.config.Notifications.Heartbeat
+	schedule, _ := config.ParseSchedule(cfg.Schedule)
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			m.logger.WithField("schedule", cfg.Schedule).Error("心跳通知的发送时间永远不会到达，停止发送")
+			return
+		}
+		time.Sleep(time.Until(next))
+
+		event := notification.HeartbeatEvent(m.Status(), cfg.Resources, m.serverName(), time.Now())
+		if err := m.notifier.Notify(event); err != nil {
+			m.logger.WithError(err).Error("发送心跳通知失败")
+		}
+	}
+}
+
+// resourceUsage 读取守护进程自身的资源占用
+// 常驻内存和文件描述符从/proc/self读取，其他系统上对应的值为0或-1
+// 参数:
+//   - channels: 各通知渠道的健康状态，用于统计通知队列长度
+// 返回:
+//   - control.Resources: 资源占用
+func (m *Monitor) resourceUsage(channels []control.ChannelHealth) control.Resources {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	r := control.Resources{
+		RSS:           procStatusKB("VmRSS") * 1024,
+		HeapAlloc:     mem.HeapAlloc,
+		Goroutines:    runtime.NumGoroutine(),
+		OpenFiles:     -1,
+		MaxOpenFiles:  maxOpenFiles(),
+		StoreBytes:    m.storeBytes(),
+		LoginQueue:    m.queuedLogins(),
+		LoginQueueCap: cap(m.logins) + cap(m.pending),
+	}
+	// 读取目录时本身会占用一个文件描述符
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		r.OpenFiles = len(entries) - 1
+	}
+	for _, c := range channels {
+		r.NotifyQueue += c.Queued + c.Pending
+	}
+	r.EventQueue, r.EventQueueCap = m.events.Pending()
+	r.EventsDropped = int64(m.events.Dropped())
+	m.mu.RLock()
+	r.TrackedIPs, r.EvictedIPs = m.failedAttempts.len(), m.failedAttempts.evicted
+	r.TempBans, r.EvictedBans = len(m.bannedIPs), m.bansEvicted
+	m.mu.RUnlock()
+	r.MaxTrackedIPs = m.config.SSHProtection.MaxTrackedIPs
+	r.MaxTempBans = m.config.SSHProtection.MaxBannedIPs
+	return r
+}
+
+// storeBytes 返回sqlite数据库文件及其WAL文件的总大小
+func (m *Monitor) storeBytes() int64 {
+	if m.config.Store.Driver != store.DriverSQLite {
+		return 0
+	}
+	var total int64
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if info, err := os.Stat(m.config.Store.Path + suffix); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// procStatusKB 读取/proc/self/status中以kB为单位的字段，无法读取时返回0
+func procStatusKB(key string) int64 {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || name != key {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return 0
+		}
+		n, _ := strconv.ParseInt(fields[0], 10, 64)
+		return n
+	}
+	return 0
+}
+
+// maxOpenFiles 从/proc/self/limits读取文件描述符数量的软限制，无法读取或不限制时返回0
+func maxOpenFiles() int {
+	data, err := os.ReadFile("/proc/self/limits")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			return 0
+		}
+		n, _ := strconv.Atoi(fields[0])
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// retentionStartDelay 启动后首次清理前的等待时间，避开启动时加载黑名单和回放日志的高峰
+const retentionStartDelay = 5 * time.Minute
+
+// pruneStore 按store.retention定期清理存储中过期的事件、每日统计和审计记录
+func (m *Monitor) pruneStore() {
+	cfg := m.config.Store.Retention
+	if cfg.EventsDays == 0 && cfg.DailyStatsDays == 0 && cfg.AuditDays == 0 {
+		return
+	}
+	time.Sleep(retentionStartDelay)
+	m.applyRetention(time.Now())
+	ticker := time.NewTicker(time.Duration(cfg.Interval))
+	for range ticker.C {
+		m.applyRetention(time.Now())
+	}
+}
+
+// applyRetention 执行一次清理，各项保留天数为0时跳过对应的清理
+// 过期事件先汇总为每日统计再删除，因此每日统计的保留期应不短于事件的保留期
+func (m *Monitor) applyRetention(now time.Time) {
+	cfg := m.config.Store.Retention
+	fields := logrus.Fields{}
+	removed := 0
+	if cfg.EventsDays > 0 {
+		n, err := m.store.RollupEvents(now.AddDate(0, 0, -cfg.EventsDays))
+		if err != nil {
+			m.logger.WithError(err).Warn("汇总过期事件失败")
+		}
+		fields["events"] = n
+		removed += n
+	}
+	if cfg.DailyStatsDays > 0 {
+		if err := m.store.PruneDailyStats(now.AddDate(0, 0, -cfg.DailyStatsDays)); err != nil {
+			m.logger.WithError(err).Warn("清理过期的每日统计失败")
+		}
+	}
+	if cfg.AuditDays > 0 {
+		n, err := m.store.PruneAudit(now.AddDate(0, 0, -cfg.AuditDays))
+		if err != nil {
+			m.logger.WithError(err).Warn("清理过期的审计记录失败")
+		}
+		fields["audit"] = n
+		removed += n
+	}
+	if removed > 0 {
+		m.logger.WithFields(fields).Info("已清理存储中的过期记录")
+	}
+}
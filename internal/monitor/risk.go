@@ -0,0 +1,128 @@
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/fleet"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// riskScore 一次登录失败时来源IP的风险分
+type riskScore struct {
+	total int
+	parts []string // 各项得分的说明，如 "失败次数+30"
+}
+
+// add 计入一项得分，0分的项不记录
+func (r *riskScore) add(name string, points int) {
+	if points <= 0 {
+		return
+	}
+	r.total += points
+	r.parts = append(r.parts, fmt.Sprintf("%s+%d", name, points))
+}
+
+// String 返回总分与各项得分，用于日志、事件和通知
+func (r riskScore) String() string {
+	if len(r.parts) == 0 {
+		return fmt.Sprintf("%d", r.total)
+	}
+	return fmt.Sprintf("%d（%s）", r.total, strings.Join(r.parts, " "))
+}
+
+// riskEnabled 检查是否以风险分代替失败次数阈值决定处置
+func (m *Monitor) riskEnabled() bool {
+	return m.config.SSHProtection.RiskScore.Enabled
+}
+
+// isPermanentRisk 检查风险分是否达到permanent_score
+func (m *Monitor) isPermanentRisk(score riskScore) bool {
+	permanent := m.config.SSHProtection.RiskScore.PermanentScore
+	return permanent > 0 && score.total >= permanent
+}
+
+// scoreRisk 按各项信号计算来源IP的风险分
+// 调用方需持有m.mu锁，且已将本次失败计入failedAttempts
+// 参数:
+//   - login: 登录失败事件
+//   - enriched: 来源IP的补充信息
+// 返回:
+//   - riskScore: 风险分与各项得分
+func (m *Monitor) scoreRisk(login LoginEvent, enriched *enrich.Result) riskScore {
+	cfg := m.config.SSHProtection.RiskScore
+	var score riskScore
+	score.add("失败次数", cfg.AttemptWeight*m.failedAttempts.get(login.IP))
+	score.add("失败速率", cfg.RateWeight*(m.recordRiskFailure(login.IP, login.Timestamp)-1))
+	if slices.Contains(cfg.SensitiveUsers, login.User) {
+		score.add("敏感用户名", cfg.SensitiveUserWeight)
+	}
+	if login.InvalidUser {
+		score.add("不存在的用户", cfg.InvalidUserWeight)
+	}
+	if info := enriched.Geo; m.isForeignCountry(countryCodeOf(info)) || m.isStrictCountry(countryOf(info)) {
+		score.add("来源国家", cfg.CountryWeight)
+	}
+	if enriched.Abuse != nil {
+		score.add("AbuseIPDB", cfg.AbuseWeight*enriched.Abuse.Score/100)
+	}
+	if len(enriched.Listed) > 0 {
+		score.add("DNSBL", cfg.DNSBLWeight)
+	}
+	if enriched.Geo != nil && enriched.Geo.Proxy {
+		score.add("代理", cfg.ProxyWeight)
+	}
+	if enriched.NetworkType() == enrich.NetworkHosting {
+		score.add("机房", cfg.HostingWeight)
+	}
+	return score
+}
+
+// recordRiskFailure 记录一次登录失败，并返回该IP在失败速率窗口内的失败次数
+// 调用方需持有m.mu锁
+func (m *Monitor) recordRiskFailure(ip string, at time.Time) int {
+	window := time.Duration(m.config.SSHProtection.RiskScore.RateWindow)
+	records, _ := pruneRecords(append(m.riskFailures[ip], failureRecord{ip: ip, time: at}), at.Add(-window))
+	m.riskFailures[ip] = records
+	return len(records)
+}
+
+// pruneRiskFailures 清理失败速率窗口外的记录
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneRiskFailures() {
+	cutoff := time.Now().Add(-time.Duration(m.config.SSHProtection.RiskScore.RateWindow))
+	for ip, records := range m.riskFailures {
+		if kept, _ := pruneRecords(records, cutoff); len(kept) == 0 {
+			delete(m.riskFailures, ip)
+		} else {
+			m.riskFailures[ip] = kept
+		}
+	}
+}
+
+// banPermanent 因风险分达到permanent_score将IP永久封禁，并发送封禁通知
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 要封禁的IP地址
+//   - at: 触发封禁的时间
+//   - reason: 封禁原因
+//   - enriched: 来源IP的补充信息
+// 返回:
+//   - error: 防火墙操作或保存黑名单失败时的错误信息
+func (m *Monitor) banPermanent(ip string, at time.Time, reason string, enriched *enrich.Result) error {
+	if m.isProxy(ip) {
+		return fmt.Errorf("IP %s 是负载均衡或代理地址，不能封禁", ip)
+	}
+	if err := m.makePermanent(ip); err != nil {
+		return err
+	}
+	m.audit(auditPermanent, ip, SourceLog, reason)
+	m.events.Publish(event.Event{Time: at, Type: event.TypeBanned, IP: ip, Message: reason + "，已永久封禁"})
+	m.publishFleet(fleet.ActionPermanent, ip, time.Time{}, reason)
+	m.notifier.Notify(notification.IPBannedEvent(ip, enriched.Format(), m.serverName(), reason, 0, time.Time{}, at).WithCountry(countryOf(enriched.Geo)))
+	return nil
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/enrich"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// 通用规则达到阈值时的动作
+const (
+	ruleActionBan    = "ban"
+	ruleActionNotify = "notify"
+	ruleActionLog    = "log"
+)
+
+// rule 一条通用正则规则及其运行状态
+type rule struct {
+	config   config.RuleConfig          // 规则配置
+	pattern  *regexp.Regexp             // 编译后的正则，包含名为ip的捕获组
+	failures map[string][]failureRecord // 各IP在时间窗口内的匹配记录
+}
+
+// startRules 为每条通用规则启动独立的jail
+// 单条规则的日志无法读取时只记录错误并定期重试，不影响SSH防护
+func (m *Monitor) startRules() {
+	for _, cfg := range m.config.Rules {
+		pattern, err := config.CompileRulePattern(cfg.Pattern)
+		if err != nil {
+			m.logger.WithError(err).WithField("jail", cfg.Name).Error("规则正则表达式无效")
+			continue
+		}
+		r := &rule{config: cfg, pattern: pattern, failures: make(map[string][]failureRecord)}
+		m.rules = append(m.rules, r)
+
+		runner := m.newJailRunner(cfg.Name, cfg.LogFile, m.config.Jails.Workers, func(l logLine) {
+			if !m.jailEnabled(r.config.Name) {
+				return
+			}
+			// 只导出匹配到规则的日志行的跟踪
+			span := m.tracer.Start("rule.line", l.observed)
+			parse := span.Child("parse")
+			if matches := r.pattern.FindStringSubmatch(l.text); matches != nil {
+				parse.End()
+				ip := matches[r.pattern.SubexpIndex("ip")]
+				span.Set("jail", r.config.Name)
+				span.Set("ip", ip)
+				m.archiveLine(r.config.Name, l.text)
+				m.handleRuleMatch(r, ip, parseLogTime(l.text, time.Now()), l.observed, span)
+				span.End()
+			}
+		})
+		go m.superviseJail(runner)
+	}
+}
+
+// handleRuleMatch 处理通用规则的一次匹配
+// 时间窗口内同一IP的匹配次数达到阈值时执行配置的动作
+// ip捕获组由用户编写的正则决定，不是有效的IP或CIDR时记录警告并忽略该次匹配，避免把任意内容交给防火墙
+// 参数:
+//   - r: 匹配的规则
+//   - ip: 日志中提取的IP地址
+//   - now: 日志中记录的匹配时间
+//   - observed: 读取到该日志行的时间
+//   - span: 该日志行的跟踪，未启用跟踪时为nil
+func (m *Monitor) handleRuleMatch(r *rule, ip string, now, observed time.Time, span *tracing.Span) {
+	if net.ParseIP(ip) == nil {
+		if _, _, err := net.ParseCIDR(ip); err != nil {
+			m.logger.WithFields(logrus.Fields{"jail": r.config.Name, "ip": ip}).Warn("规则匹配到的不是有效的IP地址，已忽略")
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.whitelist.contains(ip) || m.isTrusted(ip, now) || m.isIPBanned(ip) {
+		return
+	}
+
+	window := time.Duration(r.config.Window)
+	records, _ := pruneRecords(append(r.failures[ip], failureRecord{ip: ip, time: now}), now.Add(-window))
+	r.failures[ip] = records
+	m.totalFailures[ip]++
+
+	m.logger.WithFields(logrus.Fields{
+		"jail":         r.config.Name,
+		"ip":           ip,
+		"attempts":     len(records),
+		"max_attempts": r.config.MaxFailures,
+	}).Warn("规则匹配")
+	if len(records) < r.config.MaxFailures {
+		return
+	}
+	delete(r.failures, ip)
+
+	m.events.Publish(event.Event{
+		Time:    now,
+		Type:    event.TypeRuleMatched,
+		IP:      ip,
+		Message: fmt.Sprintf("规则 %s 已触发（%d次）", r.config.Name, len(records)),
+	})
+
+	switch r.config.Action {
+	case ruleActionBan:
+		duration := m.banDuration()
+		if r.config.BanDuration > 0 {
+			duration = time.Duration(r.config.BanDuration)
+		}
+		if err := m.banIP(ip, now, observed, span, duration, "规则 "+r.config.Name+" 触发", SourceLog); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("封禁IP失败")
+		}
+	case ruleActionNotify:
+		name, count := r.config.Name, len(records)
+		m.afterLookup(ip, span, func(enriched *enrich.Result) {
+			m.notifier.Notify(notification.RuleMatchedEvent(name, ip, enriched.Format(), m.serverName(), count, window, now).
+				WithCountry(countryOf(enriched.Geo)).WithSpan(span))
+		})
+	case ruleActionLog:
+		m.logger.WithFields(logrus.Fields{"jail": r.config.Name, "ip": ip}).Warn("规则已触发")
+	}
+}
+
+// pruneRuleFailures 清理所有通用规则的过期匹配记录
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneRuleFailures() {
+	now := time.Now()
+	for _, r := range m.rules {
+		cutoff := now.Add(-time.Duration(r.config.Window))
+		for ip, records := range r.failures {
+			if kept, _ := pruneRecords(records, cutoff); len(kept) == 0 {
+				delete(r.failures, ip)
+			} else {
+				r.failures[ip] = kept
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"sync"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// failureSampler 按固定比例抽取登录失败事件
+type failureSampler struct {
+	mu   sync.Mutex
+	seen int64 // 参与抽样的事件数
+	kept int64 // 保存和通知的事件数
+}
+
+// sampleFailure 决定一次未触发处置的登录失败是否保存和通知
+// 未启用抽样时总是保存；启用时每rate个事件保存第一个，记录的事件数为rate，按比例还原的总数与实际次数的误差小于rate
+// 返回:
+//   - int: 保存的事件代表的事件数，未抽样时为0
+//   - bool: 是否保存和通知该事件
+func (m *Monitor) sampleFailure() (int, bool) {
+	cfg := m.config.SSHProtection.Sampling
+	if !cfg.Enabled || cfg.Rate <= 1 {
+		return 0, true
+	}
+	s := &m.sampler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keep := s.seen%int64(cfg.Rate) == 0
+	s.seen++
+	if !keep {
+		return 0, false
+	}
+	s.kept++
+	return cfg.Rate, true
+}
+
+// samplingStats 返回抽样统计
+func (m *Monitor) samplingStats() control.SamplingStats {
+	cfg := m.config.SSHProtection.Sampling
+	m.sampler.mu.Lock()
+	defer m.sampler.mu.Unlock()
+	return control.SamplingStats{Enabled: cfg.Enabled, Rate: cfg.Rate, Seen: m.sampler.seen, Kept: m.sampler.kept}
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// 未记录到退出日志的会话保留的最长时间
+const sessionMaxAge = 7 * 24 * time.Hour
+
+// session 一个登录成功后尚未结束的SSH会话
+type session struct {
+	user  string    // 登录用户名
+	ip    string    // 真实客户端IP
+	port  int       // 客户端源端口
+	start time.Time // 登录成功的时间
+}
+
+// openSession 记录登录成功的会话，用于在退出时计算会话时长
+// 参数:
+//   - login: 登录成功记录
+func (m *Monitor) openSession(login LoginEvent) {
+	if login.PID == 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[login.PID] = session{user: login.User, ip: login.IP, port: login.Port, start: login.Timestamp}
+}
+
+// handleLogout 处理会话结束事件
+// 会话按sshd进程ID对应，找不到时按客户端IP和端口查找；同一会话的第二条退出日志会被忽略
+// 参数:
+//   - logout: 会话结束记录
+func (m *Monitor) handleLogout(logout LoginEvent) {
+	m.mu.Lock()
+	pid, s, ok := m.findSession(logout)
+	if ok {
+		delete(m.sessions, pid)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	duration := logout.Timestamp.Sub(s.start).Round(time.Second)
+	m.logger.WithFields(logrus.Fields{
+		"ip":       s.ip,
+		"user":     s.user,
+		"pid":      pid,
+		"duration": duration.String(),
+	}).Info("SSH会话结束")
+	m.events.Publish(event.Event{
+		Time:    logout.Timestamp,
+		Type:    event.TypeLogout,
+		IP:      s.ip,
+		Message: fmt.Sprintf("%s 退出登录，会话时长 %s", s.user, duration),
+		Port:    s.port,
+		PID:     pid,
+		User:    s.user,
+	})
+
+	m.notifier.Notify(notification.LogoutEvent(s.ip, s.user, m.serverName(), s.start, logout.Timestamp))
+}
+
+// findSession 查找退出日志对应的会话
+// 调用方需持有m.mu锁
+func (m *Monitor) findSession(logout LoginEvent) (int, session, bool) {
+	if s, ok := m.sessions[logout.PID]; ok && logout.PID != 0 {
+		return logout.PID, s, true
+	}
+	if logout.Port == 0 {
+		return 0, session{}, false
+	}
+	for pid, s := range m.sessions {
+		if s.port == logout.Port && s.user == logout.User {
+			return pid, s, true
+		}
+	}
+	return 0, session{}, false
+}
+
+// pruneSessions 清理长时间没有记录到退出日志的会话
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneSessions() {
+	cutoff := time.Now().Add(-sessionMaxAge)
+	for pid, s := range m.sessions {
+		if s.start.Before(cutoff) {
+			delete(m.sessions, pid)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// 共享IP的处置方式
+const (
+	sharedModeUser  = "user"  // 按用户名分别计数
+	sharedModeLimit = "limit" // 按IP计数，只限速不封禁
+)
+
+// sharedIPs 共享IP的标记与计数
+type sharedIPs struct {
+	networks  []*net.IPNet                    // 手动标记的共享IP
+	detected  map[string]time.Time            // 自动识别的共享IP及标记到期时间
+	successes map[string]map[string]time.Time // 各IP上登录成功的用户名及最近一次登录成功的时间
+	failures  map[string]map[string]int       // 共享IP上各用户名的失败次数，按用户名计数时使用
+}
+
+// newSharedIPs 按配置创建共享IP的标记，无效的条目已在加载配置时校验
+func newSharedIPs(cfg config.SharedIPConfig) sharedIPs {
+	shared := sharedIPs{
+		detected:  make(map[string]time.Time),
+		successes: make(map[string]map[string]time.Time),
+		failures:  make(map[string]map[string]int),
+	}
+	for _, entry := range cfg.Entries {
+		if network, err := config.ParseNetwork(entry); err == nil {
+			shared.networks = append(shared.networks, network)
+		}
+	}
+	return shared
+}
+
+// isSharedIP 检查IP是否被手动标记或自动识别为共享IP
+// 调用方需持有m.mu锁
+func (m *Monitor) isSharedIP(ip string, at time.Time) bool {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range m.sharedIPs.networks {
+			if network.Contains(parsed) {
+				return true
+			}
+		}
+	}
+	expire, ok := m.sharedIPs.detected[ip]
+	return ok && at.Before(expire)
+}
+
+// recordSharedSuccess 记录登录成功的用户名，时间窗口内不同用户名达到min_users时把IP标记为共享IP
+// 调用方需持有m.mu锁
+func (m *Monitor) recordSharedSuccess(ip, user string, at time.Time) {
+	cfg := m.config.SSHProtection.SharedIP
+	if !cfg.AutoDetect {
+		return
+	}
+	window := time.Duration(cfg.Window)
+	users := m.sharedIPs.successes[ip]
+	if users == nil {
+		users = make(map[string]time.Time)
+		m.sharedIPs.successes[ip] = users
+	}
+	users[user] = at
+	for name, last := range users {
+		if at.Sub(last) > window {
+			delete(users, name)
+		}
+	}
+	if len(users) < cfg.MinUsers {
+		return
+	}
+	if _, ok := m.sharedIPs.detected[ip]; !ok {
+		m.logger.WithFields(logrus.Fields{"ip": ip, "users": len(users)}).Info("多个用户从同一IP登录成功，已标记为共享IP")
+	}
+	m.sharedIPs.detected[ip] = at.Add(window)
+}
+
+// sharedAttempts 记录共享IP上的一次登录失败，返回用于阈值判断的失败次数
+// 按用户名计数时返回该用户名在此IP上的失败次数，否则返回IP的失败次数
+// 调用方需持有m.mu锁
+func (m *Monitor) sharedAttempts(ip, user string) int {
+	if m.config.SSHProtection.SharedIP.Mode != sharedModeUser {
+		return m.failedAttempts.get(ip)
+	}
+	users := m.sharedIPs.failures[ip]
+	if users == nil {
+		users = make(map[string]int)
+		m.sharedIPs.failures[ip] = users
+	}
+	users[user]++
+	return users[user]
+}
+
+// punishSharedIP 共享IP达到阈值时只限速，不封禁，限速期间再次达到阈值时延长限速
+// 调用方需持有m.mu锁
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) punishSharedIP(ip, user string, at, observed time.Time, span *tracing.Span) error {
+	reason := "共享IP登录失败过多"
+	if m.config.SSHProtection.SharedIP.Mode == sharedModeUser {
+		reason = fmt.Sprintf("共享IP上用户%s登录失败过多", user)
+	}
+	delete(m.sharedIPs.failures, ip)
+	return m.limitIP(ip, at, observed, span, reason)
+}
+
+// pruneSharedIPs 清理已过期的自动识别标记和登录成功记录，以及不再是共享IP的失败计数
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneSharedIPs() {
+	now := time.Now()
+	window := time.Duration(m.config.SSHProtection.SharedIP.Window)
+	for ip, expire := range m.sharedIPs.detected {
+		if !now.Before(expire) {
+			delete(m.sharedIPs.detected, ip)
+		}
+	}
+	for ip, users := range m.sharedIPs.successes {
+		for name, last := range users {
+			if now.Sub(last) > window {
+				delete(users, name)
+			}
+		}
+		if len(users) == 0 {
+			delete(m.sharedIPs.successes, ip)
+		}
+	}
+	for ip := range m.sharedIPs.failures {
+		if !m.isSharedIP(ip, now) {
+			delete(m.sharedIPs.failures, ip)
+		}
+	}
+}
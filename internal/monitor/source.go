@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 容器停止或重启导致日志读取中断后，重新读取前等待的时间
+const containerRetryInterval = 5 * time.Second
+
+// 支持的容器运行时，日志来源写作 docker://<容器名> 或 podman://<容器名>
+var containerRuntimes = []string{"docker", "podman"}
+
+// tailLog 持续读取日志来源中新写入的行
+// 日志来源为 docker://<容器名> 或 podman://<容器名> 时跟踪容器的标准输出和标准错误，否则按文件路径处理
+// 参数:
+//   - source: 日志文件路径或容器日志来源
+//   - handle: 每读到一行调用一次
+// 返回:
+//   - error: 打开或读取日志失败时的错误信息
+func (m *Monitor) tailLog(source string, handle func(line string)) error {
+	for _, runtime := range containerRuntimes {
+		if name, ok := strings.CutPrefix(source, runtime+"://"); ok {
+			return m.tailContainer(runtime, name, handle)
+		}
+	}
+	return tailFile(source, handle)
+}
+
+// tailContainer 持续读取容器日志
+// 通过 "<运行时> logs --follow --timestamps" 读取，日志行带有运行时记录的时间戳；
+// 容器停止或重启时命令会退出，稍后从中断的时间继续读取，不会重放之前的日志
+// 参数:
+//   - runtime: 容器运行时命令，docker或podman
+//   - name: 容器名称或ID
+//   - handle: 每读到一行调用一次
+// 返回:
+//   - error: 找不到容器运行时命令时的错误信息
+func (m *Monitor) tailContainer(runtime, name string, handle func(line string)) error {
+	if _, err := exec.LookPath(runtime); err != nil {
+		return fmt.Errorf("未找到%s命令: %v", runtime, err)
+	}
+
+	args := []string{"logs", "--follow", "--timestamps", "--tail", "0", name}
+	for {
+		err := followContainer(runtime, args, handle)
+		since := time.Now()
+		m.logger.WithFields(logrus.Fields{"runtime": runtime, "container": name}).WithError(err).Warn("容器日志读取中断，稍后重试")
+		time.Sleep(containerRetryInterval)
+		args = []string{"logs", "--follow", "--timestamps", "--since", since.Format(time.RFC3339Nano), name}
+	}
+}
+
+// followContainer 运行一次容器日志命令并逐行处理输出，直到命令退出
+// 标准输出和标准错误写入同一个管道，保持两者之间的顺序
+func followContainer(runtime string, args []string, handle func(line string)) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(runtime, args...)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			handle(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return cmd.Wait()
+}
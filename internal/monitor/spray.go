@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+)
+
+// failureRecord 一次登录失败记录，用于按用户名、网段等维度汇总
+type failureRecord struct {
+	ip   string    // 来源IP
+	time time.Time // 失败时间
+}
+
+// pruneRecords 丢弃cutoff之前的记录，并返回保留的记录和其中的不同IP
+func pruneRecords(records []failureRecord, cutoff time.Time) ([]failureRecord, []string) {
+	kept := records[:0]
+	seen := make(map[string]bool)
+	var ips []string
+	for _, r := range records {
+		if r.time.Before(cutoff) {
+			continue
+		}
+		kept = append(kept, r)
+		if !seen[r.ip] {
+			seen[r.ip] = true
+			ips = append(ips, r.ip)
+		}
+	}
+	sort.Strings(ips)
+	return kept, ips
+}
+
+// recordUserFailure 记录用户名的登录失败并检测密码喷洒
+// 时间窗口内失败的不同IP数量达到阈值时发出告警，每轮喷洒只告警一次
+// 调用方需持有m.mu锁
+// 参数:
+//   - user: 登录使用的用户名
+//   - ip: 来源IP地址
+//   - now: 日志中记录的失败时间
+func (m *Monitor) recordUserFailure(user, ip string, now time.Time) {
+	cfg := m.config.SSHProtection.PasswordSpray
+	if !cfg.Enabled || user == "" {
+		return
+	}
+
+	m.userFailures[user] = append(m.userFailures[user], failureRecord{ip: ip, time: now})
+	ips := m.sprayIPs(user, now)
+	if len(ips) < cfg.DistinctIPs || m.sprayUsers[user] {
+		return
+	}
+	m.sprayUsers[user] = true
+
+	window := time.Duration(cfg.Window)
+	m.logger.WithFields(logrus.Fields{
+		"user":   user,
+		"ips":    len(ips),
+		"window": cfg.Window.String(),
+	}).Warn("检测到密码喷洒攻击")
+	m.events.Publish(event.Event{
+		Time:    now,
+		Type:    event.TypePasswordSpray,
+		IP:      ip,
+		Message: "用户名 " + user + " 被多个IP尝试登录",
+	})
+
+	m.notifier.Notify(notification.PasswordSprayEvent(user, ips, window, m.serverName()))
+	m.lockAccount(user, ips, now)
+}
+
+// sprayIPs 清理窗口外的失败记录，并返回窗口内失败的不同IP
+// 不同IP数量回落到阈值以下时视为喷洒结束
+// 调用方需持有m.mu锁
+func (m *Monitor) sprayIPs(user string, now time.Time) []string {
+	cfg := m.config.SSHProtection.PasswordSpray
+	cutoff := now.Add(-time.Duration(cfg.Window))
+
+	kept, ips := pruneRecords(m.userFailures[user], cutoff)
+	if len(kept) == 0 {
+		delete(m.userFailures, user)
+	} else {
+		m.userFailures[user] = kept
+	}
+
+	if len(ips) < cfg.DistinctIPs && m.sprayUsers[user] {
+		delete(m.sprayUsers, user)
+		m.logger.WithField("user", user).Info("密码喷洒已结束")
+	}
+	return ips
+}
+
+// pruneUserFailures 清理所有用户名的过期失败记录
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneUserFailures() {
+	now := time.Now()
+	for user := range m.userFailures {
+		m.sprayIPs(user, now)
+	}
+}
@@ -0,0 +1,191 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// 滚动统计的范围
+const (
+	statsHours     = 24                 // 按小时统计时的小时数
+	statsDays      = 7                  // 按天统计时的天数
+	statsRetention = 8 * 24 * time.Hour // 小时桶的保留时长，多保留一天使按天统计的第一天也是完整的
+	statsTopLimit  = 10                 // 用户名与国家排行的条数
+)
+
+// rollingStats 按小时分桶的登录失败与封禁统计，保留最近8天
+// 启动时从存储中的事件重建，之后由事件总线的每个事件更新
+type rollingStats struct {
+	mu      sync.Mutex
+	buckets map[int64]*statsBucket // 按本地时间整点的Unix秒索引
+}
+
+// statsBucket 一小时内的统计
+type statsBucket struct {
+	failures int            // 登录失败次数
+	bans     int            // 封禁次数
+	ips      map[string]int // 各IP的登录失败次数
+	users    map[string]int // 各用户名的登录失败次数
+}
+
+// add 记录一个事件，只统计登录失败与封禁，超出保留时长的事件被忽略
+// 参数:
+//   - e: 事件
+//   - now: 当前时间
+func (s *rollingStats) add(e event.Event, now time.Time) {
+	if e.Type != event.TypeLoginFailed && e.Type != event.TypeBanned {
+		return
+	}
+	if e.Time.Before(now.Add(-statsRetention)) {
+		return
+	}
+	hour := truncateHour(e.Time).Unix()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets == nil {
+		s.buckets = make(map[int64]*statsBucket)
+	}
+	b := s.buckets[hour]
+	if b == nil {
+		b = &statsBucket{ips: make(map[string]int), users: make(map[string]int)}
+		s.buckets[hour] = b
+		s.prune(now)
+	}
+	switch e.Type {
+	case event.TypeLoginFailed:
+		b.failures += e.Count()
+		if e.IP != "" {
+			b.ips[e.IP] += e.Count()
+		}
+		if e.User != "" {
+			b.users[e.User] += e.Count()
+		}
+	case event.TypeBanned:
+		b.bans++
+	}
+}
+
+// prune 删除超出保留时长的小时桶，调用方需持有锁
+func (s *rollingStats) prune(now time.Time) {
+	oldest := truncateHour(now.Add(-statsRetention)).Unix()
+	for hour := range s.buckets {
+		if hour < oldest {
+			delete(s.buckets, hour)
+		}
+	}
+}
+
+// history 汇总最近24小时（按小时）或最近7天（按天）的统计，包括当前未结束的小时或天
+// 参数:
+//   - by: hour 或 day
+//   - now: 当前时间
+// 返回:
+//   - control.StatsHistory: 汇总结果，国家排行由调用方填写
+//   - map[string]int: 整个范围内各IP的登录失败次数，用于统计国家
+func (s *rollingStats) history(by string, now time.Time) (control.StatsHistory, map[string]int) {
+	var starts []time.Time
+	if by == "day" {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		for i := statsDays - 1; i >= 0; i-- {
+			starts = append(starts, today.AddDate(0, 0, -i))
+		}
+	} else {
+		current := truncateHour(now)
+		for i := statsHours - 1; i >= 0; i-- {
+			starts = append(starts, current.Add(-time.Duration(i)*time.Hour))
+		}
+	}
+
+	history := control.StatsHistory{By: by, Buckets: make([]control.StatsBucket, len(starts))}
+	ips := make(map[string]int)
+	users := make(map[string]int)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, start := range starts {
+		end := now
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		out := control.StatsBucket{Start: start}
+		bucketIPs := make(map[string]bool)
+		for hour := start; hour.Before(end); hour = hour.Add(time.Hour) {
+			b := s.buckets[hour.Unix()]
+			if b == nil {
+				continue
+			}
+			out.Failures += b.failures
+			out.Bans += b.bans
+			for ip, n := range b.ips {
+				bucketIPs[ip] = true
+				ips[ip] += n
+			}
+			for user, n := range b.users {
+				users[user] += n
+			}
+		}
+		out.UniqueIPs = len(bucketIPs)
+		history.Buckets[i] = out
+		history.Failures += out.Failures
+		history.Bans += out.Bans
+	}
+	history.UniqueIPs = len(ips)
+	history.TopUsers = topCounts(users, statsTopLimit)
+	return history, ips
+}
+
+// truncateHour 返回t所在的本地时间整点
+func truncateHour(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// topCounts 返回次数最多的limit项，次数相同时按名称排序
+func topCounts(counts map[string]int, limit int) []control.NamedCount {
+	top := make([]control.NamedCount, 0, len(counts))
+	for name, n := range counts {
+		top = append(top, control.NamedCount{Name: name, Count: n})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+	return top
+}
+
+// loadStats 从存储中最近8天的事件重建滚动统计，需要在事件总线开始更新统计之前调用
+func (m *Monitor) loadStats() {
+	now := time.Now()
+	events, err := m.store.Events(now.Add(-statsRetention), 0)
+	if err != nil {
+		m.logger.WithError(err).Warn("读取事件记录失败，统计从空开始")
+		return
+	}
+	for _, e := range events {
+		m.stats.add(e, now)
+	}
+}
+
+// StatsHistory 返回按小时或按天汇总的登录失败、封禁、来源IP数以及用户名和国家排行
+// 参数:
+//   - by: hour 返回最近24小时，day 返回最近7天
+// 返回:
+//   - control.StatsHistory: 统计结果
+//   - error: by取值无效时的错误信息
+func (m *Monitor) StatsHistory(by string) (control.StatsHistory, error) {
+	if by != "hour" && by != "day" {
+		return control.StatsHistory{}, fmt.Errorf("统计粒度必须为hour或day: %s", by)
+	}
+	history, ips := m.stats.history(by, time.Now())
+	history.TopCountries = topCounts(m.countryCounts(ips), statsTopLimit)
+	return history, nil
+}
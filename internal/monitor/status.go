@@ -0,0 +1,40 @@
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// failureCounter 按分钟统计最近一小时的登录失败次数
+type failureCounter struct {
+	mu      sync.Mutex
+	minutes [60]int64 // 各位置对应的分钟序号，位置为分钟序号对60取模
+	counts  [60]int   // 对应分钟的失败次数
+}
+
+// add 记录一次登录失败
+func (c *failureCounter) add(t time.Time) {
+	minute := t.Unix() / 60
+	i := minute % 60
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.minutes[i] != minute {
+		c.minutes[i] = minute
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+// lastHour 返回截至now的最近一小时的失败次数
+func (c *failureCounter) lastHour(now time.Time) int {
+	current := now.Unix() / 60
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for i, minute := range c.minutes {
+		if minute > current-60 && minute <= current {
+			total += c.counts[i]
+		}
+	}
+	return total
+}
@@ -0,0 +1,62 @@
+package monitor
+
+import (
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/store"
+)
+
+// persistEvent 将事件写入存储，写入失败只记录日志
+func (m *Monitor) persistEvent(e event.Event) {
+	if err := m.store.AppendEvent(e); err != nil {
+		m.logger.WithError(err).WithField("type", e.Type).Warn("保存事件失败")
+	}
+}
+
+// syncStoreBans 使存储中的封禁记录与当前的封禁状态一致
+// 与saveBlacklist一起调用，只写入有变化的记录；配置中的永久封禁IP同样写入，供其他服务器和工具读取
+// 尚未完成的封禁意图不在当前状态中，会被删除，因此需要在recoverBans之后调用
+// 调用方需持有m.mu锁
+// 返回:
+//   - map[string]store.Ban: 同步后各IP的封禁记录，包括封禁原因等存储中已有的信息
+//   - error: 读取或写入存储失败时的错误信息
+func (m *Monitor) syncStoreBans() (map[string]store.Ban, error) {
+	want := make(map[string]store.Ban, len(m.bannedIPs)+len(m.permanentIPs)+len(m.limitedIPs))
+	for ip, expires := range m.bannedIPs {
+		want[ip] = store.Ban{IP: ip, Type: banTypeTemporary, ExpiresAt: expires, State: store.StateApplied}
+	}
+	for ip, expires := range m.limitedIPs {
+		want[ip] = store.Ban{IP: ip, Type: banTypeLimited, ExpiresAt: expires, State: store.StateApplied}
+	}
+	for ip := range m.permanentIPs {
+		want[ip] = store.Ban{IP: ip, Type: banTypePermanent, State: store.StateApplied}
+	}
+
+	current, err := m.store.Bans()
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]store.Ban, len(want))
+	for _, ban := range current {
+		wanted, ok := want[ban.IP]
+		if !ok {
+			if err := m.store.DeleteBan(ban.IP); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if wanted.Type == ban.Type && wanted.ExpiresAt.Equal(ban.ExpiresAt) && ban.State != store.StatePending {
+			delete(want, ban.IP)
+			records[ban.IP] = ban
+			continue
+		}
+		wanted.CopySource(ban)
+		want[ban.IP] = wanted
+	}
+	for _, ban := range want {
+		if err := m.store.PutBan(ban); err != nil {
+			return nil, err
+		}
+		records[ban.IP] = ban
+	}
+	return records, nil
+}
@@ -0,0 +1,215 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/event"
+	"github.com/yourusername/ssh_fb/internal/notification"
+	"github.com/yourusername/ssh_fb/pkg/ipinfo"
+)
+
+// subnetOf 返回IP所在的汇总网段
+// 参数:
+//   - ip: IP地址
+// 返回:
+//   - *net.IPNet: 按配置前缀长度截取的网段，IP无效时为nil
+func (m *Monitor) subnetOf(ip string) *net.IPNet {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+	cfg := m.config.SSHProtection.SubnetAggregation
+	if v4 := parsed.To4(); v4 != nil {
+		mask := net.CIDRMask(cfg.PrefixLength, 32)
+		return &net.IPNet{IP: v4.Mask(mask), Mask: mask}
+	}
+	mask := net.CIDRMask(cfg.IPv6PrefixLength, 128)
+	return &net.IPNet{IP: parsed.Mask(mask), Mask: mask}
+}
+
+// recordSubnetFailure 按网段和ASN汇总登录失败
+// 单个IP未达到封禁阈值、但同一网段或ASN的失败次数过多时发出告警
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 登录失败的IP地址
+//   - info: IP信息，查询失败时为nil，此时不按ASN汇总
+//   - login: 登录失败事件，封禁网段时用于计算解封时间和封禁生效耗时
+func (m *Monitor) recordSubnetFailure(ip string, info *ipinfo.IPInfo, login LoginEvent) {
+	cfg := m.config.SSHProtection.SubnetAggregation
+	if !cfg.Enabled {
+		return
+	}
+
+	if network := m.subnetOf(ip); network != nil {
+		m.recordAggregate(network.String(), ip, network, login)
+	}
+	if cfg.ASNThreshold > 0 && info != nil && info.ASN != "" {
+		m.recordAggregate(info.ASN, ip, nil, login)
+	}
+}
+
+// recordAggregate 记录一次汇总维度上的失败，达到阈值时告警，每轮攻击只告警一次
+// 调用方需持有m.mu锁
+// 参数:
+//   - source: 汇总来源，网段CIDR或ASN
+//   - ip: 登录失败的IP地址
+//   - network: 来源为网段时的网段，来源为ASN时为nil
+//   - login: 登录失败事件
+func (m *Monitor) recordAggregate(source, ip string, network *net.IPNet, login LoginEvent) {
+	cfg := m.config.SSHProtection.SubnetAggregation
+	now := login.Timestamp
+	m.subnetFailures[source] = append(m.subnetFailures[source], failureRecord{ip: ip, time: now})
+	records, ips := m.pruneAggregate(source, now)
+	if len(records) < m.aggregateThreshold(source) || m.subnetAlerted[source] {
+		return
+	}
+	m.subnetAlerted[source] = true
+
+	action := "仅告警"
+	if network != nil && cfg.BanSubnet {
+		if err := m.banSubnet(network, login); err != nil {
+			m.logger.WithError(err).WithField("subnet", source).Error("封禁网段失败")
+			action = fmt.Sprintf("封禁网段失败: %v", err)
+		} else {
+			action = "已封禁网段"
+		}
+	}
+
+	m.logger.WithFields(logrus.Fields{
+		"source":   source,
+		"failures": len(records),
+		"ips":      len(ips),
+		"window":   cfg.Window.String(),
+	}).Warn("检测到分布式攻击")
+	m.events.Publish(event.Event{
+		Time:    now,
+		Type:    event.TypeSubnetAttack,
+		IP:      source,
+		Message: fmt.Sprintf("%d个IP共失败%d次，%s", len(ips), len(records), action),
+	})
+
+	window := time.Duration(cfg.Window)
+	m.notifier.Notify(notification.SubnetAttackEvent(source, len(records), len(ips), window, action, m.serverName()))
+}
+
+// pruneAggregate 清理汇总来源在窗口外的失败记录
+// 失败次数回落到阈值以下时允许再次告警
+// 调用方需持有m.mu锁
+// 返回:
+//   - []failureRecord: 窗口内的失败记录
+//   - []string: 窗口内失败的不同IP
+func (m *Monitor) pruneAggregate(source string, now time.Time) ([]failureRecord, []string) {
+	window := time.Duration(m.config.SSHProtection.SubnetAggregation.Window)
+	kept, ips := pruneRecords(m.subnetFailures[source], now.Add(-window))
+	if len(kept) == 0 {
+		delete(m.subnetFailures, source)
+	} else {
+		m.subnetFailures[source] = kept
+	}
+	if len(kept) < m.aggregateThreshold(source) {
+		delete(m.subnetAlerted, source)
+	}
+	return kept, ips
+}
+
+// pruneAggregates 清理所有汇总来源的过期失败记录
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneAggregates() {
+	now := time.Now()
+	for source := range m.subnetFailures {
+		m.pruneAggregate(source, now)
+	}
+}
+
+// aggregateThreshold 返回汇总来源的告警阈值，网段与ASN分别配置
+func (m *Monitor) aggregateThreshold(source string) int {
+	cfg := m.config.SSHProtection.SubnetAggregation
+	if strings.Contains(source, "/") {
+		return cfg.SubnetThreshold
+	}
+	return cfg.ASNThreshold
+}
+
+// banSubnet 封禁整个网段，封禁时长与单个IP相同
+// 网段封禁与IP封禁保存在同一个黑名单中，可通过unban按CIDR解除；
+// 网段中有代理地址或临时信任的IP时不封禁，之后与IP封禁一样经过banIP记录、同步和通知
+// 调用方需持有m.mu锁
+// 参数:
+//   - network: 要封禁的网段
+//   - login: 触发封禁的登录失败事件，解封时间从其日志时间起算
+// 返回:
+//   - error: 网段与白名单重叠、包含代理地址或临时信任的IP，或防火墙操作失败时的错误信息
+func (m *Monitor) banSubnet(network *net.IPNet, login LoginEvent) error {
+	cidr := network.String()
+	if m.whitelist.overlaps(network) {
+		return fmt.Errorf("网段 %s 与白名单重叠", cidr)
+	}
+	if proxy := m.proxyIn(network); proxy != "" {
+		return fmt.Errorf("网段 %s 包含负载均衡或代理地址 %s", cidr, proxy)
+	}
+	if trusted := m.trustedIn(network, login.Timestamp); trusted != "" {
+		return fmt.Errorf("网段 %s 包含临时信任的IP %s", cidr, trusted)
+	}
+	if _, banned := m.bannedIPs[cidr]; banned || m.permanentIPs[cidr] {
+		return nil
+	}
+	return m.banIP(cidr, login.Timestamp, login.Observed, login.Span, m.banDuration(), "分布式攻击", SourceLog)
+}
+
+// trackSubnet 封禁对象为网段时记录其解析结果，供inBannedSubnet匹配
+// 调用方需持有m.mu锁
+// 参数:
+//   - key: 封禁的IP地址或CIDR
+func (m *Monitor) trackSubnet(key string) {
+	if !strings.Contains(key, "/") {
+		return
+	}
+	if _, network, err := net.ParseCIDR(key); err == nil {
+		m.bannedSubnets[key] = network
+	}
+}
+
+// subnetBanned 检查网段的封禁是否仍然有效
+// 调用方需持有m.mu锁
+func (m *Monitor) subnetBanned(cidr string, now time.Time) bool {
+	if m.permanentIPs[cidr] {
+		return true
+	}
+	expire, banned := m.bannedIPs[cidr]
+	return banned && now.Before(expire)
+}
+
+// inBannedSubnet 检查IP是否属于已封禁的网段
+// 只遍历预先解析的封禁网段，不逐个解析封禁表中的条目
+// 调用方需持有m.mu锁
+func (m *Monitor) inBannedSubnet(ip string) bool {
+	if len(m.bannedSubnets) == 0 {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	now := time.Now()
+	for cidr, network := range m.bannedSubnets {
+		if network.Contains(parsed) && m.subnetBanned(cidr, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneBannedSubnets 清理已解除封禁的网段解析结果
+// 调用方需持有m.mu锁
+func (m *Monitor) pruneBannedSubnets() {
+	now := time.Now()
+	for cidr := range m.bannedSubnets {
+		if !m.subnetBanned(cidr, now) {
+			delete(m.bannedSubnets, cidr)
+		}
+	}
+}
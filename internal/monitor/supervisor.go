@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// 每个jail待处理日志行的队列长度，队列满时只阻塞该jail的日志读取
+const jailQueueSize = 1024
+
+// jail日志读取中断后重启的等待时间，连续失败时按倍数增加到上限
+const (
+	jailRestartMin = time.Second
+	jailRestartMax = time.Minute
+)
+
+// logLine 已读取、待处理的一行日志
+type logLine struct {
+	text     string    // 日志内容
+	observed time.Time // 读取到该行的时间，用于统计封禁生效耗时
+}
+
+// jailRunner 单个jail的日志读取协程与处理协程池
+// 每个jail独立读取自己的日志来源，某个jail的日志阻塞或读取失败不会影响其他jail
+type jailRunner struct {
+	name    string             // jail名称
+	source  string             // 日志文件路径或容器日志来源
+	workers int                // 处理协程数
+	queue   chan logLine       // 已读取、待处理的日志行
+	handle  func(line logLine) // 处理一行日志
+
+	lines    atomic.Int64 // 已读取的行数
+	lastRead atomic.Int64 // 最近一次读取到日志行的时间（UnixNano）
+	restarts atomic.Int64 // 日志读取中断后重启的次数
+	panics   atomic.Int64 // 处理日志时发生panic的次数
+
+	mu        sync.Mutex
+	running   bool   // 日志读取协程是否正在运行
+	lastError string // 最近一次读取中断或panic的原因
+}
+
+// newJailRunner 创建jail并启动处理协程，日志读取由superviseJail启动
+// 参数:
+//   - name: jail名称
+//   - source: 日志文件路径或容器日志来源
+//   - workers: 处理协程数，需要保持日志顺序时为1
+//   - handle: 处理一行日志
+// 返回:
+//   - *jailRunner: 已启动处理协程的jail
+func (m *Monitor) newJailRunner(name, source string, workers int, handle func(line logLine)) *jailRunner {
+	r := &jailRunner{
+		name:    name,
+		source:  source,
+		workers: max(workers, 1),
+		queue:   make(chan logLine, jailQueueSize),
+		handle:  handle,
+	}
+	m.mu.Lock()
+	m.jailRunners[name] = r
+	m.mu.Unlock()
+
+	for i := 0; i < r.workers; i++ {
+		go func() {
+			for line := range r.queue {
+				m.processLine(r, line)
+			}
+		}()
+	}
+	return r
+}
+
+// processLine 处理一行日志，处理过程中的panic只影响这一行
+func (m *Monitor) processLine(r *jailRunner, line logLine) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.panics.Add(1)
+			r.setError(fmt.Sprintf("处理日志时发生panic: %v", p))
+			m.logger.WithFields(logrus.Fields{"jail": r.name, "panic": p}).Error("处理日志时发生panic，已跳过该行")
+		}
+	}()
+	r.handle(line)
+}
+
+// superviseJail 持续读取jail的日志来源，读取中断或panic后等待一段时间重新读取
+// 该函数不会返回，sshd jail在Start中直接调用，其他jail在单独的协程中调用
+// 参数:
+//   - r: 由newJailRunner创建的jail
+func (m *Monitor) superviseJail(r *jailRunner) {
+	backoff := jailRestartMin
+	for {
+		started := time.Now()
+		r.setRunning(true)
+		m.logger.WithFields(logrus.Fields{"jail": r.name, "log_file": r.source}).Info("jail日志监控已启动")
+		err := m.runTail(r)
+		r.setRunning(false)
+		r.setError(err.Error())
+
+		// 运行较长时间后才中断的视为偶发故障，重新从最短的等待时间开始
+		if time.Since(started) > jailRestartMax {
+			backoff = jailRestartMin
+		}
+		m.logger.WithError(err).WithFields(logrus.Fields{"jail": r.name, "retry_in": backoff}).Error("jail日志监控已停止，稍后重启")
+		time.Sleep(backoff)
+		backoff = min(backoff*2, jailRestartMax)
+		r.restarts.Add(1)
+	}
+}
+
+// runTail 读取一次日志来源直到出错，panic转换为错误返回
+func (m *Monitor) runTail(r *jailRunner) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.panics.Add(1)
+			err = fmt.Errorf("读取日志时发生panic: %v", p)
+		}
+	}()
+	err = m.tailLog(r.source, func(line string) {
+		now := time.Now()
+		r.lines.Add(1)
+		r.lastRead.Store(now.UnixNano())
+		r.queue <- logLine{text: line, observed: now}
+	})
+	if err == nil {
+		err = fmt.Errorf("日志读取意外结束")
+	}
+	return err
+}
+
+func (r *jailRunner) setRunning(running bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.running = running
+}
+
+func (r *jailRunner) setError(msg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastError = msg
+}
+
+// fill 将运行统计写入jail信息
+func (r *jailRunner) fill(info *control.JailInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info.Running = r.running
+	info.Workers = r.workers
+	info.Lines = r.lines.Load()
+	if last := r.lastRead.Load(); last > 0 {
+		info.LastRead = time.Unix(0, last)
+	}
+	info.Queue = len(r.queue)
+	info.Restarts = r.restarts.Load()
+	info.Panics = r.panics.Load()
+	info.LastError = r.lastError
+}
@@ -0,0 +1,53 @@
+package monitor
+
+// blockIP 按配置的封禁方式在防火墙中拦截IP或网段
+// 启用tarpit时只把到达sshd端口的连接重定向到本地tarpit，否则直接丢弃该来源的所有流量
+// 参数:
+//   - ip: 要拦截的IP地址或CIDR网段
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) blockIP(ip string) error {
+	if tarpit := m.config.SSHProtection.Tarpit; tarpit.Enabled {
+		return m.firewall.RedirectIP(ip, m.config.SSHProtection.SSHPort, tarpit.Port)
+	}
+	return m.firewall.BanIP(ip)
+}
+
+// unblockIP 删除blockIP添加的防火墙规则
+// 参数:
+//   - ip: 要放行的IP地址或CIDR网段
+// 返回:
+//   - error: 防火墙操作失败时的错误信息
+func (m *Monitor) unblockIP(ip string) error {
+	if tarpit := m.config.SSHProtection.Tarpit; tarpit.Enabled {
+		return m.firewall.UnredirectIP(ip, m.config.SSHProtection.SSHPort, tarpit.Port)
+	}
+	return m.firewall.UnbanIP(ip)
+}
+
+// restoreTarpit 启动时为黑名单中的IP重新添加重定向规则
+// ufw的deny规则会持久保存，而iptables的nat规则在重启后丢失，需要按黑名单恢复
+// 调用方需持有m.mu锁
+func (m *Monitor) restoreTarpit() {
+	if !m.config.SSHProtection.Tarpit.Enabled {
+		return
+	}
+	if !m.firewall.SupportsRedirect() {
+		m.logger.Error("未找到iptables，tarpit封禁方式不可用")
+		return
+	}
+
+	restore := func(ip string) {
+		if err := m.blockIP(ip); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("恢复tarpit重定向失败")
+		}
+	}
+	for ip := range m.bannedIPs {
+		restore(ip)
+	}
+	for ip := range m.permanentIPs {
+		if !m.isConfigPermanent(ip) {
+			restore(ip)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package monitor
+
+import (
+	"regexp"
+	"time"
+)
+
+// 日志行开头的时间戳格式
+var (
+	// rsyslog高精度格式或 journalctl -o short-iso，如 2024-10-14T09:00:01.123456+08:00
+	isoTimePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2}))`)
+	// 传统syslog格式，不含年份和时区，如 Oct 14 09:00:01
+	syslogTimePattern = regexp.MustCompile(`^([A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2})`)
+)
+
+// parseLogTime 解析日志行开头的时间戳
+// 传统syslog格式没有年份，按本地时区取离now最近的年份，避免跨年时被算到将来
+// 参数:
+//   - line: 日志行
+//   - now: 当前时间，无法识别时间戳时原样返回
+// 返回:
+//   - time.Time: 日志行中记录的时间
+func parseLogTime(line string, now time.Time) time.Time {
+	if matches := isoTimePattern.FindStringSubmatch(line); matches != nil {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999-0700"} {
+			if t, err := time.Parse(layout, matches[1]); err == nil {
+				return t
+			}
+		}
+	}
+
+	if matches := syslogTimePattern.FindStringSubmatch(line); matches != nil {
+		t, err := time.ParseInLocation("Jan _2 15:04:05", matches[1], now.Location())
+		if err == nil {
+			t = t.AddDate(now.Year(), 0, 0)
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t
+		}
+	}
+
+	return now
+}
@@ -0,0 +1,243 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/store"
+	"github.com/yourusername/ssh_fb/pkg/firewall"
+)
+
+// 封禁状态检查发现的不一致类型
+const (
+	IssueStoreMissing    = "store_missing"    // 黑名单中有，存储中没有记录或类型不同
+	IssueStoreExtra      = "store_extra"      // 存储中有，黑名单中没有
+	IssueStorePending    = "store_pending"    // 存储中有未完成的封禁意图
+	IssueFirewallMissing = "firewall_missing" // 黑名单中有，防火墙中没有对应的规则
+	IssueFirewallExtra   = "firewall_extra"   // ufw中有deny规则，黑名单中没有
+)
+
+// VerifyOptions 封禁状态检查的修复选项
+type VerifyOptions struct {
+	Repair bool // 按黑名单修复存储记录并补上缺失的防火墙规则
+	Prune  bool // 同时删除黑名单中没有的ufw deny规则，需要与Repair一起使用
+}
+
+// VerifyIssue 一处不一致
+type VerifyIssue struct {
+	Kind     string `json:"kind"`            // 不一致类型，取值见Issue*常量
+	IP       string `json:"ip"`              // 相关的IP或网段
+	Detail   string `json:"detail"`          // 可读的说明
+	Repaired bool   `json:"repaired"`        // 是否已修复
+	Error    string `json:"error,omitempty"` // 修复失败的原因
+}
+
+// VerifyReport 封禁状态检查报告
+type VerifyReport struct {
+	Blacklist int           `json:"blacklist"` // 黑名单文件与配置中的封禁数
+	Store     int           `json:"store"`     // 存储中的封禁记录数
+	Firewall  int           `json:"firewall"`  // ufw中deny from规则的数量
+	Issues    []VerifyIssue `json:"issues"`    // 发现的不一致，按IP排序
+}
+
+// Unrepaired 返回未修复的不一致数量
+func (r VerifyReport) Unrepaired() int {
+	n := 0
+	for _, issue := range r.Issues {
+		if !issue.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+// VerifyBans 交叉检查黑名单文件、存储与防火墙规则，不依赖运行中的守护进程
+// 黑名单文件是守护进程启动时加载的状态，修复时以它为准；ufw中的其他deny规则可能由管理员手动添加，只有Prune时才删除
+// 参数:
+//   - cfg: 配置信息
+//   - opts: 修复选项
+// 返回:
+//   - VerifyReport: 检查报告
+//   - error: 无法读取黑名单、存储或防火墙规则时的错误信息
+func VerifyBans(cfg *config.Config, opts VerifyOptions) (VerifyReport, error) {
+	var report VerifyReport
+
+	file, err := os.Open(cfg.Blacklist.File)
+	if err != nil && !os.IsNotExist(err) {
+		return report, fmt.Errorf("读取黑名单失败: %w", err)
+	}
+	want := make(map[string]blacklistEntry)
+	if file != nil {
+		want, err = parseBlacklist(file)
+		file.Close()
+		if err != nil {
+			return report, fmt.Errorf("读取黑名单失败: %w", err)
+		}
+	}
+	for _, ip := range cfg.Blacklist.Permanent {
+		want[ip] = blacklistEntry{IP: ip, Type: banTypePermanent}
+	}
+	report.Blacklist = len(want)
+
+	st, err := store.Open(cfg.Store)
+	if err != nil {
+		return report, fmt.Errorf("打开存储失败: %w", err)
+	}
+	defer st.Close()
+
+	m := &Monitor{config: cfg, firewall: firewall.NewUFW(), store: st}
+	if err := m.verifyStore(want, &report, opts); err != nil {
+		return report, err
+	}
+	if err := m.verifyFirewall(want, &report, opts); err != nil {
+		return report, err
+	}
+
+	sort.Slice(report.Issues, func(i, j int) bool {
+		if report.Issues[i].IP != report.Issues[j].IP {
+			return report.Issues[i].IP < report.Issues[j].IP
+		}
+		return report.Issues[i].Kind < report.Issues[j].Kind
+	})
+	return report, nil
+}
+
+// verifyStore 比较存储中的封禁记录与黑名单
+// memory存储只存在于守护进程内存中，不参与比较
+func (m *Monitor) verifyStore(want map[string]blacklistEntry, report *VerifyReport, opts VerifyOptions) error {
+	if m.config.Store.Driver == store.DriverMemory {
+		return nil
+	}
+	bans, err := m.store.Bans()
+	if err != nil {
+		return fmt.Errorf("读取存储中的封禁记录失败: %w", err)
+	}
+	report.Store = len(bans)
+
+	stored := make(map[string]store.Ban, len(bans))
+	for _, ban := range bans {
+		stored[ban.IP] = ban
+		if ban.State == store.StatePending {
+			report.Issues = append(report.Issues, VerifyIssue{Kind: IssueStorePending, IP: ban.IP,
+				Detail: "存储中有未完成的封禁，守护进程启动时会重新执行"})
+			continue
+		}
+		if _, ok := want[ban.IP]; ok {
+			continue
+		}
+		issue := VerifyIssue{Kind: IssueStoreExtra, IP: ban.IP, Detail: fmt.Sprintf("存储中有%s记录，黑名单中没有", ban.Type)}
+		if opts.Repair {
+			issue.record(m.store.DeleteBan(ban.IP))
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	for ip, entry := range want {
+		ban, ok := stored[ip]
+		if ok && (ban.Type == entry.Type || ban.State == store.StatePending) {
+			continue
+		}
+		issue := VerifyIssue{Kind: IssueStoreMissing, IP: ip, Detail: fmt.Sprintf("黑名单中为%s，存储中没有记录", entry.Type)}
+		if ok {
+			issue.Detail = fmt.Sprintf("黑名单中为%s，存储中为%s", entry.Type, ban.Type)
+		}
+		if opts.Repair {
+			expires := entry.ExpiresAt
+			if expires.IsZero() {
+				expires = ban.ExpiresAt
+			}
+			repaired := m.storeBan(ip, entry.Type, expires)
+			repaired.CopySource(ban)
+			if !ok {
+				repaired.Reason = entry.Reason
+			}
+			issue.record(m.store.PutBan(repaired))
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	return nil
+}
+
+// storeBan 按黑名单中的类型创建存储记录，黑名单和存储中都没有到期时间时按配置的封禁时长从现在起计算
+func (m *Monitor) storeBan(ip, banType string, expires time.Time) store.Ban {
+	ban := store.Ban{IP: ip, Type: banType, State: store.StateApplied}
+	switch {
+	case banType == banTypePermanent:
+	case !expires.IsZero():
+		ban.ExpiresAt = expires
+	case banType == banTypeLimited:
+		ban.ExpiresAt = time.Now().Add(time.Duration(m.config.SSHProtection.RateLimit.Duration))
+	default:
+		ban.ExpiresAt = time.Now().Add(m.banDuration())
+	}
+	return ban
+}
+
+// verifyFirewall 检查黑名单中的每个IP都有对应的防火墙规则
+// 临时和永久封禁对应ufw的deny规则（tarpit模式下为iptables的重定向规则），限速对应iptables的hashlimit规则
+func (m *Monitor) verifyFirewall(want map[string]blacklistEntry, report *VerifyReport, opts VerifyOptions) error {
+	denied, err := m.firewall.DeniedIPs()
+	if err != nil {
+		return err
+	}
+	report.Firewall = len(denied)
+	deniedSet := make(map[string]bool, len(denied))
+	for _, ip := range denied {
+		deniedSet[ip] = true
+	}
+
+	protection := m.config.SSHProtection
+	for ip, entry := range want {
+		banType := entry.Type
+		var present bool
+		var rule string
+		switch {
+		case banType == banTypeLimited:
+			present = m.firewall.HasLimit(ip, protection.SSHPort, protection.RateLimit.ConnectionsPerMinute)
+			rule = "限速规则"
+		case protection.Tarpit.Enabled:
+			present = m.firewall.HasRedirect(ip, protection.SSHPort, protection.Tarpit.Port)
+			rule = "tarpit重定向规则"
+		default:
+			present = deniedSet[ip]
+			rule = "ufw deny规则"
+		}
+		if present {
+			continue
+		}
+		issue := VerifyIssue{Kind: IssueFirewallMissing, IP: ip, Detail: fmt.Sprintf("黑名单中为%s，防火墙中没有%s", banType, rule)}
+		if opts.Repair {
+			if banType == banTypeLimited {
+				issue.record(m.firewall.LimitIP(ip, protection.SSHPort, protection.RateLimit.ConnectionsPerMinute))
+			} else {
+				issue.record(m.blockIP(ip))
+			}
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	// tarpit模式下黑名单中的IP不使用ufw规则，ufw中的deny规则都不是本程序添加的
+	for _, ip := range denied {
+		if _, ok := want[ip]; ok && !protection.Tarpit.Enabled {
+			continue
+		}
+		issue := VerifyIssue{Kind: IssueFirewallExtra, IP: ip, Detail: "ufw中有deny规则，黑名单中没有，可能是手动添加的规则或解封时未删除的规则"}
+		if opts.Repair && opts.Prune {
+			issue.record(m.firewall.UnbanIP(ip))
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+	return nil
+}
+
+// record 记录修复结果
+func (i *VerifyIssue) record(err error) {
+	if err != nil {
+		i.Error = err.Error()
+		return
+	}
+	i.Repaired = true
+}
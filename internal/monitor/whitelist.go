@@ -0,0 +1,284 @@
+package monitor
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// whitelist 保存白名单网段
+// 单个IP按/32或/128网段保存
+type whitelist struct {
+	networks  []*net.IPNet     // 配置文件中的条目
+	runtime   []*net.IPNet     // 运行时添加的条目，持久化到白名单文件
+	temporary []temporaryAllow // 有有效期的条目，保存在存储中
+}
+
+// contains 检查IP是否在白名单中
+func (w *whitelist) contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, list := range [][]*net.IPNet{w.networks, w.runtime} {
+		for _, network := range list {
+			if network.Contains(parsed) {
+				return true
+			}
+		}
+	}
+	now := time.Now()
+	for _, allow := range w.temporary {
+		if allow.network.Contains(parsed) && now.Before(allow.ExpiresAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps 检查网段是否与任一白名单条目重叠
+func (w *whitelist) overlaps(network *net.IPNet) bool {
+	for _, list := range [][]*net.IPNet{w.networks, w.runtime} {
+		for _, entry := range list {
+			if entry.Contains(network.IP) || network.Contains(entry.IP) {
+				return true
+			}
+		}
+	}
+	for _, allow := range w.temporary {
+		if allow.network.Contains(network.IP) || network.Contains(allow.network.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadWhitelist 加载配置文件和白名单文件中的条目
+// 返回:
+//   - error: 加载过程中的错误信息
+func (m *Monitor) loadWhitelist() error {
+	for _, entry := range m.config.Whitelist.Entries {
+		network, err := config.ParseNetwork(entry)
+		if err != nil {
+			return err
+		}
+		m.whitelist.networks = append(m.whitelist.networks, network)
+	}
+
+	file, err := os.OpenFile(m.config.Whitelist.File, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		network, err := config.ParseNetwork(line)
+		if err != nil {
+			m.logger.WithError(err).Warn("忽略无效的白名单条目")
+			continue
+		}
+		m.whitelist.runtime = append(m.whitelist.runtime, network)
+	}
+	return scanner.Err()
+}
+
+// saveWhitelist 保存运行时添加的白名单条目
+// 调用方需持有m.mu锁
+func (m *Monitor) saveWhitelist() error {
+	file, err := os.OpenFile(m.config.Whitelist.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, network := range m.whitelist.runtime {
+		if _, err := fmt.Fprintln(file, network.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseWhitelisted 解除白名单来源的已有封禁
+// 白名单在封禁之后才添加时，确保这些来源不再被拦截
+// 调用方需持有m.mu锁
+func (m *Monitor) releaseWhitelisted() {
+	changed := false
+	for ip := range m.bannedIPs {
+		if !m.whitelist.contains(ip) {
+			continue
+		}
+		if err := m.unblockIP(ip); err != nil {
+			m.logger.WithError(err).WithField("ip", ip).Error("解除白名单IP封禁失败")
+			continue
+		}
+		delete(m.bannedIPs, ip)
+		m.failedAttempts.remove(ip)
+		changed = true
+		m.logger.WithField("ip", ip).Info("白名单IP已解除封禁")
+		m.audit(auditUnban, ip, SourceWhitelist, "来源在白名单中")
+	}
+	for ip := range m.limitedIPs {
+		if m.whitelist.contains(ip) {
+			m.liftLimit(ip)
+			changed = true
+			m.audit(auditUnban, ip, SourceWhitelist, "来源在白名单中，解除限速")
+		}
+	}
+	if changed {
+		m.saveBlacklist()
+	}
+}
+
+// AddWhitelist 运行时添加白名单条目并持久化
+// 已被临时封禁的匹配来源会立即解除封禁
+// 参数:
+//   - entry: IP或CIDR网段
+// 返回:
+//   - error: 操作过程中的错误信息
+func (m *Monitor) AddWhitelist(entry string) error {
+	return m.addWhitelist(entry, SourceControl)
+}
+
+// addWhitelist 添加白名单条目，source为审计记录中的触发来源
+func (m *Monitor) addWhitelist(entry, source string) error {
+	network, err := config.ParseNetwork(entry)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range append(m.whitelist.networks, m.whitelist.runtime...) {
+		if existing.String() == network.String() {
+			return nil
+		}
+	}
+
+	m.whitelist.runtime = append(m.whitelist.runtime, network)
+	if err := m.saveWhitelist(); err != nil {
+		return fmt.Errorf("保存白名单失败: %v", err)
+	}
+	m.audit(auditWhitelistAdd, network.String(), source, "")
+	m.releaseWhitelisted()
+	m.reapplyFeeds()
+
+	m.logger.WithField("entry", network.String()).Info("已添加白名单")
+	return nil
+}
+
+// RemoveWhitelist 删除运行时添加的白名单条目或临时白名单
+// 配置文件中的条目无法删除；删除后该来源重新开始计数，已有的失败记录不会恢复
+// 参数:
+//   - entry: IP或CIDR网段，与添加时的写法无关，按网段比较
+// 返回:
+//   - error: 条目无效、不存在或保存失败时的错误信息
+func (m *Monitor) RemoveWhitelist(entry string) error {
+	return m.removeWhitelist(entry, SourceControl)
+}
+
+// removeWhitelist 删除白名单条目，source为审计记录中的触发来源
+func (m *Monitor) removeWhitelist(entry, source string) error {
+	network, err := config.ParseNetwork(entry)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.whitelist.networks {
+		if existing.String() == network.String() {
+			return fmt.Errorf("%s 在配置文件的白名单中，请修改配置后重启", entry)
+		}
+	}
+
+	removed := false
+	for i, existing := range m.whitelist.runtime {
+		if existing.String() == network.String() {
+			m.whitelist.runtime = append(m.whitelist.runtime[:i], m.whitelist.runtime[i+1:]...)
+			if err := m.saveWhitelist(); err != nil {
+				return fmt.Errorf("保存白名单失败: %v", err)
+			}
+			removed = true
+			break
+		}
+	}
+	for i, allow := range m.whitelist.temporary {
+		if allow.network.String() == network.String() {
+			if err := m.store.DeleteAllow(allow.Entry); err != nil {
+				return fmt.Errorf("删除临时白名单失败: %v", err)
+			}
+			m.whitelist.temporary = append(m.whitelist.temporary[:i], m.whitelist.temporary[i+1:]...)
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		return fmt.Errorf("%s 不在白名单中", entry)
+	}
+	m.audit(auditWhitelistRemove, network.String(), source, "")
+	m.reapplyFeeds()
+
+	m.logger.WithField("entry", network.String()).Info("已删除白名单")
+	return nil
+}
+
+// trust 登录成功后临时信任IP，期间的登录失败不计数
+// 调用方需持有m.mu锁
+// 参数:
+//   - ip: 登录成功的IP地址
+func (m *Monitor) trust(ip string, at time.Time) {
+	ttl := time.Duration(m.config.Whitelist.TrustAfterLogin)
+	if ttl <= 0 {
+		return
+	}
+	m.trustedIPs[ip] = at.Add(ttl)
+	m.failedAttempts.remove(ip)
+	m.logger.WithFields(logrus.Fields{
+		"ip":     ip,
+		"expire": m.trustedIPs[ip].Format("2006-01-02 15:04:05"),
+	}).Info("登录成功，临时信任该IP")
+}
+
+// isTrusted 检查IP是否处于登录后的临时信任期，过期条目会被删除
+// 调用方需持有m.mu锁
+func (m *Monitor) isTrusted(ip string, at time.Time) bool {
+	expire, ok := m.trustedIPs[ip]
+	if !ok {
+		return false
+	}
+	if at.Before(expire) {
+		return true
+	}
+	delete(m.trustedIPs, ip)
+	return false
+}
+
+// trustedIn 返回网段中处于临时信任期的IP，用于封禁网段前检查
+// 调用方需持有m.mu锁
+// 参数:
+//   - network: 要封禁的网段
+//   - at: 触发封禁的时间
+// 返回:
+//   - string: 网段中的一个临时信任的IP，没有时为空
+func (m *Monitor) trustedIn(network *net.IPNet, at time.Time) string {
+	for ip, expire := range m.trustedIPs {
+		if parsed := net.ParseIP(ip); parsed != nil && network.Contains(parsed) && at.Before(expire) {
+			return ip
+		}
+	}
+	return ""
+}
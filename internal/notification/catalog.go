@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 内置通知模板的语言
+const (
+	LocaleZH = "zh" // 中文，内置模板即配置中的默认模板
+	LocaleEN = "en" // 英文
+)
+
+// defaultNotifications 默认的通知配置，其中的模板即中文内置模板
+var defaultNotifications = config.Default().Notifications
+
+// messageCatalog 中文以外各语言的内置模板，键为事件类型
+// 可用的字段与中文模板相同；属地信息、封禁原因等由检测逻辑生成的字段值仍为中文
+var messageCatalog = map[string]map[string]string{
+	LocaleEN: {
+		EventLoginSuccess:   "✅ SSH login succeeded\nTime: {{.Time}}\n{{.IPInfo}}\n{{if .User}}User: {{.User}}\n{{end}}{{if .Client}}Client: {{.Client}}\n{{end}}Server: {{.Server}}",
+		EventLoginFailed:    "⚠️ SSH login failed\nTime: {{.Time}}\n{{.IPInfo}}\n{{if .User}}User: {{.User}}\n{{end}}{{if .Client}}Client: {{.Client}}\n{{end}}Attempts: {{.Attempts}}/{{.MaxAttempts}}\nServer: {{.Server}}",
+		EventIPBanned:       "🚫 IP {{.IP}} banned\nTime: {{.Time}}\n{{.IPInfo}}\nReason: {{.Reason}}\nDuration: {{.DurationText}}\nExpires: {{.ExpireTime}}\nServer: {{.Server}}",
+		EventPasswordSpray:  "🎯 Password spraying detected\nTime: {{.Time}}\nUser: {{.User}}\nSource IPs: {{.Count}} (within {{.Window}} min)\nIPs: {{.IPs}}\nServer: {{.Server}}",
+		EventSubnetAttack:   "🌐 Distributed attack detected\nTime: {{.Time}}\nSource: {{.Source}}\nFailures: {{.Failures}} ({{.IPs}} IPs within {{.Window}} min)\nAction: {{.Action}}\nServer: {{.Server}}",
+		EventRootLogin:      "🚨 root login {{if .Attempts}}failed{{else}}succeeded{{end}}\nTime: {{.Time}}\n{{.IPInfo}}\n{{if .Client}}Client: {{.Client}}\n{{end}}{{if .Attempts}}Attempts: {{.Attempts}}/{{.MaxAttempts}}\n{{end}}Server: {{.Server}}",
+		EventRuleMatched:    "🔔 Rule {{.Rule}} triggered\nTime: {{.Time}}\n{{.IPInfo}}\nMatches: {{.Count}} (within {{.Window}} min)\nServer: {{.Server}}",
+		EventIPUnbanned:     "🔓 IP {{.IP}} unbanned\nTime: {{.Time}}\n{{.IPInfo}}\nReason: {{.Reason}}\nServer: {{.Server}}",
+		EventNewLocation:    "🚨 [HIGH] {{.User}} logged in from a new location\nTime: {{.Time}}\n{{.IPInfo}}\nCountry: {{.Country}}\nASN: {{.ASN}}\nServer: {{.Server}}",
+		EventLogout:         "👋 {{.User}} logged out\nTime: {{.Time}}\nIP: {{.IP}}\nLogin time: {{.LoginTime}}\nSession: {{.Duration}}\nServer: {{.Server}}",
+		EventAdaptiveReport: "📊 Adaptive country thresholds updated\nTime: {{.Time}}\nFailures in period: {{.Total}}\nTop sources: {{.Countries}}\nStrict threshold ({{.Threshold}}) countries: {{.Strict}}\nServer: {{.Server}}{{if .Advice}}\nsshd hardening advice:\n{{.Advice}}{{end}}",
+		EventUnbanDigest:    "📋 Auto-unban digest for {{.Date}}\nUnbanned: {{.Count}}\nIPs: {{.IPs}}\nServer: {{.Server}}",
+		EventSummary:        "📊 Attack summary\nPeriod: {{.Start}} to {{.End}}\nFailed logins: {{.Failed}} ({{.IPs}} IPs)\nNew bans: {{.Bans}}\nSuccessful logins: {{.Success}}\nTop IPs:\n{{.TopIPs}}\nTop countries:\n{{.TopCountries}}\nServer: {{.Server}}",
+		EventLogLag:         "🐢 Log processing lag is high\nTime: {{.Time}}\nLag: {{.Lag}}s (threshold {{.Threshold}}s)\nQueued events: {{.Queue}}\nServer: {{.Server}}",
+		EventHeartbeat:      "💓 SSH protection is running\nTime: {{.Time}}\nUptime: {{.Uptime}}\nBanned IPs: {{.Banned}}\nFailed logins in the last hour: {{.Failures}}{{if .Resources}}\nResource usage:\n{{.Resources}}{{end}}\nServer: {{.Server}}",
+		EventBatch:          "📦 {{.Event}} digest\nTime: {{.Time}}\n{{.Count}} more {{.Event}} notifications in the last {{.Window}} min were not sent individually\nSource IPs: {{.IPs}}\nTop sources: {{.TopIPs}}\nServer: {{.Server}}",
+		EventStartup:        "🟢 SSH protection started\nTime: {{.Time}}\n{{.Summary}}\nServer: {{.Server}}",
+		EventAuditReport:    "🛡 Host security audit\nTime: {{.Time}}\nScore: {{.Score}}/100\nFailed checks: {{.Failed}}{{if .Findings}}\n{{.Findings}}{{end}}\nServer: {{.Server}}",
+		EventAllowExpiring:  "⏳ Temporary allow entry expiring\nTime: {{.Time}}\nEntry: {{.Entry}}\nRequested by: {{.RequestedBy}}\nExpires: {{.ExpireTime}}\nServer: {{.Server}}",
+		EventAccountLock:    "🔐 Account {{if .UnlockTime}}locked{{else}}unlocked{{end}}\nTime: {{.Time}}\nUser: {{.User}}\nReason: {{.Reason}}{{if .UnlockTime}}\nAuto unlock: {{.UnlockTime}}{{end}}\nServer: {{.Server}}",
+	},
+}
+
+// defaultTemplate 返回该类通知的中文内置模板
+func defaultTemplate(eventType string) string {
+	cfg, _ := notificationConfig(defaultNotifications, eventType)
+	return cfg.Template
+}
+
+// effectiveTemplate 返回发送该类通知时使用的模板
+// 配置中的模板为空或与中文内置模板相同（未修改过，或从参考配置原样复制）时按locale选择对应语言的内置模板
+// 返回:
+//   - string: 模板内容，为空时不按模板渲染
+//   - bool: 使用的是内置模板时为true
+func effectiveTemplate(n config.NotificationsConfig, eventType string) (string, bool) {
+	cfg, ok := notificationConfig(n, eventType)
+	if !ok {
+		return "", false
+	}
+	builtin := defaultTemplate(eventType)
+	if cfg.Template != "" && cfg.Template != builtin {
+		return cfg.Template, false
+	}
+	if localized, ok := messageCatalog[n.Locale][eventType]; ok {
+		return localized, true
+	}
+	return builtin, true
+}
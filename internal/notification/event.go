@@ -0,0 +1,549 @@
+package notification
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// 通知中时间的显示格式
+const timeLayout = "2006-01-02 15:04:05"
+
+// LoginSuccessEvent 创建SSH登录成功的通知
+// 参数:
+//   - ip: 登录IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - at: 事件发生时间
+// 返回:
+//   - Event: 登录成功通知
+func LoginSuccessEvent(ip, ipInfo, server string, at time.Time) Event {
+	return Event{Type: EventLoginSuccess, Time: at, Data: LoginSuccessData{
+		Time:   at.Format(timeLayout),
+		IP:     ip,
+		IPInfo: ipInfo,
+		Server: server,
+	}}
+}
+
+// LoginFailedEvent 创建SSH登录失败的通知
+// 参数:
+//   - ip: 登录IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - attempts: 当前失败次数
+//   - maxAttempts: 最大允许失败次数
+//   - at: 事件发生时间
+// 返回:
+//   - Event: 登录失败通知
+func LoginFailedEvent(ip, ipInfo, server string, attempts, maxAttempts int, at time.Time) Event {
+	return Event{Type: EventLoginFailed, Time: at, Data: LoginFailedData{
+		Time:        at.Format(timeLayout),
+		IP:          ip,
+		IPInfo:      ipInfo,
+		Server:      server,
+		Attempts:    attempts,
+		MaxAttempts: maxAttempts,
+	}}
+}
+
+// IPBannedEvent 创建IP被封禁的通知
+// 参数:
+//   - ip: 被封禁的IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - reason: 封禁原因
+//   - duration: 封禁时长
+//   - expireTime: 解封时间，永久封禁时为零值
+//   - at: 事件发生时间
+// 返回:
+//   - Event: IP封禁通知
+func IPBannedEvent(ip, ipInfo, server, reason string, duration time.Duration, expireTime, at time.Time) Event {
+	expires, text := expireTime.Format(timeLayout), config.Duration(duration).String()
+	if expireTime.IsZero() {
+		expires, text = "永久", "永久"
+	}
+	return Event{Type: EventIPBanned, Time: at, Data: IPBannedData{
+		Time:         at.Format(timeLayout),
+		IP:           ip,
+		IPInfo:       ipInfo,
+		Server:       server,
+		Reason:       reason,
+		Duration:     int(math.Round(duration.Hours())),
+		DurationText: text,
+		ExpireTime:   expires,
+	}}
+}
+
+// PasswordSprayEvent 创建密码喷洒告警
+// 参数:
+//   - user: 被尝试的用户名
+//   - ips: 时间窗口内失败的来源IP
+//   - window: 统计时间窗口
+//   - server: 服务器信息
+// 返回:
+//   - Event: 密码喷洒告警
+func PasswordSprayEvent(user string, ips []string, window time.Duration, server string) Event {
+	now := time.Now()
+	return Event{Type: EventPasswordSpray, Time: now, Data: PasswordSprayData{
+		Time:   now.Format(timeLayout),
+		User:   user,
+		Count:  len(ips),
+		Window: minutes(window),
+		IPs:    strings.Join(ips, ", "),
+		Server: server,
+	}}
+}
+
+// SubnetAttackEvent 创建网段或ASN分布式攻击告警
+// 参数:
+//   - source: 攻击来源，网段CIDR或ASN
+//   - failures: 时间窗口内的失败次数
+//   - ips: 时间窗口内失败的不同IP数量
+//   - window: 统计时间窗口
+//   - action: 采取的处理措施
+//   - server: 服务器信息
+// 返回:
+//   - Event: 分布式攻击告警
+func SubnetAttackEvent(source string, failures, ips int, window time.Duration, action, server string) Event {
+	now := time.Now()
+	return Event{Type: EventSubnetAttack, Time: now, Data: SubnetAttackData{
+		Time:     now.Format(timeLayout),
+		Source:   source,
+		Failures: failures,
+		IPs:      ips,
+		Window:   minutes(window),
+		Action:   action,
+		Server:   server,
+	}}
+}
+
+// RootLoginEvent 创建root用户登录通知，代替普通的登录成功/失败通知
+// 参数:
+//   - ip: 登录IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - success: 是否登录成功
+//   - attempts: 当前失败次数，登录成功时为0
+//   - maxAttempts: 封禁阈值
+//   - at: 事件发生时间
+// 返回:
+//   - Event: root登录通知
+func RootLoginEvent(ip, ipInfo, server string, success bool, attempts, maxAttempts int, at time.Time) Event {
+	result := "失败"
+	if success {
+		result = "成功"
+	}
+	return Event{Type: EventRootLogin, Time: at, Data: RootLoginData{
+		Time:        at.Format(timeLayout),
+		IP:          ip,
+		IPInfo:      ipInfo,
+		Server:      server,
+		Result:      result,
+		Attempts:    attempts,
+		MaxAttempts: maxAttempts,
+	}}
+}
+
+// RuleMatchedEvent 创建通用规则达到阈值的通知
+// 参数:
+//   - rule: 规则名称
+//   - ip: 触发规则的IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - count: 时间窗口内的匹配次数
+//   - window: 统计时间窗口
+//   - at: 事件发生时间
+// 返回:
+//   - Event: 规则触发通知
+func RuleMatchedEvent(rule, ip, ipInfo, server string, count int, window time.Duration, at time.Time) Event {
+	return Event{Type: EventRuleMatched, Time: at, Data: RuleMatchedData{
+		Time:   at.Format(timeLayout),
+		Rule:   rule,
+		IP:     ip,
+		IPInfo: ipInfo,
+		Count:  count,
+		Window: minutes(window),
+		Server: server,
+	}}
+}
+
+// IPUnbannedEvent 创建IP解除封禁的通知
+// 参数:
+//   - ip: 被解除封禁的IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - reason: 解除原因
+//   - at: 事件发生时间
+// 返回:
+//   - Event: 解除封禁通知
+func IPUnbannedEvent(ip, ipInfo, server, reason string, at time.Time) Event {
+	return Event{Type: EventIPUnbanned, Time: at, Data: IPUnbannedData{
+		Time:   at.Format(timeLayout),
+		IP:     ip,
+		IPInfo: ipInfo,
+		Server: server,
+		Reason: reason,
+	}}
+}
+
+// NewLocationEvent 创建异地登录告警
+// 参数:
+//   - ip: 登录IP地址
+//   - ipInfo: IP地址的详细信息
+//   - server: 服务器信息
+//   - user: 登录用户名
+//   - kind: 首次出现的位置类型
+//   - country: 登录来源国家
+//   - asn: 登录来源ASN
+//   - at: 事件发生时间
+// 返回:
+//   - Event: 异地登录告警
+func NewLocationEvent(ip, ipInfo, server, user, kind, country, asn string, at time.Time) Event {
+	return Event{Type: EventNewLocation, Time: at, Data: NewLocationData{
+		Time:    at.Format(timeLayout),
+		IP:      ip,
+		IPInfo:  ipInfo,
+		User:    user,
+		Kind:    kind,
+		Country: country,
+		ASN:     asn,
+		Server:  server,
+	}}
+}
+
+// LogoutEvent 创建SSH会话结束的通知
+// 参数:
+//   - ip: 登录IP地址
+//   - user: 登录用户名
+//   - server: 服务器信息
+//   - loginTime: 登录成功的时间
+//   - at: 会话结束时间
+// 返回:
+//   - Event: 会话结束通知
+func LogoutEvent(ip, user, server string, loginTime, at time.Time) Event {
+	return Event{Type: EventLogout, Time: at, Data: LogoutData{
+		Time:      at.Format(timeLayout),
+		IP:        ip,
+		User:      user,
+		LoginTime: loginTime.Format(timeLayout),
+		Duration:  at.Sub(loginTime).Round(time.Second).String(),
+		Server:    server,
+	}}
+}
+
+// AdaptiveReportEvent 创建国家自适应阈值的重新计算报告
+// 参数:
+//   - total: 统计周期内带国家信息的失败次数
+//   - countries: 失败次数最多的国家及其占比
+//   - strict: 使用严格阈值的国家
+//   - threshold: 严格阈值
+//   - advice: sshd配置的加固建议，没有时为空
+//   - server: 服务器信息
+// 返回:
+//   - Event: 自适应阈值报告
+func AdaptiveReportEvent(total int, countries, strict []string, threshold int, advice []string, server string) Event {
+	now := time.Now()
+	return Event{Type: EventAdaptiveReport, Time: now, Data: AdaptiveReportData{
+		Time:      now.Format(timeLayout),
+		Total:     total,
+		Countries: joinOrNone(countries),
+		Strict:    joinOrNone(strict),
+		Threshold: threshold,
+		Advice:    strings.Join(advice, "\n"),
+		Server:    server,
+	}}
+}
+
+// UnbanDigestEvent 创建每日自动解封汇总
+// 参数:
+//   - date: 汇总日期
+//   - ips: 当天封禁到期被自动解除的IP
+//   - server: 服务器信息
+// 返回:
+//   - Event: 自动解封汇总
+func UnbanDigestEvent(date time.Time, ips []string, server string) Event {
+	return Event{Type: EventUnbanDigest, Time: date, Data: UnbanDigestData{
+		Date:   date.Format("2006-01-02"),
+		Count:  len(ips),
+		IPs:    strings.Join(ips, ", "),
+		Server: server,
+	}}
+}
+
+// SummaryEvent 创建定期汇总报告
+// 参数:
+//   - start: 统计开始时间
+//   - end: 统计结束时间
+//   - failed: 登录失败次数
+//   - bans: 新增封禁数
+//   - success: 登录成功次数
+//   - ips: 各IP的登录失败次数
+//   - countries: 各国家的登录失败次数
+//   - top: 列出的IP和国家数量
+//   - server: 服务器信息
+// 返回:
+//   - Event: 汇总报告
+func SummaryEvent(start, end time.Time, failed, bans, success int, ips, countries map[string]int, top int, server string) Event {
+	return Event{Type: EventSummary, Time: end, Data: SummaryData{
+		Start:        start.Format(timeLayout),
+		End:          end.Format(timeLayout),
+		Failed:       failed,
+		IPs:          len(ips),
+		Bans:         bans,
+		Success:      success,
+		TopIPs:       joinLinesOrNone(topCounts(ips, top)),
+		TopCountries: joinLinesOrNone(topCounts(countries, top)),
+		Server:       server,
+	}}
+}
+
+// LogLagEvent 创建日志处理延迟告警
+// 参数:
+//   - lag: 当前处理延迟
+//   - threshold: 告警阈值
+//   - queue: 等待处理的登录事件数
+//   - server: 服务器信息
+// 返回:
+//   - Event: 延迟告警
+func LogLagEvent(lag, threshold time.Duration, queue int, server string) Event {
+	now := time.Now()
+	return Event{Type: EventLogLag, Time: now, Data: LogLagData{
+		Time:      now.Format(timeLayout),
+		Lag:       int(lag.Seconds()),
+		Threshold: int(threshold.Seconds()),
+		Queue:     queue,
+		Server:    server,
+	}}
+}
+
+// HeartbeatEvent 创建心跳通知
+// 参数:
+//   - status: 守护进程的运行状态
+//   - resources: 是否附带资源占用
+//   - server: 服务器信息
+//   - now: 发送时间
+// 返回:
+//   - Event: 心跳通知
+func HeartbeatEvent(status control.Status, resources bool, server string, now time.Time) Event {
+	data := HeartbeatData{
+		Time:     now.Format(timeLayout),
+		Uptime:   formatUptime(now.Sub(status.Started)),
+		Banned:   status.Banned,
+		Failures: status.FailuresLastHour,
+		Server:   server,
+	}
+	if resources {
+		data.Resources = strings.Join(ResourceLines(status.Resources), "\n")
+	}
+	return Event{Type: EventHeartbeat, Time: now, Data: data}
+}
+
+// StartupEvent 创建启动通知
+// 参数:
+//   - summary: 生效配置摘要，每项一行
+//   - server: 服务器信息
+//   - t: 启动时间
+// 返回:
+//   - Event: 启动通知
+func StartupEvent(summary, server string, t time.Time) Event {
+	return Event{Type: EventStartup, Time: t, Data: StartupData{
+		Time:    t.Format(timeLayout),
+		Summary: summary,
+		Server:  server,
+	}}
+}
+
+// AuditReportEvent 创建主机安全检查报告
+// 参数:
+//   - score: 得分（0-100）
+//   - findings: 未通过的检查项及说明
+//   - server: 服务器信息
+//   - t: 检查时间
+// 返回:
+//   - Event: 主机安全检查报告
+func AuditReportEvent(score int, findings []string, server string, t time.Time) Event {
+	return Event{Type: EventAuditReport, Time: t, Data: AuditReportData{
+		Time:     t.Format(timeLayout),
+		Score:    score,
+		Failed:   len(findings),
+		Findings: strings.Join(findings, "\n"),
+		Server:   server,
+	}}
+}
+
+// AllowExpiringEvent 创建临时白名单即将到期的提醒
+// 参数:
+//   - entry: 白名单IP或CIDR网段
+//   - requestedBy: 申请人
+//   - server: 服务器信息
+//   - expireTime: 到期时间
+//   - t: 通知时间
+// 返回:
+//   - Event: 临时白名单到期提醒
+func AllowExpiringEvent(entry, requestedBy, server string, expireTime, t time.Time) Event {
+	return Event{Type: EventAllowExpiring, Time: t, Data: AllowExpiringData{
+		Time:        t.Format(timeLayout),
+		Entry:       entry,
+		RequestedBy: requestedBy,
+		ExpireTime:  expireTime.Format(timeLayout),
+		Server:      server,
+	}}
+}
+
+// AccountLockedEvent 创建账户锁定通知
+// 参数:
+//   - user: 本机账户名
+//   - reason: 锁定原因
+//   - server: 服务器信息
+//   - unlockTime: 自动解锁时间
+//   - t: 锁定时间
+// 返回:
+//   - Event: 账户锁定通知
+func AccountLockedEvent(user, reason, server string, unlockTime, t time.Time) Event {
+	return Event{Type: EventAccountLock, Time: t, Data: AccountLockData{
+		Time:       t.Format(timeLayout),
+		User:       user,
+		Action:     "已锁定",
+		Reason:     reason,
+		UnlockTime: unlockTime.Format(timeLayout),
+		Server:     server,
+	}}
+}
+
+// AccountUnlockedEvent 创建账户解锁通知
+// 参数:
+//   - user: 本机账户名
+//   - reason: 解锁原因，如 锁定到期
+//   - server: 服务器信息
+//   - t: 解锁时间
+// 返回:
+//   - Event: 账户解锁通知
+func AccountUnlockedEvent(user, reason, server string, t time.Time) Event {
+	return Event{Type: EventAccountLock, Time: t, Data: AccountLockData{
+		Time:   t.Format(timeLayout),
+		User:   user,
+		Action: "已解锁",
+		Reason: reason,
+		Server: server,
+	}}
+}
+
+// IP 返回触发通知的IP，汇总类通知没有单个IP，返回空字符串
+// 返回:
+//   - string: IP地址
+func (e Event) IP() string {
+	return dataField(e.Data, "IP")
+}
+
+// Server 返回通知中的服务器信息
+// 返回:
+//   - string: 服务器信息，模板字段中没有时为空
+func (e Event) Server() string {
+	return dataField(e.Data, "Server")
+}
+
+// WithClient 返回带有客户端版本标识的通知
+// 模板字段中没有Client字段的通知原样返回
+// 参数:
+//   - client: 客户端版本标识，为空时原样返回
+// 返回:
+//   - Event: 设置了Client字段的通知
+func (e Event) WithClient(client string) Event {
+	return e.withField("Client", client)
+}
+
+// WithUser 返回带有登录用户名的通知
+// 模板字段中没有User字段的通知原样返回
+// 参数:
+//   - user: 登录用户名，为空时原样返回
+// 返回:
+//   - Event: 设置了User字段的通知
+func (e Event) WithUser(user string) Event {
+	return e.withField("User", user)
+}
+
+// WithCountry 返回带有来源国家的通知
+// 模板字段中没有Country字段的通知原样返回
+// 参数:
+//   - country: 来源国家，为空时原样返回
+// 返回:
+//   - Event: 设置了Country字段的通知
+func (e Event) WithCountry(country string) Event {
+	return e.withField("Country", country)
+}
+
+// WithSpan 返回关联到日志行跟踪的通知，各渠道的发送会记录为该跟踪的子span
+// 参数:
+//   - span: 触发通知的日志行的跟踪，可为nil
+// 返回:
+//   - Event: 设置了Span的通知
+func (e Event) WithSpan(span *tracing.Span) Event {
+	e.Span = span
+	return e
+}
+
+// withField 复制模板字段结构体并设置其中的字符串字段，字段不存在或值为空时原样返回
+func (e Event) withField(name, value string) Event {
+	v := reflect.ValueOf(e.Data)
+	if value == "" || v.Kind() != reflect.Struct {
+		return e
+	}
+	data := reflect.New(v.Type()).Elem()
+	data.Set(v)
+	if f := data.FieldByName(name); f.Kind() == reflect.String {
+		f.SetString(value)
+		e.Data = data.Interface()
+	}
+	return e
+}
+
+// dataField 读取模板字段结构体中的字符串字段，字段不存在时返回空字符串
+func dataField(data interface{}, name string) string {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	if f := v.FieldByName(name); f.Kind() == reflect.String {
+		return f.String()
+	}
+	return ""
+}
+
+// Text 生成通知的纯文本消息
+// 已按配置模板渲染时返回渲染结果，否则使用中文内置模板
+// 返回:
+//   - string: 消息内容
+func (e Event) Text() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if text, err := renderTemplate(e.Type, defaultTemplate(e.Type), e.Data); err == nil && text != "" {
+		return text
+	}
+	return fmt.Sprintf("%s\n时间: %s", e.Type, e.Time.Format(timeLayout))
+}
+
+func minutes(d time.Duration) int {
+	return int(math.Round(d.Minutes()))
+}
+
+func joinLinesOrNone(items []string) string {
+	if len(items) == 0 {
+		return "无"
+	}
+	return strings.Join(items, "\n")
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "无"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,84 @@
+package notification
+
+import (
+	"html"
+	"reflect"
+	"strings"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// Telegram通知消息的格式，与配置telegram.parse_mode的取值一致
+const (
+	ParseModeHTML       = "html"
+	ParseModeMarkdownV2 = "markdownv2"
+)
+
+// Telegram接口中parse_mode参数的取值
+var telegramParseModes = map[string]string{ParseModeHTML: "HTML", ParseModeMarkdownV2: "MarkdownV2"}
+
+// MarkdownV2中所有需要转义的字符
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`)
+
+// escapeText 按消息格式转义文本，使其中的字符不被当作格式标记
+func escapeText(mode, s string) string {
+	switch mode {
+	case ParseModeHTML:
+		return html.EscapeString(s)
+	case ParseModeMarkdownV2:
+		return markdownV2Escaper.Replace(s)
+	}
+	return s
+}
+
+// bold 将已转义的文本加粗
+func bold(mode, s string) string {
+	switch mode {
+	case ParseModeHTML:
+		return "<b>" + s + "</b>"
+	case ParseModeMarkdownV2:
+		return "*" + s + "*"
+	}
+	return s
+}
+
+// formatMessage 按消息格式生成通知的消息正文
+// 内置模板先渲染为纯文本再整体转义，第一行作为标题加粗；
+// 自定义模板中的文字原样保留，可以直接使用对应格式的标记，只转义模板字段的值
+// 参数:
+//   - n: 通知配置
+//   - event: 要发送的通知
+//   - mode: 消息格式，取值见ParseMode*常量
+// 返回:
+//   - string: 消息正文
+//   - error: 自定义模板渲染失败时的错误信息
+func formatMessage(n config.NotificationsConfig, event Event, mode string) (string, error) {
+	tmpl, builtin := effectiveTemplate(n, event.Type)
+	if tmpl == "" || builtin {
+		title, body, _ := strings.Cut(event.Text(), "\n")
+		text := bold(mode, escapeText(mode, title))
+		if body != "" {
+			text += "\n" + escapeText(mode, body)
+		}
+		return text, nil
+	}
+	return renderTemplate(event.Type, tmpl, escapeData(event.Data, mode))
+}
+
+// escapeData 复制模板字段结构体，并转义其中所有字符串字段的值
+func escapeData(data interface{}, mode string) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return data
+	}
+	escaped := reflect.New(v.Type()).Elem()
+	escaped.Set(v)
+	for i := 0; i < escaped.NumField(); i++ {
+		if f := escaped.Field(i); f.Kind() == reflect.String {
+			f.SetString(escapeText(mode, f.String()))
+		}
+	}
+	return escaped.Interface()
+}
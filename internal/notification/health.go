@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// 渠道健康状态
+const (
+	StateHealthy  = "healthy"  // 最近一次发送成功，或从未发送
+	StateFailing  = "failing"  // 连续失败但尚未达到暂停阈值
+	StateDisabled = "disabled" // 连续失败次数达到阈值，已暂停发送
+)
+
+// Prober 可以在不发送消息的情况下检查连通性的通知渠道
+// 渠道被暂停后，分发器每隔probe_interval调用一次Probe，成功即恢复发送；
+// 未实现Prober的渠道在间隔到达后用下一条通知试探
+type Prober interface {
+	Probe() error
+}
+
+// channelHealth 单个渠道的发送统计与暂停状态，由Dispatcher的锁保护
+type channelHealth struct {
+	sent        int       // 发送成功的次数
+	failed      int       // 发送失败的次数
+	skipped     int       // 暂停期间推迟发送的次数
+	consecutive int       // 连续失败次数
+	lastError   string    // 最近一次失败的原因
+	lastSuccess time.Time // 最近一次发送成功的时间
+	lastFailure time.Time // 最近一次发送失败的时间
+	disabled    bool      // 是否已暂停
+	probeAt     time.Time // 暂停期间下一次探测的时间
+}
+
+// state 返回渠道的健康状态
+func (h *channelHealth) state() string {
+	switch {
+	case h.disabled:
+		return StateDisabled
+	case h.consecutive > 0:
+		return StateFailing
+	}
+	return StateHealthy
+}
+
+// allow 判断这次通知是否应当发送给渠道
+// 暂停期间到达探测时间后，没有实现Prober的渠道放行一条通知作为试探
+func (d *Dispatcher) allow(c *channel, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := &c.health
+	if !h.disabled {
+		return true
+	}
+	if _, ok := c.notifier.(Prober); !ok && !now.Before(h.probeAt) {
+		h.probeAt = now.Add(d.probeInterval())
+		return true
+	}
+	h.skipped++
+	return false
+}
+
+// record 记录一次发送结果，连续失败达到阈值时暂停渠道，暂停中的渠道发送成功时恢复
+func (d *Dispatcher) record(c *channel, err error, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := &c.health
+	if err == nil {
+		h.sent++
+		h.lastSuccess = now
+		h.consecutive = 0
+		if h.disabled {
+			h.disabled = false
+			d.logger.WithField("channel", c.name).Info("通知渠道已恢复")
+		}
+		return
+	}
+
+	h.failed++
+	h.consecutive++
+	h.lastError = err.Error()
+	h.lastFailure = now
+	limit := d.notifications.ChannelHealth.MaxFailures
+	if !h.disabled && limit > 0 && h.consecutive >= limit {
+		h.disabled = true
+		h.probeAt = now.Add(d.probeInterval())
+		d.logger.WithFields(logrus.Fields{
+			"channel":  c.name,
+			"failures": h.consecutive,
+		}).Warn("通知渠道连续发送失败，已暂停，恢复前不再向该渠道发送")
+		if prober, ok := c.notifier.(Prober); ok {
+			go d.probe(c, prober)
+		}
+	}
+}
+
+// probe 定期探测已暂停的渠道，探测成功后恢复发送
+func (d *Dispatcher) probe(c *channel, prober Prober) {
+	for {
+		time.Sleep(d.probeInterval())
+		err := prober.Probe()
+		if err != nil {
+			d.logger.WithError(err).WithField("channel", c.name).Debug("通知渠道探测失败")
+		}
+
+		d.mu.Lock()
+		if err == nil {
+			c.health.disabled = false
+			c.health.consecutive = 0
+			d.logger.WithField("channel", c.name).Info("通知渠道探测成功，已恢复")
+		} else {
+			c.health.lastError = err.Error()
+			c.health.probeAt = time.Now().Add(d.probeInterval())
+		}
+		recovered := !c.health.disabled
+		d.mu.Unlock()
+		if recovered {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) probeInterval() time.Duration {
+	return time.Duration(d.notifications.ChannelHealth.ProbeInterval) * time.Second
+}
+
+// updatesReporter 接收管理命令的渠道，提供长轮询循环的健康状态
+type updatesReporter interface {
+	UpdatesHealth() control.UpdatesHealth
+}
+
+// Health 返回各渠道的健康状态
+// 返回:
+//   - []control.ChannelHealth: 按添加顺序排列的渠道状态
+func (d *Dispatcher) Health() []control.ChannelHealth {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	health := make([]control.ChannelHealth, len(d.channels))
+	for i, c := range d.channels {
+		h := c.health
+		health[i] = control.ChannelHealth{
+			Name:                c.name,
+			State:               h.state(),
+			Sent:                h.sent,
+			Failed:              h.failed,
+			Skipped:             h.skipped,
+			Queued:              len(c.inbound),
+			Pending:             len(c.pending),
+			ConsecutiveFailures: h.consecutive,
+			LastError:           h.lastError,
+			LastSuccess:         h.lastSuccess,
+			LastFailure:         h.lastFailure,
+		}
+		if h.disabled {
+			health[i].NextProbe = h.probeAt
+		}
+		if r, ok := c.notifier.(updatesReporter); ok {
+			updates := r.UpdatesHealth()
+			health[i].Updates = &updates
+		}
+	}
+	return health
+}
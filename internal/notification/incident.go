@@ -0,0 +1,280 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 创建事件请求的超时时间
+const incidentTimeout = 10 * time.Second
+
+// Opsgenie告警标题的最大长度
+const opsgenieMessageLimit = 130
+
+// incidentFilter 告警渠道只为配置中列出的事件类型创建事件
+type incidentFilter map[string]bool
+
+func newIncidentFilter(events []string) incidentFilter {
+	filter := make(incidentFilter, len(events))
+	for _, e := range events {
+		filter[e] = true
+	}
+	return filter
+}
+
+// DedupKey 返回告警渠道使用的去重键
+// 同一IP触发的同类事件使用相同的键，在事件解决之前只产生一个事件；没有IP的事件按类型去重
+// 参数:
+//   - event: 通知
+// 返回:
+//   - string: 形如 ssh_fb/new_location/1.2.3.4 的去重键
+func DedupKey(event Event) string {
+	if ip := event.IP(); ip != "" {
+		return fmt.Sprintf("ssh_fb/%s/%s", event.Type, ip)
+	}
+	return "ssh_fb/" + event.Type
+}
+
+// subject 返回通知的第一行，作为事件标题
+func subject(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	return line
+}
+
+// PagerDuty 通过PagerDuty Events API v2创建事件
+type PagerDuty struct {
+	url        string         // Events API地址
+	routingKey string         // 服务集成的Routing Key
+	severity   string         // 事件级别
+	events     incidentFilter // 需要创建事件的通知类型
+	httpClient *http.Client   // HTTP客户端
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// pagerDutyEvent Events API v2的请求体
+type pagerDutyEvent struct {
+	RoutingKey  string           `json:"routing_key"`
+	EventAction string           `json:"event_action"`
+	DedupKey    string           `json:"dedup_key"`
+	Payload     pagerDutyPayload `json:"payload"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Timestamp     time.Time   `json:"timestamp"`
+	Component     string      `json:"component"`
+	Class         string      `json:"class"`
+	CustomDetails interface{} `json:"custom_details"`
+}
+
+// NewPagerDuty 创建PagerDuty告警渠道
+// 参数:
+//   - cfg: PagerDuty配置
+//   - events: 需要创建事件的通知类型
+//   - notifications: 各类通知的开关，用于发送测试通知
+// 返回:
+//   - *PagerDuty: 初始化后的PagerDuty渠道
+func NewPagerDuty(cfg config.PagerDutyConfig, events []string, notifications config.NotificationsConfig) *PagerDuty {
+	return &PagerDuty{
+		url:           cfg.URL,
+		routingKey:    cfg.RoutingKey,
+		severity:      cfg.Severity,
+		events:        newIncidentFilter(events),
+		httpClient:    &http.Client{Timeout: incidentTimeout},
+		notifications: notifications,
+	}
+}
+
+// Notify 为配置中列出的事件类型触发PagerDuty事件，其他通知被忽略
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送过程中的错误信息
+func (p *PagerDuty) Notify(event Event) error {
+	if !p.events[event.Type] {
+		return nil
+	}
+	text := event.Text()
+	body, err := json.Marshal(pagerDutyEvent{
+		RoutingKey:  p.routingKey,
+		EventAction: "trigger",
+		DedupKey:    DedupKey(event),
+		Payload: pagerDutyPayload{
+			Summary:       subject(text),
+			Source:        source(event),
+			Severity:      p.severity,
+			Timestamp:     event.Time,
+			Component:     "sshd",
+			Class:         event.Type,
+			CustomDetails: event.Data,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("编码PagerDuty事件失败: %v", err)
+	}
+	if err := postIncident(p.httpClient, p.url, nil, body); err != nil {
+		return fmt.Errorf("创建PagerDuty事件失败: %v", err)
+	}
+	return nil
+}
+
+// SendTest 使用示例数据触发一个PagerDuty测试事件
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用或不在alerting.events中时返回ErrDisabled
+func (p *PagerDuty) SendTest(name string) error {
+	if !p.events[name] {
+		return ErrDisabled
+	}
+	return SendTest(p, p.notifications, name)
+}
+
+// Opsgenie 通过Opsgenie Alert API创建告警
+type Opsgenie struct {
+	url        string         // Alert API地址，欧洲区使用api.eu.opsgenie.com
+	apiKey     string         // API集成的密钥
+	priority   string         // 告警优先级
+	events     incidentFilter // 需要创建告警的通知类型
+	httpClient *http.Client   // HTTP客户端
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// opsgenieAlert Alert API的请求体
+type opsgenieAlert struct {
+	Message     string      `json:"message"`
+	Alias       string      `json:"alias"`
+	Description string      `json:"description"`
+	Priority    string      `json:"priority"`
+	Source      string      `json:"source"`
+	Tags        []string    `json:"tags"`
+	Details     interface{} `json:"details"`
+}
+
+// NewOpsgenie 创建Opsgenie告警渠道
+// 参数:
+//   - cfg: Opsgenie配置
+//   - events: 需要创建告警的通知类型
+//   - notifications: 各类通知的开关，用于发送测试通知
+// 返回:
+//   - *Opsgenie: 初始化后的Opsgenie渠道
+func NewOpsgenie(cfg config.OpsgenieConfig, events []string, notifications config.NotificationsConfig) *Opsgenie {
+	return &Opsgenie{
+		url:           cfg.URL,
+		apiKey:        cfg.APIKey,
+		priority:      cfg.Priority,
+		events:        newIncidentFilter(events),
+		httpClient:    &http.Client{Timeout: incidentTimeout},
+		notifications: notifications,
+	}
+}
+
+// Notify 为配置中列出的事件类型创建Opsgenie告警，其他通知被忽略
+// 告警的alias为去重键，同一IP的告警关闭之前重复创建只会增加计数
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送过程中的错误信息
+func (o *Opsgenie) Notify(event Event) error {
+	if !o.events[event.Type] {
+		return nil
+	}
+	text := event.Text()
+	message := []rune(subject(text))
+	if len(message) > opsgenieMessageLimit {
+		message = message[:opsgenieMessageLimit]
+	}
+	body, err := json.Marshal(opsgenieAlert{
+		Message:     string(message),
+		Alias:       DedupKey(event),
+		Description: text,
+		Priority:    o.priority,
+		Source:      source(event),
+		Tags:        []string{"ssh_fb", event.Type},
+		Details:     stringDetails(event.Data),
+	})
+	if err != nil {
+		return fmt.Errorf("编码Opsgenie告警失败: %v", err)
+	}
+	header := http.Header{"Authorization": {"GenieKey " + o.apiKey}}
+	if err := postIncident(o.httpClient, o.url, header, body); err != nil {
+		return fmt.Errorf("创建Opsgenie告警失败: %v", err)
+	}
+	return nil
+}
+
+// SendTest 使用示例数据创建一条Opsgenie测试告警
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用或不在alerting.events中时返回ErrDisabled
+func (o *Opsgenie) SendTest(name string) error {
+	if !o.events[name] {
+		return ErrDisabled
+	}
+	return SendTest(o, o.notifications, name)
+}
+
+// source 返回事件来源，有IP时为触发事件的IP
+func source(event Event) string {
+	if ip := event.IP(); ip != "" {
+		return ip
+	}
+	return "ssh_fb"
+}
+
+// stringDetails 将模板字段转换为字符串映射，Opsgenie的details只接受字符串值
+func stringDetails(data interface{}) map[string]string {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	details := make(map[string]string, len(fields))
+	for k, v := range fields {
+		details[k] = fmt.Sprint(v)
+	}
+	return details
+}
+
+// postIncident 发送创建事件的请求，两个接口成功时都返回202
+func postIncident(client *http.Client, url string, header http.Header, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var result struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		if result.Message != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, result.Message)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/tracing"
+)
+
+// 通知的事件类型，与配置文件notifications下的键名一致
+const (
+	EventLoginSuccess = "login_success"
+	EventLoginFailed  = "login_failed"
+	EventIPBanned     = "ip_banned"
+
+	EventPasswordSpray  = "password_spray"
+	EventSubnetAttack   = "subnet_attack"
+	EventRootLogin      = "root_login"
+	EventRuleMatched    = "rule_matched"
+	EventIPUnbanned     = "ip_unbanned"
+	EventNewLocation    = "new_location"
+	EventLogout         = "logout"
+	EventAdaptiveReport = "adaptive_report"
+	EventUnbanDigest    = "unban_digest"
+	EventSummary        = "summary"
+	EventLogLag         = "log_lag"
+	EventHeartbeat      = "heartbeat"
+	EventStartup        = "startup"
+	EventAuditReport    = "audit_report"
+	EventAllowExpiring  = "allow_expiring"
+	EventAccountLock    = "account_lock"
+	EventBatch          = "batch"
+)
+
+// Event 一条待发送的通知
+type Event struct {
+	Type    string      // 事件类型，取值见Event*常量
+	Time    time.Time   // 事件发生时间
+	Data    interface{} // 模板字段，为与事件类型对应的*Data结构体
+	Message string      // 按配置模板渲染的消息正文，由Dispatcher在分发前填写，为空时Text使用内置格式
+
+	Span *tracing.Span // 触发通知的日志行的跟踪，各渠道的发送记录为其子span，为nil时不记录
+}
+
+// Notifier 通知渠道
+// 渠道只负责发送，是否发送由Dispatcher按配置中的开关决定
+type Notifier interface {
+	Notify(event Event) error
+}
+
+// channel 已添加到分发器的通知渠道
+type channel struct {
+	name     string
+	notifier Notifier
+	health   channelHealth
+	inbound  chan Event  // 等待首次发送的通知
+	pending  []*delivery // 发送失败、等待重试的通知，由Dispatcher的锁保护，见queue.go
+}
+
+// Dispatcher 将通知分发到所有已配置的通知渠道
+// 自身也实现Notifier，没有添加任何渠道时通知被直接丢弃；
+// 每个渠道由独立的协程异步发送，失败的通知按指数退避重试，见queue.go；
+// 同时记录各渠道的健康状态，连续失败的渠道会被暂停，见health.go
+type Dispatcher struct {
+	channels      []*channel
+	notifications config.NotificationsConfig
+	logger        *logrus.Logger
+	networks      []networkRoute          // 按来源网段的路由，见routing.go
+	batches       map[string]*batchWindow // 各类通知当前的汇总周期，见throttle.go
+	spooled       map[string][]*delivery  // 启动时从磁盘读取、尚未分配给渠道的待重试通知
+	mu            sync.Mutex              // 保护各渠道的健康状态、待重试通知与汇总周期
+	spoolMu       sync.Mutex              // 串行写入待重试通知的保存文件
+}
+
+// NewDispatcher 创建通知分发器
+// 上次运行时未发送成功的通知从queue.spool_file读取，在添加同名渠道后继续发送
+// 参数:
+//   - notifications: 各类通知的开关与模板
+//   - logger: 日志记录器
+// 返回:
+//   - *Dispatcher: 尚未添加渠道的分发器
+func NewDispatcher(notifications config.NotificationsConfig, logger *logrus.Logger) *Dispatcher {
+	d := &Dispatcher{notifications: notifications, logger: logger, batches: make(map[string]*batchWindow)}
+	spooled, err := loadSpool(notifications.Queue.SpoolFile)
+	if err != nil {
+		logger.WithError(err).Warn("读取待重试通知失败，上次未发送的通知将被丢弃")
+	}
+	d.spooled = spooled
+	d.networks = parseNetworkRoutes(notifications.Routing.Networks)
+	return d
+}
+
+// Add 添加一个通知渠道并启动该渠道的发送协程
+// 参数:
+//   - name: 渠道名称，用于日志和错误信息
+//   - notifier: 通知渠道
+func (d *Dispatcher) Add(name string, notifier Notifier) {
+	c := &channel{name: name, notifier: notifier, inbound: make(chan Event, d.notifications.Queue.Size)}
+	d.mu.Lock()
+	c.pending = d.spooled[name]
+	delete(d.spooled, name)
+	d.channels = append(d.channels, c)
+	d.mu.Unlock()
+	if len(c.pending) > 0 {
+		d.logger.WithFields(logrus.Fields{"channel": name, "count": len(c.pending)}).Info("继续发送上次运行时未发送成功的通知")
+	}
+	go d.run(c)
+}
+
+// Channels 返回已添加的渠道名称
+// 返回:
+//   - []string: 按添加顺序排列的渠道名称
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, len(d.channels))
+	for i, c := range d.channels {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Notify 将通知放入各渠道的发送队列，不等待发送完成
+// 该类通知未启用时不发送；超出发送频率的通知合并为汇总，见throttle.go；
+// 按严重级别只发送到路由中指定的渠道，免打扰时段内不重要的通知只写入日志；
+// 单个渠道失败不影响其他渠道，失败或暂停期间的通知留在队列中等待重试
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 队列已满、通知被丢弃的渠道，全部放入队列时为nil
+func (d *Dispatcher) Notify(event Event) error {
+	if !Enabled(d.notifications, event.Type) || d.throttle(event) {
+		return nil
+	}
+	return d.send(event)
+}
+
+// send 按配置模板生成消息正文，并放入按严重级别路由到的各渠道的发送队列，路由规则见routing.go
+// 模板渲染失败时记录错误日志，使用内置格式发送，不丢弃通知
+func (d *Dispatcher) send(event Event) error {
+	event, err := Render(d.notifications, event)
+	if err != nil {
+		d.logger.WithError(err).WithField("event", event.Type).Error("渲染通知模板失败，使用内置格式")
+	}
+	var dropped []string
+	for _, c := range d.route(event) {
+		select {
+		case c.inbound <- event:
+		default:
+			d.logger.WithFields(logrus.Fields{"channel": c.name, "event": event.Type}).Error("通知队列已满，丢弃通知")
+			dropped = append(dropped, c.name)
+		}
+	}
+	if len(dropped) > 0 {
+		return fmt.Errorf("通知队列已满: %s", strings.Join(dropped, ", "))
+	}
+	return nil
+}
+
+// Enabled 检查某类通知是否在配置中启用
+// 参数:
+//   - n: 通知配置
+//   - eventType: 事件类型
+// 返回:
+//   - bool: 已启用时为true，未知的事件类型为false
+func Enabled(n config.NotificationsConfig, eventType string) bool {
+	cfg, ok := notificationConfig(n, eventType)
+	return ok && cfg.Enabled
+}
+
+// notificationConfig 返回某类通知的配置
+func notificationConfig(n config.NotificationsConfig, eventType string) (config.NotificationConfig, bool) {
+	for _, spec := range templateSpecs {
+		if spec.name == eventType {
+			return spec.config(n), true
+		}
+	}
+	return config.NotificationConfig{}, false
+}
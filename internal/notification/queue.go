@@ -0,0 +1,242 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errChannelPaused 渠道已暂停，本次未发送
+var errChannelPaused = errors.New("通知渠道已暂停")
+
+// delivery 一条等待重试的通知
+type delivery struct {
+	event    Event
+	attempts int       // 已发送失败的次数
+	next     time.Time // 下一次重试的时间，零值表示立即发送
+}
+
+// spooledDelivery 待重试通知在保存文件中的格式
+type spooledDelivery struct {
+	Type     string          `json:"type"`
+	Time     time.Time       `json:"time"`
+	Data     json.RawMessage `json:"data"`
+	Message  string          `json:"message,omitempty"`
+	Attempts int             `json:"attempts"`
+}
+
+// run 渠道的发送协程
+// 没有待重试的通知时，新通知到达后立即发送，失败的转入待重试列表；
+// 有待重试的通知时，新通知排在其后，按顺序发送，队首到达重试时间后才发送，渠道恢复后依次补发
+func (d *Dispatcher) run(c *channel) {
+	for {
+		d.mu.Lock()
+		var head *delivery
+		if len(c.pending) > 0 {
+			head = c.pending[0]
+		}
+		d.mu.Unlock()
+
+		if head == nil {
+			event := <-c.inbound
+			if err := d.deliver(c, event); err != nil {
+				d.retry(c, &delivery{event: event}, err)
+			}
+			continue
+		}
+
+		timer := time.NewTimer(time.Until(head.next))
+		select {
+		case event := <-c.inbound:
+			timer.Stop()
+			d.enqueue(c, &delivery{event: event})
+		case <-timer.C:
+			err := d.deliver(c, head.event)
+			d.mu.Lock()
+			if len(c.pending) > 0 && c.pending[0] == head {
+				c.pending = c.pending[1:]
+			}
+			d.mu.Unlock()
+			if err != nil {
+				d.retry(c, head, err)
+			} else {
+				d.saveSpool()
+			}
+		}
+	}
+}
+
+// deliver 发送一条通知并记录结果，渠道已暂停时返回errChannelPaused
+func (d *Dispatcher) deliver(c *channel, event Event) error {
+	if !d.allow(c, time.Now()) {
+		return errChannelPaused
+	}
+	span := event.Span.Client("notify." + c.name)
+	span.Set("event", event.Type)
+	err := c.notifier.Notify(event)
+	span.Fail(err)
+	span.End()
+	d.record(c, err, time.Now())
+	if err != nil {
+		d.logger.WithError(err).WithFields(logrus.Fields{
+			"channel": c.name,
+			"event":   event.Type,
+		}).Error("发送通知失败")
+	}
+	return err
+}
+
+// retry 安排一条发送失败的通知重试，达到最大发送次数时丢弃
+// 重试等待时间从retry_initial开始每次加倍，不超过retry_max；渠道暂停期间不计入发送次数，等到下一次探测后再试
+func (d *Dispatcher) retry(c *channel, item *delivery, err error) {
+	cfg := d.notifications.Queue
+	if !errors.Is(err, errChannelPaused) {
+		item.attempts++
+	}
+	if cfg.MaxAttempts > 0 && item.attempts >= cfg.MaxAttempts {
+		d.logger.WithFields(logrus.Fields{
+			"channel":  c.name,
+			"event":    item.event.Type,
+			"attempts": item.attempts,
+		}).Error("通知多次发送失败，已丢弃")
+		d.saveSpool()
+		return
+	}
+	wait := time.Duration(cfg.RetryInitial) * time.Second
+	for i := 1; i < item.attempts && wait < time.Duration(cfg.RetryMax)*time.Second; i++ {
+		wait *= 2
+	}
+	item.next = time.Now().Add(min(wait, time.Duration(cfg.RetryMax)*time.Second))
+
+	d.mu.Lock()
+	if c.health.disabled && c.health.probeAt.After(item.next) {
+		// 暂停期间等到下一次探测后再试
+		item.next = c.health.probeAt
+	}
+	c.pending = append([]*delivery{item}, c.pending...)
+	d.mu.Unlock()
+	d.saveSpool()
+}
+
+// enqueue 将通知追加到待重试列表末尾，超过max_pending时丢弃最早的通知
+func (d *Dispatcher) enqueue(c *channel, item *delivery) {
+	limit := d.notifications.Queue.MaxPending
+	d.mu.Lock()
+	c.pending = append(c.pending, item)
+	dropped := len(c.pending) - limit
+	if dropped > 0 {
+		c.pending = c.pending[dropped:]
+	}
+	d.mu.Unlock()
+	if dropped > 0 {
+		d.logger.WithFields(logrus.Fields{"channel": c.name, "dropped": dropped}).Warn("待重试的通知过多，已丢弃最早的通知")
+	}
+	d.saveSpool()
+}
+
+// saveSpool 将所有渠道待重试的通知写入保存文件
+// 先写入临时文件再重命名，写入过程中进程退出不会损坏原文件；没有待重试的通知时删除文件
+func (d *Dispatcher) saveSpool() {
+	path := d.notifications.Queue.SpoolFile
+	if path == "" {
+		return
+	}
+
+	d.mu.Lock()
+	spool := make(map[string][]spooledDelivery)
+	for _, c := range d.channels {
+		for _, item := range c.pending {
+			data, err := json.Marshal(item.event.Data)
+			if err != nil {
+				continue
+			}
+			spool[c.name] = append(spool[c.name], spooledDelivery{
+				Type:     item.event.Type,
+				Time:     item.event.Time,
+				Data:     data,
+				Message:  item.event.Message,
+				Attempts: item.attempts,
+			})
+		}
+	}
+	d.mu.Unlock()
+
+	d.spoolMu.Lock()
+	defer d.spoolMu.Unlock()
+	if len(spool) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			d.logger.WithError(err).Warn("删除待重试通知文件失败")
+		}
+		return
+	}
+	data, err := json.Marshal(spool)
+	if err != nil {
+		d.logger.WithError(err).Warn("保存待重试通知失败")
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+		err = os.WriteFile(tmp, data, 0600)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		d.logger.WithError(err).Warn("保存待重试通知失败")
+	}
+}
+
+// loadSpool 读取上次运行时保存的待重试通知
+// 参数:
+//   - path: 保存文件路径，为空或文件不存在时返回空结果
+// 返回:
+//   - map[string][]*delivery: 按渠道名称分组的待重试通知
+//   - error: 读取或解析失败时的错误信息
+func loadSpool(path string) (map[string][]*delivery, error) {
+	result := make(map[string][]*delivery)
+	if path == "" {
+		return result, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	var spool map[string][]spooledDelivery
+	if err := json.Unmarshal(data, &spool); err != nil {
+		return result, fmt.Errorf("解析%s失败: %v", path, err)
+	}
+	for name, items := range spool {
+		for _, item := range items {
+			event, err := item.event()
+			if err != nil {
+				return result, err
+			}
+			result[name] = append(result[name], &delivery{event: event, attempts: item.Attempts})
+		}
+	}
+	return result, nil
+}
+
+// event 按事件类型还原通知的模板字段
+func (s spooledDelivery) event() (Event, error) {
+	for _, spec := range templateSpecs {
+		if spec.name != s.Type {
+			continue
+		}
+		data := reflect.New(reflect.TypeOf(spec.sample))
+		if err := json.Unmarshal(s.Data, data.Interface()); err != nil {
+			return Event{}, fmt.Errorf("解析%s通知失败: %v", s.Type, err)
+		}
+		return Event{Type: s.Type, Time: s.Time, Data: data.Elem().Interface(), Message: s.Message}, nil
+	}
+	return Event{}, fmt.Errorf("未知的通知类型: %s", s.Type)
+}
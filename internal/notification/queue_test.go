@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// newTestDispatcher 创建不启动发送协程的分发器，渠道只用于检查待重试列表
+func newTestDispatcher(queue config.QueueConfig, names ...string) *Dispatcher {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	notifications := config.Default().Notifications
+	notifications.Queue = queue
+	d := &Dispatcher{notifications: notifications, logger: logger, batches: make(map[string]*batchWindow)}
+	for _, name := range names {
+		d.channels = append(d.channels, &channel{name: name})
+	}
+	return d
+}
+
+func TestSpoolRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "spool.json")
+	d := newTestDispatcher(config.QueueConfig{SpoolFile: path}, "telegram", "webhook")
+	unbanned := IPUnbannedEvent("1.2.3.4", "IP: 1.2.3.4", "测试服务器", "手动解封", at)
+	unbanned.Message = "已解封"
+	d.channels[0].pending = []*delivery{{event: unbanned, attempts: 3}}
+	d.channels[1].pending = []*delivery{{event: IPUnbannedEvent("5.6.7.8", "", "测试服务器", "到期", at)}}
+	d.saveSpool()
+
+	got, err := loadSpool(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range d.channels {
+		items := got[c.name]
+		if len(items) != len(c.pending) {
+			t.Fatalf("%s: 读取到%d条，期望%d条", c.name, len(items), len(c.pending))
+		}
+		for i, item := range items {
+			want := c.pending[i]
+			if item.attempts != want.attempts || item.event.Type != want.event.Type || !item.event.Time.Equal(want.event.Time) ||
+				item.event.Message != want.event.Message || !reflect.DeepEqual(item.event.Data, want.event.Data) {
+				t.Errorf("%s[%d]: got %+v，期望 %+v", c.name, i, item, want)
+			}
+		}
+	}
+
+	// 没有待重试的通知时删除文件
+	for _, c := range d.channels {
+		c.pending = nil
+	}
+	d.saveSpool()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("没有待重试的通知时未删除保存文件: %v", err)
+	}
+}
+
+func TestLoadSpool(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string // 为空时不创建文件
+		count   int
+		ok      bool
+	}{
+		{name: "文件不存在", ok: true},
+		{name: "有效内容", content: `{"telegram":[{"type":"ip_unbanned","time":"2024-03-10T12:00:00Z","data":{"IP":"1.2.3.4"},"attempts":2}]}`, count: 1, ok: true},
+		{name: "无效的JSON", content: `{"telegram":[`},
+		{name: "未知的通知类型", content: `{"telegram":[{"type":"unknown","data":{}}]}`},
+		{name: "模板字段类型不符", content: `{"telegram":[{"type":"ip_unbanned","data":[]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "spool.json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := loadSpool(path)
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v，期望成功 %v", err, tt.ok)
+			}
+			if tt.ok && len(got["telegram"]) != tt.count {
+				t.Errorf("读取到%d条，期望%d条", len(got["telegram"]), tt.count)
+			}
+		})
+	}
+}
+
+func TestEnqueueDropsOldest(t *testing.T) {
+	d := newTestDispatcher(config.QueueConfig{MaxPending: 2}, "telegram")
+	c := d.channels[0]
+	for _, ip := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		d.enqueue(c, &delivery{event: Event{Type: EventIPUnbanned, Data: IPUnbannedData{IP: ip}}})
+	}
+	var ips []string
+	for _, item := range c.pending {
+		ips = append(ips, item.event.Data.(IPUnbannedData).IP)
+	}
+	if !reflect.DeepEqual(ips, []string{"2.2.2.2", "3.3.3.3"}) {
+		t.Errorf("待重试的通知 %v，期望保留最新的两条", ips)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int // 本次失败前已失败的次数
+		err      error
+		wait     time.Duration
+		want     int // 本次失败后的失败次数
+		dropped  bool
+	}{
+		{name: "第一次失败", attempts: 0, err: errors.New("发送失败"), wait: 5 * time.Second, want: 1},
+		{name: "等待时间加倍", attempts: 2, err: errors.New("发送失败"), wait: 20 * time.Second, want: 3},
+		{name: "不超过上限", attempts: 5, err: errors.New("发送失败"), wait: time.Minute, want: 6},
+		{name: "渠道暂停不计次数", attempts: 2, err: errChannelPaused, wait: 10 * time.Second, want: 2},
+		{name: "达到最大发送次数", attempts: 9, err: errors.New("发送失败"), dropped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDispatcher(config.QueueConfig{MaxPending: 100, MaxAttempts: 10, RetryInitial: 5, RetryMax: 60}, "telegram")
+			c := d.channels[0]
+			item := &delivery{event: Event{Type: EventIPUnbanned}, attempts: tt.attempts}
+			before := time.Now()
+			d.retry(c, item, tt.err)
+			if tt.dropped {
+				if len(c.pending) != 0 {
+					t.Errorf("达到最大发送次数的通知未被丢弃")
+				}
+				return
+			}
+			if len(c.pending) != 1 || c.pending[0] != item {
+				t.Fatalf("通知未放入待重试列表队首")
+			}
+			if item.attempts != tt.want {
+				t.Errorf("attempts = %d，期望 %d", item.attempts, tt.want)
+			}
+			if wait := item.next.Sub(before); wait < tt.wait || wait > tt.wait+time.Second {
+				t.Errorf("等待 %v，期望 %v", wait, tt.wait)
+			}
+		})
+	}
+}
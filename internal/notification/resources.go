@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// ResourceLines 将守护进程的资源占用格式化为多行文本，用于/status、status子命令和心跳通知
+// 参数:
+//   - r: 资源占用
+// 返回:
+//   - []string: 每项一行，无法读取的项不列出
+func ResourceLines(r control.Resources) []string {
+	memory := fmt.Sprintf("内存: Go堆 %s", FormatBytes(int64(r.HeapAlloc)))
+	if r.RSS > 0 {
+		memory = fmt.Sprintf("内存: 常驻 %s，Go堆 %s", FormatBytes(r.RSS), FormatBytes(int64(r.HeapAlloc)))
+	}
+	lines := []string{memory, fmt.Sprintf("协程: %d", r.Goroutines)}
+	switch {
+	case r.OpenFiles < 0:
+	case r.MaxOpenFiles > 0:
+		lines = append(lines, fmt.Sprintf("文件描述符: %d/%d", r.OpenFiles, r.MaxOpenFiles))
+	default:
+		lines = append(lines, fmt.Sprintf("文件描述符: %d", r.OpenFiles))
+	}
+	if r.StoreBytes > 0 {
+		lines = append(lines, fmt.Sprintf("存储文件: %s", FormatBytes(r.StoreBytes)))
+	}
+	events := fmt.Sprintf("事件写入队列: %d/%d", r.EventQueue, r.EventQueueCap)
+	if r.EventsDropped > 0 {
+		events += fmt.Sprintf("（已丢弃%d）", r.EventsDropped)
+	}
+	return append(lines,
+		fmt.Sprintf("登录事件队列: %d/%d", r.LoginQueue, r.LoginQueueCap),
+		events,
+		fmt.Sprintf("通知队列: %d", r.NotifyQueue),
+		boundedLine("失败计数IP", r.TrackedIPs, r.MaxTrackedIPs, r.EvictedIPs),
+		boundedLine("限时封禁", r.TempBans, r.MaxTempBans, r.EvictedBans))
+}
+
+// boundedLine 格式化有数量上限的状态，未设置上限时只显示当前数量，发生过淘汰时附带淘汰数
+func boundedLine(label string, n, max int, evicted int64) string {
+	line := fmt.Sprintf("%s: %d", label, n)
+	if max > 0 {
+		line += fmt.Sprintf("/%d", max)
+	}
+	if evicted > 0 {
+		line += fmt.Sprintf("（已淘汰%d）", evicted)
+	}
+	return line
+}
+
+// FormatBytes 将字节数格式化为B、KB、MB或GB
+func FormatBytes(n int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
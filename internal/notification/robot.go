@@ -0,0 +1,177 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 群机器人请求的超时时间
+const robotTimeout = 10 * time.Second
+
+// robotMessage 钉钉与企业微信群机器人共用的文本消息格式
+type robotMessage struct {
+	MsgType string `json:"msgtype"`
+	Text    struct {
+		Content string `json:"content"`
+	} `json:"text"`
+}
+
+// robotResponse 群机器人接口的响应，errcode为0表示成功
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// DingTalk 钉钉群机器人通知渠道
+type DingTalk struct {
+	webhook    string       // 机器人Webhook地址，包含access_token
+	secret     string       // 加签密钥，为空时不签名
+	httpClient *http.Client // HTTP客户端
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// NewDingTalk 创建钉钉群机器人通知渠道
+// 参数:
+//   - cfg: 钉钉机器人配置
+//   - notifications: 各类通知的开关，用于发送测试通知
+// 返回:
+//   - *DingTalk: 初始化后的钉钉渠道
+func NewDingTalk(cfg config.DingTalkConfig, notifications config.NotificationsConfig) *DingTalk {
+	return &DingTalk{
+		webhook:       cfg.Webhook,
+		secret:        cfg.Secret,
+		httpClient:    &http.Client{Timeout: robotTimeout},
+		notifications: notifications,
+	}
+}
+
+// Notify 将通知以文本消息发送到钉钉群
+// 配置了加签密钥时按钉钉的要求在URL中附带timestamp和sign参数
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送过程中的错误信息
+func (d *DingTalk) Notify(event Event) error {
+	target := d.webhook
+	if d.secret != "" {
+		signed, err := dingTalkSign(target, d.secret, time.Now())
+		if err != nil {
+			return err
+		}
+		target = signed
+	}
+	if err := postRobot(d.httpClient, target, event.Text()); err != nil {
+		return fmt.Errorf("发送钉钉消息失败: %v", err)
+	}
+	return nil
+}
+
+// SendTest 使用示例数据向钉钉发送一条测试通知
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func (d *DingTalk) SendTest(name string) error {
+	return SendTest(d, d.notifications, name)
+}
+
+// dingTalkSign 为钉钉机器人地址附加签名参数
+// 签名为 Base64(HMAC-SHA256(secret, "<毫秒时间戳>\n<secret>"))，钉钉只接受一小时内的时间戳
+func dingTalkSign(webhook, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "", fmt.Errorf("无效的钉钉机器人地址: %v", err)
+	}
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// WeCom 企业微信群机器人通知渠道
+type WeCom struct {
+	webhook    string       // 机器人Webhook地址，包含key
+	httpClient *http.Client // HTTP客户端
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// NewWeCom 创建企业微信群机器人通知渠道
+// 参数:
+//   - cfg: 企业微信机器人配置
+//   - notifications: 各类通知的开关，用于发送测试通知
+// 返回:
+//   - *WeCom: 初始化后的企业微信渠道
+func NewWeCom(cfg config.WeComConfig, notifications config.NotificationsConfig) *WeCom {
+	return &WeCom{
+		webhook:       cfg.Webhook,
+		httpClient:    &http.Client{Timeout: robotTimeout},
+		notifications: notifications,
+	}
+}
+
+// Notify 将通知以文本消息发送到企业微信群
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送过程中的错误信息
+func (w *WeCom) Notify(event Event) error {
+	if err := postRobot(w.httpClient, w.webhook, event.Text()); err != nil {
+		return fmt.Errorf("发送企业微信消息失败: %v", err)
+	}
+	return nil
+}
+
+// SendTest 使用示例数据向企业微信发送一条测试通知
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func (w *WeCom) SendTest(name string) error {
+	return SendTest(w, w.notifications, name)
+}
+
+// postRobot 向群机器人发送文本消息
+// 两个平台在请求被拒绝时同样返回HTTP 200，需要检查响应中的errcode
+func postRobot(client *http.Client, target, text string) error {
+	msg := robotMessage{MsgType: "text"}
+	msg.Text.Content = text
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(target, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var result robotResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("错误码 %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package notification
+
+import (
+	"net"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 通知的严重级别，与config.Severities一致
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// defaultSeverities 各类通知的内置严重级别，可通过routing.severities覆盖
+// 封禁和登录成功需要立即知道，单次登录失败和周期报告只是参考信息
+var defaultSeverities = map[string]string{
+	EventLoginSuccess:   SeverityCritical,
+	EventIPBanned:       SeverityCritical,
+	EventRootLogin:      SeverityCritical,
+	EventNewLocation:    SeverityCritical,
+	EventPasswordSpray:  SeverityWarning,
+	EventSubnetAttack:   SeverityWarning,
+	EventRuleMatched:    SeverityWarning,
+	EventLogLag:         SeverityWarning,
+	EventAuditReport:    SeverityWarning,
+	EventAllowExpiring:  SeverityWarning,
+	EventAccountLock:    SeverityCritical,
+	EventLoginFailed:    SeverityInfo,
+	EventIPUnbanned:     SeverityInfo,
+	EventLogout:         SeverityInfo,
+	EventAdaptiveReport: SeverityInfo,
+	EventUnbanDigest:    SeverityInfo,
+	EventSummary:        SeverityInfo,
+	EventHeartbeat:      SeverityInfo,
+	EventStartup:        SeverityInfo,
+	EventBatch:          SeverityInfo,
+}
+
+// Severity 返回某类通知的严重级别
+// 参数:
+//   - routing: 路由配置
+//   - eventType: 事件类型
+// 返回:
+//   - string: 严重级别，未知的事件类型为info
+func Severity(routing config.RoutingConfig, eventType string) string {
+	if s, ok := routing.Severities[eventType]; ok {
+		return s
+	}
+	if s, ok := defaultSeverities[eventType]; ok {
+		return s
+	}
+	return SeverityInfo
+}
+
+// networkRoute 解析后的来源网段路由条目
+type networkRoute struct {
+	config.NetworkRouteConfig
+	parsed []*net.IPNet
+}
+
+// parseNetworkRoutes 解析来源网段路由，无效的网段在加载配置时已被拒绝，这里直接跳过
+func parseNetworkRoutes(routes []config.NetworkRouteConfig) []networkRoute {
+	parsed := make([]networkRoute, 0, len(routes))
+	for _, r := range routes {
+		route := networkRoute{NetworkRouteConfig: r}
+		for _, entry := range r.Networks {
+			if network, err := config.ParseNetwork(entry); err == nil {
+				route.parsed = append(route.parsed, network)
+			}
+		}
+		parsed = append(parsed, route)
+	}
+	return parsed
+}
+
+// networkRouteFor 返回通知来源IP匹配的第一个网段路由条目
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - *networkRoute: 匹配的条目，通知没有来源IP或没有匹配的条目时为nil
+func (d *Dispatcher) networkRouteFor(event Event) *networkRoute {
+	ip := net.ParseIP(dataField(event.Data, "IP"))
+	if ip == nil {
+		return nil
+	}
+	for i := range d.networks {
+		route := &d.networks[i]
+		if len(route.Events) > 0 && !slices.Contains(route.Events, event.Type) {
+			continue
+		}
+		for _, network := range route.parsed {
+			if network.Contains(ip) {
+				return route
+			}
+		}
+	}
+	return nil
+}
+
+// severityRank 返回严重级别的排序，级别越高数值越大
+func severityRank(severity string) int {
+	return slices.Index(config.Severities, severity)
+}
+
+// inQuietHours 判断某一时刻是否处于免打扰时段
+// 参数:
+//   - q: 免打扰配置
+//   - now: 要判断的时刻
+// 返回:
+//   - bool: 已启用且now落在[start, end)内时为true，start晚于end时跨越午夜
+func inQuietHours(q config.QuietHoursConfig, now time.Time) bool {
+	if !q.Enabled {
+		return false
+	}
+	start, err1 := time.Parse(config.ClockLayout, q.Start)
+	end, err2 := time.Parse(config.ClockLayout, q.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	minute := now.Hour()*60 + now.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
+
+// route 返回一条通知应发送到的渠道
+// 来源IP匹配routing.networks中的条目时发送到该条目的渠道，否则按严重级别路由；
+// 免打扰期间低于min_severity的通知以及路由到空渠道列表的通知只写入日志，返回空列表
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - []*channel: 应发送的渠道
+func (d *Dispatcher) route(event Event) []*channel {
+	routing := d.notifications.Routing
+	severity := Severity(routing, event.Type)
+	fields := logrus.Fields{"event": event.Type, "severity": severity, "text": event.Text()}
+
+	if inQuietHours(routing.QuietHours, time.Now()) && severityRank(severity) < severityRank(routing.QuietHours.MinSeverity) {
+		d.logger.WithFields(fields).Info("免打扰时段，通知只写入日志")
+		return nil
+	}
+
+	names, ok := routing.Channels[severity]
+	if route := d.networkRouteFor(event); route != nil {
+		names, ok = route.Channels, true
+		fields["network"] = route.Name
+	}
+	if !ok {
+		return d.channels
+	}
+	var channels []*channel
+	for _, c := range d.channels {
+		if slices.Contains(names, c.name) {
+			channels = append(channels, c)
+		}
+	}
+	if len(channels) == 0 {
+		d.logger.WithFields(fields).Info("该级别的通知未路由到任何渠道，只写入日志")
+	}
+	return channels
+}
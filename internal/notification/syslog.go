@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/logging"
+)
+
+// Syslog 将安全事件发送到syslog
+// 事件类型作为MSGID，模板字段作为结构化数据，纯文本消息作为MSG
+type Syslog struct {
+	client  *logging.Syslog      // syslog客户端
+	events  incidentFilter       // 发送的事件类型，为空时发送所有事件
+	routing config.RoutingConfig // 用于确定事件的严重级别
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// NewSyslog 创建syslog通知渠道
+// 参数:
+//   - client: syslog客户端，与程序日志共用
+//   - events: 发送的事件类型，为空时发送所有事件
+//   - notifications: 通知配置，用于确定严重级别和发送测试通知
+// 返回:
+//   - *Syslog: 初始化后的syslog渠道
+func NewSyslog(client *logging.Syslog, events []string, notifications config.NotificationsConfig) *Syslog {
+	return &Syslog{
+		client:        client,
+		events:        newIncidentFilter(events),
+		routing:       notifications.Routing,
+		notifications: notifications,
+	}
+}
+
+// Notify 将事件发送到syslog，未在events中列出的事件被忽略
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送失败时的错误信息
+func (s *Syslog) Notify(event Event) error {
+	if len(s.events) > 0 && !s.events[event.Type] {
+		return nil
+	}
+	at := event.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return s.client.Send(syslogSeverity(Severity(s.routing, event.Type)), event.Type, at, event.Text(), syslogFields(event.Data))
+}
+
+// SendTest 使用示例数据向syslog发送一条测试通知
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func (s *Syslog) SendTest(name string) error {
+	return SendTest(s, s.notifications, name)
+}
+
+// syslogSeverity 将通知的严重级别转换为syslog严重级别，info对应notice
+func syslogSeverity(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return logging.SyslogCrit
+	case SeverityWarning:
+		return logging.SyslogWarning
+	}
+	return logging.SyslogNotice
+}
+
+// syslogFields 将模板字段结构体中非空的字符串与数字字段转换为结构化数据，参数名与Webhook中的JSON字段名一致
+func syslogFields(data interface{}) map[string]string {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	fields := make(map[string]string)
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		switch value := v.Field(i); value.Kind() {
+		case reflect.String:
+			if value.String() != "" {
+				fields[name] = strings.ReplaceAll(value.String(), "\n", "; ")
+			}
+		case reflect.Int:
+			fields[name] = fmt.Sprint(value.Int())
+		}
+	}
+	return fields
+}
@@ -2,26 +2,51 @@
 package notification
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
 )
 
+// /banned命令每页显示的封禁条数
+const bannedPageSize = 10
+
 // Telegram 结构体封装了Telegram机器人的功能
 type Telegram struct {
-	bot     *tgbotapi.BotAPI  // Telegram机器人API实例
-	chatID  int64            // 接收通知的聊天ID
-	logger  *logrus.Logger   // 日志记录器
-	config  *Config          // 配置信息
+	bot    *tgbotapi.BotAPI            // Telegram机器人API实例
+	chats  []config.TelegramChatConfig // 接收通知的聊天
+	logger *logrus.Logger              // 日志记录器
+	config *Config                     // 配置信息
+
+	controller control.Controller // 管理操作，由监控器提供
+	poll       pollState          // 命令处理循环的健康状态，见updates.go
 }
 
 // Config 定义了Telegram机器人的配置参数
 type Config struct {
 	BotToken string // Telegram机器人Token
-	ChatID   int64  // 接收通知的聊天ID
+	ChatID   int64  // 接收全部通知的聊天ID，为0时只发送到Chats
+	ThreadID int    // ChatID中的话题ID，0表示General话题
 	Debug    bool   // 是否启用调试模式
+	ProxyURL string // 访问Telegram接口使用的代理，为空时直连
+
+	ParseMode string // 通知消息的格式，取值见ParseMode*常量，为空时发送纯文本
+
+	Chats []config.TelegramChatConfig // 按通知类型接收通知的额外聊天
+
+	AuthorizedUserIDs []int64 // 允许执行管理命令的用户ID，为空时按聊天判断
+
+	Notifications config.NotificationsConfig // 各类通知的开关与模板
 }
 
 // NewTelegram 创建并初始化一个新的Telegram通知实例
@@ -32,7 +57,11 @@ type Config struct {
 //   - *Telegram: 初始化后的Telegram实例
 //   - error: 初始化过程中的错误信息
 func NewTelegram(config *Config, logger *logrus.Logger) (*Telegram, error) {
-	bot, err := tgbotapi.NewBotAPI(config.BotToken)
+	client, err := telegramClient(config.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	bot, err := tgbotapi.NewBotAPIWithClient(config.BotToken, tgbotapi.APIEndpoint, client)
 	if err != nil {
 		return nil, fmt.Errorf("Telegram机器人初始化失败: %v", err)
 	}
@@ -41,165 +70,758 @@ func NewTelegram(config *Config, logger *logrus.Logger) (*Telegram, error) {
 
 	return &Telegram{
 		bot:    bot,
-		chatID: config.ChatID,
+		chats:  config.targets(),
 		logger: logger,
 		config: config,
 	}, nil
 }
 
+// targets 返回接收通知的所有聊天，chat_id排在最前面且接收全部通知
+func (c *Config) targets() []config.TelegramChatConfig {
+	var chats []config.TelegramChatConfig
+	if c.ChatID != 0 {
+		chats = append(chats, config.TelegramChatConfig{ChatID: c.ChatID, ThreadID: c.ThreadID})
+	}
+	return append(chats, c.Chats...)
+}
+
+// telegramClient 创建访问Telegram接口的HTTP客户端
+// net/http按代理地址的协议自动选择HTTP CONNECT或SOCKS5，socks5h表示由代理解析域名
+// 参数:
+//   - proxyURL: 代理地址，为空时使用直连的默认客户端
+// 返回:
+//   - *http.Client: HTTP客户端
+//   - error: 代理地址无效时的错误信息
+func telegramClient(proxyURL string) (*http.Client, error) {
+	if proxyURL == "" {
+		return &http.Client{}, nil
+	}
+	proxy, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("Telegram代理地址无效: %v", err)
+	}
+	if proxy.Scheme == "socks5h" {
+		// 较早版本的net/http只识别socks5，两者都把域名交给代理解析
+		proxy.Scheme = "socks5"
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+	return &http.Client{Transport: transport}, nil
+}
+
+// SetController 设置管理命令使用的控制器
+// 参数:
+//   - controller: 监控器提供的管理操作
+func (t *Telegram) SetController(controller control.Controller) {
+	t.controller = controller
+}
+
 // SendMessage 发送文本消息到指定的聊天
+// 使用的接口库不支持message_thread_id，直接调用sendMessage接口
 // 参数:
+//   - chat: 接收消息的聊天及话题
 //   - text: 要发送的消息内容
 // 返回:
 //   - error: 发送过程中的错误信息
-func (t *Telegram) SendMessage(text string) error {
-	msg := tgbotapi.NewMessage(t.chatID, text)
-	_, err := t.bot.Send(msg)
-	if err != nil {
+func (t *Telegram) SendMessage(chat config.TelegramChatConfig, text string) error {
+	return t.send(chat, text, "", nil)
+}
+
+// send 发送文本消息，parseMode为Telegram接口的格式参数，为空时发送纯文本；markup不为nil时附带内联键盘
+func (t *Telegram) send(chat config.TelegramChatConfig, text, parseMode string, markup interface{}) error {
+	params := tgbotapi.Params{"text": text}
+	params.AddFirstValid("chat_id", chat.ChatID)
+	params.AddNonZero("message_thread_id", chat.ThreadID)
+	params.AddNonEmpty("parse_mode", parseMode)
+	if err := params.AddInterface("reply_markup", markup); err != nil {
+		return fmt.Errorf("发送Telegram消息失败: %v", err)
+	}
+	if _, err := t.bot.MakeRequest("sendMessage", params); err != nil {
 		return fmt.Errorf("发送Telegram消息失败: %v", err)
 	}
 	return nil
 }
 
-// NotifyLoginSuccess 发送SSH登录成功的通知
+// Probe 检查Telegram接口是否可用，不发送消息
+// 返回:
+//   - error: 接口不可用或Token无效时的错误信息
+func (t *Telegram) Probe() error {
+	_, err := t.bot.GetMe()
+	return err
+}
+
+// Notify 将通知发送到订阅了该类通知的所有聊天
+// 实现Notifier接口，是否发送由Dispatcher判断；封禁通知附带解封、临时白名单和永久封禁按钮，
+// 临时白名单到期提醒附带续期按钮。配置了parse_mode时按对应格式发送，
+// Telegram无法解析消息中的格式标记时改为发送纯文本，通知不会因为模板中的标记有误而丢失
 // 参数:
-//   - ip: 登录IP地址
-//   - ipInfo: IP地址的详细信息
-//   - server: 服务器信息
+//   - event: 要发送的通知
 // 返回:
-//   - error: 发送过程中的错误信息
-func (t *Telegram) NotifyLoginSuccess(ip, ipInfo, server string) error {
-	if !t.config.Notifications.LoginSuccess.Enabled {
-		return nil
+//   - error: 发送过程中的错误信息，部分聊天发送失败时合并返回
+func (t *Telegram) Notify(event Event) error {
+	text, parseMode := event.Text(), ""
+	if mode := t.config.ParseMode; mode != "" {
+		formatted, err := formatMessage(t.config.Notifications, event, mode)
+		if err != nil {
+			t.logger.WithError(err).WithField("event", event.Type).Warn("按parse_mode渲染通知失败，发送纯文本")
+		} else {
+			text, parseMode = formatted, telegramParseModes[mode]
+		}
 	}
+	var markup interface{}
+	if t.controller != nil {
+		switch data := event.Data.(type) {
+		case IPBannedData:
+			markup = banKeyboard(data.IP)
+		case AllowExpiringData:
+			markup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("续期", "renew:"+data.Entry)))
+		}
+	}
+	var errs []error
+	for _, chat := range t.chats {
+		if len(chat.Events) > 0 && !slices.Contains(chat.Events, event.Type) {
+			continue
+		}
+		err := t.send(chat, text, parseMode, markup)
+		if err != nil && parseMode != "" && strings.Contains(err.Error(), "can't parse entities") {
+			t.logger.WithError(err).WithField("event", event.Type).Warn("Telegram无法解析消息格式，改为发送纯文本")
+			err = t.send(chat, event.Text(), "", markup)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("聊天%d: %v", chat.ChatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	text := fmt.Sprintf("✅ SSH登录成功\n时间: %s\n%s\n服务器: %s",
-		time.Now().Format("2006-01-02 15:04:05"),
-		ipInfo,
-		server)
-
-	return t.SendMessage(text)
+// TestCommand 测试所有通知功能
+// 发送测试消息以验证通知系统是否正常工作，未启用的通知类型会被跳过
+// 返回:
+//   - error: 测试过程中的错误信息
+func (t *Telegram) TestCommand() error {
+	for _, name := range TestEvents {
+		if err := t.SendTest(name); err != nil && err != ErrDisabled {
+			return fmt.Errorf("测试%s通知失败: %v", name, err)
+		}
+	}
+	return nil
 }
 
-// NotifyLoginFailed 发送SSH登录失败的通知
+// HandleCommands 处理Telegram命令
+// 通过长轮询接收并处理来自Telegram的命令消息以及封禁通知中按钮的回调；拉取更新失败时按指数退避重试，
+// 处理单条更新时的panic会被恢复，命令处理不会因为网络中断或个别异常而永久停止
+// 支持的命令:
+//   - /start: 显示欢迎信息
+//   - /status: 显示系统状态
+//   - /test: 测试通知功能
+//   - /permanent <IP>: 将IP提升为永久封禁
+//   - /jail [enable|disable <名称>]: 查看或切换jail的启用状态
+//   - /help: 显示帮助信息
 // 参数:
-//   - ip: 登录IP地址
-//   - ipInfo: IP地址的详细信息
-//   - server: 服务器信息
-//   - attempts: 当前失败次数
-//   - maxAttempts: 最大允许失败次数
+//   - ctx: 取消时停止接收更新并返回
 // 返回:
-//   - error: 发送过程中的错误信息
-func (t *Telegram) NotifyLoginFailed(ip, ipInfo, server string, attempts, maxAttempts int) error {
-	if !t.config.Notifications.LoginFailed.Enabled {
-		return nil
+//   - error: 目前总是返回nil
+func (t *Telegram) HandleCommands(ctx context.Context) error {
+	t.poll.start()
+	defer t.poll.stop()
+
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = pollTimeout
+	retry := pollRetryInitial
+	for {
+		updates, err := t.getUpdates(ctx, u)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			failures := t.poll.failed(err)
+			t.logger.WithError(err).WithFields(logrus.Fields{"failures": failures, "retry_in": retry.String()}).Warn("拉取Telegram更新失败，稍后重试")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(retry):
+			}
+			retry = min(retry*2, pollRetryMax)
+			continue
+		}
+		if t.poll.succeeded() {
+			t.logger.Info("已恢复拉取Telegram更新")
+		}
+		retry = pollRetryInitial
+
+		for _, update := range updates {
+			if update.UpdateID >= u.Offset {
+				u.Offset = update.UpdateID + 1
+			}
+			t.handleUpdate(update)
+		}
+	}
+}
+
+// handleUpdate 处理一条Telegram更新：按钮回调或命令消息
+// 处理过程中的panic被恢复并记录，不影响后续更新
+func (t *Telegram) handleUpdate(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.poll.recovered()
+			t.logger.WithFields(logrus.Fields{"update_id": update.UpdateID, "panic": r}).Error("处理Telegram更新时发生异常，已跳过该更新")
+		}
+	}()
+
+	if query := update.CallbackQuery; query != nil {
+		if !t.authorized(query.From, query.Message) {
+			t.rejectUnauthorized(query.From, query.Message, query.Data)
+			if _, err := t.bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, "无权执行该操作")); err != nil {
+				t.logger.WithError(err).Error("响应按钮回调失败")
+			}
+			return
+		}
+		t.handleCallback(query)
+		return
+	}
+	if update.Message == nil {
+		return
+	}
+
+	if !update.Message.IsCommand() {
+		return
+	}
+	if !t.authorized(update.Message.From, update.Message) {
+		t.rejectUnauthorized(update.Message.From, update.Message, update.Message.Text)
+		return
 	}
 
-	text := fmt.Sprintf("⚠️ SSH登录失败\n时间: %s\n%s\n失败次数: %d/%d\n服务器: %s",
-		time.Now().Format("2006-01-02 15:04:05"),
-		ipInfo,
-		attempts,
-		maxAttempts,
-		server)
+	msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+
+	switch update.Message.Command() {
+	case "start":
+		msg.Text = "欢迎使用SSH防护系统！\n可用命令：\n/status - 查看系统状态\n/test - 测试通知功能\n/ban <IP> [时长] - 封禁IP\n/unban <IP> - 解除封禁\n/banned - 查看封禁列表\n/stats [day] - 查看攻击统计\n/permanent <IP> - 永久封禁IP\n/whitelist add|del <IP或网段> - 管理白名单\n/jail [enable|disable <名称>] - 查看或切换jail\n/help - 显示帮助信息"
+	case "status":
+		msg.Text = t.handleStatus()
+	case "test":
+		if err := t.TestCommand(); err != nil {
+			msg.Text = fmt.Sprintf("测试失败: %v", err)
+		} else {
+			msg.Text = "测试通知已发送，请检查是否收到"
+		}
+	case "ban":
+		msg.Text = t.handleBan(update.Message.CommandArguments())
+	case "unban":
+		msg.Text = t.handleUnban(update.Message.CommandArguments())
+	case "banned":
+		page, _ := strconv.Atoi(strings.TrimSpace(update.Message.CommandArguments()))
+		var markup *tgbotapi.InlineKeyboardMarkup
+		msg.Text, markup = t.handleBanned(page)
+		if markup != nil {
+			msg.ReplyMarkup = *markup
+		}
+	case "stats":
+		msg.Text = t.handleStats(update.Message.CommandArguments())
+	case "permanent":
+		msg.Text = t.handlePermanent(update.Message.CommandArguments())
+	case "whitelist":
+		msg.Text = t.handleWhitelist(update.Message.CommandArguments())
+	case "jail":
+		msg.Text = t.handleJail(update.Message.CommandArguments())
+	case "help":
+		msg.Text = "SSH防护系统命令：\n/start - 开始使用\n/status - 查看系统状态\n/test - 测试通知功能\n/ban <IP> [时长] - 封禁IP，时长如 30m、12h、7d，只写数字时按小时计，不指定时使用配置的封禁时长\n/unban <IP> - 解除封禁\n/banned [页码] - 查看封禁列表\n/stats [hour|day] - 查看最近24小时或最近7天的攻击统计\n/permanent <IP> - 永久封禁IP\n/whitelist add|del <IP或网段> - 添加或删除白名单\n/jail [enable|disable <名称>] - 查看或切换jail\n/help - 显示此帮助信息"
+	default:
+		msg.Text = "未知命令，请使用 /help 查看可用命令"
+	}
 
-	return t.SendMessage(text)
+	if _, err := t.bot.Send(msg); err != nil {
+		t.logger.WithError(err).Error("发送命令响应失败")
+	}
 }
 
-// NotifyIPBanned 发送IP被封禁的通知
+// authorized 检查用户是否可以执行管理命令
+// 配置了authorized_user_ids时只按用户ID判断；否则只接受来自接收通知的聊天中的命令
 // 参数:
-//   - ip: 被封禁的IP地址
-//   - ipInfo: IP地址的详细信息
-//   - server: 服务器信息
-//   - duration: 封禁时长
-//   - expireTime: 解封时间
+//   - user: 发送命令或点击按钮的用户
+//   - message: 命令消息或按钮所在的消息
 // 返回:
-//   - error: 发送过程中的错误信息
-func (t *Telegram) NotifyIPBanned(ip, ipInfo, server string, duration time.Duration, expireTime time.Time) error {
-	if !t.config.Notifications.IPBanned.Enabled {
-		return nil
+//   - bool: true表示允许执行
+func (t *Telegram) authorized(user *tgbotapi.User, message *tgbotapi.Message) bool {
+	if len(t.config.AuthorizedUserIDs) > 0 {
+		return user != nil && slices.Contains(t.config.AuthorizedUserIDs, user.ID)
 	}
+	if message == nil || message.Chat == nil {
+		return false
+	}
+	for _, chat := range t.chats {
+		if chat.ChatID == message.Chat.ID {
+			return true
+		}
+	}
+	return false
+}
 
-	text := fmt.Sprintf("🚫 IP %s 已被封禁\n时间: %s\n%s\n原因: SSH暴力破解\n封禁时长: %.0f小时\n解封时间: %s\n服务器: %s",
-		ip,
-		time.Now().Format("2006-01-02 15:04:05"),
-		ipInfo,
-		duration.Hours(),
-		expireTime.Format("2006-01-02 15:04:05"),
-		server)
+// rejectUnauthorized 记录未授权的命令，不回复消息，避免向陌生用户暴露机器人的用途
+func (t *Telegram) rejectUnauthorized(user *tgbotapi.User, message *tgbotapi.Message, command string) {
+	fields := logrus.Fields{"command": command}
+	if user != nil {
+		fields["user_id"] = user.ID
+		fields["username"] = user.UserName
+	}
+	if message != nil && message.Chat != nil {
+		fields["chat_id"] = message.Chat.ID
+	}
+	t.logger.WithFields(fields).Warn("拒绝未授权用户的Telegram命令")
+}
 
-	return t.SendMessage(text)
+// handleStatus 处理/status命令
+// 返回:
+//   - string: 命令响应内容
+func (t *Telegram) handleStatus() string {
+	var b strings.Builder
+	if t.controller == nil {
+		return "系统状态：\n- 运行中"
+	}
+
+	status := t.controller.Status()
+	b.WriteString("系统状态：")
+	if !status.Started.IsZero() {
+		fmt.Fprintf(&b, "\n- 已运行 %s", formatUptime(time.Since(status.Started)))
+	}
+	fmt.Fprintf(&b, "\n- 当前封禁 %d 个IP（永久 %d 个），限速 %d 个", status.Banned, status.Permanent, status.Limited)
+	fmt.Fprintf(&b, "\n- 最近1小时登录失败 %d 次", status.FailuresLastHour)
+	switch fw := status.Firewall; {
+	case fw.Error != "":
+		fmt.Fprintf(&b, "\n- 防火墙 %s 状态未知：%s", fw.Backend, fw.Error)
+	case fw.Active:
+		fmt.Fprintf(&b, "\n- 防火墙 %s 已启用", fw.Backend)
+	default:
+		fmt.Fprintf(&b, "\n- ⚠️ 防火墙 %s 未启用，封禁不会生效", fw.Backend)
+	}
+
+	b.WriteString("\n日志读取：")
+	for _, jail := range status.Jails {
+		if !jail.Enabled {
+			fmt.Fprintf(&b, "\n- %s：已停用", jail.Name)
+			continue
+		}
+		state := "读取中"
+		if !jail.Running {
+			state = "⚠️ 已中断"
+		}
+		fmt.Fprintf(&b, "\n- %s：%s，已读取 %d 行", jail.Name, state, jail.Lines)
+		if !jail.LastRead.IsZero() {
+			fmt.Fprintf(&b, "，最近一行 %s 前", formatUptime(time.Since(jail.LastRead)))
+		}
+		if jail.Queue > 0 {
+			fmt.Fprintf(&b, "，待处理 %d 行", jail.Queue)
+		}
+		if jail.LastError != "" {
+			fmt.Fprintf(&b, "\n  最近错误：%s", jail.LastError)
+		}
+	}
+
+	info := status.IPInfo
+	fmt.Fprintf(&b, "\nIP信息接口：\n- 请求 %d 次，失败 %d 次，限流 %d 次", info.Requests, info.Failures, info.Throttled)
+	if lookups := info.CacheHits + info.CacheMisses; lookups > 0 {
+		fmt.Fprintf(&b, "\n- 缓存 %d 个IP，命中率 %d%%", info.Cached, info.CacheHits*100/lookups)
+	}
+	if info.QuotaRemaining >= 0 {
+		fmt.Fprintf(&b, "\n- 剩余配额 %d", info.QuotaRemaining)
+	}
+	if info.BreakerOpen {
+		fmt.Fprintf(&b, "\n- 熔断中，%s 恢复，期间跳过 %d 次查询", info.BreakerUntil.Format("15:04:05"), info.Skipped)
+	}
+	for _, c := range status.Channels {
+		if c.State == StateHealthy {
+			continue
+		}
+		fmt.Fprintf(&b, "\n通知渠道 %s：连续失败 %d 次，%d 条待重试", c.Name, c.ConsecutiveFailures, c.Pending)
+		if c.State == StateDisabled {
+			fmt.Fprintf(&b, "，已暂停，%s 探测恢复", c.NextProbe.Format("15:04:05"))
+		}
+	}
+	if u := t.UpdatesHealth(); u.Failures > 0 || u.Panics > 0 {
+		fmt.Fprintf(&b, "\n命令接收：拉取更新累计失败 %d 次，处理异常 %d 次", u.Failures, u.Panics)
+		if !u.LastFailure.IsZero() {
+			fmt.Fprintf(&b, "，最近一次失败于 %s", u.LastFailure.Format("2006-01-02 15:04:05"))
+		}
+	}
+	if ban := status.BanLatency; ban.Count > 0 {
+		fmt.Fprintf(&b, "\n封禁生效耗时：\n- P50 %.2f 秒，P99 %.2f 秒，最大 %.2f 秒", ban.Total.P50, ban.Total.P99, ban.Total.Max)
+		if ban.OverSLO > 0 {
+			fmt.Fprintf(&b, "\n- %d 次超过目标 %g 秒", ban.OverSLO, ban.SLO)
+		}
+	}
+	if lag := status.LogLag; lag.Count > 0 {
+		fmt.Fprintf(&b, "\n日志处理延迟：\n- 平均 %.1f 秒，最大 %.1f 秒，最近 %.1f 秒\n- 待处理 %d 条", lag.Sum/float64(lag.Count), lag.Max, lag.Last, lag.Queue)
+	}
+	if s := status.Sampling; s.Enabled {
+		fmt.Fprintf(&b, "\n登录失败抽样：\n- 每 %d 个保存 1 个，共 %d 个，已保存 %d 个", s.Rate, s.Seen, s.Kept)
+	}
+	b.WriteString("\n资源占用：")
+	for _, line := range ResourceLines(status.Resources) {
+		fmt.Fprintf(&b, "\n- %s", line)
+	}
+	return b.String()
 }
 
-// TestCommand 测试所有通知功能
-// 发送测试消息以验证通知系统是否正常工作
+// formatUptime 将时长格式化为天、小时、分钟，不足一分钟时显示秒数
+func formatUptime(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%d秒", int(d.Seconds()))
+	}
+	days, hours, minutes := int(d.Hours())/24, int(d.Hours())%24, int(d.Minutes())%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d天%d小时%d分钟", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d小时%d分钟", hours, minutes)
+	}
+	return fmt.Sprintf("%d分钟", minutes)
+}
+
+// handleBan 处理/ban命令
+// 参数:
+//   - args: 命令参数，格式为 <IP> [时长]，时长只写数字时按小时计
 // 返回:
-//   - error: 测试过程中的错误信息
-func (t *Telegram) TestCommand() error {
-	// 测试登录成功通知
-	if err := t.NotifyLoginSuccess("192.168.1.1", "IP: 192.168.1.1\n属地: 中国 北京\nISP: 测试ISP", "测试服务器"); err != nil {
-		return fmt.Errorf("测试登录成功通知失败: %v", err)
+//   - string: 命令响应内容
+func (t *Telegram) handleBan(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
 	}
 
-	// 测试登录失败通知
-	if err := t.NotifyLoginFailed("192.168.1.2", "IP: 192.168.1.2\n属地: 中国 上海\nISP: 测试ISP", "测试服务器", 3, 5); err != nil {
-		return fmt.Errorf("测试登录失败通知失败: %v", err)
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "用法: /ban <IP> [时长]"
+	}
+	var duration time.Duration
+	if len(fields) == 2 {
+		d, err := parseBanDuration(fields[1])
+		if err != nil || d <= 0 {
+			return "封禁时长必须为正数，如 30m、12h、7d，只写数字时按小时计"
+		}
+		duration = d
 	}
 
-	// 测试IP封禁通知
-	if err := t.NotifyIPBanned("192.168.1.3", "IP: 192.168.1.3\n属地: 中国 广州\nISP: 测试ISP", "测试服务器", 24*time.Hour, time.Now().Add(24*time.Hour)); err != nil {
-		return fmt.Errorf("测试IP封禁通知失败: %v", err)
+	if err := t.controller.Ban(fields[0], duration); err != nil {
+		return fmt.Sprintf("封禁失败: %v", err)
+	}
+	if duration == 0 {
+		return fmt.Sprintf("IP %s 已封禁", fields[0])
 	}
+	return fmt.Sprintf("IP %s 已封禁 %s", fields[0], config.Duration(duration))
+}
 
-	return nil
+// parseBanDuration 解析/ban命令的时长，只写数字时按小时计，兼容旧的用法
+func parseBanDuration(s string) (time.Duration, error) {
+	if hours, err := strconv.Atoi(s); err == nil {
+		return time.Duration(hours) * time.Hour, nil
+	}
+	return config.ParseDuration(s)
 }
 
-// HandleCommands 处理Telegram命令
-// 监听并处理来自Telegram的命令消息
-// 支持的命令:
-//   - /start: 显示欢迎信息
-//   - /status: 显示系统状态
-//   - /test: 测试通知功能
-//   - /help: 显示帮助信息
+// handleUnban 处理/unban命令
+// 参数:
+//   - args: 命令参数，即要解除封禁的IP
 // 返回:
-//   - error: 处理过程中的错误信息
-func (t *Telegram) HandleCommands() error {
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+//   - string: 命令响应内容
+func (t *Telegram) handleUnban(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
+	}
 
-	updates := t.bot.GetUpdatesChan(u)
+	ip := strings.TrimSpace(args)
+	if ip == "" {
+		return "用法: /unban <IP>"
+	}
 
-	for update := range updates {
-		if update.Message == nil {
-			continue
+	if err := t.controller.Unban(ip); err != nil {
+		return fmt.Sprintf("解封失败: %v", err)
+	}
+	return fmt.Sprintf("IP %s 已解除封禁", ip)
+}
+
+// handleBanned 处理/banned命令，分页列出当前的封禁
+// 参数:
+//   - page: 页码，从1开始，超出范围时显示最后一页
+// 返回:
+//   - string: 命令响应内容
+//   - *tgbotapi.InlineKeyboardMarkup: 翻页按钮，只有一页时为nil
+func (t *Telegram) handleBanned(page int) (string, *tgbotapi.InlineKeyboardMarkup) {
+	if t.controller == nil {
+		return "管理功能不可用", nil
+	}
+
+	bans := t.controller.Bans()
+	if len(bans) == 0 {
+		return "当前没有封禁的IP", nil
+	}
+	pages := (len(bans) + bannedPageSize - 1) / bannedPageSize
+	page = min(max(page, 1), pages)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "当前封禁 %d 个IP（第 %d/%d 页）：", len(bans), page, pages)
+	for _, ban := range bans[(page-1)*bannedPageSize : min(page*bannedPageSize, len(bans))] {
+		switch {
+		case ban.Permanent:
+			fmt.Fprintf(&b, "\n- %s 永久封禁", ban.IP)
+		case ban.Limited:
+			fmt.Fprintf(&b, "\n- %s 限速至 %s", ban.IP, ban.ExpireTime.Format(timeLayout))
+		default:
+			fmt.Fprintf(&b, "\n- %s 封禁至 %s", ban.IP, ban.ExpireTime.Format(timeLayout))
 		}
+		if ban.ASN != "" {
+			fmt.Fprintf(&b, "（%s %s）", ban.ASN, ban.ASOrg)
+		}
+		if ban.Origin != "" {
+			fmt.Fprintf(&b, " 来自 %s", ban.Origin)
+		}
+	}
+	if pages == 1 {
+		return b.String(), nil
+	}
 
-		if !update.Message.IsCommand() {
-			continue
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("上一页", fmt.Sprintf("banned:%d", page-1)))
+	}
+	if page < pages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("下一页", fmt.Sprintf("banned:%d", page+1)))
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(row)
+	return b.String(), &markup
+}
+
+// handleWhitelist 处理/whitelist命令
+// 参数:
+//   - args: 命令参数，格式为 add|del <IP或网段>
+// 返回:
+//   - string: 命令响应内容
+func (t *Telegram) handleWhitelist(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) != 2 || (fields[0] != "add" && fields[0] != "del") {
+		return "用法: /whitelist add|del <IP或网段>"
+	}
+	if fields[0] == "add" {
+		if err := t.controller.AddWhitelist(fields[1]); err != nil {
+			return fmt.Sprintf("添加白名单失败: %v", err)
 		}
+		return fmt.Sprintf("%s 已加入白名单", fields[1])
+	}
+	if err := t.controller.RemoveWhitelist(fields[1]); err != nil {
+		return fmt.Sprintf("删除白名单失败: %v", err)
+	}
+	return fmt.Sprintf("%s 已从白名单删除", fields[1])
+}
+
+// handlePermanent 处理/permanent命令
+// 参数:
+//   - args: 命令参数，即要永久封禁的IP
+// 返回:
+//   - string: 命令响应内容
+func (t *Telegram) handlePermanent(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
+	}
 
-		msg := tgbotapi.NewMessage(update.Message.Chat.ID, "")
+	ip := strings.TrimSpace(args)
+	if ip == "" {
+		return "用法: /permanent <IP>"
+	}
+
+	if err := t.controller.MakePermanent(ip); err != nil {
+		return fmt.Sprintf("永久封禁失败: %v", err)
+	}
+	return fmt.Sprintf("IP %s 已永久封禁", ip)
+}
 
-		switch update.Message.Command() {
-		case "start":
-			msg.Text = "欢迎使用SSH防护系统！\n可用命令：\n/status - 查看系统状态\n/test - 测试通知功能\n/help - 显示帮助信息"
-		case "status":
-			msg.Text = "系统状态：\n- 运行中\n- 监控正常\n- 通知正常"
-		case "test":
-			if err := t.TestCommand(); err != nil {
-				msg.Text = fmt.Sprintf("测试失败: %v", err)
-			} else {
-				msg.Text = "测试通知已发送，请检查是否收到"
+// handleJail 处理/jail命令
+// 参数:
+//   - args: 命令参数，为空时列出所有jail，否则为 enable|disable <名称>
+// 返回:
+//   - string: 命令响应内容
+func (t *Telegram) handleJail(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
+	}
+
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		var b strings.Builder
+		b.WriteString("Jail状态：")
+		for _, jail := range t.controller.Jails() {
+			state := "已启用"
+			if !jail.Enabled {
+				state = "已停用"
 			}
-		case "help":
-			msg.Text = "SSH防护系统命令：\n/start - 开始使用\n/status - 查看系统状态\n/test - 测试通知功能\n/help - 显示此帮助信息"
-		default:
-			msg.Text = "未知命令，请使用 /help 查看可用命令"
+			fmt.Fprintf(&b, "\n- %s: %s", jail.Name, state)
 		}
+		return b.String()
+	}
 
-		if _, err := t.bot.Send(msg); err != nil {
-			t.logger.WithError(err).Error("发送命令响应失败")
+	if len(fields) != 2 || (fields[0] != "enable" && fields[0] != "disable") {
+		return "用法: /jail [enable|disable <名称>]"
+	}
+	enabled := fields[0] == "enable"
+	if err := t.controller.SetJailEnabled(fields[1], enabled); err != nil {
+		return fmt.Sprintf("切换jail状态失败: %v", err)
+	}
+	if enabled {
+		return fmt.Sprintf("jail %s 已启用", fields[1])
+	}
+	return fmt.Sprintf("jail %s 已停用", fields[1])
+}
+
+// handleStats 处理/stats命令
+// 参数:
+//   - args: 命令参数，hour（默认）或 day
+// 返回:
+//   - string: 命令响应内容
+func (t *Telegram) handleStats(args string) string {
+	if t.controller == nil {
+		return "管理功能不可用"
+	}
+	by := strings.TrimSpace(args)
+	if by == "" {
+		by = "hour"
+	}
+	history, err := t.controller.StatsHistory(by)
+	if err != nil {
+		return "用法: /stats [hour|day]"
+	}
+
+	var b strings.Builder
+	layout, span := "01-02 15:00", "最近24小时"
+	if by == "day" {
+		layout, span = "01-02", "最近7天"
+	}
+	fmt.Fprintf(&b, "%s统计：\n- 登录失败 %d 次，来自 %d 个IP\n- 封禁 %d 次", span, history.Failures, history.UniqueIPs, history.Bans)
+	if history.Failures > 0 || history.Bans > 0 {
+		b.WriteString("\n分时段：")
+		for _, bucket := range history.Buckets {
+			if bucket.Failures == 0 && bucket.Bans == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "\n- %s 失败 %d 次，%d 个IP，封禁 %d 次", bucket.Start.Format(layout), bucket.Failures, bucket.UniqueIPs, bucket.Bans)
+		}
+	}
+	if len(history.TopUsers) > 0 {
+		b.WriteString("\n用户名TOP：")
+		for _, user := range history.TopUsers {
+			fmt.Fprintf(&b, "\n- %s：%d 次", user.Name, user.Count)
 		}
 	}
+	if len(history.TopCountries) > 0 {
+		b.WriteString("\n国家TOP：")
+		for _, country := range history.TopCountries {
+			fmt.Fprintf(&b, "\n- %s：%d 次", country.Name, country.Count)
+		}
+	}
+	return b.String()
+}
 
-	return nil
-} 
\ No newline at end of file
+// banKeyboard 创建封禁通知下方的操作按钮，回调数据为 <操作>:<IP>
+func banKeyboard(ip string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("立即解封", "unban:"+ip),
+		tgbotapi.NewInlineKeyboardButtonData("临时白名单", "whitelist:"+ip),
+		tgbotapi.NewInlineKeyboardButtonData("永久封禁", "permanent:"+ip),
+	))
+}
+
+// handleCallback 处理封禁通知中按钮的回调
+// 操作成功后在原消息末尾注明结果并移除按钮，失败时只弹出错误提示，按钮保留以便重试
+// 参数:
+//   - query: 按钮回调
+func (t *Telegram) handleCallback(query *tgbotapi.CallbackQuery) {
+	if page, ok := strings.CutPrefix(query.Data, "banned:"); ok {
+		t.turnBannedPage(query, page)
+		return
+	}
+
+	operator := query.From.UserName
+	if operator == "" {
+		operator = query.From.FirstName
+	}
+	result, err := t.applyCallback(query.Data, operator)
+	if err != nil {
+		if _, err := t.bot.Request(tgbotapi.NewCallbackWithAlert(query.ID, err.Error())); err != nil {
+			t.logger.WithError(err).Error("响应按钮回调失败")
+		}
+		return
+	}
+
+	t.logger.WithFields(logrus.Fields{"action": query.Data, "operator": operator}).Info("已通过Telegram按钮执行操作")
+
+	if _, err := t.bot.Request(tgbotapi.NewCallback(query.ID, result)); err != nil {
+		t.logger.WithError(err).Error("响应按钮回调失败")
+	}
+	if query.Message == nil {
+		return
+	}
+	// 编辑时不带reply_markup，Telegram会移除原消息的按钮
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID,
+		fmt.Sprintf("%s\n\n✅ %s（操作人: %s）", query.Message.Text, result, operator))
+	if _, err := t.bot.Request(edit); err != nil {
+		t.logger.WithError(err).Error("更新封禁通知失败")
+	}
+}
+
+// turnBannedPage 处理封禁列表的翻页按钮，在原消息上显示指定页
+func (t *Telegram) turnBannedPage(query *tgbotapi.CallbackQuery, page string) {
+	if _, err := t.bot.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+		t.logger.WithError(err).Error("响应按钮回调失败")
+	}
+	if query.Message == nil {
+		return
+	}
+	n, _ := strconv.Atoi(page)
+	text, markup := t.handleBanned(n)
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, text)
+	edit.ReplyMarkup = markup
+	if _, err := t.bot.Request(edit); err != nil {
+		t.logger.WithError(err).Error("更新封禁列表失败")
+	}
+}
+
+// applyCallback 执行按钮对应的操作
+// 参数:
+//   - data: 回调数据，格式见banKeyboard
+//   - operator: 点击按钮的用户，记录为临时白名单的申请人
+// 返回:
+//   - string: 操作结果
+//   - error: 回调数据无效或操作失败时的错误信息
+func (t *Telegram) applyCallback(data, operator string) (string, error) {
+	if t.controller == nil {
+		return "", fmt.Errorf("管理功能不可用")
+	}
+	action, ip, ok := strings.Cut(data, ":")
+	if !ok || ip == "" {
+		return "", fmt.Errorf("无效的操作: %s", data)
+	}
+
+	switch action {
+	case "unban":
+		if err := t.controller.Unban(ip); err != nil {
+			return "", fmt.Errorf("解封失败: %v", err)
+		}
+		return fmt.Sprintf("IP %s 已解除封禁", ip), nil
+	case "whitelist", "renew":
+		expire, err := t.controller.AllowTemporary(ip, "telegram:"+operator)
+		if err != nil {
+			return "", fmt.Errorf("加入临时白名单失败: %v", err)
+		}
+		if action == "renew" {
+			return fmt.Sprintf("%s 的临时白名单已续期至 %s", ip, expire.Format(timeLayout)), nil
+		}
+		return fmt.Sprintf("IP %s 已加入临时白名单并解除封禁，%s 到期", ip, expire.Format(timeLayout)), nil
+	case "permanent":
+		if err := t.controller.MakePermanent(ip); err != nil {
+			return "", fmt.Errorf("永久封禁失败: %v", err)
+		}
+		return fmt.Sprintf("IP %s 已永久封禁", ip), nil
+	default:
+		return "", fmt.Errorf("无效的操作: %s", data)
+	}
+}
@@ -0,0 +1,378 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// LoginSuccessData 登录成功通知模板可用的字段
+type LoginSuccessData struct {
+	Time    string `json:"time"`    // 通知时间
+	IP      string `json:"ip"`      // 登录IP地址
+	IPInfo  string `json:"ip_info"` // IP属地信息
+	Country string `json:"country"` // 登录来源国家，查询失败时为空
+	User    string `json:"user"`    // 登录用户名
+	Server  string `json:"server"`  // 服务器信息
+	Client  string `json:"client"`  // 客户端版本标识，sshd未记录时为空
+}
+
+// LoginFailedData 登录失败通知模板可用的字段
+type LoginFailedData struct {
+	Time        string `json:"time"`         // 通知时间
+	IP          string `json:"ip"`           // 登录IP地址
+	IPInfo      string `json:"ip_info"`      // IP属地信息
+	Country     string `json:"country"`      // 登录来源国家，查询失败时为空
+	User        string `json:"user"`         // 登录使用的用户名
+	Server      string `json:"server"`       // 服务器信息
+	Attempts    int    `json:"attempts"`     // 当前失败次数
+	MaxAttempts int    `json:"max_attempts"` // 封禁阈值
+	Client      string `json:"client"`       // 客户端版本标识，sshd未记录时为空
+}
+
+// IPBannedData IP封禁通知模板可用的字段
+type IPBannedData struct {
+	Time         string `json:"time"`          // 通知时间
+	IP           string `json:"ip"`            // 被封禁的IP地址
+	IPInfo       string `json:"ip_info"`       // IP属地信息
+	Country      string `json:"country"`       // 来源国家，查询失败时为空
+	Server       string `json:"server"`        // 服务器信息
+	Reason       string `json:"reason"`        // 封禁原因
+	Duration     int    `json:"duration"`      // 封禁时长（小时，四舍五入），保留用于兼容旧模板
+	DurationText string `json:"duration_text"` // 封禁时长，如 30m、12h、7d
+	ExpireTime   string `json:"expire_time"`   // 解封时间
+}
+
+// PasswordSprayData 密码喷洒告警模板可用的字段
+type PasswordSprayData struct {
+	Time   string `json:"time"`   // 通知时间
+	User   string `json:"user"`   // 被尝试的用户名
+	Count  int    `json:"count"`  // 来源IP数量
+	Window int    `json:"window"` // 统计时间窗口（分钟）
+	IPs    string `json:"ips"`    // 逗号分隔的来源IP
+	Server string `json:"server"` // 服务器信息
+}
+
+// SubnetAttackData 分布式攻击告警模板可用的字段
+type SubnetAttackData struct {
+	Time     string `json:"time"`     // 通知时间
+	Source   string `json:"source"`   // 攻击来源，网段CIDR或ASN
+	Failures int    `json:"failures"` // 失败次数
+	IPs      int    `json:"ips"`      // 不同IP数量
+	Window   int    `json:"window"`   // 统计时间窗口（分钟）
+	Action   string `json:"action"`   // 采取的处理措施
+	Server   string `json:"server"`   // 服务器信息
+}
+
+// RootLoginData root用户登录通知模板可用的字段
+type RootLoginData struct {
+	Time        string `json:"time"`         // 通知时间
+	IP          string `json:"ip"`           // 登录IP地址
+	IPInfo      string `json:"ip_info"`      // IP属地信息
+	Country     string `json:"country"`      // 登录来源国家，查询失败时为空
+	Server      string `json:"server"`       // 服务器信息
+	Result      string `json:"result"`       // 登录结果：成功或失败
+	Attempts    int    `json:"attempts"`     // 当前失败次数，登录成功时为0
+	MaxAttempts int    `json:"max_attempts"` // 封禁阈值
+	Client      string `json:"client"`       // 客户端版本标识，sshd未记录时为空
+}
+
+// RuleMatchedData 通用规则通知模板可用的字段
+type RuleMatchedData struct {
+	Time    string `json:"time"`    // 通知时间
+	Rule    string `json:"rule"`    // 规则名称
+	IP      string `json:"ip"`      // 触发规则的IP地址
+	IPInfo  string `json:"ip_info"` // IP属地信息
+	Country string `json:"country"` // 来源国家，查询失败时为空
+	Count   int    `json:"count"`   // 时间窗口内的匹配次数
+	Window  int    `json:"window"`  // 统计时间窗口（分钟）
+	Server  string `json:"server"`  // 服务器信息
+}
+
+// IPUnbannedData IP解除封禁通知模板可用的字段
+type IPUnbannedData struct {
+	Time    string `json:"time"`    // 通知时间
+	IP      string `json:"ip"`      // 被解除封禁的IP地址
+	IPInfo  string `json:"ip_info"` // IP属地信息
+	Country string `json:"country"` // 来源国家，查询失败时为空
+	Server  string `json:"server"`  // 服务器信息
+	Reason  string `json:"reason"`  // 解除原因：封禁到期或手动解除
+}
+
+// NewLocationData 异地登录告警模板可用的字段
+type NewLocationData struct {
+	Time    string `json:"time"`    // 通知时间
+	IP      string `json:"ip"`      // 登录IP地址
+	IPInfo  string `json:"ip_info"` // IP属地信息
+	User    string `json:"user"`    // 登录用户名
+	Kind    string `json:"kind"`    // 首次出现的位置类型：国家、ASN或国家和ASN
+	Country string `json:"country"` // 登录来源国家
+	ASN     string `json:"asn"`     // 登录来源ASN
+	Server  string `json:"server"`  // 服务器信息
+}
+
+// LogoutData 会话结束通知模板可用的字段
+type LogoutData struct {
+	Time      string `json:"time"`       // 会话结束时间
+	IP        string `json:"ip"`         // 登录IP地址
+	User      string `json:"user"`       // 登录用户名
+	LoginTime string `json:"login_time"` // 登录成功的时间
+	Duration  string `json:"duration"`   // 会话时长，如 1h23m45s
+	Server    string `json:"server"`     // 服务器信息
+}
+
+// AdaptiveReportData 国家自适应阈值报告模板可用的字段
+type AdaptiveReportData struct {
+	Time      string `json:"time"`      // 通知时间
+	Total     int    `json:"total"`     // 统计周期内带国家信息的失败次数
+	Countries string `json:"countries"` // 失败次数最多的国家及其占比
+	Strict    string `json:"strict"`    // 使用严格阈值的国家，没有时为"无"
+	Threshold int    `json:"threshold"` // 严格阈值
+	Advice    string `json:"advice"`    // sshd配置的加固建议，每行一条，没有时为空
+	Server    string `json:"server"`    // 服务器信息
+}
+
+// UnbanDigestData 每日自动解封汇总模板可用的字段
+type UnbanDigestData struct {
+	Date   string `json:"date"`   // 汇总日期
+	Count  int    `json:"count"`  // 当天自动解封的IP数量
+	IPs    string `json:"ips"`    // 逗号分隔的解封IP
+	Server string `json:"server"` // 服务器信息
+}
+
+// SummaryData 定期汇总报告模板可用的字段
+type SummaryData struct {
+	Start        string `json:"start"`         // 统计开始时间
+	End          string `json:"end"`           // 统计结束时间
+	Failed       int    `json:"failed"`        // 登录失败次数
+	IPs          int    `json:"ips"`           // 登录失败的不同IP数
+	Bans         int    `json:"bans"`          // 新增封禁数
+	Success      int    `json:"success"`       // 登录成功次数
+	TopIPs       string `json:"top_ips"`       // 失败次数最多的IP，每行一个，格式为 IP(次数)
+	TopCountries string `json:"top_countries"` // 失败次数最多的国家，每行一个，格式为 国家(次数)
+	Server       string `json:"server"`        // 服务器信息
+}
+
+// LogLagData 日志处理延迟告警模板可用的字段
+type LogLagData struct {
+	Time      string `json:"time"`      // 告警时间
+	Lag       int    `json:"lag"`       // 当前处理延迟（秒）
+	Threshold int    `json:"threshold"` // 告警阈值（秒）
+	Queue     int    `json:"queue"`     // 等待处理的登录事件数
+	Server    string `json:"server"`    // 服务器信息
+}
+
+// HeartbeatData 心跳通知模板可用的字段
+type HeartbeatData struct {
+	Time      string `json:"time"`      // 发送时间
+	Uptime    string `json:"uptime"`    // 已运行的时长
+	Banned    int    `json:"banned"`    // 当前封禁的IP数
+	Failures  int    `json:"failures"`  // 最近1小时登录失败次数
+	Resources string `json:"resources"` // 资源占用，每项一行，未启用notifications.heartbeat.resources时为空
+	Server    string `json:"server"`    // 服务器信息
+}
+
+// StartupData 启动通知模板可用的字段
+type StartupData struct {
+	Time    string `json:"time"`    // 启动时间
+	Summary string `json:"summary"` // 生效配置摘要，每项一行
+	Server  string `json:"server"`  // 服务器信息
+}
+
+// AuditReportData 主机安全检查报告模板可用的字段
+type AuditReportData struct {
+	Time     string `json:"time"`     // 检查时间
+	Score    int    `json:"score"`    // 得分（0-100）
+	Failed   int    `json:"failed"`   // 未通过的检查项数量
+	Findings string `json:"findings"` // 未通过的检查项及说明，每项一行，全部通过时为空
+	Server   string `json:"server"`   // 服务器信息
+}
+
+// AllowExpiringData 临时白名单到期提醒模板可用的字段
+type AllowExpiringData struct {
+	Time        string `json:"time"`         // 通知时间
+	Entry       string `json:"entry"`        // 白名单IP或CIDR网段
+	RequestedBy string `json:"requested_by"` // 申请人
+	ExpireTime  string `json:"expire_time"`  // 到期时间
+	Server      string `json:"server"`       // 服务器信息
+}
+
+// AccountLockData 账户锁定与解锁通知模板可用的字段
+type AccountLockData struct {
+	Time       string `json:"time"`        // 锁定或解锁时间
+	User       string `json:"user"`        // 本机账户名
+	Action     string `json:"action"`      // 已锁定 或 已解锁
+	Reason     string `json:"reason"`      // 锁定或解锁的原因
+	UnlockTime string `json:"unlock_time"` // 自动解锁时间，解锁通知中为空
+	Server     string `json:"server"`      // 服务器信息
+}
+
+// BatchData 合并通知的汇总模板可用的字段
+type BatchData struct {
+	Time   string `json:"time"`    // 汇总时间
+	Event  string `json:"event"`   // 被合并的通知类型
+	Label  string `json:"label"`   // 被合并的通知类型的中文名称
+	Count  int    `json:"count"`   // 被合并的通知条数
+	IPs    int    `json:"ips"`     // 涉及的IP数量
+	TopIPs string `json:"top_ips"` // 条数最多的IP及条数
+	Window int    `json:"window"`  // 汇总周期（分钟）
+	Server string `json:"server"`  // 服务器信息
+}
+
+// templateSpec 描述一类通知的模板配置及其示例数据
+type templateSpec struct {
+	name   string                                                       // 事件类型，与配置键名一致
+	config func(n config.NotificationsConfig) config.NotificationConfig // 取出该类通知的配置
+	sample interface{}                                                  // 用于试渲染的示例数据
+}
+
+// sampleStartupSummary 启动通知示例数据中的配置摘要
+const sampleStartupSummary = "防火墙: ufw，封禁时丢弃流量\n日志来源: sshd=/var/log/auth.log\njail: sshd(启用)\n阈值: 失败5次封禁24小时\n通知渠道: telegram\n存储: sqlite\n当前状态: 临时封禁3个，永久封禁1个，限速0个，白名单2条"
+
+// sampleAuditFindings 主机安全检查示例数据中未通过的检查项
+const sampleAuditFindings = "密码登录: PasswordAuthentication未关闭，建议改用公钥登录并设置 PasswordAuthentication no\n账户锁定: PAM中未配置pam_faillock，建议在sshd的auth配置中启用pam_faillock"
+
+// sampleUsage 心跳通知示例数据中的资源占用
+var sampleUsage = control.Resources{RSS: 38 << 20, HeapAlloc: 12 << 20, Goroutines: 57, OpenFiles: 23, MaxOpenFiles: 1024,
+	StoreBytes: 4608 << 10, LoginQueue: 0, LoginQueueCap: 1000, NotifyQueue: 2}
+
+// sampleResources 心跳通知示例数据中格式化后的资源占用
+var sampleResources = strings.Join(ResourceLines(sampleUsage), "\n")
+
+// templateSpecs 所有通知模板
+var templateSpecs = []templateSpec{
+	{EventLoginSuccess, func(n config.NotificationsConfig) config.NotificationConfig { return n.LoginSuccess },
+		LoginSuccessData{Time: "2024-01-01 12:00:00", IP: "192.168.1.1", IPInfo: "IP: 192.168.1.1\n属地: 中国 北京\nISP: 测试ISP", Country: "中国", User: "admin", Server: "测试服务器", Client: "OpenSSH_9.6p1"}},
+	{EventLoginFailed, func(n config.NotificationsConfig) config.NotificationConfig { return n.LoginFailed },
+		LoginFailedData{Time: "2024-01-01 12:00:00", IP: "192.168.1.2", IPInfo: "IP: 192.168.1.2\n属地: 中国 上海\nISP: 测试ISP", Country: "中国", User: "admin", Server: "测试服务器", Attempts: 3, MaxAttempts: 5, Client: "libssh_0.9.6"}},
+	{EventIPBanned, func(n config.NotificationsConfig) config.NotificationConfig { return n.IPBanned },
+		IPBannedData{Time: "2024-01-01 12:00:00", IP: "192.168.1.3", IPInfo: "IP: 192.168.1.3\n属地: 中国 广州\nISP: 测试ISP", Country: "中国", Server: "测试服务器", Reason: "SSH暴力破解", Duration: 24, DurationText: "1d", ExpireTime: "2024-01-02 12:00:00"}},
+	{EventPasswordSpray, func(n config.NotificationsConfig) config.NotificationConfig { return n.PasswordSpray },
+		PasswordSprayData{Time: "2024-01-01 12:00:00", User: "admin", Count: 3, Window: 10, IPs: "192.168.1.4, 192.168.1.5, 192.168.1.6", Server: "测试服务器"}},
+	{EventSubnetAttack, func(n config.NotificationsConfig) config.NotificationConfig { return n.SubnetAttack },
+		SubnetAttackData{Time: "2024-01-01 12:00:00", Source: "192.168.1.0/24", Failures: 100, IPs: 50, Window: 60, Action: "仅告警", Server: "测试服务器"}},
+	{EventRootLogin, func(n config.NotificationsConfig) config.NotificationConfig { return n.RootLogin },
+		RootLoginData{Time: "2024-01-01 12:00:00", IP: "192.168.1.7", IPInfo: "IP: 192.168.1.7\n属地: 中国 深圳\nISP: 测试ISP", Country: "中国", Server: "测试服务器", Result: "失败", Attempts: 1, MaxAttempts: 2, Client: "libssh_0.9.6"}},
+	{EventRuleMatched, func(n config.NotificationsConfig) config.NotificationConfig { return n.RuleMatched },
+		RuleMatchedData{Time: "2024-01-01 12:00:00", Rule: "vsftpd", IP: "192.168.1.8", IPInfo: "IP: 192.168.1.8\n属地: 中国 杭州\nISP: 测试ISP", Country: "中国", Count: 5, Window: 10, Server: "测试服务器"}},
+	{EventIPUnbanned, func(n config.NotificationsConfig) config.NotificationConfig { return n.IPUnbanned },
+		IPUnbannedData{Time: "2024-01-01 12:00:00", IP: "192.168.1.3", IPInfo: "IP: 192.168.1.3\n属地: 中国 广州\nISP: 测试ISP", Country: "中国", Server: "测试服务器", Reason: "封禁到期"}},
+	{EventNewLocation, func(n config.NotificationsConfig) config.NotificationConfig { return n.NewLocation },
+		NewLocationData{Time: "2024-01-01 12:00:00", IP: "192.168.1.10", IPInfo: "IP: 192.168.1.10\n属地: 美国 加利福尼亚\nISP: 测试ISP", User: "admin", Kind: "国家", Country: "美国", ASN: "AS15169", Server: "测试服务器"}},
+	{EventLogout, func(n config.NotificationsConfig) config.NotificationConfig { return n.Logout },
+		LogoutData{Time: "2024-01-01 13:23:45", IP: "192.168.1.1", User: "admin", LoginTime: "2024-01-01 12:00:00", Duration: "1h23m45s", Server: "测试服务器"}},
+	{EventAdaptiveReport, func(n config.NotificationsConfig) config.NotificationConfig { return n.AdaptiveReport },
+		AdaptiveReportData{Time: "2024-01-01 12:00:00", Total: 1000, Countries: "中国 45%, 美国 22%, 俄罗斯 8%", Strict: "中国, 美国", Threshold: 2,
+			Advice: "PasswordAuthentication yes → no：密码登录是暴力破解的唯一目标，改用公钥登录后攻击无法成功", Server: "测试服务器"}},
+	{EventUnbanDigest, func(n config.NotificationsConfig) config.NotificationConfig {
+		return config.NotificationConfig{Enabled: n.UnbanDigest.Enabled, Template: n.UnbanDigest.Template}
+	},
+		UnbanDigestData{Date: "2024-01-01", Count: 2, IPs: "192.168.1.3, 192.168.1.9", Server: "测试服务器"}},
+	{EventSummary, func(n config.NotificationsConfig) config.NotificationConfig {
+		return config.NotificationConfig{Enabled: n.Summary.Enabled, Template: n.Summary.Template}
+	},
+		SummaryData{Start: "2024-01-01 08:00:00", End: "2024-01-02 08:00:00", Failed: 1532, IPs: 87, Bans: 41, Success: 6,
+			TopIPs: "192.168.1.2(240)\n192.168.1.5(122)", TopCountries: "中国(812)\n美国(301)", Server: "测试服务器"}},
+	{EventLogLag, func(n config.NotificationsConfig) config.NotificationConfig {
+		return config.NotificationConfig{Enabled: n.LogLag.Enabled, Template: n.LogLag.Template}
+	},
+		LogLagData{Time: "2024-01-01 12:00:00", Lag: 95, Threshold: 60, Queue: 830, Server: "测试服务器"}},
+	{EventHeartbeat, func(n config.NotificationsConfig) config.NotificationConfig {
+		return config.NotificationConfig{Enabled: n.Heartbeat.Enabled, Template: n.Heartbeat.Template}
+	},
+		HeartbeatData{Time: "2024-01-01 09:00:00", Uptime: "3天2小时5分钟", Banned: 42, Failures: 118, Resources: sampleResources, Server: "测试服务器"}},
+	{EventBatch, func(n config.NotificationsConfig) config.NotificationConfig { return n.Batch },
+		BatchData{Time: "2024-01-01 12:05:00", Event: EventLoginFailed, Label: "登录失败", Count: 137, IPs: 12, TopIPs: "192.168.1.2(40), 192.168.1.5(22)", Window: 5, Server: "测试服务器"}},
+	{EventStartup, func(n config.NotificationsConfig) config.NotificationConfig { return n.Startup },
+		StartupData{Time: "2024-01-01 12:00:00", Summary: sampleStartupSummary, Server: "测试服务器"}},
+	{EventAuditReport, func(n config.NotificationsConfig) config.NotificationConfig { return n.AuditReport },
+		AuditReportData{Time: "2024-01-01 12:00:00", Score: 60, Failed: 2, Findings: sampleAuditFindings, Server: "测试服务器"}},
+	{EventAllowExpiring, func(n config.NotificationsConfig) config.NotificationConfig { return n.AllowExpiring },
+		AllowExpiringData{Time: "2024-01-01 12:00:00", Entry: "192.168.1.20", RequestedBy: "admin", ExpireTime: "2024-01-01 13:00:00", Server: "测试服务器"}},
+	{EventAccountLock, func(n config.NotificationsConfig) config.NotificationConfig { return n.AccountLock },
+		AccountLockData{Time: "2024-01-01 12:00:00", User: "admin", Action: "已锁定", Reason: "10分钟内被8个IP尝试登录", UnlockTime: "2024-01-01 12:30:00", Server: "测试服务器"}},
+}
+
+// TemplateResult 单个通知模板的检查结果
+type TemplateResult struct {
+	Name    string `json:"name"`            // 事件类型
+	Enabled bool   `json:"enabled"`         // 该类通知是否启用
+	Output  string `json:"output"`          // 使用示例数据渲染的结果
+	Error   string `json:"error,omitempty"` // 解析或渲染失败的原因
+}
+
+// LintTemplates 解析所有通知模板，并使用示例数据试渲染
+// 模板中引用了不存在的字段时渲染会失败，未启用的通知同样检查
+// 参数:
+//   - n: 通知配置
+//
+// 返回:
+//   - []TemplateResult: 每个模板的检查结果
+func LintTemplates(n config.NotificationsConfig) []TemplateResult {
+	results := make([]TemplateResult, 0, len(templateSpecs))
+	for _, spec := range templateSpecs {
+		cfg := spec.config(n)
+		result := TemplateResult{Name: spec.name, Enabled: cfg.Enabled}
+		text, _ := effectiveTemplate(n, spec.name)
+		output, err := renderTemplate(spec.name, text, spec.sample)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		result.Output = output
+		results = append(results, result)
+	}
+	return results
+}
+
+// CheckTemplates 检查所有通知模板，返回第一个错误
+// 参数:
+//   - n: 通知配置
+//
+// 返回:
+//   - error: 模板解析或渲染失败时的错误信息
+func CheckTemplates(n config.NotificationsConfig) error {
+	for _, result := range LintTemplates(n) {
+		if result.Error != "" {
+			return fmt.Errorf("通知配置错误: %s.template%s", result.Name, result.Error)
+		}
+	}
+	return nil
+}
+
+// Render 按该类通知配置的模板生成消息正文
+// 未修改过的模板按notifications.locale使用对应语言的内置模板，见catalog.go
+// 参数:
+//   - n: 通知配置
+//   - event: 要发送的通知
+// 返回:
+//   - Event: 设置了Message字段的通知
+//   - error: 模板解析或渲染失败时的错误信息，此时返回原通知
+func Render(n config.NotificationsConfig, event Event) (Event, error) {
+	tmpl, _ := effectiveTemplate(n, event.Type)
+	if tmpl == "" || event.Message != "" {
+		return event, nil
+	}
+	text, err := renderTemplate(event.Type, tmpl, event.Data)
+	if err != nil {
+		return event, fmt.Errorf("%s.template%v", event.Type, err)
+	}
+	event.Message = text
+	return event, nil
+}
+
+// renderTemplate 解析并渲染通知模板
+func renderTemplate(name, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析失败: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染失败: %v", err)
+	}
+	return buf.String(), nil
+}
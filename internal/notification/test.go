@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// TestEvents 所有可测试的事件类型
+var TestEvents = []string{EventLoginSuccess, EventLoginFailed, EventIPBanned, EventPasswordSpray, EventSubnetAttack, EventRootLogin, EventRuleMatched, EventIPUnbanned, EventNewLocation, EventLogout, EventAdaptiveReport, EventUnbanDigest, EventSummary, EventLogLag, EventHeartbeat, EventStartup, EventAuditReport, EventAllowExpiring, EventAccountLock, EventBatch}
+
+// ErrDisabled 表示该类通知在配置中未启用
+var ErrDisabled = errors.New("该类通知未启用")
+
+// SendTest 使用示例数据向Telegram发送一条测试通知
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func (t *Telegram) SendTest(name string) error {
+	return SendTest(t, t.config.Notifications, name)
+}
+
+// SendTest 使用示例数据向指定渠道发送一条测试通知，消息正文按配置的模板生成
+// 参数:
+//   - notifier: 通知渠道
+//   - n: 通知配置，用于判断该类通知是否启用
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func SendTest(notifier Notifier, n config.NotificationsConfig, name string) error {
+	event, err := testEvent(name)
+	if err != nil {
+		return err
+	}
+	if !Enabled(n, name) {
+		return ErrDisabled
+	}
+	if event, err = Render(n, event); err != nil {
+		return err
+	}
+	return notifier.Notify(event)
+}
+
+// testEvent 创建指定类型的示例通知
+func testEvent(name string) (Event, error) {
+	switch name {
+	case EventLoginSuccess:
+		return LoginSuccessEvent("192.168.1.1", "IP: 192.168.1.1\n属地: 中国 北京\nISP: 测试ISP", "测试服务器", time.Now()).WithUser("admin").WithCountry("中国"), nil
+	case EventLoginFailed:
+		return LoginFailedEvent("192.168.1.2", "IP: 192.168.1.2\n属地: 中国 上海\nISP: 测试ISP", "测试服务器", 3, 5, time.Now()).WithUser("admin").WithCountry("中国"), nil
+	case EventIPBanned:
+		return IPBannedEvent("192.168.1.3", "IP: 192.168.1.3\n属地: 中国 广州\nISP: 测试ISP", "测试服务器", "SSH暴力破解", 24*time.Hour, time.Now().Add(24*time.Hour), time.Now()).WithCountry("中国"), nil
+	case EventPasswordSpray:
+		return PasswordSprayEvent("admin", []string{"192.168.1.4", "192.168.1.5", "192.168.1.6"}, 10*time.Minute, "测试服务器"), nil
+	case EventSubnetAttack:
+		return SubnetAttackEvent("192.168.1.0/24", 100, 50, time.Hour, "仅告警", "测试服务器"), nil
+	case EventRootLogin:
+		return RootLoginEvent("192.168.1.7", "IP: 192.168.1.7\n属地: 中国 深圳\nISP: 测试ISP", "测试服务器", false, 1, 2, time.Now()).WithCountry("中国"), nil
+	case EventRuleMatched:
+		return RuleMatchedEvent("vsftpd", "192.168.1.8", "IP: 192.168.1.8\n属地: 中国 杭州\nISP: 测试ISP", "测试服务器", 5, 10*time.Minute, time.Now()).WithCountry("中国"), nil
+	case EventIPUnbanned:
+		return IPUnbannedEvent("192.168.1.3", "IP: 192.168.1.3\n属地: 中国 广州\nISP: 测试ISP", "测试服务器", "封禁到期", time.Now()).WithCountry("中国"), nil
+	case EventNewLocation:
+		return NewLocationEvent("192.168.1.10", "IP: 192.168.1.10\n属地: 美国 加利福尼亚\nISP: 测试ISP", "测试服务器", "admin", "国家", "美国", "AS15169", time.Now()), nil
+	case EventLogout:
+		return LogoutEvent("192.168.1.1", "admin", "测试服务器", time.Now().Add(-83*time.Minute), time.Now()), nil
+	case EventAdaptiveReport:
+		return AdaptiveReportEvent(1000, []string{"中国 45%", "美国 22%", "俄罗斯 8%"}, []string{"中国", "美国"}, 2,
+			[]string{"PasswordAuthentication yes → no：密码登录是暴力破解的唯一目标，改用公钥登录后攻击无法成功"}, "测试服务器"), nil
+	case EventUnbanDigest:
+		return UnbanDigestEvent(time.Now(), []string{"192.168.1.3", "192.168.1.9"}, "测试服务器"), nil
+	case EventSummary:
+		return SummaryEvent(time.Now().Add(-24*time.Hour), time.Now(), 1532, 41, 6,
+			map[string]int{"192.168.1.2": 240, "192.168.1.5": 122}, map[string]int{"中国": 812, "美国": 301}, 10, "测试服务器"), nil
+	case EventLogLag:
+		return LogLagEvent(95*time.Second, time.Minute, 830, "测试服务器"), nil
+	case EventHeartbeat:
+		return HeartbeatEvent(control.Status{Started: time.Now().Add(-74 * time.Hour), Banned: 42, FailuresLastHour: 118, Resources: sampleUsage}, true, "测试服务器", time.Now()), nil
+	case EventBatch:
+		return BatchEvent(EventLoginFailed, 137, map[string]int{"192.168.1.2": 40, "192.168.1.5": 22, "192.168.1.6": 3}, 5*time.Minute, "测试服务器", time.Now()), nil
+	case EventStartup:
+		return StartupEvent(sampleStartupSummary, "测试服务器", time.Now()), nil
+	case EventAllowExpiring:
+		return AllowExpiringEvent("192.168.1.20", "admin", "测试服务器", time.Now().Add(time.Hour), time.Now()), nil
+	case EventAccountLock:
+		return AccountLockedEvent("admin", "10分钟内被8个IP尝试登录", "测试服务器", time.Now().Add(30*time.Minute), time.Now()), nil
+	case EventAuditReport:
+		return AuditReportEvent(60, strings.Split(sampleAuditFindings, "\n"), "测试服务器", time.Now()), nil
+	default:
+		return Event{}, fmt.Errorf("未知的事件类型: %s", name)
+	}
+}
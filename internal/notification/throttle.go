@@ -0,0 +1,151 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// 汇总通知中列出的来源IP数量上限
+const batchTopIPs = 5
+
+// batchWindow 一类通知当前汇总周期的状态
+type batchWindow struct {
+	start      time.Time      // 周期开始时间
+	sent       int            // 本周期已立即发送的条数
+	suppressed int            // 本周期合并到汇总中的条数
+	ips        map[string]int // 被合并的通知中各IP的条数
+	server     string         // 被合并通知的服务器信息
+}
+
+// throttle 检查一条通知是否超出该类通知的发送频率
+// 每个batch_interval周期内前burst条立即发送，之后的通知合并，在周期结束时发送一条汇总
+// 返回:
+//   - bool: 通知已被合并、调用方不应再发送时为true
+func (d *Dispatcher) throttle(event Event) bool {
+	cfg, ok := notificationConfig(d.notifications, event.Type)
+	if !ok || cfg.Burst == 0 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	interval := time.Duration(cfg.BatchInterval) * time.Second
+	w := d.batches[event.Type]
+	if w == nil || now.Sub(w.start) >= interval {
+		w = &batchWindow{start: now, ips: make(map[string]int)}
+		d.batches[event.Type] = w
+	}
+	if w.sent < cfg.Burst {
+		w.sent++
+		return false
+	}
+
+	if w.suppressed == 0 {
+		// 第一条被合并的通知安排周期结束时的汇总
+		time.AfterFunc(w.start.Add(interval).Sub(now), func() { d.flush(event.Type, w) })
+	}
+	w.suppressed++
+	if ip := event.IP(); ip != "" {
+		w.ips[ip]++
+	}
+	w.server = event.Server()
+	return true
+}
+
+// flush 发送一个汇总周期内被合并的通知
+func (d *Dispatcher) flush(eventType string, w *batchWindow) {
+	d.mu.Lock()
+	if d.batches[eventType] == w {
+		delete(d.batches, eventType)
+	}
+	count, ips, server := w.suppressed, w.ips, w.server
+	interval := time.Since(w.start)
+	d.mu.Unlock()
+
+	if Enabled(d.notifications, EventBatch) {
+		d.send(BatchEvent(eventType, count, ips, interval, server, time.Now()))
+	}
+}
+
+// BatchEvent 创建合并通知的汇总
+// 参数:
+//   - eventType: 被合并的通知类型
+//   - count: 被合并的通知条数
+//   - ips: 被合并的通知中各IP的条数
+//   - window: 汇总周期
+//   - server: 服务器信息
+//   - at: 汇总时间
+// 返回:
+//   - Event: 汇总通知
+func BatchEvent(eventType string, count int, ips map[string]int, window time.Duration, server string, at time.Time) Event {
+	top := topCounts(ips, batchTopIPs)
+	return Event{Type: EventBatch, Time: at, Data: BatchData{
+		Time:   at.Format(timeLayout),
+		Event:  eventType,
+		Label:  EventLabel(eventType),
+		Count:  count,
+		IPs:    len(ips),
+		TopIPs: joinOrNone(top),
+		Window: max(minutes(window), 1),
+		Server: server,
+	}}
+}
+
+// topCounts 按次数从高到低返回前n项，格式为 名称(次数)，次数相同时按名称排序
+func topCounts(counts map[string]int, n int) []string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	for i, name := range names {
+		names[i] = fmt.Sprintf("%s(%d)", name, counts[name])
+	}
+	return names
+}
+
+// EventLabel 返回通知类型的中文名称，未知类型返回类型本身
+// 参数:
+//   - eventType: 事件类型
+// 返回:
+//   - string: 中文名称
+func EventLabel(eventType string) string {
+	if label, ok := eventLabels[eventType]; ok {
+		return label
+	}
+	return eventType
+}
+
+// eventLabels 各类通知的中文名称
+var eventLabels = map[string]string{
+	EventLoginSuccess:   "登录成功",
+	EventLoginFailed:    "登录失败",
+	EventIPBanned:       "IP封禁",
+	EventPasswordSpray:  "密码喷洒告警",
+	EventSubnetAttack:   "分布式攻击告警",
+	EventRootLogin:      "root登录",
+	EventRuleMatched:    "规则触发",
+	EventIPUnbanned:     "解除封禁",
+	EventNewLocation:    "异地登录",
+	EventLogout:         "退出登录",
+	EventAdaptiveReport: "自适应阈值报告",
+	EventUnbanDigest:    "解封汇总",
+	EventSummary:        "汇总报告",
+	EventLogLag:         "日志延迟告警",
+	EventHeartbeat:      "心跳",
+	EventStartup:        "启动通知",
+	EventAuditReport:    "主机安全检查",
+	EventAllowExpiring:  "临时白名单到期提醒",
+	EventAccountLock:    "账户锁定",
+	EventBatch:          "通知汇总",
+}
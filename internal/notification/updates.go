@@ -0,0 +1,110 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/yourusername/ssh_fb/internal/control"
+)
+
+// Telegram长轮询的参数
+const (
+	pollTimeout      = 60               // 每次拉取更新等待的最长时间（秒）
+	pollRetryInitial = time.Second      // 拉取失败后首次重试的间隔
+	pollRetryMax     = 2 * time.Minute  // 重试间隔的上限
+	pollDeadline     = 90 * time.Second // 单次拉取超过该时长未返回时视为连接已失效
+)
+
+// pollState 命令处理循环的健康状态
+type pollState struct {
+	mu          sync.Mutex
+	running     bool      // 循环是否在运行
+	lastPoll    time.Time // 最近一次成功拉取更新的时间
+	consecutive int       // 连续拉取失败的次数
+	failures    int       // 累计拉取失败的次数
+	panics      int       // 处理更新时恢复的panic次数
+	lastError   string    // 最近一次拉取失败的原因
+	lastFailure time.Time // 最近一次拉取失败的时间
+}
+
+func (p *pollState) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = true
+}
+
+func (p *pollState) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.running = false
+}
+
+// failed 记录一次拉取失败，返回连续失败的次数
+func (p *pollState) failed(err error) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.consecutive++
+	p.failures++
+	p.lastError = err.Error()
+	p.lastFailure = time.Now()
+	return p.consecutive
+}
+
+// succeeded 记录一次成功的拉取，之前处于连续失败状态时返回true
+func (p *pollState) succeeded() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	recovered := p.consecutive > 0
+	p.consecutive = 0
+	p.lastPoll = time.Now()
+	return recovered
+}
+
+func (p *pollState) recovered() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.panics++
+}
+
+// getUpdates 拉取一次更新，ctx取消或超过pollDeadline时不等待正在进行的长轮询结束
+// 被放弃的请求在返回后结果被丢弃，其中的更新未被确认，下次拉取时会重新收到
+func (t *Telegram) getUpdates(ctx context.Context, u tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	type result struct {
+		updates []tgbotapi.Update
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		updates, err := t.bot.GetUpdates(u)
+		done <- result{updates, err}
+	}()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(pollDeadline):
+		return nil, errors.New("拉取更新超时")
+	case r := <-done:
+		return r.updates, r.err
+	}
+}
+
+// UpdatesHealth 返回命令处理循环的健康状态
+// 返回:
+//   - control.UpdatesHealth: 长轮询的运行状态与失败统计
+func (t *Telegram) UpdatesHealth() control.UpdatesHealth {
+	p := &t.poll
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return control.UpdatesHealth{
+		Running:             p.running,
+		LastPoll:            p.lastPoll,
+		ConsecutiveFailures: p.consecutive,
+		Failures:            p.failures,
+		Panics:              p.panics,
+		LastError:           p.lastError,
+		LastFailure:         p.lastFailure,
+	}
+}
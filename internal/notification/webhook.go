@@ -0,0 +1,131 @@
+package notification
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// Webhook 将通知以JSON格式POST到配置的URL
+type Webhook struct {
+	urls          []string      // 接收通知的URL
+	secret        []byte        // HMAC签名密钥，为空时不签名
+	retryCount    int           // 重试次数
+	retryInterval time.Duration // 重试间隔
+	httpClient    *http.Client  // HTTP客户端
+
+	notifications config.NotificationsConfig // 各类通知的开关
+}
+
+// webhookPayload Webhook请求体
+type webhookPayload struct {
+	Type string      `json:"type"` // 事件类型
+	Time time.Time   `json:"time"` // 事件发生时间
+	Text string      `json:"text"` // 纯文本消息，与Telegram中显示的一致
+	Data interface{} `json:"data"` // 事件字段，与通知模板可用的字段一致
+}
+
+// NewWebhook 创建Webhook通知渠道
+// 参数:
+//   - cfg: Webhook配置
+//   - notifications: 各类通知的开关，用于发送测试通知
+// 返回:
+//   - *Webhook: 初始化后的Webhook渠道
+func NewWebhook(cfg config.WebhookConfig, notifications config.NotificationsConfig) *Webhook {
+	return &Webhook{
+		urls:          cfg.URLs,
+		secret:        []byte(cfg.Secret),
+		retryCount:    cfg.RetryCount,
+		retryInterval: time.Duration(cfg.RetryInterval) * time.Second,
+		httpClient:    &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		notifications: notifications,
+	}
+}
+
+// Notify 将通知POST到所有URL
+// 请求头X-SSHFB-Event为事件类型；配置了密钥时X-SSHFB-Signature为 "sha256=<请求体的HMAC-SHA256>"
+// 参数:
+//   - event: 要发送的通知
+// 返回:
+//   - error: 发送失败的URL及原因，全部成功时为nil
+func (w *Webhook) Notify(event Event) error {
+	body, err := json.Marshal(webhookPayload{Type: event.Type, Time: event.Time, Text: event.Text(), Data: event.Data})
+	if err != nil {
+		return fmt.Errorf("编码Webhook请求失败: %v", err)
+	}
+	var failed []string
+	for _, url := range w.urls {
+		if err := w.post(url, event.Type, body); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", url, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("发送Webhook失败: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SendTest 使用示例数据向Webhook发送一条测试通知
+// 参数:
+//   - name: 事件类型，取值见TestEvents
+// 返回:
+//   - error: 发送失败时的错误信息，通知未启用时返回ErrDisabled
+func (w *Webhook) SendTest(name string) error {
+	return SendTest(w, w.notifications, name)
+}
+
+// post 发送一次请求，网络错误、5xx与429响应会按配置重试
+func (w *Webhook) post(url, eventType string, body []byte) error {
+	var lastErr error
+	for i := 0; i <= w.retryCount; i++ {
+		if i > 0 {
+			time.Sleep(w.retryInterval)
+		}
+		retry, err := w.do(url, eventType, body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return lastErr
+}
+
+// do 发出一次请求
+// 返回:
+//   - bool: 失败时是否值得重试
+//   - error: 请求失败或返回非2xx状态码时的错误信息
+func (w *Webhook) do(url, eventType string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-SSHFB-Event", eventType)
+	if len(w.secret) > 0 {
+		mac := hmac.New(sha256.New, w.secret)
+		mac.Write(body)
+		req.Header.Set("X-SSHFB-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("HTTP %d", resp.StatusCode)
+}
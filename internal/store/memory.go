@@ -0,0 +1,201 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// 内存存储最多保留的事件与审计记录数量，超出后丢弃最早的记录
+const memoryLogLimit = 10000
+
+// Memory 只保存在内存中的存储，进程退出后数据丢失
+// 适用于不需要持久化的最小部署
+type Memory struct {
+	mu       sync.Mutex
+	bans     map[string]Ban
+	allows   map[string]Allow
+	attempts map[string]int
+	events   []event.Event
+	audit    []AuditEntry
+	daily    map[DailyStat]int64 // 每日统计，键中的Count为0
+}
+
+// NewMemory 创建内存存储
+// 返回:
+//   - *Memory: 空的内存存储
+func NewMemory() *Memory {
+	return &Memory{
+		bans:     make(map[string]Ban),
+		allows:   make(map[string]Allow),
+		attempts: make(map[string]int),
+	}
+}
+
+func (s *Memory) PutBan(ban Ban) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bans[ban.IP] = ban
+	return nil
+}
+
+func (s *Memory) DeleteBan(ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bans, ip)
+	return nil
+}
+
+func (s *Memory) Bans() ([]Ban, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bans := make([]Ban, 0, len(s.bans))
+	for _, ban := range s.bans {
+		bans = append(bans, ban)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].IP < bans[j].IP })
+	return bans, nil
+}
+
+func (s *Memory) PutAllow(allow Allow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allows[allow.Entry] = allow
+	return nil
+}
+
+func (s *Memory) DeleteAllow(entry string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.allows, entry)
+	return nil
+}
+
+func (s *Memory) Allows() ([]Allow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	allows := make([]Allow, 0, len(s.allows))
+	for _, allow := range s.allows {
+		allows = append(allows, allow)
+	}
+	sort.Slice(allows, func(i, j int) bool { return allows[i].Entry < allows[j].Entry })
+	return allows, nil
+}
+
+func (s *Memory) AddAttempt(ip string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[ip]++
+	return s.attempts[ip], nil
+}
+
+func (s *Memory) ResetAttempts(ip string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, ip)
+	return nil
+}
+
+func (s *Memory) AppendEvent(e event.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(tail(s.events, memoryLogLimit-1), e)
+	return nil
+}
+
+func (s *Memory) Events(since time.Time, limit int) ([]event.Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []event.Event
+	for _, e := range s.events {
+		if e.Time.After(since) {
+			result = append(result, e)
+		}
+	}
+	return tail(result, limit), nil
+}
+
+func (s *Memory) AppendAudit(entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = append(tail(s.audit, memoryLogLimit-1), entry)
+	return nil
+}
+
+func (s *Memory) Audit(since time.Time, limit int) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []AuditEntry
+	for _, entry := range s.audit {
+		if entry.Time.After(since) {
+			result = append(result, entry)
+		}
+	}
+	return tail(result, limit), nil
+}
+
+func (s *Memory) RollupEvents(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.events[:0]
+	for _, e := range s.events {
+		if !e.Time.Before(before) {
+			kept = append(kept, e)
+			continue
+		}
+		if s.daily == nil {
+			s.daily = make(map[DailyStat]int64)
+		}
+		s.daily[DailyStat{Day: e.Time.Format(dayLayout), Type: e.Type}] += int64(e.Count())
+	}
+	removed := len(s.events) - len(kept)
+	s.events = kept
+	return removed, nil
+}
+
+func (s *Memory) DailyStats(since time.Time) ([]DailyStat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	day := since.Format(dayLayout)
+	var stats []DailyStat
+	for key, count := range s.daily {
+		if key.Day >= day {
+			key.Count = count
+			stats = append(stats, key)
+		}
+	}
+	sortDailyStats(stats)
+	return stats, nil
+}
+
+func (s *Memory) PruneDailyStats(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	day := before.Format(dayLayout)
+	for key := range s.daily {
+		if key.Day < day {
+			delete(s.daily, key)
+		}
+	}
+	return nil
+}
+
+func (s *Memory) PruneAudit(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.audit[:0]
+	for _, entry := range s.audit {
+		if !entry.Time.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	removed := len(s.audit) - len(kept)
+	s.audit = kept
+	return removed, nil
+}
+
+func (s *Memory) Close() error {
+	return nil
+}
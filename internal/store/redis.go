@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// Redis请求的超时时间
+const redisTimeout = 5 * time.Second
+
+// Redis 保存在Redis中的存储，多台服务器使用相同前缀时共享同一份数据
+// 封禁、临时白名单、失败次数与每日统计保存为哈希，事件与审计记录保存为按时间排序的有序集合
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// OpenRedis 连接Redis
+// 参数:
+//   - cfg: Redis连接配置
+// 返回:
+//   - *Redis: 连接成功的存储
+//   - error: 无法连接时的错误信息
+func OpenRedis(cfg config.RedisConfig) (*Redis, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接Redis失败 %s: %v", cfg.Addr, err)
+	}
+	return &Redis{client: client, prefix: cfg.Prefix}, nil
+}
+
+func (s *Redis) key(name string) string {
+	return s.prefix + name
+}
+
+func (s *Redis) PutBan(ban Ban) error {
+	data, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.HSet(ctx, s.key("bans"), ban.IP, data).Err()
+}
+
+func (s *Redis) DeleteBan(ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.HDel(ctx, s.key("bans"), ip).Err()
+}
+
+func (s *Redis) Bans() ([]Ban, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := s.client.HGetAll(ctx, s.key("bans")).Result()
+	if err != nil {
+		return nil, err
+	}
+	bans := make([]Ban, 0, len(values))
+	for _, value := range values {
+		var ban Ban
+		if err := json.Unmarshal([]byte(value), &ban); err != nil {
+			return nil, fmt.Errorf("解析封禁记录失败: %v", err)
+		}
+		bans = append(bans, ban)
+	}
+	return bans, nil
+}
+
+func (s *Redis) PutAllow(allow Allow) error {
+	data, err := json.Marshal(allow)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.HSet(ctx, s.key("allows"), allow.Entry, data).Err()
+}
+
+func (s *Redis) DeleteAllow(entry string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.HDel(ctx, s.key("allows"), entry).Err()
+}
+
+func (s *Redis) Allows() ([]Allow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := s.client.HGetAll(ctx, s.key("allows")).Result()
+	if err != nil {
+		return nil, err
+	}
+	allows := make([]Allow, 0, len(values))
+	for _, value := range values {
+		var allow Allow
+		if err := json.Unmarshal([]byte(value), &allow); err != nil {
+			return nil, fmt.Errorf("解析白名单记录失败: %v", err)
+		}
+		allows = append(allows, allow)
+	}
+	return allows, nil
+}
+
+func (s *Redis) AddAttempt(ip string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	count, err := s.client.HIncrBy(ctx, s.key("attempts"), ip, 1).Result()
+	return int(count), err
+}
+
+func (s *Redis) ResetAttempts(ip string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.HDel(ctx, s.key("attempts"), ip).Err()
+}
+
+func (s *Redis) AppendEvent(e event.Event) error {
+	return s.appendLog("events", e.Time, e)
+}
+
+func (s *Redis) Events(since time.Time, limit int) ([]event.Event, error) {
+	values, err := s.readLog("events", since, limit)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]event.Event, 0, len(values))
+	for _, value := range values {
+		var e event.Event
+		if err := json.Unmarshal([]byte(value), &e); err != nil {
+			return nil, fmt.Errorf("解析事件失败: %v", err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+func (s *Redis) AppendAudit(entry AuditEntry) error {
+	return s.appendLog("audit", entry.Time, entry)
+}
+
+func (s *Redis) Audit(since time.Time, limit int) ([]AuditEntry, error) {
+	values, err := s.readLog("audit", since, limit)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]AuditEntry, 0, len(values))
+	for _, value := range values {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("解析审计记录失败: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *Redis) RollupEvents(before time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := s.rollupOnce(before)
+		total += n
+		if err != nil || n == 0 {
+			return total, err
+		}
+	}
+}
+
+// rollupOnce 汇总并删除最早的至多rollupBatch条过期事件
+// 共享同一前缀的多台服务器可能同时汇总，只统计本次实际删除的事件，避免重复计数
+func (s *Redis) rollupOnce(before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := s.client.ZRangeByScore(ctx, s.key("events"), &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + strconv.FormatInt(before.UnixNano(), 10),
+		Count: rollupBatch,
+	}).Result()
+	if err != nil || len(values) == 0 {
+		return 0, err
+	}
+
+	pipe := s.client.Pipeline()
+	removed := make([]*redis.IntCmd, len(values))
+	for i, value := range values {
+		removed[i] = pipe.ZRem(ctx, s.key("events"), value)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	counts := make(map[string]int64)
+	n := 0
+	for i, value := range values {
+		if removed[i].Val() == 0 {
+			continue
+		}
+		n++
+		var e event.Event
+		if err := json.Unmarshal([]byte(value), &e); err != nil {
+			continue
+		}
+		counts[e.Time.Format(dayLayout)+"|"+string(e.Type)] += int64(e.Count())
+	}
+	pipe = s.client.Pipeline()
+	for field, count := range counts {
+		pipe.HIncrBy(ctx, s.key("daily_stats"), field, count)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (s *Redis) DailyStats(since time.Time) ([]DailyStat, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := s.client.HGetAll(ctx, s.key("daily_stats")).Result()
+	if err != nil {
+		return nil, err
+	}
+	day := since.Format(dayLayout)
+	var stats []DailyStat
+	for field, value := range values {
+		d, typ, ok := strings.Cut(field, "|")
+		count, err := strconv.ParseInt(value, 10, 64)
+		if !ok || err != nil || d < day {
+			continue
+		}
+		stats = append(stats, DailyStat{Day: d, Type: event.Type(typ), Count: count})
+	}
+	sortDailyStats(stats)
+	return stats, nil
+}
+
+func (s *Redis) PruneDailyStats(before time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	fields, err := s.client.HKeys(ctx, s.key("daily_stats")).Result()
+	if err != nil {
+		return err
+	}
+	day := before.Format(dayLayout)
+	var expired []string
+	for _, field := range fields {
+		if d, _, _ := strings.Cut(field, "|"); d < day {
+			expired = append(expired, field)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	return s.client.HDel(ctx, s.key("daily_stats"), expired...).Err()
+}
+
+func (s *Redis) PruneAudit(before time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	n, err := s.client.ZRemRangeByScore(ctx, s.key("audit"), "-inf", "("+strconv.FormatInt(before.UnixNano(), 10)).Result()
+	return int(n), err
+}
+
+func (s *Redis) Close() error {
+	return s.client.Close()
+}
+
+// appendLog 将记录以时间为分数写入有序集合
+func (s *Redis) appendLog(name string, at time.Time, record interface{}) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return s.client.ZAdd(ctx, s.key(name), redis.Z{Score: float64(at.UnixNano()), Member: data}).Err()
+}
+
+// readLog 读取有序集合中since之后的最近limit条记录，按时间顺序返回
+func (s *Redis) readLog(name string, since time.Time, limit int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	values, err := s.client.ZRevRangeByScore(ctx, s.key(name), &redis.ZRangeBy{
+		Min:   "(" + strconv.FormatInt(unixNano(since), 10),
+		Max:   "+inf",
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	reverse(values)
+	return values, nil
+}
@@ -0,0 +1,400 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/event"
+	_ "modernc.org/sqlite" // 纯Go实现的SQLite驱动，不依赖cgo
+)
+
+// sqliteSchema 数据库表结构，时间均以Unix纳秒保存
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS bans (
+	ip           TEXT PRIMARY KEY,
+	type         TEXT NOT NULL,
+	expires_at   INTEGER NOT NULL DEFAULT 0,
+	state        TEXT NOT NULL DEFAULT 'applied',
+	reason       TEXT NOT NULL DEFAULT '',
+	country      TEXT NOT NULL DEFAULT '',
+	hostname     TEXT NOT NULL DEFAULT '',
+	asn          TEXT NOT NULL DEFAULT '',
+	as_org       TEXT NOT NULL DEFAULT '',
+	network_type TEXT NOT NULL DEFAULT '',
+	origin       TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS allows (
+	entry        TEXT PRIMARY KEY,
+	requested_by TEXT NOT NULL DEFAULT '',
+	created_at   INTEGER NOT NULL,
+	expires_at   INTEGER NOT NULL,
+	reminded     INTEGER NOT NULL DEFAULT 0
+);
+CREATE TABLE IF NOT EXISTS attempts (
+	ip    TEXT PRIMARY KEY,
+	count INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS events (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	time    INTEGER NOT NULL,
+	type    TEXT NOT NULL,
+	ip      TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	port    INTEGER NOT NULL DEFAULT 0,
+	pid     INTEGER NOT NULL DEFAULT 0,
+	user    TEXT NOT NULL DEFAULT '',
+	method  TEXT NOT NULL DEFAULT '',
+	weight  INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS events_time ON events (time);
+CREATE TABLE IF NOT EXISTS audit (
+	id     INTEGER PRIMARY KEY AUTOINCREMENT,
+	time   INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	target TEXT NOT NULL DEFAULT '',
+	source TEXT NOT NULL DEFAULT '',
+	detail TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS audit_time ON audit (time);
+CREATE TABLE IF NOT EXISTS daily_stats (
+	day   TEXT NOT NULL,
+	type  TEXT NOT NULL,
+	count INTEGER NOT NULL,
+	PRIMARY KEY (day, type)
+);
+`
+
+// rollupBatch 汇总事件时每个事务处理的最大事件数，避免长时间占用写锁
+const rollupBatch = 5000
+
+// SQLite 保存在本地数据库文件中的存储
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite 打开或创建SQLite数据库
+// 使用WAL模式，读取不会阻塞日志处理中的写入
+// 参数:
+//   - path: 数据库文件路径
+// 返回:
+//   - *SQLite: 打开的存储
+//   - error: 打开或初始化表结构失败时的错误信息
+func OpenSQLite(path string) (*SQLite, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %v", err)
+	}
+	// SQLite同一时间只允许一个写入者，单连接避免SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化数据库失败 %s: %v", path, err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("升级数据库失败 %s: %v", path, err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// sqliteColumns 旧版本创建的数据库中可能缺少的列及其定义
+var sqliteColumns = []struct {
+	table, column, definition string
+}{
+	{"bans", "state", "TEXT NOT NULL DEFAULT 'applied'"},
+	{"bans", "reason", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "country", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "hostname", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "asn", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "as_org", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "network_type", "TEXT NOT NULL DEFAULT ''"},
+	{"bans", "origin", "TEXT NOT NULL DEFAULT ''"},
+	{"events", "weight", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// migrateSQLite 为旧版本创建的数据库补充新增的列
+func migrateSQLite(db *sql.DB) error {
+	for _, c := range sqliteColumns {
+		var n int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLite) PutBan(ban Ban) error {
+	state := ban.State
+	if state == "" {
+		state = StateApplied
+	}
+	_, err := s.db.Exec(`INSERT INTO bans (ip, type, expires_at, state, reason, country, hostname, asn, as_org, network_type, origin)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(ip) DO UPDATE SET type = excluded.type, expires_at = excluded.expires_at, state = excluded.state,
+		reason = excluded.reason, country = excluded.country, hostname = excluded.hostname, asn = excluded.asn,
+		as_org = excluded.as_org, network_type = excluded.network_type, origin = excluded.origin`,
+		ban.IP, ban.Type, unixNano(ban.ExpiresAt), state, ban.Reason, ban.Country, ban.Hostname, ban.ASN, ban.ASOrg, ban.NetworkType, ban.Origin)
+	return err
+}
+
+func (s *SQLite) DeleteBan(ip string) error {
+	_, err := s.db.Exec(`DELETE FROM bans WHERE ip = ?`, ip)
+	return err
+}
+
+func (s *SQLite) Bans() ([]Ban, error) {
+	rows, err := s.db.Query(`SELECT ip, type, expires_at, state, reason, country, hostname, asn, as_org, network_type, origin FROM bans ORDER BY ip`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var bans []Ban
+	for rows.Next() {
+		var ban Ban
+		var expires int64
+		if err := rows.Scan(&ban.IP, &ban.Type, &expires, &ban.State,
+			&ban.Reason, &ban.Country, &ban.Hostname, &ban.ASN, &ban.ASOrg, &ban.NetworkType, &ban.Origin); err != nil {
+			return nil, err
+		}
+		ban.ExpiresAt = fromUnixNano(expires)
+		bans = append(bans, ban)
+	}
+	return bans, rows.Err()
+}
+
+func (s *SQLite) PutAllow(allow Allow) error {
+	_, err := s.db.Exec(`INSERT INTO allows (entry, requested_by, created_at, expires_at, reminded) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(entry) DO UPDATE SET requested_by = excluded.requested_by, created_at = excluded.created_at,
+		expires_at = excluded.expires_at, reminded = excluded.reminded`,
+		allow.Entry, allow.RequestedBy, unixNano(allow.CreatedAt), unixNano(allow.ExpiresAt), allow.Reminded)
+	return err
+}
+
+func (s *SQLite) DeleteAllow(entry string) error {
+	_, err := s.db.Exec(`DELETE FROM allows WHERE entry = ?`, entry)
+	return err
+}
+
+func (s *SQLite) Allows() ([]Allow, error) {
+	rows, err := s.db.Query(`SELECT entry, requested_by, created_at, expires_at, reminded FROM allows ORDER BY entry`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allows []Allow
+	for rows.Next() {
+		var allow Allow
+		var created, expires int64
+		if err := rows.Scan(&allow.Entry, &allow.RequestedBy, &created, &expires, &allow.Reminded); err != nil {
+			return nil, err
+		}
+		allow.CreatedAt, allow.ExpiresAt = fromUnixNano(created), fromUnixNano(expires)
+		allows = append(allows, allow)
+	}
+	return allows, rows.Err()
+}
+
+func (s *SQLite) AddAttempt(ip string) (int, error) {
+	var count int
+	err := s.db.QueryRow(`INSERT INTO attempts (ip, count) VALUES (?, 1)
+		ON CONFLICT(ip) DO UPDATE SET count = count + 1 RETURNING count`, ip).Scan(&count)
+	return count, err
+}
+
+func (s *SQLite) ResetAttempts(ip string) error {
+	_, err := s.db.Exec(`DELETE FROM attempts WHERE ip = ?`, ip)
+	return err
+}
+
+func (s *SQLite) AppendEvent(e event.Event) error {
+	_, err := s.db.Exec(`INSERT INTO events (time, type, ip, message, port, pid, user, method, weight) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Time.UnixNano(), string(e.Type), e.IP, e.Message, e.Port, e.PID, e.User, e.Method, e.Weight)
+	return err
+}
+
+func (s *SQLite) Events(since time.Time, limit int) ([]event.Event, error) {
+	rows, err := s.db.Query(`SELECT id, time, type, ip, message, port, pid, user, method, weight FROM events
+		WHERE time > ? ORDER BY time DESC, id DESC LIMIT ?`, unixNano(since), sqliteLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []event.Event
+	for rows.Next() {
+		var e event.Event
+		var at int64
+		var typ string
+		if err := rows.Scan(&e.Seq, &at, &typ, &e.IP, &e.Message, &e.Port, &e.PID, &e.User, &e.Method, &e.Weight); err != nil {
+			return nil, err
+		}
+		e.Time, e.Type = fromUnixNano(at), event.Type(typ)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverse(events)
+	return events, nil
+}
+
+func (s *SQLite) AppendAudit(entry AuditEntry) error {
+	_, err := s.db.Exec(`INSERT INTO audit (time, action, target, source, detail) VALUES (?, ?, ?, ?, ?)`,
+		entry.Time.UnixNano(), entry.Action, entry.Target, entry.Source, entry.Detail)
+	return err
+}
+
+func (s *SQLite) Audit(since time.Time, limit int) ([]AuditEntry, error) {
+	rows, err := s.db.Query(`SELECT time, action, target, source, detail FROM audit
+		WHERE time > ? ORDER BY time DESC, id DESC LIMIT ?`, unixNano(since), sqliteLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var at int64
+		if err := rows.Scan(&at, &entry.Action, &entry.Target, &entry.Source, &entry.Detail); err != nil {
+			return nil, err
+		}
+		entry.Time = fromUnixNano(at)
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	reverse(entries)
+	return entries, nil
+}
+
+func (s *SQLite) RollupEvents(before time.Time) (int, error) {
+	total := 0
+	for {
+		n, err := s.rollupOnce(before)
+		total += n
+		if err != nil || n < rollupBatch {
+			return total, err
+		}
+	}
+}
+
+// rollupOnce 在一个事务中汇总并删除最早的至多rollupBatch条过期事件
+func (s *SQLite) rollupOnce(before time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, time, type, weight FROM events WHERE time < ? ORDER BY id LIMIT ?`, before.UnixNano(), rollupBatch)
+	if err != nil {
+		return 0, err
+	}
+	counts := make(map[DailyStat]int64)
+	var lastID int64
+	n := 0
+	for rows.Next() {
+		var e event.Event
+		var at int64
+		var typ string
+		if err := rows.Scan(&lastID, &at, &typ, &e.Weight); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		counts[DailyStat{Day: fromUnixNano(at).Format(dayLayout), Type: event.Type(typ)}] += int64(e.Count())
+		n++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || n == 0 {
+		return 0, err
+	}
+
+	for key, count := range counts {
+		if _, err := tx.Exec(`INSERT INTO daily_stats (day, type, count) VALUES (?, ?, ?)
+			ON CONFLICT(day, type) DO UPDATE SET count = count + excluded.count`, key.Day, string(key.Type), count); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM events WHERE id <= ? AND time < ?`, lastID, before.UnixNano()); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func (s *SQLite) DailyStats(since time.Time) ([]DailyStat, error) {
+	rows, err := s.db.Query(`SELECT day, type, count FROM daily_stats WHERE day >= ? ORDER BY day, type`, since.Format(dayLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []DailyStat
+	for rows.Next() {
+		var stat DailyStat
+		var typ string
+		if err := rows.Scan(&stat.Day, &typ, &stat.Count); err != nil {
+			return nil, err
+		}
+		stat.Type = event.Type(typ)
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+func (s *SQLite) PruneDailyStats(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM daily_stats WHERE day < ?`, before.Format(dayLayout))
+	return err
+}
+
+func (s *SQLite) PruneAudit(before time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM audit WHERE time < ?`, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// sqliteLimit 将不大于0的limit转换为SQLite中表示不限制的-1
+func sqliteLimit(limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
+
+// unixNano 将时间转换为Unix纳秒，零值时间保存为0
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
@@ -0,0 +1,164 @@
+// Package store 提供封禁、临时白名单、失败次数、事件与审计记录的持久化
+// 各功能只依赖Store接口，具体使用内存、SQLite还是Redis由配置决定
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+	"github.com/yourusername/ssh_fb/internal/event"
+)
+
+// 支持的存储驱动
+const (
+	DriverMemory = "memory"
+	DriverSQLite = "sqlite"
+	DriverRedis  = "redis"
+)
+
+// 封禁记录的状态
+const (
+	StatePending = "pending" // 已记录封禁意图，防火墙规则尚未确认添加
+	StateApplied = "applied" // 防火墙规则已添加
+)
+
+// Ban 一条封禁记录
+type Ban struct {
+	IP        string    `json:"ip"`                   // 被封禁的IP或网段
+	Type      string    `json:"type"`                 // 封禁类型：temporary、permanent或limited
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // 解封时间，永久封禁为零值
+	State     string    `json:"state,omitempty"`      // 记录状态，为空时按applied处理
+
+	// 以下为封禁时查询到的来源信息，查询未启用或失败时为空
+	Reason      string `json:"reason,omitempty"`       // 封禁原因
+	Country     string `json:"country,omitempty"`      // 国家代码，如 CN
+	Hostname    string `json:"hostname,omitempty"`     // 反向解析得到的主机名
+	ASN         string `json:"asn,omitempty"`          // 自治系统号，如 AS4134
+	ASOrg       string `json:"as_org,omitempty"`       // 自治系统名称
+	NetworkType string `json:"network_type,omitempty"` // 网络类型：hosting、residential
+	Origin      string `json:"origin,omitempty"`       // 从集群同步的封禁所来自的服务器，本机封禁时为空
+}
+
+// HasSource 检查记录中是否有封禁时查询到的来源信息
+func (b Ban) HasSource() bool {
+	return b.Country != "" || b.Hostname != "" || b.ASN != "" || b.ASOrg != "" || b.NetworkType != ""
+}
+
+// CopySource 复制另一条记录中的封禁原因与来源信息，用于按当前状态重建记录时保留这些信息
+func (b *Ban) CopySource(from Ban) {
+	b.Reason, b.Country, b.Hostname = from.Reason, from.Country, from.Hostname
+	b.ASN, b.ASOrg, b.NetworkType = from.ASN, from.ASOrg, from.NetworkType
+	b.Origin = from.Origin
+}
+
+// Allow 一条有有效期的白名单记录
+type Allow struct {
+	Entry       string    `json:"entry"`              // IP或CIDR网段
+	RequestedBy string    `json:"requested_by"`       // 申请人，如Telegram用户名
+	CreatedAt   time.Time `json:"created_at"`         // 添加时间
+	ExpiresAt   time.Time `json:"expires_at"`         // 到期时间
+	Reminded    bool      `json:"reminded,omitempty"` // 是否已发送即将到期的提醒
+}
+
+// AuditEntry 一条执法操作的审计记录
+type AuditEntry struct {
+	Time   time.Time `json:"time"`             // 操作时间
+	Action string    `json:"action"`           // 操作类型，如ban、unban
+	Target string    `json:"target"`           // 操作对象，如IP地址
+	Source string    `json:"source"`           // 触发来源，如日志匹配、Telegram命令
+	Detail string    `json:"detail,omitempty"` // 补充说明
+}
+
+// DailyStat 一天内某类事件的数量，由超过保留期的事件汇总而来
+type DailyStat struct {
+	Day   string     `json:"day"`   // 日期，本地时间，格式为 2006-01-02
+	Type  event.Type `json:"type"`  // 事件类型
+	Count int64      `json:"count"` // 事件数，抽样事件按其代表的数量计算
+}
+
+// dayLayout 每日统计的日期格式
+const dayLayout = "2006-01-02"
+
+// Store 持久化存储
+// 所有方法都可以并发调用
+type Store interface {
+	// PutBan 写入或覆盖一条封禁记录
+	PutBan(ban Ban) error
+	// DeleteBan 删除IP的封禁记录，记录不存在时不报错
+	DeleteBan(ip string) error
+	// Bans 返回所有封禁记录
+	Bans() ([]Ban, error)
+
+	// PutAllow 写入或覆盖一条临时白名单记录
+	PutAllow(allow Allow) error
+	// DeleteAllow 删除临时白名单记录，记录不存在时不报错
+	DeleteAllow(entry string) error
+	// Allows 返回所有临时白名单记录
+	Allows() ([]Allow, error)
+
+	// AddAttempt 将IP的失败次数加一并返回累计次数
+	AddAttempt(ip string) (int, error)
+	// ResetAttempts 清零IP的失败次数
+	ResetAttempts(ip string) error
+
+	// AppendEvent 追加一条事件
+	AppendEvent(e event.Event) error
+	// Events 返回发生在since之后的最近limit条事件，按时间顺序排列
+	Events(since time.Time, limit int) ([]event.Event, error)
+
+	// AppendAudit 追加一条审计记录
+	AppendAudit(entry AuditEntry) error
+	// Audit 返回since之后的最近limit条审计记录，按时间顺序排列
+	Audit(since time.Time, limit int) ([]AuditEntry, error)
+
+	// RollupEvents 将发生在before之前的事件按天和类型汇总到每日统计后删除，返回删除的事件数
+	RollupEvents(before time.Time) (int, error)
+	// DailyStats 返回since当天及之后的每日统计，按日期和类型排序
+	DailyStats(since time.Time) ([]DailyStat, error)
+	// PruneDailyStats 删除before当天之前的每日统计
+	PruneDailyStats(before time.Time) error
+	// PruneAudit 删除发生在before之前的审计记录，返回删除的记录数
+	PruneAudit(before time.Time) (int, error)
+
+	// Close 关闭存储，释放连接与文件
+	Close() error
+}
+
+// Open 按配置打开存储
+// 参数:
+//   - cfg: 存储配置
+// 返回:
+//   - Store: 打开的存储
+//   - error: 驱动未知或连接失败时的错误信息
+func Open(cfg config.StoreConfig) (Store, error) {
+	switch cfg.Driver {
+	case DriverMemory:
+		return NewMemory(), nil
+	case DriverSQLite:
+		return OpenSQLite(cfg.Path)
+	case DriverRedis:
+		return OpenRedis(cfg.Redis)
+	default:
+		return nil, fmt.Errorf("未知的存储驱动: %s", cfg.Driver)
+	}
+}
+
+// sortDailyStats 按日期和类型排序
+func sortDailyStats(stats []DailyStat) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Day != stats[j].Day {
+			return stats[i].Day < stats[j].Day
+		}
+		return stats[i].Type < stats[j].Type
+	})
+}
+
+// tail 返回切片中最后limit个元素，limit不大于0时返回全部
+func tail[T any](items []T, limit int) []T {
+	if limit > 0 && len(items) > limit {
+		return items[len(items)-limit:]
+	}
+	return items
+}
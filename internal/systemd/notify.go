@@ -0,0 +1,59 @@
+// Package systemd 实现systemd的sd_notify协议，用于Type=notify服务报告启动完成和看门狗心跳
+// 不在systemd下运行（没有NOTIFY_SOCKET环境变量）时所有函数都不做任何事
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 常用的通知内容
+const (
+	Ready    = "READY=1"    // 启动完成
+	Stopping = "STOPPING=1" // 开始退出
+	Watchdog = "WATCHDOG=1" // 看门狗心跳
+)
+
+// Notify 向systemd发送状态通知
+// 参数:
+//   - state: 通知内容，如Ready、Watchdog
+// 返回:
+//   - bool: 是否在systemd下运行并已发送
+//   - error: 发送失败时的错误信息
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	// 以@开头的为抽象命名空间中的socket
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("连接systemd通知socket失败: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("发送systemd通知失败: %v", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval 返回systemd要求的看门狗超时时间，服务文件中设置了WatchdogSec时才有
+// 在该时间内没有收到Watchdog通知，systemd会按Restart设置重启服务
+// 返回:
+//   - time.Duration: 超时时间，未启用看门狗或通知不是发给本进程时为0
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
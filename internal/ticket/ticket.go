@@ -0,0 +1,249 @@
+// Package ticket 将事件报告提交到工单系统
+package ticket
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 工单接口请求的超时时间，附件较大时上传需要更长的时间
+const requestTimeout = 60 * time.Second
+
+// Attachment 工单附件
+type Attachment struct {
+	Name string // 文件名
+	Data []byte // 文件内容
+}
+
+// Ticket 要创建的工单
+type Ticket struct {
+	Title       string       // 标题
+	Description string       // 描述，纯文本
+	Attachments []Attachment // 附件
+}
+
+// Tracker 工单系统
+type Tracker interface {
+	// Create 创建工单并上传附件，返回工单的编号或地址
+	Create(t Ticket) (string, error)
+}
+
+// New 按名称创建工单系统客户端
+// 参数:
+//   - name: 工单系统，jira或servicenow
+//   - cfg: 告警配置，其中包含各工单系统的连接信息
+// 返回:
+//   - Tracker: 工单系统客户端
+//   - error: 名称未知或对应的工单系统未启用时的错误信息
+func New(name string, cfg config.AlertingConfig) (Tracker, error) {
+	client := &http.Client{Timeout: requestTimeout}
+	switch name {
+	case "jira":
+		if !cfg.Jira.Enabled {
+			return nil, fmt.Errorf("Jira未启用，请在配置文件中设置alerting.jira")
+		}
+		return &Jira{config: cfg.Jira, httpClient: client}, nil
+	case "servicenow":
+		if !cfg.ServiceNow.Enabled {
+			return nil, fmt.Errorf("ServiceNow未启用，请在配置文件中设置alerting.servicenow")
+		}
+		return &ServiceNow{config: cfg.ServiceNow, httpClient: client}, nil
+	}
+	return nil, fmt.Errorf("未知的工单系统: %s，可选值为jira、servicenow", name)
+}
+
+// Jira 通过REST API v2在Jira中创建工单
+type Jira struct {
+	config     config.JiraConfig // Jira配置
+	httpClient *http.Client      // HTTP客户端
+}
+
+// Create 创建Jira工单并上传附件
+// 参数:
+//   - t: 要创建的工单
+// 返回:
+//   - string: 工单地址，如 https://example.atlassian.net/browse/SEC-12
+//   - error: 创建工单或上传附件失败时的错误信息，工单已创建但附件上传失败时同时返回工单地址
+func (j *Jira) Create(t Ticket) (string, error) {
+	base := strings.TrimRight(j.config.URL, "/")
+	var fields struct {
+		Fields struct {
+			Project     map[string]string `json:"project"`
+			IssueType   map[string]string `json:"issuetype"`
+			Summary     string            `json:"summary"`
+			Description string            `json:"description"`
+		} `json:"fields"`
+	}
+	fields.Fields.Project = map[string]string{"key": j.config.Project}
+	fields.Fields.IssueType = map[string]string{"name": j.config.IssueType}
+	fields.Fields.Summary = t.Title
+	fields.Fields.Description = t.Description
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("编码Jira工单失败: %v", err)
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	if err := j.do(http.MethodPost, base+"/rest/api/2/issue", "application/json", bytes.NewReader(body), &created); err != nil {
+		return "", fmt.Errorf("创建Jira工单失败: %v", err)
+	}
+	link := base + "/browse/" + created.Key
+
+	if len(t.Attachments) == 0 {
+		return link, nil
+	}
+	form, contentType, err := multipartBody("file", t.Attachments)
+	if err != nil {
+		return link, fmt.Errorf("编码Jira附件失败: %v", err)
+	}
+	if err := j.do(http.MethodPost, base+"/rest/api/2/issue/"+created.Key+"/attachments", contentType, form, nil); err != nil {
+		return link, fmt.Errorf("上传Jira附件失败: %v", err)
+	}
+	return link, nil
+}
+
+// do 发送Jira请求，result不为nil时解码响应
+func (j *Jira) do(method, endpoint, contentType string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(j.config.User, j.config.Token)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	// 上传附件的接口要求该请求头以跳过XSRF检查
+	req.Header.Set("X-Atlassian-Token", "no-check")
+	return send(j.httpClient, req, result)
+}
+
+// ServiceNow 通过Table API在ServiceNow中创建记录
+type ServiceNow struct {
+	config     config.ServiceNowConfig // ServiceNow配置
+	httpClient *http.Client            // HTTP客户端
+}
+
+// Create 创建ServiceNow记录并上传附件
+// 参数:
+//   - t: 要创建的工单
+// 返回:
+//   - string: 记录编号，如 INC0010001
+//   - error: 创建记录或上传附件失败时的错误信息，记录已创建但附件上传失败时同时返回编号
+func (s *ServiceNow) Create(t Ticket) (string, error) {
+	base := strings.TrimRight(s.config.URL, "/")
+	body, err := json.Marshal(map[string]string{
+		"short_description": t.Title,
+		"description":       t.Description,
+		"category":          "security",
+	})
+	if err != nil {
+		return "", fmt.Errorf("编码ServiceNow记录失败: %v", err)
+	}
+
+	var created struct {
+		Result struct {
+			SysID  string `json:"sys_id"`
+			Number string `json:"number"`
+		} `json:"result"`
+	}
+	if err := s.do(http.MethodPost, base+"/api/now/table/"+s.config.Table, "application/json", bytes.NewReader(body), &created); err != nil {
+		return "", fmt.Errorf("创建ServiceNow记录失败: %v", err)
+	}
+	number := created.Result.Number
+	if number == "" {
+		number = created.Result.SysID
+	}
+
+	for _, a := range t.Attachments {
+		query := url.Values{"table_name": {s.config.Table}, "table_sys_id": {created.Result.SysID}, "file_name": {a.Name}}
+		if err := s.do(http.MethodPost, base+"/api/now/attachment/file?"+query.Encode(), contentTypeFor(a.Name), bytes.NewReader(a.Data), nil); err != nil {
+			return number, fmt.Errorf("上传ServiceNow附件%s失败: %v", a.Name, err)
+		}
+	}
+	return number, nil
+}
+
+// do 发送ServiceNow请求，result不为nil时解码响应
+func (s *ServiceNow) do(method, endpoint, contentType string, body io.Reader, result interface{}) error {
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.config.User, s.config.Password)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	return send(s.httpClient, req, result)
+}
+
+// send 发送请求并检查状态码，出错时附上响应中的错误说明
+func send(client *http.Client, req *http.Request, result interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if text := strings.TrimSpace(string(data)); text != "" {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncate(text, 300))
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	return nil
+}
+
+// multipartBody 将附件编码为multipart/form-data
+func multipartBody(field string, attachments []Attachment) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, a := range attachments {
+		part, err := w.CreateFormFile(field, a.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(a.Data); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+// contentTypeFor 按扩展名返回附件的类型
+func contentTypeFor(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(name, ".json"):
+		return "application/json"
+	case strings.HasSuffix(name, ".md"):
+		return "text/markdown"
+	}
+	return "text/plain"
+}
+
+// truncate 截断过长的错误说明
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,339 @@
+// Package tracing 记录日志处理流程各阶段的耗时，以OTLP/HTTP（JSON编码）协议导出到OpenTelemetry Collector或兼容的后端
+// 一条日志行对应一个trace：读取日志行为根span，解析、属地查询、防火墙操作与通知发送为其子span
+// 未启用跟踪或未被抽样时Span为nil，Span的所有方法对nil都不做任何事，调用方不需要判断
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ssh_fb/internal/config"
+)
+
+// 导出参数
+const (
+	exportBatch    = 512             // 每次请求最多导出的span数
+	exportInterval = 5 * time.Second // 未凑满一批时的导出间隔
+	exportTimeout  = 10 * time.Second
+	queueSize      = 8192 // 等待导出的最大span数，导出跟不上时超出的被丢弃
+)
+
+// serviceName OTLP资源属性service.name
+const serviceName = "ssh_fb"
+
+// OTLP中span的类型与状态
+const (
+	kindInternal = 1
+	kindClient   = 3
+	statusError  = 2
+)
+
+// Tracer 创建span并在后台批量导出
+type Tracer struct {
+	endpoint string            // OTLP/HTTP traces接口地址
+	headers  map[string]string // 导出请求附带的请求头
+	ratio    float64           // 根span的抽样比例
+	hostname string            // 资源属性host.name
+	client   *http.Client
+	logger   *logrus.Logger
+
+	queue chan *Span
+
+	mu      sync.Mutex
+	dropped int // 上次导出以来因队列已满丢弃的span数
+}
+
+// Span 一个处理阶段
+// 同一span只应由一个协程设置属性和结束，子span可以在其他协程中创建
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    []attribute
+	err      string
+}
+
+// attribute span属性
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// New 按调试配置创建Tracer并启动导出协程
+// 参数:
+//   - cfg: 调试配置，未启用trace_requests时返回nil
+//   - logger: 日志记录器，用于记录导出失败
+// 返回:
+//   - *Tracer: 初始化后的Tracer，未启用时为nil
+func New(cfg config.DebugConfig, logger *logrus.Logger) *Tracer {
+	if !cfg.TraceRequests {
+		return nil
+	}
+	t := &Tracer{
+		endpoint: cfg.TraceEndpoint,
+		headers:  cfg.TraceHeaders,
+		ratio:    cfg.TraceSampleRatio,
+		client:   &http.Client{Timeout: exportTimeout},
+		logger:   logger,
+		queue:    make(chan *Span, queueSize),
+	}
+	t.hostname, _ = os.Hostname()
+	go t.run()
+	return t
+}
+
+// Start 开始一个trace的根span，按抽样比例决定是否记录
+// 参数:
+//   - name: span名称
+//   - start: 开始时间，如读取到日志行的时间
+// 返回:
+//   - *Span: 根span，Tracer为nil或未被抽样时为nil
+func (t *Tracer) Start(name string, start time.Time) *Span {
+	if t == nil || (t.ratio < 1 && mathrand.Float64() >= t.ratio) {
+		return nil
+	}
+	s := &Span{tracer: t, name: name, kind: kindInternal, start: start}
+	rand.Read(s.traceID[:])
+	rand.Read(s.spanID[:])
+	return s
+}
+
+// Child 在当前span下开始一个子span
+// 参数:
+//   - name: span名称
+// 返回:
+//   - *Span: 子span，当前span为nil时为nil
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	c := &Span{tracer: s.tracer, traceID: s.traceID, parentID: s.spanID, name: name, kind: kindInternal, start: time.Now()}
+	rand.Read(c.spanID[:])
+	return c
+}
+
+// Client 开始一个调用外部服务的子span，如属地查询接口、防火墙命令与通知渠道
+// 参数:
+//   - name: span名称
+// 返回:
+//   - *Span: 子span，当前span为nil时为nil
+func (s *Span) Client(name string) *Span {
+	c := s.Child(name)
+	if c != nil {
+		c.kind = kindClient
+	}
+	return c
+}
+
+// Set 设置span属性
+// 参数:
+//   - key: 属性名，如 ip、jail
+//   - value: 属性值，支持字符串、整数、浮点数和布尔值，其他类型按%v格式化
+func (s *Span) Set(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.attrs = append(s.attrs, attribute{key: key, value: value})
+}
+
+// Fail 将span标记为失败，err为nil时不做任何事
+// 参数:
+//   - err: 错误信息
+func (s *Span) Fail(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err.Error()
+}
+
+// End 结束span并放入导出队列，重复调用只导出一次
+func (s *Span) End() {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	t := s.tracer
+	select {
+	case t.queue <- s:
+	default:
+		t.mu.Lock()
+		t.dropped++
+		t.mu.Unlock()
+	}
+}
+
+// run 导出协程，凑满一批或每隔exportInterval导出一次
+func (t *Tracer) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) >= exportBatch {
+				t.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			t.export(batch)
+			batch = nil
+		}
+	}
+}
+
+// export 导出一批span，失败时记录警告，不重试
+func (t *Tracer) export(batch []*Span) {
+	t.mu.Lock()
+	dropped := t.dropped
+	t.dropped = 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.logger.WithField("dropped", dropped).Warn("跟踪数据导出队列已满，已丢弃部分span")
+	}
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(t.encode(batch))
+	if err != nil {
+		t.logger.WithError(err).Warn("编码跟踪数据失败")
+		return
+	}
+	if err := t.post(body); err != nil {
+		t.logger.WithError(err).WithField("spans", len(batch)).Warn("导出跟踪数据失败")
+	}
+}
+
+// post 发送一次OTLP/HTTP导出请求
+func (t *Tracer) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP JSON编码，字段名见opentelemetry-proto的trace/v1/trace.proto
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resource     `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resource struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope scope      `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	scope struct {
+		Name string `json:"name"`
+	}
+	spanJSON struct {
+		TraceID           string     `json:"traceId"`
+		SpanID            string     `json:"spanId"`
+		ParentSpanID      string     `json:"parentSpanId,omitempty"`
+		Name              string     `json:"name"`
+		Kind              int        `json:"kind"`
+		StartTimeUnixNano string     `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+		Attributes        []keyValue `json:"attributes,omitempty"`
+		Status            *status    `json:"status,omitempty"`
+	}
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	// anyValue 整数按proto3的JSON映射编码为字符串
+	anyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// encode 将一批span编码为OTLP导出请求
+func (t *Tracer) encode(batch []*Span) exportRequest {
+	spans := make([]spanJSON, 0, len(batch))
+	for _, s := range batch {
+		js := spanJSON{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			js.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for _, a := range s.attrs {
+			js.Attributes = append(js.Attributes, keyValue{Key: a.key, Value: encodeValue(a.value)})
+		}
+		if s.err != "" {
+			js.Status = &status{Code: statusError, Message: s.err}
+		}
+		spans = append(spans, js)
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource: resource{Attributes: []keyValue{
+			{Key: "service.name", Value: encodeValue(serviceName)},
+			{Key: "host.name", Value: encodeValue(t.hostname)},
+		}},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: serviceName}, Spans: spans}},
+	}}}
+}
+
+// encodeValue 按类型编码属性值
+func encodeValue(v interface{}) anyValue {
+	switch v := v.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case int:
+		s := strconv.Itoa(v)
+		return anyValue{IntValue: &s}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return anyValue{IntValue: &s}
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	}
+	s := fmt.Sprint(v)
+	return anyValue{StringValue: &s}
+}
@@ -0,0 +1,67 @@
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// 限速规则共用的hashlimit表名，按源IP分别计数
+const hashlimitName = "ssh_fb"
+
+// LimitIP 限制指定IP对SSH端口新建连接的速率，超出速率的连接被丢弃
+// 与RedirectIP相同，规则直接写入iptables（IPv6为ip6tables），插入在INPUT链最前面，先于ufw的规则生效
+// 参数:
+//   - ip: 要限速的IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - perMinute: 每分钟允许的新建连接数
+// 返回:
+//   - error: 添加规则过程中的错误信息
+func (u *UFW) LimitIP(ip string, sshPort, perMinute int) error {
+	bin := iptablesFor(ip)
+	rule := limitRule(ip, sshPort, perMinute)
+	// 规则已存在时不重复添加，避免重启后规则叠加
+	if exec.Command(bin, append([]string{"-C", "INPUT"}, rule...)...).Run() == nil {
+		return nil
+	}
+	cmd := exec.Command(bin, append([]string{"-I", "INPUT"}, rule...)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("限速IP失败 %s: %v", ip, err)
+	}
+	return nil
+}
+
+// UnlimitIP 删除指定IP的限速规则
+// 参数:
+//   - ip: 要解除限速的IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - perMinute: 添加规则时使用的速率
+// 返回:
+//   - error: 删除规则过程中的错误信息
+func (u *UFW) UnlimitIP(ip string, sshPort, perMinute int) error {
+	cmd := exec.Command(iptablesFor(ip), append([]string{"-D", "INPUT"}, limitRule(ip, sshPort, perMinute)...)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("解除IP限速失败 %s: %v", ip, err)
+	}
+	return nil
+}
+
+// HasLimit 检查指定IP的限速规则是否存在
+// 参数:
+//   - ip: IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - perMinute: 添加规则时使用的速率
+// 返回:
+//   - bool: 规则存在时为true
+func (u *UFW) HasLimit(ip string, sshPort, perMinute int) bool {
+	return exec.Command(iptablesFor(ip), append([]string{"-C", "INPUT"}, limitRule(ip, sshPort, perMinute)...)...).Run() == nil
+}
+
+func limitRule(ip string, sshPort, perMinute int) []string {
+	return []string{"-s", ip, "-p", "tcp", "--dport", strconv.Itoa(sshPort),
+		"-m", "conntrack", "--ctstate", "NEW",
+		"-m", "hashlimit", "--hashlimit-above", strconv.Itoa(perMinute) + "/minute",
+		"--hashlimit-burst", strconv.Itoa(perMinute),
+		"--hashlimit-mode", "srcip", "--hashlimit-name", hashlimitName,
+		"-j", "DROP"}
+}
@@ -0,0 +1,77 @@
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// RedirectIP 将指定IP对SSH端口的连接重定向到本机的tarpit端口
+// ufw本身不支持端口重定向，规则直接写入iptables的nat表，ufw与iptables共用同一套netfilter规则；
+// IPv6地址使用ip6tables。重定向后的连接到达tarpit端口，该端口需要在ufw中放行
+// 参数:
+//   - ip: 要重定向的IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - tarpitPort: 本机tarpit服务监听的端口
+// 返回:
+//   - error: 添加规则过程中的错误信息
+func (u *UFW) RedirectIP(ip string, sshPort, tarpitPort int) error {
+	bin := iptablesFor(ip)
+	rule := redirectRule(ip, sshPort, tarpitPort)
+	// 规则已存在时不重复添加，避免重启后规则叠加
+	if exec.Command(bin, append([]string{"-t", "nat", "-C", "PREROUTING"}, rule...)...).Run() == nil {
+		return nil
+	}
+	cmd := exec.Command(bin, append([]string{"-t", "nat", "-I", "PREROUTING"}, rule...)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("重定向IP失败 %s: %v", ip, err)
+	}
+	return nil
+}
+
+// UnredirectIP 删除指定IP的tarpit重定向规则
+// 参数:
+//   - ip: 要解除重定向的IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - tarpitPort: 本机tarpit服务监听的端口
+// 返回:
+//   - error: 删除规则过程中的错误信息
+func (u *UFW) UnredirectIP(ip string, sshPort, tarpitPort int) error {
+	cmd := exec.Command(iptablesFor(ip), append([]string{"-t", "nat", "-D", "PREROUTING"}, redirectRule(ip, sshPort, tarpitPort)...)...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("解除IP重定向失败 %s: %v", ip, err)
+	}
+	return nil
+}
+
+// HasRedirect 检查指定IP的tarpit重定向规则是否存在
+// 参数:
+//   - ip: IP地址或CIDR网段
+//   - sshPort: sshd监听的端口
+//   - tarpitPort: 本机tarpit服务监听的端口
+// 返回:
+//   - bool: 规则存在时为true
+func (u *UFW) HasRedirect(ip string, sshPort, tarpitPort int) bool {
+	return exec.Command(iptablesFor(ip), append([]string{"-t", "nat", "-C", "PREROUTING"}, redirectRule(ip, sshPort, tarpitPort)...)...).Run() == nil
+}
+
+// SupportsRedirect 检查当前环境是否可以添加重定向规则
+// 返回:
+//   - bool: 系统中存在iptables时为true
+func (u *UFW) SupportsRedirect() bool {
+	_, err := exec.LookPath("iptables")
+	return err == nil
+}
+
+func redirectRule(ip string, sshPort, tarpitPort int) []string {
+	return []string{"-s", ip, "-p", "tcp", "--dport", strconv.Itoa(sshPort),
+		"-j", "REDIRECT", "--to-ports", strconv.Itoa(tarpitPort)}
+}
+
+func iptablesFor(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "ip6tables"
+	}
+	return "iptables"
+}
@@ -3,7 +3,10 @@ package firewall
 
 import (
 	"fmt"
+	"net"
 	"os/exec"
+	"slices"
+	"strings"
 )
 
 // UFW 结构体封装了UFW防火墙的操作
@@ -50,6 +53,57 @@ func (u *UFW) IsEnabled() bool {
 	return cmd.Run() == nil
 }
 
+// Active 查询UFW防火墙当前是否处于启用状态
+// 与IsEnabled不同，未启用时ufw status同样执行成功，这里根据输出判断
+// 返回:
+//   - bool: true表示规则正在生效
+//   - error: 无法执行ufw时的错误信息
+func (u *UFW) Active() (bool, error) {
+	out, err := exec.Command("ufw", "status").Output()
+	if err != nil {
+		return false, fmt.Errorf("查询ufw状态失败: %v", err)
+	}
+	return strings.Contains(string(out), "Status: active"), nil
+}
+
+// DeniedIPs 返回ufw中所有 deny from <IP> to any 形式的规则的来源地址
+// 返回:
+//   - []string: 规则中的IP或CIDR网段，按ufw status的顺序排列
+//   - error: 无法执行ufw时的错误信息
+func (u *UFW) DeniedIPs() ([]string, error) {
+	out, err := exec.Command("ufw", "status").Output()
+	if err != nil {
+		return nil, fmt.Errorf("查询ufw规则失败: %v", err)
+	}
+	return parseDeniedIPs(string(out)), nil
+}
+
+// parseDeniedIPs 解析ufw status的输出，每条规则一行，格式为 <To> <Action> <From>，
+// BanIP添加的规则中To为Anywhere（IPv6规则为Anywhere (v6)），Action为DENY或DENY IN
+func parseDeniedIPs(output string) []string {
+	var ips []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		i := slices.Index(fields, "DENY")
+		if i < 1 || fields[0] != "Anywhere" || i > 2 {
+			continue
+		}
+		rest := fields[i+1:]
+		if len(rest) > 0 && rest[0] == "IN" {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			continue
+		}
+		if net.ParseIP(rest[0]) != nil {
+			ips = append(ips, rest[0])
+		} else if _, _, err := net.ParseCIDR(rest[0]); err == nil {
+			ips = append(ips, rest[0])
+		}
+	}
+	return ips
+}
+
 // Enable 启用UFW防火墙
 // 返回:
 //   - error: 启用过程中的错误信息
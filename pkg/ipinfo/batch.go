@@ -0,0 +1,167 @@
+package ipinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 批量查询请求中每个IP请求的字段，与ip-api.com的批量接口一致
+const batchFields = "status,message,country,countryCode,regionName,city,isp,org,as,proxy,hosting,query"
+
+// batchResult ip-api.com批量接口返回的单个IP结果
+type batchResult struct {
+	Status      string `json:"status"`      // success或fail
+	Message     string `json:"message"`     // 失败原因
+	Query       string `json:"query"`       // 查询的IP
+	Country     string `json:"country"`     // 国家
+	CountryCode string `json:"countryCode"` // 两位国家代码
+	RegionName  string `json:"regionName"`  // 地区/省份
+	City        string `json:"city"`        // 城市
+	ISP         string `json:"isp"`         // 网络服务提供商
+	Org         string `json:"org"`         // 组织
+	AS          string `json:"as"`          // 自治系统，如 "AS4134 Chinanet"
+	Proxy       bool   `json:"proxy"`       // 是否为代理、VPN或TOR出口节点
+	Hosting     bool   `json:"hosting"`     // 是否为机房或云服务器
+}
+
+// SetBatch 设置批量查询接口
+// 批量接口使用ip-api.com的格式：POST一组 {"query": IP} 并按顺序返回结果
+// 参数:
+//   - url: 批量查询接口地址，为空时批量查询逐个调用GetIPInfo
+//   - size: 每次请求最多包含的IP数量
+//   - interval: 两次批量请求之间的最小间隔
+func (c *Client) SetBatch(url string, size int, interval time.Duration) {
+	c.batchURL = url
+	c.batchSize = size
+	c.batchInterval = interval
+}
+
+// GetIPInfoBatch 批量获取多个IP的详细信息
+// 已缓存的IP不会重新查询；其余IP按批量大小分块请求，块之间遵守最小间隔，
+// 接口返回剩余配额为0时等待到配额重置。查询结果写入缓存
+// 参数:
+//   - ips: 要查询的IP列表
+// 返回:
+//   - map[string]*IPInfo: 查询成功的IP及其信息，失败的IP不在其中
+//   - error: 部分块查询失败时返回最后一个错误，已查询到的结果仍会返回
+func (c *Client) GetIPInfoBatch(ips []string) (map[string]*IPInfo, error) {
+	results := make(map[string]*IPInfo, len(ips))
+	var pending []string
+	seen := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		if seen[ip] {
+			continue
+		}
+		seen[ip] = true
+		if info, ok := c.cache.get(ip); ok {
+			results[ip] = info
+		} else {
+			pending = append(pending, ip)
+		}
+	}
+
+	var lastErr error
+	if c.batchURL == "" || c.batchSize <= 0 {
+		for _, ip := range pending {
+			info, err := c.GetIPInfo(ip)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			results[ip] = info
+		}
+		return results, lastErr
+	}
+
+	var wait time.Duration
+	for start := 0; start < len(pending); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		if start > 0 {
+			time.Sleep(max(wait, c.batchInterval))
+		}
+		if !c.breaker.allow() {
+			return results, ErrCircuitOpen
+		}
+		var err error
+		wait, err = c.fetchBatch(pending[start:end], results)
+		if err != nil {
+			c.breaker.failure(err)
+			lastErr = err
+		} else {
+			c.breaker.success()
+		}
+	}
+	return results, lastErr
+}
+
+// fetchBatch 发出一次批量查询请求，查询成功的结果写入results和缓存
+// 返回:
+//   - time.Duration: 配额用尽时需要等待的时间，否则为0
+//   - error: 请求或解析过程中的错误信息
+func (c *Client) fetchBatch(ips []string, results map[string]*IPInfo) (time.Duration, error) {
+	queries := make([]map[string]string, len(ips))
+	for i, ip := range ips {
+		queries[i] = map[string]string{"query": ip, "fields": batchFields, "lang": c.language}
+	}
+	body, err := json.Marshal(queries)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Post(c.batchURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		c.breaker.record(0, nil)
+		return 0, fmt.Errorf("批量获取IP信息失败: %v", err)
+	}
+	defer resp.Body.Close()
+	c.breaker.record(resp.StatusCode, resp.Header)
+
+	// ip-api.com在X-Rl中返回剩余请求数，X-Ttl中返回配额重置前的秒数
+	var wait time.Duration
+	if remaining, err := strconv.Atoi(resp.Header.Get("X-Rl")); err == nil && remaining == 0 {
+		if ttl, err := strconv.Atoi(resp.Header.Get("X-Ttl")); err == nil {
+			wait = time.Duration(ttl) * time.Second
+		}
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		c.breaker.throttle()
+		return wait, fmt.Errorf("接口限流: HTTP %d", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return wait, fmt.Errorf("批量接口返回HTTP %d", resp.StatusCode)
+	}
+
+	var items []batchResult
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return wait, fmt.Errorf("解析批量IP信息失败: %v", err)
+	}
+	for _, item := range items {
+		if item.Status != "success" || item.Query == "" {
+			continue
+		}
+		info := &IPInfo{
+			Country:     item.Country,
+			CountryCode: item.CountryCode,
+			Region:      item.RegionName,
+			City:        item.City,
+			ISP:         item.ISP,
+			Org:         item.Org,
+			Proxy:       item.Proxy,
+			Hosting:     item.Hosting,
+		}
+		// "AS4134 Chinanet" 只保留自治系统号，与单个查询接口的asn字段一致
+		info.ASN, _, _ = strings.Cut(item.AS, " ")
+		results[item.Query] = info
+		c.cache.put(item.Query, info)
+	}
+	return wait, nil
+}
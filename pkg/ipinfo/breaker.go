@@ -0,0 +1,158 @@
+package ipinfo
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示接口处于熔断期间，本次查询未发出请求
+var ErrCircuitOpen = errors.New("IP信息接口熔断中")
+
+// 常见接口返回剩余配额的响应头
+var quotaHeaders = []string{"X-RateLimit-Remaining", "X-Rl"}
+
+// Stats IP信息接口的调用统计与熔断状态
+type Stats struct {
+	Requests       int             `json:"requests"`                // 发出的HTTP请求数，包括重试
+	Failures       int             `json:"failures"`                // 重试后仍然失败的查询次数
+	Throttled      int             `json:"throttled"`               // 被接口限流的次数
+	Skipped        int             `json:"skipped"`                 // 熔断期间跳过的查询次数
+	StatusCodes    map[int]int     `json:"status_codes"`            // 各HTTP状态码的次数，0表示请求未完成
+	QuotaRemaining int             `json:"quota_remaining"`         // 接口最近一次返回的剩余配额，-1表示未知
+	LastError      string          `json:"last_error,omitempty"`    // 最近一次查询失败的原因
+	BreakerOpen    bool            `json:"breaker_open"`            // 当前是否处于熔断期间
+	BreakerUntil   time.Time       `json:"breaker_until,omitempty"` // 熔断结束时间
+	Providers      []ProviderStats `json:"providers,omitempty"`     // 使用多个属地查询接口时各接口的统计
+	Cached         int             `json:"cached"`                  // 缓存中的IP数量
+	CacheSize      int             `json:"cache_size"`              // 缓存容量，0表示不限
+	CacheHits      int             `json:"cache_hits"`              // 命中缓存的查询次数
+	CacheMisses    int             `json:"cache_misses"`            // 未命中缓存的查询次数
+	CacheEvicted   int             `json:"cache_evicted"`           // 因缓存已满淘汰的条目数
+}
+
+// ProviderStats 一个属地查询接口的调用统计与熔断状态
+type ProviderStats struct {
+	Name         string    `json:"name"`                    // 接口名称
+	Requests     int       `json:"requests"`                // 发出的HTTP请求数，包括重试
+	Failures     int       `json:"failures"`                // 重试后仍然失败的查询次数
+	Throttled    int       `json:"throttled"`               // 被接口限流的次数
+	BreakerOpen  bool      `json:"breaker_open"`            // 当前是否处于熔断期间
+	BreakerUntil time.Time `json:"breaker_until,omitempty"` // 熔断结束时间
+	LastError    string    `json:"last_error,omitempty"`    // 最近一次查询失败的原因
+}
+
+// breaker 统计接口调用并在接口持续失败或被限流时熔断
+type breaker struct {
+	mu          sync.Mutex
+	threshold   int           // 触发熔断的连续失败次数，0表示只在被限流时熔断
+	cooldown    time.Duration // 熔断持续时间，0表示不熔断
+	consecutive int           // 当前连续失败次数
+	openUntil   time.Time     // 熔断结束时间
+	failedAt    time.Time     // 最近一次查询失败的时间
+	stats       Stats         // 调用统计
+}
+
+func (b *breaker) configure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = threshold
+	b.cooldown = cooldown
+}
+
+// settings 返回熔断参数
+func (b *breaker) settings() (int, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.threshold, b.cooldown
+}
+
+// lastFailure 返回最近一次查询失败的时间
+func (b *breaker) lastFailure() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failedAt
+}
+
+// allow 检查是否可以发出请求，熔断期间返回false并计入跳过次数
+// 熔断结束后放行请求，若仍然失败会立即再次熔断
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if time.Now().Before(b.openUntil) {
+		b.stats.Skipped++
+		return false
+	}
+	return true
+}
+
+// record 记录一次HTTP请求的状态码和响应头中的剩余配额
+func (b *breaker) record(status int, header http.Header) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Requests++
+	if b.stats.StatusCodes == nil {
+		b.stats.StatusCodes = make(map[int]int)
+		b.stats.QuotaRemaining = -1
+	}
+	b.stats.StatusCodes[status]++
+	for _, name := range quotaHeaders {
+		if n, err := strconv.Atoi(header.Get(name)); err == nil {
+			b.stats.QuotaRemaining = n
+			break
+		}
+	}
+}
+
+// throttle 接口限流时立即熔断
+func (b *breaker) throttle() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Throttled++
+	b.open()
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutive = 0
+}
+
+func (b *breaker) failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.Failures++
+	b.stats.LastError = err.Error()
+	b.failedAt = time.Now()
+	b.consecutive++
+	if b.threshold > 0 && b.consecutive >= b.threshold {
+		b.open()
+	}
+}
+
+// open 进入熔断，调用方需持有b.mu锁
+func (b *breaker) open() {
+	if b.cooldown > 0 {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+func (b *breaker) snapshot() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.StatusCodes = make(map[int]int, len(b.stats.StatusCodes))
+	for code, n := range b.stats.StatusCodes {
+		stats.StatusCodes[code] = n
+	}
+	if b.stats.Requests == 0 {
+		stats.QuotaRemaining = -1
+	}
+	if time.Now().Before(b.openUntil) {
+		stats.BreakerOpen = true
+		stats.BreakerUntil = b.openUntil
+	}
+	return stats
+}
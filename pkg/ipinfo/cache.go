@@ -0,0 +1,197 @@
+package ipinfo
+
+import (
+	"container/list"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cacheEntry 缓存的一条IP信息
+type cacheEntry struct {
+	Info    IPInfo    `json:"info"`    // 查询到的IP信息
+	Expires time.Time `json:"expires"` // 过期时间
+	Used    time.Time `json:"used"`    // 最近一次命中或写入的时间，加载文件时用于恢复淘汰顺序
+}
+
+// cacheItem LRU链表中的一个元素
+type cacheItem struct {
+	ip    string
+	entry cacheEntry
+}
+
+// cache 带过期时间和容量上限的IP信息缓存，可持久化到文件
+// 条目数超过上限时淘汰最久未使用的条目，攻击者IP反复出现时始终留在缓存中
+type cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration            // 缓存有效期，0表示不缓存
+	size    int                      // 最多缓存的IP数量，0表示不限
+	path    string                   // 持久化文件路径，为空时只保存在内存中
+	entries map[string]*list.Element // 按IP索引的链表元素
+	order   *list.List               // 按最近使用排列的条目，表头为最近使用
+	dirty   bool                     // 上次保存后是否有新的查询结果
+
+	hits, misses, evicted int // 命中、未命中与因容量淘汰的次数
+}
+
+func (c *cache) get(ip string) (*IPInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[ip]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	item := elem.Value.(*cacheItem)
+	now := time.Now()
+	if now.After(item.entry.Expires) {
+		c.remove(elem)
+		c.misses++
+		return nil, false
+	}
+	item.entry.Used = now
+	c.order.MoveToFront(elem)
+	c.hits++
+	info := item.entry.Info
+	return &info, true
+}
+
+func (c *cache) put(ip string, info *IPInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+	c.insert(ip, cacheEntry{Info: *info, Expires: now.Add(c.ttl), Used: now})
+	c.dirty = true
+}
+
+// insert 写入或更新一个条目并移到表头，超出容量时淘汰表尾的条目，调用方需持有c.mu锁
+func (c *cache) insert(ip string, entry cacheEntry) {
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+	if elem, ok := c.entries[ip]; ok {
+		elem.Value.(*cacheItem).entry = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[ip] = c.order.PushFront(&cacheItem{ip: ip, entry: entry})
+	for c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.evicted++
+	}
+}
+
+// remove 删除一个条目，调用方需持有c.mu锁
+func (c *cache) remove(elem *list.Element) {
+	delete(c.entries, elem.Value.(*cacheItem).ip)
+	c.order.Remove(elem)
+}
+
+// stats 将缓存统计写入stats
+func (c *cache) stats(stats *Stats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats.Cached = len(c.entries)
+	stats.CacheSize = c.size
+	stats.CacheHits = c.hits
+	stats.CacheMisses = c.misses
+	stats.CacheEvicted = c.evicted
+}
+
+// SetCache 启用查询结果缓存
+// 缓存文件存在时加载其中未过期的条目，守护进程重启后无需重新查询已知的IP；文件中的条目超过size时保留最近使用的
+// 参数:
+//   - path: 缓存文件路径，为空时只缓存在内存中
+//   - ttl: 缓存有效期，0表示不缓存
+//   - size: 最多缓存的IP数量，超过时淘汰最久未使用的，0表示不限
+// 返回:
+//   - error: 读取或解析缓存文件失败时的错误信息
+func (c *Client) SetCache(path string, ttl time.Duration, size int) error {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	c.cache.ttl = ttl
+	c.cache.size = size
+	c.cache.path = path
+	c.cache.entries = make(map[string]*list.Element)
+	c.cache.order = list.New()
+	if path == "" || ttl <= 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries map[string]cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	// 按最近使用时间从旧到新写入，最近使用的条目排在表头，超出容量时先淘汰旧条目
+	// 旧版本的缓存文件没有used字段，按过期时间排列
+	ips := make([]string, 0, len(entries))
+	now := time.Now()
+	for ip, entry := range entries {
+		// 旧版本缓存的条目没有国家代码，按国家的封禁策略无法使用，重新查询
+		if entry.Info.Country != "" && entry.Info.CountryCode == "" {
+			continue
+		}
+		if now.Before(entry.Expires) {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Slice(ips, func(i, j int) bool {
+		a, b := entries[ips[i]], entries[ips[j]]
+		if !a.Used.Equal(b.Used) {
+			return a.Used.Before(b.Used)
+		}
+		return a.Expires.Before(b.Expires)
+	})
+	for _, ip := range ips {
+		c.cache.insert(ip, entries[ip])
+	}
+	c.cache.evicted = 0
+	return nil
+}
+
+// SaveCache 将缓存写入文件，过期的条目不会写入
+// 未设置缓存文件或上次保存后没有新的查询结果时不做任何事
+// 返回:
+//   - error: 写入文件失败时的错误信息
+func (c *Client) SaveCache() error {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+	if c.cache.path == "" || !c.cache.dirty {
+		return nil
+	}
+
+	now := time.Now()
+	entries := make(map[string]cacheEntry, len(c.cache.entries))
+	for elem := c.cache.order.Front(); elem != nil; {
+		next := elem.Next()
+		item := elem.Value.(*cacheItem)
+		if now.After(item.entry.Expires) {
+			c.cache.remove(elem)
+		} else {
+			entries[item.ip] = item.entry
+		}
+		elem = next
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.cache.path, data, 0644); err != nil {
+		return err
+	}
+	c.cache.dirty = false
+	return nil
+}
@@ -2,32 +2,44 @@
 package ipinfo
 
 import (
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
 // IPInfo 结构体存储IP地址的详细信息
 type IPInfo struct {
-	Country  string `json:"country"`  // 国家
-	Region   string `json:"region"`   // 地区/省份
-	City     string `json:"city"`     // 城市
-	ISP      string `json:"isp"`      // 网络服务提供商
-	Location string `json:"location"` // 地理位置
+	Country     string `json:"country"`      // 国家
+	CountryCode string `json:"country_code"` // ISO 3166-1两位国家代码，如 CN
+	Region      string `json:"region"`       // 地区/省份
+	City        string `json:"city"`         // 城市
+	ISP         string `json:"isp"`          // 网络服务提供商
+	Location    string `json:"location"`     // 地理位置
+	ASN         string `json:"asn"`          // 自治系统号，如 AS4134
+	Org         string `json:"org"`          // 自治系统所属组织
+	Proxy       bool   `json:"proxy"`        // 是否为代理、VPN或TOR出口节点，接口不提供时为false
+	Hosting     bool   `json:"hosting"`      // 是否为机房或云服务器，接口不提供时为false
 }
 
 // Client 结构体封装了IP信息查询客户端
 type Client struct {
-	apiURL        string        // API接口地址
+	providers     []*provider   // 按顺序尝试的属地查询接口
 	language      string        // 返回信息的语言
-	timeout       int          // 请求超时时间（秒）
-	retryCount    int          // 重试次数
-	retryInterval int          // 重试间隔（秒）
-	httpClient    *http.Client // HTTP客户端
+	timeout       int           // 请求超时时间（秒）
+	retryCount    int           // 重试次数
+	retryInterval int           // 重试间隔（秒）
+	httpClient    *http.Client  // HTTP客户端
+	breaker       breaker       // 批量查询接口的熔断器与调用统计
+	cache         cache         // 查询结果缓存
+	batchURL      string        // 批量查询接口地址，为空时不使用批量接口
+	batchSize     int           // 每次批量请求最多包含的IP数量
+	batchInterval time.Duration // 两次批量请求之间的最小间隔
 }
 
 // NewClient 创建并初始化一个新的IP信息查询客户端
+// 默认只使用apiURL一个接口，响应的字段名与IPInfo相同；可以通过SetProviders改为按顺序尝试多个接口
 // 参数:
 //   - apiURL: API接口地址
 //   - language: 返回信息的语言
@@ -38,7 +50,7 @@ type Client struct {
 //   - *Client: 初始化后的客户端实例
 func NewClient(apiURL, language string, timeout, retryCount, retryInterval int) *Client {
 	return &Client{
-		apiURL:        apiURL,
+		providers:     []*provider{legacyProvider(apiURL)},
 		language:      language,
 		timeout:       timeout,
 		retryCount:    retryCount,
@@ -49,42 +61,187 @@ func NewClient(apiURL, language string, timeout, retryCount, retryInterval int)
 	}
 }
 
+// SetProviders 设置按顺序尝试的属地查询接口，代替NewClient中的apiURL
+// 前一个接口出错、被限流或处于熔断期间时依次尝试下一个，每个接口单独统计和熔断
+// 参数:
+//   - providers: 属地查询接口，内置接口只需设置名称，见ResolveProvider
+// 返回:
+//   - error: 接口配置无效时的错误信息，此时保持原有设置
+func (c *Client) SetProviders(providers []Provider) error {
+	if len(providers) == 0 {
+		return nil
+	}
+	threshold, cooldown := c.breaker.settings()
+	chain := make([]*provider, 0, len(providers))
+	for _, p := range providers {
+		resolved, err := ResolveProvider(p)
+		if err != nil {
+			return err
+		}
+		chained := &provider{Provider: resolved}
+		chained.breaker.configure(threshold, cooldown)
+		chain = append(chain, chained)
+	}
+	c.providers = chain
+	return nil
+}
+
 // GetIPInfo 获取指定IP地址的详细信息
+// 优先使用未过期的缓存；否则按顺序查询各接口，跳过熔断期间的接口，所有接口都在熔断期间时直接返回ErrCircuitOpen；
+// 被接口限流（HTTP 429）时不再重试该接口
 // 参数:
 //   - ip: 要查询的IP地址
 // 返回:
 //   - *IPInfo: IP地址的详细信息
 //   - error: 查询过程中的错误信息
 func (c *Client) GetIPInfo(ip string) (*IPInfo, error) {
-	url := fmt.Sprintf("%s/%s?lang=%s", c.apiURL, ip, c.language)
-	
+	if info, ok := c.cache.get(ip); ok {
+		return info, nil
+	}
+
+	var errs []string
+	for _, p := range c.providers {
+		if !p.breaker.allow() {
+			continue
+		}
+		ipInfo, err := c.query(p, ip)
+		if err == nil {
+			c.cache.put(ip, ipInfo)
+			return ipInfo, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return nil, ErrCircuitOpen
+	}
+	return nil, fmt.Errorf("获取IP信息失败: %s", strings.Join(errs, "; "))
+}
+
+// query 通过一个接口查询IP信息，失败时按配置重试
+func (c *Client) query(p *provider, ip string) (*IPInfo, error) {
+	url := p.requestURL(ip, c.language)
 	var lastErr error
 	for i := 0; i <= c.retryCount; i++ {
-		resp, err := c.httpClient.Get(url)
-		if err != nil {
-			lastErr = err
-			if i < c.retryCount {
-				time.Sleep(time.Duration(c.retryInterval) * time.Second)
-				continue
-			}
-			return nil, fmt.Errorf("获取IP信息失败: %v", err)
+		ipInfo, status, err := c.fetch(p, url)
+		if err == nil {
+			p.breaker.success()
+			return ipInfo, nil
+		}
+		lastErr = err
+		if status == http.StatusTooManyRequests {
+			break
 		}
-		defer resp.Body.Close()
-
-		var ipInfo IPInfo
-		if err := json.NewDecoder(resp.Body).Decode(&ipInfo); err != nil {
-			lastErr = err
-			if i < c.retryCount {
-				time.Sleep(time.Duration(c.retryInterval) * time.Second)
-				continue
-			}
-			return nil, fmt.Errorf("解析IP信息失败: %v", err)
+		if i < c.retryCount {
+			time.Sleep(time.Duration(c.retryInterval) * time.Second)
+		}
+	}
+
+	p.breaker.failure(lastErr)
+	if len(c.providers) > 1 {
+		return nil, fmt.Errorf("%s: %v", p.Name, lastErr)
+	}
+	return nil, lastErr
+}
+
+// fetch 向一个接口发出一次查询请求并记录响应状态码与剩余配额
+// 返回:
+//   - *IPInfo: IP地址的详细信息
+//   - int: HTTP状态码，请求未完成时为0
+//   - error: 请求或解析过程中的错误信息
+func (c *Client) fetch(p *provider, url string) (*IPInfo, int, error) {
+	resp, err := c.httpClient.Get(url)
+	if err != nil {
+		p.breaker.record(0, nil)
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	p.breaker.record(resp.StatusCode, resp.Header)
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		p.breaker.throttle()
+		return nil, resp.StatusCode, fmt.Errorf("接口限流: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("读取IP信息失败: %v", err)
+	}
+	// 部分接口以HTTP 200返回错误，如ipapi.co的 {"error": true, "reason": "RateLimited"}、ipwho.is的 {"success": false}
+	info, limited, err := p.decode(data)
+	if limited {
+		p.breaker.throttle()
+		return nil, http.StatusTooManyRequests, fmt.Errorf("接口限流: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if err != nil {
+			return nil, resp.StatusCode, fmt.Errorf("接口返回HTTP %d: %v", resp.StatusCode, err)
 		}
+		return nil, resp.StatusCode, fmt.Errorf("接口返回HTTP %d", resp.StatusCode)
+	}
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return info, resp.StatusCode, nil
+}
 
-		return &ipInfo, nil
+// SetBreaker 设置熔断参数
+// 连续failures次查询失败或被接口限流后，cooldown时间内不再请求接口，属地信息按未知处理
+// 参数:
+//   - failures: 触发熔断的连续失败次数，0表示只在被限流时熔断
+//   - cooldown: 熔断持续时间
+func (c *Client) SetBreaker(failures int, cooldown time.Duration) {
+	c.breaker.configure(failures, cooldown)
+	for _, p := range c.providers {
+		p.breaker.configure(failures, cooldown)
 	}
+}
 
-	return nil, fmt.Errorf("获取IP信息失败: %v", lastErr)
+// Stats 返回接口调用统计与熔断状态
+// 各属地查询接口和批量查询接口的调用次数合并统计；所有属地查询接口都在熔断期间时BreakerOpen为true，
+// BreakerUntil为最早恢复的时间。使用多个接口时Providers中列出每个接口的统计
+// 返回:
+//   - Stats: 统计信息的副本
+func (c *Client) Stats() Stats {
+	stats := c.breaker.snapshot()
+	open := len(c.providers) > 0
+	lastFailure := c.breaker.lastFailure()
+	for _, p := range c.providers {
+		ps := p.breaker.snapshot()
+		stats.Requests += ps.Requests
+		stats.Failures += ps.Failures
+		stats.Throttled += ps.Throttled
+		stats.Skipped += ps.Skipped
+		for code, n := range ps.StatusCodes {
+			stats.StatusCodes[code] += n
+		}
+		if stats.QuotaRemaining < 0 {
+			stats.QuotaRemaining = ps.QuotaRemaining
+		}
+		if failedAt := p.breaker.lastFailure(); ps.LastError != "" && failedAt.After(lastFailure) {
+			stats.LastError, lastFailure = ps.LastError, failedAt
+		}
+		if !ps.BreakerOpen {
+			open = false
+		} else if stats.BreakerUntil.IsZero() || ps.BreakerUntil.Before(stats.BreakerUntil) {
+			stats.BreakerUntil = ps.BreakerUntil
+		}
+		if len(c.providers) > 1 {
+			stats.Providers = append(stats.Providers, ProviderStats{
+				Name:         p.Name,
+				Requests:     ps.Requests,
+				Failures:     ps.Failures,
+				Throttled:    ps.Throttled,
+				BreakerOpen:  ps.BreakerOpen,
+				BreakerUntil: ps.BreakerUntil,
+				LastError:    ps.LastError,
+			})
+		}
+	}
+	stats.BreakerOpen = open || stats.BreakerOpen
+	if !stats.BreakerOpen {
+		stats.BreakerUntil = time.Time{}
+	}
+	c.cache.stats(&stats)
+	return stats
 }
 
 // FormatIPInfo 格式化IP地址信息为可读字符串
@@ -95,6 +252,19 @@ func (c *Client) GetIPInfo(ip string) (*IPInfo, error) {
 func (c *Client) FormatIPInfo(ip string) string {
 	ipInfo, err := c.GetIPInfo(ip)
 	if err != nil {
+		return Format(ip, nil)
+	}
+	return Format(ip, ipInfo)
+}
+
+// Format 将已查询到的IP信息格式化为可读字符串
+// 参数:
+//   - ip: IP地址
+//   - ipInfo: IP地址的详细信息，查询失败时为nil
+// 返回:
+//   - string: 格式化后的IP信息字符串
+func Format(ip string, ipInfo *IPInfo) string {
+	if ipInfo == nil {
 		return fmt.Sprintf("IP: %s (无法获取属地信息)", ip)
 	}
 
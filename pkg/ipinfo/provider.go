@@ -0,0 +1,258 @@
+package ipinfo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IPInfo中可以映射的字段名
+var infoFields = []string{"country", "country_code", "region", "city", "isp", "location", "asn", "org", "proxy", "hosting"}
+
+// Provider 一个属地查询接口及其响应的字段映射
+type Provider struct {
+	Name string // 接口名称，用于日志和统计
+	// URL 查询地址，{ip}、{lang}和{token}分别替换为要查询的IP、语言和令牌；令牌为空时删除值为{token}的查询参数
+	URL   string
+	Token string // 接口令牌
+	// Fields IPInfo字段名（country、country_code、region、city、isp、location、asn、org、proxy、hosting）到响应JSON路径的映射，
+	// 路径以.分隔嵌套的对象，如 connection.asn；proxy和hosting的值为true时表示是
+	Fields map[string]string
+	// SuccessField 表示查询成功的字段路径，值不为true或"success"时按失败处理，为空时不检查
+	SuccessField string
+	// ErrorField 表示查询失败的字段路径，值为true或非空的字符串、对象时按失败处理，为空时不检查
+	ErrorField string
+	// MessageField 失败原因的字段路径，原因中包含limit时按限流处理
+	MessageField string
+}
+
+// builtinProviders 内置的属地查询接口
+var builtinProviders = map[string]Provider{
+	"ipapi.co": {
+		URL:          "https://ipapi.co/{ip}/json/",
+		Fields:       map[string]string{"country": "country_name", "country_code": "country_code", "region": "region", "city": "city", "isp": "org", "asn": "asn", "org": "org"},
+		ErrorField:   "error",
+		MessageField: "reason",
+	},
+	"ip-api.com": {
+		URL:          "http://ip-api.com/json/{ip}?lang={lang}&fields=status,message,country,countryCode,regionName,city,isp,org,as,proxy,hosting",
+		Fields:       map[string]string{"country": "country", "country_code": "countryCode", "region": "regionName", "city": "city", "isp": "isp", "asn": "as", "org": "org", "proxy": "proxy", "hosting": "hosting"},
+		SuccessField: "status",
+		MessageField: "message",
+	},
+	"ipinfo.io": {
+		URL:          "https://ipinfo.io/{ip}/json?token={token}",
+		Fields:       map[string]string{"country": "country", "country_code": "country", "region": "region", "city": "city", "isp": "org", "location": "loc", "asn": "org", "org": "org"},
+		ErrorField:   "error",
+		MessageField: "error.message",
+	},
+	"ipwho.is": {
+		URL:          "https://ipwho.is/{ip}?lang={lang}",
+		Fields:       map[string]string{"country": "country", "country_code": "country_code", "region": "region", "city": "city", "isp": "connection.isp", "asn": "connection.asn", "org": "connection.org"},
+		SuccessField: "success",
+		MessageField: "message",
+	},
+}
+
+// BuiltinProviders 返回内置属地查询接口的名称
+// 返回:
+//   - []string: 按名称排序的内置接口
+func BuiltinProviders() []string {
+	names := make([]string, 0, len(builtinProviders))
+	for name := range builtinProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveProvider 以内置接口为基础补全接口配置
+// 名称为内置接口时，未设置的地址、字段映射和成功/失败字段使用内置值，Fields只需列出与内置映射不同的字段
+// 参数:
+//   - p: 接口配置
+// 返回:
+//   - Provider: 补全后的接口
+//   - error: 不是内置接口且缺少地址或字段映射，或字段名无效时的错误信息
+func ResolveProvider(p Provider) (Provider, error) {
+	resolved := p
+	if builtin, ok := builtinProviders[p.Name]; ok {
+		if resolved.URL == "" {
+			resolved.URL = builtin.URL
+		}
+		if resolved.SuccessField == "" && resolved.ErrorField == "" {
+			resolved.SuccessField, resolved.ErrorField = builtin.SuccessField, builtin.ErrorField
+		}
+		if resolved.MessageField == "" {
+			resolved.MessageField = builtin.MessageField
+		}
+		resolved.Fields = make(map[string]string, len(builtin.Fields))
+		for field, path := range builtin.Fields {
+			resolved.Fields[field] = path
+		}
+		for field, path := range p.Fields {
+			resolved.Fields[field] = path
+		}
+	} else if p.URL == "" || len(p.Fields) == 0 {
+		return Provider{}, fmt.Errorf("%s 不是内置接口（%s），需要设置url和fields", p.Name, strings.Join(BuiltinProviders(), "、"))
+	}
+	for field := range resolved.Fields {
+		if !slices.Contains(infoFields, field) {
+			return Provider{}, fmt.Errorf("%s 的fields包含未知的字段 %s，可选值为 %s", p.Name, field, strings.Join(infoFields, "、"))
+		}
+	}
+	return resolved, nil
+}
+
+// provider 属地查询链中的一个接口，每个接口单独熔断
+type provider struct {
+	Provider
+	breaker breaker // 该接口的熔断器与调用统计
+}
+
+// requestURL 返回查询ip时请求的地址
+func (p *provider) requestURL(ip, language string) string {
+	raw := strings.NewReplacer("{ip}", url.PathEscape(ip), "{lang}", url.QueryEscape(language)).Replace(p.URL)
+	if p.Token != "" {
+		return strings.ReplaceAll(raw, "{token}", url.QueryEscape(p.Token))
+	}
+	// 未设置令牌时删除 token={token} 这样的参数，免费额度通常不需要令牌
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return strings.ReplaceAll(raw, "{token}", "")
+	}
+	query := parsed.Query()
+	for key, values := range query {
+		if len(values) == 1 && values[0] == "{token}" {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// decode 按字段映射解析响应
+// 返回:
+//   - *IPInfo: IP地址的详细信息
+//   - bool: 响应表示被限流时为true
+//   - error: 解析失败或响应表示查询失败时的错误信息
+func (p *provider) decode(data []byte) (*IPInfo, bool, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, fmt.Errorf("解析IP信息失败: %v", err)
+	}
+
+	failed := false
+	if p.SuccessField != "" {
+		value := lookupPath(doc, p.SuccessField)
+		failed = value != true && value != "success"
+	}
+	if p.ErrorField != "" {
+		switch value := lookupPath(doc, p.ErrorField).(type) {
+		case bool:
+			failed = failed || value
+		case string:
+			failed = failed || value != ""
+		case map[string]interface{}:
+			failed = true
+		}
+	}
+	if failed {
+		reason := stringValue(lookupPath(doc, p.MessageField))
+		if reason == "" {
+			reason = "未知原因"
+		}
+		return nil, isRateLimit(reason), fmt.Errorf("接口返回错误: %s", reason)
+	}
+
+	values := make(map[string]string, len(p.Fields))
+	for field, path := range p.Fields {
+		values[field] = stringValue(lookupPath(doc, path))
+	}
+	info := &IPInfo{
+		Country:     values["country"],
+		CountryCode: strings.ToUpper(values["country_code"]),
+		Region:      values["region"],
+		City:        values["city"],
+		ISP:         values["isp"],
+		Location:    values["location"],
+		ASN:         normalizeASN(values["asn"]),
+		Org:         values["org"],
+		Proxy:       values["proxy"] == "true",
+		Hosting:     values["hosting"] == "true",
+	}
+	// ipinfo.io、ip-api.com的org或as形如 "AS4134 Chinanet"，ASN已单独保存，名称中去掉前缀
+	if info.ASN != "" {
+		info.ISP = strings.TrimPrefix(info.ISP, info.ASN+" ")
+		info.Org = strings.TrimPrefix(info.Org, info.ASN+" ")
+	}
+	return info, false, nil
+}
+
+// isRateLimit 检查失败原因是否表示被限流，如ipapi.co的RateLimited、ipwho.is的 "You've hit the monthly limit"
+func isRateLimit(reason string) bool {
+	lower := strings.ToLower(reason)
+	return strings.Contains(lower, "limit") || strings.Contains(lower, "quota")
+}
+
+// lookupPath 按.分隔的路径在JSON对象中取值，路径不存在时返回nil
+func lookupPath(doc map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+	return value
+}
+
+// stringValue 将JSON值转换为字符串，数字不带小数部分时按整数输出
+func stringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+// normalizeASN 将 4134、"AS4134" 和 "AS4134 Chinanet" 统一为 AS4134
+func normalizeASN(value string) string {
+	value, _, _ = strings.Cut(strings.TrimSpace(value), " ")
+	if value == "" {
+		return ""
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		return "AS" + value
+	}
+	if !strings.HasPrefix(strings.ToUpper(value), "AS") {
+		return ""
+	}
+	return "AS" + value[2:]
+}
+
+// legacyProvider 返回api_url对应的接口，响应的字段名与IPInfo的json标签相同，与ipapi.co一样以error和reason表示失败
+func legacyProvider(apiURL string) *provider {
+	fields := make(map[string]string, len(infoFields))
+	for _, field := range infoFields {
+		fields[field] = field
+	}
+	return &provider{Provider: Provider{
+		Name:         "api_url",
+		URL:          strings.TrimRight(apiURL, "/") + "/{ip}?lang={lang}",
+		Fields:       fields,
+		ErrorField:   "error",
+		MessageField: "reason",
+	}}
+}